@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/backup"
+	"github.com/Raikerian/go-discord-chatgpt/internal/commands"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const cliUsage = `usage:
+  go-discord-chatgpt                  run the bot
+  go-discord-chatgpt backup <file>    write a backup of the bot's data to file, or - for stdout
+  go-discord-chatgpt restore <file>   restore a backup while the bot is stopped`
+
+// runCLI runs the subcommand in args, if any, and reports whether it did.
+// Subcommands use the same config as the bot, but not the bot itself.
+func runCLI(configPath string, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	if len(args) != 2 || (args[0] != "backup" && args[0] != "restore") {
+		return true, fmt.Errorf("unknown arguments %q\n%s", args, cliUsage)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return true, fmt.Errorf("failed to load config: %w", err)
+	}
+	state, err := storage.OpenProvider(cfg.Storage)
+	if err != nil {
+		return true, err
+	}
+	defer state.Close()
+	archive, err := storage.NewColdStore(cfg)
+	if err != nil {
+		return true, err
+	}
+	backups := backup.NewService(cfg, state, archive)
+	ctx := context.Background()
+
+	if args[0] == "backup" {
+		return true, writeBackup(ctx, backups, args[1])
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		return true, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+	manifest, err := backups.Restore(ctx, f)
+	if err != nil {
+		return true, fmt.Errorf("failed to restore backup: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Restored %s.\n", manifest.Summary())
+
+	return true, nil
+}
+
+// writeBackup writes a backup to file, which must not exist yet, or to
+// stdout for "-".
+func writeBackup(ctx context.Context, backups *backup.Service, file string) error {
+	if file == "-" {
+		_, err := backups.Create(ctx, os.Stdout, commands.AppVersion)
+
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	manifest, err := backups.Create(ctx, f, commands.AppVersion)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write backup: %w", closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(file)
+
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Backed up %s to %s.\n", manifest.Summary(), file)
+
+	return nil
+}
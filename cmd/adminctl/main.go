@@ -0,0 +1,239 @@
+// Command adminctl manages a running bot through its admin API: it lists the
+// guilds the bot is in, adjusts their budgets, flushes caches and exports
+// usage, for operators hosting the bot for many communities.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/adminapi"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const usage = `usage: adminctl [-url URL] [-token TOKEN] <command> [arguments]
+
+commands:
+  guilds                          list the guilds the bot is in, with their budgets and usage
+  usage [-format table|csv|json]  export the usage of every guild and user this UTC day and month
+  budget <guild-id> [limits]      set a guild's budgets, e.g. -guild-daily-usd 5 -user-daily-tokens 20000
+  budget <guild-id> -clear        drop the budgets set for a guild, so the configured ones apply
+  flush [cache...]                flush caches: conversations, ignored_threads (default: all)
+
+The URL and token default to $ADMINCTL_URL and $ADMINCTL_TOKEN.`
+
+// requestTimeout bounds each call to the admin API.
+const requestTimeout = 30 * time.Second
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "adminctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("adminctl", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), usage) }
+	baseURL := flags.String("url", envOr("ADMINCTL_URL", "http://127.0.0.1:9091"), "admin API URL")
+	token := flags.String("token", os.Getenv("ADMINCTL_TOKEN"), "admin API token")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+
+		return errors.New("no command given")
+	}
+	if *token == "" {
+		return errors.New("no token given, set -token or $ADMINCTL_TOKEN")
+	}
+
+	client := adminapi.NewClient(*baseURL, *token, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "guilds":
+		return listGuilds(ctx, client, out)
+	case "usage":
+		return exportUsage(ctx, client, rest, out)
+	case "budget":
+		return setBudget(ctx, client, rest, out)
+	case "flush":
+		return flushCaches(ctx, client, rest, out)
+	default:
+		flags.Usage()
+
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func listGuilds(ctx context.Context, client *adminapi.Client, out io.Writer) error {
+	guilds, err := client.Guilds(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tMEMBERS\tTODAY\tTHIS MONTH\tGUILD BUDGET\tUSER BUDGET")
+	for _, g := range guilds {
+		today, month := "-", "-"
+		if g.Usage != nil {
+			today = fmt.Sprintf("%d tokens, $%.2f", g.Usage.DayTokens, g.Usage.DayUSD)
+			month = fmt.Sprintf("%d tokens, $%.2f", g.Usage.MonthTokens, g.Usage.MonthUSD)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			g.ID, g.Name, g.Members, today, month, formatLimits(g.Budgets.Guild), formatLimits(g.Budgets.User))
+	}
+
+	return tw.Flush()
+}
+
+func exportUsage(ctx context.Context, client *adminapi.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("usage", flag.ContinueOnError)
+	format := flags.String("format", "table", "output format: table, csv or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	records, err := client.Usage(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(records)
+	case "csv":
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"scope", "id", "day", "day_tokens", "day_usd", "month", "month_tokens", "month_usd"})
+		for _, r := range records {
+			_ = w.Write([]string{
+				r.Scope, r.ID,
+				r.Day, strconv.Itoa(r.DayTokens), strconv.FormatFloat(r.DayUSD, 'f', 4, 64),
+				r.Month, strconv.Itoa(r.MonthTokens), strconv.FormatFloat(r.MonthUSD, 'f', 4, 64),
+			})
+		}
+		w.Flush()
+
+		return w.Error()
+	case "table":
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SCOPE\tID\tDAY\tDAY TOKENS\tDAY USD\tMONTH\tMONTH TOKENS\tMONTH USD")
+		for _, r := range records {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2f\t%s\t%d\t%.2f\n",
+				r.Scope, r.ID, r.Day, r.DayTokens, r.DayUSD, r.Month, r.MonthTokens, r.MonthUSD)
+		}
+
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %q, expected table, csv or json", *format)
+	}
+}
+
+func setBudget(ctx context.Context, client *adminapi.Client, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("budget needs a guild ID")
+	}
+	sf, err := discord.ParseSnowflake(args[0])
+	if err != nil || !sf.IsValid() {
+		return fmt.Errorf("invalid guild ID %q", args[0])
+	}
+	guildID := discord.GuildID(sf)
+
+	flags := flag.NewFlagSet("budget", flag.ContinueOnError)
+	clearLimits := flags.Bool("clear", false, "drop the budgets set for the guild")
+	var limits config.GuildBudgetsConfig
+	limitFlags(flags, "guild", &limits.Guild)
+	limitFlags(flags, "user", &limits.User)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var budgets config.GuildBudgetsConfig
+	switch {
+	case *clearLimits:
+		budgets, err = client.ClearBudget(ctx, guildID)
+	case limits.IsZero():
+		return errors.New("budget needs at least one limit, or -clear")
+	default:
+		budgets, err = client.SetBudget(ctx, guildID, limits)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Budgets of guild %s: guild %s, each user %s\n", guildID, formatLimits(budgets.Guild), formatLimits(budgets.User))
+
+	return nil
+}
+
+// limitFlags defines the flags setting limits, named after scope.
+func limitFlags(flags *flag.FlagSet, scope string, limits *config.BudgetLimits) {
+	flags.IntVar(&limits.DailyTokens, scope+"-daily-tokens", 0, "tokens per UTC day")
+	flags.IntVar(&limits.MonthlyTokens, scope+"-monthly-tokens", 0, "tokens per UTC month")
+	flags.Float64Var(&limits.DailyUSD, scope+"-daily-usd", 0, "estimated USD per UTC day")
+	flags.Float64Var(&limits.MonthlyUSD, scope+"-monthly-usd", 0, "estimated USD per UTC month")
+}
+
+func flushCaches(ctx context.Context, client *adminapi.Client, caches []string, out io.Writer) error {
+	flushed, err := client.FlushCaches(ctx, caches...)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range adminapi.Caches {
+		if n, ok := flushed[name]; ok {
+			fmt.Fprintf(out, "Flushed %d %s\n", n, strings.ReplaceAll(name, "_", " "))
+		}
+	}
+
+	return nil
+}
+
+// formatLimits describes the limits set in l.
+func formatLimits(l config.BudgetLimits) string {
+	var parts []string
+	if l.DailyTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens/day", l.DailyTokens))
+	}
+	if l.MonthlyTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens/month", l.MonthlyTokens))
+	}
+	if l.DailyUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f/day", l.DailyUSD))
+	}
+	if l.MonthlyUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f/month", l.MonthlyUSD))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
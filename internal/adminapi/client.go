@@ -0,0 +1,132 @@
+package adminapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// Client calls the admin API of a running bot.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a Client for the admin API at baseURL, such as
+// "http://127.0.0.1:9091", authenticating with token.
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, http: httpClient}
+}
+
+// Guilds lists the guilds the bot is in.
+func (c *Client) Guilds(ctx context.Context) ([]Guild, error) {
+	var resp GuildsResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/guilds", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Guilds, nil
+}
+
+// SetBudget sets the budgets of guildID, returning the limits in effect.
+func (c *Client) SetBudget(ctx context.Context, guildID discord.GuildID, limits config.GuildBudgetsConfig) (config.GuildBudgetsConfig, error) {
+	var resp BudgetResponse
+	if err := c.do(ctx, http.MethodPut, "/v1/guilds/"+guildID.String()+"/budget", limits, &resp); err != nil {
+		return config.GuildBudgetsConfig{}, err
+	}
+
+	return resp.Budgets, nil
+}
+
+// ClearBudget drops the budgets set for guildID, returning the configured
+// limits that apply again.
+func (c *Client) ClearBudget(ctx context.Context, guildID discord.GuildID) (config.GuildBudgetsConfig, error) {
+	var resp BudgetResponse
+	if err := c.do(ctx, http.MethodDelete, "/v1/guilds/"+guildID.String()+"/budget", nil, &resp); err != nil {
+		return config.GuildBudgetsConfig{}, err
+	}
+
+	return resp.Budgets, nil
+}
+
+// Usage exports the usage of every guild and user.
+func (c *Client) Usage(ctx context.Context) ([]chat.BudgetUsage, error) {
+	var resp UsageResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/usage", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Usage, nil
+}
+
+// FlushCaches empties caches, or every cache when none is named, returning
+// the entries removed from each.
+func (c *Client) FlushCaches(ctx context.Context, caches ...string) (map[string]int, error) {
+	path := "/v1/caches/flush"
+	if len(caches) > 0 {
+		path += "?" + url.Values{"cache": caches}.Encode()
+	}
+	var resp FlushResponse
+	if err := c.do(ctx, http.MethodPost, path, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Flushed, nil
+}
+
+// do sends a request with body encoded as JSON, when set, and decodes the
+// response into out. Failed requests return the error the API reported.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			return fmt.Errorf("admin API returned %s", resp.Status)
+		}
+
+		return errors.New(apiErr.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
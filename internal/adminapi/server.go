@@ -0,0 +1,381 @@
+// Package adminapi serves the HTTP admin API that adminctl talks to, so that
+// operators hosting the bot for many guilds can manage them all at once.
+package adminapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultAddr = "127.0.0.1:9091"
+	// maxBodyBytes bounds request bodies, which are small JSON documents.
+	maxBodyBytes = 64 << 10
+)
+
+// The caches POST /v1/caches/flush empties.
+const (
+	CacheConversations  = "conversations"
+	CacheIgnoredThreads = "ignored_threads"
+)
+
+// Caches lists every cache that can be flushed, in the order they are.
+var Caches = []string{CacheConversations, CacheIgnoredThreads}
+
+// Module provides the admin API server.
+var Module = fx.Module("adminapi",
+	fx.Provide(NewServer),
+	fx.Invoke(registerServer),
+)
+
+// Guild is a guild the bot is in, with its budgets.
+type Guild struct {
+	ID      discord.GuildID           `json:"id"`
+	Name    string                    `json:"name"`
+	Members uint64                    `json:"members,omitempty"`
+	Budgets config.GuildBudgetsConfig `json:"budgets"`         // Limits in effect, zero when budgets are off
+	Usage   *chat.BudgetUsage         `json:"usage,omitempty"` // Nil until the guild uses its budget
+}
+
+// GuildsResponse is the body of GET /v1/guilds.
+type GuildsResponse struct {
+	Guilds []Guild `json:"guilds"`
+}
+
+// BudgetResponse is the body of PUT and DELETE /v1/guilds/{id}/budget.
+type BudgetResponse struct {
+	GuildID discord.GuildID           `json:"guild_id"`
+	Budgets config.GuildBudgetsConfig `json:"budgets"` // Limits in effect after the change
+}
+
+// UsageResponse is the body of GET /v1/usage.
+type UsageResponse struct {
+	Usage []chat.BudgetUsage `json:"usage"`
+}
+
+// FlushResponse is the body of POST /v1/caches/flush: the entries removed
+// from each cache flushed.
+type FlushResponse struct {
+	Flushed map[string]int `json:"flushed"`
+}
+
+// ErrorResponse is the body of failed requests.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server is the admin API. It is nil when the API is disabled.
+type Server struct {
+	logger   *zap.Logger
+	addr     string
+	token    []byte
+	store    chat.ConversationStore
+	archiver *chat.ConversationArchiver
+	budgets  *chat.Budgets
+
+	mu     sync.Mutex
+	guilds map[discord.GuildID]Guild // Guilds the gateway reported, without budgets
+}
+
+// NewServer creates the admin API configured in admin_api, or nil when it is
+// disabled. The API refuses to start without a token.
+func NewServer(
+	logger *zap.Logger,
+	cfg *config.Config,
+	ses *session.Session,
+	store chat.ConversationStore,
+	archiver *chat.ConversationArchiver,
+	budgets *chat.Budgets,
+) (*Server, error) {
+	if !cfg.AdminAPI.Enabled {
+		return nil, nil
+	}
+	if cfg.AdminAPI.Token == "" {
+		return nil, errors.New("admin_api.token must be set to enable the admin API")
+	}
+
+	s := &Server{
+		logger:   logger.Named("admin_api"),
+		addr:     cfg.AdminAPI.Addr,
+		token:    []byte(cfg.AdminAPI.Token),
+		store:    store,
+		archiver: archiver,
+		budgets:  budgets,
+		guilds:   make(map[discord.GuildID]Guild),
+	}
+	if s.addr == "" {
+		s.addr = defaultAddr
+	}
+	ses.AddHandler(s.handleGuildCreate)
+	ses.AddHandler(s.handleGuildUpdate)
+	ses.AddHandler(s.handleGuildDelete)
+
+	return s, nil
+}
+
+func registerServer(lc fx.Lifecycle, s *Server) {
+	if s == nil {
+		return
+	}
+
+	srv := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			// Listen before returning, so a taken port fails startup
+			ln, err := net.Listen("tcp", s.addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen for the admin API on %s: %w", s.addr, err)
+			}
+			s.logger.Info("Serving admin API", zap.String("addr", ln.Addr().String()))
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					s.logger.Error("Admin API server stopped", zap.Error(err))
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return srv.Shutdown(ctx)
+		},
+	})
+}
+
+// Handler returns the admin API routes. Every request must carry the
+// configured token as a bearer token.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/guilds", s.listGuilds)
+	mux.HandleFunc("PUT /v1/guilds/{id}/budget", s.setBudget)
+	mux.HandleFunc("DELETE /v1/guilds/{id}/budget", s.clearBudget)
+	mux.HandleFunc("GET /v1/usage", s.exportUsage)
+	mux.HandleFunc("POST /v1/caches/flush", s.flushCaches)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), s.token) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleGuildCreate(e *gateway.GuildCreateEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.guilds[e.ID] = Guild{ID: e.ID, Name: e.Name, Members: e.MemberCount}
+}
+
+func (s *Server) handleGuildUpdate(e *gateway.GuildUpdateEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if guild, ok := s.guilds[e.ID]; ok {
+		guild.Name = e.Name
+		s.guilds[e.ID] = guild
+	}
+}
+
+// handleGuildDelete forgets guilds the bot was removed from. Outages also
+// produce this event, but do not remove the bot from the guild.
+func (s *Server) handleGuildDelete(e *gateway.GuildDeleteEvent) {
+	if e.Unavailable {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.guilds, e.ID)
+}
+
+// listGuilds returns the guilds the bot is in, with the budgets and usage of
+// each, by name.
+func (s *Server) listGuilds(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	guilds := make([]Guild, 0, len(s.guilds))
+	for _, guild := range s.guilds {
+		guilds = append(guilds, guild)
+	}
+	s.mu.Unlock()
+
+	usage := make(map[string]chat.BudgetUsage)
+	for _, u := range s.budgets.Usage() {
+		if u.Scope == "guild" {
+			usage[u.ID] = u
+		}
+	}
+	for i := range guilds {
+		guilds[i].Budgets = s.limits(guilds[i].ID)
+		if u, ok := usage[guilds[i].ID.String()]; ok {
+			guilds[i].Usage = &u
+		}
+	}
+	slices.SortFunc(guilds, func(a, b Guild) int {
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	writeJSON(w, http.StatusOK, GuildsResponse{Guilds: guilds})
+}
+
+// setBudget replaces the budgets of a guild with the limits in the body. A
+// zero body clears the limits set before.
+func (s *Server) setBudget(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := guildParam(w, r)
+	if !ok {
+		return
+	}
+	var limits config.GuildBudgetsConfig
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&limits); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid budget: "+err.Error())
+
+		return
+	}
+	if negative(limits.User) || negative(limits.Guild) {
+		writeError(w, http.StatusBadRequest, "budget limits cannot be negative")
+
+		return
+	}
+
+	s.applyBudget(w, guildID, limits)
+}
+
+// clearBudget drops the limits set for a guild, so its configured budgets
+// apply again.
+func (s *Server) clearBudget(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := guildParam(w, r)
+	if !ok {
+		return
+	}
+
+	s.applyBudget(w, guildID, config.GuildBudgetsConfig{})
+}
+
+func (s *Server) applyBudget(w http.ResponseWriter, guildID discord.GuildID, limits config.GuildBudgetsConfig) {
+	if _, err := s.budgets.SetGuildLimits(guildID, limits); err != nil {
+		if errors.Is(err, chat.ErrBudgetsDisabled) {
+			writeError(w, http.StatusConflict, err.Error())
+
+			return
+		}
+		s.logger.Error("Failed to set guild budget", zap.Error(err), zap.String("guildID", guildID.String()))
+		writeError(w, http.StatusInternalServerError, "failed to save budget")
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, BudgetResponse{GuildID: guildID, Budgets: s.limits(guildID)})
+}
+
+// exportUsage returns the usage of every guild and user in the current UTC
+// day and month.
+func (s *Server) exportUsage(w http.ResponseWriter, _ *http.Request) {
+	if s.budgets == nil {
+		writeError(w, http.StatusConflict, chat.ErrBudgetsDisabled.Error())
+
+		return
+	}
+
+	usage := s.budgets.Usage()
+	if usage == nil {
+		usage = []chat.BudgetUsage{}
+	}
+	writeJSON(w, http.StatusOK, UsageResponse{Usage: usage})
+}
+
+// flushCaches empties the caches named by the cache query parameters, or
+// every cache without any. Cached conversations are archived first when the
+// archive is enabled, and rebuilt from Discord otherwise.
+func (s *Server) flushCaches(w http.ResponseWriter, r *http.Request) {
+	caches := r.URL.Query()["cache"]
+	for _, name := range caches {
+		if !slices.Contains(Caches, name) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown cache %q, expected one of %s", name, strings.Join(Caches, ", ")))
+
+			return
+		}
+	}
+	if len(caches) == 0 {
+		caches = Caches
+	}
+
+	flushed := make(map[string]int, len(caches))
+	for _, name := range Caches {
+		if !slices.Contains(caches, name) {
+			continue
+		}
+		switch name {
+		case CacheConversations:
+			n, err := s.archiver.FlushConversations(r.Context())
+			flushed[name] = n
+			if err != nil {
+				s.logger.Error("Failed to flush conversations", zap.Error(err), zap.Int("flushed", n))
+				writeError(w, http.StatusInternalServerError, "failed to flush conversations: "+err.Error())
+
+				return
+			}
+		case CacheIgnoredThreads:
+			flushed[name] = s.store.FlushIgnoredThreads()
+		}
+	}
+	s.logger.Info("Flushed caches", zap.Any("flushed", flushed))
+
+	writeJSON(w, http.StatusOK, FlushResponse{Flushed: flushed})
+}
+
+// limits returns the budgets in effect in guildID.
+func (s *Server) limits(guildID discord.GuildID) config.GuildBudgetsConfig {
+	user, guild := s.budgets.Limits(guildID)
+
+	return config.GuildBudgetsConfig{User: user, Guild: guild}
+}
+
+func guildParam(w http.ResponseWriter, r *http.Request) (discord.GuildID, bool) {
+	sf, err := discord.ParseSnowflake(r.PathValue("id"))
+	if err != nil || !sf.IsValid() {
+		writeError(w, http.StatusBadRequest, "invalid guild ID")
+
+		return 0, false
+	}
+
+	return discord.GuildID(sf), true
+}
+
+func negative(l config.BudgetLimits) bool {
+	return l.DailyTokens < 0 || l.MonthlyTokens < 0 || l.DailyUSD < 0 || l.MonthlyUSD < 0
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
@@ -0,0 +1,119 @@
+package adminapi_test
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/adminapi"
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func TestNewServer_Disabled(t *testing.T) {
+	s, err := adminapi.NewServer(zap.NewNop(), &config.Config{}, nil, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, s)
+
+	cfg := &config.Config{AdminAPI: config.AdminAPIConfig{Enabled: true}}
+	_, err = adminapi.NewServer(zap.NewNop(), cfg, nil, nil, nil, nil)
+	assert.Error(t, err, "the API is never served without a token")
+}
+
+func TestServer(t *testing.T) {
+	cfg := &config.Config{
+		AdminAPI: config.AdminAPIConfig{Enabled: true, Token: "secret"},
+		Budgets: config.BudgetsConfig{
+			File:       filepath.Join(t.TempDir(), "budgets.json"),
+			LimitsFile: filepath.Join(t.TempDir(), "budget_limits.json"),
+			Guild:      config.BudgetLimits{DailyUSD: 5, MonthlyUSD: 50},
+		},
+	}
+	ses := session.New("Bot test")
+	budgets, err := chat.NewBudgets(zap.NewNop(), config.NewStaticProvider(cfg), storage.NewFileProvider(""))
+	require.NoError(t, err)
+	store := chat.NewConversationStore(zap.NewNop(), 10, 10, chat.NewSummaryParser(zap.NewNop()), chat.NewContentNormalizer(ses), nil)
+	archiver := chat.NewConversationArchiver(zap.NewNop(), cfg, ses, store, nil, nil)
+	s, err := adminapi.NewServer(zap.NewNop(), cfg, ses, store, archiver, budgets)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	client := adminapi.NewClient(srv.URL, "secret", srv.Client())
+	ctx := t.Context()
+
+	_, err = adminapi.NewClient(srv.URL, "wrong", srv.Client()).Guilds(ctx)
+	require.EqualError(t, err, "missing or invalid token")
+
+	ses.Call(&gateway.GuildCreateEvent{Guild: discord.Guild{ID: 1, Name: "Beta"}, MemberCount: 12})
+	ses.Call(&gateway.GuildCreateEvent{Guild: discord.Guild{ID: 2, Name: "alpha"}})
+	require.NoError(t, budgets.Record(1, 10, 100, 0.5))
+
+	var guilds []adminapi.Guild
+	require.Eventually(t, func() bool {
+		guilds, err = client.Guilds(ctx)
+
+		return err == nil && len(guilds) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "alpha", guilds[0].Name, "guilds are listed by name")
+	assert.Equal(t, uint64(12), guilds[1].Members)
+	assert.Equal(t, 5.0, guilds[1].Budgets.Guild.DailyUSD)
+	require.NotNil(t, guilds[1].Usage)
+	assert.Equal(t, 100, guilds[1].Usage.DayTokens)
+	assert.Nil(t, guilds[0].Usage)
+
+	budget, err := client.SetBudget(ctx, 1, config.GuildBudgetsConfig{Guild: config.BudgetLimits{DailyUSD: 20}, User: config.BudgetLimits{DailyTokens: 1000}})
+	require.NoError(t, err)
+	assert.Equal(t, config.BudgetLimits{DailyUSD: 20, MonthlyUSD: 50}, budget.Guild, "set limits replace the configured ones")
+	assert.Equal(t, config.BudgetLimits{DailyTokens: 1000}, budget.User)
+	_, guildLimits := budgets.Limits(1)
+	assert.Equal(t, 20.0, guildLimits.DailyUSD, "requests use the limits set")
+	_, err = client.SetBudget(ctx, 1, config.GuildBudgetsConfig{Guild: config.BudgetLimits{DailyTokens: -1}})
+	require.Error(t, err)
+	budget, err = client.ClearBudget(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, config.BudgetLimits{DailyUSD: 5, MonthlyUSD: 50}, budget.Guild)
+
+	usage, err := client.Usage(ctx)
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, []string{"guild", "user"}, []string{usage[0].Scope, usage[1].Scope})
+	assert.Equal(t, "10", usage[1].ID)
+
+	store.StoreInitialConversation("100", "hi", "hello", "gpt-4o", "user", "bot", chat.ThreadAccess{}, "", nil, chat.SanitizeOpenAIName)
+	store.AddToNegativeCache("200")
+	_, err = client.FlushCaches(ctx, "nope")
+	require.Error(t, err)
+	flushed, err := client.FlushCaches(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{adminapi.CacheConversations: 1, adminapi.CacheIgnoredThreads: 1}, flushed)
+	_, cached := store.GetConversation("100")
+	assert.False(t, cached)
+	assert.False(t, store.IsInNegativeCache("200"))
+}
+
+func TestServer_BudgetsDisabled(t *testing.T) {
+	cfg := &config.Config{AdminAPI: config.AdminAPIConfig{Enabled: true, Token: "secret"}}
+	s, err := adminapi.NewServer(zap.NewNop(), cfg, session.New("Bot test"), nil, nil, nil)
+	require.NoError(t, err)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	client := adminapi.NewClient(srv.URL, "secret", srv.Client())
+
+	_, err = client.Usage(t.Context())
+	require.ErrorContains(t, err, "budgets are not enabled")
+	_, err = client.SetBudget(t.Context(), 1, config.GuildBudgetsConfig{Guild: config.BudgetLimits{DailyUSD: 1}})
+	require.ErrorContains(t, err, "budgets are not enabled")
+	guilds, err := client.Guilds(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, guilds)
+}
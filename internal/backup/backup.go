@@ -0,0 +1,338 @@
+// Package backup snapshots the bot's persistent data to a portable archive
+// and restores it, so a bot can move between hosts or storage backends.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const (
+	// FormatVersion is the layout of the archives Create writes. Restore
+	// accepts archives up to this version.
+	FormatVersion = 1
+
+	manifestName = "manifest.json"
+	// Sections of an archive.
+	sectionState   = "state"
+	sectionArchive = "archive"
+	// maxEntrySize bounds one file read from an archive, so a corrupt or
+	// hostile archive cannot exhaust memory.
+	maxEntrySize = 256 << 20
+)
+
+// Manifest describes an archive. It is its first file.
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	BotVersion    string    `json:"bot_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Entries       []Entry   `json:"entries"`
+}
+
+// Entry is one file of an archive.
+type Entry struct {
+	Section string `json:"section"` // "state" or "archive"
+	// Name is the document name for state, such as "budgets", which is saved
+	// under the key the restoring host configures; or the object key for the
+	// conversation archive.
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Count returns the number of entries in section.
+func (m Manifest) Count(section string) int {
+	n := 0
+	for _, e := range m.Entries {
+		if e.Section == section {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Summary describes the manifest in one line, such as "9 state documents
+// and 340 archived conversations from v1.4.0, created 2025-06-01T12:00:00Z".
+func (m Manifest) Summary() string {
+	return fmt.Sprintf("%d state documents and %d archived conversations from %s, created %s",
+		m.Count(sectionState), m.Count(sectionArchive), m.BotVersion, m.CreatedAt.Format(time.RFC3339))
+}
+
+// Module provides the backup Service.
+var Module = fx.Module("backup",
+	fx.Provide(NewService),
+)
+
+// Service backs up and restores the state documents of every store, and the
+// conversation archive when it is enabled. In-memory conversations are not
+// included; they are rebuilt from their Discord threads.
+type Service struct {
+	cfg     *config.Config
+	state   storage.Provider
+	archive storage.ColdStore
+}
+
+// NewService creates a Service over the configured state backend and archive,
+// which may be nil.
+func NewService(cfg *config.Config, state storage.Provider, archive storage.ColdStore) *Service {
+	return &Service{cfg: cfg, state: state, archive: archive}
+}
+
+// document is a state document and the key this host keeps it under.
+type document struct {
+	name string
+	key  string
+}
+
+// documents returns every state document with its configured key.
+func (s *Service) documents() []document {
+	key := func(configured, fallback string) string {
+		if configured != "" {
+			return configured
+		}
+
+		return fallback
+	}
+	cfg := s.cfg
+
+	return []document{
+		{"budgets", key(cfg.Budgets.File, "chat_budgets.json")},
+		{"budget_limits", key(cfg.Budgets.LimitsFile, "chat_budget_limits.json")},
+		{"outbox", key(cfg.Outbox.File, "outbox.json")},
+		{"dead_letters", key(cfg.DeadLetters.File, "dead_letters.json")},
+		{"prompts", key(cfg.Prompts.File, "prompts.json")},
+		{"characters", key(cfg.Characters.File, "characters.json")},
+		{"ignored_users", key(cfg.Moderation.IgnoreFile, "ignored_users.json")},
+		{"muted_threads", key(cfg.Moderation.MutedThreadsFile, "muted_threads.json")},
+		{"voice_schedules", key(cfg.Voice.ScheduleFile, "voice_schedules.json")},
+		{"voice_accessibility", key(cfg.Voice.AccessibilityFile, "voice_accessibility.json")},
+		{"schema_version", storage.SchemaKey},
+	}
+}
+
+// Create writes a gzipped tar archive of the persistent data to w, with a
+// manifest first listing every file and its checksum. Documents that were
+// never saved are left out.
+func (s *Service) Create(ctx context.Context, w io.Writer, botVersion string) (Manifest, error) {
+	manifest := Manifest{FormatVersion: FormatVersion, BotVersion: botVersion, CreatedAt: time.Now().UTC()}
+	var files [][]byte
+
+	add := func(section, name string, data []byte) {
+		sum := sha256.Sum256(data)
+		manifest.Entries = append(manifest.Entries, Entry{Section: section, Name: name, Size: len(data), SHA256: hex.EncodeToString(sum[:])})
+		files = append(files, data)
+	}
+	for _, doc := range s.documents() {
+		data, err := s.state.Get(ctx, doc.key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to read %s: %w", doc.name, err)
+		}
+		add(sectionState, doc.name, data)
+	}
+	if s.archive != nil {
+		keys, err := s.archive.List(ctx, "")
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to list archived conversations: %w", err)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			data, err := s.archive.Get(ctx, key)
+			if err != nil {
+				return Manifest{}, fmt.Errorf("failed to read archived %s: %w", key, err)
+			}
+			add(sectionArchive, key, data)
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	header, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(tw, manifestName, header, manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+	for i, e := range manifest.Entries {
+		if err := writeFile(tw, path.Join(e.Section, e.Name), files[i], manifest.CreatedAt); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg})
+	if err == nil {
+		_, err = tw.Write(data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s to backup: %w", name, err)
+	}
+
+	return nil
+}
+
+// Read reads and validates an archive without restoring it: the format
+// version must be supported, and every file the manifest lists must be
+// present, unchanged and expected.
+func Read(r io.Reader) (Manifest, map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest Manifest
+	files := make(map[string][]byte)
+	for first := true; ; first = false {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Manifest{}, nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxEntrySize+1))
+		if err != nil {
+			return Manifest{}, nil, fmt.Errorf("failed to read %s from backup: %w", header.Name, err)
+		}
+		if len(data) > maxEntrySize {
+			return Manifest{}, nil, fmt.Errorf("%s in backup is larger than %d bytes", header.Name, maxEntrySize)
+		}
+
+		if first {
+			if header.Name != manifestName {
+				return Manifest{}, nil, errors.New("backup does not start with a manifest")
+			}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return Manifest{}, nil, fmt.Errorf("failed to parse backup manifest: %w", err)
+			}
+			if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+				return Manifest{}, nil, fmt.Errorf("backup format version %d is not supported by this version of the bot, which reads up to %d",
+					manifest.FormatVersion, FormatVersion)
+			}
+
+			continue
+		}
+		files[header.Name] = data
+	}
+	if manifest.FormatVersion == 0 {
+		return Manifest{}, nil, errors.New("backup is empty")
+	}
+
+	byEntry := make(map[string][]byte, len(manifest.Entries))
+	for _, e := range manifest.Entries {
+		name := path.Join(e.Section, e.Name)
+		data, ok := files[name]
+		if !ok {
+			return Manifest{}, nil, fmt.Errorf("backup is missing %s", name)
+		}
+		if sum := sha256.Sum256(data); len(data) != e.Size || hex.EncodeToString(sum[:]) != e.SHA256 {
+			return Manifest{}, nil, fmt.Errorf("%s in backup does not match its checksum", name)
+		}
+		if e.Section != sectionState && e.Section != sectionArchive {
+			return Manifest{}, nil, fmt.Errorf("unknown backup section %q", e.Section)
+		}
+		if e.Section == sectionState && !json.Valid(data) {
+			return Manifest{}, nil, fmt.Errorf("%s in backup is not JSON", name)
+		}
+		byEntry[name] = data
+		delete(files, name)
+	}
+	for name := range files {
+		return Manifest{}, nil, fmt.Errorf("backup holds %s, which its manifest does not list", name)
+	}
+
+	return manifest, byEntry, nil
+}
+
+// Restore validates the archive read from r and then writes its documents
+// under the keys this host configures, and its archived conversations to
+// the archive. Nothing is written unless the whole archive is valid. The bot
+// must not be running, or its stores would overwrite the restored documents.
+// The restored documents keep the schema version they were backed up at, and
+// are migrated when the bot next starts.
+func (s *Service) Restore(ctx context.Context, r io.Reader) (Manifest, error) {
+	manifest, files, err := Read(r)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	keys := make(map[string]string)
+	for _, doc := range s.documents() {
+		keys[doc.name] = doc.key
+	}
+	versioned := false
+	for _, e := range manifest.Entries {
+		if e.Section == sectionState && e.Name == "schema_version" {
+			schema, err := storage.ParseSchemaState(files[path.Join(e.Section, e.Name)])
+			if err != nil {
+				return Manifest{}, err
+			}
+			if latest := storage.LatestSchemaVersion(); schema.Version > latest {
+				return Manifest{}, fmt.Errorf("backup is at state schema version %d, newer than the %d this version of the bot reads; restore it with the release that made it",
+					schema.Version, latest)
+			}
+			versioned = true
+		}
+		switch {
+		case e.Section == sectionState && keys[e.Name] == "":
+			return Manifest{}, fmt.Errorf("backup holds unknown state document %q", e.Name)
+		case e.Section == sectionArchive && s.archive == nil:
+			return Manifest{}, fmt.Errorf("backup holds %d archived conversations but the archive is disabled; enable archive in config first", manifest.Count(sectionArchive))
+		case e.Section == sectionArchive && (strings.HasPrefix(e.Name, "/") || slices.Contains(strings.Split(e.Name, "/"), "..")):
+			return Manifest{}, fmt.Errorf("backup holds invalid archive key %q", e.Name)
+		}
+	}
+
+	for _, e := range manifest.Entries {
+		data := files[path.Join(e.Section, e.Name)]
+		if e.Section == sectionState {
+			err = s.state.Put(ctx, keys[e.Name], data)
+		} else {
+			err = s.archive.Put(ctx, e.Name, data)
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to restore %s: %w", path.Join(e.Section, e.Name), err)
+		}
+	}
+	if !versioned {
+		// Backups made before schema versions are migrated from the start
+		if err := s.state.Delete(ctx, storage.SchemaKey); err != nil {
+			return Manifest{}, fmt.Errorf("failed to reset the state schema version: %w", err)
+		}
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,132 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/backup"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := t.Context()
+	state := storage.NewMemoryProvider()
+	archive := storage.NewMemoryProvider()
+	require.NoError(t, state.Put(ctx, "chat_budgets.json", []byte(`{"users":{}}`)))
+	require.NoError(t, state.Put(ctx, "prompts.json", []byte(`{"1":"Be brief."}`)))
+	require.NoError(t, archive.Put(ctx, "conversations/1.json", []byte("sealed")))
+
+	var buf bytes.Buffer
+	manifest, err := backup.NewService(&config.Config{}, state, archive).Create(ctx, &buf, "v1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, backup.FormatVersion, manifest.FormatVersion)
+	assert.Equal(t, "v1.2.3", manifest.BotVersion)
+	assert.Len(t, manifest.Entries, 3, "documents never saved are left out")
+
+	// The new host keeps its documents under other keys
+	cfg := &config.Config{}
+	cfg.Budgets.File = "/var/lib/bot/budgets.json"
+	newState := storage.NewMemoryProvider()
+	newArchive := storage.NewMemoryProvider()
+	require.NoError(t, newState.Put(ctx, storage.SchemaKey, []byte(`{"version":3}`)))
+	restored, err := backup.NewService(cfg, newState, newArchive).Restore(ctx, bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Summary(), restored.Summary())
+
+	data, err := newState.Get(ctx, "/var/lib/bot/budgets.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"users":{}}`, string(data))
+	data, err = newState.Get(ctx, "prompts.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"1":"Be brief."}`, string(data))
+	data, err = newArchive.Get(ctx, "conversations/1.json")
+	require.NoError(t, err)
+	assert.Equal(t, "sealed", string(data), "archived conversations are copied as they are")
+	_, err = newState.Get(ctx, storage.SchemaKey)
+	assert.ErrorIs(t, err, storage.ErrNotFound, "state backed up without a schema version is migrated from the start")
+}
+
+// archiveOf builds a backup archive from a manifest and files by path.
+func archiveOf(t *testing.T, manifest backup.Manifest, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte) {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+	header, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	write("manifest.json", header)
+	for name, data := range files {
+		write(name, []byte(data))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func TestRestore_Validation(t *testing.T) {
+	ctx := t.Context()
+	state := storage.NewMemoryProvider()
+	require.NoError(t, state.Put(ctx, "outbox.json", []byte(`[]`)))
+	archive := storage.NewMemoryProvider()
+	require.NoError(t, archive.Put(ctx, "conversations/1.json", []byte("sealed")))
+	var buf bytes.Buffer
+	valid, err := backup.NewService(&config.Config{}, state, archive).Create(ctx, &buf, "dev")
+	require.NoError(t, err)
+	validFiles := map[string]string{"state/outbox": "[]", "archive/conversations/1.json": "sealed"}
+	newer := storage.NewMemoryProvider()
+	require.NoError(t, newer.Put(ctx, storage.SchemaKey, []byte(`{"version":99}`)))
+	var newerSchema bytes.Buffer
+	_, err = backup.NewService(&config.Config{}, newer, nil).Create(ctx, &newerSchema, "v99")
+	require.NoError(t, err)
+	rename := func(name string) backup.Manifest {
+		m := valid
+		m.Entries = []backup.Entry{valid.Entries[0]}
+		m.Entries[0].Name = name
+
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		archive  bool
+		contains string
+	}{
+		{"not an archive", []byte("hello"), true, "not a backup archive"},
+		{"newer format", archiveOf(t, backup.Manifest{FormatVersion: backup.FormatVersion + 1}, nil), true, "format version 2 is not supported"},
+		{"tampered", archiveOf(t, valid, map[string]string{"state/outbox": "[1]", "archive/conversations/1.json": "sealed"}), true, "does not match its checksum"},
+		{"missing file", archiveOf(t, valid, map[string]string{"state/outbox": "[]"}), true, "backup is missing archive/conversations/1.json"},
+		{"unlisted file", archiveOf(t, valid, map[string]string{"state/outbox": "[]", "archive/conversations/1.json": "sealed", "state/extra": "{}"}), true, "which its manifest does not list"},
+		{"archive disabled", archiveOf(t, valid, validFiles), false, "the archive is disabled"},
+		{"unknown document", archiveOf(t, rename("settings"), map[string]string{"state/settings": "[]"}), true, `unknown state document "settings"`},
+		{"newer schema", newerSchema.Bytes(), true, "state schema version 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := storage.NewMemoryProvider()
+			var destArchive storage.ColdStore
+			if tt.archive {
+				destArchive = storage.NewMemoryProvider()
+			}
+			_, err := backup.NewService(&config.Config{}, dest, destArchive).Restore(ctx, bytes.NewReader(tt.data))
+			assert.ErrorContains(t, err, tt.contains)
+
+			keys, err := dest.List(ctx, "")
+			require.NoError(t, err)
+			assert.Empty(t, keys, "nothing is restored from an invalid backup")
+		})
+	}
+}
@@ -9,6 +9,10 @@ import (
 	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
 	"github.com/Raikerian/go-discord-chatgpt/internal/commands"
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internaldiscord "github.com/Raikerian/go-discord-chatgpt/internal/discord"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+	"github.com/Raikerian/go-discord-chatgpt/internal/retention"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
@@ -20,22 +24,38 @@ import (
 
 // Bot represents the Discord bot.
 type Bot struct {
-	Session     *session.Session
-	Config      *config.Config
-	CmdManager  *commands.CommandManager
-	Logger      *zap.Logger
-	ChatService *chat.Service
+	Session      *session.Session
+	Config       *config.Config
+	CmdManager   *commands.CommandManager
+	Logger       *zap.Logger
+	ChatService  *chat.Service
+	CacheWarmer  *chat.CacheWarmer
+	Archiver     *chat.ConversationArchiver
+	Retention    *retention.Job
+	Deprecations *chat.ModelDeprecations
+	SelfTest     *SelfTest
+	Ignored      moderation.IgnoreList
+	Loops        moderation.LoopDetector
+	Intents      gateway.Intents
 }
 
 // NewBotParameters holds dependencies for NewBot.
 type NewBotParameters struct {
 	fx.In // Required by Fx
 
-	Cfg        *config.Config
-	S          *session.Session
-	Logger     *zap.Logger
-	CmdManager *commands.CommandManager
-	ChatSvc    *chat.Service
+	Cfg          *config.Config
+	S            *session.Session
+	Logger       *zap.Logger
+	CmdManager   *commands.CommandManager
+	ChatSvc      *chat.Service
+	Warmer       *chat.CacheWarmer          `optional:"true"`
+	Archiver     *chat.ConversationArchiver `optional:"true"`
+	Retention    *retention.Job             `optional:"true"`
+	Deprecations *chat.ModelDeprecations    `optional:"true"`
+	SelfTest     *SelfTest                  `optional:"true"`
+	Ignored      moderation.IgnoreList
+	Loops        moderation.LoopDetector
+	Intents      gateway.Intents
 }
 
 // NewBot creates and initializes a new Bot.
@@ -61,11 +81,19 @@ func NewBot(params NewBotParameters) (*Bot, error) {
 	}
 
 	b := &Bot{
-		Session:     params.S,
-		Config:      params.Cfg,
-		Logger:      params.Logger,
-		CmdManager:  params.CmdManager,
-		ChatService: params.ChatSvc, // Initialize ChatService
+		Session:      params.S,
+		Config:       params.Cfg,
+		Logger:       params.Logger,
+		CmdManager:   params.CmdManager,
+		ChatService:  params.ChatSvc, // Initialize ChatService
+		CacheWarmer:  params.Warmer,
+		Archiver:     params.Archiver,
+		Retention:    params.Retention,
+		Deprecations: params.Deprecations,
+		SelfTest:     params.SelfTest,
+		Ignored:      params.Ignored,
+		Loops:        params.Loops,
+		Intents:      params.Intents,
 	}
 
 	params.Logger.Info("NewBot created successfully. Handler registration will occur in Start.")
@@ -79,6 +107,11 @@ func NewBot(params NewBotParameters) (*Bot, error) {
 func (b *Bot) Start(ctx context.Context) error {
 	b.Logger.Info("Bot.Start called.")
 
+	// Subsystems are checked before any handler can take traffic
+	if err := b.SelfTest.Run(ctx); err != nil {
+		return err
+	}
+
 	// Determine interaction timeout
 	// Default to 30 seconds if not specified or invalid in config
 	interactionTimeout := 30 * time.Second
@@ -97,11 +130,19 @@ func (b *Bot) Start(ctx context.Context) error {
 		// interactionCtx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
 		// defer cancel()
 
+		if b.rejectIgnored(e) {
+			b.CmdManager.RecordDropped(e.Data, metrics.DropPermission)
+
+			return
+		}
 		handleInteraction(context.Background(), b.Session, e, b.Logger, b.CmdManager)
 	})
 
 	// Add MessageCreateEvent handler
 	b.Session.AddHandler(func(e *gateway.MessageCreateEvent) {
+		// Bots and webhooks are watched for loops before they are filtered out
+		b.watchForLoops(e)
+
 		// Filter out messages from bots
 		if e.Author.Bot {
 			return
@@ -146,6 +187,26 @@ func (b *Bot) Start(ctx context.Context) error {
 	// Register slash commands on startup
 	b.CmdManager.RegisterCommands(guildIDs)
 
+	// Warn about missing intents and permissions before users hit them
+	features := []internaldiscord.Feature{internaldiscord.ChatFeature, internaldiscord.CharacterFeature, internaldiscord.VoiceFeature}
+	internaldiscord.CheckIntents(b.Logger, b.Intents, features...)
+	internaldiscord.CheckPermissions(b.Session, b.Logger, guildIDs, features...)
+
+	b.Deprecations.Announce()
+	if b.ChatService != nil {
+		// The start context ends once startup is done, so redelivery gets its own
+		go b.ChatService.RedeliverPending(context.Background())
+	}
+	if b.CacheWarmer != nil {
+		b.CacheWarmer.Start()
+	}
+	if b.Archiver != nil {
+		b.Archiver.Start()
+	}
+	if b.Retention != nil {
+		b.Retention.Start()
+	}
+
 	b.Logger.Info("Bot started, event handler and commands registered.")
 
 	return nil
@@ -155,6 +216,16 @@ func (b *Bot) Start(ctx context.Context) error {
 func (b *Bot) Stop(ctx context.Context) error {
 	b.Logger.Info("Stopping bot...")
 
+	if b.CacheWarmer != nil {
+		b.CacheWarmer.Stop()
+	}
+	if b.Archiver != nil {
+		b.Archiver.Stop()
+	}
+	if b.Retention != nil {
+		b.Retention.Stop()
+	}
+
 	// Unregister slash commands on shutdown
 	var guildIDs []discord.GuildID
 	if b.Config != nil && len(b.Config.Discord.GuildIDs) > 0 {
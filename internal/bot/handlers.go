@@ -2,8 +2,12 @@ package bot
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
 	"github.com/Raikerian/go-discord-chatgpt/internal/commands"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
 
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -24,7 +28,12 @@ func (b *Bot) handleMessageCreate(ctx context.Context, s *session.Session, e *ga
 
 		return
 	}
-	if e.Author.ID == selfUser.ID {
+	if e.Author.ID == selfUser.ID || chat.IsPersonaMessage(&e.Message, selfUser) {
+		return
+	}
+	// Paused and muted channels and ignored users get no replies, without a
+	// notice
+	if b.ChatService != nil && b.ChatService.Silenced(e.GuildID, e.ChannelID, e.Author.ID) {
 		return
 	}
 
@@ -36,8 +45,28 @@ func (b *Bot) handleMessageCreate(ctx context.Context, s *session.Session, e *ga
 		return
 	}
 
+	// Audio is transcribed wherever voice notes are allowed, threads or not
+	if len(e.Attachments) > 0 && b.ChatService != nil {
+		handled, err := b.ChatService.HandleVoiceNote(ctx, e, ch)
+		if err != nil {
+			b.Logger.Error("Error handling voice note", zap.Error(err), zap.String("channelID", e.ChannelID.String()))
+		}
+		if handled {
+			return
+		}
+	}
+
 	// Use discord.GuildAnnouncementThread as discord.GuildNewsThread is deprecated.
 	isThread := ch.Type == discord.GuildPublicThread || ch.Type == discord.GuildPrivateThread || ch.Type == discord.GuildAnnouncementThread
+	if !isThread && b.ChatService != nil {
+		handled, err := b.ChatService.HandleMentionMessage(ctx, e, ch)
+		if err != nil {
+			b.Logger.Error("Error handling mention message", zap.Error(err), zap.String("channelID", e.ChannelID.String()))
+		}
+		if handled {
+			return
+		}
+	}
 	if !isThread {
 		b.Logger.Debug("Message is not in a thread, ignoring", zap.String("messageID", e.ID.String()))
 
@@ -65,11 +94,102 @@ func (b *Bot) handleMessageCreate(ctx context.Context, s *session.Session, e *ga
 	}
 }
 
+// rejectIgnored answers interactions from ignored users with an ephemeral
+// notice, reporting whether the interaction was rejected.
+func (b *Bot) rejectIgnored(e *gateway.InteractionCreateEvent) bool {
+	if b.Ignored == nil || !b.Ignored.Ignored(e.GuildID, e.SenderID()) {
+		return false
+	}
+	b.Logger.Info("Rejecting interaction from ignored user", zap.String("userID", e.SenderID().String()), zap.String("guildID", e.GuildID.String()))
+
+	err := b.Session.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content: option.NewNullableString("You cannot use this bot here."),
+			Flags:   discord.EphemeralMessage,
+		},
+	})
+	if err != nil {
+		b.Logger.Error("Failed to reject interaction from ignored user", zap.Error(err))
+	}
+
+	return true
+}
+
+// watchForLoops records a guild message with the loop detector and alerts
+// the guild's moderators when it pauses the message's channel.
+func (b *Bot) watchForLoops(e *gateway.MessageCreateEvent) {
+	if b.Loops == nil || !e.GuildID.IsValid() {
+		return
+	}
+	selfUser, err := b.Session.Me()
+	if err != nil {
+		b.Logger.Error("Failed to get self user information", zap.Error(err))
+
+		return
+	}
+
+	fromSelf := e.Author.ID == selfUser.ID || chat.IsPersonaMessage(&e.Message, selfUser)
+	reason := b.Loops.Observe(&e.Message, fromSelf)
+	if reason == "" {
+		return
+	}
+	b.Logger.Warn("Paused replies in channel caught in a loop",
+		zap.String("guildID", e.GuildID.String()),
+		zap.String("channelID", e.ChannelID.String()),
+		zap.String("reason", reason))
+
+	b.alertLoop(e, reason)
+}
+
+// alertLoop tells moderators that replies in the channel of e are paused, in
+// the guild's alert channel or, without one, the paused channel itself.
+func (b *Bot) alertLoop(e *gateway.MessageCreateEvent, reason string) {
+	guild := b.Config.Guild(e.GuildID.String()).Moderation
+
+	alertChannelID := e.ChannelID
+	if guild.AlertChannelID != "" {
+		sf, err := discord.ParseSnowflake(guild.AlertChannelID)
+		if err != nil {
+			b.Logger.Warn("Invalid moderation alert channel", zap.Error(err), zap.String("guildID", e.GuildID.String()))
+		} else {
+			alertChannelID = discord.ChannelID(sf)
+		}
+	}
+
+	var mentions []string
+	var roleIDs []discord.RoleID
+	for _, id := range guild.AlertRoleIDs {
+		sf, err := discord.ParseSnowflake(id)
+		if err != nil {
+			b.Logger.Warn("Invalid moderation alert role", zap.Error(err), zap.String("roleID", id))
+
+			continue
+		}
+		roleIDs = append(roleIDs, discord.RoleID(sf))
+		mentions = append(mentions, discord.RoleID(sf).Mention())
+	}
+
+	content := fmt.Sprintf("%sI paused my replies in %s because %s, which looks like a loop with another bot or relay. "+
+		"They resume on their own later, or a moderator can run `/admin loop resume` there.", moderation.LoopAlertPrefix, e.ChannelID.Mention(), reason)
+	if len(mentions) > 0 {
+		content += "\n" + strings.Join(mentions, " ")
+	}
+
+	_, err := b.Session.SendMessageComplex(alertChannelID, api.SendMessageData{
+		Content:         content,
+		AllowedMentions: &api.AllowedMentions{Roles: roleIDs},
+	})
+	if err != nil {
+		b.Logger.Error("Failed to send loop alert", zap.Error(err), zap.String("channelID", alertChannelID.String()))
+	}
+}
+
 func handleInteraction(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, logger *zap.Logger, cmdManager *commands.CommandManager) {
 	// Check if it's a slash command
 	switch data := e.Data.(type) {
 	case *discord.CommandInteraction:
-		logger.Info("Received slash command", zap.String("commandName", data.Name), zap.String("user", e.Member.User.Username))
+		logger.Info("Received slash command", zap.String("commandName", data.Name), zap.String("user", e.Sender().Username))
 
 		// Get the command handler
 		cmd, ok := cmdManager.GetCommand(data.Name)
@@ -107,6 +227,20 @@ func handleInteraction(ctx context.Context, s *session.Session, e *gateway.Inter
 			logger.Info("Command executed successfully", zap.String("commandName", data.Name))
 		}
 
+	case discord.ComponentInteraction:
+		logger.Info("Received component interaction", zap.String("customID", string(data.ID())))
+
+		handler, ok := cmdManager.GetComponentHandler(data.ID())
+		if !ok {
+			logger.Warn("No handler for component", zap.String("customID", string(data.ID())))
+
+			return
+		}
+
+		if err := handler.HandleComponent(ctx, s, e, data); err != nil {
+			logger.Error("Error handling component interaction", zap.String("customID", string(data.ID())), zap.Error(err))
+		}
+
 	default:
 		logger.Debug("Received unhandled interaction type", zap.Any("type", e.Data))
 	}
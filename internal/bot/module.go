@@ -7,5 +7,5 @@ import (
 
 // Module provides bot service dependencies.
 var Module = fx.Module("bot",
-	fx.Provide(NewBot),
+	fx.Provide(NewBot, NewSelfTest),
 )
@@ -0,0 +1,310 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internaldiscord "github.com/Raikerian/go-discord-chatgpt/internal/discord"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+const (
+	// selfTestTimeout bounds each check that goes over the network.
+	selfTestTimeout = 15 * time.Second
+	// selfTestProbeKey is the archive object written and removed again to
+	// check the backend.
+	selfTestProbeKey = "self-test/probe"
+)
+
+// CheckStatus is the outcome of one self-test check.
+type CheckStatus int
+
+const (
+	CheckPassed CheckStatus = iota
+	CheckFailed
+	CheckSkipped // Not configured
+)
+
+func (s CheckStatus) String() string {
+	switch s {
+	case CheckFailed:
+		return "failed"
+	case CheckSkipped:
+		return "skipped"
+	default:
+		return "passed"
+	}
+}
+
+// CheckResult is one row of the self-test matrix.
+type CheckResult struct {
+	Name   string
+	Status CheckStatus
+	Detail string
+}
+
+// SelfTest exercises each configured subsystem on startup, before the bot
+// answers anyone, and reports which ones work. A nil SelfTest, returned when
+// self_test is off, runs nothing.
+type SelfTest struct {
+	logger  *zap.Logger
+	cfg     *config.Config
+	ses     *session.Session
+	keys    internalopenai.KeyResolver
+	pricing pkgopenai.PricingService
+	archive storage.ColdStore
+}
+
+// NewSelfTest creates the SelfTest configured under self_test.
+func NewSelfTest(
+	logger *zap.Logger,
+	cfg *config.Config,
+	ses *session.Session,
+	keys internalopenai.KeyResolver,
+	pricing pkgopenai.PricingService,
+	archive storage.ColdStore,
+) *SelfTest {
+	if !cfg.SelfTest.Enabled {
+		return nil
+	}
+
+	return &SelfTest{
+		logger:  logger.Named("self_test"),
+		cfg:     cfg,
+		ses:     ses,
+		keys:    keys,
+		pricing: pricing,
+		archive: archive,
+	}
+}
+
+// Run runs every check, logs the results and posts them to the ops channel.
+// It returns an error when a check failed and self_test.required is set.
+func (t *SelfTest) Run(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	results := []CheckResult{t.checkOpenAI(ctx), t.checkPricing(), t.checkArchive(ctx)}
+	results = append(results, t.checkGuilds()...)
+
+	failed := 0
+	for _, r := range results {
+		fields := []zap.Field{zap.String("check", r.Name), zap.Stringer("status", r.Status), zap.String("detail", r.Detail)}
+		if r.Status == CheckFailed {
+			failed++
+			t.logger.Error("Self-test check failed", fields...)
+
+			continue
+		}
+		t.logger.Info("Self-test check", fields...)
+	}
+	t.logger.Info("Self-test finished", zap.Int("checks", len(results)), zap.Int("failed", failed))
+	t.announce(results, failed)
+
+	if failed > 0 && t.cfg.SelfTest.Required {
+		return fmt.Errorf("self-test: %d of %d checks failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// checkOpenAI asks the first configured model for a one-token completion
+// with the global credentials.
+func (t *SelfTest) checkOpenAI(ctx context.Context) CheckResult {
+	result := CheckResult{Name: "OpenAI chat"}
+	if len(t.cfg.OpenAI.Models) == 0 {
+		return failCheck(result, "no models in openai.models")
+	}
+	model := t.cfg.OpenAI.Models[0]
+
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := t.keys.Client(discord.NullGuildID).CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:               model,
+		Messages:            []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "ping"}},
+		MaxCompletionTokens: 1,
+	})
+	if err != nil {
+		return failCheck(result, fmt.Sprintf("%s: %v", model, err))
+	}
+	result.Detail = fmt.Sprintf("%s answered in %d ms", model, time.Since(start).Milliseconds())
+
+	return result
+}
+
+// checkPricing loads models.json and looks up the configured models in it.
+func (t *SelfTest) checkPricing() CheckResult {
+	result := CheckResult{Name: "Pricing"}
+	data := t.pricing.GetPricingData()
+	if len(data.Models) == 0 {
+		if data.Note != "" {
+			return failCheck(result, data.Note)
+		}
+
+		return failCheck(result, "models.json lists no models")
+	}
+
+	var unpriced []string
+	for _, model := range t.cfg.ChatModels() {
+		if _, ok := data.Models[model]; !ok {
+			unpriced = append(unpriced, model)
+		}
+	}
+	result.Detail = fmt.Sprintf("%d models loaded", len(data.Models))
+	if len(unpriced) > 0 {
+		// Replies still work, their cost just goes unreported
+		result.Detail += "; no prices for " + strings.Join(unpriced, ", ")
+	}
+
+	return result
+}
+
+// checkArchive writes, reads back and deletes a probe object in the archive.
+func (t *SelfTest) checkArchive(ctx context.Context) CheckResult {
+	result := CheckResult{Name: "Archive"}
+	if t.archive == nil {
+		result.Status = CheckSkipped
+		result.Detail = "archive is disabled"
+
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	probe := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	if err := t.archive.Put(ctx, selfTestProbeKey, probe); err != nil {
+		return failCheck(result, "write: "+err.Error())
+	}
+	read, err := t.archive.Get(ctx, selfTestProbeKey)
+	if err == nil && !bytes.Equal(read, probe) {
+		err = errors.New("read back different data")
+	}
+	if err != nil {
+		return failCheck(result, "read: "+err.Error())
+	}
+	if err := t.archive.Delete(ctx, selfTestProbeKey); err != nil {
+		return failCheck(result, "delete: "+err.Error())
+	}
+	result.Detail = fmt.Sprintf("%s backend is readable and writable", orDefault(t.cfg.Archive.Backend, "local"))
+
+	return result
+}
+
+// checkGuilds checks the bot's permissions for chat, characters and voice
+// in each guild in discord.guild_ids.
+func (t *SelfTest) checkGuilds() []CheckResult {
+	if len(t.cfg.Discord.GuildIDs) == 0 {
+		return []CheckResult{{Name: "Discord permissions", Status: CheckSkipped, Detail: "no guilds in discord.guild_ids"}}
+	}
+	me, err := t.ses.Me()
+	if err != nil {
+		return []CheckResult{failCheck(CheckResult{Name: "Discord permissions"}, "failed to get bot user: "+err.Error())}
+	}
+
+	features := []internaldiscord.Feature{internaldiscord.ChatFeature, internaldiscord.CharacterFeature, internaldiscord.VoiceFeature}
+	results := make([]CheckResult, 0, len(t.cfg.Discord.GuildIDs))
+	for _, idStr := range t.cfg.Discord.GuildIDs {
+		result := CheckResult{Name: "Guild " + idStr}
+		sf, err := discord.ParseSnowflake(idStr)
+		if err != nil {
+			results = append(results, failCheck(result, "invalid guild ID"))
+
+			continue
+		}
+		guildID := discord.GuildID(sf)
+		if guild, err := t.ses.Guild(guildID); err == nil {
+			result.Name = guild.Name
+		}
+
+		missing, err := internaldiscord.MissingPermissions(t.ses, guildID, me.ID, features...)
+		if err != nil {
+			results = append(results, failCheck(result, "failed to read permissions: "+err.Error()))
+
+			continue
+		}
+		var gaps []string
+		for _, f := range features {
+			if missing[f.Name] != 0 {
+				gaps = append(gaps, fmt.Sprintf("%s needs %s", f.Name, internaldiscord.PermissionNames(missing[f.Name])))
+			}
+		}
+		if len(gaps) > 0 {
+			results = append(results, failCheck(result, strings.Join(gaps, "; ")))
+
+			continue
+		}
+		result.Detail = "all permissions granted"
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// announce posts the results to the ops channel, if one is configured.
+func (t *SelfTest) announce(results []CheckResult, failed int) {
+	channelID := t.cfg.SelfTest.OpsChannelID
+	if channelID == "" {
+		return
+	}
+	sf, err := discord.ParseSnowflake(channelID)
+	if err != nil {
+		t.logger.Warn("Invalid self-test ops channel", zap.Error(err), zap.String("channelID", channelID))
+
+		return
+	}
+
+	title := fmt.Sprintf("✅ **Startup self-test passed** (%d checks)", len(results))
+	if failed > 0 {
+		title = fmt.Sprintf("❌ **Startup self-test: %d of %d checks failed**", failed, len(results))
+	}
+	lines := []string{title}
+	for _, r := range results {
+		icon := "✅"
+		switch r.Status {
+		case CheckFailed:
+			icon = "❌"
+		case CheckSkipped:
+			icon = "⏭️"
+		}
+		lines = append(lines, fmt.Sprintf("%s **%s**: %s", icon, r.Name, r.Detail))
+	}
+
+	// A bot in many guilds can outgrow one message
+	_, err = chat.SendLongMessage(t.ses, discord.ChannelID(sf), strings.Join(lines, "\n"), "", &api.AllowedMentions{})
+	if err != nil {
+		t.logger.Error("Failed to post self-test results", zap.Error(err), zap.String("channelID", channelID))
+	}
+}
+
+func failCheck(result CheckResult, detail string) CheckResult {
+	result.Status = CheckFailed
+	result.Detail = detail
+
+	return result
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,112 @@
+// Package characters stores the role-play characters that guilds define for /chat.
+package characters
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/fx"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const (
+	// MaxNameLength keeps names usable as webhook usernames and option values.
+	MaxNameLength = 32
+	// MaxPersonalityLength and MaxExampleDialogueLength bound the persona added to every prompt.
+	MaxPersonalityLength     = 2000
+	MaxExampleDialogueLength = 2000
+	// MaxPerGuild limits how many characters one guild can define.
+	MaxPerGuild = 25
+)
+
+// ErrLimitReached is returned by Store.Save when a guild already has MaxPerGuild characters.
+var ErrLimitReached = fmt.Errorf("a server can have at most %d characters", MaxPerGuild)
+
+// Character is a persona that /chat can answer as.
+type Character struct {
+	Name            string `json:"name"`
+	AvatarURL       string `json:"avatar_url,omitempty"`
+	Personality     string `json:"personality"`
+	ExampleDialogue string `json:"example_dialogue,omitempty"`
+}
+
+// Validate checks that c can be used as a persona and as a webhook identity.
+func (c Character) Validate() error {
+	name := strings.TrimSpace(c.Name)
+	switch {
+	case name == "":
+		return errors.New("character name is empty")
+	case len([]rune(name)) > MaxNameLength:
+		return fmt.Errorf("character name must be at most %d characters", MaxNameLength)
+	case containsReservedName(name):
+		// Discord rejects webhook usernames containing these
+		return errors.New(`character name cannot contain "discord" or "clyde"`)
+	case strings.TrimSpace(c.Personality) == "":
+		return errors.New("character personality is empty")
+	case len([]rune(c.Personality)) > MaxPersonalityLength:
+		return fmt.Errorf("character personality must be at most %d characters", MaxPersonalityLength)
+	case len([]rune(c.ExampleDialogue)) > MaxExampleDialogueLength:
+		return fmt.Errorf("character example dialogue must be at most %d characters", MaxExampleDialogueLength)
+	}
+
+	if c.AvatarURL != "" {
+		u, err := url.Parse(c.AvatarURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("character avatar must be an http or https URL")
+		}
+	}
+
+	return nil
+}
+
+func containsReservedName(name string) bool {
+	lower := strings.ToLower(name)
+
+	return strings.Contains(lower, "discord") || strings.Contains(lower, "clyde")
+}
+
+// SystemPrompt returns the system message that makes the model answer as c.
+func (c Character) SystemPrompt() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "You are %s, a character in a Discord conversation. Stay in character and answer as %s would.\n\n", c.Name, c.Name)
+	sb.WriteString("Personality:\n")
+	sb.WriteString(strings.TrimSpace(c.Personality))
+	if dialogue := strings.TrimSpace(c.ExampleDialogue); dialogue != "" {
+		sb.WriteString("\n\nExample dialogue:\n")
+		sb.WriteString(dialogue)
+	}
+
+	return sb.String()
+}
+
+// Store holds the characters of every guild. Names are matched case-insensitively.
+type Store interface {
+	// List returns the characters of guildID sorted by name.
+	List(guildID discord.GuildID) []Character
+	Get(guildID discord.GuildID, name string) (Character, bool)
+	// Save creates or replaces a character.
+	Save(guildID discord.GuildID, c Character) error
+	// Delete removes a character and reports whether it existed.
+	Delete(guildID discord.GuildID, name string) (bool, error)
+}
+
+// Module provides the character Store.
+var Module = fx.Module("characters",
+	fx.Provide(NewStoreProvider),
+)
+
+// NewStoreProvider creates the Store configured in cfg, kept in the state
+// backend.
+func NewStoreProvider(cfg *config.Config, state storage.Provider) (Store, error) {
+	path := cfg.Characters.File
+	if path == "" {
+		path = "characters.json"
+	}
+
+	return NewDocumentStore(storage.NewDocument(state, path))
+}
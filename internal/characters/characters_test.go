@@ -0,0 +1,93 @@
+package characters_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+)
+
+func TestCharacterValidate(t *testing.T) {
+	valid := characters.Character{Name: "Captain", Personality: "A gruff pirate."}
+	require.NoError(t, valid.Validate())
+
+	tests := map[string]characters.Character{
+		"empty name":       {Personality: "x"},
+		"long name":        {Name: strings.Repeat("a", characters.MaxNameLength+1), Personality: "x"},
+		"reserved name":    {Name: "Discord Helper", Personality: "x"},
+		"no personality":   {Name: "Captain"},
+		"non-http avatar":  {Name: "Captain", Personality: "x", AvatarURL: "ftp://example.com/a.png"},
+		"relative avatar":  {Name: "Captain", Personality: "x", AvatarURL: "a.png"},
+		"long personality": {Name: "Captain", Personality: strings.Repeat("a", characters.MaxPersonalityLength+1)},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Error(t, c.Validate())
+		})
+	}
+}
+
+func TestCharacterSystemPrompt(t *testing.T) {
+	c := characters.Character{Name: "Captain", Personality: "A gruff pirate.", ExampleDialogue: "Arr, matey!"}
+	prompt := c.SystemPrompt()
+	assert.Contains(t, prompt, "You are Captain")
+	assert.Contains(t, prompt, "A gruff pirate.")
+	assert.Contains(t, prompt, "Arr, matey!")
+
+	c.ExampleDialogue = ""
+	assert.NotContains(t, c.SystemPrompt(), "Example dialogue")
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "characters.json")
+	guild := discord.GuildID(1)
+
+	store, err := characters.NewFileStore(path)
+	require.NoError(t, err)
+	assert.Empty(t, store.List(guild))
+
+	require.NoError(t, store.Save(guild, characters.Character{Name: " Zed ", Personality: "Calm."}))
+	require.NoError(t, store.Save(guild, characters.Character{Name: "amy", Personality: "Cheerful."}))
+	require.NoError(t, store.Save(guild, characters.Character{Name: "Amy", Personality: "Grumpy."}), "same name replaces")
+	require.Error(t, store.Save(guild, characters.Character{Name: "Bad"}))
+
+	got, ok := store.Get(guild, "AMY")
+	require.True(t, ok)
+	assert.Equal(t, "Grumpy.", got.Personality)
+	_, ok = store.Get(discord.GuildID(2), "amy")
+	assert.False(t, ok, "characters are per guild")
+
+	// Characters survive a restart
+	reloaded, err := characters.NewFileStore(path)
+	require.NoError(t, err)
+	list := reloaded.List(guild)
+	require.Len(t, list, 2)
+	assert.Equal(t, "Amy", list[0].Name)
+	assert.Equal(t, "Zed", list[1].Name)
+
+	deleted, err := reloaded.Delete(guild, "zed")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = reloaded.Delete(guild, "zed")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	assert.Len(t, reloaded.List(guild), 1)
+}
+
+func TestFileStore_Limit(t *testing.T) {
+	store, err := characters.NewFileStore(filepath.Join(t.TempDir(), "characters.json"))
+	require.NoError(t, err)
+	guild := discord.GuildID(1)
+
+	for i := range characters.MaxPerGuild {
+		require.NoError(t, store.Save(guild, characters.Character{Name: fmt.Sprintf("c%d", i), Personality: "x"}))
+	}
+	require.ErrorIs(t, store.Save(guild, characters.Character{Name: "extra", Personality: "x"}), characters.ErrLimitReached)
+	require.NoError(t, store.Save(guild, characters.Character{Name: "c0", Personality: "y"}), "replacing stays within the limit")
+}
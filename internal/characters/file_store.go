@@ -0,0 +1,147 @@
+package characters
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+// documentStore keeps characters in memory and saves them to a JSON document
+// on every change.
+type documentStore struct {
+	doc storage.Document
+
+	mu     sync.RWMutex
+	guilds map[discord.GuildID]map[string]Character // keyed by lowercased name
+}
+
+// NewFileStore creates a Store persisted to the file at path, loading any
+// characters already saved there.
+func NewFileStore(path string) (Store, error) {
+	return NewDocumentStore(storage.NewDocument(storage.NewFileProvider(""), path))
+}
+
+// NewDocumentStore creates a Store persisted to doc, loading any characters
+// already saved in it.
+func NewDocumentStore(doc storage.Document) (Store, error) {
+	s := &documentStore{doc: doc, guilds: make(map[discord.GuildID]map[string]Character)}
+
+	var saved map[discord.GuildID][]Character
+	if _, err := doc.Load(&saved); err != nil {
+		return nil, fmt.Errorf("failed to load characters: %w", err)
+	}
+	for guildID, chars := range saved {
+		byName := make(map[string]Character, len(chars))
+		for _, c := range chars {
+			byName[nameKey(c.Name)] = c
+		}
+		s.guilds[guildID] = byName
+	}
+
+	return s, nil
+}
+
+// List returns the characters of guildID sorted by name.
+func (s *documentStore) List(guildID discord.GuildID) []Character {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedCharacters(s.guilds[guildID])
+}
+
+// Get returns the character of guildID with the given name.
+func (s *documentStore) Get(guildID discord.GuildID, name string) (Character, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.guilds[guildID][nameKey(name)]
+
+	return c, ok
+}
+
+// Save validates and stores c, replacing a character with the same name.
+func (s *documentStore) Save(guildID discord.GuildID, c Character) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chars := s.guilds[guildID]
+	if chars == nil {
+		chars = make(map[string]Character)
+		s.guilds[guildID] = chars
+	}
+	key := nameKey(c.Name)
+	previous, existed := chars[key]
+	if !existed && len(chars) >= MaxPerGuild {
+		return ErrLimitReached
+	}
+
+	chars[key] = c
+	if err := s.persist(); err != nil {
+		if existed {
+			chars[key] = previous
+		} else {
+			delete(chars, key)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes the character of guildID with the given name.
+func (s *documentStore) Delete(guildID discord.GuildID, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := nameKey(name)
+	previous, ok := s.guilds[guildID][key]
+	if !ok {
+		return false, nil
+	}
+
+	delete(s.guilds[guildID], key)
+	if err := s.persist(); err != nil {
+		s.guilds[guildID][key] = previous
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// persist saves all characters. The caller must hold s.mu.
+func (s *documentStore) persist() error {
+	saved := make(map[discord.GuildID][]Character, len(s.guilds))
+	for guildID, chars := range s.guilds {
+		if len(chars) > 0 {
+			saved[guildID] = sortedCharacters(chars)
+		}
+	}
+
+	return s.doc.Save(saved)
+}
+
+func sortedCharacters(chars map[string]Character) []Character {
+	list := make([]Character, 0, len(chars))
+	for _, c := range chars {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return nameKey(list[i].Name) < nameKey(list[j].Name) })
+
+	return list
+}
+
+func nameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
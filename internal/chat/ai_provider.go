@@ -4,72 +4,273 @@ package chat
 import (
 	"context"
 	"errors"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
 
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
 	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
 )
 
+// summaryTemperature is the sampling temperature of summaries.
+const summaryTemperature = 0.2
+
 // AIProvider defines the interface for interacting with an AI chat completion service.
 type AIProvider interface {
 	GetChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error)
 }
 
+// ToolCallingProvider is implemented by AIProviders that can offer tools to the model.
+type ToolCallingProvider interface {
+	// GetChatCompletionWithTools is GetChatCompletion with tools the model may
+	// call. A response asking for tool calls may have no content.
+	GetChatCompletionWithTools(ctx context.Context, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*openai.ChatCompletionResponse, error)
+}
+
+// StreamingProvider is implemented by AIProviders that can stream replies.
+type StreamingProvider interface {
+	// StreamChatCompletion is GetChatCompletion with the reply streamed. If
+	// the stream stops after some content arrived, the error is an
+	// *InterruptedError holding that content.
+	StreamChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error)
+}
+
+// SummarizingProvider is implemented by AIProviders with a mode for
+// condensing conversations.
+type SummarizingProvider interface {
+	// Summarize returns a summary of messages written as instructions ask,
+	// as the content of the response.
+	Summarize(ctx context.Context, model string, messages []openai.ChatCompletionMessage, instructions string) (*openai.ChatCompletionResponse, error)
+}
+
+// StructuredProvider is implemented by AIProviders that can hold replies to
+// a JSON schema.
+type StructuredProvider interface {
+	// GetStructuredCompletion is GetChatCompletion with the content of the
+	// response following the JSON schema of format.
+	GetStructuredCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error)
+}
+
+// InterruptedError reports a streamed reply that stopped before it was
+// complete, usually because a newer message canceled the request.
+type InterruptedError struct {
+	Partial string // content received before the stream stopped
+	Err     error
+}
+
+func (e *InterruptedError) Error() string {
+	return "reply interrupted: " + e.Err.Error()
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
 // NewOpenAIProvider creates a new OpenAI-based AIProvider implementation.
-func NewOpenAIProvider(logger *zap.Logger, cfg *config.Config, client *openai.Client, pricingService pkgopenai.PricingService) AIProvider {
+// Requests use the OpenAI credentials of the guild set on their context.
+func NewOpenAIProvider(logger *zap.Logger, cfg *config.Config, keys internalopenai.KeyResolver, pricingService pkgopenai.PricingService, m *metrics.Metrics) AIProvider {
 	return &openAIProvider{
 		logger:         logger.Named("openai_provider"),
 		cfg:            cfg,
-		client:         client,
+		keys:           keys,
 		pricingService: pricingService,
+		metrics:        m,
 	}
 }
 
 type openAIProvider struct {
 	logger         *zap.Logger
-	client         *openai.Client
+	keys           internalopenai.KeyResolver
 	cfg            *config.Config
 	pricingService pkgopenai.PricingService
+	metrics        *metrics.Metrics
 }
 
 // GetChatCompletion sends a chat completion request to OpenAI and returns the response.
 func (oai *openAIProvider) GetChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
-	oai.logger.Info("Sending request to OpenAI",
-		zap.String("model", model),
-		zap.Int("messageCount", len(messages)),
-	)
+	return oai.complete(ctx, openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: messages,
+	})
+}
 
-	aiRequest := openai.ChatCompletionRequest{
+// GetChatCompletionWithTools sends a chat completion request offering tools to the model.
+func (oai *openAIProvider) GetChatCompletionWithTools(ctx context.Context, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*openai.ChatCompletionResponse, error) {
+	return oai.complete(ctx, openai.ChatCompletionRequest{
 		Model:    model,
 		Messages: messages,
-	}
+		Tools:    tools,
+	})
+}
+
+// GetStructuredCompletion sends a chat completion request whose reply follows
+// the JSON schema of format.
+func (oai *openAIProvider) GetStructuredCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	return oai.complete(ctx, openai.ChatCompletionRequest{
+		Model:          model,
+		Messages:       messages,
+		ResponseFormat: format,
+	})
+}
+
+// Summarize asks for a summary of messages at a low temperature, so it sticks
+// to what was said.
+func (oai *openAIProvider) Summarize(ctx context.Context, model string, messages []openai.ChatCompletionMessage, instructions string) (*openai.ChatCompletionResponse, error) {
+	request := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	request = append(request, messages...)
+	request = append(request, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: instructions})
+
+	return oai.complete(ctx, openai.ChatCompletionRequest{
+		Model:       model,
+		Messages:    request,
+		Temperature: summaryTemperature,
+	})
+}
+
+func (oai *openAIProvider) complete(ctx context.Context, aiRequest openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	model := aiRequest.Model
+	aiRequest.Seed = SeedFrom(ctx)
+	aiRequest.Model, aiRequest.MaxCompletionTokens = pinnedRequest(ctx, model, oai.maxResponseTokens(ctx))
+	oai.logger.Info("Sending request to OpenAI",
+		zap.String("model", model),
+		zap.String("requestedModel", aiRequest.Model),
+		zap.Int("messageCount", len(aiRequest.Messages)),
+		zap.Int("toolCount", len(aiRequest.Tools)),
+		seedField(aiRequest.Seed),
+	)
 
-	aiResponse, err := oai.client.CreateChatCompletion(ctx, aiRequest)
+	start := time.Now()
+	aiResponse, err := oai.keys.Client(internalopenai.GuildFrom(ctx)).CreateChatCompletion(ctx, aiRequest)
+	oai.metrics.ObserveOpenAI(model, time.Since(start), err)
 	if err != nil {
 		oai.logger.Error("Failed to get response from OpenAI", zap.Error(err))
 
 		return nil, err
 	}
 
-	if len(aiResponse.Choices) == 0 || aiResponse.Choices[0].Message.Content == "" {
+	if len(aiResponse.Choices) == 0 ||
+		(aiResponse.Choices[0].Message.Content == "" && len(aiResponse.Choices[0].Message.ToolCalls) == 0) {
 		oai.logger.Warn("OpenAI returned an empty response", zap.Any("aiResponse", aiResponse))
 
 		return nil, errors.New("OpenAI returned empty response")
 	}
 
-	// Calculate and log cost information
+	oai.logUsage(model, aiRequest.Seed, &aiResponse)
+
+	return &aiResponse, nil
+}
+
+// StreamChatCompletion streams a chat completion from OpenAI and returns the
+// assembled response once the stream ends.
+func (oai *openAIProvider) StreamChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	seed := SeedFrom(ctx)
+	requestedModel, maxTokens := pinnedRequest(ctx, model, oai.maxResponseTokens(ctx))
+	oai.logger.Info("Streaming request to OpenAI",
+		zap.String("model", model),
+		zap.String("requestedModel", requestedModel),
+		zap.Int("messageCount", len(messages)),
+		seedField(seed),
+	)
+
+	start := time.Now()
+	stream, err := oai.keys.Client(internalopenai.GuildFrom(ctx)).CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model:               requestedModel,
+		Messages:            messages,
+		Seed:                seed,
+		MaxCompletionTokens: maxTokens,
+		Stream:              true,
+		StreamOptions:       &openai.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		oai.metrics.ObserveOpenAI(model, time.Since(start), err)
+		oai.logger.Error("Failed to start stream from OpenAI", zap.Error(err))
+
+		return nil, err
+	}
+	defer func() { _ = stream.Close() }()
+
+	aiResponse := openai.ChatCompletionResponse{Model: model}
+	var content strings.Builder
+	var finishReason openai.FinishReason
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			oai.metrics.ObserveOpenAI(model, time.Since(start), err)
+			if content.Len() > 0 {
+				return nil, &InterruptedError{Partial: content.String(), Err: err}
+			}
+			oai.logger.Error("Failed to stream response from OpenAI", zap.Error(err))
+
+			return nil, err
+		}
+
+		aiResponse.ID = chunk.ID
+		if chunk.Model != "" {
+			aiResponse.Model = chunk.Model
+		}
+		if chunk.SystemFingerprint != "" {
+			aiResponse.SystemFingerprint = chunk.SystemFingerprint
+		}
+		if chunk.Usage != nil {
+			aiResponse.Usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+
+	oai.metrics.ObserveOpenAI(model, time.Since(start), nil)
+
+	if content.Len() == 0 {
+		oai.logger.Warn("OpenAI streamed an empty response", zap.String("model", model))
+
+		return nil, errors.New("OpenAI returned empty response")
+	}
+	aiResponse.Choices = []openai.ChatCompletionChoice{{
+		Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content.String()},
+		FinishReason: finishReason,
+	}}
+	oai.logUsage(model, seed, &aiResponse)
+
+	return &aiResponse, nil
+}
+
+// maxResponseTokens returns the reply token limit of the guild set on ctx.
+func (oai *openAIProvider) maxResponseTokens(ctx context.Context) int {
+	return oai.cfg.Limits(internalopenai.GuildFrom(ctx).String()).MaxResponseTokens
+}
+
+// logUsage logs the token usage and estimated cost of a response, with the
+// seed and system fingerprint needed to reproduce it.
+func (oai *openAIProvider) logUsage(model string, seed *int, aiResponse *openai.ChatCompletionResponse) {
+	usage := aiResponse.Usage
+	oai.metrics.AddTokens(model, usage.PromptTokens, usage.CompletionTokens)
 	cost, costErr := oai.pricingService.CalculateTokenCost(
 		model,
-		aiResponse.Usage.PromptTokens,
-		aiResponse.Usage.CompletionTokens,
+		usage.PromptTokens,
+		usage.CompletionTokens,
 	)
 
 	logFields := []zap.Field{
-		zap.Int("promptTokens", aiResponse.Usage.PromptTokens),
-		zap.Int("completionTokens", aiResponse.Usage.CompletionTokens),
-		zap.Int("totalTokens", aiResponse.Usage.TotalTokens),
+		zap.Int("promptTokens", usage.PromptTokens),
+		zap.Int("completionTokens", usage.CompletionTokens),
+		zap.Int("totalTokens", usage.TotalTokens),
+		seedField(seed),
+		zap.String("systemFingerprint", aiResponse.SystemFingerprint),
 	}
 
 	if costErr == nil {
@@ -79,6 +280,13 @@ func (oai *openAIProvider) GetChatCompletion(ctx context.Context, model string,
 	}
 
 	oai.logger.Info("Received response from OpenAI", logFields...)
+}
 
-	return &aiResponse, nil
+// seedField logs the seed of a request, or nothing when it has none.
+func seedField(seed *int) zap.Field {
+	if seed == nil {
+		return zap.Skip()
+	}
+
+	return zap.Int("seed", *seed)
 }
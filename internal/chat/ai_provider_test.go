@@ -0,0 +1,178 @@
+package chat_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+func streamChunk(content string) string {
+	return fmt.Sprintf(`data: {"id":"1","choices":[{"index":0,"delta":{"content":%q}}]}`+"\n\n", content)
+}
+
+func newStreamingProvider(t *testing.T, handler http.HandlerFunc) chat.StreamingProvider {
+	t.Helper()
+
+	return newStreamingProviderWithConfig(t, &config.Config{}, handler)
+}
+
+func newStreamingProviderWithConfig(t *testing.T, cfg *config.Config, handler http.HandlerFunc) chat.StreamingProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	clientConfig := openai.DefaultConfig("test")
+	clientConfig.BaseURL = server.URL
+	provider := chat.NewOpenAIProvider(zap.NewNop(), cfg, internalopenai.NewKeyResolverFromClient(openai.NewClientWithConfig(clientConfig)), pkgopenai.NewPricingService(""), nil)
+	streamer, ok := provider.(chat.StreamingProvider)
+	require.True(t, ok)
+
+	return streamer
+}
+
+func TestOpenAIProvider_StreamChatCompletion(t *testing.T) {
+	streamer := newStreamingProvider(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, streamChunk("Hello"), streamChunk(", world"))
+		_, _ = fmt.Fprint(w, `data: {"id":"1","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`+"\n\n")
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	resp, err := streamer.StreamChatCompletion(context.Background(), "gpt-4o", refinePrompt)
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "Hello, world", resp.Choices[0].Message.Content)
+	assert.Equal(t, openai.ChatMessageRoleAssistant, resp.Choices[0].Message.Role)
+	assert.Equal(t, 5, resp.Usage.TotalTokens)
+}
+
+func TestOpenAIProvider_StreamInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	streamer := newStreamingProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, streamChunk("Half an"))
+		w.(http.Flusher).Flush()
+		// A newer message cancels the request mid-stream
+		time.AfterFunc(50*time.Millisecond, cancel)
+		<-r.Context().Done()
+	})
+
+	_, err := streamer.StreamChatCompletion(ctx, "gpt-4o", refinePrompt)
+	var interrupted *chat.InterruptedError
+	require.ErrorAs(t, err, &interrupted)
+	assert.Equal(t, "Half an", interrupted.Partial)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestOpenAIProvider_Seed(t *testing.T) {
+	var seeds []*int
+	streamer := newStreamingProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		seeds = append(seeds, request.Seed)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"system_fingerprint":"fp_1","choices":[{"message":{"role":"assistant","content":"4"}}]}`)
+	})
+	provider := streamer.(chat.AIProvider)
+
+	resp, err := provider.GetChatCompletion(chat.WithSeed(context.Background(), 42), "gpt-4o", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "fp_1", resp.SystemFingerprint)
+	_, err = provider.GetChatCompletion(context.Background(), "gpt-4o", refinePrompt)
+	require.NoError(t, err)
+
+	require.Len(t, seeds, 2)
+	require.NotNil(t, seeds[0])
+	assert.Equal(t, 42, *seeds[0])
+	assert.Nil(t, seeds[1], "requests without a seed leave it unset")
+	assert.Nil(t, chat.SeedFrom(context.Background()))
+}
+
+func TestOpenAIProvider_MaxResponseTokens(t *testing.T) {
+	cfg := &config.Config{
+		OpenAI: config.OpenAIConfig{Limits: config.LimitsConfig{MaxResponseTokens: 500}},
+		Guilds: map[string]config.GuildConfig{"1": {Limits: config.LimitsConfig{MaxResponseTokens: 50}}},
+	}
+	var limits []int
+	streamer := newStreamingProviderWithConfig(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		limits = append(limits, request.MaxCompletionTokens)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"4"}}]}`)
+	})
+	provider := streamer.(chat.AIProvider)
+
+	_, err := provider.GetChatCompletion(context.Background(), "gpt-4o", refinePrompt)
+	require.NoError(t, err)
+	_, err = provider.GetChatCompletion(internalopenai.WithGuild(context.Background(), 1), "gpt-4o", refinePrompt)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{500, 50}, limits, "guild limits replace the global one")
+}
+
+func TestOpenAIProvider_ModelPin(t *testing.T) {
+	cfg := &config.Config{OpenAI: config.OpenAIConfig{Limits: config.LimitsConfig{MaxResponseTokens: 500}}}
+	var requests []openai.ChatCompletionRequest
+	streamer := newStreamingProviderWithConfig(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"model":"gpt-4o-2024-08-06","choices":[{"message":{"role":"assistant","content":"4"}}]}`)
+	})
+	provider := streamer.(chat.AIProvider)
+
+	ctx := chat.WithModelPin(context.Background(), &chat.ModelPin{Model: "gpt-4o", Snapshot: "gpt-4o-2024-05-13", MaxResponseTokens: 200})
+	resp, err := provider.GetChatCompletion(ctx, "gpt-4o", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-2024-08-06", resp.Model, "responses name the snapshot that answered")
+	_, err = provider.GetChatCompletion(ctx, "gpt-4o-mini", refinePrompt)
+	require.NoError(t, err)
+	_, err = provider.GetChatCompletion(chat.WithModelPin(context.Background(), nil), "gpt-4o", refinePrompt)
+	require.NoError(t, err)
+
+	require.Len(t, requests, 3)
+	assert.Equal(t, "gpt-4o-2024-05-13", requests[0].Model)
+	assert.Equal(t, 200, requests[0].MaxCompletionTokens, "pinned threads keep the limit they started with")
+	assert.Equal(t, "gpt-4o-mini", requests[1].Model, "pins only apply to the model pinned")
+	assert.Equal(t, 500, requests[1].MaxCompletionTokens)
+	assert.Equal(t, "gpt-4o", requests[2].Model)
+}
+
+func TestOpenAIProvider_Summarize(t *testing.T) {
+	var request openai.ChatCompletionRequest
+	streamer := newStreamingProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"- Asked about 2+2"}}]}`)
+	})
+	summarizer, ok := streamer.(chat.SummarizingProvider)
+	require.True(t, ok)
+
+	resp, err := summarizer.Summarize(context.Background(), "gpt-4o", refinePrompt, "Summarize it.")
+	require.NoError(t, err)
+	assert.Equal(t, "- Asked about 2+2", resp.Choices[0].Message.Content)
+
+	require.Len(t, request.Messages, len(refinePrompt)+1)
+	last := request.Messages[len(request.Messages)-1]
+	assert.Equal(t, openai.ChatMessageRoleSystem, last.Role)
+	assert.Equal(t, "Summarize it.", last.Content)
+	assert.InDelta(t, 0.2, request.Temperature, 0.001)
+}
@@ -0,0 +1,385 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com"
+	defaultAnthropicMaxTokens = 4096
+	// anthropicVersion is the Messages API version requests are written for.
+	anthropicVersion = "2023-06-01"
+	// anthropicModelPrefix names Claude models, which go to Anthropic even
+	// when anthropic.models does not list them.
+	anthropicModelPrefix = "claude-"
+)
+
+// NewAnthropicProvider creates an AIProvider for Anthropic's Claude models.
+// It speaks the Messages API and translates to and from the OpenAI message
+// types the rest of the bot uses.
+func NewAnthropicProvider(logger *zap.Logger, cfg *config.Config, pricingService pkgopenai.PricingService, m *metrics.Metrics) AIProvider {
+	baseURL := strings.TrimSuffix(cfg.Anthropic.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
+	}
+
+	return &anthropicProvider{
+		logger:         logger.Named("anthropic_provider"),
+		cfg:            cfg,
+		pricingService: pricingService,
+		metrics:        m,
+		baseURL:        baseURL,
+		httpClient:     &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type anthropicProvider struct {
+	logger         *zap.Logger
+	cfg            *config.Config
+	pricingService pkgopenai.PricingService
+	metrics        *metrics.Metrics
+	baseURL        string
+	httpClient     *http.Client
+}
+
+// Messages API request and response bodies, as far as the bot uses them.
+type (
+	anthropicRequest struct {
+		Model       string             `json:"model"`
+		MaxTokens   int                `json:"max_tokens"`
+		System      string             `json:"system,omitempty"`
+		Messages    []anthropicMessage `json:"messages"`
+		Tools       []anthropicTool    `json:"tools,omitempty"`
+		Temperature *float32           `json:"temperature,omitempty"`
+	}
+	anthropicMessage struct {
+		Role    string           `json:"role"` // "user" or "assistant"
+		Content []anthropicBlock `json:"content"`
+	}
+	anthropicBlock struct {
+		Type string `json:"type"` // "text", "image", "tool_use" or "tool_result"
+		Text string `json:"text,omitempty"`
+		// Image
+		Source *anthropicImageSource `json:"source,omitempty"`
+		// Tool use
+		ID    string          `json:"id,omitempty"`
+		Name  string          `json:"name,omitempty"`
+		Input json.RawMessage `json:"input,omitempty"`
+		// Tool result
+		ToolUseID string `json:"tool_use_id,omitempty"`
+		Content   string `json:"content,omitempty"`
+	}
+	anthropicImageSource struct {
+		Type      string `json:"type"` // "url" or "base64"
+		URL       string `json:"url,omitempty"`
+		MediaType string `json:"media_type,omitempty"`
+		Data      string `json:"data,omitempty"`
+	}
+	anthropicTool struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		InputSchema any    `json:"input_schema"`
+	}
+	anthropicResponse struct {
+		ID         string           `json:"id"`
+		Model      string           `json:"model"`
+		Content    []anthropicBlock `json:"content"`
+		StopReason string           `json:"stop_reason"`
+		Usage      struct {
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		} `json:"usage"`
+	}
+	anthropicError struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+)
+
+// GetChatCompletion sends a chat completion request to Anthropic and returns the response.
+func (ap *anthropicProvider) GetChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	return ap.complete(ctx, model, messages, nil, nil)
+}
+
+// GetChatCompletionWithTools sends a chat completion request offering tools to the model.
+func (ap *anthropicProvider) GetChatCompletionWithTools(ctx context.Context, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*openai.ChatCompletionResponse, error) {
+	return ap.complete(ctx, model, messages, tools, nil)
+}
+
+// Summarize asks for a summary of messages at a low temperature, so it sticks
+// to what was said.
+func (ap *anthropicProvider) Summarize(ctx context.Context, model string, messages []openai.ChatCompletionMessage, instructions string) (*openai.ChatCompletionResponse, error) {
+	request := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	request = append(request, messages...)
+	request = append(request, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: instructions})
+	temperature := float32(summaryTemperature)
+
+	return ap.complete(ctx, model, request, nil, &temperature)
+}
+
+func (ap *anthropicProvider) complete(ctx context.Context, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool, temperature *float32) (*openai.ChatCompletionResponse, error) {
+	request, err := ap.request(ctx, model, messages, tools)
+	if err != nil {
+		return nil, err
+	}
+	request.Temperature = temperature
+	ap.logger.Info("Sending request to Anthropic",
+		zap.String("model", model),
+		zap.Int("messageCount", len(request.Messages)),
+		zap.Int("toolCount", len(request.Tools)),
+	)
+
+	start := time.Now()
+	response, err := ap.send(ctx, request)
+	ap.metrics.ObserveOpenAI(model, time.Since(start), err)
+	if err != nil {
+		ap.logger.Error("Failed to get response from Anthropic", zap.Error(err))
+
+		return nil, err
+	}
+
+	aiResponse := toOpenAIResponse(response)
+	if aiResponse.Choices[0].Message.Content == "" && len(aiResponse.Choices[0].Message.ToolCalls) == 0 {
+		ap.logger.Warn("Anthropic returned an empty response", zap.String("model", model), zap.String("stopReason", response.StopReason))
+
+		return nil, errors.New("Anthropic returned empty response")
+	}
+
+	ap.logUsage(model, aiResponse)
+
+	return aiResponse, nil
+}
+
+// request translates an OpenAI request. System messages become the system
+// prompt, tool results become user turns, and consecutive turns of the same
+// role are merged, as the Messages API requires turns to alternate.
+func (ap *anthropicProvider) request(ctx context.Context, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (anthropicRequest, error) {
+	model, maxTokens := pinnedRequest(ctx, model, ap.cfg.Limits(internalopenai.GuildFrom(ctx).String()).MaxResponseTokens)
+	if maxTokens <= 0 {
+		maxTokens = ap.cfg.Anthropic.MaxTokens
+	}
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+	request := anthropicRequest{Model: model, MaxTokens: maxTokens}
+
+	var system []string
+	for _, msg := range messages {
+		role := openai.ChatMessageRoleUser
+		var blocks []anthropicBlock
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			if msg.Content != "" {
+				system = append(system, msg.Content)
+			}
+
+			continue
+		case openai.ChatMessageRoleAssistant:
+			role = openai.ChatMessageRoleAssistant
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+			}
+		case openai.ChatMessageRoleTool:
+			blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		default:
+			blocks = userBlocks(msg)
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(request.Messages); n > 0 && request.Messages[n-1].Role == role {
+			request.Messages[n-1].Content = append(request.Messages[n-1].Content, blocks...)
+		} else {
+			request.Messages = append(request.Messages, anthropicMessage{Role: role, Content: blocks})
+		}
+	}
+	if len(request.Messages) == 0 {
+		return anthropicRequest{}, errors.New("no messages to send to Anthropic")
+	}
+	// The conversation must open with a user turn, which a seeded assistant
+	// greeting would not
+	if request.Messages[0].Role != openai.ChatMessageRoleUser {
+		request.Messages = append([]anthropicMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: []anthropicBlock{{Type: "text", Text: "(conversation start)"}},
+		}}, request.Messages...)
+	}
+	request.System = strings.Join(system, "\n\n")
+
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		request.Tools = append(request.Tools, anthropicTool{Name: tool.Function.Name, Description: tool.Function.Description, InputSchema: schema})
+	}
+
+	return request, nil
+}
+
+// userBlocks translates the text and images of a user message. Images given
+// as data URLs, as inlined attachments are, are sent as base64.
+func userBlocks(msg openai.ChatCompletionMessage) []anthropicBlock {
+	if len(msg.MultiContent) == 0 {
+		if msg.Content == "" {
+			return nil
+		}
+
+		return []anthropicBlock{{Type: "text", Text: msg.Content}}
+	}
+
+	blocks := make([]anthropicBlock, 0, len(msg.MultiContent))
+	for _, part := range msg.MultiContent {
+		switch {
+		case part.Type == openai.ChatMessagePartTypeText && part.Text != "":
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+		case part.Type == openai.ChatMessagePartTypeImageURL && part.ImageURL != nil:
+			source := &anthropicImageSource{Type: "url", URL: part.ImageURL.URL}
+			if rest, ok := strings.CutPrefix(part.ImageURL.URL, "data:"); ok {
+				if mediaType, data, ok := strings.Cut(rest, ";base64,"); ok {
+					source = &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+				}
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: source})
+		}
+	}
+
+	return blocks
+}
+
+// send posts request to the Messages API. Error responses are returned as
+// *openai.APIError, so rate limits are handled as OpenAI's are.
+func (ap *anthropicProvider) send(ctx context.Context, request anthropicRequest) (*anthropicResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Anthropic request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ap.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Anthropic request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", ap.cfg.Anthropic.APIKey)
+	req.Header.Set("Anthropic-Version", anthropicVersion)
+
+	resp, err := ap.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Anthropic: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Anthropic response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &openai.APIError{HTTPStatusCode: resp.StatusCode, HTTPStatus: resp.Status, Message: strings.TrimSpace(string(data))}
+		var body anthropicError
+		if json.Unmarshal(data, &body) == nil && body.Error.Message != "" {
+			apiErr.Type = body.Error.Type
+			apiErr.Message = body.Error.Message
+		}
+
+		return nil, apiErr
+	}
+
+	var response anthropicResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse Anthropic response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// toOpenAIResponse translates a Messages API response. Prompt tokens include
+// cached ones, which OpenAI counts the same way.
+func toOpenAIResponse(response *anthropicResponse) *openai.ChatCompletionResponse {
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	var text []string
+	for _, block := range response.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:       block.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	message.Content = strings.Join(text, "")
+
+	finishReason := openai.FinishReasonStop
+	switch response.StopReason {
+	case "max_tokens":
+		finishReason = openai.FinishReasonLength
+	case "tool_use":
+		finishReason = openai.FinishReasonToolCalls
+	}
+
+	usage := response.Usage
+	promptTokens := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+
+	return &openai.ChatCompletionResponse{
+		ID:      response.ID,
+		Model:   response.Model,
+		Choices: []openai.ChatCompletionChoice{{Message: message, FinishReason: finishReason}},
+		Usage: openai.Usage{
+			PromptTokens:        promptTokens,
+			CompletionTokens:    usage.OutputTokens,
+			TotalTokens:         promptTokens + usage.OutputTokens,
+			PromptTokensDetails: &openai.PromptTokensDetails{CachedTokens: usage.CacheReadInputTokens},
+		},
+	}
+}
+
+// logUsage logs the token usage and estimated cost of a response.
+func (ap *anthropicProvider) logUsage(model string, aiResponse *openai.ChatCompletionResponse) {
+	usage := aiResponse.Usage
+	ap.metrics.AddTokens(model, usage.PromptTokens, usage.CompletionTokens)
+
+	logFields := []zap.Field{
+		zap.Int("promptTokens", usage.PromptTokens),
+		zap.Int("completionTokens", usage.CompletionTokens),
+		zap.Int("totalTokens", usage.TotalTokens),
+	}
+	if cost, err := ap.pricingService.CalculateTokenCost(model, usage.PromptTokens, usage.CompletionTokens); err == nil {
+		logFields = append(logFields, zap.Float64("estimatedCostUSD", cost))
+	} else {
+		logFields = append(logFields, zap.String("costCalculationError", err.Error()))
+	}
+
+	ap.logger.Info("Received response from Anthropic", logFields...)
+}
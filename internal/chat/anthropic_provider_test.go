@@ -0,0 +1,133 @@
+package chat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// newRoutedProvider creates the AIProvider for a bot with both OpenAI and
+// Anthropic configured, each served by its own handler.
+func newRoutedProvider(t *testing.T, openAIHandler, anthropicHandler http.HandlerFunc) chat.AIProvider {
+	t.Helper()
+	openAIServer := httptest.NewServer(openAIHandler)
+	t.Cleanup(openAIServer.Close)
+	anthropicServer := httptest.NewServer(anthropicHandler)
+	t.Cleanup(anthropicServer.Close)
+
+	cfg := &config.Config{Anthropic: config.AnthropicConfig{APIKey: "sk-ant-test", BaseURL: anthropicServer.URL, Models: []string{"sonnet-gateway"}}}
+	clientConfig := openai.DefaultConfig("test")
+	clientConfig.BaseURL = openAIServer.URL
+
+	return chat.NewAIProvider(zap.NewNop(), cfg, internalopenai.NewKeyResolverFromClient(openai.NewClientWithConfig(clientConfig)), pkgopenai.NewPricingService(""), nil, nil)
+}
+
+func anthropicReply(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = fmt.Fprint(w, body)
+}
+
+func TestAIProvider_RoutesByModel(t *testing.T) {
+	var openAICalls, anthropicCalls int
+	provider := newRoutedProvider(t,
+		func(w http.ResponseWriter, _ *http.Request) {
+			openAICalls++
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `{"id":"1","choices":[{"message":{"role":"assistant","content":"from openai"}}]}`)
+		},
+		func(w http.ResponseWriter, _ *http.Request) {
+			anthropicCalls++
+			anthropicReply(w, `{"id":"msg_1","content":[{"type":"text","text":"from claude"}],"stop_reason":"end_turn"}`)
+		},
+	)
+
+	for model, want := range map[string]string{"gpt-4o": "from openai", "claude-sonnet-4-5": "from claude", "sonnet-gateway": "from claude"} {
+		resp, err := provider.GetChatCompletion(context.Background(), model, refinePrompt)
+		require.NoError(t, err, model)
+		assert.Equal(t, want, resp.Choices[0].Message.Content, model)
+	}
+	assert.Equal(t, 1, openAICalls)
+	assert.Equal(t, 2, anthropicCalls, "anthropic.models and claude-* models go to Anthropic")
+
+	// Claude models are not streamed, so the router asks for a whole reply
+	streamer, ok := provider.(chat.StreamingProvider)
+	require.True(t, ok)
+	resp, err := streamer.StreamChatCompletion(context.Background(), "claude-sonnet-4-5", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "from claude", resp.Choices[0].Message.Content)
+}
+
+func TestAnthropicProvider_Request(t *testing.T) {
+	var got map[string]any
+	provider := newRoutedProvider(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "sk-ant-test", r.Header.Get("X-Api-Key"))
+		assert.NotEmpty(t, r.Header.Get("Anthropic-Version"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		anthropicReply(w, `{"id":"msg_1","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"call_2","name":"fetch","input":{"url":"b"}}],
+			"stop_reason":"tool_use","usage":{"input_tokens":10,"cache_read_input_tokens":90,"output_tokens":5}}`)
+	})
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "Be brief."},
+		{Role: openai.ChatMessageRoleUser, Content: "Read a."},
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "fetch", Arguments: `{"url":"a"}`}}}},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: "page a"},
+		{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{
+			{Type: openai.ChatMessagePartTypeText, Text: "And this?"},
+			{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,iVBOR"}},
+		}},
+	}
+	tools := []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "fetch", Description: "Fetch a page"}}}
+	resp, err := provider.(chat.ToolCallingProvider).GetChatCompletionWithTools(context.Background(), "claude-sonnet-4-5", messages, tools)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Be brief.", got["system"])
+	assert.InDelta(t, 4096, got["max_tokens"], 0, "Anthropic requires a reply token limit")
+	assert.Equal(t, []any{
+		map[string]any{"role": "user", "content": []any{map[string]any{"type": "text", "text": "Read a."}}},
+		map[string]any{"role": "assistant", "content": []any{map[string]any{"type": "tool_use", "id": "call_1", "name": "fetch", "input": map[string]any{"url": "a"}}}},
+		// The tool result and the next message merge into one user turn
+		map[string]any{"role": "user", "content": []any{
+			map[string]any{"type": "tool_result", "tool_use_id": "call_1", "content": "page a"},
+			map[string]any{"type": "text", "text": "And this?"},
+			map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/png", "data": "iVBOR"}},
+		}},
+	}, got["messages"])
+	assert.Equal(t, []any{map[string]any{"name": "fetch", "description": "Fetch a page", "input_schema": map[string]any{"type": "object"}}}, got["tools"])
+
+	reply := resp.Choices[0]
+	assert.Equal(t, "Checking.", reply.Message.Content)
+	assert.Equal(t, openai.FinishReasonToolCalls, reply.FinishReason)
+	require.Len(t, reply.Message.ToolCalls, 1)
+	assert.Equal(t, openai.FunctionCall{Name: "fetch", Arguments: `{"url":"b"}`}, reply.Message.ToolCalls[0].Function)
+	assert.Equal(t, 100, resp.Usage.PromptTokens, "cached prompt tokens are counted")
+	assert.Equal(t, 105, resp.Usage.TotalTokens)
+}
+
+func TestAnthropicProvider_Errors(t *testing.T) {
+	provider := newRoutedProvider(t, nil, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"Slow down."}}`)
+	})
+
+	_, err := provider.GetChatCompletion(context.Background(), "claude-haiku-4-5", refinePrompt)
+	var apiErr *openai.APIError
+	require.True(t, errors.As(err, &apiErr), "errors look like OpenAI's, so rate limits are handled the same way")
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.HTTPStatusCode)
+	assert.Equal(t, "Slow down.", apiErr.Message)
+}
@@ -0,0 +1,409 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const (
+	defaultArchiveIdleDays      = 7
+	defaultArchiveCheckInterval = time.Hour
+	// archiveFormatVersion is bumped when archivedConversation changes incompatibly.
+	archiveFormatVersion = 1
+)
+
+// archivedConversation is the cold storage representation of a conversation.
+type archivedConversation struct {
+	Version        int                            `json:"version"`
+	ThreadID       string                         `json:"thread_id"`
+	GuildID        discord.GuildID                `json:"guild_id,omitempty"`
+	Model          string                         `json:"model"`
+	Messages       []openai.ChatCompletionMessage `json:"messages"`
+	SealedMessages []byte                         `json:"sealed_messages,omitempty"` // Replaces Messages when encrypted with the key of GuildID
+	Policy         ThreadPolicy                   `json:"policy,omitempty"`
+	InitiatorID    discord.UserID                 `json:"initiator_id,omitempty"`
+	Participants   []discord.UserID               `json:"participants,omitempty"`
+	Character      string                         `json:"character,omitempty"`
+	Seed           *int                           `json:"seed,omitempty"`
+	Pin            *ModelPin                      `json:"pin,omitempty"`
+	UpdatedAt      time.Time                      `json:"updated_at,omitempty"`
+	ArchivedAt     time.Time                      `json:"archived_at"`
+}
+
+// lastActivity returns when the conversation last changed. Archives written
+// before UpdatedAt was recorded fall back to the archive time.
+func (c *archivedConversation) lastActivity() time.Time {
+	if c.UpdatedAt.IsZero() {
+		return c.ArchivedAt
+	}
+
+	return c.UpdatedAt
+}
+
+// ConversationArchiver moves conversations of archived or idle threads from
+// the conversation cache to cold storage, and restores them when the thread
+// becomes active again. A nil archiver or one without a store does nothing.
+// With a sealer, message content is encrypted with the key of the thread's guild.
+type ConversationArchiver struct {
+	logger *zap.Logger
+	ses    *session.Session
+	store  ConversationStore
+	cold   storage.ColdStore
+	sealer storage.Sealer
+
+	idleAfter     time.Duration
+	checkInterval time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewConversationArchiver creates a ConversationArchiver. cold is nil when
+// archiving is disabled and sealer is nil when encryption is disabled.
+func NewConversationArchiver(
+	logger *zap.Logger,
+	cfg *config.Config,
+	ses *session.Session,
+	store ConversationStore,
+	cold storage.ColdStore,
+	sealer storage.Sealer,
+) *ConversationArchiver {
+	a := &ConversationArchiver{
+		logger:        logger.Named("conversation_archiver"),
+		ses:           ses,
+		store:         store,
+		cold:          cold,
+		sealer:        sealer,
+		idleAfter:     defaultArchiveIdleDays * 24 * time.Hour,
+		checkInterval: defaultArchiveCheckInterval,
+	}
+	if cfg.Archive.IdleDays > 0 {
+		a.idleAfter = time.Duration(cfg.Archive.IdleDays) * 24 * time.Hour
+	}
+	if cfg.Archive.CheckIntervalMinutes > 0 {
+		a.checkInterval = time.Duration(cfg.Archive.CheckIntervalMinutes) * time.Minute
+	}
+
+	if cold != nil {
+		ses.AddHandler(a.handleThreadUpdate)
+	}
+
+	return a
+}
+
+// Start launches the periodic idle sweep in the background.
+func (a *ConversationArchiver) Start() {
+	if a == nil || a.cold == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		ticker := time.NewTicker(a.checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.ArchiveIdle(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends the idle sweep and waits for it to finish.
+func (a *ConversationArchiver) Stop() {
+	if a == nil || a.cancel == nil {
+		return
+	}
+	a.cancel()
+	a.wg.Wait()
+}
+
+// ArchiveIdle archives every cached conversation idle for longer than the configured period.
+func (a *ConversationArchiver) ArchiveIdle(ctx context.Context) {
+	idle := a.store.IdleConversations(time.Now().Add(-a.idleAfter))
+	for _, threadID := range idle {
+		if err := a.Archive(ctx, threadID); err != nil {
+			a.logger.Warn("Failed to archive idle conversation", zap.Error(err), zap.String("threadID", threadID))
+		}
+	}
+	if len(idle) > 0 {
+		a.logger.Info("Archived idle conversations", zap.Int("count", len(idle)))
+	}
+}
+
+// FlushConversations drops every cached conversation and returns how many
+// were dropped. With the archive enabled they are archived first, so nothing
+// is lost; otherwise they are rebuilt from Discord on their next message.
+func (a *ConversationArchiver) FlushConversations(ctx context.Context) (int, error) {
+	cached := a.store.IdleConversations(time.Now().Add(time.Second))
+	flushed := 0
+	var errs []error
+	for _, threadID := range cached {
+		if a.cold == nil {
+			a.store.Evict(threadID)
+		} else if err := a.Archive(ctx, threadID); err != nil {
+			errs = append(errs, fmt.Errorf("thread %s: %w", threadID, err))
+
+			continue
+		}
+		flushed++
+	}
+	a.logger.Info("Flushed cached conversations", zap.Int("count", flushed), zap.Int("failed", len(errs)))
+
+	return flushed, errors.Join(errs...)
+}
+
+// Archive writes a cached conversation to cold storage and drops it from the
+// cache. Threads that are not cached are left alone.
+func (a *ConversationArchiver) Archive(ctx context.Context, threadID string) error {
+	if a == nil || a.cold == nil {
+		return nil
+	}
+
+	var guildID discord.GuildID
+	if a.sealer != nil {
+		// The guild is only needed to pick the encryption key
+		thread, err := a.threadChannel(threadID)
+		if err != nil {
+			return fmt.Errorf("failed to look up guild of thread: %w", err)
+		}
+		guildID = thread.GuildID
+	}
+
+	return a.archive(ctx, guildID, threadID)
+}
+
+func (a *ConversationArchiver) archive(ctx context.Context, guildID discord.GuildID, threadID string) error {
+	data, found := a.store.GetConversation(threadID)
+	if !found {
+		return nil
+	}
+
+	archived := archivedConversation{
+		Version:      archiveFormatVersion,
+		ThreadID:     threadID,
+		GuildID:      guildID,
+		Model:        data.Model,
+		Messages:     data.Messages,
+		Policy:       data.Access.Policy,
+		InitiatorID:  data.Access.InitiatorID,
+		Participants: data.Participants,
+		Character:    data.Character,
+		Seed:         data.Seed,
+		Pin:          data.Pin,
+		UpdatedAt:    data.UpdatedAt.UTC(),
+		ArchivedAt:   time.Now().UTC(),
+	}
+	if a.sealer != nil {
+		if err := a.seal(ctx, &archived); err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(archived)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %w", err)
+	}
+
+	if err := a.cold.Put(ctx, archiveKey(threadID), payload); err != nil {
+		return fmt.Errorf("failed to write conversation to cold storage: %w", err)
+	}
+	a.store.Evict(threadID)
+
+	a.logger.Debug("Archived conversation", zap.String("threadID", threadID), zap.Int("messageCount", len(data.Messages)))
+
+	return nil
+}
+
+// Rehydrate loads an archived conversation back into the cache. It reports
+// false if the thread has no archive, so that callers can fall back to
+// reconstructing it from Discord.
+func (a *ConversationArchiver) Rehydrate(ctx context.Context, threadID string) (*MessagesCacheData, bool) {
+	if a == nil || a.cold == nil {
+		return nil, false
+	}
+
+	payload, err := a.cold.Get(ctx, archiveKey(threadID))
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			a.logger.Warn("Failed to read archived conversation", zap.Error(err), zap.String("threadID", threadID))
+		}
+
+		return nil, false
+	}
+
+	var archived archivedConversation
+	if err := json.Unmarshal(payload, &archived); err != nil || archived.Version != archiveFormatVersion {
+		a.logger.Warn("Ignoring unreadable archived conversation", zap.Error(err), zap.String("threadID", threadID))
+
+		return nil, false
+	}
+	if err := a.unseal(ctx, &archived); err != nil {
+		a.logger.Warn("Ignoring archived conversation that cannot be decrypted", zap.Error(err), zap.String("threadID", threadID))
+
+		return nil, false
+	}
+
+	data := &MessagesCacheData{
+		Messages:     archived.Messages,
+		Model:        archived.Model,
+		Access:       ThreadAccess{Policy: archived.Policy, InitiatorID: archived.InitiatorID},
+		Character:    archived.Character,
+		Seed:         archived.Seed,
+		Pin:          archived.Pin,
+		Participants: archived.Participants,
+	}
+	a.store.Restore(threadID, data)
+
+	a.logger.Info("Rehydrated archived conversation", zap.String("threadID", threadID), zap.Int("messageCount", len(data.Messages)))
+
+	return data, true
+}
+
+// handleThreadUpdate archives a conversation as soon as its thread is archived.
+func (a *ConversationArchiver) handleThreadUpdate(e *gateway.ThreadUpdateEvent) {
+	if e.ThreadMetadata == nil || !e.ThreadMetadata.Archived {
+		return
+	}
+
+	if err := a.archive(context.Background(), e.GuildID, e.ID.String()); err != nil {
+		a.logger.Warn("Failed to archive conversation of archived thread", zap.Error(err), zap.String("threadID", e.ID.String()))
+	}
+}
+
+// ForgetInitiator deletes every archived conversation started by userID and
+// returns the affected thread IDs.
+func (a *ConversationArchiver) ForgetInitiator(ctx context.Context, userID discord.UserID) ([]string, error) {
+	return a.deleteWhere(ctx, func(archived *archivedConversation) bool {
+		return archived.InitiatorID == userID
+	})
+}
+
+// ForgetParticipant deletes every archived conversation userID posted in
+// without starting it and returns the affected thread IDs. Continuing one
+// reconstructs it from Discord.
+func (a *ConversationArchiver) ForgetParticipant(ctx context.Context, userID discord.UserID) ([]string, error) {
+	return a.deleteWhere(ctx, func(archived *archivedConversation) bool {
+		return archived.InitiatorID != userID && slices.Contains(archived.Participants, userID)
+	})
+}
+
+// PurgeBefore deletes archived conversations with no activity since cutoff
+// and returns how many were removed.
+func (a *ConversationArchiver) PurgeBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	purged, err := a.deleteWhere(ctx, func(archived *archivedConversation) bool {
+		return archived.lastActivity().Before(cutoff)
+	})
+
+	return len(purged), err
+}
+
+// deleteWhere deletes the archived conversations matching match and returns
+// their thread IDs. Unreadable archives are skipped.
+func (a *ConversationArchiver) deleteWhere(ctx context.Context, match func(archived *archivedConversation) bool) ([]string, error) {
+	if a == nil || a.cold == nil {
+		return nil, nil
+	}
+
+	keys, err := a.cold.List(ctx, archivePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archived conversations: %w", err)
+	}
+
+	var deleted []string
+	for _, key := range keys {
+		payload, err := a.cold.Get(ctx, key)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to read archived conversation %s: %w", key, err)
+		}
+
+		var archived archivedConversation
+		if err := json.Unmarshal(payload, &archived); err != nil || !match(&archived) {
+			continue
+		}
+
+		if err := a.cold.Delete(ctx, key); err != nil {
+			return deleted, fmt.Errorf("failed to delete archived conversation %s: %w", key, err)
+		}
+		deleted = append(deleted, archived.ThreadID)
+	}
+
+	return deleted, nil
+}
+
+// seal replaces the messages of c with their encrypted form.
+func (a *ConversationArchiver) seal(ctx context.Context, c *archivedConversation) error {
+	plaintext, err := json.Marshal(c.Messages)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation messages: %w", err)
+	}
+
+	sealed, err := a.sealer.Seal(ctx, c.GuildID.String(), plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt conversation: %w", err)
+	}
+	c.Messages = nil
+	c.SealedMessages = sealed
+
+	return nil
+}
+
+// unseal restores the messages of an encrypted archive. Unencrypted archives
+// are left unchanged, so enabling encryption keeps existing archives readable.
+func (a *ConversationArchiver) unseal(ctx context.Context, c *archivedConversation) error {
+	if len(c.SealedMessages) == 0 {
+		return nil
+	}
+	if a.sealer == nil {
+		return errors.New("conversation is encrypted but archive encryption is not configured")
+	}
+
+	plaintext, err := a.sealer.Open(ctx, c.GuildID.String(), c.SealedMessages)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(plaintext, &c.Messages); err != nil {
+		return fmt.Errorf("failed to decode conversation messages: %w", err)
+	}
+	c.SealedMessages = nil
+
+	return nil
+}
+
+// threadChannel fetches a thread from the Discord API.
+func (a *ConversationArchiver) threadChannel(threadID string) (*discord.Channel, error) {
+	sf, err := discord.ParseSnowflake(threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.ses.Channel(discord.ChannelID(sf))
+}
+
+const archivePrefix = "conversations/"
+
+func archiveKey(threadID string) string {
+	return archivePrefix + threadID + ".json"
+}
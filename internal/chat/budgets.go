@@ -0,0 +1,448 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/privacy"
+	"github.com/Raikerian/go-discord-chatgpt/internal/retention"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const (
+	defaultBudgetsFile      = "chat_budgets.json"
+	defaultBudgetLimitsFile = "chat_budget_limits.json"
+)
+
+// ErrBudgetsDisabled is returned when setting limits while no budget is set,
+// as budgets need a restart to be turned on.
+var ErrBudgetsDisabled = errors.New("budgets are not enabled, set a budget in config.yaml and restart")
+
+// BudgetExhaustedError is returned before calling OpenAI when the user or
+// guild making a request has used up a daily or monthly budget.
+type BudgetExhaustedError struct {
+	Guild    bool // The guild's budget rather than the user's
+	Monthly  bool // The monthly budget rather than the daily one
+	ResetsAt time.Time
+}
+
+func (e *BudgetExhaustedError) Error() string {
+	return fmt.Sprintf("%s %s budget exhausted until %s", e.scope(), e.period(), e.ResetsAt.Format(time.RFC3339))
+}
+
+func (e *BudgetExhaustedError) scope() string {
+	if e.Guild {
+		return "server"
+	}
+
+	return "user"
+}
+
+func (e *BudgetExhaustedError) period() string {
+	if e.Monthly {
+		return "monthly"
+	}
+
+	return "daily"
+}
+
+// budgetUsage is the usage of one user or guild in the current UTC day and
+// month. Usage from an earlier day or month is dropped when it is next read.
+type budgetUsage struct {
+	Day         string  `json:"day"` // 2006-01-02
+	DayTokens   int     `json:"day_tokens"`
+	DayUSD      float64 `json:"day_usd"`
+	Month       string  `json:"month"` // 2006-01
+	MonthTokens int     `json:"month_tokens"`
+	MonthUSD    float64 `json:"month_usd"`
+}
+
+// current returns u for the day and month of now, resetting the periods that
+// have ended.
+func (u budgetUsage) current(now time.Time) budgetUsage {
+	if day := now.Format(time.DateOnly); u.Day != day {
+		u.Day, u.DayTokens, u.DayUSD = day, 0, 0
+	}
+	if month := now.Format("2006-01"); u.Month != month {
+		u.Month, u.MonthTokens, u.MonthUSD = month, 0, 0
+	}
+
+	return u
+}
+
+// exhausted returns the error for the first of limits that u has reached.
+func (u budgetUsage) exhausted(limits config.BudgetLimits, guild bool, now time.Time) *BudgetExhaustedError {
+	switch {
+	case limits.DailyTokens > 0 && u.DayTokens >= limits.DailyTokens,
+		limits.DailyUSD > 0 && u.DayUSD >= limits.DailyUSD:
+		y, m, d := now.Date()
+
+		return &BudgetExhaustedError{Guild: guild, ResetsAt: time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)}
+	case limits.MonthlyTokens > 0 && u.MonthTokens >= limits.MonthlyTokens,
+		limits.MonthlyUSD > 0 && u.MonthUSD >= limits.MonthlyUSD:
+		y, m, _ := now.Date()
+
+		return &BudgetExhaustedError{Guild: guild, Monthly: true, ResetsAt: time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+
+	return nil
+}
+
+// Budgets tracks the tokens and estimated cost of AI replies per user and per
+// guild in a JSON document, and refuses requests once a daily or monthly budget
+// is used up. A nil *Budgets allows every request, as when no budget is set.
+type Budgets struct {
+	logger    *zap.Logger
+	configs   config.Provider
+	doc       storage.Document
+	limitsDoc storage.Document
+
+	mu     sync.Mutex
+	usage  map[string]budgetUsage               // key: "user:<id>" or "guild:<id>"
+	limits map[string]config.GuildBudgetsConfig // key: guild ID; set through the admin API
+}
+
+// BudgetUsage is the usage of one user or guild in the current UTC day and
+// month, as exported through the admin API.
+type BudgetUsage struct {
+	Scope       string  `json:"scope"` // "user" or "guild"
+	ID          string  `json:"id"`
+	Day         string  `json:"day"`
+	DayTokens   int     `json:"day_tokens"`
+	DayUSD      float64 `json:"day_usd"`
+	Month       string  `json:"month"`
+	MonthTokens int     `json:"month_tokens"`
+	MonthUSD    float64 `json:"month_usd"`
+}
+
+// NewBudgets creates the budgets configured, loading the usage recorded so
+// far. It returns nil when no budget is set globally, for any guild or with
+// adminctl. Reloaded limits apply to the next request; budgets set where
+// none were need a restart.
+func NewBudgets(logger *zap.Logger, configs config.Provider, state storage.Provider) (*Budgets, error) {
+	cfg := configs.Current()
+	limitsKey := cfg.Budgets.LimitsFile
+	if limitsKey == "" {
+		limitsKey = defaultBudgetLimitsFile
+	}
+	limitsDoc := storage.NewDocument(state, limitsKey)
+	limits := make(map[string]config.GuildBudgetsConfig)
+	if _, err := limitsDoc.Load(&limits); err != nil {
+		return nil, fmt.Errorf("failed to load budget limits: %w", err)
+	}
+
+	enabled := !cfg.Budgets.User.IsZero() || !cfg.Budgets.Guild.IsZero() || len(limits) > 0
+	for _, guild := range cfg.Guilds {
+		enabled = enabled || !guild.Budgets.User.IsZero() || !guild.Budgets.Guild.IsZero()
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	key := cfg.Budgets.File
+	if key == "" {
+		key = defaultBudgetsFile
+	}
+	b := &Budgets{
+		logger:    logger.Named("budgets"),
+		configs:   configs,
+		doc:       storage.NewDocument(state, key),
+		limitsDoc: limitsDoc,
+		usage:     make(map[string]budgetUsage),
+		limits:    limits,
+	}
+	if _, err := b.doc.Load(&b.usage); err != nil {
+		return nil, fmt.Errorf("failed to load budgets: %w", err)
+	}
+
+	return b, nil
+}
+
+func userBudgetKey(userID discord.UserID) string {
+	return "user:" + userID.String()
+}
+
+func guildBudgetKey(guildID discord.GuildID) string {
+	return "guild:" + guildID.String()
+}
+
+// Check returns a *BudgetExhaustedError when userID or guildID has used up a
+// budget that applies in guildID. A null userID or guildID is not checked.
+func (b *Budgets) Check(guildID discord.GuildID, userID discord.UserID) error {
+	if b == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	userLimits, guildLimits := b.limitsLocked(guildID)
+
+	var err *BudgetExhaustedError
+	if userID.IsValid() {
+		err = b.usage[userBudgetKey(userID)].current(now).exhausted(userLimits, false, now)
+	}
+	if err == nil && guildID.IsValid() {
+		err = b.usage[guildBudgetKey(guildID)].current(now).exhausted(guildLimits, true, now)
+	}
+	if err == nil {
+		return nil
+	}
+	b.logger.Info("Request refused, budget exhausted",
+		zap.String("guildID", guildID.String()),
+		zap.String("userID", userID.String()),
+		zap.String("budget", err.scope()+" "+err.period()),
+		zap.Time("resetsAt", err.ResetsAt))
+
+	return err
+}
+
+// Record adds tokens and costUSD to the usage of userID and guildID. A null
+// userID or guildID is not recorded.
+func (b *Budgets) Record(guildID discord.GuildID, userID discord.UserID, tokens int, costUSD float64) error {
+	if b == nil || tokens <= 0 && costUSD <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	var keys []string
+	if userID.IsValid() {
+		keys = append(keys, userBudgetKey(userID))
+	}
+	if guildID.IsValid() {
+		keys = append(keys, guildBudgetKey(guildID))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		u := b.usage[key].current(now)
+		u.DayTokens += tokens
+		u.MonthTokens += tokens
+		u.DayUSD += costUSD
+		u.MonthUSD += costUSD
+		b.usage[key] = u
+	}
+
+	return b.doc.Save(b.usage)
+}
+
+// Limits returns the user and guild budgets in effect in guildID: those set
+// with adminctl replace the configured ones.
+func (b *Budgets) Limits(guildID discord.GuildID) (user, guild config.BudgetLimits) {
+	if b == nil {
+		return config.BudgetLimits{}, config.BudgetLimits{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.limitsLocked(guildID)
+}
+
+func (b *Budgets) limitsLocked(guildID discord.GuildID) (user, guild config.BudgetLimits) {
+	user, guild = b.configs.Current().BudgetLimits(guildID.String())
+	set := b.limits[guildID.String()]
+
+	return user.Merge(set.User), guild.Merge(set.Guild)
+}
+
+// SetGuildLimits sets the budgets of guildID, replacing the configured limits
+// that limits sets until they are cleared with a zero value. It reports
+// whether guildID had limits set before.
+func (b *Budgets) SetGuildLimits(guildID discord.GuildID, limits config.GuildBudgetsConfig) (bool, error) {
+	if b == nil {
+		return false, ErrBudgetsDisabled
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := guildID.String()
+	_, existed := b.limits[key]
+	if limits.IsZero() {
+		delete(b.limits, key)
+	} else {
+		b.limits[key] = limits
+	}
+	if err := b.limitsDoc.Save(b.limits); err != nil {
+		return existed, err
+	}
+	b.logger.Info("Guild budget limits set",
+		zap.String("guildID", key),
+		zap.Bool("cleared", limits.IsZero()))
+
+	return existed, nil
+}
+
+// Usage returns the usage of every user and guild in the current UTC day
+// and month, guilds first.
+func (b *Budgets) Usage() []BudgetUsage {
+	if b == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usage := make([]BudgetUsage, 0, len(b.usage))
+	for key, u := range b.usage {
+		scope, id, ok := strings.Cut(key, ":")
+		if !ok {
+			continue
+		}
+		u = u.current(now)
+		usage = append(usage, BudgetUsage{
+			Scope:       scope,
+			ID:          id,
+			Day:         u.Day,
+			DayTokens:   u.DayTokens,
+			DayUSD:      u.DayUSD,
+			Month:       u.Month,
+			MonthTokens: u.MonthTokens,
+			MonthUSD:    u.MonthUSD,
+		})
+	}
+	slices.SortFunc(usage, func(a, b BudgetUsage) int {
+		if a.Scope != b.Scope {
+			return strings.Compare(a.Scope, b.Scope) // "guild" before "user"
+		}
+
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return usage
+}
+
+// forgetUser drops the usage recorded for userID, which also resets the
+// user's daily and monthly budgets, and returns how many records were removed.
+func (b *Budgets) forgetUser(userID discord.UserID) (int, error) {
+	if b == nil {
+		return 0, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := userBudgetKey(userID)
+	if _, ok := b.usage[key]; !ok {
+		return 0, nil
+	}
+	delete(b.usage, key)
+
+	return 1, b.doc.Save(b.usage)
+}
+
+// purgeBefore drops the usage of users and guilds last recorded on a UTC day
+// that ended before cutoff, and returns how many records were removed.
+func (b *Budgets) purgeBefore(cutoff time.Time) (int, error) {
+	if b == nil {
+		return 0, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	purged := 0
+	for key, u := range b.usage {
+		day, err := time.Parse(time.DateOnly, u.Day)
+		if err == nil && day.AddDate(0, 0, 1).After(cutoff) {
+			continue
+		}
+		delete(b.usage, key)
+		purged++
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+
+	return purged, b.doc.Save(b.usage)
+}
+
+// budgetEraser forgets the budget usage recorded for a user.
+type budgetEraser struct {
+	budgets *Budgets
+}
+
+// NewBudgetEraser creates the privacy.Eraser for budget usage.
+func NewBudgetEraser(budgets *Budgets) privacy.Eraser {
+	return &budgetEraser{budgets: budgets}
+}
+
+// Name implements privacy.Eraser.
+func (e *budgetEraser) Name() string {
+	return "budget usage"
+}
+
+// ForgetUser implements privacy.Eraser.
+func (e *budgetEraser) ForgetUser(_ context.Context, userID discord.UserID) (int, error) {
+	return e.budgets.forgetUser(userID)
+}
+
+// budgetPurger enforces the usage retention window on budget usage.
+type budgetPurger struct {
+	budgets *Budgets
+}
+
+// NewBudgetPurger creates the retention.Purger for budget usage.
+func NewBudgetPurger(budgets *Budgets) retention.Purger {
+	return &budgetPurger{budgets: budgets}
+}
+
+// DataType implements retention.Purger.
+func (p *budgetPurger) DataType() string {
+	return retention.Usage
+}
+
+// PurgeBefore implements retention.Purger. A window shorter than a month
+// also resets monthly budgets that were used early in the month.
+func (p *budgetPurger) PurgeBefore(_ context.Context, cutoff time.Time) (int, error) {
+	return p.budgets.purgeBefore(cutoff)
+}
+
+type requesterKey struct{}
+
+// withRequester attributes the AI requests made with ctx to userID's budget.
+func withRequester(ctx context.Context, userID discord.UserID) context.Context {
+	return context.WithValue(ctx, requesterKey{}, userID)
+}
+
+func requesterFrom(ctx context.Context) discord.UserID {
+	userID, _ := ctx.Value(requesterKey{}).(discord.UserID)
+
+	return userID
+}
+
+// recordBudgetUsage adds the tokens and estimated cost of calls to the
+// budgets of the requester of ctx and of guildID.
+func (s *Service) recordBudgetUsage(ctx context.Context, guildID discord.GuildID, calls []CallUsage) {
+	if s.budgets == nil || len(calls) == 0 {
+		return
+	}
+
+	tokens := 0
+	for _, call := range calls {
+		tokens += call.Usage.TotalTokens
+	}
+	cost, ok := turnCost(s.pricing, calls)
+	if !ok {
+		s.logger.Debug("Cannot price reply, budget usage is a lower bound", zap.String("guildID", guildID.String()))
+	}
+	if err := s.budgets.Record(guildID, requesterFrom(ctx), tokens, cost); err != nil {
+		s.logger.Error("Failed to record budget usage", zap.Error(err), zap.String("guildID", guildID.String()))
+	}
+}
@@ -0,0 +1,94 @@
+package chat_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func newBudgets(t *testing.T, cfg *config.Config) *chat.Budgets {
+	t.Helper()
+	b, err := chat.NewBudgets(zap.NewNop(), config.NewStaticProvider(cfg), storage.NewFileProvider(""))
+	require.NoError(t, err)
+	require.NotNil(t, b)
+
+	return b
+}
+
+func TestBudgets(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Budgets = config.BudgetsConfig{
+		File:  filepath.Join(t.TempDir(), "budgets.json"),
+		User:  config.BudgetLimits{DailyTokens: 1000},
+		Guild: config.BudgetLimits{MonthlyUSD: 1},
+	}
+	cfg.Guilds = map[string]config.GuildConfig{
+		"2": {Budgets: config.GuildBudgetsConfig{User: config.BudgetLimits{DailyTokens: 5000}}},
+	}
+	b := newBudgets(t, cfg)
+
+	require.NoError(t, b.Check(1, 10))
+	require.NoError(t, b.Record(1, 10, 1200, 0.01))
+
+	var exhausted *chat.BudgetExhaustedError
+	require.ErrorAs(t, b.Check(1, 10), &exhausted)
+	assert.False(t, exhausted.Guild)
+	assert.False(t, exhausted.Monthly)
+	assert.True(t, exhausted.ResetsAt.After(time.Now()), "daily budgets reset at the next UTC midnight")
+	assert.Equal(t, exhausted.ResetsAt, exhausted.ResetsAt.Truncate(24*time.Hour))
+	require.NoError(t, b.Check(1, 11), "other users keep their budget")
+	require.NoError(t, b.Check(2, 10), "the guild's own user budget applies in it")
+
+	// The guild's spend is shared by its users and survives a restart
+	require.NoError(t, b.Record(1, 11, 10, 0.99))
+	require.ErrorAs(t, newBudgets(t, cfg).Check(1, 12), &exhausted)
+	assert.True(t, exhausted.Guild)
+	assert.True(t, exhausted.Monthly)
+}
+
+func TestBudgets_EraseAndPurge(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Budgets = config.BudgetsConfig{
+		File: filepath.Join(t.TempDir(), "budgets.json"),
+		User: config.BudgetLimits{DailyTokens: 1000},
+	}
+	b := newBudgets(t, cfg)
+	require.NoError(t, b.Record(1, 10, 1200, 0))
+	require.NoError(t, b.Record(1, 11, 10, 0))
+
+	eraser := chat.NewBudgetEraser(b)
+	removed, err := eraser.ForgetUser(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	require.NoError(t, newBudgets(t, cfg).Check(1, 10), "forgetting the usage resets the budget")
+	removed, err = eraser.ForgetUser(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+
+	purger := chat.NewBudgetPurger(b)
+	purged, err := purger.PurgeBefore(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, purged, "today's usage is kept")
+	purged, err = purger.PurgeBefore(context.Background(), time.Now().Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged, "the user's and the guild's usage")
+	assert.Empty(t, b.Usage())
+}
+
+func TestBudgets_Disabled(t *testing.T) {
+	b, err := chat.NewBudgets(zap.NewNop(), config.NewStaticProvider(&config.Config{}), storage.NewFileProvider(""))
+	require.NoError(t, err)
+	assert.Nil(t, b)
+
+	require.NoError(t, b.Record(1, 10, 1000, 1))
+	require.NoError(t, b.Check(1, 10))
+}
@@ -2,6 +2,9 @@
 package chat
 
 import (
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/sashabaranov/go-openai"
 )
@@ -13,6 +16,12 @@ type MessagesCacheData struct {
 	Model         string
 	Temperature   *float32
 	TokenCount    int
+	Access        ThreadAccess
+	Character     string           // Name of the persona replying in the thread, empty for the bot itself
+	Seed          *int             // Sampling seed chosen with /chat, nil when unset
+	Pin           *ModelPin        // Snapshot and parameters replies use, nil until the next reply pins them
+	UpdatedAt     time.Time        // Last time the conversation was stored or changed
+	Participants  []discord.UserID // Users who posted in the conversation, so that they can be forgotten
 }
 
 // NewMessagesCache creates a new LRU cache for chat messages with the given size.
@@ -28,6 +37,18 @@ func NewMessagesCache(size int) *lru.Cache[string, *MessagesCacheData] {
 	return lruCache
 }
 
+// NewMessagesCacheWithEvict is like NewMessagesCache, calling onEvict with
+// every conversation that is removed or pushed out of the cache.
+func NewMessagesCacheWithEvict(size int, onEvict func(threadID string, data *MessagesCacheData)) *lru.Cache[string, *MessagesCacheData] {
+	lruCache, err := lru.NewWithEvict(size, onEvict)
+	if err != nil {
+		// As in NewMessagesCache, only an invalid size fails
+		panic(err)
+	}
+
+	return lruCache
+}
+
 // NewNegativeThreadCache creates a new LRU cache for thread IDs that should be ignored.
 // The size parameter determines the maximum number of items the cache can hold.
 func NewNegativeThreadCache(size int) *lru.Cache[string, bool] {
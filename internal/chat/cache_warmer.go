@@ -0,0 +1,212 @@
+package chat
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultWarmupMaxThreads = 25
+	defaultWarmupInterval   = time.Second
+	// warmupStartupDelay gives the gateway time to settle before the first run.
+	warmupStartupDelay = 15 * time.Second
+)
+
+// CacheWarmer reconstructs the conversation cache for the most recently active
+// bot-owned threads on startup and, optionally, every night.
+type CacheWarmer struct {
+	logger *zap.Logger
+	cfg    config.CacheWarmupConfig
+	ses    *session.Session
+	store  ConversationStore
+
+	guildIDs []discord.GuildID
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewCacheWarmer creates a CacheWarmer. It does nothing until Start is called.
+func NewCacheWarmer(logger *zap.Logger, cfg *config.Config, ses *session.Session, store ConversationStore) *CacheWarmer {
+	w := &CacheWarmer{
+		logger: logger.Named("cache_warmer"),
+		cfg:    cfg.OpenAI.CacheWarmup,
+		ses:    ses,
+		store:  store,
+	}
+	if w.cfg.MaxThreads <= 0 {
+		w.cfg.MaxThreads = defaultWarmupMaxThreads
+	}
+	if h := w.cfg.NightlyHour; h != nil && (*h < 0 || *h > 23) {
+		w.logger.Warn("Ignoring invalid cache warm-up nightly_hour, warming only on startup", zap.Int("nightlyHour", *h))
+		w.cfg.NightlyHour = nil
+	}
+
+	for _, idStr := range cfg.Discord.GuildIDs {
+		sf, err := discord.ParseSnowflake(idStr)
+		if err != nil {
+			continue
+		}
+		w.guildIDs = append(w.guildIDs, discord.GuildID(sf))
+	}
+
+	return w
+}
+
+// Start launches the warm-up schedule in the background if it is enabled.
+func (w *CacheWarmer) Start() {
+	if !w.cfg.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run(ctx)
+	}()
+}
+
+// Stop cancels any running warm-up and waits for it to finish.
+func (w *CacheWarmer) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *CacheWarmer) run(ctx context.Context) {
+	next := time.Now().Add(warmupStartupDelay)
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+
+		warmed, err := w.Warm(ctx)
+		if err != nil {
+			w.logger.Warn("Conversation cache warm-up failed", zap.Error(err))
+		} else {
+			w.logger.Info("Conversation cache warm-up finished", zap.Int("warmedThreads", warmed))
+		}
+
+		if w.cfg.NightlyHour == nil {
+			return
+		}
+		next = NextWarmupRun(time.Now(), *w.cfg.NightlyHour)
+		w.logger.Debug("Scheduled next conversation cache warm-up", zap.Time("at", next))
+	}
+}
+
+// Warm reconstructs the cache for up to MaxThreads recently active managed
+// threads that are not cached yet, and returns how many were warmed.
+func (w *CacheWarmer) Warm(ctx context.Context) (int, error) {
+	self, err := w.ses.Me()
+	if err != nil {
+		return 0, err
+	}
+	botDisplayName := GetUserDisplayName(self)
+
+	guildIDs := w.guildIDs
+	if len(guildIDs) == 0 {
+		guilds, err := w.ses.Guilds(0)
+		if err != nil {
+			return 0, err
+		}
+		for _, g := range guilds {
+			guildIDs = append(guildIDs, g.ID)
+		}
+	}
+
+	var threads []discord.Channel
+	for _, guildID := range guildIDs {
+		active, err := w.ses.ActiveThreads(guildID)
+		if err != nil {
+			w.logger.Warn("Failed to list active threads for warm-up", zap.Error(err), zap.String("guildID", guildID.String()))
+
+			continue
+		}
+		threads = append(threads, active.Threads...)
+	}
+
+	interval := defaultWarmupInterval
+	if w.cfg.IntervalMS > 0 {
+		interval = time.Duration(w.cfg.IntervalMS) * time.Millisecond
+	}
+
+	warmed := 0
+	for _, thread := range RecentManagedThreads(threads, self.ID, w.cfg.MaxThreads) {
+		threadIDStr := thread.ID.String()
+		if _, found := w.store.GetConversation(threadIDStr); found || w.store.IsInNegativeCache(threadIDStr) {
+			continue
+		}
+
+		if warmed > 0 {
+			select {
+			case <-ctx.Done():
+				return warmed, ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+
+		data, _, err := w.store.ReconstructAndCache(ctx, w.ses, thread.ID, 0, self, botDisplayName, SanitizeOpenAIName, GetUserDisplayName)
+		if err != nil {
+			w.logger.Warn("Failed to warm conversation cache for thread", zap.Error(err), zap.String("threadID", threadIDStr))
+
+			continue
+		}
+		if data == nil {
+			w.store.AddToNegativeCache(threadIDStr)
+
+			continue
+		}
+		warmed++
+	}
+
+	return warmed, nil
+}
+
+// RecentManagedThreads returns up to limit threads created by ownerID, most
+// recently active first.
+func RecentManagedThreads(threads []discord.Channel, ownerID discord.UserID, limit int) []discord.Channel {
+	managed := make([]discord.Channel, 0, len(threads))
+	for _, th := range threads {
+		if th.OwnerID == ownerID {
+			managed = append(managed, th)
+		}
+	}
+
+	// Message IDs are snowflakes, so they sort by time
+	sort.Slice(managed, func(i, j int) bool { return managed[i].LastMessageID > managed[j].LastMessageID })
+	if len(managed) > limit {
+		managed = managed[:limit]
+	}
+
+	return managed
+}
+
+// NextWarmupRun returns the next time after now at the given UTC hour.
+func NextWarmupRun(now time.Time, hour int) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next
+}
@@ -0,0 +1,41 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+func TestRecentManagedThreads(t *testing.T) {
+	const bot = discord.UserID(1)
+	threads := []discord.Channel{
+		{ID: 10, OwnerID: bot, LastMessageID: 100},
+		{ID: 11, OwnerID: 2, LastMessageID: 500},
+		{ID: 12, OwnerID: bot, LastMessageID: 300},
+		{ID: 13, OwnerID: bot, LastMessageID: 200},
+	}
+
+	recent := chat.RecentManagedThreads(threads, bot, 2)
+	require.Len(t, recent, 2)
+	assert.Equal(t, discord.ChannelID(12), recent[0].ID)
+	assert.Equal(t, discord.ChannelID(13), recent[1].ID)
+
+	assert.Len(t, chat.RecentManagedThreads(threads, bot, 10), 3)
+}
+
+func TestNextWarmupRun(t *testing.T) {
+	now := time.Date(2025, 6, 1, 3, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, 6, 1, 4, 0, 0, 0, time.UTC), chat.NextWarmupRun(now, 4))
+	assert.Equal(t, time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC), chat.NextWarmupRun(now, 3))
+	assert.Equal(t, time.Date(2025, 6, 2, 1, 0, 0, 0, time.UTC), chat.NextWarmupRun(now, 1))
+
+	// Local times are converted to UTC first
+	local := now.In(time.FixedZone("UTC+9", 9*60*60))
+	assert.Equal(t, time.Date(2025, 6, 1, 4, 0, 0, 0, time.UTC), chat.NextWarmupRun(local, 4))
+}
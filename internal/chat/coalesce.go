@@ -0,0 +1,101 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+)
+
+// pendingTurn is a user message still waiting for its reply.
+type pendingTurn struct {
+	authorID discord.UserID
+	at       time.Time
+}
+
+// coalesceWindow returns how long a thread message waits for follow-ups from
+// the same user before it is sent, or 0 when coalescing is off.
+func (s *Service) coalesceWindow() time.Duration {
+	if s.cfg.OpenAI.CoalesceWindowMS <= 0 {
+		return 0
+	}
+
+	return time.Duration(s.cfg.OpenAI.CoalesceWindowMS) * time.Millisecond
+}
+
+// addUserTurn appends turn to history, or merges it into the last turn when
+// that is an unanswered message of the same author sent within the coalescing
+// window. It records turn as the thread's pending turn.
+func (s *Service) addUserTurn(threadID discord.ChannelID, authorID discord.UserID, history []openai.ChatCompletionMessage, turn openai.ChatCompletionMessage) ([]openai.ChatCompletionMessage, bool) {
+	now := time.Now()
+	previous, loaded := s.pendingTurns.Swap(threadID, pendingTurn{authorID: authorID, at: now})
+
+	messages := make([]openai.ChatCompletionMessage, len(history), len(history)+1)
+	copy(messages, history)
+
+	window := s.coalesceWindow()
+	last := len(messages) - 1
+	if window > 0 && loaded && last >= 0 && messages[last].Role == openai.ChatMessageRoleUser {
+		if p, ok := previous.(pendingTurn); ok && p.authorID == authorID && now.Sub(p.at) <= window {
+			messages[last] = MergeUserTurns(messages[last], turn)
+
+			return messages, true
+		}
+	}
+
+	return append(messages, turn), false
+}
+
+// waitForFollowUps holds a thread message for the coalescing window. It
+// returns false when a newer message canceled ctx in the meantime.
+func (s *Service) waitForFollowUps(ctx context.Context) bool {
+	window := s.coalesceWindow()
+	if window == 0 {
+		return true
+	}
+
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// MergeUserTurns combines two consecutive user messages into one, joining
+// their text with a newline and keeping the images of both, up to the
+// per-message image limit.
+func MergeUserTurns(first, second openai.ChatCompletionMessage) openai.ChatCompletionMessage {
+	merged := openai.ChatCompletionMessage{Role: first.Role, Name: first.Name}
+
+	var texts []string
+	var images []openai.ChatMessagePart
+	for _, msg := range []openai.ChatCompletionMessage{first, second} {
+		if text := messageText(msg); strings.TrimSpace(text) != "" {
+			texts = append(texts, text)
+		}
+		for _, part := range msg.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeImageURL && len(images) < maxImageAttachments {
+				images = append(images, part)
+			}
+		}
+	}
+
+	text := strings.Join(texts, "\n")
+	if len(images) == 0 {
+		merged.Content = text
+
+		return merged
+	}
+
+	if text != "" {
+		merged.MultiContent = append(merged.MultiContent, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: text})
+	}
+	merged.MultiContent = append(merged.MultiContent, images...)
+
+	return merged
+}
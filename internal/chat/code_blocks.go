@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const codeFence = "```"
+
+// languageRule detects a language from the text of a code block.
+type languageRule struct {
+	language string
+	pattern  *regexp.Regexp
+}
+
+// languageRules are tried in order, so the more distinctive markers of a
+// language come before the generic ones that several languages share.
+var languageRules = []languageRule{
+	{"diff", regexp.MustCompile(`(?m)^(@@ .* @@|\+\+\+ |--- a/|diff --git )`)},
+	{"bash", regexp.MustCompile(`^#!.*\b(ba|z)?sh\b`)},
+	{"python", regexp.MustCompile(`^#!.*\bpython`)},
+	{"php", regexp.MustCompile(`<\?php`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$|^func (\(\w+ \*?\w+\) )?\w+\(|:= `)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(fn \w+\(|let mut |impl\b|use std::|pub fn )`)},
+	{"java", regexp.MustCompile(`(?m)^\s*(public (static )?(class|void|final)|System\.out\.)`)},
+	{"csharp", regexp.MustCompile(`(?m)^\s*(using System|namespace \w+|Console\.Write)`)},
+	{"cpp", regexp.MustCompile(`(?m)^\s*(#include\s*<(iostream|vector|string)>|std::)`)},
+	{"c", regexp.MustCompile(`(?m)^\s*#include\s*[<"]`)},
+	{"dockerfile", regexp.MustCompile(`(?m)^FROM \S+(\n|$)(.*\n)*?^(RUN|COPY|CMD|ENTRYPOINT) `)},
+	{"python", regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|class \w+(\(.*\))?:|from [\w.]+ import |import \w+$|if __name__ == )`)},
+	{"typescript", regexp.MustCompile(`(?m)^\s*(interface \w+ \{|type \w+ = |export (interface|type) )|: (string|number|boolean)\b`)},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(const|let|var) \w+ = |\bfunction\s*\w*\(|=> \{|console\.log\(|require\(`)},
+	{"html", regexp.MustCompile(`(?mi)^\s*<(!doctype|html|head|body|div|span|p|a|ul|li|script|style)[\s>]`)},
+	{"css", regexp.MustCompile(`(?m)^\s*[.#]?[\w-]+[^{\n]*\{\s*\n\s*[\w-]+\s*:\s*[^;\n]+;`)},
+	{"sql", regexp.MustCompile(`(?mi)^\s*(select .+ from |insert into |create (table|index) |update \w+ set |delete from )`)},
+	{"bash", regexp.MustCompile(`(?m)^\s*(\$ |sudo |apt(-get)? |brew |npm |pip |go (get|install|run|build) |git |cd |echo |export \w+=|curl |docker )`)},
+	{"yaml", regexp.MustCompile(`(?m)^[\w-]+:( .*)?\n(\s+-? ?[\w-]+:.*\n?|\s+- .*\n?)+`)},
+}
+
+// AddCodeLanguageHints labels the code blocks of content whose opening fence
+// has no language with the language detected from their text, so that Discord
+// highlights them. Blocks whose language cannot be told are left as they are.
+func AddCodeLanguageHints(content string) string {
+	if !strings.Contains(content, codeFence) {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(trimmed, codeFence) {
+			continue
+		}
+
+		// Find the closing fence; an unclosed block runs to the end
+		end := i + 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != codeFence {
+			end++
+		}
+		if trimmed == codeFence {
+			if language := DetectCodeLanguage(strings.Join(lines[i+1:min(end, len(lines))], "\n")); language != "" {
+				lines[i] += language
+			}
+		}
+		i = end
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// DetectCodeLanguage guesses the language of code for a fence info string.
+// It returns an empty string when it cannot tell.
+func DetectCodeLanguage(code string) string {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ""
+	}
+	if (strings.HasPrefix(code, "{") || strings.HasPrefix(code, "[")) && json.Valid([]byte(code)) {
+		return "json"
+	}
+	for _, rule := range languageRules {
+		if rule.pattern.MatchString(code) {
+			return rule.language
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,50 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+func TestDetectCodeLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"go", "package main\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}", "go"},
+		{"go snippet", "x := compute()\nif x > 1 {\n}", "go"},
+		{"python", "def greet(name):\n    print(f\"hi {name}\")", "python"},
+		{"javascript", "const total = items.reduce((a, b) => a + b, 0);\nconsole.log(total);", "javascript"},
+		{"typescript", "interface User {\n  name: string;\n}", "typescript"},
+		{"rust", "fn main() {\n    let mut v = Vec::new();\n}", "rust"},
+		{"java", "public class Main {\n    public static void main(String[] args) {}\n}", "java"},
+		{"c", "#include \"stdio.h\"\nint main(void) { return 0; }", "c"},
+		{"sql", "SELECT id, name FROM users WHERE active = 1;", "sql"},
+		{"json", "{\"name\": \"bot\", \"version\": 2}", "json"},
+		{"bash", "$ go build ./...\n$ ./bot", "bash"},
+		{"shebang", "#!/usr/bin/env bash\nset -e", "bash"},
+		{"yaml", "openai:\n  models:\n    - gpt-4o", "yaml"},
+		{"html", "<div class=\"card\">\n  <p>Hello</p>\n</div>", "html"},
+		{"css", ".card {\n  color: red;\n}", "css"},
+		{"diff", "--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@", "diff"},
+		{"prose", "Just some words in a box.", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, chat.DetectCodeLanguage(tt.code))
+		})
+	}
+}
+
+func TestAddCodeLanguageHints(t *testing.T) {
+	in := "Try this:\n```\nSELECT * FROM users;\n```\nor in Go:\n```go\nx := 1\n```\nand:\n```\nno idea what this is\n```"
+	want := "Try this:\n```sql\nSELECT * FROM users;\n```\nor in Go:\n```go\nx := 1\n```\nand:\n```\nno idea what this is\n```"
+
+	assert.Equal(t, want, chat.AddCodeLanguageHints(in))
+	assert.Equal(t, "no code here", chat.AddCodeLanguageHints("no code here"))
+	assert.Equal(t, "cut off:\n```python\ndef f():", chat.AddCodeLanguageHints("cut off:\n```\ndef f():"), "unclosed blocks are labelled too")
+}
@@ -0,0 +1,353 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// What to do with requests longer than the model's context window.
+const (
+	ContextOverflowTrim      = "trim"
+	ContextOverflowReject    = "reject"
+	ContextOverflowSummarize = "summarize"
+)
+
+const (
+	// Token counts of a request beyond its text, counted with CountTokens.
+	messageTokens      = 4   // Role and formatting overhead of every message
+	requestTokens      = 3   // Priming of the reply
+	imageTokenEstimate = 765 // A high detail image of about 1024x1024
+	// defaultReplyShare is the fraction of the context window kept free for
+	// the reply when no reply token limit is configured.
+	defaultReplyShare = 8
+	// contextSummaryCacheSize bounds the threads whose summary of their
+	// oldest turns is kept for later requests.
+	contextSummaryCacheSize = 256
+)
+
+// StepContextSummary is the call that summarizes the oldest turns of a
+// conversation outgrowing the context window, as it appears in usage footers.
+const StepContextSummary = "context summary"
+
+// ContextTooLongError is returned before calling OpenAI when a request does
+// not fit the model's context window and cannot be trimmed to fit.
+type ContextTooLongError struct {
+	Model       string
+	Tokens      int // Estimated tokens of the request
+	ContextSize int
+}
+
+func (e *ContextTooLongError) Error() string {
+	return fmt.Sprintf("request of about %d tokens exceeds the %d token context of %s", e.Tokens, e.ContextSize, e.Model)
+}
+
+// userErrorMessage returns what to tell the user about err, or fallback for
+// errors without a specific explanation.
+func userErrorMessage(err error, fallback string) string {
+	var tooLong *ContextTooLongError
+	if errors.As(err, &tooLong) {
+		return fmt.Sprintf("Sorry, this is too long for %s: it needs about %d tokens, but the model reads at most %d. "+
+			"Try a shorter message or start a new conversation with /chat.", tooLong.Model, tooLong.Tokens, tooLong.ContextSize)
+	}
+	var exhausted *BudgetExhaustedError
+	if errors.As(err, &exhausted) {
+		whose := "you have used up your"
+		if exhausted.Guild {
+			whose = "this server has used up its"
+		}
+
+		return fmt.Sprintf("Sorry, %s %s budget for AI replies. It resets <t:%d:R>.",
+			whose, exhausted.period(), exhausted.ResetsAt.Unix())
+	}
+	var unavailable *internalopenai.UnavailableError
+	if errors.As(err, &unavailable) {
+		return unavailable.UserMessage()
+	}
+
+	return fallback
+}
+
+// EstimateTokens estimates the prompt tokens of messages sent to model,
+// counting their text with CountTokens and their images and formatting with
+// fixed estimates.
+func EstimateTokens(model string, messages []openai.ChatCompletionMessage) int {
+	tokens := requestTokens
+	for _, msg := range messages {
+		tokens += estimateMessageTokens(model, msg)
+	}
+
+	return tokens
+}
+
+func estimateMessageTokens(model string, msg openai.ChatCompletionMessage) int {
+	tokens := messageTokens + CountTokens(model, msg.Content) + CountTokens(model, msg.Name)
+	for _, part := range msg.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeImageURL {
+			tokens += imageTokenEstimate
+		} else {
+			tokens += CountTokens(model, part.Text)
+		}
+	}
+	for _, call := range msg.ToolCalls {
+		tokens += CountTokens(model, call.Function.Name) + CountTokens(model, call.Function.Arguments)
+	}
+
+	return tokens
+}
+
+// FitContext drops the oldest turns of messages sent to model until their
+// estimate is within budget tokens. Leading system messages and the last
+// message are always kept. It reports whether the result fits.
+func FitContext(model string, messages []openai.ChatCompletionMessage, budget int) ([]openai.ChatCompletionMessage, bool) {
+	total := EstimateTokens(model, messages)
+	if total <= budget {
+		return messages, true
+	}
+
+	start := 0
+	for start < len(messages)-1 && messages[start].Role == openai.ChatMessageRoleSystem {
+		start++
+	}
+
+	drop := start
+	for drop < len(messages)-1 && total > budget {
+		total -= estimateMessageTokens(model, messages[drop])
+		drop++
+	}
+	// Tool results cannot lead the history without the call they answer
+	for drop < len(messages)-1 && messages[drop].Role == openai.ChatMessageRoleTool {
+		total -= estimateMessageTokens(model, messages[drop])
+		drop++
+	}
+
+	fitted := make([]openai.ChatCompletionMessage, 0, start+len(messages)-drop)
+	fitted = append(fitted, messages[:start]...)
+	fitted = append(fitted, messages[drop:]...)
+
+	return fitted, total <= budget
+}
+
+// FitContextPinned fits messages like FitContext, keeping the pinned answers
+// among the turns it drops in a system message after the leading system
+// messages. pinned are the texts of the answers, newest first.
+func FitContextPinned(model string, messages []openai.ChatCompletionMessage, pinned []string, budget int) ([]openai.ChatCompletionMessage, bool) {
+	if len(pinned) == 0 || EstimateTokens(model, messages) <= budget {
+		return FitContext(model, messages, budget)
+	}
+
+	// Room for every answer is kept, so the ones dropped always fit
+	fitted, ok := FitContext(model, messages, budget-estimateMessageTokens(model, pinnedAnswersMessage(pinned)))
+	if !ok {
+		return FitContext(model, messages, budget)
+	}
+	var dropped []string
+	for _, answer := range pinned {
+		if !slices.ContainsFunc(fitted, func(msg openai.ChatCompletionMessage) bool {
+			return msg.Role == openai.ChatMessageRoleAssistant && strings.Contains(msg.Content, answer)
+		}) {
+			dropped = append(dropped, answer)
+		}
+	}
+	if len(dropped) == 0 {
+		return FitContext(model, messages, budget)
+	}
+
+	start := 0
+	for start < len(fitted)-1 && fitted[start].Role == openai.ChatMessageRoleSystem {
+		start++
+	}
+
+	return slices.Insert(fitted, start, pinnedAnswersMessage(dropped)), true
+}
+
+// ContextWindowManager fits requests to the context window of their model
+// before they are sent, so long threads keep working instead of failing at
+// OpenAI.
+type ContextWindowManager interface {
+	// Fit returns messages fitted to the context window of model, trimming
+	// the oldest turns, summarizing them or rejecting the request as
+	// configured for guildID. Trimming keeps the answers pinned in the
+	// thread. It also returns the calls made to summarize.
+	// Summaries are reused by later requests of threadID, which may be
+	// discord.NullChannelID for requests outside threads.
+	Fit(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, model string, messages []openai.ChatCompletionMessage) ([]openai.ChatCompletionMessage, []CallUsage, error)
+}
+
+// contextSummary stands in for the oldest turns of a thread's history.
+type contextSummary struct {
+	covered int    // Turns after the leading system messages it summarizes
+	digest  uint64 // Digest of those turns, to tell when the history changed
+	text    string
+}
+
+type contextWindowManager struct {
+	logger     *zap.Logger
+	cfg        *config.Config
+	pricing    pkgopenai.PricingService
+	aiProvider AIProvider
+	summaries  *lru.Cache[discord.ChannelID, contextSummary]
+}
+
+// NewContextWindowManager creates a ContextWindowManager using the context
+// sizes of pricing and summarizing with aiProvider.
+func NewContextWindowManager(logger *zap.Logger, cfg *config.Config, pricing pkgopenai.PricingService, aiProvider AIProvider) ContextWindowManager {
+	summaries, err := lru.New[discord.ChannelID, contextSummary](contextSummaryCacheSize)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create context summary cache: %v", err))
+	}
+
+	return &contextWindowManager{
+		logger:     logger.Named("context_window"),
+		cfg:        cfg,
+		pricing:    pricing,
+		aiProvider: aiProvider,
+		summaries:  summaries,
+	}
+}
+
+// Fit checks messages against the context window of model. Models without a
+// known context size are not checked.
+func (m *contextWindowManager) Fit(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, model string, messages []openai.ChatCompletionMessage) ([]openai.ChatCompletionMessage, []CallUsage, error) {
+	contextSize, err := m.pricing.GetContextSize(model)
+	if err != nil || contextSize <= 0 {
+		return messages, nil, nil
+	}
+
+	limits := m.cfg.Limits(guildID.String())
+	reserve := limits.MaxResponseTokens
+	if reserve <= 0 || reserve >= contextSize {
+		reserve = contextSize / defaultReplyShare
+	}
+	budget := contextSize - reserve
+
+	tokens := EstimateTokens(model, messages)
+	if tokens <= budget {
+		return messages, nil, nil
+	}
+	tooLong := &ContextTooLongError{Model: model, Tokens: tokens, ContextSize: contextSize}
+	var fitted []openai.ChatCompletionMessage
+	var calls []CallUsage
+	switch {
+	case strings.EqualFold(limits.ContextOverflow, ContextOverflowReject):
+		return nil, nil, tooLong
+	case strings.EqualFold(limits.ContextOverflow, ContextOverflowSummarize):
+		fitted, calls = m.summarizeOldest(ctx, threadID, model, messages, budget)
+	}
+	if fitted == nil {
+		var ok bool
+		if fitted, ok = FitContextPinned(model, messages, pinnedAnswersFrom(ctx), budget); !ok {
+			return nil, calls, tooLong
+		}
+	}
+	if EstimateTokens(model, fitted) > budget {
+		return nil, calls, tooLong
+	}
+	m.logger.Info("Fitted conversation to the model's context window",
+		zap.String("model", model),
+		zap.String("threadID", threadID.String()),
+		zap.Int("estimatedTokens", tokens),
+		zap.Int("contextSize", contextSize),
+		zap.Int("messages", len(messages)),
+		zap.Int("fittedMessages", len(fitted)))
+
+	return fitted, calls, nil
+}
+
+// summarizeOldest replaces the oldest turns of messages with a summary so
+// the rest fit within budget. The turns summarized leave room for the next
+// few requests, which reuse the summary kept for threadID until the history
+// outgrows it again. It returns nil messages when there is nothing to
+// summarize or summarizing fails, leaving the turns to be trimmed.
+func (m *contextWindowManager) summarizeOldest(ctx context.Context, threadID discord.ChannelID, model string, messages []openai.ChatCompletionMessage, budget int) ([]openai.ChatCompletionMessage, []CallUsage) {
+	start := 0
+	for start < len(messages)-1 && messages[start].Role == openai.ChatMessageRoleSystem {
+		start++
+	}
+	turns := messages[start:]
+
+	var previous contextSummary
+	if threadID.IsValid() {
+		if summary, ok := m.summaries.Get(threadID); ok && summary.covered < len(turns) && summary.digest == digestMessages(turns[:summary.covered]) {
+			previous = summary
+			if fitted := withContextSummary(messages[:start], summary.text, turns[summary.covered:]); EstimateTokens(model, fitted) <= budget {
+				return fitted, nil
+			}
+		}
+	}
+
+	// Keep the latest turns within half the budget, so the summary lasts
+	kept, _ := FitContext(model, turns, budget/2)
+	covered := len(turns) - len(kept)
+	if covered <= previous.covered {
+		return nil, nil
+	}
+
+	// A summary being extended is summarized along with the turns after it
+	input := make([]openai.ChatCompletionMessage, 0, covered-previous.covered+1)
+	if previous.text != "" {
+		input = append(input, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: summarySystemPrefix + previous.text})
+	}
+	input = append(input, turns[previous.covered:covered]...)
+	input, _ = FitContext(model, input, budget)
+
+	resp, err := summarizeMessages(ctx, m.aiProvider, model, input)
+	if err != nil || len(resp.Choices) == 0 {
+		m.logger.Warn("Failed to summarize the oldest turns, trimming them instead",
+			zap.String("threadID", threadID.String()),
+			zap.Error(err))
+
+		return nil, nil
+	}
+	summary := contextSummary{
+		covered: covered,
+		digest:  digestMessages(turns[:covered]),
+		text:    strings.TrimSpace(resp.Choices[0].Message.Content),
+	}
+	if threadID.IsValid() {
+		m.summaries.Add(threadID, summary)
+	}
+
+	return withContextSummary(messages[:start], summary.text, turns[covered:]), []CallUsage{{Step: StepContextSummary, Model: model, Usage: resp.Usage}}
+}
+
+// withContextSummary returns the system messages, a summary of the turns
+// left out and the turns kept.
+func withContextSummary(system []openai.ChatCompletionMessage, summary string, turns []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	fitted := make([]openai.ChatCompletionMessage, 0, len(system)+1+len(turns))
+	fitted = append(fitted, system...)
+	fitted = append(fitted, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: summarySystemPrefix + summary})
+
+	return append(fitted, turns...)
+}
+
+// digestMessages hashes the content of messages.
+func digestMessages(messages []openai.ChatCompletionMessage) uint64 {
+	h := fnv.New64a()
+	for _, msg := range messages {
+		_, _ = h.Write([]byte(msg.Role + "\x00" + msg.Name + "\x00" + msg.Content + "\x00" + msg.ToolCallID + "\x00"))
+		for _, part := range msg.MultiContent {
+			_, _ = h.Write([]byte(part.Text + "\x00"))
+			if part.ImageURL != nil {
+				_, _ = h.Write([]byte(part.ImageURL.URL + "\x00"))
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			_, _ = h.Write([]byte(call.ID + "\x00" + call.Function.Name + "\x00" + call.Function.Arguments + "\x00"))
+		}
+	}
+
+	return h.Sum64()
+}
@@ -0,0 +1,173 @@
+package chat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/pkg/test"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	text := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: strings.Repeat(" word", 100)}}
+	image := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{
+		{Type: openai.ChatMessagePartTypeText, Text: strings.Repeat(" word", 100)},
+		{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "https://cdn.example/a.png"}},
+	}}}
+
+	assert.Equal(t, 107, chat.EstimateTokens("gpt-4o", text), "a token per word, with the request and message overhead")
+	assert.Greater(t, chat.EstimateTokens("gpt-4o", image), chat.EstimateTokens("gpt-4o", text)+500, "images are counted")
+	assert.Greater(t, chat.EstimateTokens("claude-sonnet-4-5", text), chat.EstimateTokens("gpt-4o", text), "Claude counts err on the high side")
+}
+
+func TestFitContext(t *testing.T) {
+	turn := func(role, content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: role, Content: content}
+	}
+	long := strings.Repeat("word ", 200) // About 200 tokens
+	messages := []openai.ChatCompletionMessage{
+		turn(openai.ChatMessageRoleSystem, "Be brief."),
+		turn(openai.ChatMessageRoleUser, long),
+		turn(openai.ChatMessageRoleAssistant, long),
+		turn(openai.ChatMessageRoleUser, long),
+		turn(openai.ChatMessageRoleAssistant, long),
+		turn(openai.ChatMessageRoleUser, "And now?"),
+	}
+
+	fitted, ok := chat.FitContext(testModel, messages, 10_000)
+	assert.True(t, ok)
+	assert.Equal(t, messages, fitted, "requests within budget are unchanged")
+
+	fitted, ok = chat.FitContext(testModel, messages, 600)
+	assert.True(t, ok)
+	assert.LessOrEqual(t, chat.EstimateTokens(testModel, fitted), 600)
+	assert.Equal(t, messages[0], fitted[0], "system messages are kept")
+	assert.Equal(t, messages[len(messages)-1], fitted[len(fitted)-1], "the last message is kept")
+	assert.Len(t, fitted, 4, "the oldest turns are dropped")
+
+	_, ok = chat.FitContext(testModel, []openai.ChatCompletionMessage{turn(openai.ChatMessageRoleUser, long)}, 100)
+	assert.False(t, ok, "a single message over budget cannot be trimmed")
+}
+
+func TestFitContextPinned(t *testing.T) {
+	turn := func(role, content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: role, Content: content}
+	}
+	long := strings.Repeat("word ", 200) // About 200 tokens
+	answer := "Use a buffered channel of size one."
+	messages := []openai.ChatCompletionMessage{
+		turn(openai.ChatMessageRoleSystem, "Be brief."),
+		turn(openai.ChatMessageRoleUser, long),
+		turn(openai.ChatMessageRoleAssistant, long+answer),
+		turn(openai.ChatMessageRoleUser, long),
+		turn(openai.ChatMessageRoleAssistant, long+"Close it when done."),
+		turn(openai.ChatMessageRoleUser, "And now?"),
+	}
+
+	fitted, ok := chat.FitContextPinned(testModel, messages, []string{answer}, 600)
+	require.True(t, ok)
+	assert.LessOrEqual(t, chat.EstimateTokens(testModel, fitted), 600)
+	assert.Equal(t, messages[0], fitted[0])
+	assert.Equal(t, openai.ChatMessageRoleSystem, fitted[1].Role)
+	assert.Contains(t, fitted[1].Content, answer, "pinned answers in dropped turns are kept")
+	assert.Equal(t, messages[len(messages)-1], fitted[len(fitted)-1])
+
+	fitted, ok = chat.FitContextPinned(testModel, messages, []string{"Close it when done."}, 600)
+	require.True(t, ok)
+	assert.NotEqual(t, openai.ChatMessageRoleSystem, fitted[1].Role, "answers in kept turns are not repeated")
+
+	fitted, ok = chat.FitContextPinned(testModel, messages, []string{answer}, 10_000)
+	assert.True(t, ok)
+	assert.Equal(t, messages, fitted, "requests within budget are unchanged")
+}
+
+func contextWindowConfig(overflow string) *config.Config {
+	cfg := &config.Config{}
+	cfg.OpenAI.Limits = config.LimitsConfig{MaxResponseTokens: 200, ContextOverflow: overflow}
+
+	return cfg
+}
+
+// longThread returns a system message, turns of about 200 tokens each and a
+// short last question.
+func longThread(turns int) []openai.ChatCompletionMessage {
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: "Be brief."}}
+	for i := range turns {
+		role := openai.ChatMessageRoleUser
+		if i%2 == 1 {
+			role = openai.ChatMessageRoleAssistant
+		}
+		messages = append(messages, openai.ChatCompletionMessage{Role: role, Content: strings.Repeat("word ", 200)})
+	}
+
+	return append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "And now?"})
+}
+
+func TestContextWindowManager_Trims(t *testing.T) {
+	pricing := test.NewMockPricingService(t)
+	pricing.EXPECT().GetContextSize("gpt-4o").Return(1000, nil)
+	manager := chat.NewContextWindowManager(zap.NewNop(), contextWindowConfig(""), pricing, &queuedAI{})
+
+	messages := longThread(6)
+	fitted, calls, err := manager.Fit(context.Background(), 1, 2, "gpt-4o", messages)
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+	assert.LessOrEqual(t, chat.EstimateTokens(testModel, fitted), 800, "the reply keeps its tokens")
+	assert.Equal(t, messages[0], fitted[0])
+	assert.Equal(t, messages[len(messages)-1], fitted[len(fitted)-1])
+}
+
+func TestContextWindowManager_Rejects(t *testing.T) {
+	pricing := test.NewMockPricingService(t)
+	pricing.EXPECT().GetContextSize("gpt-4o").Return(1000, nil)
+	manager := chat.NewContextWindowManager(zap.NewNop(), contextWindowConfig(chat.ContextOverflowReject), pricing, &queuedAI{})
+
+	_, _, err := manager.Fit(context.Background(), 1, 2, "gpt-4o", longThread(6))
+	var tooLong *chat.ContextTooLongError
+	require.ErrorAs(t, err, &tooLong)
+	assert.Equal(t, 1000, tooLong.ContextSize)
+}
+
+func TestContextWindowManager_Summarizes(t *testing.T) {
+	pricing := test.NewMockPricingService(t)
+	pricing.EXPECT().GetContextSize("gpt-4o").Return(1000, nil)
+	ai := &queuedAI{replies: []string{"They repeated a word."}}
+	manager := chat.NewContextWindowManager(zap.NewNop(), contextWindowConfig(chat.ContextOverflowSummarize), pricing, ai)
+
+	messages := longThread(6)
+	fitted, calls, err := manager.Fit(context.Background(), 1, 2, "gpt-4o", messages)
+	require.NoError(t, err)
+	assert.Equal(t, []string{chat.StepContextSummary + ":gpt-4o"}, steps(calls))
+	assert.LessOrEqual(t, chat.EstimateTokens(testModel, fitted), 800)
+	assert.Equal(t, messages[0], fitted[0], "system messages are kept")
+	assert.Contains(t, fitted[1].Content, "They repeated a word.", "the summary follows them")
+	assert.Equal(t, messages[len(messages)-1], fitted[len(fitted)-1])
+
+	// The next turn of the thread reuses the summary
+	next := append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "Now this."})
+	fitted, calls, err = manager.Fit(context.Background(), 1, 2, "gpt-4o", next)
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+	assert.Contains(t, fitted[1].Content, "They repeated a word.")
+	assert.Equal(t, next[len(next)-1], fitted[len(fitted)-1])
+}
+
+func TestContextWindowManager_SummaryFailureTrims(t *testing.T) {
+	pricing := test.NewMockPricingService(t)
+	pricing.EXPECT().GetContextSize("gpt-4o").Return(1000, nil)
+	manager := chat.NewContextWindowManager(zap.NewNop(), contextWindowConfig(chat.ContextOverflowSummarize), pricing, &queuedAI{replies: []string{""}})
+
+	messages := longThread(6)
+	fitted, calls, err := manager.Fit(context.Background(), 1, 2, "gpt-4o", messages)
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+	assert.LessOrEqual(t, chat.EstimateTokens(testModel, fitted), 800)
+	assert.Equal(t, messages[len(messages)-1], fitted[len(fitted)-1])
+}
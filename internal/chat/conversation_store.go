@@ -3,13 +3,18 @@ package chat
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/session"
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
 )
 
 const (
@@ -20,9 +25,14 @@ const (
 // ConversationStore defines the interface for storing, retrieving, and reconstructing conversation history.
 type ConversationStore interface {
 	GetConversation(threadID string) (data *MessagesCacheData, found bool)
-	StoreInitialConversation(threadID string, userPrompt, aiResponse, model, userName, botName string, nameSanitizer func(string) string)
+	// character is the name of the persona the conversation uses; empty for the bot itself.
+	// seed is the sampling seed chosen with /chat, or nil.
+	StoreInitialConversation(threadID string, userPrompt, aiResponse, model, userName, botName string, access ThreadAccess, character string, seed *int, nameSanitizer func(string) string)
 	UpdateConversationWithNewMessages(threadID string, existingMessages []openai.ChatCompletionMessage, newUserMessage, newAssistantMessage *openai.ChatCompletionMessage, modelName string)
 	UpdateConversationMessages(threadID string, messages []openai.ChatCompletionMessage, model string)
+	// PinModel sets the model of a cached conversation and the pin its
+	// replies use; a nil pin leaves the next reply to pin it.
+	PinModel(threadID, model string, pin *ModelPin)
 	ReconstructAndCache(
 		ctx context.Context,
 		ses *session.Session,
@@ -35,6 +45,41 @@ type ConversationStore interface {
 	) (cacheData *MessagesCacheData, modelName string, err error)
 	AddToNegativeCache(threadID string)
 	IsInNegativeCache(threadID string) bool
+	// IdleConversations returns the threads whose cached conversation has not changed since before.
+	IdleConversations(before time.Time) []string
+	// Evict drops a conversation from the cache.
+	Evict(threadID string)
+	// Restore puts a previously exported conversation back into the cache.
+	Restore(threadID string, data *MessagesCacheData)
+	// ForgetInitiator drops every cached conversation started by userID and
+	// keeps them from being reconstructed, returning the affected threads.
+	ForgetInitiator(userID discord.UserID) []string
+	// AddParticipant records that userID posted in the cached conversation of
+	// threadID, so that ForgetParticipant finds it.
+	AddParticipant(threadID string, userID discord.UserID)
+	// ForgetParticipant drops every cached conversation userID posted in
+	// without starting it, returning the affected threads. They are
+	// reconstructed from Discord when someone continues them.
+	ForgetParticipant(userID discord.UserID) []string
+	// OnRemove registers fn to be called with the thread of every conversation
+	// that leaves the cache: evicted, archived, purged, forgotten or pushed out
+	// by newer ones. Replacing a cached conversation does not count. It must
+	// be called before the store is used.
+	OnRemove(fn func(threadID string))
+	// Stats returns how full the caches are.
+	Stats() ConversationStats
+	// FlushIgnoredThreads empties the negative cache, so that every thread is
+	// checked again, and returns how many threads it held.
+	FlushIgnoredThreads() int
+}
+
+// ConversationStats counts the entries of a ConversationStore's caches
+// against their sizes.
+type ConversationStats struct {
+	Conversations      int // Cached conversations
+	ConversationsSize  int
+	IgnoredThreads     int // Threads in the negative cache, known not to be chat threads
+	IgnoredThreadsSize int
 }
 
 // NewConversationStore creates a new ConversationStore implementation with internal caches.
@@ -43,17 +88,24 @@ func NewConversationStore(
 	messageCacheSize int,
 	negativeThreadCacheSize int,
 	summaryParser SummaryParser,
+	normalizer ContentNormalizer,
+	m *metrics.Metrics,
 ) ConversationStore {
-	// Create caches directly using the constructor functions
-	messagesCache := NewMessagesCache(messageCacheSize)
-	negativeThreadCache := NewNegativeThreadCache(negativeThreadCacheSize)
-
-	return &cacheBasedConversationStore{
+	cs := &cacheBasedConversationStore{
 		logger:              logger.Named("conversation_store"),
-		messagesCache:       messagesCache,
-		negativeThreadCache: negativeThreadCache,
+		negativeThreadCache: NewNegativeThreadCache(negativeThreadCacheSize),
 		summaryParser:       summaryParser,
+		normalizer:          normalizer,
+		metrics:             m,
+		stats:               ConversationStats{ConversationsSize: messageCacheSize, IgnoredThreadsSize: negativeThreadCacheSize},
 	}
+	cs.messagesCache = NewMessagesCacheWithEvict(messageCacheSize, func(threadID string, _ *MessagesCacheData) {
+		for _, fn := range cs.onRemove {
+			fn(threadID)
+		}
+	})
+
+	return cs
 }
 
 type cacheBasedConversationStore struct {
@@ -61,23 +113,48 @@ type cacheBasedConversationStore struct {
 	messagesCache       *lru.Cache[string, *MessagesCacheData]
 	negativeThreadCache *lru.Cache[string, bool]
 	summaryParser       SummaryParser
+	normalizer          ContentNormalizer
+	metrics             *metrics.Metrics
+	stats               ConversationStats // sizes of the caches
+	onRemove            []func(threadID string)
+}
+
+// OnRemove registers fn to be called when a conversation leaves the cache.
+func (cs *cacheBasedConversationStore) OnRemove(fn func(threadID string)) {
+	cs.onRemove = append(cs.onRemove, fn)
+}
+
+// Stats returns the number of entries in each cache.
+func (cs *cacheBasedConversationStore) Stats() ConversationStats {
+	stats := cs.stats
+	stats.Conversations = cs.messagesCache.Len()
+	stats.IgnoredThreads = cs.negativeThreadCache.Len()
+
+	return stats
 }
 
 // GetConversation retrieves a conversation from the cache.
 func (cs *cacheBasedConversationStore) GetConversation(threadID string) (*MessagesCacheData, bool) {
-	return cs.messagesCache.Get(threadID)
+	data, ok := cs.messagesCache.Get(threadID)
+	cs.metrics.CacheLookup("conversations", ok)
+
+	return data, ok
 }
 
 // StoreInitialConversation stores the initial user prompt and AI response in the message cache.
-func (cs *cacheBasedConversationStore) StoreInitialConversation(threadID, userPrompt, aiResponse, model, userName, botName string, nameSanitizer func(string) string) {
+func (cs *cacheBasedConversationStore) StoreInitialConversation(threadID, userPrompt, aiResponse, model, userName, botName string, access ThreadAccess, character string, seed *int, nameSanitizer func(string) string) {
 	if cs.messagesCache != nil {
 		history := []openai.ChatCompletionMessage{
 			{Role: openai.ChatMessageRoleUser, Content: userPrompt, Name: nameSanitizer(userName)},
 			{Role: openai.ChatMessageRoleAssistant, Content: aiResponse, Name: nameSanitizer(botName)},
 		}
 		cacheData := &MessagesCacheData{
-			Messages: history,
-			Model:    model,
+			Messages:  history,
+			Model:     model,
+			Access:    access,
+			Character: character,
+			Seed:      seed,
+			UpdatedAt: time.Now(),
 		}
 		cs.messagesCache.Add(threadID, cacheData)
 		cs.logger.Debug("Stored initial messages in cache", zap.String("threadID", threadID))
@@ -87,24 +164,54 @@ func (cs *cacheBasedConversationStore) StoreInitialConversation(threadID, userPr
 // UpdateConversationWithNewMessages updates an existing conversation with new messages.
 func (cs *cacheBasedConversationStore) UpdateConversationWithNewMessages(threadID string, existingMessages []openai.ChatCompletionMessage, newUserMessage, newAssistantMessage *openai.ChatCompletionMessage, modelName string) {
 	updatedMessages := append(existingMessages, *newUserMessage, *newAssistantMessage)
-	cacheData := &MessagesCacheData{
-		Messages: updatedMessages,
-		Model:    modelName,
-	}
+	cacheData := cs.withThreadSettings(threadID, &MessagesCacheData{
+		Messages:  updatedMessages,
+		Model:     modelName,
+		UpdatedAt: time.Now(),
+	})
 	cs.messagesCache.Add(threadID, cacheData)
 	cs.logger.Debug("Updated conversation in cache", zap.String("threadID", threadID), zap.Int("messageCount", len(updatedMessages)))
 }
 
 // UpdateConversationMessages updates conversation with new messages (for immediate user message caching and AI response updates).
 func (cs *cacheBasedConversationStore) UpdateConversationMessages(threadID string, messages []openai.ChatCompletionMessage, model string) {
-	cacheData := &MessagesCacheData{
-		Messages: messages,
-		Model:    model,
-	}
+	cacheData := cs.withThreadSettings(threadID, &MessagesCacheData{
+		Messages:  messages,
+		Model:     model,
+		UpdatedAt: time.Now(),
+	})
 	cs.messagesCache.Add(threadID, cacheData)
 	cs.logger.Debug("Updated conversation messages in cache", zap.String("threadID", threadID), zap.Int("messageCount", len(messages)))
 }
 
+// withThreadSettings copies the settings chosen when the conversation started,
+// its access policy, character, seed and model pin, from the cached entry into
+// data, so that updates replacing the cache entry keep them.
+func (cs *cacheBasedConversationStore) withThreadSettings(threadID string, data *MessagesCacheData) *MessagesCacheData {
+	if existing, ok := cs.messagesCache.Peek(threadID); ok {
+		data.Access = existing.Access
+		data.Character = existing.Character
+		data.Seed = existing.Seed
+		data.Pin = existing.Pin
+		data.Participants = existing.Participants
+	}
+
+	return data
+}
+
+// PinModel replaces the cached entry of threadID with one using model and pin.
+func (cs *cacheBasedConversationStore) PinModel(threadID, model string, pin *ModelPin) {
+	existing, ok := cs.messagesCache.Peek(threadID)
+	if !ok {
+		return
+	}
+	data := *existing
+	data.Model = model
+	data.Pin = pin
+	cs.messagesCache.Add(threadID, &data)
+	cs.logger.Debug("Pinned conversation model", zap.String("threadID", threadID), zap.String("model", model))
+}
+
 // ReconstructAndCache reconstructs conversation history from Discord messages and caches it.
 func (cs *cacheBasedConversationStore) ReconstructAndCache(
 	ctx context.Context,
@@ -173,6 +280,15 @@ func (cs *cacheBasedConversationStore) ReconstructAndCache(
 		return nil, "", nil // Not an error, but signals not our thread or unreadable
 	}
 
+	// Threads started from a member's message, such as one mentioning the bot,
+	// open with a reference to that message ahead of the summary
+	if first := allDiscordMessages[0]; first.Type == discord.ThreadStarterMessage && first.Author.ID != selfUser.ID {
+		allDiscordMessages = allDiscordMessages[1:]
+		if len(allDiscordMessages) == 0 {
+			return nil, "", nil
+		}
+	}
+
 	summaryDiscordMessage := allDiscordMessages[0]
 	if summaryDiscordMessage.Author.ID != selfUser.ID {
 		cs.logger.Warn("First message in reconstructed thread not from bot, not a managed thread.",
@@ -197,8 +313,17 @@ func (cs *cacheBasedConversationStore) ReconstructAndCache(
 		return nil, "", nil
 	}
 
+	access := parseThreadAccess(summaryDiscordMessage.Content, summaryDiscordMessage.ReferencedMessage)
+	character := parseCharacterName(summaryDiscordMessage.Content, summaryDiscordMessage.ReferencedMessage)
+	seed := parseSeed(summaryDiscordMessage.Content, summaryDiscordMessage.ReferencedMessage)
+	assistantName := botDisplayName
+	if character != "" {
+		assistantName = character
+	}
+
 	history := []openai.ChatCompletionMessage{}
-	history = append(history, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: parsedUserPrompt, Name: nameSanitizer(initialUserDisplayName)})
+	var participants []discord.UserID
+	history = append(history, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: cs.normalizer.NormalizeText(threadID, parsedUserPrompt), Name: nameSanitizer(initialUserDisplayName)})
 
 	for i := 1; i < len(allDiscordMessages); i++ {
 		msg := allDiscordMessages[i]
@@ -212,28 +337,60 @@ func (cs *cacheBasedConversationStore) ReconstructAndCache(
 			continue
 		}
 
-		var role string
-		var name string
-		if msg.Author.ID == selfUser.ID {
-			role = openai.ChatMessageRoleAssistant
-			name = nameSanitizer(botDisplayName)
+		// Character replies are posted through the bot's webhooks
+		fromBot := msg.Author.ID == selfUser.ID || IsPersonaMessage(&msg, selfUser)
+
+		// Suggested questions are asked by the bot on behalf of the member who
+		// clicked them
+		if fromBot && msg.Interaction != nil && strings.HasPrefix(msg.Content, suggestionPrefix) {
+			question := strings.TrimPrefix(msg.Content, suggestionPrefix)
+			history = append(history, UserTurn(question, nameSanitizer(userDisplayNameResolver(&msg.Interaction.User)), nil))
+			participants = withParticipant(participants, msg.Interaction.User.ID)
+
+			continue
+		}
+
+		// Leave out messages the thread policy ignored and the notices sent about
+		// them, notices about shortened messages, loop alerts and responses to
+		// commands run in the thread
+		if fromBot && (msg.Interaction != nil ||
+			strings.HasPrefix(msg.Content, blockedNoticePrefix) ||
+			strings.HasPrefix(msg.Content, truncationNoticePrefix) ||
+			strings.HasPrefix(msg.Content, moderation.LoopAlertPrefix)) {
+			continue
+		}
+		if !fromBot && access.Policy == ThreadPolicyInitiator && msg.Author.ID != access.InitiatorID {
+			continue
+		}
+
+		var turn openai.ChatCompletionMessage
+		if fromBot {
+			turn = openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: msg.Content, Name: nameSanitizer(assistantName)}
 		} else {
-			role = openai.ChatMessageRoleUser
+			// Image attachments are re-read from their current URLs
 			messageAuthorDisplayName := userDisplayNameResolver(&msg.Author)
-			name = nameSanitizer(messageAuthorDisplayName)
+			turn = UserTurn(cs.normalizer.Normalize(&msg), nameSanitizer(messageAuthorDisplayName), msg.Attachments)
 		}
-		if strings.TrimSpace(msg.Content) == "" {
+		if strings.TrimSpace(turn.Content) == "" && len(turn.MultiContent) == 0 {
 			cs.logger.Debug("Skipping empty message during history reconstruction", zap.String("threadID", threadID.String()), zap.String("messageID", msg.ID.String()))
 
 			continue
 		}
-		history = append(history, openai.ChatCompletionMessage{Role: role, Content: msg.Content, Name: name})
+		history = append(history, turn)
+		if !fromBot {
+			participants = withParticipant(participants, msg.Author.ID)
+		}
 	}
 	cs.logger.Debug("Reconstructed message history", zap.Int("count", len(history)), zap.String("threadID", threadID.String()))
 
 	reconstructedCacheData := &MessagesCacheData{
-		Messages: history,
-		Model:    parsedModelName,
+		Messages:     history,
+		Model:        parsedModelName,
+		Access:       access,
+		Character:    character,
+		Seed:         seed,
+		Participants: participants,
+		UpdatedAt:    time.Now(),
 	}
 	cs.messagesCache.Add(threadID.String(), reconstructedCacheData)
 	cs.logger.Info("Successfully reconstructed and cached conversation",
@@ -257,3 +414,89 @@ func (cs *cacheBasedConversationStore) IsInNegativeCache(threadID string) bool {
 
 	return found
 }
+
+// IdleConversations returns the threads whose cached conversation was last updated before the given time.
+func (cs *cacheBasedConversationStore) IdleConversations(before time.Time) []string {
+	var idle []string
+	for _, threadID := range cs.messagesCache.Keys() {
+		if data, ok := cs.messagesCache.Peek(threadID); ok && data.UpdatedAt.Before(before) {
+			idle = append(idle, threadID)
+		}
+	}
+
+	return idle
+}
+
+// Evict removes a conversation from the message cache.
+func (cs *cacheBasedConversationStore) Evict(threadID string) {
+	cs.messagesCache.Remove(threadID)
+	cs.logger.Debug("Evicted conversation from cache", zap.String("threadID", threadID))
+}
+
+// Restore adds a conversation to the message cache as if it had just been updated.
+func (cs *cacheBasedConversationStore) Restore(threadID string, data *MessagesCacheData) {
+	data.UpdatedAt = time.Now()
+	cs.messagesCache.Add(threadID, data)
+	cs.logger.Debug("Restored conversation to cache", zap.String("threadID", threadID), zap.Int("messageCount", len(data.Messages)))
+}
+
+// FlushIgnoredThreads purges the negative cache.
+func (cs *cacheBasedConversationStore) FlushIgnoredThreads() int {
+	n := cs.negativeThreadCache.Len()
+	cs.negativeThreadCache.Purge()
+	cs.logger.Info("Flushed ignored threads", zap.Int("count", n))
+
+	return n
+}
+
+// ForgetInitiator removes conversations started by userID and adds their threads to the negative cache.
+func (cs *cacheBasedConversationStore) ForgetInitiator(userID discord.UserID) []string {
+	var forgotten []string
+	for _, threadID := range cs.messagesCache.Keys() {
+		if data, ok := cs.messagesCache.Peek(threadID); ok && data.Access.InitiatorID == userID {
+			cs.messagesCache.Remove(threadID)
+			cs.negativeThreadCache.Add(threadID, true)
+			forgotten = append(forgotten, threadID)
+		}
+	}
+	cs.logger.Info("Forgot cached conversations of user", zap.String("userID", userID.String()), zap.Int("count", len(forgotten)))
+
+	return forgotten
+}
+
+// AddParticipant replaces the cached entry of threadID with one listing userID
+// among its participants.
+func (cs *cacheBasedConversationStore) AddParticipant(threadID string, userID discord.UserID) {
+	existing, ok := cs.messagesCache.Peek(threadID)
+	if !ok || slices.Contains(existing.Participants, userID) {
+		return
+	}
+	updated := *existing
+	updated.Participants = withParticipant(existing.Participants, userID)
+	cs.messagesCache.Add(threadID, &updated)
+}
+
+// ForgetParticipant removes conversations userID posted in but did not start.
+// Their threads stay out of the negative cache, so the other participants can
+// continue them.
+func (cs *cacheBasedConversationStore) ForgetParticipant(userID discord.UserID) []string {
+	var forgotten []string
+	for _, threadID := range cs.messagesCache.Keys() {
+		if data, ok := cs.messagesCache.Peek(threadID); ok && data.Access.InitiatorID != userID && slices.Contains(data.Participants, userID) {
+			cs.messagesCache.Remove(threadID)
+			forgotten = append(forgotten, threadID)
+		}
+	}
+	cs.logger.Info("Forgot cached conversations user took part in", zap.String("userID", userID.String()), zap.Int("count", len(forgotten)))
+
+	return forgotten
+}
+
+// withParticipant returns participants with userID, copying it if userID is added.
+func withParticipant(participants []discord.UserID, userID discord.UserID) []discord.UserID {
+	if slices.Contains(participants, userID) {
+		return participants
+	}
+
+	return append(slices.Clone(participants), userID)
+}
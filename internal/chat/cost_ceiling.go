@@ -0,0 +1,228 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+)
+
+// ContinueSpendingComponentID is the button on cost ceiling notices. It is
+// routed to /chat, which hands it to HandleContinueSpending.
+const ContinueSpendingComponentID = discord.ComponentID("chat:continue-spending")
+
+// threadSpend is the estimated cost of a thread's replies so far, and how
+// much it may reach before the initiator must confirm further spending.
+type threadSpend struct {
+	mu        sync.Mutex
+	spentUSD  float64
+	allowance float64 // Zero until the first ceiling is set
+	noticeID  discord.MessageID
+	// initiatorID may confirm further spending; anyone may when unknown.
+	initiatorID discord.UserID
+	// held is the latest message left unanswered while the thread is paused,
+	// answered once spending is confirmed.
+	held *gateway.MessageCreateEvent
+}
+
+func (s *Service) threadSpend(threadID discord.ChannelID) *threadSpend {
+	spend, _ := s.threadSpends.LoadOrStore(threadID, &threadSpend{})
+
+	return spend.(*threadSpend)
+}
+
+// recordSpend adds the cost of calls to the spend of threadID, if the guild
+// has a cost ceiling.
+func (s *Service) recordSpend(guildID discord.GuildID, threadID discord.ChannelID, calls []CallUsage) {
+	if !threadID.IsValid() || len(calls) == 0 || s.cfg.Limits(guildID.String()).ThreadCostCeilingUSD <= 0 {
+		return
+	}
+	cost, ok := turnCost(s.pricing, calls)
+	if !ok {
+		s.logger.Debug("Cannot price reply, thread spend is a lower bound", zap.String("threadID", threadID.String()))
+	}
+
+	spend := s.threadSpend(threadID)
+	spend.mu.Lock()
+	spend.spentUSD += cost
+	spend.mu.Unlock()
+}
+
+// forgetSpend drops the spend of a thread whose conversation left the cache.
+func (s *Service) forgetSpend(threadID string) {
+	if id, err := discord.ParseSnowflake(threadID); err == nil {
+		s.threadSpends.Delete(discord.ChannelID(id))
+	}
+}
+
+// spendingPaused reports whether the thread of evt has reached its cost
+// ceiling. A paused thread keeps evt for later and asks initiatorID, once,
+// to confirm more spending.
+func (s *Service) spendingPaused(evt *gateway.MessageCreateEvent, initiatorID discord.UserID) bool {
+	ceiling := s.cfg.Limits(evt.GuildID.String()).ThreadCostCeilingUSD
+	if ceiling <= 0 {
+		return false
+	}
+
+	spend := s.threadSpend(evt.ChannelID)
+	spend.mu.Lock()
+	defer spend.mu.Unlock()
+
+	if spend.allowance == 0 {
+		spend.allowance = ceiling
+	}
+	if spend.spentUSD < spend.allowance {
+		return false
+	}
+	spend.held = evt
+	spend.initiatorID = initiatorID
+	if spend.noticeID.IsValid() {
+		return true
+	}
+
+	s.logger.Info("Thread reached its cost ceiling, waiting for confirmation",
+		zap.String("threadID", evt.ChannelID.String()),
+		zap.Float64("spentUSD", spend.spentUSD),
+		zap.Float64("allowanceUSD", spend.allowance))
+
+	mention := "The thread's initiator"
+	mentions := &api.AllowedMentions{}
+	if initiatorID.IsValid() {
+		mention = initiatorID.Mention()
+		mentions.Users = []discord.UserID{initiatorID}
+	}
+	components := discord.ContainerComponents{&discord.ActionRowComponent{
+		&discord.ButtonComponent{
+			Label:    fmt.Sprintf("Continue (+$%.2f)", ceiling),
+			CustomID: ContinueSpendingComponentID,
+			Style:    discord.PrimaryButtonStyle(),
+			Emoji:    &discord.ComponentEmoji{Name: "💸"},
+		},
+	}}
+	msg, err := s.ses.SendMessageComplex(evt.ChannelID, api.SendMessageData{
+		Content: fmt.Sprintf("💸 This conversation has cost about $%.2f, reaching its $%.2f limit. "+
+			"%s, press Continue to allow another $%.2f. I will not reply until then.", spend.spentUSD, spend.allowance, mention, ceiling),
+		Components:      components,
+		AllowedMentions: mentions,
+	})
+	if err != nil {
+		s.logger.Error("Failed to send cost ceiling notice", zap.Error(err), zap.String("threadID", evt.ChannelID.String()))
+
+		return true
+	}
+	spend.noticeID = msg.ID
+
+	return true
+}
+
+// costCeilingReached reports whether threadID has spent what it may before
+// its initiator confirms more, so that optional requests are left out.
+func (s *Service) costCeilingReached(guildID discord.GuildID, threadID discord.ChannelID) bool {
+	ceiling := s.cfg.Limits(guildID.String()).ThreadCostCeilingUSD
+	value, ok := s.threadSpends.Load(threadID)
+	if ceiling <= 0 || !ok {
+		return false
+	}
+
+	spend := value.(*threadSpend)
+	spend.mu.Lock()
+	defer spend.mu.Unlock()
+
+	allowance := spend.allowance
+	if allowance == 0 {
+		allowance = ceiling
+	}
+
+	return spend.spentUSD >= allowance
+}
+
+// HandleContinueSpending handles the button on cost ceiling notices. The
+// thread's initiator raises its allowance by another ceiling, and the message
+// held while it was paused is answered.
+func (s *Service) HandleContinueSpending(ctx context.Context, e *gateway.InteractionCreateEvent) error {
+	threadID := e.ChannelID
+	user := e.SenderID()
+	ceiling := s.cfg.Limits(e.GuildID.String()).ThreadCostCeilingUSD
+	value, ok := s.threadSpends.Load(threadID)
+	if !ok {
+		// The conversation left the cache since the notice, so its spend
+		// starts over
+		return s.respondComponent(e, api.UpdateMessage,
+			"This limit no longer applies, the conversation's spending has been reset. Send your message again for a reply.", false)
+	}
+	spend := value.(*threadSpend)
+	spend.mu.Lock()
+	if initiatorID := spend.initiatorID; initiatorID.IsValid() && user != initiatorID {
+		spend.mu.Unlock()
+
+		return s.respondComponent(e, api.MessageInteractionWithSource,
+			fmt.Sprintf("Only %s can approve more spending in this thread.", initiatorID.Mention()), true)
+	}
+	// The held message stays held if the thread was muted or handed off
+	// meanwhile
+	if notice := s.silenceNotice(e.GuildID, threadID, user); notice != "" {
+		spend.mu.Unlock()
+
+		return s.respondComponent(e, api.MessageInteractionWithSource, notice, true)
+	}
+	if ceiling > 0 && spend.spentUSD >= spend.allowance {
+		// The new allowance counts from what was spent, so a thread far over
+		// its ceiling still gets a full ceiling more
+		spend.allowance = spend.spentUSD + ceiling
+	}
+	held := spend.held
+	spend.held = nil
+	spend.noticeID = 0
+	allowance := spend.allowance
+	spend.mu.Unlock()
+
+	s.logger.Info("Thread spending approved",
+		zap.String("threadID", threadID.String()),
+		zap.String("userID", user.String()),
+		zap.Float64("allowanceUSD", allowance))
+	err := s.respondComponent(e, api.UpdateMessage,
+		fmt.Sprintf("✅ %s approved spending up to $%.2f in this conversation.", user.Mention(), allowance), false)
+	if err != nil || held == nil {
+		return err
+	}
+
+	return s.HandleThreadMessage(withResumedTurn(ctx), held)
+}
+
+// respondComponent answers a component interaction, removing the buttons
+// when it updates the notice.
+func (s *Service) respondComponent(e *gateway.InteractionCreateEvent, responseType api.InteractionResponseType, content string, ephemeral bool) error {
+	data := &api.InteractionResponseData{
+		Content:         option.NewNullableString(content),
+		AllowedMentions: &api.AllowedMentions{},
+	}
+	if ephemeral {
+		data.Flags = discord.EphemeralMessage
+	} else {
+		data.Components = &discord.ContainerComponents{}
+	}
+	if err := s.ses.RespondInteraction(e.ID, e.Token, api.InteractionResponse{Type: responseType, Data: data}); err != nil {
+		return fmt.Errorf("failed to respond to spending confirmation: %w", err)
+	}
+
+	return nil
+}
+
+type resumedTurnKey struct{}
+
+// withResumedTurn marks a thread message that is already in the conversation
+// and only needs its reply.
+func withResumedTurn(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resumedTurnKey{}, true)
+}
+
+func isResumedTurn(ctx context.Context) bool {
+	resumed, _ := ctx.Value(resumedTurnKey{}).(bool)
+
+	return resumed
+}
@@ -0,0 +1,131 @@
+package chat_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func threadMessage(threadID discord.ChannelID, authorID discord.UserID, id discord.MessageID, content string) *gateway.MessageCreateEvent {
+	return &gateway.MessageCreateEvent{Message: discord.Message{
+		ID:        id,
+		ChannelID: threadID,
+		GuildID:   1,
+		Author:    discord.User{ID: authorID, Username: "user" + authorID.String()},
+		Content:   content,
+	}}
+}
+
+func continueSpending(threadID discord.ChannelID, userID discord.UserID) *gateway.InteractionCreateEvent {
+	return &gateway.InteractionCreateEvent{InteractionEvent: discord.InteractionEvent{
+		ID:        discord.InteractionID(userID),
+		ChannelID: threadID,
+		GuildID:   1,
+		Token:     "token",
+		Member:    &discord.Member{User: discord.User{ID: userID}},
+		Data:      &discord.ButtonInteraction{CustomID: chat.ContinueSpendingComponentID},
+	}}
+}
+
+func costCeilingConfig(ceiling float64) *config.Config {
+	cfg := &config.Config{}
+	cfg.OpenAI.Limits.ThreadCostCeilingUSD = ceiling
+
+	return cfg
+}
+
+func TestCostCeiling(t *testing.T) {
+	const threadID, initiatorID, otherID = discord.ChannelID(10), discord.UserID(5), discord.UserID(6)
+	ts := newTestService(t, costCeilingConfig(0.01), nil)
+	ts.startThread(threadID, initiatorID, chat.ThreadPolicyAnyone)
+	ctx := t.Context()
+
+	// The first reply costs $0.01, reaching the ceiling
+	require.NoError(t, ts.HandleThreadMessage(ctx, threadMessage(threadID, initiatorID, 1, "first")))
+	require.NoError(t, ts.HandleThreadMessage(ctx, threadMessage(threadID, otherID, 2, "second")))
+	require.NoError(t, ts.HandleThreadMessage(ctx, threadMessage(threadID, otherID, 3, "third")))
+	assert.Equal(t, []string{"first"}, ts.ai.asked(), "a paused thread is not answered")
+
+	var notices []fakeRequest
+	for _, request := range ts.discord.posted() {
+		if components, ok := request.Body["components"].([]any); ok && len(components) > 0 && request.Method == "POST" {
+			notices = append(notices, request)
+		}
+	}
+	require.Len(t, notices, 1, "the initiator is asked once")
+	assert.Contains(t, notices[0].content(), initiatorID.Mention())
+	assert.Contains(t, notices[0].content(), "$0.01 limit")
+
+	// Only the initiator may approve more spending
+	require.NoError(t, ts.HandleContinueSpending(ctx, continueSpending(threadID, otherID)))
+	refusal := ts.discord.posted()[len(ts.discord.posted())-1]
+	assert.Contains(t, refusal.content(), "Only "+initiatorID.Mention())
+	assert.InDelta(t, float64(discord.EphemeralMessage), refusal.Body["data"].(map[string]any)["flags"], 0)
+	assert.Len(t, ts.ai.asked(), 1)
+
+	// Approval answers the latest held message, with the earlier one in the
+	// conversation
+	require.NoError(t, ts.HandleContinueSpending(ctx, continueSpending(threadID, initiatorID)))
+	assert.Equal(t, []string{"first", "third"}, ts.ai.asked())
+	assert.Contains(t, requestText(ts.ai.requests[1]), "second")
+	var approval fakeRequest
+	for _, request := range ts.discord.posted() {
+		if strings.Contains(request.content(), "approved spending") {
+			approval = request
+		}
+	}
+	assert.InDelta(t, float64(api.UpdateMessage), approval.Body["type"], 0)
+	assert.Contains(t, approval.content(), "up to $0.02")
+
+	// The spend is dropped with the conversation, which starts over
+	ts.store.Evict(threadID.String())
+	require.NoError(t, ts.HandleContinueSpending(ctx, continueSpending(threadID, initiatorID)))
+	assert.Contains(t, ts.discord.posted()[len(ts.discord.posted())-1].content(), "no longer applies")
+}
+
+func TestCostCeiling_Disabled(t *testing.T) {
+	const threadID = discord.ChannelID(10)
+	ts := newTestService(t, costCeilingConfig(0), nil)
+	ts.startThread(threadID, 5, chat.ThreadPolicyAnyone)
+
+	for i, content := range []string{"first", "second", "third"} {
+		require.NoError(t, ts.HandleThreadMessage(t.Context(), threadMessage(threadID, 5, discord.MessageID(i+1), content)))
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, ts.ai.asked())
+
+	// Nothing was tracked, so there is nothing to approve
+	require.NoError(t, ts.HandleContinueSpending(t.Context(), continueSpending(threadID, 5)))
+	assert.Contains(t, ts.discord.posted()[len(ts.discord.posted())-1].content(), "no longer applies")
+}
+
+func TestCostCeiling_MutedWhileHeld(t *testing.T) {
+	const threadID, initiatorID = discord.ChannelID(10), discord.UserID(5)
+	ts := newTestService(t, costCeilingConfig(0.01), nil)
+	ts.startThread(threadID, initiatorID, chat.ThreadPolicyAnyone)
+	ctx := t.Context()
+
+	require.NoError(t, ts.HandleThreadMessage(ctx, threadMessage(threadID, initiatorID, 1, "first")))
+	require.NoError(t, ts.HandleThreadMessage(ctx, threadMessage(threadID, initiatorID, 2, "held")))
+	_, err := ts.mutes.Mute(threadID, initiatorID)
+	require.NoError(t, err)
+
+	require.NoError(t, ts.HandleContinueSpending(ctx, continueSpending(threadID, initiatorID)))
+	notice := ts.discord.posted()[len(ts.discord.posted())-1]
+	assert.Contains(t, notice.content(), "muted")
+	assert.InDelta(t, float64(discord.EphemeralMessage), notice.Body["data"].(map[string]any)["flags"], 0)
+	assert.Equal(t, []string{"first"}, ts.ai.asked(), "the held message is not answered")
+
+	// Once unmuted, approval answers the message still held
+	_, err = ts.mutes.Unmute(threadID)
+	require.NoError(t, err)
+	require.NoError(t, ts.HandleContinueSpending(ctx, continueSpending(threadID, initiatorID)))
+	assert.Equal(t, []string{"first", "held"}, ts.ai.asked())
+}
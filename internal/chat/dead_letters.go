@@ -0,0 +1,327 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const (
+	defaultDeadLettersFile = "dead_letters.json"
+	defaultMaxDeadLetters  = 100
+	// errCodeThreadArchived is Discord's error for posting in an archived thread.
+	errCodeThreadArchived = 50083
+)
+
+// ErrDeadLetterNotFound is returned for dead letter IDs that are not queued.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is an AI reply that could not be posted to Discord, kept so
+// that the paid-for completion can still be delivered.
+type DeadLetter struct {
+	ID            string            `json:"id"`
+	GuildID       discord.GuildID   `json:"guild_id"`
+	ChannelID     discord.ChannelID `json:"channel_id"`
+	RequesterID   discord.UserID    `json:"requester_id,omitempty"` // User the reply answered, for /forget-me
+	Character     string            `json:"character,omitempty"`    // Persona the reply was written as
+	Content       string            `json:"content,omitempty"`
+	SealedContent []byte            `json:"sealed_content,omitempty"` // Content encrypted for the guild, when encryption is on
+	Error         string            `json:"error"`
+	FailedAt      time.Time         `json:"failed_at"`
+}
+
+// IsPermanentDeliveryError reports whether Discord rejected a post for a
+// reason retrying will not fix, such as missing permissions or a deleted or
+// archived thread.
+func IsPermanentDeliveryError(err error) bool {
+	var httpErr *httputil.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+
+	return httpErr.Status == http.StatusForbidden || httpErr.Status == http.StatusNotFound || httpErr.Code == errCodeThreadArchived
+}
+
+// DeadLetters keeps replies that could not be delivered in a JSON document and
+// tells the ops channel about them. A nil *DeadLetters drops them, as when
+// the queue is disabled.
+type DeadLetters struct {
+	logger       *zap.Logger
+	ses          *session.Session
+	doc          storage.Document
+	sealer       storage.Sealer
+	max          int
+	opsChannelID discord.ChannelID
+
+	mu      sync.Mutex
+	letters []DeadLetter // Oldest first, as stored
+}
+
+// NewDeadLetters creates the dead letter queue configured in cfg, loading the
+// letters already saved. Replies are sealed with sealer when encryption at
+// rest is on. It returns nil when the queue is disabled.
+func NewDeadLetters(logger *zap.Logger, cfg *config.Config, ses *session.Session, state storage.Provider, sealer storage.Sealer) (*DeadLetters, error) {
+	dlq := cfg.DeadLetters
+	if !dlq.Enabled {
+		return nil, nil
+	}
+
+	key := dlq.File
+	if key == "" {
+		key = defaultDeadLettersFile
+	}
+	d := &DeadLetters{
+		logger: logger.Named("dead_letters"),
+		ses:    ses,
+		doc:    storage.NewDocument(state, key),
+		sealer: sealer,
+		max:    dlq.MaxEntries,
+	}
+	if d.max <= 0 {
+		d.max = defaultMaxDeadLetters
+	}
+	if dlq.OpsChannelID != "" {
+		sf, err := discord.ParseSnowflake(dlq.OpsChannelID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dead letter ops_channel_id %q: %w", dlq.OpsChannelID, err)
+		}
+		d.opsChannelID = discord.ChannelID(sf)
+	}
+
+	if _, err := d.doc.Load(&d.letters); err != nil {
+		return nil, fmt.Errorf("failed to load dead letters: %w", err)
+	}
+
+	return d, nil
+}
+
+// Push queues letter under a new ID, dropping the oldest letters beyond the
+// configured maximum, and tells the ops channel how to retry it.
+func (d *DeadLetters) Push(ctx context.Context, letter DeadLetter) (DeadLetter, error) {
+	if d == nil {
+		return letter, nil
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return letter, fmt.Errorf("failed to create dead letter ID: %w", err)
+	}
+	letter.ID = hex.EncodeToString(id)
+	letter.FailedAt = time.Now().UTC()
+	stored := letter
+	sealed, err := sealContent(ctx, d.sealer, letter.GuildID, letter.Content)
+	if err != nil {
+		return letter, err
+	}
+	if sealed != nil {
+		stored.Content = ""
+		stored.SealedContent = sealed
+	}
+
+	d.mu.Lock()
+	previous := d.letters
+	d.letters = append(slices.Clone(d.letters), stored)
+	if over := len(d.letters) - d.max; over > 0 {
+		d.letters = d.letters[over:]
+	}
+	err = d.persist()
+	if err != nil {
+		d.letters = previous
+	}
+	d.mu.Unlock()
+	if err != nil {
+		return letter, err
+	}
+
+	d.logger.Warn("Reply could not be delivered, queued as dead letter",
+		zap.String("id", letter.ID),
+		zap.String("guildID", letter.GuildID.String()),
+		zap.String("channelID", letter.ChannelID.String()),
+		zap.String("error", letter.Error))
+	d.notify(letter)
+
+	return letter, nil
+}
+
+// notify posts letter to the ops channel, if one is configured.
+func (d *DeadLetters) notify(letter DeadLetter) {
+	if !d.opsChannelID.IsValid() {
+		return
+	}
+
+	_, err := d.ses.SendMessageComplex(d.opsChannelID, api.SendMessageData{
+		Content: fmt.Sprintf("📮 A reply for %s (server %s) could not be posted: %s\n"+
+			"Retry it with `/admin delivery retry id:%s`, optionally in another channel.",
+			letter.ChannelID.Mention(), letter.GuildID, letter.Error, letter.ID),
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	if err != nil {
+		d.logger.Error("Failed to notify ops channel of dead letter", zap.Error(err), zap.String("id", letter.ID))
+	}
+}
+
+// Get returns the letter with id, with its content decrypted, or
+// ErrDeadLetterNotFound.
+func (d *DeadLetters) Get(ctx context.Context, id string) (DeadLetter, error) {
+	if d == nil {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+
+	d.mu.Lock()
+	i := slices.IndexFunc(d.letters, func(l DeadLetter) bool { return l.ID == id })
+	var letter DeadLetter
+	if i >= 0 {
+		letter = d.letters[i]
+	}
+	d.mu.Unlock()
+	if i < 0 {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+
+	if len(letter.SealedContent) > 0 {
+		content, err := openContent(ctx, d.sealer, letter.GuildID, letter.SealedContent)
+		if err != nil {
+			return letter, fmt.Errorf("failed to decrypt dead letter: %w", err)
+		}
+		letter.Content = content
+		letter.SealedContent = nil
+	}
+
+	return letter, nil
+}
+
+// List returns the letters of guildID, or of every guild for a null guildID,
+// newest first. Their content is left out; Get returns it.
+func (d *DeadLetters) List(guildID discord.GuildID) []DeadLetter {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var letters []DeadLetter
+	for i := len(d.letters) - 1; i >= 0; i-- {
+		if !guildID.IsValid() || d.letters[i].GuildID == guildID {
+			letter := d.letters[i]
+			letter.Content, letter.SealedContent = "", nil
+			letters = append(letters, letter)
+		}
+	}
+
+	return letters
+}
+
+// Remove drops the letter with id and reports whether it was queued.
+func (d *DeadLetters) Remove(id string) (bool, error) {
+	if d == nil {
+		return false, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := slices.IndexFunc(d.letters, func(l DeadLetter) bool { return l.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+
+	previous := d.letters
+	d.letters = slices.Delete(slices.Clone(d.letters), i, i+1)
+	if err := d.persist(); err != nil {
+		d.letters = previous
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ForgetRequester drops the letters answering userID and returns how many
+// were queued. Letters queued before requesters were recorded are kept, as
+// they cannot be told apart.
+func (d *DeadLetters) ForgetRequester(userID discord.UserID) (int, error) {
+	if d == nil {
+		return 0, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	kept := slices.DeleteFunc(slices.Clone(d.letters), func(l DeadLetter) bool { return l.RequesterID == userID })
+	removed := len(d.letters) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	previous := d.letters
+	d.letters = kept
+	if err := d.persist(); err != nil {
+		d.letters = previous
+
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// persist saves the letters. The caller must hold d.mu.
+func (d *DeadLetters) persist() error {
+	return d.doc.Save(d.letters)
+}
+
+// deadLetter queues a reply to threadID that Discord permanently refused,
+// for the requester of ctx. Other errors are left to the caller, since the
+// post may still succeed.
+func (s *Service) deadLetter(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, character *characters.Character, content string, err error) {
+	if s.deadLetters == nil || !IsPermanentDeliveryError(err) {
+		return
+	}
+
+	letter := DeadLetter{GuildID: guildID, ChannelID: threadID, RequesterID: requesterFrom(ctx), Content: content, Error: err.Error()}
+	if character != nil {
+		letter.Character = character.Name
+	}
+	if _, pushErr := s.deadLetters.Push(ctx, letter); pushErr != nil {
+		s.logger.Error("Failed to queue undelivered reply", zap.Error(pushErr), zap.String("threadID", threadID.String()))
+	}
+}
+
+// RetryDeadLetter posts the queued reply with id to channelID, or to the
+// channel it was meant for when channelID is null, and drops it once posted.
+func (s *Service) RetryDeadLetter(ctx context.Context, id string, channelID discord.ChannelID) (DeadLetter, error) {
+	letter, err := s.deadLetters.Get(ctx, id)
+	if err != nil {
+		return DeadLetter{}, err
+	}
+	if !channelID.IsValid() {
+		channelID = letter.ChannelID
+	}
+
+	character := s.threadCharacter(letter.GuildID, letter.Character)
+	if err := s.postReply(ctx, letter.GuildID, channelID, character, letter.Content, nil); err != nil {
+		return letter, fmt.Errorf("failed to deliver dead letter: %w", err)
+	}
+	if _, err := s.deadLetters.Remove(id); err != nil {
+		s.logger.Warn("Delivered dead letter could not be removed", zap.Error(err), zap.String("id", id))
+	}
+	s.logger.Info("Dead letter delivered", zap.String("id", id), zap.String("channelID", channelID.String()))
+
+	return letter, nil
+}
@@ -0,0 +1,125 @@
+package chat_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func newDeadLetters(t *testing.T, path string, maxEntries int, sealer storage.Sealer) *chat.DeadLetters {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.DeadLetters = config.DeadLettersConfig{Enabled: true, File: path, MaxEntries: maxEntries}
+	d, err := chat.NewDeadLetters(zap.NewNop(), cfg, nil, storage.NewFileProvider(""), sealer)
+	require.NoError(t, err)
+	require.NotNil(t, d)
+
+	return d
+}
+
+func TestDeadLetters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead_letters.json")
+	d := newDeadLetters(t, path, 2, nil)
+
+	first, err := d.Push(t.Context(), chat.DeadLetter{GuildID: 1, ChannelID: 10, Content: "one", Error: "Missing Access"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.ID)
+	assert.False(t, first.FailedAt.IsZero())
+	second, err := d.Push(t.Context(), chat.DeadLetter{GuildID: 2, ChannelID: 20, Content: "two"})
+	require.NoError(t, err)
+
+	got, err := d.Get(t.Context(), first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "one", got.Content)
+	assert.Len(t, d.List(discord.NullGuildID), 2)
+	guildLetters := d.List(2)
+	require.Len(t, guildLetters, 1)
+	assert.Equal(t, second.ID, guildLetters[0].ID)
+
+	// Letters survive a restart
+	reloaded := newDeadLetters(t, path, 2, nil)
+	assert.Len(t, reloaded.List(discord.NullGuildID), 2)
+
+	third, err := d.Push(t.Context(), chat.DeadLetter{GuildID: 1, ChannelID: 30, Content: "three"})
+	require.NoError(t, err)
+	_, err = d.Get(t.Context(), first.ID)
+	require.ErrorIs(t, err, chat.ErrDeadLetterNotFound, "the oldest letter is dropped beyond max_entries")
+	assert.Equal(t, third.ID, d.List(discord.NullGuildID)[0].ID, "the newest letter is listed first")
+	assert.Empty(t, d.List(discord.NullGuildID)[0].Content, "letters are listed without their content")
+
+	removed, err := d.Remove(second.ID)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = d.Remove(second.ID)
+	require.NoError(t, err)
+	assert.False(t, removed)
+}
+
+func TestDeadLetters_Disabled(t *testing.T) {
+	d, err := chat.NewDeadLetters(zap.NewNop(), &config.Config{}, nil, storage.NewFileProvider(""), nil)
+	require.NoError(t, err)
+	assert.Nil(t, d)
+
+	_, err = d.Push(t.Context(), chat.DeadLetter{Content: "dropped"})
+	require.NoError(t, err)
+	assert.Empty(t, d.List(discord.NullGuildID))
+	_, err = d.Get(t.Context(), "missing")
+	require.ErrorIs(t, err, chat.ErrDeadLetterNotFound)
+	removed, err := d.ForgetRequester(5)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func TestDeadLetters_Sealed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead_letters.json")
+	sealer := newSealer(t)
+	d := newDeadLetters(t, path, 10, sealer)
+
+	letter, err := d.Push(t.Context(), chat.DeadLetter{GuildID: 1, ChannelID: 10, Content: "secret reply"})
+	require.NoError(t, err)
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(saved), "secret reply", "replies are stored encrypted")
+
+	got, err := newDeadLetters(t, path, 10, sealer).Get(t.Context(), letter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "secret reply", got.Content)
+	_, err = newDeadLetters(t, path, 10, nil).Get(t.Context(), letter.ID)
+	require.Error(t, err, "a sealed letter cannot be read without the key")
+}
+
+func TestDeadLetterEraser(t *testing.T) {
+	d := newDeadLetters(t, filepath.Join(t.TempDir(), "dead_letters.json"), 10, nil)
+	for _, requesterID := range []discord.UserID{5, 6, 5} {
+		_, err := d.Push(t.Context(), chat.DeadLetter{GuildID: 1, ChannelID: 10, RequesterID: requesterID, Content: "reply"})
+		require.NoError(t, err)
+	}
+
+	removed, err := chat.NewDeadLetterEraser(d).ForgetUser(t.Context(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	letters := d.List(discord.NullGuildID)
+	require.Len(t, letters, 1)
+	assert.Equal(t, discord.UserID(6), letters[0].RequesterID)
+}
+
+func TestIsPermanentDeliveryError(t *testing.T) {
+	assert.True(t, chat.IsPermanentDeliveryError(&httputil.HTTPError{Status: http.StatusForbidden, Code: 50013}))
+	assert.True(t, chat.IsPermanentDeliveryError(fmt.Errorf("send: %w", &httputil.HTTPError{Status: http.StatusNotFound, Code: 10003})))
+	assert.True(t, chat.IsPermanentDeliveryError(&httputil.HTTPError{Status: http.StatusBadRequest, Code: 50083}), "archived threads refuse posts")
+	assert.False(t, chat.IsPermanentDeliveryError(&httputil.HTTPError{Status: http.StatusTooManyRequests}))
+	assert.False(t, chat.IsPermanentDeliveryError(errors.New("connection reset")))
+}
@@ -0,0 +1,152 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// deprecatedModel is a configured model that models.json marks as deprecated.
+type deprecatedModel struct {
+	name        string
+	deprecation pkgopenai.ModelDeprecation
+}
+
+// ModelDeprecations finds configured models that models.json marks as
+// deprecated. With auto-migration on, it replaces them in the configured
+// model lists as it is created, so commands offer the replacement, and moves
+// stored threads to the replacement on their next reply. A nil
+// ModelDeprecations migrates nothing.
+type ModelDeprecations struct {
+	logger  *zap.Logger
+	ses     *session.Session
+	pricing pkgopenai.PricingService
+
+	opsChannelID string
+	autoMigrate  bool
+	deprecated   []deprecatedModel
+}
+
+// NewModelDeprecations creates ModelDeprecations for the models in cfg and,
+// when auto-migration is on, rewrites cfg's model lists.
+func NewModelDeprecations(logger *zap.Logger, cfg *config.Config, ses *session.Session, pricing pkgopenai.PricingService) *ModelDeprecations {
+	d := &ModelDeprecations{
+		logger:       logger.Named("model_deprecations"),
+		ses:          ses,
+		pricing:      pricing,
+		opsChannelID: cfg.OpenAI.Deprecations.OpsChannelID,
+		autoMigrate:  cfg.OpenAI.Deprecations.AutoMigrate,
+	}
+
+	for _, model := range cfg.OpenAI.Models {
+		if deprecation := d.deprecation(model); deprecation != nil {
+			d.deprecated = append(d.deprecated, deprecatedModel{name: model, deprecation: *deprecation})
+			d.logger.Warn("Configured model is deprecated",
+				zap.String("model", model),
+				zap.String("shutdownDate", deprecation.ShutdownDate),
+				zap.String("replacement", deprecation.Replacement))
+		}
+	}
+
+	if d.autoMigrate {
+		cfg.OpenAI.Models = d.migrateList(cfg.OpenAI.Models)
+		cfg.OpenAI.Vision.Models = d.migrateList(cfg.OpenAI.Vision.Models)
+	}
+
+	return d
+}
+
+// Replacement returns the model a stored thread using model should move to,
+// if auto-migration is on and model is deprecated with a replacement.
+func (d *ModelDeprecations) Replacement(model string) (string, bool) {
+	if d == nil || !d.autoMigrate {
+		return "", false
+	}
+	deprecation := d.deprecation(model)
+	if deprecation == nil || deprecation.Replacement == "" || deprecation.Replacement == model {
+		return "", false
+	}
+
+	return deprecation.Replacement, true
+}
+
+// Announce warns the ops channel about deprecated models in the configuration.
+func (d *ModelDeprecations) Announce() {
+	if d == nil || len(d.deprecated) == 0 || d.opsChannelID == "" {
+		return
+	}
+
+	sf, err := discord.ParseSnowflake(d.opsChannelID)
+	if err != nil {
+		d.logger.Warn("Invalid deprecations ops channel", zap.Error(err), zap.String("channelID", d.opsChannelID))
+
+		return
+	}
+
+	lines := []string{"⚠️ **Deprecated models are configured**"}
+	for _, m := range d.deprecated {
+		line := fmt.Sprintf("• `%s`", m.name)
+		if m.deprecation.ShutdownDate != "" {
+			line += " shuts down on " + m.deprecation.ShutdownDate
+		} else {
+			line += " is deprecated"
+		}
+		switch {
+		case m.deprecation.Replacement == "":
+			line += "; no replacement is designated, so remove it from `openai.models`."
+		case d.autoMigrate:
+			line += fmt.Sprintf("; it was replaced with `%s`, and threads using it switch on their next reply.", m.deprecation.Replacement)
+		default:
+			line += fmt.Sprintf("; replace it with `%s` or turn on `openai.deprecations.auto_migrate`.", m.deprecation.Replacement)
+		}
+		lines = append(lines, line)
+	}
+
+	_, err = d.ses.SendMessageComplex(discord.ChannelID(sf), api.SendMessageData{
+		Content:         strings.Join(lines, "\n"),
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	if err != nil {
+		d.logger.Error("Failed to send deprecation warning", zap.Error(err), zap.String("channelID", d.opsChannelID))
+	}
+}
+
+// migrateList replaces the deprecated models in models with their
+// replacement, dropping duplicates.
+func (d *ModelDeprecations) migrateList(models []string) []string {
+	if len(models) == 0 {
+		return models
+	}
+
+	migrated := make([]string, 0, len(models))
+	for _, model := range models {
+		if replacement, ok := d.Replacement(model); ok {
+			d.logger.Info("Migrating deprecated model", zap.String("model", model), zap.String("replacement", replacement))
+			model = replacement
+		}
+		if !slices.Contains(migrated, model) {
+			migrated = append(migrated, model)
+		}
+	}
+
+	return migrated
+}
+
+// deprecation returns the deprecation of model, or nil if models.json does
+// not mark it as deprecated.
+func (d *ModelDeprecations) deprecation(model string) *pkgopenai.ModelDeprecation {
+	info, err := d.pricing.GetModelPricing(model)
+	if err != nil {
+		return nil
+	}
+
+	return info.Deprecation
+}
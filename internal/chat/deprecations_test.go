@@ -0,0 +1,65 @@
+package chat_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+	"github.com/Raikerian/go-discord-chatgpt/pkg/test"
+)
+
+func deprecationsPricing(t *testing.T) *test.MockPricingService {
+	t.Helper()
+
+	pricing := test.NewMockPricingService(t)
+	pricing.On("GetModelPricing", "gpt-4.5-preview").Return(&pkgopenai.ModelInfo{
+		Name:        "gpt-4.5-preview",
+		Deprecation: &pkgopenai.ModelDeprecation{ShutdownDate: "2025-07-14", Replacement: "gpt-4.1"},
+	}, nil).Maybe()
+	pricing.On("GetModelPricing", "gpt-4.1").Return(&pkgopenai.ModelInfo{Name: "gpt-4.1"}, nil).Maybe()
+	pricing.On("GetModelPricing", "custom-model").Return(nil, errors.New("pricing data not found")).Maybe()
+
+	return pricing
+}
+
+func TestModelDeprecations_AutoMigrate(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.Models = []string{"gpt-4.5-preview", "gpt-4.1", "custom-model"}
+	cfg.OpenAI.Vision.Models = []string{"gpt-4.5-preview"}
+	cfg.OpenAI.Deprecations.AutoMigrate = true
+
+	d := chat.NewModelDeprecations(zap.NewNop(), cfg, nil, deprecationsPricing(t))
+
+	assert.Equal(t, []string{"gpt-4.1", "custom-model"}, cfg.OpenAI.Models, "the replacement takes the deprecated model's place once")
+	assert.Equal(t, []string{"gpt-4.1"}, cfg.OpenAI.Vision.Models)
+
+	replacement, ok := d.Replacement("gpt-4.5-preview")
+	assert.True(t, ok, "stored threads move to the replacement")
+	assert.Equal(t, "gpt-4.1", replacement)
+
+	_, ok = d.Replacement("gpt-4.1")
+	assert.False(t, ok)
+	_, ok = d.Replacement("custom-model")
+	assert.False(t, ok, "models unknown to models.json are left alone")
+}
+
+func TestModelDeprecations_WarnOnly(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.Models = []string{"gpt-4.5-preview"}
+
+	d := chat.NewModelDeprecations(zap.NewNop(), cfg, nil, deprecationsPricing(t))
+
+	assert.Equal(t, []string{"gpt-4.5-preview"}, cfg.OpenAI.Models)
+	_, ok := d.Replacement("gpt-4.5-preview")
+	assert.False(t, ok, "threads are not migrated without auto_migrate")
+
+	var nilDeprecations *chat.ModelDeprecations
+	_, ok = nilDeprecations.Replacement("gpt-4.5-preview")
+	assert.False(t, ok)
+	nilDeprecations.Announce()
+}
@@ -0,0 +1,288 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/hooks"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+const (
+	// MinDiscussionCast and MaxDiscussionCast bound how many characters take part in a discussion.
+	MinDiscussionCast = 2
+	MaxDiscussionCast = 4
+
+	defaultDiscussionTurns   = 6
+	defaultDiscussionCostUSD = 0.25
+)
+
+// DiscussionStopReason tells why a discussion ended.
+type DiscussionStopReason string
+
+const (
+	// DiscussionCompleted means every requested turn was played.
+	DiscussionCompleted DiscussionStopReason = "completed"
+	// DiscussionCostCapped means the discussion stopped at the configured cost cap.
+	DiscussionCostCapped DiscussionStopReason = "cost_cap"
+)
+
+// Discussion is a prompt discussed by several characters taking turns.
+type Discussion struct {
+	GuildID  discord.GuildID
+	Prompt   string
+	Model    string
+	UserName string // display name of the user who asked
+	Cast     []characters.Character
+	// Turns is the number of replies requested, across all characters. Zero
+	// or more than the configured limit plays the configured limit.
+	Turns int
+}
+
+// DiscussionTurn is one reply of a discussion.
+type DiscussionTurn struct {
+	Character characters.Character
+	Content   string
+	Usage     openai.Usage
+}
+
+// DiscussionResult summarizes a played discussion.
+type DiscussionResult struct {
+	Turns   []DiscussionTurn
+	CostUSD float64
+	// CostKnown is false when the model has no pricing, in which case the
+	// cost cap could not be enforced and only the turn limit applied.
+	CostKnown bool
+	Stopped   DiscussionStopReason
+}
+
+// DiscussionRunner plays discussions between characters.
+type DiscussionRunner interface {
+	// Run plays d round-robin, calling post with each turn as soon as it is
+	// generated. An error from post ends the discussion.
+	Run(ctx context.Context, d Discussion, post func(DiscussionTurn) error) (DiscussionResult, error)
+}
+
+// NewDiscussionRunner creates a DiscussionRunner limited by the discussion config.
+func NewDiscussionRunner(logger *zap.Logger, cfg *config.Config, aiProvider AIProvider, pricingService pkgopenai.PricingService, hookPipeline hooks.Pipeline) DiscussionRunner {
+	maxTurns := cfg.Characters.Discussion.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = defaultDiscussionTurns
+	}
+	maxCost := cfg.Characters.Discussion.MaxCostUSD
+	if maxCost <= 0 {
+		maxCost = defaultDiscussionCostUSD
+	}
+
+	return &discussionRunner{
+		logger:         logger.Named("discussion_runner"),
+		aiProvider:     aiProvider,
+		pricingService: pricingService,
+		hooks:          hookPipeline,
+		maxTurns:       maxTurns,
+		maxCostUSD:     maxCost,
+	}
+}
+
+type discussionRunner struct {
+	logger         *zap.Logger
+	aiProvider     AIProvider
+	pricingService pkgopenai.PricingService
+	hooks          hooks.Pipeline
+	maxTurns       int
+	maxCostUSD     float64
+}
+
+// Run plays d until its turns are used up or its cost reaches the cap.
+func (r *discussionRunner) Run(ctx context.Context, d Discussion, post func(DiscussionTurn) error) (DiscussionResult, error) {
+	result := DiscussionResult{CostKnown: true, Stopped: DiscussionCompleted}
+	if len(d.Cast) < MinDiscussionCast {
+		return result, fmt.Errorf("a discussion needs at least %d characters", MinDiscussionCast)
+	}
+	if strings.TrimSpace(d.Prompt) == "" {
+		return result, errors.New("prompt is empty")
+	}
+	ctx = internalopenai.WithGuild(ctx, d.GuildID)
+
+	turns := d.Turns
+	if turns <= 0 || turns > r.maxTurns {
+		turns = r.maxTurns
+	}
+
+	for i := range turns {
+		if result.CostKnown && result.CostUSD >= r.maxCostUSD {
+			result.Stopped = DiscussionCostCapped
+
+			break
+		}
+
+		speaker := d.Cast[i%len(d.Cast)]
+		messages := r.hooks.BeforeRequest(d.GuildID, discussionMessages(d, speaker, result.Turns))
+		resp, err := r.aiProvider.GetChatCompletion(ctx, d.Model, messages)
+		if err != nil {
+			return result, fmt.Errorf("failed to generate reply for %s: %w", speaker.Name, err)
+		}
+
+		turn := DiscussionTurn{
+			Character: speaker,
+			Content:   r.hooks.AfterResponse(d.GuildID, resp.Choices[0].Message.Content),
+			Usage:     resp.Usage,
+		}
+		result.Turns = append(result.Turns, turn)
+
+		cost, err := r.pricingService.CalculateTokenCost(d.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+		if err != nil {
+			if result.CostKnown {
+				r.logger.Warn("Cannot price discussion model, only the turn limit applies",
+					zap.String("model", d.Model),
+					zap.Error(err))
+			}
+			result.CostKnown = false
+		}
+		result.CostUSD += cost
+
+		if err := post(turn); err != nil {
+			return result, fmt.Errorf("failed to post reply for %s: %w", speaker.Name, err)
+		}
+	}
+
+	r.logger.Info("Discussion finished",
+		zap.Int("turns", len(result.Turns)),
+		zap.Float64("estimatedCostUSD", result.CostUSD),
+		zap.String("stopped", string(result.Stopped)))
+
+	return result, nil
+}
+
+// discussionMessages builds the request for speaker's next turn. The speaker's
+// earlier turns are its own assistant messages; the other characters' turns
+// are user messages labelled with their names.
+func discussionMessages(d Discussion, speaker characters.Character, turns []DiscussionTurn) []openai.ChatCompletionMessage {
+	others := make([]string, 0, len(d.Cast)-1)
+	for _, c := range d.Cast {
+		if !strings.EqualFold(c.Name, speaker.Name) {
+			others = append(others, c.Name)
+		}
+	}
+
+	system := speaker.SystemPrompt() + fmt.Sprintf("\n\nYou are discussing a question from %s with %s. "+
+		"Build on or challenge what the others said instead of repeating it, and keep each reply to a short paragraph. "+
+		"Messages from the others start with their name; do not start yours with a name.",
+		d.UserName, strings.Join(others, ", "))
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: system},
+		{Role: openai.ChatMessageRoleUser, Content: d.Prompt, Name: SanitizeOpenAIName(d.UserName)},
+	}
+	for _, turn := range turns {
+		if strings.EqualFold(turn.Character.Name, speaker.Name) {
+			messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: turn.Content})
+
+			continue
+		}
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: turn.Character.Name + ": " + turn.Content,
+			Name:    SanitizeOpenAIName(turn.Character.Name),
+		})
+	}
+
+	return messages
+}
+
+// HandleDiscussionInteraction starts a thread where the characters of d take
+// turns replying to its prompt, each under its own webhook identity. The
+// thread only holds the discussion; messages sent to it are not answered.
+func (s *Service) HandleDiscussionInteraction(ctx context.Context, e *gateway.InteractionCreateEvent, d Discussion, modelOption string) error {
+	user := e.Sender()
+	if !s.canOpenThread(e.GuildID) {
+		return errors.New("discussions need a server the bot has joined")
+	}
+
+	model, err := s.modelSelector.SelectModel(modelOption)
+	if err != nil {
+		s.logger.Error("Failed to determine model", zap.Error(err))
+
+		return err
+	}
+	d.GuildID = e.GuildID
+	d.Model = model
+	d.UserName = GetUserDisplayName(user)
+
+	names := make([]string, len(d.Cast))
+	for i, c := range d.Cast {
+		names[i] = c.Name
+	}
+	summaryMessage := fmt.Sprintf(
+		"Starting a discussion for %s!\n**User:** %s\n**Characters:** %s\n**Topic:** %s\n**Model:** %s\n\nThe characters will take turns; replies in this thread are not answered.",
+		user.Username,
+		user.Mention(),
+		strings.Join(names, ", "),
+		d.Prompt,
+		model,
+	)
+
+	originalMessage, err := s.interactionManager.SendInitialResponse(s.ses, e.ID, e.Token, e.AppID, summaryMessage)
+	if err != nil {
+		return err
+	}
+
+	threadName := MakeThreadName(strings.Join(names, " vs "), d.Prompt, 100)
+	newThread, err := s.interactionManager.CreateThreadForInteraction(s.ses, originalMessage, e.AppID, e.Token, threadName, summaryMessage)
+	if err != nil {
+		return err
+	}
+	// The summary is not a /chat summary, so never try to continue the thread as a chat
+	s.conversationStore.AddToNegativeCache(newThread.ID.String())
+
+	s.logger.Info("Discussion thread created",
+		zap.String("threadID", newThread.ID.String()),
+		zap.Strings("characters", names),
+		zap.String("model", model))
+
+	stopTypingIndicator := s.interactionManager.StartTypingIndicator(s.ses, newThread.ID)
+	result, runErr := s.discussions.Run(ctx, d, func(turn DiscussionTurn) error {
+		_, err := s.sendPersonaMessage(newThread.ID, &turn.Character, turn.Content, nil, s.disclosure(e.GuildID))
+
+		return err
+	})
+	stopTypingIndicator()
+
+	closing := discussionClosing(result, runErr)
+	if _, err := s.interactionManager.SendMessage(s.ses, newThread.ID, closing, ""); err != nil {
+		s.logger.Warn("Failed to send discussion closing message", zap.Error(err), zap.String("threadID", newThread.ID.String()))
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("discussion failed: %w", runErr)
+	}
+
+	return nil
+}
+
+// discussionClosing describes how a discussion ended.
+func discussionClosing(result DiscussionResult, err error) string {
+	cost := fmt.Sprintf("$%.4f", result.CostUSD)
+	if !result.CostKnown {
+		cost = "unknown"
+	}
+
+	switch {
+	case err != nil:
+		return fmt.Sprintf("⚠️ The discussion stopped after %d replies because of an error. Estimated cost: %s", len(result.Turns), cost)
+	case result.Stopped == DiscussionCostCapped:
+		return fmt.Sprintf("💸 The discussion reached its cost limit after %d replies. Estimated cost: %s", len(result.Turns), cost)
+	default:
+		return fmt.Sprintf("🏁 The discussion ended after %d replies. Estimated cost: %s", len(result.Turns), cost)
+	}
+}
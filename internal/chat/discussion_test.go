@@ -0,0 +1,162 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/hooks"
+	"github.com/Raikerian/go-discord-chatgpt/pkg/test"
+)
+
+// scriptedAI answers every request with the next reply and records the requests.
+type scriptedAI struct {
+	requests [][]openai.ChatCompletionMessage
+	fail     bool
+}
+
+func (a *scriptedAI) GetChatCompletion(_ context.Context, _ string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	if a.fail {
+		return nil, errors.New("boom")
+	}
+	a.requests = append(a.requests, messages)
+
+	return &openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "reply"}}},
+		Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 5},
+	}, nil
+}
+
+func noHooks(t *testing.T) hooks.Pipeline {
+	t.Helper()
+	pipeline, err := hooks.NewPipeline(zap.NewNop(), nil)
+	require.NoError(t, err)
+
+	return pipeline
+}
+
+func discussionConfig(maxTurns int, maxCost float64) *config.Config {
+	cfg := &config.Config{}
+	cfg.Characters.Discussion = config.DiscussionConfig{Enabled: true, MaxTurns: maxTurns, MaxCostUSD: maxCost}
+
+	return cfg
+}
+
+func testDiscussion(turns int) chat.Discussion {
+	return chat.Discussion{
+		Prompt:   "Cats or dogs?",
+		Model:    "gpt-4",
+		UserName: "Alice",
+		Cast: []characters.Character{
+			{Name: "Ada", Personality: "Analytical."},
+			{Name: "Bo", Personality: "Contrarian."},
+		},
+		Turns: turns,
+	}
+}
+
+func TestDiscussionRunner_RoundRobin(t *testing.T) {
+	ai := &scriptedAI{}
+	pricing := test.NewMockPricingService(t)
+	pricing.On("CalculateTokenCost", "gpt-4", 10, 5).Return(0.001, nil)
+	runner := chat.NewDiscussionRunner(zap.NewNop(), discussionConfig(10, 1), ai, pricing, noHooks(t))
+
+	var speakers []string
+	result, err := runner.Run(context.Background(), testDiscussion(3), func(turn chat.DiscussionTurn) error {
+		speakers = append(speakers, turn.Character.Name)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Ada", "Bo", "Ada"}, speakers)
+	assert.Equal(t, chat.DiscussionCompleted, result.Stopped)
+	assert.True(t, result.CostKnown)
+	assert.InDelta(t, 0.003, result.CostUSD, 1e-9)
+
+	// Ada's second turn sees her own reply as assistant and Bo's as a named user message
+	last := ai.requests[2]
+	require.Len(t, last, 4)
+	assert.Equal(t, openai.ChatMessageRoleSystem, last[0].Role)
+	assert.Contains(t, last[0].Content, "You are Ada")
+	assert.Contains(t, last[0].Content, "with Bo")
+	assert.Equal(t, "Cats or dogs?", last[1].Content)
+	assert.Equal(t, openai.ChatMessageRoleAssistant, last[2].Role)
+	assert.Equal(t, openai.ChatMessageRoleUser, last[3].Role)
+	assert.Equal(t, "Bo: reply", last[3].Content)
+}
+
+func TestDiscussionRunner_TurnLimit(t *testing.T) {
+	ai := &scriptedAI{}
+	pricing := test.NewMockPricingService(t)
+	pricing.On("CalculateTokenCost", "gpt-4", 10, 5).Return(0.0, nil)
+	runner := chat.NewDiscussionRunner(zap.NewNop(), discussionConfig(4, 1), ai, pricing, noHooks(t))
+
+	result, err := runner.Run(context.Background(), testDiscussion(20), func(chat.DiscussionTurn) error { return nil })
+	require.NoError(t, err)
+	assert.Len(t, result.Turns, 4, "requested turns are capped by config")
+}
+
+func TestDiscussionRunner_CostCap(t *testing.T) {
+	ai := &scriptedAI{}
+	pricing := test.NewMockPricingService(t)
+	pricing.On("CalculateTokenCost", "gpt-4", 10, 5).Return(0.05, nil)
+	runner := chat.NewDiscussionRunner(zap.NewNop(), discussionConfig(10, 0.1), ai, pricing, noHooks(t))
+
+	result, err := runner.Run(context.Background(), testDiscussion(0), func(chat.DiscussionTurn) error { return nil })
+	require.NoError(t, err)
+	assert.Len(t, result.Turns, 2)
+	assert.Equal(t, chat.DiscussionCostCapped, result.Stopped)
+}
+
+func TestDiscussionRunner_UnknownPricing(t *testing.T) {
+	ai := &scriptedAI{}
+	pricing := test.NewMockPricingService(t)
+	pricing.On("CalculateTokenCost", "gpt-4", mock.Anything, mock.Anything).Return(0.0, errors.New("unknown model"))
+	runner := chat.NewDiscussionRunner(zap.NewNop(), discussionConfig(3, 0.1), ai, pricing, noHooks(t))
+
+	result, err := runner.Run(context.Background(), testDiscussion(0), func(chat.DiscussionTurn) error { return nil })
+	require.NoError(t, err)
+	assert.False(t, result.CostKnown)
+	assert.Len(t, result.Turns, 3)
+}
+
+func TestDiscussionRunner_Errors(t *testing.T) {
+	pricing := test.NewMockPricingService(t)
+
+	t.Run("NeedsTwoCharacters", func(t *testing.T) {
+		runner := chat.NewDiscussionRunner(zap.NewNop(), discussionConfig(3, 1), &scriptedAI{}, pricing, noHooks(t))
+		d := testDiscussion(0)
+		d.Cast = d.Cast[:1]
+
+		_, err := runner.Run(context.Background(), d, func(chat.DiscussionTurn) error { return nil })
+		assert.ErrorContains(t, err, "at least 2")
+	})
+
+	t.Run("AIFailure", func(t *testing.T) {
+		runner := chat.NewDiscussionRunner(zap.NewNop(), discussionConfig(3, 1), &scriptedAI{fail: true}, pricing, noHooks(t))
+
+		result, err := runner.Run(context.Background(), testDiscussion(0), func(chat.DiscussionTurn) error { return nil })
+		assert.ErrorContains(t, err, "Ada")
+		assert.Empty(t, result.Turns)
+	})
+
+	t.Run("PostFailure", func(t *testing.T) {
+		pricing := test.NewMockPricingService(t)
+		pricing.On("CalculateTokenCost", "gpt-4", 10, 5).Return(0.0, nil)
+		runner := chat.NewDiscussionRunner(zap.NewNop(), discussionConfig(3, 1), &scriptedAI{}, pricing, noHooks(t))
+
+		result, err := runner.Run(context.Background(), testDiscussion(0), func(chat.DiscussionTurn) error { return errors.New("webhook gone") })
+		assert.ErrorContains(t, err, "webhook gone")
+		assert.Len(t, result.Turns, 1)
+	})
+}
@@ -20,6 +20,12 @@ const (
 // MessageEmbedService defines the interface for managing Discord message embeds.
 type MessageEmbedService interface {
 	AddUsageFooter(ctx context.Context, message *discord.Message, usage openai.Usage, modelName string) error
+	// UsageEmbed builds the usage footer embed, for messages that are sent
+	// with it rather than edited afterwards.
+	UsageEmbed(usage openai.Usage, modelName string) discord.Embed
+	// AddTurnUsageFooter and TurnUsageEmbed cover replies that took several calls.
+	AddTurnUsageFooter(ctx context.Context, message *discord.Message, calls []CallUsage) error
+	TurnUsageEmbed(calls []CallUsage) discord.Embed
 }
 
 // discordEmbedService implements the MessageEmbedService interface for Discord.
@@ -40,29 +46,19 @@ func NewDiscordEmbedService(ses *session.Session, usageFormatter UsageFormatter,
 
 // AddUsageFooter adds a usage footer embed to a Discord message.
 func (s *discordEmbedService) AddUsageFooter(ctx context.Context, message *discord.Message, usage openai.Usage, modelName string) error {
-	// Format usage information
-	usageText, err := s.usageFormatter.FormatUsage(usage, modelName)
-	if err != nil {
-		s.logger.Warn("Failed to format usage information", zap.Error(err))
-		// Fallback to basic token info without cost
-		usageText = fmt.Sprintf("Completion: %d tokens, Total: %d tokens",
-			usage.CompletionTokens, usage.TotalTokens)
-	}
+	return s.AddTurnUsageFooter(ctx, message, []CallUsage{{Model: modelName, Usage: usage}})
+}
 
-	// Create embed with footer
-	embed := discord.Embed{
-		Footer: &discord.EmbedFooter{
-			Text: usageText,
-			Icon: openAIIconURL,
-		},
-	}
+// AddTurnUsageFooter adds a footer with the combined usage of calls to a Discord message.
+func (s *discordEmbedService) AddTurnUsageFooter(ctx context.Context, message *discord.Message, calls []CallUsage) error {
+	embed := s.TurnUsageEmbed(calls)
 
 	// Edit the message to add the embed
 	editData := api.EditMessageData{
 		Embeds: &[]discord.Embed{embed},
 	}
 
-	_, err = s.session.EditMessageComplex(message.ChannelID, message.ID, editData)
+	_, err := s.session.EditMessageComplex(message.ChannelID, message.ID, editData)
 	if err != nil {
 		s.logger.Warn("Failed to add usage footer to message",
 			zap.Error(err),
@@ -74,7 +70,35 @@ func (s *discordEmbedService) AddUsageFooter(ctx context.Context, message *disco
 
 	s.logger.Debug("Successfully added usage footer to message",
 		zap.String("messageID", message.ID.String()),
-		zap.String("usageText", usageText))
+		zap.String("usageText", embed.Footer.Text))
 
 	return nil
 }
+
+// UsageEmbed builds an embed whose footer shows token usage and cost.
+func (s *discordEmbedService) UsageEmbed(usage openai.Usage, modelName string) discord.Embed {
+	return s.TurnUsageEmbed([]CallUsage{{Model: modelName, Usage: usage}})
+}
+
+// TurnUsageEmbed builds an embed whose footer shows the combined usage and cost of calls.
+func (s *discordEmbedService) TurnUsageEmbed(calls []CallUsage) discord.Embed {
+	// Format usage information
+	usageText, err := s.usageFormatter.FormatTurnUsage(calls)
+	if err != nil {
+		s.logger.Warn("Failed to format usage information", zap.Error(err))
+		// Fallback to basic token info without cost
+		var completion, total int
+		for _, call := range calls {
+			completion += call.Usage.CompletionTokens
+			total += call.Usage.TotalTokens
+		}
+		usageText = fmt.Sprintf("Completion: %d tokens, Total: %d tokens", completion, total)
+	}
+
+	return discord.Embed{
+		Footer: &discord.EmbedFooter{
+			Text: usageText,
+			Icon: openAIIconURL,
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/privacy"
+)
+
+// conversationEraser forgets the /chat conversations a user started, both in
+// the conversation cache and in cold storage.
+type conversationEraser struct {
+	store    ConversationStore
+	archiver *ConversationArchiver
+}
+
+// NewConversationEraser creates the privacy.Eraser for chat conversations.
+func NewConversationEraser(store ConversationStore, archiver *ConversationArchiver) privacy.Eraser {
+	return &conversationEraser{store: store, archiver: archiver}
+}
+
+// Name implements privacy.Eraser.
+func (e *conversationEraser) Name() string {
+	return "conversations you started"
+}
+
+// ForgetUser implements privacy.Eraser. A conversation counts once even if it
+// was both cached and archived.
+func (e *conversationEraser) ForgetUser(ctx context.Context, userID discord.UserID) (int, error) {
+	archived, err := e.archiver.ForgetInitiator(ctx, userID)
+
+	return countThreads(e.store.ForgetInitiator(userID), archived), err
+}
+
+// participationEraser forgets the /chat conversations a user posted in
+// without starting them. Turns do not record who posted them, so the whole
+// conversation is dropped from the cache and cold storage; it is read again
+// from the thread in Discord if someone continues it.
+type participationEraser struct {
+	store    ConversationStore
+	archiver *ConversationArchiver
+}
+
+// NewParticipationEraser creates the privacy.Eraser for chat conversations
+// started by others.
+func NewParticipationEraser(store ConversationStore, archiver *ConversationArchiver) privacy.Eraser {
+	return &participationEraser{store: store, archiver: archiver}
+}
+
+// Name implements privacy.Eraser.
+func (e *participationEraser) Name() string {
+	return "conversations you took part in"
+}
+
+// ForgetUser implements privacy.Eraser.
+func (e *participationEraser) ForgetUser(ctx context.Context, userID discord.UserID) (int, error) {
+	archived, err := e.archiver.ForgetParticipant(ctx, userID)
+
+	return countThreads(e.store.ForgetParticipant(userID), archived), err
+}
+
+// countThreads counts the distinct threads of cached and archived.
+func countThreads(cached, archived []string) int {
+	threads := make(map[string]struct{})
+	for _, id := range cached {
+		threads[id] = struct{}{}
+	}
+	for _, id := range archived {
+		threads[id] = struct{}{}
+	}
+
+	return len(threads)
+}
+
+// deadLetterEraser forgets the undelivered replies to a user's messages. The
+// outbox is left alone: its replies are posted or handed to the dead letter
+// queue within its maximum age.
+type deadLetterEraser struct {
+	deadLetters *DeadLetters
+}
+
+// NewDeadLetterEraser creates the privacy.Eraser for undelivered replies.
+func NewDeadLetterEraser(deadLetters *DeadLetters) privacy.Eraser {
+	return &deadLetterEraser{deadLetters: deadLetters}
+}
+
+// Name implements privacy.Eraser.
+func (e *deadLetterEraser) Name() string {
+	return "undelivered replies to you"
+}
+
+// ForgetUser implements privacy.Eraser.
+func (e *deadLetterEraser) ForgetUser(_ context.Context, userID discord.UserID) (int, error) {
+	return e.deadLetters.ForgetRequester(userID)
+}
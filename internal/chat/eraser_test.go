@@ -0,0 +1,49 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func TestConversationErasers(t *testing.T) {
+	ses := session.New("Bot test")
+	store := chat.NewConversationStore(zap.NewNop(), 10, 10, chat.NewSummaryParser(zap.NewNop()), chat.NewContentNormalizer(ses), nil)
+	cold := storage.NewMemoryProvider()
+	archiver := chat.NewConversationArchiver(zap.NewNop(), &config.Config{}, ses, store, cold, nil)
+	start := func(threadID string, initiator, participant discord.UserID) {
+		store.StoreInitialConversation(threadID, "hi", "hello", "gpt-4o", "user", "bot", chat.ThreadAccess{InitiatorID: initiator}, "", nil, chat.SanitizeOpenAIName)
+		store.AddParticipant(threadID, participant)
+	}
+	start("10", 1, 1)
+	start("20", 2, 1)
+	start("30", 3, 4)
+	require.NoError(t, cold.Put(t.Context(), "conversations/40.json", []byte(`{"version":1,"thread_id":"40","initiator_id":"5","participants":["1"]}`)))
+	require.NoError(t, cold.Put(t.Context(), "conversations/50.json", []byte(`{"version":1,"thread_id":"50","initiator_id":"5","participants":["6"]}`)))
+
+	removed, err := chat.NewParticipationEraser(store, archiver).ForgetUser(t.Context(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed, "cached and archived conversations started by others are forgotten")
+	_, found := store.GetConversation("20")
+	assert.False(t, found)
+	assert.False(t, store.IsInNegativeCache("20"), "others can still continue the thread")
+	_, found = store.GetConversation("10")
+	assert.True(t, found, "conversations the user started are left to the other eraser")
+	_, err = cold.Get(t.Context(), "conversations/50.json")
+	require.NoError(t, err)
+
+	removed, err = chat.NewConversationEraser(store, archiver).ForgetUser(t.Context(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.True(t, store.IsInNegativeCache("10"))
+	_, found = store.GetConversation("30")
+	assert.True(t, found, "conversations without the user are kept")
+}
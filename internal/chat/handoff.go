@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// handoffPrompt asks for the summary posted for moderators taking over a thread.
+const handoffPrompt = "A human moderator is taking over this conversation because the assistant could not resolve it. " +
+	"Summarize it for them in a few short bullet points: what the user needs, what was already suggested or tried, " +
+	"and what is still unresolved. Write the summary only, without addressing the user."
+
+// ErrNotManaged is returned for threads that are not chat conversations.
+var ErrNotManaged = errors.New("thread is not a managed chat conversation")
+
+// HandOff cancels any reply being written in threadID and returns a summary
+// of its conversation for the human taking it over. The conversation is
+// loaded from the cache, the archive or the thread's history.
+func (s *Service) HandOff(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID) (string, error) {
+	if cancel, loaded := s.ongoingRequests.LoadAndDelete(threadID); loaded {
+		if cancelFunc, ok := cancel.(context.CancelFunc); ok {
+			cancelFunc()
+		}
+	}
+
+	data, err := s.loadConversation(ctx, threadID)
+	if err != nil {
+		return "", err
+	}
+
+	model := data.Model
+	if replacement, ok := s.deprecations.Replacement(model); ok {
+		model = replacement
+	}
+
+	messages := make([]openai.ChatCompletionMessage, 0, len(data.Messages)+1)
+	messages = append(messages, data.Messages...)
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: handoffPrompt})
+
+	resp, _, err := s.complete(withChannel(ctx, threadID), guildID, discord.NullChannelID, model, ForModel(s.cfg.OpenAI.Vision, model, messages))
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("OpenAI returned no choices for the handoff summary")
+	}
+	s.logger.Info("Summarized thread for handoff", zap.String("threadID", threadID.String()), zap.Int("historyLength", len(data.Messages)))
+
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// loadConversation returns the conversation of threadID from the cache, the
+// archive or, failing both, rebuilt from the thread's messages.
+func (s *Service) loadConversation(ctx context.Context, threadID discord.ChannelID) (*MessagesCacheData, error) {
+	threadIDStr := threadID.String()
+	if data, found := s.conversationStore.GetConversation(threadIDStr); found {
+		return data, nil
+	}
+	if data, found := s.archiver.Rehydrate(ctx, threadIDStr); found {
+		return data, nil
+	}
+
+	selfUser, err := s.getSelfUser()
+	if err != nil {
+		return nil, err
+	}
+	botDisplayName, err := s.getBotDisplayName()
+	if err != nil {
+		botDisplayName = defaultBotName
+	}
+	data, _, err := s.conversationStore.ReconstructAndCache(
+		ctx, s.ses, threadID, 0, selfUser, botDisplayName, SanitizeOpenAIName, GetUserDisplayName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, ErrNotManaged
+	}
+
+	return data, nil
+}
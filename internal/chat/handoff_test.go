@@ -0,0 +1,28 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func TestHandOff(t *testing.T) {
+	const threadID = discord.ChannelID(10)
+	ts := newTestService(t, &config.Config{}, nil)
+	ts.startThread(threadID, 5, chat.ThreadPolicyAnyone)
+
+	summary, err := ts.HandOff(t.Context(), 1, threadID)
+	require.NoError(t, err)
+	assert.Equal(t, "reply 1", summary)
+	assert.Contains(t, requestText(ts.ai.requests[0]), "hi there", "the conversation is summarized")
+
+	// A completion without choices fails instead of panicking
+	ts.ai.noChoices = true
+	_, err = ts.HandOff(t.Context(), 1, threadID)
+	require.Error(t, err)
+}
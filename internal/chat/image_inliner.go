@@ -0,0 +1,189 @@
+package chat
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultMaxImageBytes = 8 << 20
+	imageDownloadTimeout = 30 * time.Second
+	// imageCacheSize bounds the downloaded images kept for later turns.
+	imageCacheSize = 16
+)
+
+// imageUnavailable stands in for images that could not be downloaded, such as
+// attachments whose CDN links have expired.
+const imageUnavailable = "[image no longer available]"
+
+// ImageInliner downloads the images referenced in a request so the model is
+// sent the image itself rather than a link it may fail to fetch.
+type ImageInliner interface {
+	// Inline returns messages with each image URL replaced by the
+	// downloaded image. Images that cannot be used are replaced by a note. The
+	// given slice is not modified.
+	Inline(ctx context.Context, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage
+}
+
+// NewImageInliner creates an ImageInliner following the vision config.
+func NewImageInliner(logger *zap.Logger, cfg *config.Config) ImageInliner {
+	return NewImageInlinerFromClient(logger, cfg.OpenAI.Vision, &http.Client{Timeout: imageDownloadTimeout})
+}
+
+// NewImageInlinerFromClient creates an ImageInliner downloading images with client.
+func NewImageInlinerFromClient(logger *zap.Logger, cfg config.VisionConfig, client *http.Client) ImageInliner {
+	maxBytes := cfg.MaxImageBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxImageBytes
+	}
+	cache, err := lru.New[string, string](imageCacheSize)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create image cache: %v", err))
+	}
+
+	return &imageInliner{
+		logger:   logger.Named("image_inliner"),
+		enabled:  cfg.Enabled,
+		maxBytes: int64(maxBytes),
+		client:   client,
+		cache:    cache,
+	}
+}
+
+type imageInliner struct {
+	logger   *zap.Logger
+	enabled  bool
+	maxBytes int64
+	client   *http.Client
+	cache    *lru.Cache[string, string] // key: image URL, value: data URL
+}
+
+// Inline downloads the images not already cached concurrently. Cached
+// conversations keep the URLs, so later turns reuse the downloads.
+func (r *imageInliner) Inline(ctx context.Context, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if !r.enabled {
+		return messages
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, msg := range messages {
+		for _, part := range msg.MultiContent {
+			if part.ImageURL != nil && isRemoteImage(part.ImageURL.URL) && !seen[part.ImageURL.URL] {
+				seen[part.ImageURL.URL] = true
+				urls = append(urls, part.ImageURL.URL)
+			}
+		}
+	}
+	if len(urls) == 0 {
+		return messages
+	}
+
+	// Each download writes its own slot, so no lock is needed
+	dataURLs := make([]string, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dataURL, err := r.dataURL(ctx, url)
+			if err != nil {
+				r.logger.Info("Failed to download image", zap.String("url", url), zap.Error(err))
+
+				return
+			}
+			dataURLs[i] = dataURL
+		}()
+	}
+	wg.Wait()
+
+	images := make(map[string]string, len(urls))
+	for i, url := range urls {
+		images[url] = dataURLs[i]
+	}
+
+	out := append([]openai.ChatCompletionMessage(nil), messages...)
+	for i, msg := range out {
+		if len(msg.MultiContent) == 0 {
+			continue
+		}
+		parts := make([]openai.ChatMessagePart, 0, len(msg.MultiContent))
+		for _, part := range msg.MultiContent {
+			if part.ImageURL == nil || !isRemoteImage(part.ImageURL.URL) {
+				parts = append(parts, part)
+
+				continue
+			}
+			dataURL := images[part.ImageURL.URL]
+			if dataURL == "" {
+				parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: imageUnavailable})
+
+				continue
+			}
+			image := *part.ImageURL
+			image.URL = dataURL
+			part.ImageURL = &image
+			parts = append(parts, part)
+		}
+		out[i].MultiContent = parts
+	}
+
+	return out
+}
+
+// dataURL returns the image at url as a base64 data URL, downloading it
+// unless it is cached.
+func (r *imageInliner) dataURL(ctx context.Context, url string) (string, error) {
+	if dataURL, ok := r.cache.Get(url); ok {
+		return dataURL, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download image: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, r.maxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
+	}
+	if int64(len(data)) > r.maxBytes {
+		return "", fmt.Errorf("image is over the %d byte limit", r.maxBytes)
+	}
+	// The content is checked rather than the header, which the CDN may get wrong
+	contentType := http.DetectContentType(data)
+	if !imageTypes[contentType] {
+		return "", fmt.Errorf("unsupported image type %q", contentType)
+	}
+
+	dataURL := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	r.cache.Add(url, dataURL)
+
+	return dataURL, nil
+}
+
+// isRemoteImage reports whether url points at an image to download, rather
+// than one already inlined.
+func isRemoteImage(url string) bool {
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")
+}
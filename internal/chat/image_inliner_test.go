@@ -0,0 +1,84 @@
+package chat_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// pngHeader is enough of a PNG file for its type to be detected.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func imageTurn(urls ...string) openai.ChatCompletionMessage {
+	msg := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser}
+	msg.MultiContent = append(msg.MultiContent, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: "What is this?"})
+	for _, url := range urls {
+		msg.MultiContent = append(msg.MultiContent, openai.ChatMessagePart{
+			Type:     openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{URL: url, Detail: openai.ImageURLDetailLow},
+		})
+	}
+
+	return msg
+}
+
+func TestImageInliner_Inline(t *testing.T) {
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat.png":
+			downloads++
+			_, _ = w.Write(pngHeader)
+		case "/notes.png":
+			_, _ = w.Write([]byte("just some text"))
+		case "/huge.png":
+			_, _ = w.Write(append(pngHeader, make([]byte, 64)...))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.VisionConfig{Enabled: true, MaxImageBytes: 32}
+	inliner := chat.NewImageInlinerFromClient(zap.NewNop(), cfg, http.DefaultClient)
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You are helpful."},
+		imageTurn(srv.URL+"/cat.png", srv.URL+"/expired.png", srv.URL+"/notes.png", srv.URL+"/huge.png"),
+	}
+
+	out := inliner.Inline(context.Background(), messages)
+	require.Len(t, out, 2)
+	parts := out[1].MultiContent
+	require.Len(t, parts, 5)
+	assert.Equal(t, "What is this?", parts[0].Text)
+	require.NotNil(t, parts[1].ImageURL)
+	assert.Equal(t, "data:image/png;base64,"+base64.StdEncoding.EncodeToString(pngHeader), parts[1].ImageURL.URL)
+	assert.Equal(t, openai.ImageURLDetailLow, parts[1].ImageURL.Detail)
+	for _, part := range parts[2:] {
+		assert.Equal(t, openai.ChatMessagePartTypeText, part.Type)
+		assert.Equal(t, "[image no longer available]", part.Text)
+	}
+	assert.True(t, strings.HasPrefix(messages[1].MultiContent[1].ImageURL.URL, srv.URL), "cached messages are not modified")
+
+	// Later turns reuse the download
+	inliner.Inline(context.Background(), messages)
+	assert.Equal(t, 1, downloads)
+}
+
+func TestImageInliner_Disabled(t *testing.T) {
+	inliner := chat.NewImageInlinerFromClient(zap.NewNop(), config.VisionConfig{}, http.DefaultClient)
+	messages := []openai.ChatCompletionMessage{imageTurn("https://cdn.example.com/cat.png")}
+
+	assert.Equal(t, messages, inliner.Inline(context.Background(), messages))
+}
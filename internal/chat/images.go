@@ -0,0 +1,137 @@
+package chat
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// maxImageAttachments bounds the images taken from one message.
+const maxImageAttachments = 4
+
+// imagePlaceholder stands in for images sent to models that cannot see them.
+const imagePlaceholder = "[image attached]"
+
+// imageExtensions are the attachment types passed on when Discord sends no content type.
+var imageExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+
+// imageTypes are the image formats the vision API accepts.
+var imageTypes = map[string]bool{"image/png": true, "image/jpeg": true, "image/gif": true, "image/webp": true}
+
+// isImage reports whether a is an image the vision API accepts.
+func isImage(a discord.Attachment) bool {
+	if a.ContentType != "" {
+		mediaType, _, _ := strings.Cut(a.ContentType, ";")
+
+		return imageTypes[strings.TrimSpace(mediaType)]
+	}
+
+	return imageExtensions[strings.ToLower(path.Ext(a.Filename))]
+}
+
+// UserTurn builds the user message for content and the images among
+// attachments. Images are kept as URL references, so cached and reconstructed
+// conversations both point the model at the attachment on Discord's CDN.
+func UserTurn(content, name string, attachments []discord.Attachment) openai.ChatCompletionMessage {
+	msg := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: content, Name: name}
+
+	var images []openai.ChatMessagePart
+	for _, a := range attachments {
+		if len(images) == maxImageAttachments {
+			break
+		}
+		if isImage(a) {
+			images = append(images, openai.ChatMessagePart{
+				Type:     openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{URL: a.URL},
+			})
+		}
+	}
+	if len(images) == 0 {
+		return msg
+	}
+
+	// Content and MultiContent cannot both be set
+	msg.Content = ""
+	if strings.TrimSpace(content) != "" {
+		msg.MultiContent = append(msg.MultiContent, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: content})
+	}
+	msg.MultiContent = append(msg.MultiContent, images...)
+
+	return msg
+}
+
+// messageText returns the text of msg, joining its text parts.
+func messageText(msg openai.ChatCompletionMessage) string {
+	if len(msg.MultiContent) == 0 {
+		return msg.Content
+	}
+
+	var texts []string
+	for _, part := range msg.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			texts = append(texts, part.Text)
+		}
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// VisionEnabled reports whether vision passes images to model.
+func VisionEnabled(vision config.VisionConfig, model string) bool {
+	if !vision.Enabled {
+		return false
+	}
+
+	return len(vision.Models) == 0 || slices.Contains(vision.Models, model)
+}
+
+// ForModel prepares messages holding images for model. Vision models get the
+// configured detail level; other models get a placeholder noting the image.
+// The given messages are not modified.
+func ForModel(visionCfg config.VisionConfig, model string, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	vision := VisionEnabled(visionCfg, model)
+	detail := openai.ImageURLDetail(visionCfg.Detail)
+	if detail == "" {
+		detail = openai.ImageURLDetailAuto
+	}
+
+	var out []openai.ChatCompletionMessage
+	for i, msg := range messages {
+		if len(msg.MultiContent) == 0 {
+			continue
+		}
+		if out == nil {
+			out = append([]openai.ChatCompletionMessage(nil), messages...)
+		}
+
+		if !vision {
+			text := strings.TrimSpace(messageText(msg) + "\n" + imagePlaceholder)
+			out[i].MultiContent = nil
+			out[i].Content = text
+
+			continue
+		}
+
+		parts := make([]openai.ChatMessagePart, len(msg.MultiContent))
+		for j, part := range msg.MultiContent {
+			if part.ImageURL != nil {
+				image := *part.ImageURL
+				image.Detail = detail
+				part.ImageURL = &image
+			}
+			parts[j] = part
+		}
+		out[i].MultiContent = parts
+	}
+	if out == nil {
+		return messages
+	}
+
+	return out
+}
@@ -0,0 +1,83 @@
+package chat_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+var testAttachments = []discord.Attachment{
+	{Filename: "cat.png", ContentType: "image/png", URL: "https://cdn.example/cat.png"},
+	{Filename: "notes.txt", ContentType: "text/plain", URL: "https://cdn.example/notes.txt"},
+	{Filename: "dog.JPG", URL: "https://cdn.example/dog.JPG"},
+}
+
+func TestUserTurn(t *testing.T) {
+	msg := chat.UserTurn("What breed?", "Alice", testAttachments)
+	assert.Empty(t, msg.Content)
+	require.Len(t, msg.MultiContent, 3)
+	assert.Equal(t, "What breed?", msg.MultiContent[0].Text)
+	assert.Equal(t, "https://cdn.example/cat.png", msg.MultiContent[1].ImageURL.URL)
+	assert.Equal(t, "https://cdn.example/dog.JPG", msg.MultiContent[2].ImageURL.URL)
+
+	imageOnly := chat.UserTurn("", "Alice", testAttachments[:1])
+	require.Len(t, imageOnly.MultiContent, 1)
+	assert.Equal(t, openai.ChatMessagePartTypeImageURL, imageOnly.MultiContent[0].Type)
+
+	plain := chat.UserTurn("hi", "Alice", testAttachments[1:2])
+	assert.Equal(t, "hi", plain.Content)
+	assert.Nil(t, plain.MultiContent)
+
+	// The vision API cannot read every image format
+	svg := chat.UserTurn("hi", "Alice", []discord.Attachment{{Filename: "logo.svg", ContentType: "image/svg+xml", URL: "https://cdn.example/logo.svg"}})
+	assert.Nil(t, svg.MultiContent)
+
+	// The cached representation survives the archive's JSON round trip
+	b, err := json.Marshal(msg)
+	require.NoError(t, err)
+	var decoded openai.ChatCompletionMessage
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, msg.MultiContent, decoded.MultiContent)
+}
+
+func TestForModel(t *testing.T) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hello"},
+		chat.UserTurn("What breed?", "Alice", testAttachments[:1]),
+	}
+	vision := config.VisionConfig{Enabled: true, Models: []string{"gpt-4o"}, Detail: "low"}
+
+	out := chat.ForModel(vision, "gpt-4o", messages)
+	require.Len(t, out[1].MultiContent, 2)
+	assert.Equal(t, openai.ImageURLDetailLow, out[1].MultiContent[1].ImageURL.Detail)
+	assert.Empty(t, messages[1].MultiContent[1].ImageURL.Detail, "cached messages are not modified")
+
+	out = chat.ForModel(vision, "gpt-3.5-turbo", messages)
+	assert.Nil(t, out[1].MultiContent)
+	assert.Equal(t, "What breed?\n[image attached]", out[1].Content)
+	assert.Len(t, messages[1].MultiContent, 2, "cached messages are not modified")
+
+	textOnly := messages[:1]
+	assert.Equal(t, textOnly, chat.ForModel(config.VisionConfig{}, "gpt-4o", textOnly))
+}
+
+func TestMergeUserTurns(t *testing.T) {
+	merged := chat.MergeUserTurns(chat.UserTurn("hey", "Alice", nil), chat.UserTurn("quick question", "Alice", nil))
+	assert.Equal(t, "hey\nquick question", merged.Content)
+	assert.Equal(t, "Alice", merged.Name)
+	assert.Nil(t, merged.MultiContent)
+
+	// Images from either message are kept after the combined text
+	withImage := chat.MergeUserTurns(chat.UserTurn("look", "Alice", testAttachments[:1]), chat.UserTurn("what breed?", "Alice", nil))
+	assert.Empty(t, withImage.Content)
+	require.Len(t, withImage.MultiContent, 2)
+	assert.Equal(t, "look\nwhat breed?", withImage.MultiContent[0].Text)
+	assert.Equal(t, "https://cdn.example/cat.png", withImage.MultiContent[1].ImageURL.URL)
+}
@@ -0,0 +1,97 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+)
+
+// canOpenThread reports whether a /chat interaction from guildID can be moved
+// into a thread. User-installed interactions from DMs, group DMs and guilds
+// the bot has not joined can only be answered through the interaction itself.
+func (s *Service) canOpenThread(guildID discord.GuildID) bool {
+	if !guildID.IsValid() {
+		return false
+	}
+
+	if joined, ok := s.joinedGuilds.Load(guildID); ok {
+		return joined.(bool)
+	}
+
+	// Fall back to the API when no guild event has been seen yet
+	_, err := s.ses.Guild(guildID)
+	joined := err == nil
+	s.joinedGuilds.Store(guildID, joined)
+
+	return joined
+}
+
+// handleGuildCreate records guilds the bot is a member of.
+func (s *Service) handleGuildCreate(e *gateway.GuildCreateEvent) {
+	s.joinedGuilds.Store(e.ID, true)
+}
+
+// handleGuildDelete forgets guilds the bot was removed from. Outages also
+// produce this event, but do not remove the bot from the guild.
+func (s *Service) handleGuildDelete(e *gateway.GuildDeleteEvent) {
+	if !e.Unavailable {
+		s.joinedGuilds.Store(e.ID, false)
+	}
+}
+
+// handleInPlaceChat answers a /chat interaction directly in the interaction
+// response. There is no thread, so the exchange is not continued afterwards.
+func (s *Service) handleInPlaceChat(ctx context.Context, e *gateway.InteractionCreateEvent, userPrompt, modelToUse string, character *characters.Character) error {
+	s.logger.Info("Answering chat interaction in place",
+		zap.String("guildID", e.GuildID.String()),
+		zap.String("channelID", e.ChannelID.String()),
+	)
+
+	if err := s.interactionManager.DeferResponse(s.ses, e.ID, e.Token); err != nil {
+		return err
+	}
+
+	modelPrompt, truncationNotice := s.limitPrompt(e.GuildID, userPrompt)
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: modelPrompt,
+			Name:    SanitizeOpenAIName(GetUserDisplayName(e.Sender())),
+		},
+	}
+
+	aiResponse, _, err := s.complete(withChannel(withRequester(ctx, e.SenderID()), e.ChannelID), e.GuildID, discord.NullChannelID, modelToUse, withPersona(character, messages))
+	if err != nil {
+		errMsg := userErrorMessage(err, "Sorry, I encountered an error trying to reach the AI. Please try again later.")
+		if _, sendErr := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, errMsg, ""); sendErr != nil {
+			s.logger.Error("Failed to send error message after OpenAI failure", zap.Error(sendErr))
+		}
+
+		return err
+	}
+
+	// Webhooks are unavailable outside joined guilds, so the character is only named
+	characterLine := ""
+	if character != nil {
+		characterLine = characterSummaryLine(character.Name)
+	}
+	if truncationNotice != "" {
+		truncationNotice += "\n"
+	}
+	content := fmt.Sprintf("%s%s**Prompt:** %s\n**Model:** %s\n\n%s", truncationNotice, characterLine, userPrompt, modelToUse, aiResponse.Choices[0].Message.Content)
+	if _, err := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, content, s.disclosure(e.GuildID)); err != nil {
+		s.logger.Error("Failed to send in-place AI response", zap.Error(err))
+
+		return fmt.Errorf("failed to send AI response to Discord: %w", err)
+	}
+
+	s.logger.Info("In-place chat interaction completed successfully")
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/diamondburned/arikawa/v3/session"
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
 	"go.uber.org/zap"
+
+	internaldiscord "github.com/Raikerian/go-discord-chatgpt/internal/discord"
 )
 
 const (
@@ -25,25 +28,37 @@ type DiscordInteractionManager interface {
 	// StartTypingIndicator sends typing indicators periodically until the returned stop function is called.
 	StartTypingIndicator(ses *session.Session, channelID discord.ChannelID) (stopFunc func())
 	// SendMessage sends a message to a channel, handling long messages by splitting them.
+	// Every part ends with footer, if any. Mentions only notify as the mention policy allows.
 	// Returns the ID of the last message sent (important for multi-part messages).
-	SendMessage(ses *session.Session, channelID discord.ChannelID, content string) (*discord.Message, error)
+	SendMessage(ses *session.Session, channelID discord.ChannelID, content, footer string) (*discord.Message, error)
+	// DeferResponse acknowledges an interaction that will be answered in place later.
+	DeferResponse(ses *session.Session, eventID discord.InteractionID, eventToken string) error
+	// SendInteractionMessage answers a deferred interaction in place, splitting long
+	// content across follow-up messages that each end with footer, if any.
+	// Returns the last message sent.
+	SendInteractionMessage(ses *session.Session, appID discord.AppID, eventToken, content, footer string) (*discord.Message, error)
 }
 
 // NewDiscordInteractionManager creates a new instance of DiscordInteractionManager.
-func NewDiscordInteractionManager(logger *zap.Logger) DiscordInteractionManager {
+// Everything it posts may contain generated text or user prompts, so mentions
+// follow the mention policy.
+func NewDiscordInteractionManager(logger *zap.Logger, mentions *internaldiscord.MentionPolicy) DiscordInteractionManager {
 	return &discordInteractionManagerImpl{
-		logger: logger.Named("discord_interaction_manager"),
+		logger:   logger.Named("discord_interaction_manager"),
+		mentions: mentions,
 	}
 }
 
 type discordInteractionManagerImpl struct {
-	logger *zap.Logger
+	logger   *zap.Logger
+	mentions *internaldiscord.MentionPolicy
 }
 
 // SendInitialResponse sends the first response to the interaction.
 func (dim *discordInteractionManagerImpl) SendInitialResponse(ses *session.Session, eventID discord.InteractionID, eventToken string, appID discord.AppID, summaryMessage string) (*discord.Message, error) {
 	initialResponseData := api.InteractionResponseData{
-		Content: option.NewNullableString(summaryMessage),
+		Content:         option.NewNullableString(dim.mentions.Sanitize(summaryMessage)),
+		AllowedMentions: dim.mentions.Allowed(),
 	}
 	initialResponse := api.InteractionResponse{
 		Type: api.MessageInteractionWithSource,
@@ -92,7 +107,8 @@ func (dim *discordInteractionManagerImpl) CreateThreadForInteraction(ses *sessio
 		dim.logger.Error("Failed to create thread from message", zap.Error(err))
 		errMsgContent := originalSummaryMessageForFallback + "\n\n**(Sorry, I couldn't create a discussion thread for this chat. Please try again or contact an administrator if the issue persists.)**"
 		_, editErr := ses.EditInteractionResponse(appID, eventToken, api.EditInteractionResponseData{
-			Content: option.NewNullableString(errMsgContent),
+			Content:         option.NewNullableString(dim.mentions.Sanitize(errMsgContent)),
+			AllowedMentions: dim.mentions.Allowed(),
 		})
 		if editErr != nil {
 			dim.logger.Error("Failed to edit interaction response to indicate thread creation failure", zap.Error(editErr))
@@ -135,6 +151,49 @@ func (dim *discordInteractionManagerImpl) StartTypingIndicator(ses *session.Sess
 }
 
 // SendMessage sends a message to a channel, handling long messages by splitting them.
-func (dim *discordInteractionManagerImpl) SendMessage(ses *session.Session, channelID discord.ChannelID, content string) (*discord.Message, error) {
-	return SendLongMessage(ses, channelID, content)
+func (dim *discordInteractionManagerImpl) SendMessage(ses *session.Session, channelID discord.ChannelID, content, footer string) (*discord.Message, error) {
+	return SendLongMessage(ses, channelID, dim.mentions.Sanitize(content), footer, dim.mentions.Allowed())
+}
+
+// DeferResponse acknowledges the interaction with a "thinking" state.
+func (dim *discordInteractionManagerImpl) DeferResponse(ses *session.Session, eventID discord.InteractionID, eventToken string) error {
+	err := ses.RespondInteraction(eventID, eventToken, api.InteractionResponse{
+		Type: api.DeferredMessageInteractionWithSource,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to defer interaction response: %w", err)
+	}
+
+	return nil
+}
+
+// SendInteractionMessage replaces the deferred response with the first part of
+// content and sends the rest as follow-ups. Interaction webhooks work even where
+// the bot cannot post to the channel, such as user-installed DMs.
+func (dim *discordInteractionManagerImpl) SendInteractionMessage(ses *session.Session, appID discord.AppID, eventToken, content, footer string) (*discord.Message, error) {
+	parts := SplitMessageWithFooter(dim.mentions.Sanitize(content), footer)
+	if len(parts) == 0 {
+		return nil, errors.New("interaction message is empty")
+	}
+
+	lastMessage, err := ses.EditInteractionResponse(appID, eventToken, api.EditInteractionResponseData{
+		Content:         option.NewNullableString(parts[0]),
+		AllowedMentions: dim.mentions.Allowed(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit interaction response: %w", err)
+	}
+
+	for i, part := range parts[1:] {
+		msg, err := ses.FollowUpInteraction(appID, eventToken, api.InteractionResponseData{
+			Content:         option.NewNullableString(part),
+			AllowedMentions: dim.mentions.Allowed(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to send follow-up part %d/%d: %w", i+2, len(parts), err)
+		}
+		lastMessage = msg
+	}
+
+	return lastMessage, nil
 }
@@ -0,0 +1,376 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const defaultMaxConcurrentRequests = 5
+
+// RequestPriority orders the requests waiting for a slot.
+type RequestPriority int
+
+const (
+	// PriorityText is the priority of chat completions.
+	PriorityText RequestPriority = iota
+	// PriorityVoice is the priority of voice session turns, which someone is
+	// waiting to hear. They are served before any waiting text request.
+	PriorityVoice
+)
+
+type priorityKey struct{}
+
+// WithPriority sets the priority of the requests that acquire a slot with ctx.
+func WithPriority(ctx context.Context, priority RequestPriority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityFrom(ctx context.Context) RequestPriority {
+	priority, _ := ctx.Value(priorityKey{}).(RequestPriority)
+
+	return priority
+}
+
+// RequestLimiter bounds how many AI requests run at once, globally and per
+// guild. Waiting voice turns are served first, then waiting requests are
+// served round-robin across guilds, so one busy guild cannot starve the
+// others. The global limit adapts: it is halved when OpenAI rate limits a
+// request and grows back by one after a full window of successful requests.
+type RequestLimiter interface {
+	// Acquire waits for a slot for a request from guildID. If the request has
+	// to wait, onQueued, when set, is called once with its position in the
+	// queue. The priority set on ctx with WithPriority places the request
+	// ahead of waiting requests of lower priority. release must be called
+	// with the request's error when it is done.
+	Acquire(ctx context.Context, guildID discord.GuildID, onQueued func(position int)) (release func(err error), err error)
+}
+
+// NewRequestLimiter creates a RequestLimiter from openai.max_concurrent_requests
+// and openai.max_concurrent_requests_per_guild.
+func NewRequestLimiter(logger *zap.Logger, cfg *config.Config) RequestLimiter {
+	maxGlobal := cfg.OpenAI.MaxConcurrentRequests
+	if maxGlobal <= 0 {
+		maxGlobal = defaultMaxConcurrentRequests
+	}
+	maxGuild := cfg.OpenAI.MaxConcurrentRequestsPerGuild
+	if maxGuild <= 0 || maxGuild > maxGlobal {
+		maxGuild = maxGlobal
+	}
+
+	return &requestLimiter{
+		logger:        logger.Named("request_limiter"),
+		maxGlobal:     maxGlobal,
+		maxGuild:      maxGuild,
+		limit:         maxGlobal,
+		activeByGuild: make(map[discord.GuildID]int),
+		queues:        make(map[discord.GuildID][]*limiterWaiter),
+	}
+}
+
+type limiterWaiter struct {
+	guildID  discord.GuildID
+	priority RequestPriority
+	ready    chan struct{} // closed once the waiter holds a slot
+}
+
+type requestLimiter struct {
+	logger    *zap.Logger
+	maxGlobal int
+	maxGuild  int
+
+	mu            sync.Mutex
+	limit         int // current global limit, between 1 and maxGlobal
+	successes     int // successful requests since the limit last changed
+	active        int
+	activeByGuild map[discord.GuildID]int
+	queues        map[discord.GuildID][]*limiterWaiter
+	ring          []discord.GuildID // guilds with waiters, served in turn starting at next
+	next          int
+	urgent        int // waiters of PriorityVoice, queued at the front of their guild's queue
+}
+
+// Acquire takes a slot at once when one is free and no request of the same
+// guild and priority is waiting, and otherwise queues the request: behind
+// the waiting requests of its guild of the same or a higher priority.
+func (l *requestLimiter) Acquire(ctx context.Context, guildID discord.GuildID, onQueued func(position int)) (func(err error), error) {
+	priority := priorityFrom(ctx)
+	l.mu.Lock()
+	queue := l.queues[guildID]
+	if l.active < l.limit && l.activeByGuild[guildID] < l.maxGuild && (len(queue) == 0 || queue[0].priority < priority) {
+		l.take(guildID)
+		l.mu.Unlock()
+
+		return l.releaser(guildID), nil
+	}
+
+	w := &limiterWaiter{guildID: guildID, priority: priority, ready: make(chan struct{})}
+	if len(queue) == 0 {
+		l.ring = append(l.ring, guildID)
+	}
+	at := len(queue)
+	for at > 0 && queue[at-1].priority < priority {
+		at--
+	}
+	l.queues[guildID] = slices.Insert(queue, at, w)
+	if priority > PriorityText {
+		l.urgent++
+	}
+	position := l.position(w)
+	l.mu.Unlock()
+
+	l.logger.Debug("Request queued", zap.String("guildID", guildID.String()), zap.Int("position", position))
+	if onQueued != nil {
+		onQueued(position)
+	}
+
+	select {
+	case <-w.ready:
+		return l.releaser(guildID), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-w.ready:
+			// The slot was granted as the request gave up; hand it on
+			l.done(guildID)
+		default:
+			l.dequeue(w)
+		}
+
+		return nil, fmt.Errorf("gave up waiting for a request slot: %w", ctx.Err())
+	}
+}
+
+// releaser returns the release function of a slot held by guildID.
+func (l *requestLimiter) releaser(guildID discord.GuildID) func(err error) {
+	var once sync.Once
+
+	return func(err error) {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.adapt(err)
+			l.done(guildID)
+		})
+	}
+}
+
+// take records a running request. The caller must hold l.mu.
+func (l *requestLimiter) take(guildID discord.GuildID) {
+	l.active++
+	l.activeByGuild[guildID]++
+}
+
+// done frees the slot of guildID and hands free slots to waiters. The caller must hold l.mu.
+func (l *requestLimiter) done(guildID discord.GuildID) {
+	l.active--
+	if l.activeByGuild[guildID]--; l.activeByGuild[guildID] <= 0 {
+		delete(l.activeByGuild, guildID)
+	}
+	l.dispatch()
+}
+
+// dispatch grants free slots to waiting voice turns, then to the first
+// waiter of each guild in turn, skipping guilds at their own limit. The
+// caller must hold l.mu.
+func (l *requestLimiter) dispatch() {
+	for l.active < l.limit && l.urgent > 0 {
+		granted := false
+		for i, guildID := range l.ring {
+			if l.queues[guildID][0].priority > PriorityText && l.activeByGuild[guildID] < l.maxGuild {
+				// Urgent turns do not use up the guild's round-robin turn
+				l.grant(i, l.next)
+				granted = true
+
+				break
+			}
+		}
+		if !granted {
+			break
+		}
+	}
+
+	for l.active < l.limit && len(l.ring) > 0 {
+		granted := false
+		for range len(l.ring) {
+			i := l.next % len(l.ring)
+			if l.activeByGuild[l.ring[i]] >= l.maxGuild {
+				l.next = i + 1
+
+				continue
+			}
+
+			l.grant(i, i+1)
+			granted = true
+
+			break
+		}
+		if !granted {
+			return
+		}
+	}
+}
+
+// grant gives a slot to the first waiter of the guild at ring index i, and
+// moves the round-robin to next, adjusted if the guild leaves the ring. The
+// caller must hold l.mu.
+func (l *requestLimiter) grant(i, next int) {
+	guildID := l.ring[i]
+	w := l.queues[guildID][0]
+	l.queues[guildID] = l.queues[guildID][1:]
+	if len(l.queues[guildID]) == 0 {
+		delete(l.queues, guildID)
+		l.ring = append(l.ring[:i], l.ring[i+1:]...)
+		if next > i {
+			next--
+		}
+	}
+	l.next = next
+	if w.priority > PriorityText {
+		l.urgent--
+	}
+	l.take(guildID)
+	close(w.ready)
+}
+
+// dequeue removes a waiter that gave up. The caller must hold l.mu.
+func (l *requestLimiter) dequeue(w *limiterWaiter) {
+	queue := l.queues[w.guildID]
+	for i, queued := range queue {
+		if queued != w {
+			continue
+		}
+		if w.priority > PriorityText {
+			l.urgent--
+		}
+		l.queues[w.guildID] = append(queue[:i], queue[i+1:]...)
+		if len(l.queues[w.guildID]) == 0 {
+			delete(l.queues, w.guildID)
+			for j, guildID := range l.ring {
+				if guildID == w.guildID {
+					l.ring = append(l.ring[:j], l.ring[j+1:]...)
+					if l.next > j {
+						l.next--
+					}
+
+					break
+				}
+			}
+		}
+
+		return
+	}
+}
+
+// position estimates how many requests will be served up to and including w,
+// given the round-robin order. The caller must hold l.mu.
+func (l *requestLimiter) position(w *limiterWaiter) int {
+	index := 0
+	for i, queued := range l.queues[w.guildID] {
+		if queued == w {
+			index = i
+		}
+	}
+
+	start := 0
+	if len(l.ring) > 0 {
+		start = l.next % len(l.ring)
+	}
+	own := 0
+	for i, guildID := range l.ring {
+		if guildID == w.guildID {
+			own = (i - start + len(l.ring)) % len(l.ring)
+		}
+	}
+
+	position := index + 1
+	for i, guildID := range l.ring {
+		if guildID == w.guildID {
+			continue
+		}
+		// Guilds served before w's guild in a round get one more turn
+		turns := index
+		if (i-start+len(l.ring))%len(l.ring) < own {
+			turns++
+		}
+		position += min(len(l.queues[guildID]), turns)
+	}
+
+	return position
+}
+
+// adapt shrinks the limit after a rate-limited request and grows it back
+// after a window of successes. The caller must hold l.mu.
+func (l *requestLimiter) adapt(err error) {
+	switch {
+	case isRateLimited(err):
+		if l.limit > 1 {
+			l.limit /= 2
+			l.logger.Warn("OpenAI rate limited a request, lowering concurrency", zap.Int("limit", l.limit))
+		}
+		l.successes = 0
+	case err == nil && l.limit < l.maxGlobal:
+		if l.successes++; l.successes >= l.limit {
+			l.limit++
+			l.successes = 0
+			l.logger.Info("Raising concurrency after successful requests", zap.Int("limit", l.limit))
+		}
+	}
+}
+
+// isRateLimited reports whether err is an OpenAI rate limit response.
+func isRateLimited(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+
+	return false
+}
+
+// queueNotice returns callbacks that tell a channel its request is waiting
+// for a slot, and remove the notice once the request starts.
+func (s *Service) queueNotice(channelID discord.ChannelID) (onQueued func(position int), done func()) {
+	if !channelID.IsValid() {
+		return nil, func() {}
+	}
+
+	var notice *discord.Message
+	onQueued = func(position int) {
+		msg, err := s.ses.SendMessageComplex(channelID, api.SendMessageData{
+			Content:         fmt.Sprintf("⏳ Busy right now, your message is #%d in the queue.", position),
+			AllowedMentions: &api.AllowedMentions{},
+		})
+		if err != nil {
+			s.logger.Warn("Failed to send queue notice", zap.Error(err), zap.String("channelID", channelID.String()))
+
+			return
+		}
+		notice = msg
+	}
+	done = func() {
+		if notice == nil {
+			return
+		}
+		if err := s.ses.DeleteMessage(channelID, notice.ID, ""); err != nil {
+			s.logger.Warn("Failed to delete queue notice", zap.Error(err), zap.String("channelID", channelID.String()))
+		}
+	}
+
+	return onQueued, done
+}
@@ -0,0 +1,177 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func newTestLimiter(global, perGuild int) chat.RequestLimiter {
+	return chat.NewRequestLimiter(zap.NewNop(), &config.Config{OpenAI: config.OpenAIConfig{
+		MaxConcurrentRequests:         global,
+		MaxConcurrentRequestsPerGuild: perGuild,
+	}})
+}
+
+type grant struct {
+	name    string
+	release func(error)
+}
+
+// enqueue starts a request that waits for a slot and returns its queue position.
+func enqueue(t *testing.T, limiter chat.RequestLimiter, guildID discord.GuildID, name string, granted chan<- grant) int {
+	t.Helper()
+
+	return enqueueWith(t, context.Background(), limiter, guildID, name, granted)
+}
+
+func enqueueWith(t *testing.T, ctx context.Context, limiter chat.RequestLimiter, guildID discord.GuildID, name string, granted chan<- grant) int {
+	t.Helper()
+	queued := make(chan int, 1)
+	go func() {
+		release, err := limiter.Acquire(ctx, guildID, func(position int) { queued <- position })
+		if err == nil {
+			granted <- grant{name, release}
+		}
+	}()
+
+	select {
+	case position := <-queued:
+		return position
+	case <-time.After(time.Second):
+		t.Fatalf("%s was not queued", name)
+
+		return 0
+	}
+}
+
+func next(t *testing.T, granted <-chan grant) grant {
+	t.Helper()
+	select {
+	case g := <-granted:
+		return g
+	case <-time.After(time.Second):
+		t.Fatal("no request was granted a slot")
+
+		return grant{}
+	}
+}
+
+func TestRequestLimiterServesGuildsInTurn(t *testing.T) {
+	limiter := newTestLimiter(1, 0)
+	guildA, guildB := discord.GuildID(1), discord.GuildID(2)
+	granted := make(chan grant, 3)
+
+	release, err := limiter.Acquire(context.Background(), guildA, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, enqueue(t, limiter, guildA, "A2", granted))
+	assert.Equal(t, 2, enqueue(t, limiter, guildA, "A3", granted))
+	assert.Equal(t, 2, enqueue(t, limiter, guildB, "B1", granted), "B1 is served between A2 and A3")
+
+	release(nil)
+	var order []string
+	for range 3 {
+		g := next(t, granted)
+		order = append(order, g.name)
+		g.release(nil)
+	}
+	assert.Equal(t, []string{"A2", "B1", "A3"}, order)
+}
+
+func TestRequestLimiterServesVoiceFirst(t *testing.T) {
+	limiter := newTestLimiter(1, 0)
+	guildA, guildB := discord.GuildID(1), discord.GuildID(2)
+	voice := chat.WithPriority(context.Background(), chat.PriorityVoice)
+	granted := make(chan grant, 5)
+
+	release, err := limiter.Acquire(context.Background(), guildA, nil)
+	require.NoError(t, err)
+
+	enqueue(t, limiter, guildA, "A2", granted)
+	enqueue(t, limiter, guildB, "B1", granted)
+	enqueueWith(t, voice, limiter, guildB, "B-voice", granted)
+	assert.Equal(t, 1, enqueueWith(t, voice, limiter, guildA, "A-voice", granted), "voice turns go ahead of the guild's text requests")
+	enqueue(t, limiter, guildA, "A3", granted)
+
+	release(nil)
+	var order []string
+	for range 5 {
+		g := next(t, granted)
+		order = append(order, g.name)
+		g.release(nil)
+	}
+	assert.Equal(t, []string{"A-voice", "B-voice", "A2", "B1", "A3"}, order, "text requests keep their round-robin order")
+}
+
+func TestRequestLimiterGuildLimit(t *testing.T) {
+	limiter := newTestLimiter(3, 1)
+	guildA, guildB := discord.GuildID(1), discord.GuildID(2)
+	granted := make(chan grant, 1)
+
+	releaseA, err := limiter.Acquire(context.Background(), guildA, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, enqueue(t, limiter, guildA, "A2", granted), "guild A is at its own limit")
+
+	// Other guilds still get free slots
+	releaseB, err := limiter.Acquire(context.Background(), guildB, nil)
+	require.NoError(t, err)
+	releaseB(nil)
+
+	releaseA(nil)
+	assert.Equal(t, "A2", next(t, granted).name)
+}
+
+func TestRequestLimiterCancelledWhileQueued(t *testing.T) {
+	limiter := newTestLimiter(1, 0)
+	guildID := discord.GuildID(1)
+
+	release, err := limiter.Acquire(context.Background(), guildID, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx, guildID, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The request that gave up no longer holds a place in the queue
+	release(nil)
+	release(nil) // releasing twice is harmless
+	again, err := limiter.Acquire(context.Background(), guildID, func(int) { t.Error("request should not be queued") })
+	require.NoError(t, err)
+	again(nil)
+}
+
+func TestRequestLimiterBacksOffOnRateLimit(t *testing.T) {
+	limiter := newTestLimiter(2, 0)
+	guildID := discord.GuildID(1)
+
+	first, err := limiter.Acquire(context.Background(), guildID, nil)
+	require.NoError(t, err)
+	first(&openai.APIError{HTTPStatusCode: http.StatusTooManyRequests})
+
+	// The limit dropped to one request at a time
+	held, err := limiter.Acquire(context.Background(), guildID, nil)
+	require.NoError(t, err)
+	granted := make(chan grant, 1)
+	assert.Equal(t, 1, enqueue(t, limiter, guildID, "queued", granted))
+
+	// One success at the lowered limit raises it again
+	held(nil)
+	queued := next(t, granted)
+	raised, err := limiter.Acquire(context.Background(), guildID, func(int) { t.Error("limit should be back to two") })
+	require.NoError(t, err)
+	queued.release(errors.New("other failures do not change the limit"))
+	raised(nil)
+}
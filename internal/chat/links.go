@@ -0,0 +1,182 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/webpage"
+)
+
+// Link reading modes.
+const (
+	LinksOff     = "off"
+	LinksContext = "context"
+	LinksTool    = "tool"
+)
+
+const defaultMaxLinks = 3
+
+const linkContextInstructions = "The user's message links to the web pages below. " +
+	"Use their text to answer, and say so if a page could not be read rather than guessing its content."
+
+// LinkReader adds the text of pages linked in a prompt to the request.
+type LinkReader interface {
+	// Enrich returns messages with the pages linked in the latest user message
+	// inserted before it. The given slice is not modified.
+	Enrich(ctx context.Context, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage
+}
+
+// NewLinkReader creates a LinkReader that reads links when the links mode is "context".
+func NewLinkReader(logger *zap.Logger, cfg *config.Config, fetcher webpage.Fetcher) LinkReader {
+	maxLinks := cfg.OpenAI.Links.MaxLinks
+	if maxLinks <= 0 {
+		maxLinks = defaultMaxLinks
+	}
+
+	return &linkReader{
+		logger:   logger.Named("link_reader"),
+		enabled:  strings.EqualFold(cfg.OpenAI.Links.Mode, LinksContext),
+		fetcher:  fetcher,
+		maxLinks: maxLinks,
+	}
+}
+
+type linkReader struct {
+	logger   *zap.Logger
+	enabled  bool
+	fetcher  webpage.Fetcher
+	maxLinks int
+}
+
+// Enrich fetches the links in the latest user message concurrently.
+func (r *linkReader) Enrich(ctx context.Context, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if !r.enabled {
+		return messages
+	}
+
+	last := len(messages) - 1
+	if last < 0 || messages[last].Role != openai.ChatMessageRoleUser {
+		return messages
+	}
+	urls := webpage.FindURLs(messageText(messages[last]), r.maxLinks)
+	if len(urls) == 0 {
+		return messages
+	}
+
+	sections := make([]string, len(urls))
+	var wg sync.WaitGroup
+	for i, link := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			page, err := r.fetcher.Fetch(ctx, link)
+			if err != nil {
+				r.logger.Info("Failed to read linked page", zap.String("url", link), zap.Error(err))
+				sections[i] = fmt.Sprintf("%s could not be read: %v", link, err)
+
+				return
+			}
+			sections[i] = formatPage(page)
+		}()
+	}
+	wg.Wait()
+
+	r.logger.Debug("Read linked pages", zap.Int("count", len(urls)))
+
+	pages := openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: linkContextInstructions + "\n\n" + strings.Join(sections, "\n\n"),
+	}
+	out := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	out = append(out, messages[:last]...)
+	out = append(out, pages, messages[last])
+
+	return out
+}
+
+// formatPage renders a page as a titled block of text for the model.
+func formatPage(page *webpage.Page) string {
+	var sb strings.Builder
+	sb.WriteString("--- ")
+	if page.Title != "" {
+		sb.WriteString(page.Title + " ")
+	}
+	sb.WriteString("<" + page.URL + ">\n")
+	sb.WriteString(page.Text)
+	if page.Truncated {
+		sb.WriteString("\n[page text truncated]")
+	}
+
+	return sb.String()
+}
+
+// Tool is a function the model can call while drafting a reply.
+// Implementations are provided to the Fx group "chat_tools".
+type Tool interface {
+	Definition() openai.FunctionDefinition
+	// Call runs the tool with the JSON arguments chosen by the model and
+	// returns the result to show it.
+	Call(ctx context.Context, arguments string) (string, error)
+}
+
+// readWebpageTool is the name the model calls the link tool by.
+const readWebpageTool = "read_webpage"
+
+// NewLinkTool creates the web page reading tool, or returns nil unless the links mode is "tool".
+func NewLinkTool(logger *zap.Logger, cfg *config.Config, fetcher webpage.Fetcher) Tool {
+	if !strings.EqualFold(cfg.OpenAI.Links.Mode, LinksTool) {
+		return nil
+	}
+
+	return &linkTool{logger: logger.Named("link_tool"), fetcher: fetcher}
+}
+
+type linkTool struct {
+	logger  *zap.Logger
+	fetcher webpage.Fetcher
+}
+
+// Definition describes read_webpage to the model.
+func (t *linkTool) Definition() openai.FunctionDefinition {
+	return openai.FunctionDefinition{
+		Name:        readWebpageTool,
+		Description: "Fetch a web page and return its title and readable text. Use it when the user refers to a link.",
+		Parameters: jsonschema.Definition{
+			Type: jsonschema.Object,
+			Properties: map[string]jsonschema.Definition{
+				"url": {Type: jsonschema.String, Description: "The http or https address of the page"},
+			},
+			Required: []string{"url"},
+		},
+	}
+}
+
+// Call fetches the page named in arguments.
+func (t *linkTool) Call(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.URL == "" {
+		return "", errors.New("url is required")
+	}
+
+	page, err := t.fetcher.Fetch(ctx, args.URL)
+	if err != nil {
+		return "", err
+	}
+	t.logger.Debug("Model read a web page", zap.String("url", page.URL), zap.Int("chars", len(page.Text)))
+
+	return formatPage(page), nil
+}
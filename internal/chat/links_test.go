@@ -0,0 +1,189 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/webpage"
+	"github.com/Raikerian/go-discord-chatgpt/internal/youtube"
+)
+
+// fakeFetcher serves pages by URL; unknown URLs fail.
+type fakeFetcher map[string]*webpage.Page
+
+func (f fakeFetcher) Fetch(_ context.Context, rawURL string) (*webpage.Page, error) {
+	if page, ok := f[rawURL]; ok {
+		return page, nil
+	}
+
+	return nil, errors.New("not found")
+}
+
+var testPages = fakeFetcher{
+	"https://example.com/cats": {URL: "https://example.com/cats", Title: "Cats", Text: "Cats nap a lot.", Truncated: true},
+}
+
+func linksConfig(mode string) *config.Config {
+	cfg := &config.Config{}
+	cfg.OpenAI.Links = config.LinksConfig{Mode: mode}
+
+	return cfg
+}
+
+func TestLinkReader_Enrich(t *testing.T) {
+	reader := chat.NewLinkReader(zap.NewNop(), linksConfig("context"), testPages)
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "Be brief."},
+		{Role: openai.ChatMessageRoleUser, Content: "Summarize https://example.com/cats and https://example.com/dogs"},
+	}
+
+	out := reader.Enrich(context.Background(), messages)
+	require.Len(t, out, 3)
+	assert.Equal(t, "Be brief.", out[0].Content)
+	assert.Equal(t, openai.ChatMessageRoleSystem, out[1].Role)
+	assert.Contains(t, out[1].Content, "--- Cats <https://example.com/cats>\nCats nap a lot.\n[page text truncated]")
+	assert.Contains(t, out[1].Content, "https://example.com/dogs could not be read")
+	assert.Equal(t, messages[1], out[2], "the prompt stays last")
+	assert.Len(t, messages, 2, "input is not modified")
+}
+
+func TestLinkReader_Skips(t *testing.T) {
+	prompt := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "read https://example.com/cats"}}
+
+	off := chat.NewLinkReader(zap.NewNop(), linksConfig("tool"), testPages)
+	assert.Equal(t, prompt, off.Enrich(context.Background(), prompt), "only context mode inlines pages")
+
+	reader := chat.NewLinkReader(zap.NewNop(), linksConfig("context"), testPages)
+	noLinks := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hello"}}
+	assert.Equal(t, noLinks, reader.Enrich(context.Background(), noLinks))
+}
+
+func TestNewLinkTool(t *testing.T) {
+	assert.Nil(t, chat.NewLinkTool(zap.NewNop(), linksConfig("context"), testPages))
+
+	tool := chat.NewLinkTool(zap.NewNop(), linksConfig("tool"), testPages)
+	require.NotNil(t, tool)
+	assert.Equal(t, "read_webpage", tool.Definition().Name)
+
+	result, err := tool.Call(context.Background(), `{"url":"https://example.com/cats"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Cats nap a lot.")
+
+	_, err = tool.Call(context.Background(), `{"url":"https://example.com/dogs"}`)
+	require.Error(t, err)
+	_, err = tool.Call(context.Background(), `{}`)
+	assert.ErrorContains(t, err, "url is required")
+}
+
+// toolAI asks for the link tool until it has called it rounds times, then answers.
+type toolAI struct {
+	rounds   int
+	requests [][]openai.ChatCompletionMessage
+	withTool int
+}
+
+func (a *toolAI) GetChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	return a.GetChatCompletionWithTools(ctx, model, messages, nil)
+}
+
+func (a *toolAI) GetChatCompletionWithTools(_ context.Context, _ string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*openai.ChatCompletionResponse, error) {
+	a.requests = append(a.requests, messages)
+	reply := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "Cats nap."}
+	if len(tools) > 0 {
+		a.withTool++
+		if a.withTool <= a.rounds {
+			reply = openai.ChatCompletionMessage{
+				Role: openai.ChatMessageRoleAssistant,
+				ToolCalls: []openai.ToolCall{{
+					ID:       "call_1",
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: "read_webpage", Arguments: `{"url":"https://example.com/cats"}`},
+				}},
+			}
+		}
+	}
+
+	return &openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: reply}},
+		Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func TestRefiner_ToolCalls(t *testing.T) {
+	tools := []chat.Tool{nil, chat.NewLinkTool(zap.NewNop(), linksConfig("tool"), testPages)}
+
+	t.Run("OneRound", func(t *testing.T) {
+		ai := &toolAI{rounds: 1}
+		refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(false), ai, tools)
+
+		resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+		require.NoError(t, err)
+		assert.Equal(t, "Cats nap.", resp.Choices[0].Message.Content)
+		assert.Equal(t, []string{"tool:big", "draft:big"}, steps(calls))
+
+		// The second request carries the tool call and its result
+		last := ai.requests[1]
+		require.Len(t, last, 3)
+		assert.Len(t, last[1].ToolCalls, 1)
+		assert.Equal(t, openai.ChatMessageRoleTool, last[2].Role)
+		assert.Equal(t, "call_1", last[2].ToolCallID)
+		assert.Contains(t, last[2].Content, "Cats nap a lot.")
+	})
+
+	t.Run("RoundLimit", func(t *testing.T) {
+		ai := &toolAI{rounds: 10}
+		refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(false), ai, tools)
+
+		resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+		require.NoError(t, err)
+		assert.Equal(t, "Cats nap.", resp.Choices[0].Message.Content)
+		assert.Equal(t, []string{"tool:big", "tool:big", "tool:big", "draft:big"}, steps(calls))
+	})
+
+	t.Run("UnsupportedProvider", func(t *testing.T) {
+		ai := &queuedAI{replies: []string{"4"}}
+		refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(false), ai, tools)
+
+		_, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"draft:big"}, steps(calls))
+	})
+}
+
+// fakeTranscripts serves one transcript by video ID.
+type fakeTranscripts struct{}
+
+func (fakeTranscripts) Fetch(_ context.Context, videoID string) (*youtube.Transcript, error) {
+	if videoID != "dQw4w9WgXcQ" {
+		return nil, youtube.ErrNoCaptions
+	}
+
+	return &youtube.Transcript{VideoID: videoID, Title: "Cats", Language: "en", Generated: true, Text: "Cats nap."}, nil
+}
+
+func TestNewVideoTool(t *testing.T) {
+	cfg := linksConfig("tool")
+	assert.Nil(t, chat.NewVideoTool(zap.NewNop(), cfg, fakeTranscripts{}), "YouTube is off")
+
+	cfg.OpenAI.YouTube.Enabled = true
+	tool := chat.NewVideoTool(zap.NewNop(), cfg, fakeTranscripts{})
+	require.NotNil(t, tool)
+	assert.Equal(t, "youtube_transcript", tool.Definition().Name)
+
+	result, err := tool.Call(context.Background(), `{"url":"https://youtu.be/dQw4w9WgXcQ"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "--- Transcript of \"Cats\" <https://www.youtube.com/watch?v=dQw4w9WgXcQ> (en, generated captions)\nCats nap.", result)
+
+	_, err = tool.Call(context.Background(), `{"url":"https://example.com"}`)
+	require.ErrorIs(t, err, youtube.ErrNotVideo)
+	_, err = tool.Call(context.Background(), `{"url":"https://youtu.be/aaaaaaaaaaa"}`)
+	assert.ErrorIs(t, err, youtube.ErrNoCaptions)
+}
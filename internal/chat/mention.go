@@ -0,0 +1,149 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"go.uber.org/zap"
+)
+
+// chatCommandPath is the command whose permission rule mentions follow, as
+// they start the same conversations.
+const chatCommandPath = "chat"
+
+// HandleMentionMessage starts a conversation from a message in ch that
+// mentions the bot, when the mention trigger is enabled there and its author
+// may use /chat. The bot answers in a thread created from the message, which
+// then continues like a /chat thread. It reports whether the message was
+// handled.
+func (s *Service) HandleMentionMessage(ctx context.Context, evt *gateway.MessageCreateEvent, ch *discord.Channel) (bool, error) {
+	if !s.mentionTriggerAllowed(evt.GuildID, ch) {
+		return false, nil
+	}
+	selfUser, err := s.getSelfUser()
+	if err != nil {
+		return false, fmt.Errorf("failed to get self user: %w", err)
+	}
+	userPrompt, mentioned := StripMention(evt.Content, selfUser.ID)
+	if !mentioned {
+		return false, nil
+	}
+	if userPrompt == "" && len(evt.Attachments) == 0 {
+		s.replyToMessage(evt, "👋 Mention me with a question, and I will answer in a thread.")
+
+		return true, nil
+	}
+
+	if allowed, err := s.mentionAllowed(evt); !allowed {
+		return true, err
+	}
+
+	user := &evt.Author
+	ctx = withRequester(ctx, user.ID)
+	s.logger.Info("Mention message processing started",
+		zap.String("userID", user.ID.String()),
+		zap.String("channelID", evt.ChannelID.String()),
+		zap.String("userPrompt", userPrompt),
+	)
+
+	modelToUse, err := s.modelSelector.SelectModel("")
+	if err != nil {
+		return true, err
+	}
+	access := ThreadAccess{Policy: s.defaultThreadPolicy, InitiatorID: user.ID}
+
+	thread, err := s.ses.StartThreadWithMessage(evt.ChannelID, evt.ID, api.StartThreadData{
+		Name:                MakeThreadName(user.Username, userPrompt, 100),
+		AutoArchiveDuration: discord.ArchiveDuration(60),
+	})
+	if err != nil {
+		s.replyToMessage(evt, "**(Sorry, I couldn't create a discussion thread for this chat. Please try again or contact an administrator if the issue persists.)**")
+
+		return true, fmt.Errorf("failed to create thread from message: %w", err)
+	}
+	s.threadParents.Add(thread.ID, evt.ChannelID)
+	s.logger.Info("Thread created from mention",
+		zap.String("threadID", thread.ID.String()),
+		zap.String("threadName", thread.Name),
+	)
+
+	// The summary opens the thread as it does for /chat, so the thread can be
+	// rebuilt once its conversation is no longer cached
+	if _, err := s.interactionManager.SendMessage(s.ses, thread.ID, sessionSummary(user, access, "", nil, userPrompt, modelToUse), ""); err != nil {
+		return true, fmt.Errorf("failed to send session summary: %w", err)
+	}
+
+	if err := s.answerInThread(ctx, evt.GuildID, evt.ChannelID, thread.ID, user, userPrompt, modelToUse, access, nil, nil, evt.Attachments); err != nil {
+		return true, err
+	}
+
+	s.logger.Info("Mention message processing completed successfully", zap.String("threadID", thread.ID.String()))
+
+	return true, nil
+}
+
+// mentionAllowed reports whether the author of evt may start a conversation
+// under the permission rule of /chat, telling them what they need when they
+// may not.
+func (s *Service) mentionAllowed(evt *gateway.MessageCreateEvent) (bool, error) {
+	if !s.permissions.Restricts(chatCommandPath) {
+		return true, nil
+	}
+
+	var member *discord.Member
+	if evt.Member != nil {
+		// Members sent with messages leave out their user
+		withUser := *evt.Member
+		withUser.User = evt.Author
+		member = &withUser
+	}
+	decision, err := s.permissions.Check(s.ses, evt.GuildID, member, 0, chatCommandPath)
+	if err != nil {
+		s.replyToMessage(evt, "❌ Could not check your permissions for `/chat`. Please try again.")
+
+		return false, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if !decision.Allowed {
+		s.logger.Info("Mention denied by permissions",
+			zap.String("userID", evt.Author.ID.String()),
+			zap.String("guildID", evt.GuildID.String()))
+		s.replyToMessage(evt, decision.Message(evt.GuildID, chatCommandPath))
+	}
+
+	return decision.Allowed, nil
+}
+
+// mentionTriggerAllowed reports whether mentions in ch start conversations:
+// with the trigger enabled, in a guild text channel listed in channel_ids,
+// or any when none are.
+func (s *Service) mentionTriggerAllowed(guildID discord.GuildID, ch *discord.Channel) bool {
+	trigger := s.cfg.OpenAI.MentionTrigger
+	if !trigger.Enabled || !guildID.IsValid() || ch == nil {
+		return false
+	}
+	if ch.Type != discord.GuildText && ch.Type != discord.GuildAnnouncement {
+		return false
+	}
+
+	return len(trigger.ChannelIDs) == 0 || slices.Contains(trigger.ChannelIDs, ch.ID.String())
+}
+
+// StripMention removes the mentions of selfID from content, reporting
+// whether there were any. Reply pings do not count, as they leave no mention
+// in the content.
+func StripMention(content string, selfID discord.UserID) (string, bool) {
+	mentioned := false
+	for _, mention := range []string{"<@" + selfID.String() + ">", "<@!" + selfID.String() + ">"} {
+		if strings.Contains(content, mention) {
+			mentioned = true
+			content = strings.ReplaceAll(content, mention, "")
+		}
+	}
+
+	return strings.Join(strings.Fields(content), " "), mentioned
+}
@@ -0,0 +1,118 @@
+package chat_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func TestStripMention(t *testing.T) {
+	prompt, ok := chat.StripMention("<@42> what is   a goroutine?", 42)
+	assert.True(t, ok)
+	assert.Equal(t, "what is a goroutine?", prompt)
+
+	prompt, ok = chat.StripMention("hey <@!42> explain <@7>", 42)
+	assert.True(t, ok)
+	assert.Equal(t, "hey explain <@7>", prompt)
+
+	prompt, ok = chat.StripMention("<@42>", 42)
+	assert.True(t, ok)
+	assert.Empty(t, prompt)
+
+	_, ok = chat.StripMention("<@7> and <@420>", 42)
+	assert.False(t, ok, "only mentions of the bot count")
+}
+
+func mentionConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.OpenAI.Models = []string{testModel}
+	cfg.OpenAI.MentionTrigger.Enabled = true
+
+	return cfg
+}
+
+func mention(authorID discord.UserID, roleIDs []discord.RoleID, content string, attachments ...discord.Attachment) *gateway.MessageCreateEvent {
+	return &gateway.MessageCreateEvent{
+		Message: discord.Message{
+			ID:          50,
+			ChannelID:   20,
+			GuildID:     1,
+			Author:      discord.User{ID: authorID, Username: "user" + authorID.String()},
+			Content:     content,
+			Attachments: attachments,
+		},
+		Member: &discord.Member{RoleIDs: roleIDs},
+	}
+}
+
+// threadsStarted returns the IDs of the threads created from messages.
+func (ts *testService) threadsStarted() []string {
+	var started []string
+	for _, request := range ts.discord.posted() {
+		if strings.HasSuffix(request.Path, "/threads") {
+			started = append(started, strconv.Itoa(request.ID))
+		}
+	}
+
+	return started
+}
+
+func TestHandleMentionMessage_Permissions(t *testing.T) {
+	cfg := mentionConfig()
+	cfg.Permissions = config.PermissionsConfig{"chat": {RoleIDs: []string{"7"}}}
+	ts := newTestService(t, cfg, nil)
+	ch := &discord.Channel{ID: 20, Type: discord.GuildText}
+
+	handled, err := ts.HandleMentionMessage(t.Context(), mention(5, nil, "<@100> hello"), ch)
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.Empty(t, ts.threadsStarted(), "members without the /chat role get no thread")
+	assert.Empty(t, ts.ai.asked())
+	refusal := ts.discord.posted()[len(ts.discord.posted())-1]
+	assert.Contains(t, refusal.content(), "/chat")
+
+	handled, err = ts.HandleMentionMessage(t.Context(), mention(5, []discord.RoleID{7}, "<@100> hello"), ch)
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.Len(t, ts.threadsStarted(), 1)
+	assert.Equal(t, []string{"hello"}, ts.ai.asked())
+}
+
+func TestHandleMentionMessage_Images(t *testing.T) {
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer images.Close()
+	cfg := mentionConfig()
+	cfg.OpenAI.Vision.Enabled = true
+	ts := newTestService(t, cfg, nil)
+
+	image := discord.Attachment{Filename: "cat.png", ContentType: "image/png", URL: images.URL + "/cat.png"}
+	handled, err := ts.HandleMentionMessage(t.Context(), mention(5, nil, "<@100>", image), &discord.Channel{ID: 20, Type: discord.GuildText})
+	require.NoError(t, err)
+	assert.True(t, handled)
+
+	require.Len(t, ts.ai.requests, 1)
+	prompt := ts.ai.requests[0][len(ts.ai.requests[0])-1]
+	require.Len(t, prompt.MultiContent, 1, "an image-only mention sends the image")
+	assert.Equal(t, openai.ChatMessagePartTypeImageURL, prompt.MultiContent[0].Type)
+
+	// The image stays in the conversation for the next turns
+	threads := ts.threadsStarted()
+	require.Len(t, threads, 1)
+	data, found := ts.store.GetConversation(threads[0])
+	require.True(t, found)
+	assert.Equal(t, image.URL, data.Messages[0].MultiContent[0].ImageURL.URL)
+}
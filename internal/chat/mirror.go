@@ -0,0 +1,250 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	// maxMirroredPrompt bounds the prompt shown in an audit entry.
+	maxMirroredPrompt = 2000
+	// mirrorColor is the embed color of audit entries.
+	mirrorColor = 0x5865F2
+	// mirrorTitle marks audit entries among the bot's messages.
+	mirrorTitle = "🔍 AI interaction"
+	// maxAuditPurgePerRun bounds the entries one purge deletes per audit
+	// channel. Entries older than two weeks are deleted one request at a
+	// time; the next run deletes the rest.
+	maxAuditPurgePerRun = 500
+)
+
+// MirrorEntry is one AI interaction to mirror: the prompt and what answered
+// it. The reply itself stays in its channel; only its length is mirrored.
+type MirrorEntry struct {
+	GuildID   discord.GuildID
+	ChannelID discord.ChannelID // Where the reply was posted
+	ParentID  discord.ChannelID // Channel ChannelID is a thread of, NullChannelID outside threads
+	UserID    discord.UserID
+	Prompt    string
+	Reply     string
+	Calls     []CallUsage
+}
+
+// AuditMirror copies the AI interactions in the channels a guild selects to
+// its private audit channel, for communities whose moderators must be able
+// to review AI usage. It is nil when no guild mirrors interactions.
+type AuditMirror struct {
+	logger *zap.Logger
+	ses    *session.Session
+	guilds map[discord.GuildID]mirrorTarget
+}
+
+// mirrorTarget is where a guild mirrors interactions to, and from which
+// channels; every channel when channels is empty.
+type mirrorTarget struct {
+	auditChannelID discord.ChannelID
+	channels       []discord.ChannelID
+}
+
+// NewAuditMirror creates the AuditMirror of the guilds that set
+// guilds.<id>.moderation.mirror.audit_channel_id, or nil when none does.
+func NewAuditMirror(logger *zap.Logger, cfg *config.Config, ses *session.Session) *AuditMirror {
+	m := &AuditMirror{
+		logger: logger.Named("audit_mirror"),
+		ses:    ses,
+		guilds: make(map[discord.GuildID]mirrorTarget),
+	}
+	for guildIDStr, guild := range cfg.Guilds {
+		mirror := guild.Moderation.Mirror
+		if mirror.AuditChannelID == "" {
+			continue
+		}
+		guildID, err := discord.ParseSnowflake(guildIDStr)
+		if err != nil {
+			m.logger.Warn("Ignoring mirror of invalid guild ID", zap.Error(err), zap.String("guildID", guildIDStr))
+
+			continue
+		}
+		auditID, err := discord.ParseSnowflake(mirror.AuditChannelID)
+		if err != nil {
+			m.logger.Warn("Ignoring mirror with invalid audit channel", zap.Error(err), zap.String("guildID", guildIDStr))
+
+			continue
+		}
+		target := mirrorTarget{auditChannelID: discord.ChannelID(auditID)}
+		for _, idStr := range mirror.ChannelIDs {
+			sf, err := discord.ParseSnowflake(idStr)
+			if err != nil {
+				m.logger.Warn("Ignoring invalid mirrored channel ID", zap.Error(err), zap.String("channelID", idStr))
+
+				continue
+			}
+			target.channels = append(target.channels, discord.ChannelID(sf))
+		}
+		m.guilds[discord.GuildID(guildID)] = target
+	}
+	if len(m.guilds) == 0 {
+		return nil
+	}
+	m.logger.Info("Mirroring AI interactions to audit channels", zap.Int("guilds", len(m.guilds)))
+
+	return m
+}
+
+// Mirrors reports whether interactions in channelID, a thread of parentID
+// when that is valid, are mirrored.
+func (m *AuditMirror) Mirrors(guildID discord.GuildID, channelID, parentID discord.ChannelID) bool {
+	if m == nil {
+		return false
+	}
+	target, ok := m.guilds[guildID]
+	if !ok {
+		return false
+	}
+
+	return len(target.channels) == 0 ||
+		slices.Contains(target.channels, channelID) ||
+		parentID.IsValid() && slices.Contains(target.channels, parentID)
+}
+
+// Record posts entry to its guild's audit channel in the background, if the
+// guild mirrors its channel.
+func (m *AuditMirror) Record(entry MirrorEntry) {
+	if !m.Mirrors(entry.GuildID, entry.ChannelID, entry.ParentID) {
+		return
+	}
+	auditChannelID := m.guilds[entry.GuildID].auditChannelID
+	at := time.Now()
+
+	go func() {
+		_, err := m.ses.SendMessageComplex(auditChannelID, api.SendMessageData{
+			Embeds: []discord.Embed{mirrorEmbed(entry, at)},
+			// Audit entries must not ping the people they are about
+			AllowedMentions: &api.AllowedMentions{},
+		})
+		if err != nil {
+			m.logger.Error("Failed to mirror AI interaction",
+				zap.Error(err),
+				zap.String("guildID", entry.GuildID.String()),
+				zap.String("auditChannelID", auditChannelID.String()))
+		}
+	}()
+}
+
+// PurgeBefore deletes the audit entries posted before cutoff from every
+// audit channel, returning how many were deleted.
+func (m *AuditMirror) PurgeBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	if m == nil {
+		return 0, nil
+	}
+	me, err := m.ses.Me()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get self user: %w", err)
+	}
+
+	purged := 0
+	var errs []error
+	for guildID, target := range m.guilds {
+		deleted, err := m.purgeChannel(ctx, target.auditChannelID, me.ID, cutoff)
+		purged += deleted
+		if err != nil {
+			errs = append(errs, fmt.Errorf("guild %s: %w", guildID, err))
+		}
+	}
+
+	return purged, errors.Join(errs...)
+}
+
+// purgeChannel deletes the audit entries selfID posted in channelID before
+// cutoff, leaving every other message.
+func (m *AuditMirror) purgeChannel(ctx context.Context, channelID discord.ChannelID, selfID discord.UserID, cutoff time.Time) (int, error) {
+	const page = 100
+
+	before := discord.MessageID(discord.NewSnowflake(cutoff))
+	purged := 0
+	for purged < maxAuditPurgePerRun {
+		msgs, err := m.ses.MessagesBefore(channelID, before, page)
+		if err != nil {
+			return purged, fmt.Errorf("failed to read audit channel %s: %w", channelID, err)
+		}
+		for _, msg := range msgs {
+			if msg.Author.ID != selfID || len(msg.Embeds) == 0 || msg.Embeds[0].Title != mirrorTitle {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return purged, err
+			}
+			if err := m.ses.DeleteMessage(channelID, msg.ID, "Audit log retention"); err != nil {
+				return purged, fmt.Errorf("failed to delete audit entry %s: %w", msg.ID, err)
+			}
+			purged++
+		}
+		if len(msgs) < page {
+			break
+		}
+		before = msgs[len(msgs)-1].ID
+	}
+
+	return purged, nil
+}
+
+// mirrorEmbed renders entry as an audit entry.
+func mirrorEmbed(entry MirrorEntry, at time.Time) discord.Embed {
+	prompt, _ := TruncateMiddle(entry.Prompt, maxMirroredPrompt)
+
+	var models []string
+	var promptTokens, completionTokens int
+	for _, call := range entry.Calls {
+		if !slices.Contains(models, call.Model) {
+			models = append(models, call.Model)
+		}
+		promptTokens += call.Usage.PromptTokens
+		completionTokens += call.Usage.CompletionTokens
+	}
+	modelList := "unknown"
+	if len(models) > 0 {
+		modelList = "`" + strings.Join(models, "`, `") + "`"
+	}
+
+	return discord.Embed{
+		Title:       mirrorTitle,
+		Description: prompt,
+		Fields: []discord.EmbedField{
+			{Name: "User", Value: entry.UserID.Mention(), Inline: true},
+			{Name: "Channel", Value: entry.ChannelID.Mention(), Inline: true},
+			{Name: "Models", Value: modelList, Inline: true},
+			{Name: "Tokens", Value: fmt.Sprintf("%d prompt, %d completion", promptTokens, completionTokens), Inline: true},
+			{Name: "Reply", Value: fmt.Sprintf("%d characters", len([]rune(entry.Reply))), Inline: true},
+		},
+		Color:     mirrorColor,
+		Timestamp: discord.NewTimestamp(at),
+	}
+}
+
+// mirrorReply mirrors a reply to userID's prompt posted in channelID.
+func (s *Service) mirrorReply(guildID discord.GuildID, channelID discord.ChannelID, userID discord.UserID, prompt, reply string, calls []CallUsage) {
+	if s.mirror == nil {
+		return
+	}
+
+	s.mirror.Record(MirrorEntry{
+		GuildID:   guildID,
+		ChannelID: channelID,
+		ParentID:  s.threadParent(channelID),
+		UserID:    userID,
+		Prompt:    prompt,
+		Reply:     reply,
+		Calls:     calls,
+	})
+}
@@ -0,0 +1,88 @@
+package chat_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func TestAuditMirror(t *testing.T) {
+	assert.Nil(t, chat.NewAuditMirror(zap.NewNop(), &config.Config{}, nil), "no guild mirrors interactions")
+
+	cfg := &config.Config{Guilds: map[string]config.GuildConfig{}}
+	selected := config.GuildConfig{}
+	selected.Moderation.Mirror = config.MirrorConfig{AuditChannelID: "900", ChannelIDs: []string{"10", "not-a-channel"}}
+	everything := config.GuildConfig{}
+	everything.Moderation.Mirror.AuditChannelID = "901"
+	cfg.Guilds["1"] = selected
+	cfg.Guilds["2"] = everything
+	cfg.Guilds["3"] = config.GuildConfig{}
+	mirror := chat.NewAuditMirror(zap.NewNop(), cfg, nil)
+
+	tests := []struct {
+		name      string
+		guildID   discord.GuildID
+		channelID discord.ChannelID
+		parentID  discord.ChannelID
+		want      bool
+	}{
+		{"selected channel", 1, 10, discord.NullChannelID, true},
+		{"thread of a selected channel", 1, 55, 10, true},
+		{"other channel", 1, 11, discord.NullChannelID, false},
+		{"thread of another channel", 1, 56, 11, false},
+		{"every channel", 2, 12, discord.NullChannelID, true},
+		{"guild without a mirror", 3, 10, discord.NullChannelID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mirror.Mirrors(tt.guildID, tt.channelID, tt.parentID))
+		})
+	}
+
+	var disabled *chat.AuditMirror
+	assert.False(t, disabled.Mirrors(1, 10, discord.NullChannelID))
+	disabled.Record(chat.MirrorEntry{GuildID: 1, ChannelID: 10})
+	purged, err := disabled.PurgeBefore(t.Context(), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+}
+
+func TestAuditMirror_PurgeBefore(t *testing.T) {
+	cutoff := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	message := func(at time.Time, authorID discord.UserID, title string) discord.Message {
+		msg := discord.Message{ID: discord.MessageID(discord.NewSnowflake(at)), ChannelID: 900, Author: discord.User{ID: authorID}}
+		if title != "" {
+			msg.Embeds = []discord.Embed{{Title: title}}
+		}
+
+		return msg
+	}
+	expired := message(cutoff.Add(-time.Hour), 100, "🔍 AI interaction")
+	fake := &fakeDiscord{history: map[discord.ChannelID][]discord.Message{900: {
+		message(cutoff.Add(time.Hour), 100, "🔍 AI interaction"),
+		message(cutoff.Add(-time.Minute), 7, "🔍 AI interaction"),
+		message(cutoff.Add(-30*time.Minute), 100, "Moderator note"),
+		expired,
+	}}}
+	cfg := &config.Config{Guilds: map[string]config.GuildConfig{}}
+	guild := config.GuildConfig{}
+	guild.Moderation.Mirror.AuditChannelID = "900"
+	cfg.Guilds["1"] = guild
+	mirror := chat.NewAuditMirror(zap.NewNop(), cfg, fakeSession(fake))
+
+	purged, err := mirror.PurgeBefore(t.Context(), cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged, "only the bot's expired audit entries are deleted")
+	deleted := fake.posted()
+	require.Len(t, deleted, 1)
+	assert.Equal(t, http.MethodDelete, deleted[0].Method)
+	assert.Equal(t, "/channels/900/messages/"+expired.ID.String(), deleted[0].Path)
+}
@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+)
+
+// ModelPin is what a thread's replies are generated with once its first
+// reply is: the exact model snapshot the configured model resolved to, and
+// the generation parameters in effect then. Threads keep it when the guild's
+// defaults or the model's alias change, until /model upgrades them.
+type ModelPin struct {
+	Model             string `json:"model"`                         // Configured model the pin applies to, e.g. gpt-4o
+	Snapshot          string `json:"snapshot"`                      // Model version that answered, e.g. gpt-4o-2024-08-06
+	MaxResponseTokens int    `json:"max_response_tokens,omitempty"` // Reply token limit, 0 for none
+}
+
+// ThreadModelState is the model the conversation of a thread uses.
+type ThreadModelState struct {
+	Model       string
+	Pin         *ModelPin // nil until the next reply pins the thread
+	InitiatorID discord.UserID
+}
+
+type modelPinKey struct{}
+
+// WithModelPin returns a context whose chat completion requests for the
+// pinned model use the pin's snapshot and parameters.
+func WithModelPin(ctx context.Context, pin *ModelPin) context.Context {
+	if pin == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, modelPinKey{}, pin)
+}
+
+// pinnedRequest returns the model to request and the reply token limit for a
+// request for model: its pin's when ctx pins model, else model itself and
+// maxTokens.
+func pinnedRequest(ctx context.Context, model string, maxTokens int) (string, int) {
+	pin, ok := ctx.Value(modelPinKey{}).(*ModelPin)
+	if !ok || pin.Model != model || pin.Snapshot == "" {
+		return model, maxTokens
+	}
+
+	return pin.Snapshot, pin.MaxResponseTokens
+}
+
+// newModelPin pins model to the snapshot named in the response to a request
+// for it, with the current parameters of guildID. It is nil when the
+// provider named no snapshot.
+func (s *Service) newModelPin(guildID discord.GuildID, model, snapshot string) *ModelPin {
+	if snapshot == "" {
+		return nil
+	}
+
+	return &ModelPin{
+		Model:             model,
+		Snapshot:          snapshot,
+		MaxResponseTokens: s.cfg.Limits(guildID.String()).MaxResponseTokens,
+	}
+}
+
+// pinThread pins the conversation of threadID to the snapshot that answered
+// it, unless it is already pinned to model. Threads started before pinning,
+// rebuilt from Discord history or upgraded with /model are pinned by their
+// next reply.
+func (s *Service) pinThread(guildID discord.GuildID, threadID discord.ChannelID, model, snapshot string, current *ModelPin) {
+	if current != nil && current.Model == model {
+		return
+	}
+	pin := s.newModelPin(guildID, model, snapshot)
+	if pin == nil {
+		return
+	}
+	s.conversationStore.PinModel(threadID.String(), model, pin)
+	s.logger.Info("Pinned thread model",
+		zap.String("threadID", threadID.String()),
+		zap.String("model", model),
+		zap.String("snapshot", snapshot),
+		zap.Int("maxResponseTokens", pin.MaxResponseTokens))
+}
+
+// ThreadModel returns the model state of the conversation in threadID. It
+// reports false when the conversation is neither cached nor archived.
+func (s *Service) ThreadModel(ctx context.Context, threadID discord.ChannelID) (ThreadModelState, bool) {
+	data, ok := s.conversationStore.GetConversation(threadID.String())
+	if !ok {
+		data, ok = s.archiver.Rehydrate(ctx, threadID.String())
+	}
+	if !ok {
+		return ThreadModelState{}, false
+	}
+
+	return ThreadModelState{Model: data.Model, Pin: data.Pin, InitiatorID: data.Access.InitiatorID}, true
+}
+
+// UpgradeThreadModel drops the pin of the conversation in threadID, so that
+// its next reply is generated with the current snapshot and defaults and
+// pins them again. A non-empty model switches the thread to that configured
+// model. It returns the model the thread uses from now on.
+func (s *Service) UpgradeThreadModel(ctx context.Context, threadID discord.ChannelID, model string) (string, error) {
+	state, ok := s.ThreadModel(ctx, threadID)
+	if !ok {
+		return "", errors.New("this thread's conversation is not loaded, send a message in it first")
+	}
+	if model == "" {
+		model = state.Model
+	} else {
+		selected, err := s.modelSelector.SelectModel(model)
+		if err != nil {
+			return "", err
+		}
+		if selected != model {
+			return "", fmt.Errorf("model %q is not available", model)
+		}
+	}
+
+	s.conversationStore.PinModel(threadID.String(), model, nil)
+	s.logger.Info("Upgraded thread model",
+		zap.String("threadID", threadID.String()),
+		zap.String("previousModel", state.Model),
+		zap.String("model", model))
+
+	return model, nil
+}
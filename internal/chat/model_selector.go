@@ -14,34 +14,37 @@ type ModelSelector interface {
 }
 
 // NewConfigModelSelector creates a new ModelSelector based on application configuration.
-func NewConfigModelSelector(logger *zap.Logger, cfg *config.Config) ModelSelector {
+func NewConfigModelSelector(logger *zap.Logger, configs config.Provider) ModelSelector {
 	return &configModelSelector{
-		logger: logger.Named("model_selector"),
-		cfg:    cfg,
+		logger:  logger.Named("model_selector"),
+		configs: configs,
 	}
 }
 
-// NewModelSelector creates a new ModelSelector implementation.
-func NewModelSelector(logger *zap.Logger, cfg *config.Config) ModelSelector {
+// NewModelSelector creates a new ModelSelector implementation. It selects
+// from the models configured at the time, so reloaded model lists apply to
+// the next request.
+func NewModelSelector(logger *zap.Logger, configs config.Provider) ModelSelector {
 	return &configModelSelector{
-		logger: logger.Named("model_selector"),
-		cfg:    cfg,
+		logger:  logger.Named("model_selector"),
+		configs: configs,
 	}
 }
 
 type configModelSelector struct {
-	logger *zap.Logger
-	cfg    *config.Config
+	logger  *zap.Logger
+	configs config.Provider
 }
 
 // SelectModel validates model configuration and selects the model to use.
 func (cms *configModelSelector) SelectModel(userPreference string) (string, error) {
-	if len(cms.cfg.OpenAI.Models) == 0 {
-		return "", errors.New("no OpenAI models configured")
+	models := cms.configs.Current().ChatModels()
+	if len(models) == 0 {
+		return "", errors.New("no AI models configured")
 	}
 
 	if userPreference != "" {
-		for _, configuredModel := range cms.cfg.OpenAI.Models {
+		for _, configuredModel := range models {
 			if userPreference == configuredModel {
 				cms.logger.Debug("Using user-specified model", zap.String("model", userPreference))
 
@@ -50,11 +53,11 @@ func (cms *configModelSelector) SelectModel(userPreference string) (string, erro
 		}
 		cms.logger.Warn("User specified an invalid model, defaulting.",
 			zap.String("specifiedModel", userPreference),
-			zap.Strings("availableModels", cms.cfg.OpenAI.Models),
+			zap.Strings("availableModels", models),
 		)
 	}
 
-	defaultModel := cms.cfg.OpenAI.Models[0]
+	defaultModel := models[0]
 	cms.logger.Debug("Using default model", zap.String("model", defaultModel))
 
 	return defaultModel, nil // Default to the first configured model
@@ -8,6 +8,7 @@ import (
 	"github.com/diamondburned/arikawa/v3/session"
 
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
 	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
 )
 
@@ -15,14 +16,74 @@ import (
 var Module = fx.Module("chat",
 	fx.Provide(
 		NewDiscordInteractionManager,
-		NewOpenAIProvider,
+		NewAIProvider,
 		NewConversationStoreProvider,
 		NewModelSelector,
 		NewSummaryParser,
+		NewContentNormalizer,
+		NewRequestLimiter,
 		NewOpenAITitleGenerator,
+		NewOpenAISuggestionGenerator,
 		NewUsageFormatterProvider,
 		NewMessageEmbedServiceProvider,
+		NewDiscussionRunner,
+		fx.Annotate(
+			NewRefiner,
+			fx.ParamTags(``, ``, ``, `group:"chat_tools"`),
+		),
+		NewLinkReader,
+		NewImageInliner,
+		NewContextWindowManager,
+		NewWhisperTranscriber,
+		NewVoiceNotes,
+		fx.Annotate(
+			NewLinkTool,
+			fx.ResultTags(`group:"chat_tools"`),
+		),
+		fx.Annotate(
+			NewVideoTool,
+			fx.ResultTags(`group:"chat_tools"`),
+		),
+		fx.Annotate(
+			NewPatchTool,
+			fx.ResultTags(`group:"chat_tools"`),
+		),
+		NewModelDeprecations,
+		NewDeadLetters,
+		NewOutbox,
+		NewBudgets,
+		NewAuditMirror,
 		NewService,
+		NewCacheWarmer,
+		NewConversationArchiver,
+		fx.Annotate(
+			NewConversationEraser,
+			fx.ResultTags(`group:"erasers"`),
+		),
+		fx.Annotate(
+			NewParticipationEraser,
+			fx.ResultTags(`group:"erasers"`),
+		),
+		fx.Annotate(
+			NewConversationPurger,
+			fx.ResultTags(`group:"purgers"`),
+		),
+		fx.Annotate(
+			NewAuditLogPurger,
+			fx.ResultTags(`group:"purgers"`),
+		),
+		fx.Annotate(
+			NewBudgetEraser,
+			fx.ResultTags(`group:"erasers"`),
+		),
+		fx.Annotate(
+			NewDeadLetterEraser,
+			fx.ResultTags(`group:"erasers"`),
+		),
+		fx.Annotate(
+			NewBudgetPurger,
+			fx.ResultTags(`group:"purgers"`),
+		),
 	),
 )
 
@@ -31,6 +92,8 @@ func NewConversationStoreProvider(
 	logger *zap.Logger,
 	cfg *config.Config,
 	summaryParser SummaryParser,
+	normalizer ContentNormalizer,
+	m *metrics.Metrics,
 ) ConversationStore {
 	messageCacheSize := cfg.OpenAI.MessageCacheSize
 	if messageCacheSize <= 0 {
@@ -46,7 +109,7 @@ func NewConversationStoreProvider(
 		negativeThreadCacheSize = 1000
 	}
 
-	return NewConversationStore(logger, messageCacheSize, negativeThreadCacheSize, summaryParser)
+	return NewConversationStore(logger, messageCacheSize, negativeThreadCacheSize, summaryParser, normalizer, m)
 }
 
 // NewUsageFormatterProvider creates a UsageFormatter with the pricing service.
@@ -0,0 +1,222 @@
+package chat
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+const (
+	nameCacheSize = 1024
+	// nameCacheTTL bounds how long a renamed user, role or channel keeps its old name.
+	nameCacheTTL = 30 * time.Minute
+)
+
+// Names shown for mentions that cannot be resolved.
+const (
+	unknownUserName    = "unknown-user"
+	unknownRoleName    = "unknown-role"
+	unknownChannelName = "unknown-channel"
+)
+
+var (
+	customEmojiPattern  = regexp.MustCompile(`<a?:(\w{2,32}):\d+>`)
+	mentionPattern      = regexp.MustCompile(`<(@!?|@&|#)(\d+)>`)
+	slashCommandPattern = regexp.MustCompile(`</([\w\- ]+):\d+>`)
+	timestampPattern    = regexp.MustCompile(`<t:(-?\d+)(?::[tTdDfFR])?>`)
+
+	// zeroWidthReplacer drops invisible characters users paste to break up text.
+	zeroWidthReplacer = strings.NewReplacer("\u200b", "", "\u200c", "", "\u200d", "", "\u2060", "", "\ufeff", "")
+)
+
+// ContentNormalizer turns Discord markup in messages into the plain text the
+// model is sent: custom emojis become their names, mentions become display
+// names, and stickers are noted by name.
+type ContentNormalizer interface {
+	// Normalize returns the text of msg for the model.
+	Normalize(msg *discord.Message) string
+	// NormalizeText rewrites the markup in text posted in channelID.
+	NormalizeText(channelID discord.ChannelID, text string) string
+}
+
+// NameAPI is the subset of the Discord API used to resolve mentions.
+type NameAPI interface {
+	Channel(channelID discord.ChannelID) (*discord.Channel, error)
+	Member(guildID discord.GuildID, userID discord.UserID) (*discord.Member, error)
+	Roles(guildID discord.GuildID) ([]discord.Role, error)
+	User(userID discord.UserID) (*discord.User, error)
+}
+
+// NewContentNormalizer creates a ContentNormalizer that resolves names through the session.
+func NewContentNormalizer(ses *session.Session) ContentNormalizer {
+	return NewContentNormalizerFromAPI(ses)
+}
+
+// NewContentNormalizerFromAPI creates a ContentNormalizer on any NameAPI.
+// Looked up names, including failed lookups, are cached for a while.
+func NewContentNormalizerFromAPI(nameAPI NameAPI) ContentNormalizer {
+	return &contentNormalizer{
+		api:      nameAPI,
+		users:    expirable.NewLRU[userKey, string](nameCacheSize, nil, nameCacheTTL),
+		roles:    expirable.NewLRU[discord.GuildID, map[discord.RoleID]string](nameCacheSize, nil, nameCacheTTL),
+		channels: expirable.NewLRU[discord.ChannelID, channelInfo](nameCacheSize, nil, nameCacheTTL),
+	}
+}
+
+type userKey struct {
+	guildID discord.GuildID
+	userID  discord.UserID
+}
+
+type channelInfo struct {
+	name    string
+	guildID discord.GuildID
+}
+
+type contentNormalizer struct {
+	api      NameAPI
+	users    *expirable.LRU[userKey, string]
+	roles    *expirable.LRU[discord.GuildID, map[discord.RoleID]string]
+	channels *expirable.LRU[discord.ChannelID, channelInfo]
+}
+
+// Normalize rewrites msg.Content, naming mentioned users as the message
+// reports them, and appends a line for each sticker.
+func (n *contentNormalizer) Normalize(msg *discord.Message) string {
+	guildID := msg.GuildID
+	if !guildID.IsValid() {
+		// Messages fetched over REST do not carry their guild
+		guildID = n.channel(msg.ChannelID).guildID
+	}
+
+	mentioned := make(map[discord.UserID]string, len(msg.Mentions))
+	for _, user := range msg.Mentions {
+		if user.Member != nil && user.Member.Nick != "" {
+			mentioned[user.ID] = user.Member.Nick
+		} else {
+			mentioned[user.ID] = GetUserDisplayName(&user.User)
+		}
+	}
+
+	text := n.normalize(guildID, msg.Content, mentioned)
+	for _, sticker := range msg.Stickers {
+		text = strings.TrimSpace(text + "\n[sticker: " + sticker.Name + "]")
+	}
+
+	return text
+}
+
+// NormalizeText rewrites text, looking up the guild of channelID for names.
+func (n *contentNormalizer) NormalizeText(channelID discord.ChannelID, text string) string {
+	return n.normalize(n.channel(channelID).guildID, text, nil)
+}
+
+func (n *contentNormalizer) normalize(guildID discord.GuildID, text string, mentioned map[discord.UserID]string) string {
+	text = zeroWidthReplacer.Replace(text)
+	text = customEmojiPattern.ReplaceAllString(text, ":$1:")
+	text = slashCommandPattern.ReplaceAllString(text, "/$1")
+	text = timestampPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		unix, err := strconv.ParseInt(timestampPattern.FindStringSubmatch(tag)[1], 10, 64)
+		if err != nil {
+			return tag
+		}
+
+		return time.Unix(unix, 0).UTC().Format("2006-01-02 15:04 UTC")
+	})
+
+	return mentionPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		match := mentionPattern.FindStringSubmatch(tag)
+		id, err := discord.ParseSnowflake(match[2])
+		if err != nil {
+			return tag
+		}
+
+		switch match[1] {
+		case "@&":
+			return "@" + n.roleName(guildID, discord.RoleID(id))
+		case "#":
+			if name := n.channel(discord.ChannelID(id)).name; name != "" {
+				return "#" + name
+			}
+
+			return "#" + unknownChannelName
+		default:
+			if name, ok := mentioned[discord.UserID(id)]; ok {
+				return "@" + name
+			}
+
+			return "@" + n.userName(guildID, discord.UserID(id))
+		}
+	})
+}
+
+// userName returns the nickname of a guild member, or the user's display name.
+func (n *contentNormalizer) userName(guildID discord.GuildID, userID discord.UserID) string {
+	key := userKey{guildID: guildID, userID: userID}
+	if name, ok := n.users.Get(key); ok {
+		return name
+	}
+
+	name := unknownUserName
+	if guildID.IsValid() {
+		if member, err := n.api.Member(guildID, userID); err == nil {
+			name = member.Nick
+			if name == "" {
+				name = GetUserDisplayName(&member.User)
+			}
+		}
+	}
+	if name == unknownUserName {
+		if user, err := n.api.User(userID); err == nil {
+			name = GetUserDisplayName(user)
+		}
+	}
+	n.users.Add(key, name)
+
+	return name
+}
+
+func (n *contentNormalizer) roleName(guildID discord.GuildID, roleID discord.RoleID) string {
+	if !guildID.IsValid() {
+		return unknownRoleName
+	}
+
+	names, ok := n.roles.Get(guildID)
+	if !ok {
+		names = map[discord.RoleID]string{}
+		if roles, err := n.api.Roles(guildID); err == nil {
+			for _, role := range roles {
+				names[role.ID] = role.Name
+			}
+		}
+		n.roles.Add(guildID, names)
+	}
+	if name, ok := names[roleID]; ok {
+		return name
+	}
+
+	return unknownRoleName
+}
+
+// channel returns the name and guild of channelID, both empty if it cannot be read.
+func (n *contentNormalizer) channel(channelID discord.ChannelID) channelInfo {
+	if !channelID.IsValid() {
+		return channelInfo{}
+	}
+	if info, ok := n.channels.Get(channelID); ok {
+		return info
+	}
+
+	var info channelInfo
+	if ch, err := n.api.Channel(channelID); err == nil {
+		info = channelInfo{name: ch.Name, guildID: ch.GuildID}
+	}
+	n.channels.Add(channelID, info)
+
+	return info
+}
@@ -0,0 +1,104 @@
+package chat_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+// fakeNameAPI serves names for guild 1 and counts lookups.
+type fakeNameAPI struct {
+	calls int
+}
+
+func (f *fakeNameAPI) Channel(channelID discord.ChannelID) (*discord.Channel, error) {
+	f.calls++
+	switch channelID {
+	case 100:
+		return &discord.Channel{ID: 100, GuildID: 1, Name: "general"}, nil
+	case 101:
+		return &discord.Channel{ID: 101, GuildID: 1, Name: "Help thread"}, nil
+	}
+
+	return nil, errors.New("unknown channel")
+}
+
+func (f *fakeNameAPI) Member(guildID discord.GuildID, userID discord.UserID) (*discord.Member, error) {
+	f.calls++
+	if guildID == 1 && userID == 20 {
+		return &discord.Member{User: discord.User{ID: 20, Username: "bob"}, Nick: "Bobby"}, nil
+	}
+
+	return nil, errors.New("unknown member")
+}
+
+func (f *fakeNameAPI) Roles(guildID discord.GuildID) ([]discord.Role, error) {
+	f.calls++
+	if guildID == 1 {
+		return []discord.Role{{ID: 30, Name: "Moderators"}}, nil
+	}
+
+	return nil, errors.New("unknown guild")
+}
+
+func (f *fakeNameAPI) User(userID discord.UserID) (*discord.User, error) {
+	f.calls++
+	if userID == 21 {
+		return &discord.User{ID: 21, Username: "carol", DisplayName: "Carol"}, nil
+	}
+
+	return nil, errors.New("unknown user")
+}
+
+func TestContentNormalizer_NormalizeText(t *testing.T) {
+	normalizer := chat.NewContentNormalizerFromAPI(&fakeNameAPI{})
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"custom emoji", "nice <:pepe_hands:123456> and <a:party:789>", "nice :pepe_hands: and :party:"},
+		{"member nickname", "ask <@20> or <@!20>", "ask @Bobby or @Bobby"},
+		{"user outside guild", "thanks <@21>", "thanks @Carol"},
+		{"unknown user", "hi <@99>", "hi @unknown-user"},
+		{"role", "ping <@&30>, not <@&31>", "ping @Moderators, not @unknown-role"},
+		{"channel", "see <#100> and <#999>", "see #general and #unknown-channel"},
+		{"slash command", "try </chat:42>", "try /chat"},
+		{"timestamp", "starts <t:1618953630:R>", "starts 2021-04-20 21:20 UTC"},
+		{"zero-width", "he\u200bllo\ufeff", "hello"},
+		{"plain text", "nothing <here> to change :smile:", "nothing <here> to change :smile:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizer.NormalizeText(101, tt.text))
+		})
+	}
+}
+
+func TestContentNormalizer_Normalize(t *testing.T) {
+	nameAPI := &fakeNameAPI{}
+	normalizer := chat.NewContentNormalizerFromAPI(nameAPI)
+
+	msg := &discord.Message{
+		ChannelID: 101,
+		Content:   "<@20> <@22> look <:wow:1>",
+		Mentions: []discord.GuildUser{
+			{User: discord.User{ID: 22, Username: "dave", DisplayName: "Dave"}},
+		},
+		Stickers: []discord.StickerItem{{Name: "Wumpus Wave"}},
+	}
+	assert.Equal(t, "@Bobby @Dave look :wow:\n[sticker: Wumpus Wave]", normalizer.Normalize(msg))
+
+	calls := nameAPI.calls
+	assert.Equal(t, "@Bobby @Dave look :wow:\n[sticker: Wumpus Wave]", normalizer.Normalize(msg))
+	assert.Equal(t, calls, nameAPI.calls, "names are cached")
+
+	stickerOnly := &discord.Message{GuildID: 1, Stickers: []discord.StickerItem{{Name: "Hi"}}}
+	assert.Equal(t, "[sticker: Hi]", normalizer.Normalize(stickerOnly))
+}
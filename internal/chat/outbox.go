@@ -0,0 +1,270 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const (
+	defaultOutboxFile   = "outbox.json"
+	defaultOutboxMaxAge = time.Hour
+	// outboxScanMessages is how many recent messages are searched for a
+	// reply that was posted before the bot stopped.
+	outboxScanMessages = 25
+	// outboxMatchRunes is the length of the reply prefix searched for.
+	outboxMatchRunes = 80
+)
+
+// OutboxEntry is a completed AI reply, recorded before it is posted.
+type OutboxEntry struct {
+	ID            string            `json:"id"`
+	GuildID       discord.GuildID   `json:"guild_id"`
+	ChannelID     discord.ChannelID `json:"channel_id"`
+	RequesterID   discord.UserID    `json:"requester_id,omitempty"` // Carried to the reply's dead letter
+	Character     string            `json:"character,omitempty"`
+	Content       string            `json:"content,omitempty"`
+	SealedContent []byte            `json:"sealed_content,omitempty"` // Content encrypted for the guild, when encryption is on
+	RecordedAt    time.Time         `json:"recorded_at"`
+}
+
+// Outbox records replies in a JSON document until Discord has accepted them, so
+// that replies interrupted by a restart are posted once the bot is back. A
+// nil *Outbox records nothing, as when it is disabled.
+type Outbox struct {
+	logger *zap.Logger
+	doc    storage.Document
+	sealer storage.Sealer
+	maxAge time.Duration
+
+	mu      sync.Mutex
+	entries []OutboxEntry // Oldest first, as stored
+}
+
+// NewOutbox creates the outbox configured in cfg, loading the replies still
+// pending from the last run. Replies are sealed with sealer when encryption
+// at rest is on. It returns nil when the outbox is disabled.
+func NewOutbox(logger *zap.Logger, cfg *config.Config, state storage.Provider, sealer storage.Sealer) (*Outbox, error) {
+	outbox := cfg.Outbox
+	if !outbox.Enabled {
+		return nil, nil
+	}
+
+	key := outbox.File
+	if key == "" {
+		key = defaultOutboxFile
+	}
+	o := &Outbox{
+		logger: logger.Named("outbox"),
+		doc:    storage.NewDocument(state, key),
+		sealer: sealer,
+		maxAge: time.Duration(outbox.MaxAgeMinutes) * time.Minute,
+	}
+	if o.maxAge <= 0 {
+		o.maxAge = defaultOutboxMaxAge
+	}
+	if _, err := o.doc.Load(&o.entries); err != nil {
+		return nil, fmt.Errorf("failed to load outbox: %w", err)
+	}
+
+	return o, nil
+}
+
+// Record stores entry under a new ID before it is posted and returns the ID.
+func (o *Outbox) Record(ctx context.Context, entry OutboxEntry) (string, error) {
+	if o == nil {
+		return "", nil
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to create outbox ID: %w", err)
+	}
+	entry.ID = hex.EncodeToString(id)
+	entry.RecordedAt = time.Now().UTC()
+	sealed, err := sealContent(ctx, o.sealer, entry.GuildID, entry.Content)
+	if err != nil {
+		return "", err
+	}
+	if sealed != nil {
+		entry.Content = ""
+		entry.SealedContent = sealed
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	previous := o.entries
+	o.entries = append(slices.Clone(o.entries), entry)
+	if err := o.persist(); err != nil {
+		o.entries = previous
+
+		return "", err
+	}
+
+	return entry.ID, nil
+}
+
+// Delivered drops the entry with id once Discord has it, or once it has been
+// handed to the dead letter queue.
+func (o *Outbox) Delivered(id string) error {
+	if o == nil || id == "" {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	i := slices.IndexFunc(o.entries, func(e OutboxEntry) bool { return e.ID == id })
+	if i < 0 {
+		return nil
+	}
+	previous := o.entries
+	o.entries = slices.Delete(slices.Clone(o.entries), i, i+1)
+	if err := o.persist(); err != nil {
+		o.entries = previous
+
+		return err
+	}
+
+	return nil
+}
+
+// Pending returns the entries not delivered yet, oldest first, with their
+// content decrypted. Entries that cannot be decrypted are left out and kept
+// for a later run.
+func (o *Outbox) Pending(ctx context.Context) []OutboxEntry {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	entries := slices.Clone(o.entries)
+	o.mu.Unlock()
+
+	pending := make([]OutboxEntry, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry.SealedContent) > 0 {
+			content, err := openContent(ctx, o.sealer, entry.GuildID, entry.SealedContent)
+			if err != nil {
+				o.logger.Error("Failed to decrypt outbox reply", zap.Error(err), zap.String("id", entry.ID))
+
+				continue
+			}
+			entry.Content = content
+			entry.SealedContent = nil
+		}
+		pending = append(pending, entry)
+	}
+
+	return pending
+}
+
+// persist saves the entries. The caller must hold o.mu.
+func (o *Outbox) persist() error {
+	return o.doc.Save(o.entries)
+}
+
+// recordReply adds a reply to the outbox before it is posted. Replies are
+// still posted when they cannot be recorded.
+func (s *Service) recordReply(ctx context.Context, guildID discord.GuildID, channelID discord.ChannelID, character *characters.Character, content string) string {
+	entry := OutboxEntry{GuildID: guildID, ChannelID: channelID, RequesterID: requesterFrom(ctx), Content: content}
+	if character != nil {
+		entry.Character = character.Name
+	}
+	id, err := s.outbox.Record(ctx, entry)
+	if err != nil {
+		s.logger.Error("Failed to record reply in outbox", zap.Error(err), zap.String("channelID", channelID.String()))
+	}
+
+	return id
+}
+
+// markDelivered drops a reply from the outbox after it was posted or queued
+// as a dead letter.
+func (s *Service) markDelivered(id string) {
+	if err := s.outbox.Delivered(id); err != nil {
+		s.logger.Error("Failed to mark reply delivered in outbox", zap.Error(err), zap.String("id", id))
+	}
+}
+
+// RedeliverPending posts the replies left in the outbox by the last run.
+// Replies already in their channel are only marked delivered, so none is
+// posted twice; replies older than the outbox's maximum age are queued as
+// dead letters instead, since the conversation has likely moved on.
+func (s *Service) RedeliverPending(ctx context.Context) {
+	pending := s.outbox.Pending(ctx)
+	if len(pending) == 0 {
+		return
+	}
+	s.logger.Info("Redelivering replies left in the outbox", zap.Int("pending", len(pending)))
+
+	for _, entry := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+
+		character := s.threadCharacter(entry.GuildID, entry.Character)
+		entryCtx := withRequester(ctx, entry.RequesterID)
+		switch {
+		case time.Since(entry.RecordedAt) > s.outbox.maxAge:
+			s.deadLetter(entryCtx, entry.GuildID, entry.ChannelID, character, entry.Content, errReplyExpired)
+		case s.alreadyPosted(entry):
+			s.logger.Debug("Outbox reply was posted before the restart", zap.String("id", entry.ID))
+		default:
+			if err := s.postReply(entryCtx, entry.GuildID, entry.ChannelID, character, entry.Content, nil); err != nil {
+				s.logger.Warn("Failed to redeliver outbox reply", zap.Error(err), zap.String("id", entry.ID))
+				if !IsPermanentDeliveryError(err) {
+					continue
+				}
+				s.deadLetter(entryCtx, entry.GuildID, entry.ChannelID, character, entry.Content, err)
+			}
+		}
+		s.markDelivered(entry.ID)
+	}
+}
+
+// errReplyExpired is recorded for outbox replies too old to post.
+var errReplyExpired = errors.New("reply was not delivered before the bot restarted, and is too old to post")
+
+// alreadyPosted reports whether the start of entry's reply is among the bot's
+// recent messages in its channel.
+func (s *Service) alreadyPosted(entry OutboxEntry) bool {
+	prefix := []rune(strings.TrimSpace(entry.Content))
+	prefix = prefix[:min(len(prefix), outboxMatchRunes)]
+	if len(prefix) == 0 {
+		return false
+	}
+
+	self, err := s.getSelfUser()
+	if err != nil {
+		return false
+	}
+	messages, err := s.ses.Messages(entry.ChannelID, outboxScanMessages)
+	if err != nil {
+		s.logger.Warn("Failed to check channel for posted reply", zap.Error(err), zap.String("channelID", entry.ChannelID.String()))
+
+		return false
+	}
+	for _, msg := range messages {
+		fromBot := msg.Author.ID == self.ID || msg.WebhookID.IsValid()
+		if fromBot && msg.Timestamp.Time().After(entry.RecordedAt.Add(-time.Minute)) && strings.HasPrefix(msg.Content, string(prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package chat_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func newOutbox(t *testing.T, path string, sealer storage.Sealer) *chat.Outbox {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Outbox = config.OutboxConfig{Enabled: true, File: path}
+	o, err := chat.NewOutbox(zap.NewNop(), cfg, storage.NewFileProvider(""), sealer)
+	require.NoError(t, err)
+	require.NotNil(t, o)
+
+	return o
+}
+
+// newSealer returns a Sealer with a fresh local key.
+func newSealer(t *testing.T) storage.Sealer {
+	t.Helper()
+	keyFile := filepath.Join(t.TempDir(), "archive.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(make([]byte, 32))), 0o600))
+	keys, err := storage.NewLocalKeyProvider(keyFile)
+	require.NoError(t, err)
+
+	return storage.NewEnvelopeSealer(keys)
+}
+
+func TestOutbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	o := newOutbox(t, path, nil)
+
+	first, err := o.Record(t.Context(), chat.OutboxEntry{GuildID: 1, ChannelID: 10, Content: "first reply"})
+	require.NoError(t, err)
+	second, err := o.Record(t.Context(), chat.OutboxEntry{GuildID: 1, ChannelID: 20, Content: "second reply", Character: "Ada"})
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	require.NoError(t, o.Delivered(first))
+
+	// Replies not delivered before a restart are still pending after it
+	pending := newOutbox(t, path, nil).Pending(t.Context())
+	require.Len(t, pending, 1)
+	assert.Equal(t, second, pending[0].ID)
+	assert.Equal(t, "second reply", pending[0].Content)
+	assert.Equal(t, "Ada", pending[0].Character)
+	assert.False(t, pending[0].RecordedAt.IsZero())
+
+	require.NoError(t, o.Delivered(second))
+	require.NoError(t, o.Delivered(second), "delivering twice is harmless")
+	assert.Empty(t, newOutbox(t, path, nil).Pending(t.Context()))
+}
+
+func TestOutbox_Sealed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	sealer := newSealer(t)
+	o := newOutbox(t, path, sealer)
+
+	_, err := o.Record(t.Context(), chat.OutboxEntry{GuildID: 1, ChannelID: 10, Content: "secret reply"})
+	require.NoError(t, err)
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(saved), "secret reply", "replies are stored encrypted")
+
+	pending := newOutbox(t, path, sealer).Pending(t.Context())
+	require.Len(t, pending, 1)
+	assert.Equal(t, "secret reply", pending[0].Content)
+	assert.Empty(t, newOutbox(t, path, nil).Pending(t.Context()), "replies are kept until they can be decrypted")
+}
+
+func TestOutbox_Disabled(t *testing.T) {
+	o, err := chat.NewOutbox(zap.NewNop(), &config.Config{}, storage.NewFileProvider(""), nil)
+	require.NoError(t, err)
+	assert.Nil(t, o)
+
+	id, err := o.Record(t.Context(), chat.OutboxEntry{Content: "not recorded"})
+	require.NoError(t, err)
+	assert.Empty(t, id)
+	require.NoError(t, o.Delivered(id))
+	assert.Empty(t, o.Pending(t.Context()))
+}
@@ -0,0 +1,344 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	// createPatchTool is the name the model calls the patch tool by.
+	createPatchTool = "create_patch"
+	// diffContext is the number of unchanged lines around each hunk.
+	diffContext = 3
+	// maxDiffCells bounds the line comparison table, so huge files fail
+	// instead of taking all the memory.
+	maxDiffCells = 4_000_000
+	// maxPatchesPerReply bounds the files attached to one reply.
+	maxPatchesPerReply = 5
+)
+
+// ErrDiffTooLarge is returned by UnifiedDiff for files too large to compare.
+var ErrDiffTooLarge = errors.New("files are too large to diff")
+
+// UnifiedDiff returns the changes from original to modified as a unified
+// diff of filename, or an empty string when they are the same.
+func UnifiedDiff(filename, original, modified string) (string, error) {
+	a, b := splitLines(original), splitLines(modified)
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return "", ErrDiffTooLarge
+	}
+
+	ops := diffLines(a, b)
+	var out strings.Builder
+	for _, hunk := range diffHunks(ops) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", filename, filename)
+		}
+		oldStart, oldCount, newStart, newCount := hunkRange(hunk)
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range hunk {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+	}
+
+	return out.String(), nil
+}
+
+// diffOp is one line of an edit script: kept (' '), deleted ('-') or
+// inserted ('+'). a and b are the positions in the old and new lines.
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns a shortest edit script from a to b, from their longest
+// common subsequence.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], a: i, b: j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[i], a: i, b: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j], a: i, b: j})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// diffHunks groups the changes of ops with diffContext lines around them,
+// merging changes that are close together.
+func diffHunks(ops []diffOp) [][]diffOp {
+	var hunks [][]diffOp
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+
+			continue
+		}
+
+		start := max(0, i-diffContext)
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			kept := 0
+			for end+kept < len(ops) && ops[end+kept].kind == ' ' {
+				kept++
+			}
+			if end+kept < len(ops) && kept <= 2*diffContext {
+				end += kept
+
+				continue
+			}
+			end += min(kept, diffContext)
+
+			break
+		}
+		hunks = append(hunks, ops[start:end])
+		i = end
+	}
+
+	return hunks
+}
+
+// hunkRange returns the line ranges of hunk for its header. Empty ranges
+// start at the line before them, as in diff -u.
+func hunkRange(hunk []diffOp) (oldStart, oldCount, newStart, newCount int) {
+	for _, op := range hunk {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	oldStart, newStart = hunk[0].a, hunk[0].b
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+
+	return oldStart, oldCount, newStart, newCount
+}
+
+// patchFile is a patch made by the tool, attached to the reply.
+type patchFile struct {
+	name string
+	diff string
+}
+
+// patchSession collects the patches made while one reply is written, and
+// holds the user messages they are checked against.
+type patchSession struct {
+	mu      sync.Mutex
+	pasted  []string
+	patches []patchFile
+}
+
+type patchSessionKey struct{}
+
+// withPatchSession returns a context in which the patch tool records its
+// patches, checking them against the user messages of messages.
+func withPatchSession(ctx context.Context, messages []openai.ChatCompletionMessage) (context.Context, *patchSession) {
+	session := &patchSession{}
+	for _, msg := range messages {
+		if msg.Role == openai.ChatMessageRoleUser {
+			session.pasted = append(session.pasted, normalizeCode(messageText(msg)))
+		}
+	}
+
+	return context.WithValue(ctx, patchSessionKey{}, session), session
+}
+
+// matchesPasted reports whether original appears in one of the user messages.
+func (p *patchSession) matchesPasted(original string) bool {
+	original = normalizeCode(original)
+	for _, pasted := range p.pasted {
+		if strings.Contains(pasted, original) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *patchSession) add(file patchFile) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.patches) >= maxPatchesPerReply {
+		return false
+	}
+	p.patches = append(p.patches, file)
+
+	return true
+}
+
+func (p *patchSession) files() []patchFile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]patchFile(nil), p.patches...)
+}
+
+// normalizeCode drops trailing whitespace from each line, which chat clients
+// and models often change.
+func normalizeCode(code string) string {
+	lines := strings.Split(strings.TrimSpace(code), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// NewPatchTool creates the patch tool, or returns nil unless patches are enabled.
+func NewPatchTool(logger *zap.Logger, cfg *config.Config) Tool {
+	if !cfg.OpenAI.Patches.Enabled {
+		return nil
+	}
+
+	return &patchTool{logger: logger.Named("patch_tool")}
+}
+
+type patchTool struct {
+	logger *zap.Logger
+}
+
+// Definition describes create_patch to the model.
+func (t *patchTool) Definition() openai.FunctionDefinition {
+	return openai.FunctionDefinition{
+		Name: createPatchTool,
+		Description: "Turn a proposed code change into a unified diff, attached to your reply as a .patch file the user can apply with git apply. " +
+			"Use it when you change code the user pasted. The result says whether the original matches the code in the conversation.",
+		Parameters: jsonschema.Definition{
+			Type: jsonschema.Object,
+			Properties: map[string]jsonschema.Definition{
+				"filename": {Type: jsonschema.String, Description: "Path of the changed file, such as cmd/main.go"},
+				"original": {Type: jsonschema.String, Description: "The complete original code being changed, exactly as the user pasted it"},
+				"modified": {Type: jsonschema.String, Description: "The same code with the change applied"},
+			},
+			Required: []string{"filename", "original", "modified"},
+		},
+	}
+}
+
+// Call diffs the code in arguments and records the patch for the reply.
+func (t *patchTool) Call(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		Filename string `json:"filename"`
+		Original string `json:"original"`
+		Modified string `json:"modified"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	filename := strings.TrimPrefix(path.Clean("/"+strings.TrimSpace(args.Filename)), "/")
+	if filename == "" || filename == "." {
+		return "", errors.New("filename is required")
+	}
+
+	diff, err := UnifiedDiff(filename, args.Original, args.Modified)
+	if err != nil {
+		return "", err
+	}
+	if diff == "" {
+		return "The original and modified code are the same, so there is no patch.", nil
+	}
+
+	result := "Patch created"
+	session, ok := ctx.Value(patchSessionKey{}).(*patchSession)
+	switch {
+	case !ok:
+		result += ", but it cannot be attached here, so include the diff in your answer"
+	case !session.add(patchFile{name: path.Base(filename) + ".patch", diff: diff}):
+		result += ", but no more patches can be attached to this reply, so include the diff in your answer"
+	default:
+		result += " and attached to your reply; do not repeat it in full"
+	}
+	if ok && !session.matchesPasted(args.Original) {
+		result += ". Warning: the original does not match the code in the conversation, so the patch may not apply cleanly; tell the user"
+	} else if ok {
+		result += ". The original matches the code in the conversation, so it applies cleanly"
+	}
+	t.logger.Debug("Model created a patch", zap.String("filename", filename), zap.Int("diffBytes", len(diff)))
+
+	return result + ".\n\n" + diff, nil
+}
+
+// sendPatches attaches the patches made for the reply in threadID, if any.
+func (s *Service) sendPatches(threadID discord.ChannelID) {
+	value, ok := s.pendingPatches.LoadAndDelete(threadID)
+	if !ok {
+		return
+	}
+	patches, _ := value.([]patchFile)
+
+	files := make([]sendpart.File, len(patches))
+	names := make([]string, len(patches))
+	for i, patch := range patches {
+		files[i] = sendpart.File{Name: patch.name, Reader: strings.NewReader(patch.diff)}
+		names[i] = "`" + patch.name + "`"
+	}
+
+	_, err := s.ses.SendMessageComplex(threadID, api.SendMessageData{
+		Content:         "📎 " + strings.Join(names, ", ") + " (apply with `git apply`)",
+		Files:           files,
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	if err != nil {
+		s.logger.Error("Failed to attach patches", zap.Error(err), zap.String("threadID", threadID.String()))
+	}
+}
@@ -0,0 +1,68 @@
+package chat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	original := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+	modified := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"
+
+	diff, err := chat.UnifiedDiff("main.go", original, modified)
+	require.NoError(t, err)
+	assert.Equal(t, "--- a/main.go\n+++ b/main.go\n"+
+		"@@ -3,5 +3,5 @@\n"+
+		" import \"fmt\"\n \n func main() {\n-\tfmt.Println(\"hi\")\n+\tfmt.Println(\"hello\")\n }\n", diff)
+
+	diff, err = chat.UnifiedDiff("main.go", original, original)
+	require.NoError(t, err)
+	assert.Empty(t, diff, "identical code has no diff")
+}
+
+func TestUnifiedDiff_Hunks(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, strings.Repeat("x", i))
+	}
+	original := strings.Join(lines, "\n")
+	lines[1] = "changed 2"
+	lines[17] = "changed 18"
+	modified := strings.Join(lines, "\n")
+
+	diff, err := chat.UnifiedDiff("a.txt", original, modified)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "@@ -1,5 +1,5 @@\n", "the first hunk is clipped at the start of the file")
+	assert.Contains(t, diff, "@@ -15,6 +15,6 @@\n", "the second hunk is clipped at the end of the file")
+	assert.Equal(t, 2, strings.Count(diff, "@@ -"), "distant changes get their own hunks")
+
+	diff, err = chat.UnifiedDiff("new.txt", "", "one\ntwo\n")
+	require.NoError(t, err)
+	assert.Contains(t, diff, "@@ -0,0 +1,2 @@\n+one\n+two\n", "an added file starts at line 0")
+}
+
+func TestPatchTool(t *testing.T) {
+	assert.Nil(t, chat.NewPatchTool(zap.NewNop(), &config.Config{}), "the tool is off unless enabled")
+
+	cfg := &config.Config{}
+	cfg.OpenAI.Patches.Enabled = true
+	tool := chat.NewPatchTool(zap.NewNop(), cfg)
+	require.NotNil(t, tool)
+	assert.Equal(t, "create_patch", tool.Definition().Name)
+
+	result, err := tool.Call(context.Background(), `{"filename": "../x/main.go", "original": "a\nb\n", "modified": "a\nc\n"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "--- a/x/main.go\n+++ b/x/main.go\n", "paths cannot leave the repository")
+	assert.Contains(t, result, "-b\n+c\n")
+
+	_, err = tool.Call(context.Background(), `{"filename": "", "original": "a", "modified": "b"}`)
+	assert.Error(t, err, "a filename is required")
+}
@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/api/webhook"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+)
+
+const characterMarker = "**Character:** "
+
+// characterSummaryLine returns the summary message line naming the thread's character.
+func characterSummaryLine(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return characterMarker + name + "\n"
+}
+
+// parseCharacterName reads the character name from a thread summary message.
+func parseCharacterName(content string, referencedMessage *discord.Message) string {
+	if content == "" && referencedMessage != nil {
+		content = referencedMessage.Content
+	}
+
+	_, rest, ok := strings.Cut(content, characterMarker)
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, "\n")
+
+	return strings.TrimSpace(name)
+}
+
+// IsPersonaMessage reports whether msg was posted by a character through one
+// of the bot's webhooks. Webhooks created by a bot carry its application ID,
+// which equals the bot's user ID.
+func IsPersonaMessage(msg *discord.Message, selfUser *discord.User) bool {
+	return msg.WebhookID.IsValid() && discord.Snowflake(msg.ApplicationID) == discord.Snowflake(selfUser.ID)
+}
+
+// withPersona prepends the character's system prompt to messages. Without a
+// character messages are returned unchanged.
+func withPersona(character *characters.Character, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if character == nil {
+		return messages
+	}
+
+	withSystem := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	withSystem = append(withSystem, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: character.SystemPrompt(),
+	})
+
+	return append(withSystem, messages...)
+}
+
+// assistantName returns the name recorded for assistant messages.
+func assistantName(character *characters.Character, botDisplayName string) string {
+	if character != nil {
+		return character.Name
+	}
+
+	return botDisplayName
+}
+
+// threadCharacter looks up the character of a cached conversation. Characters
+// deleted since the thread started fall back to the bot's own identity.
+func (s *Service) threadCharacter(guildID discord.GuildID, name string) *characters.Character {
+	if name == "" || s.characters == nil {
+		return nil
+	}
+
+	character, ok := s.characters.Get(guildID, name)
+	if !ok {
+		s.logger.Info("Thread character no longer exists, replying as the bot",
+			zap.String("guildID", guildID.String()),
+			zap.String("character", name))
+
+		return nil
+	}
+
+	return &character
+}
+
+// sendPersonaMessage posts content to a thread through the webhook pool, under
+// the character's name and avatar. Long content is split over
+// several messages, each ending with disclosure if set; footer is attached to
+// the last one.
+func (s *Service) sendPersonaMessage(threadID discord.ChannelID, character *characters.Character, content string, footer *discord.Embed, disclosure string) (*discord.Message, error) {
+	chunks := SplitMessageWithFooter(content, disclosure)
+	var last *discord.Message
+	for i, chunk := range chunks {
+		data := webhook.ExecuteData{
+			Content:   chunk,
+			Username:  character.Name,
+			AvatarURL: character.AvatarURL,
+			// Characters must not be able to ping anyone
+			AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+		}
+		if i == len(chunks)-1 && footer != nil {
+			data.Embeds = []discord.Embed{*footer}
+		}
+
+		var err error
+		last, err = s.webhooks.Execute(threadID, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send character message: %w", err)
+		}
+	}
+
+	return last, nil
+}
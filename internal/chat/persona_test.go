@@ -0,0 +1,18 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+func TestIsPersonaMessage(t *testing.T) {
+	self := &discord.User{ID: 42}
+
+	assert.True(t, chat.IsPersonaMessage(&discord.Message{WebhookID: 7, ApplicationID: 42}, self))
+	assert.False(t, chat.IsPersonaMessage(&discord.Message{WebhookID: 7, ApplicationID: 99}, self), "webhooks of other apps are not personas")
+	assert.False(t, chat.IsPersonaMessage(&discord.Message{Author: discord.User{ID: 42}}, self), "the bot's own messages are not webhook messages")
+}
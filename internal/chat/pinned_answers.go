@@ -0,0 +1,215 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// PinAnswerComponentID is the button on AI replies that pins them as answers.
+// It is routed to /chat, which hands it to HandlePinAnswer.
+const PinAnswerComponentID = discord.ComponentID("chat:pin-answer")
+
+const (
+	// defaultPinnedAnswerTokens bounds the pinned answers kept in a trimmed
+	// request when pinned_answers.max_tokens is unset.
+	defaultPinnedAnswerTokens = 1000
+	// pinnedAnswersPrefix introduces the pinned answers kept in a request
+	// whose turns they were given in were trimmed.
+	pinnedAnswersPrefix = "Answers pinned earlier in this conversation, newest first:\n"
+)
+
+// pinnedAnswer is an AI reply pinned in its thread.
+type pinnedAnswer struct {
+	messageID discord.MessageID
+	text      string
+}
+
+// answerIndex lists the answers pinned in a thread, newest first. It is
+// loaded from the thread's pins on first use, so it survives restarts.
+type answerIndex struct {
+	mu      sync.Mutex
+	loaded  bool
+	answers []pinnedAnswer
+}
+
+// answerIndex returns the index of threadID in guildID, loading it from the
+// thread's pins if needed.
+func (s *Service) answerIndex(guildID discord.GuildID, threadID discord.ChannelID) *answerIndex {
+	value, _ := s.pinnedAnswers.LoadOrStore(threadID, &answerIndex{})
+	index := value.(*answerIndex)
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.loaded {
+		return index
+	}
+	selfUser, err := s.getSelfUser()
+	if err != nil {
+		s.logger.Warn("Failed to get self user for pinned answers", zap.Error(err), zap.String("threadID", threadID.String()))
+
+		return index
+	}
+	pins, err := s.ses.PinnedMessages(threadID)
+	if err != nil {
+		s.logger.Warn("Failed to load pinned answers", zap.Error(err), zap.String("threadID", threadID.String()))
+
+		return index
+	}
+	for i := range pins {
+		if pins[i].Author.ID != selfUser.ID {
+			continue
+		}
+		if text := s.answerText(guildID, &pins[i]); text != "" && !index.has(pins[i].ID) {
+			index.answers = append(index.answers, pinnedAnswer{messageID: pins[i].ID, text: text})
+		}
+	}
+	index.loaded = true
+
+	return index
+}
+
+func (i *answerIndex) has(messageID discord.MessageID) bool {
+	for _, answer := range i.answers {
+		if answer.messageID == messageID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pinnedAnswerTexts returns the answers pinned in threadID, newest first, up
+// to the configured tokens.
+func (s *Service) pinnedAnswerTexts(guildID discord.GuildID, threadID discord.ChannelID) []string {
+	if !s.cfg.OpenAI.PinnedAnswers.Enabled || !threadID.IsValid() {
+		return nil
+	}
+	maxTokens := s.cfg.OpenAI.PinnedAnswers.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultPinnedAnswerTokens
+	}
+
+	index := s.answerIndex(guildID, threadID)
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	var texts []string
+	tokens := 0
+	for _, answer := range index.answers {
+		if tokens += CountTokens("", answer.text); tokens > maxTokens {
+			break
+		}
+		texts = append(texts, answer.text)
+	}
+
+	return texts
+}
+
+// answerText returns the text of an AI reply posted as msg, without the
+// guild's disclosure line.
+func (s *Service) answerText(guildID discord.GuildID, msg *discord.Message) string {
+	text := msg.Content
+	if disclosure := s.disclosure(guildID); disclosure != "" {
+		text = strings.TrimSuffix(text, "\n"+disclosure)
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// HandlePinAnswer handles the button on AI replies. The reply is pinned in
+// its thread and added to the thread's answers, which trimmed requests keep.
+// Only members who may continue the thread can pin its answers.
+func (s *Service) HandlePinAnswer(_ context.Context, e *gateway.InteractionCreateEvent) error {
+	msg := e.Message
+	if msg == nil {
+		return errors.New("pin button interaction has no message")
+	}
+	threadID := e.ChannelID
+	user := e.SenderID()
+	if cached, ok := s.conversationStore.GetConversation(threadID.String()); ok && !cached.Access.Allows(user, e.Member, s.threadRoleIDs) {
+		return s.respondComponent(e, api.MessageInteractionWithSource, "Only people who can continue this conversation can pin its answers.", true)
+	}
+
+	index := s.answerIndex(e.GuildID, threadID)
+	reason := api.AuditLogReason("Pinned as answer by " + e.Sender().Username)
+	if err := s.ses.PinMessage(threadID, msg.ID, reason); err != nil {
+		s.logger.Warn("Failed to pin answer", zap.Error(err), zap.String("threadID", threadID.String()), zap.String("messageID", msg.ID.String()))
+
+		return s.respondComponent(e, api.MessageInteractionWithSource,
+			"I couldn't pin this answer. I need the Manage Messages permission, and a thread holds at most 50 pins.", true)
+	}
+
+	index.mu.Lock()
+	if !index.has(msg.ID) {
+		index.answers = append([]pinnedAnswer{{messageID: msg.ID, text: s.answerText(e.GuildID, msg)}}, index.answers...)
+	}
+	index.mu.Unlock()
+
+	s.logger.Info("Answer pinned",
+		zap.String("threadID", threadID.String()),
+		zap.String("messageID", msg.ID.String()),
+		zap.String("userID", user.String()))
+	components := replyComponents(true, true, suggestedQuestions(msg))
+	err := s.ses.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.UpdateMessage,
+		Data: &api.InteractionResponseData{Components: &components},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to respond to pin button: %w", err)
+	}
+
+	return nil
+}
+
+// pinAnswerButton returns the pin button, disabled once the reply is pinned.
+func pinAnswerButton(pinned bool) *discord.ButtonComponent {
+	button := &discord.ButtonComponent{
+		Label:    "Pin as answer",
+		CustomID: PinAnswerComponentID,
+		Style:    discord.SecondaryButtonStyle(),
+		Emoji:    &discord.ComponentEmoji{Name: "📌"},
+	}
+	if pinned {
+		button.Label = "Pinned as answer"
+		button.Disabled = true
+	}
+
+	return button
+}
+
+// pinnedAnswersMessage is the system message holding answers of a request
+// whose turns they were given in were trimmed.
+func pinnedAnswersMessage(answers []string) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: pinnedAnswersPrefix + strings.Join(answers, "\n\n---\n\n"),
+	}
+}
+
+type pinnedAnswersKey struct{}
+
+// withPinnedAnswers passes the answers pinned in a thread to the context
+// window manager.
+func withPinnedAnswers(ctx context.Context, answers []string) context.Context {
+	if len(answers) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, pinnedAnswersKey{}, answers)
+}
+
+func pinnedAnswersFrom(ctx context.Context) []string {
+	answers, _ := ctx.Value(pinnedAnswersKey{}).([]string)
+
+	return answers
+}
@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// NewAIProvider creates the AIProvider chat requests go through: OpenAI for
+// every model, or, with an Anthropic API key set, a router that sends Claude
+// models to Anthropic and the rest to OpenAI.
+// Each provider retries transient failures and has its own circuit breaker.
+func NewAIProvider(logger *zap.Logger, cfg *config.Config, keys internalopenai.KeyResolver, pricingService pkgopenai.PricingService, m *metrics.Metrics, resilience *internalopenai.Resilience) AIProvider {
+	openAI := NewResilientProvider(NewOpenAIProvider(logger, cfg, keys, pricingService, m), resilience, "OpenAI", keys)
+	if cfg.Anthropic.APIKey == "" {
+		return openAI
+	}
+
+	anthropic := NewResilientProvider(NewAnthropicProvider(logger, cfg, pricingService, m), resilience, "Anthropic", nil)
+	anthropicModels := cfg.Anthropic.Models
+
+	return NewProviderRouter(openAI, func(model string) AIProvider {
+		if strings.HasPrefix(model, anthropicModelPrefix) || slices.Contains(anthropicModels, model) {
+			return anthropic
+		}
+
+		return nil
+	})
+}
+
+// NewProviderRouter creates an AIProvider that sends each request to the
+// provider route returns for its model, or to fallback when route returns
+// nil. It offers tools, streaming, summaries and structured outputs for every
+// model; providers without one of them get a plain completion instead.
+func NewProviderRouter(fallback AIProvider, route func(model string) AIProvider) AIProvider {
+	return &providerRouter{fallback: fallback, route: route}
+}
+
+type providerRouter struct {
+	fallback AIProvider
+	route    func(model string) AIProvider
+}
+
+func (r *providerRouter) provider(model string) AIProvider {
+	if p := r.route(model); p != nil {
+		return p
+	}
+
+	return r.fallback
+}
+
+// GetChatCompletion sends the request to the model's provider.
+func (r *providerRouter) GetChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	return r.provider(model).GetChatCompletion(ctx, model, messages)
+}
+
+// GetChatCompletionWithTools offers tools when the model's provider can call them.
+func (r *providerRouter) GetChatCompletionWithTools(ctx context.Context, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*openai.ChatCompletionResponse, error) {
+	return completeWithTools(ctx, r.provider(model), model, messages, tools)
+}
+
+// StreamChatCompletion streams the reply when the model's provider can.
+func (r *providerRouter) StreamChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	return streamCompletion(ctx, r.provider(model), model, messages)
+}
+
+// Summarize summarizes in the summarizing mode of the model's provider, when
+// it has one.
+func (r *providerRouter) Summarize(ctx context.Context, model string, messages []openai.ChatCompletionMessage, instructions string) (*openai.ChatCompletionResponse, error) {
+	return summarizeWith(ctx, r.provider(model), model, messages, instructions)
+}
+
+// GetStructuredCompletion holds the reply to the JSON schema of format when
+// the model's provider can.
+func (r *providerRouter) GetStructuredCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	return completeStructured(ctx, r.provider(model), model, messages, format)
+}
+
+// completeWithTools offers tools when p can call them.
+func completeWithTools(ctx context.Context, p AIProvider, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*openai.ChatCompletionResponse, error) {
+	if tc, ok := p.(ToolCallingProvider); ok {
+		return tc.GetChatCompletionWithTools(ctx, model, messages, tools)
+	}
+
+	return p.GetChatCompletion(ctx, model, messages)
+}
+
+// streamCompletion streams the reply when p can.
+func streamCompletion(ctx context.Context, p AIProvider, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	if streamer, ok := p.(StreamingProvider); ok {
+		return streamer.StreamChatCompletion(ctx, model, messages)
+	}
+
+	return p.GetChatCompletion(ctx, model, messages)
+}
+
+// completeStructured holds the reply to the JSON schema of format when p can.
+// Otherwise only the instructions in messages ask for it.
+func completeStructured(ctx context.Context, p AIProvider, model string, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	if structured, ok := p.(StructuredProvider); ok {
+		return structured.GetStructuredCompletion(ctx, model, messages, format)
+	}
+
+	return p.GetChatCompletion(ctx, model, messages)
+}
+
+// summarizeWith summarizes in the summarizing mode of p, when it has one.
+func summarizeWith(ctx context.Context, p AIProvider, model string, messages []openai.ChatCompletionMessage, instructions string) (*openai.ChatCompletionResponse, error) {
+	if summarizer, ok := p.(SummarizingProvider); ok {
+		return summarizer.Summarize(ctx, model, messages, instructions)
+	}
+
+	request := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	request = append(request, messages...)
+	request = append(request, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: instructions})
+
+	return p.GetChatCompletion(ctx, model, request)
+}
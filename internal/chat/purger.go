@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/retention"
+)
+
+// conversationPurger enforces the conversations retention window on the
+// conversation cache and on cold storage.
+type conversationPurger struct {
+	store    ConversationStore
+	archiver *ConversationArchiver
+}
+
+// NewConversationPurger creates the retention.Purger for chat conversations.
+func NewConversationPurger(store ConversationStore, archiver *ConversationArchiver) retention.Purger {
+	return &conversationPurger{store: store, archiver: archiver}
+}
+
+// DataType implements retention.Purger.
+func (p *conversationPurger) DataType() string {
+	return retention.Conversations
+}
+
+// PurgeBefore implements retention.Purger. Threads stay in Discord, so a
+// purged conversation that receives a new message is reconstructed from the
+// thread history like any other cache miss.
+func (p *conversationPurger) PurgeBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	expired := p.store.IdleConversations(cutoff)
+	for _, threadID := range expired {
+		p.store.Evict(threadID)
+	}
+
+	archived, err := p.archiver.PurgeBefore(ctx, cutoff)
+
+	return len(expired) + archived, err
+}
+
+// auditLogPurger enforces the audit_logs retention window on the entries the
+// AuditMirror posted to audit channels.
+type auditLogPurger struct {
+	mirror *AuditMirror
+}
+
+// NewAuditLogPurger creates the retention.Purger for mirrored audit entries.
+func NewAuditLogPurger(mirror *AuditMirror) retention.Purger {
+	return &auditLogPurger{mirror: mirror}
+}
+
+// DataType implements retention.Purger.
+func (p *auditLogPurger) DataType() string {
+	return retention.AuditLogs
+}
+
+// PurgeBefore implements retention.Purger.
+func (p *auditLogPurger) PurgeBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	return p.mirror.PurgeBefore(ctx, cutoff)
+}
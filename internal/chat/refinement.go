@@ -0,0 +1,276 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// Steps of a refined reply, as they appear in usage footers.
+const (
+	StepDraft    = "draft"
+	StepCritique = "critique"
+	StepRevision = "revision"
+	StepTool     = "tool" // a draft round that ended in tool calls
+	// StepContinuation continues a reply that stopped at the token limit.
+	StepContinuation = "continuation"
+)
+
+// maxToolRounds bounds how many times the model may call tools for one reply.
+const maxToolRounds = 3
+
+// defaultMaxContinuations bounds the continuations of a cut off reply.
+const defaultMaxContinuations = 2
+
+const continuationInstructions = "Your answer above was cut off at the length limit. Continue it exactly where it stopped, " +
+	"without repeating anything or adding an introduction. If it stopped inside a code block, continue inside that block."
+
+// critiqueApproved is the critique reply meaning the draft needs no revision.
+const critiqueApproved = "LGTM"
+
+const critiqueInstructions = "You review draft answers written by an assistant. Check the draft against the question for " +
+	"factual errors, parts of the question it does not answer, and unclear or overly long passages. " +
+	"Reply with a short list of concrete problems and how to fix them. " +
+	"If the draft needs no changes, reply with exactly " + critiqueApproved + "."
+
+const revisionInstructions = "A reviewer critiqued your answer above:\n\n%s\n\n" +
+	"Rewrite your answer to address the critique. Reply with the final answer only, " +
+	"without mentioning the critique or that the answer was revised."
+
+// Refiner produces chat replies. Where refinement is enabled, the reply is
+// drafted, critiqued by a cheaper model and revised; elsewhere it is a single call.
+type Refiner interface {
+	// Complete returns the reply to post and the usage of every call made for it.
+	Complete(ctx context.Context, guildID discord.GuildID, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, []CallUsage, error)
+}
+
+// NewRefiner creates a Refiner that follows the global and per-guild refinement
+// settings. Drafts may call tools when aiProvider supports them; nil tools are ignored.
+func NewRefiner(logger *zap.Logger, cfg *config.Config, aiProvider AIProvider, tools []Tool) Refiner {
+	r := &refiner{
+		logger:     logger.Named("refiner"),
+		cfg:        cfg,
+		aiProvider: aiProvider,
+		tools:      make(map[string]Tool),
+	}
+	for _, tool := range tools {
+		if tool == nil {
+			continue
+		}
+		def := tool.Definition()
+		r.tools[def.Name] = tool
+		r.toolDefs = append(r.toolDefs, openai.Tool{Type: openai.ToolTypeFunction, Function: &def})
+	}
+	if _, ok := aiProvider.(ToolCallingProvider); !ok && len(r.toolDefs) > 0 {
+		r.logger.Warn("AI provider does not support tools, ignoring them", zap.Int("toolCount", len(r.toolDefs)))
+		r.toolDefs = nil
+	}
+
+	return r
+}
+
+type refiner struct {
+	logger     *zap.Logger
+	cfg        *config.Config
+	aiProvider AIProvider
+	tools      map[string]Tool
+	toolDefs   []openai.Tool
+}
+
+// Complete drafts a reply and, when refinement applies to guildID, critiques
+// and revises it. Failures after the draft fall back to posting the draft.
+func (r *refiner) Complete(ctx context.Context, guildID discord.GuildID, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, []CallUsage, error) {
+	draft, calls, err := r.draft(ctx, model, messages)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Without a reply there is nothing to continue or refine; callers report it
+	if len(draft.Choices) == 0 {
+		return draft, calls, nil
+	}
+	calls = append(calls, r.continueReply(ctx, model, messages, draft)...)
+	if !r.enabled(guildID) {
+		return draft, calls, nil
+	}
+
+	draftContent := draft.Choices[0].Message.Content
+	critiqueModel := r.cfg.OpenAI.Refinement.CritiqueModel
+	if critiqueModel == "" {
+		critiqueModel = model
+	}
+
+	critique, err := r.aiProvider.GetChatCompletion(ctx, critiqueModel, critiqueMessages(messages, draftContent))
+	if err != nil {
+		r.logger.Warn("Critique failed, posting the draft", zap.Error(err), zap.String("critiqueModel", critiqueModel))
+
+		return draft, calls, nil
+	}
+	calls = append(calls, CallUsage{Step: StepCritique, Model: critiqueModel, Usage: critique.Usage})
+
+	critiqueContent := strings.TrimSpace(critique.Choices[0].Message.Content)
+	if strings.EqualFold(strings.Trim(critiqueContent, ".! "), critiqueApproved) {
+		r.logger.Debug("Critique approved the draft")
+
+		return draft, calls, nil
+	}
+
+	revisionRequest := make([]openai.ChatCompletionMessage, 0, len(messages)+2)
+	revisionRequest = append(revisionRequest, messages...)
+	revisionRequest = append(revisionRequest,
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: draftContent},
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: fmt.Sprintf(revisionInstructions, critiqueContent)},
+	)
+
+	revision, err := r.aiProvider.GetChatCompletion(ctx, model, revisionRequest)
+	if err != nil {
+		r.logger.Warn("Revision failed, posting the draft", zap.Error(err))
+
+		return draft, calls, nil
+	}
+	calls = append(calls, CallUsage{Step: StepRevision, Model: model, Usage: revision.Usage})
+	calls = append(calls, r.continueReply(ctx, model, revisionRequest, revision)...)
+
+	r.logger.Info("Reply refined", zap.String("model", model), zap.String("critiqueModel", critiqueModel))
+
+	return revision, calls, nil
+}
+
+// draft gets the first reply, running the tools the model asks for. After
+// maxToolRounds the model has to answer without tools. Without tools the
+// reply is streamed when openai.stream is set.
+func (r *refiner) draft(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, []CallUsage, error) {
+	if len(r.toolDefs) == 0 {
+		var resp *openai.ChatCompletionResponse
+		var err error
+		if streamer, ok := r.aiProvider.(StreamingProvider); ok && r.cfg.OpenAI.Stream {
+			resp, err = streamer.StreamChatCompletion(ctx, model, messages)
+		} else {
+			resp, err = r.aiProvider.GetChatCompletion(ctx, model, messages)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return resp, []CallUsage{{Step: StepDraft, Model: model, Usage: resp.Usage}}, nil
+	}
+
+	provider := r.aiProvider.(ToolCallingProvider)
+	request := append([]openai.ChatCompletionMessage(nil), messages...)
+	var calls []CallUsage
+	for round := 0; ; round++ {
+		var resp *openai.ChatCompletionResponse
+		var err error
+		if round < maxToolRounds {
+			resp, err = provider.GetChatCompletionWithTools(ctx, model, request, r.toolDefs)
+		} else {
+			resp, err = r.aiProvider.GetChatCompletion(ctx, model, request)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		reply := resp.Choices[0].Message
+		if len(reply.ToolCalls) == 0 {
+			calls = append(calls, CallUsage{Step: StepDraft, Model: model, Usage: resp.Usage})
+
+			return resp, calls, nil
+		}
+		calls = append(calls, CallUsage{Step: StepTool, Model: model, Usage: resp.Usage})
+
+		request = append(request, reply)
+		for _, call := range reply.ToolCalls {
+			request = append(request, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    r.runTool(ctx, call),
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
+
+// continueReply asks the model to continue resp while it stops at the token
+// limit, up to the configured number of times, and appends each part to resp.
+// If a continuation fails, resp is kept as far as it got.
+func (r *refiner) continueReply(ctx context.Context, model string, messages []openai.ChatCompletionMessage, resp *openai.ChatCompletionResponse) []CallUsage {
+	limit := defaultMaxContinuations
+	if r.cfg.OpenAI.MaxContinuations != nil {
+		limit = *r.cfg.OpenAI.MaxContinuations
+	}
+
+	var calls []CallUsage
+	for len(calls) < limit && resp.Choices[0].FinishReason == openai.FinishReasonLength {
+		request := make([]openai.ChatCompletionMessage, 0, len(messages)+2)
+		request = append(request, messages...)
+		request = append(request,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: resp.Choices[0].Message.Content},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: continuationInstructions},
+		)
+
+		next, err := r.aiProvider.GetChatCompletion(ctx, model, request)
+		if err != nil {
+			r.logger.Warn("Continuation failed, posting the reply as cut off", zap.Error(err), zap.Int("continuations", len(calls)))
+
+			break
+		}
+		calls = append(calls, CallUsage{Step: StepContinuation, Model: model, Usage: next.Usage})
+		resp.Choices[0].Message.Content += next.Choices[0].Message.Content
+		resp.Choices[0].FinishReason = next.Choices[0].FinishReason
+	}
+	if len(calls) > 0 {
+		r.logger.Info("Continued a reply cut off at the token limit", zap.String("model", model), zap.Int("continuations", len(calls)))
+	}
+
+	return calls
+}
+
+// runTool runs one tool call and returns its result, or the error for the model to see.
+func (r *refiner) runTool(ctx context.Context, call openai.ToolCall) string {
+	tool, ok := r.tools[call.Function.Name]
+	if !ok {
+		return fmt.Sprintf("error: unknown tool %q", call.Function.Name)
+	}
+
+	result, err := tool.Call(ctx, call.Function.Arguments)
+	if err != nil {
+		r.logger.Info("Tool call failed", zap.String("tool", call.Function.Name), zap.Error(err))
+
+		return "error: " + err.Error()
+	}
+	r.logger.Debug("Ran tool", zap.String("tool", call.Function.Name))
+
+	return result
+}
+
+// enabled reports whether replies in guildID are refined. Guild overrides
+// take precedence over the global setting.
+func (r *refiner) enabled(guildID discord.GuildID) bool {
+	if override := r.cfg.Guild(guildID.String()).Chat.Refinement; override != nil {
+		return *override
+	}
+
+	return r.cfg.OpenAI.Refinement.Enabled
+}
+
+// critiqueMessages asks the critique model to review draft against the
+// latest user message.
+func critiqueMessages(messages []openai.ChatCompletionMessage, draft string) []openai.ChatCompletionMessage {
+	question := ""
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == openai.ChatMessageRoleUser {
+			question = messageText(messages[i])
+
+			break
+		}
+	}
+
+	return []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: critiqueInstructions},
+		{Role: openai.ChatMessageRoleUser, Content: fmt.Sprintf("Question:\n%s\n\nDraft answer:\n%s", question, draft)},
+	}
+}
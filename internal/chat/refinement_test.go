@@ -0,0 +1,211 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// queuedAI answers requests with queued replies; an empty reply fails the call.
+// The first truncated replies stop at the token limit.
+type queuedAI struct {
+	replies   []string
+	models    []string
+	last      []openai.ChatCompletionMessage
+	truncated int
+}
+
+func (a *queuedAI) GetChatCompletion(_ context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	a.models = append(a.models, model)
+	a.last = messages
+	reply := a.replies[0]
+	a.replies = a.replies[1:]
+	if reply == "" {
+		return nil, errors.New("call failed")
+	}
+	finishReason := openai.FinishReasonStop
+	if a.truncated > 0 {
+		a.truncated--
+		finishReason = openai.FinishReasonLength
+	}
+
+	return &openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: reply}, FinishReason: finishReason}},
+		Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func refinementConfig(enabled bool) *config.Config {
+	cfg := &config.Config{}
+	cfg.OpenAI.Refinement = config.RefinementConfig{Enabled: enabled, CritiqueModel: "cheap"}
+
+	return cfg
+}
+
+var refinePrompt = []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "What is 2+2?"}}
+
+func steps(calls []chat.CallUsage) []string {
+	out := make([]string, len(calls))
+	for i, call := range calls {
+		out[i] = call.Step + ":" + call.Model
+	}
+
+	return out
+}
+
+func TestRefiner_Disabled(t *testing.T) {
+	ai := &queuedAI{replies: []string{"4"}}
+	refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(false), ai, nil)
+
+	resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "4", resp.Choices[0].Message.Content)
+	assert.Equal(t, []string{"draft:big"}, steps(calls))
+}
+
+// streamingAI streams its queued replies.
+type streamingAI struct {
+	queuedAI
+	streamed int
+}
+
+func (a *streamingAI) StreamChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	a.streamed++
+
+	return a.GetChatCompletion(ctx, model, messages)
+}
+
+func TestRefiner_Streams(t *testing.T) {
+	ai := &streamingAI{queuedAI: queuedAI{replies: []string{"4", "4"}}}
+	cfg := refinementConfig(false)
+	refiner := chat.NewRefiner(zap.NewNop(), cfg, ai, nil)
+
+	_, _, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Zero(t, ai.streamed, "streaming is off by default")
+
+	cfg.OpenAI.Stream = true
+	resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "4", resp.Choices[0].Message.Content)
+	assert.Equal(t, []string{"draft:big"}, steps(calls))
+	assert.Equal(t, 1, ai.streamed)
+}
+
+func TestRefiner_Revises(t *testing.T) {
+	ai := &queuedAI{replies: []string{"5", "The sum is wrong.", "4"}}
+	refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(true), ai, nil)
+
+	resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "4", resp.Choices[0].Message.Content)
+	assert.Equal(t, []string{"draft:big", "critique:cheap", "revision:big"}, steps(calls))
+
+	// The revision sees the draft and the critique after the conversation
+	require.Len(t, ai.last, 3)
+	assert.Equal(t, "5", ai.last[1].Content)
+	assert.Contains(t, ai.last[2].Content, "The sum is wrong.")
+}
+
+func TestRefiner_ApprovedDraft(t *testing.T) {
+	ai := &queuedAI{replies: []string{"4", "LGTM."}}
+	refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(true), ai, nil)
+
+	resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "4", resp.Choices[0].Message.Content)
+	assert.Equal(t, []string{"draft:big", "critique:cheap"}, steps(calls))
+}
+
+func TestRefiner_FallsBackToDraft(t *testing.T) {
+	t.Run("CritiqueFails", func(t *testing.T) {
+		ai := &queuedAI{replies: []string{"4", ""}}
+		refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(true), ai, nil)
+
+		resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+		require.NoError(t, err)
+		assert.Equal(t, "4", resp.Choices[0].Message.Content)
+		assert.Len(t, calls, 1)
+	})
+
+	t.Run("RevisionFails", func(t *testing.T) {
+		ai := &queuedAI{replies: []string{"4", "Add units.", ""}}
+		refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(true), ai, nil)
+
+		resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+		require.NoError(t, err)
+		assert.Equal(t, "4", resp.Choices[0].Message.Content)
+		assert.Len(t, calls, 2)
+	})
+
+	t.Run("DraftFails", func(t *testing.T) {
+		ai := &queuedAI{replies: []string{""}}
+		refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(true), ai, nil)
+
+		_, _, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+		assert.Error(t, err)
+	})
+}
+
+func TestRefiner_GuildOverride(t *testing.T) {
+	on, off := true, false
+	cfg := refinementConfig(false)
+	cfg.OpenAI.Refinement.CritiqueModel = ""
+	cfg.Guilds = map[string]config.GuildConfig{
+		"1": {Chat: config.GuildChatConfig{Refinement: &on}},
+		"2": {Chat: config.GuildChatConfig{Refinement: &off}},
+	}
+
+	ai := &queuedAI{replies: []string{"4", "LGTM", "4"}}
+	refiner := chat.NewRefiner(zap.NewNop(), cfg, ai, nil)
+
+	_, calls, err := refiner.Complete(context.Background(), discord.GuildID(1), "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"draft:big", "critique:big"}, steps(calls), "critique defaults to the reply model")
+
+	_, calls, err = refiner.Complete(context.Background(), discord.GuildID(2), "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Len(t, calls, 1)
+}
+
+func TestRefiner_ContinuesCutOffReplies(t *testing.T) {
+	ai := &queuedAI{replies: []string{"```go\nfunc main() {", "\n\tprintln(1)", "\n}\n```"}, truncated: 2}
+	refiner := chat.NewRefiner(zap.NewNop(), refinementConfig(false), ai, nil)
+
+	resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "```go\nfunc main() {\n\tprintln(1)\n}\n```", resp.Choices[0].Message.Content, "the parts are stitched together")
+	assert.Equal(t, openai.FinishReasonStop, resp.Choices[0].FinishReason)
+	assert.Equal(t, []string{"draft:big", "continuation:big", "continuation:big"}, steps(calls))
+	assert.Equal(t, openai.ChatMessageRoleAssistant, ai.last[len(ai.last)-2].Role)
+	assert.Equal(t, "```go\nfunc main() {\n\tprintln(1)", ai.last[len(ai.last)-2].Content, "continuations see the reply so far")
+}
+
+func TestRefiner_ContinuationLimit(t *testing.T) {
+	limit := 1
+	ai := &queuedAI{replies: []string{"part one", " part two", ""}, truncated: 3}
+	cfg := refinementConfig(false)
+	cfg.OpenAI.MaxContinuations = &limit
+	refiner := chat.NewRefiner(zap.NewNop(), cfg, ai, nil)
+
+	resp, calls, err := refiner.Complete(context.Background(), 1, "big", refinePrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "part one part two", resp.Choices[0].Message.Content)
+	assert.Equal(t, openai.FinishReasonLength, resp.Choices[0].FinishReason, "still marked as cut off")
+	assert.Len(t, calls, 2)
+
+	ai = &queuedAI{replies: []string{"part one", ""}, truncated: 1}
+	resp, calls, err = chat.NewRefiner(zap.NewNop(), refinementConfig(false), ai, nil).Complete(context.Background(), 1, "big", refinePrompt)
+	require.NoError(t, err, "a failed continuation keeps the reply so far")
+	assert.Equal(t, "part one", resp.Choices[0].Message.Content)
+	assert.Len(t, calls, 1)
+}
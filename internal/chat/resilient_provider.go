@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sashabaranov/go-openai"
+
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+)
+
+// NewResilientProvider creates an AIProvider sending requests to provider
+// through resilience, which retries transient failures and fails requests
+// fast while service keeps failing with the credentials keys resolves for
+// the request's guild. keys is nil for services with one set of credentials.
+// Like the provider router, it offers tools, streaming, summaries and
+// structured outputs, falling back to plain completions when provider has
+// none.
+func NewResilientProvider(provider AIProvider, resilience *internalopenai.Resilience, service string, keys internalopenai.KeyResolver) AIProvider {
+	return &resilientProvider{provider: provider, resilience: resilience, service: service, keys: keys}
+}
+
+type resilientProvider struct {
+	provider   AIProvider
+	resilience *internalopenai.Resilience
+	service    string
+	keys       internalopenai.KeyResolver
+}
+
+// GetChatCompletion requests a completion, retrying transient failures.
+func (p *resilientProvider) GetChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	return p.do(ctx, func(ctx context.Context) (*openai.ChatCompletionResponse, error) {
+		return p.provider.GetChatCompletion(ctx, model, messages)
+	})
+}
+
+// GetChatCompletionWithTools requests a completion offering tools, retrying
+// transient failures.
+func (p *resilientProvider) GetChatCompletionWithTools(ctx context.Context, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*openai.ChatCompletionResponse, error) {
+	return p.do(ctx, func(ctx context.Context) (*openai.ChatCompletionResponse, error) {
+		return completeWithTools(ctx, p.provider, model, messages, tools)
+	})
+}
+
+// StreamChatCompletion streams a completion, retrying transient failures
+// until content arrives. A stream interrupted after that is not retried, so
+// its partial reply is kept.
+func (p *resilientProvider) StreamChatCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	return p.do(ctx, func(ctx context.Context) (*openai.ChatCompletionResponse, error) {
+		resp, err := streamCompletion(ctx, p.provider, model, messages)
+		var interrupted *InterruptedError
+		if errors.As(err, &interrupted) {
+			return resp, internalopenai.Permanent(err)
+		}
+
+		return resp, err
+	})
+}
+
+// Summarize requests a summary, retrying transient failures.
+func (p *resilientProvider) Summarize(ctx context.Context, model string, messages []openai.ChatCompletionMessage, instructions string) (*openai.ChatCompletionResponse, error) {
+	return p.do(ctx, func(ctx context.Context) (*openai.ChatCompletionResponse, error) {
+		return summarizeWith(ctx, p.provider, model, messages, instructions)
+	})
+}
+
+// GetStructuredCompletion requests a completion following the JSON schema of
+// format, retrying transient failures.
+func (p *resilientProvider) GetStructuredCompletion(ctx context.Context, model string, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	return p.do(ctx, func(ctx context.Context) (*openai.ChatCompletionResponse, error) {
+		return completeStructured(ctx, p.provider, model, messages, format)
+	})
+}
+
+func (p *resilientProvider) do(ctx context.Context, call func(ctx context.Context) (*openai.ChatCompletionResponse, error)) (*openai.ChatCompletionResponse, error) {
+	var creds internalopenai.Credentials
+	if p.keys != nil {
+		creds = p.keys.Credentials(internalopenai.GuildFrom(ctx))
+	}
+
+	var resp *openai.ChatCompletionResponse
+	err := p.resilience.Do(ctx, p.service, creds, func(ctx context.Context) error {
+		var err error
+		resp, err = call(ctx)
+
+		return err
+	})
+
+	return resp, err
+}
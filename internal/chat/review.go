@@ -0,0 +1,256 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultReviewModel    = "gpt-4.1-mini"
+	defaultReviewMaxChars = 40000
+	reviewDownloadTimeout = 30 * time.Second
+)
+
+// Severities of review findings, from most to least severe.
+const (
+	SeverityCritical = "critical"
+	SeverityMajor    = "major"
+	SeverityMinor    = "minor"
+	SeverityNit      = "nit"
+)
+
+var severityOrder = []string{SeverityCritical, SeverityMajor, SeverityMinor, SeverityNit}
+
+var severityLabels = map[string]string{
+	SeverityCritical: "🔴 critical",
+	SeverityMajor:    "🟠 major",
+	SeverityMinor:    "🟡 minor",
+	SeverityNit:      "⚪ nit",
+}
+
+const reviewInstructions = "You are a senior engineer reviewing a code change given as a unified diff. " +
+	"Look for bugs, security problems, missing error handling, race conditions and unclear code in the changed lines. " +
+	"Do not comment on unchanged code or praise the change. Reply with JSON only, in this shape: " +
+	`{"summary": "one or two sentences on the change and its overall quality", ` +
+	`"findings": [{"file": "path/in/diff.go", "line": 42, "severity": "critical|major|minor|nit", "comment": "the problem and how to fix it"}]}. ` +
+	"Use the line number in the new version of the file, or 0 when a finding is not about one line. " +
+	"Return an empty findings list when the change looks good."
+
+// ErrEmptyDiff is returned by /review when there is no diff to review.
+var ErrEmptyDiff = errors.New("no diff to review")
+
+// ReviewFinding is one problem found in a reviewed diff.
+type ReviewFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Comment  string `json:"comment"`
+}
+
+// Review is the structured result of a code review.
+type Review struct {
+	Summary  string          `json:"summary"`
+	Findings []ReviewFinding `json:"findings"`
+}
+
+// ParseReview reads the review the model returned, allowing for a code
+// fence around the JSON.
+func ParseReview(content string) (*Review, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+
+	var review Review
+	if err := json.Unmarshal([]byte(content), &review); err != nil {
+		return nil, fmt.Errorf("failed to parse review: %w", err)
+	}
+
+	return &review, nil
+}
+
+// FormatReview lists the findings of review grouped by file, in the order
+// the files first appear, with the most severe findings of each file first.
+func FormatReview(review *Review) string {
+	var b strings.Builder
+	if review.Summary != "" {
+		b.WriteString(review.Summary + "\n")
+	}
+	if len(review.Findings) == 0 {
+		b.WriteString("\n✅ No problems found.")
+
+		return strings.TrimSpace(b.String())
+	}
+
+	var files []string
+	byFile := make(map[string][]ReviewFinding)
+	for _, f := range review.Findings {
+		file := strings.TrimSpace(f.File)
+		if file == "" {
+			file = "General"
+		}
+		if _, ok := byFile[file]; !ok {
+			files = append(files, file)
+		}
+		byFile[file] = append(byFile[file], f)
+	}
+
+	for _, file := range files {
+		findings := byFile[file]
+		slices.SortStableFunc(findings, func(a, b ReviewFinding) int {
+			return severityRank(a.Severity) - severityRank(b.Severity)
+		})
+
+		fmt.Fprintf(&b, "\n📄 **%s**\n", file)
+		for _, f := range findings {
+			label, ok := severityLabels[strings.ToLower(f.Severity)]
+			if !ok {
+				label = severityLabels[SeverityMinor]
+			}
+			location := ""
+			if f.Line > 0 {
+				location = fmt.Sprintf(" L%d", f.Line)
+			}
+			fmt.Fprintf(&b, "- **%s**%s: %s\n", label, location, strings.TrimSpace(f.Comment))
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// severityRank orders severities, with unknown ones ranked as minor.
+func severityRank(severity string) int {
+	if i := slices.Index(severityOrder, strings.ToLower(severity)); i >= 0 {
+		return i
+	}
+
+	return slices.Index(severityOrder, SeverityMinor)
+}
+
+// HandleReviewInteraction reviews the diff pasted in the command or the one
+// in attachment, and answers the interaction with the findings.
+func (s *Service) HandleReviewInteraction(ctx context.Context, e *gateway.InteractionCreateEvent, diff string, attachment *discord.Attachment, modelOption string) error {
+	model := s.cfg.OpenAI.Review.Model
+	if model == "" {
+		model = defaultReviewModel
+	}
+	if modelOption != "" {
+		selected, err := s.modelSelector.SelectModel(modelOption)
+		if err != nil {
+			s.logger.Error("Failed to determine model", zap.Error(err))
+
+			return err
+		}
+		model = selected
+	}
+
+	if err := s.interactionManager.DeferResponse(s.ses, e.ID, e.Token); err != nil {
+		return err
+	}
+
+	maxChars := s.cfg.OpenAI.Review.MaxChars
+	if maxChars <= 0 {
+		maxChars = defaultReviewMaxChars
+	}
+	if attachment != nil {
+		text, err := downloadDiff(ctx, attachment.URL, maxChars)
+		if err != nil {
+			s.logger.Warn("Failed to download diff attachment", zap.Error(err), zap.String("filename", attachment.Filename))
+			if _, sendErr := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, "Sorry, I could not read that attachment.", ""); sendErr != nil {
+				s.logger.Error("Failed to send attachment error message", zap.Error(sendErr))
+			}
+
+			return fmt.Errorf("failed to download diff: %w", err)
+		}
+		diff = text
+	}
+	diff = strings.TrimSpace(diff)
+	if diff == "" {
+		if _, sendErr := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, "There is nothing to review: paste a diff or attach one.", ""); sendErr != nil {
+			s.logger.Error("Failed to send empty diff message", zap.Error(sendErr))
+		}
+
+		return ErrEmptyDiff
+	}
+
+	diff, truncated := TruncateMiddle(diff, maxChars)
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: reviewInstructions},
+		{Role: openai.ChatMessageRoleUser, Content: "```diff\n" + diff + "\n```", Name: SanitizeOpenAIName(GetUserDisplayName(e.Sender()))},
+	}
+
+	aiResponse, _, err := s.complete(withChannel(withRequester(ctx, e.SenderID()), e.ChannelID), e.GuildID, discord.NullChannelID, model, messages)
+	if err != nil {
+		errMsg := userErrorMessage(err, "Sorry, I encountered an error trying to reach the AI. Please try again later.")
+		if _, sendErr := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, errMsg, ""); sendErr != nil {
+			s.logger.Error("Failed to send error message after OpenAI failure", zap.Error(sendErr))
+		}
+
+		return err
+	}
+
+	reply := aiResponse.Choices[0].Message.Content
+	body := reply
+	review, err := ParseReview(reply)
+	if err != nil {
+		// The model ignored the format, so its answer is posted as written
+		s.logger.Warn("Review was not in the expected format", zap.Error(err), zap.String("model", model))
+	} else {
+		body = FormatReview(review)
+	}
+
+	header := fmt.Sprintf("🔍 **Code review** (%s)", model)
+	if truncated {
+		header += fmt.Sprintf("\n%sThe diff was longer than %d characters, so its middle was left out.", truncationNoticePrefix, maxChars)
+	}
+	if _, err := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, header+"\n\n"+body, s.disclosure(e.GuildID)); err != nil {
+		s.logger.Error("Failed to send review", zap.Error(err))
+
+		return fmt.Errorf("failed to send AI response to Discord: %w", err)
+	}
+	s.logger.Info("Posted code review", zap.String("model", model), zap.Int("diffChars", len(diff)), zap.Bool("structured", review != nil))
+
+	return nil
+}
+
+// downloadDiff reads the text of an attached diff, keeping a little more than
+// maxChars so that the middle can be cut like a pasted diff.
+func downloadDiff(ctx context.Context, url string, maxChars int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, reviewDownloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	// Characters take up to 4 bytes, so this is never less than maxChars
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxChars)*4))
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
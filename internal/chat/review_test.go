@@ -0,0 +1,41 @@
+package chat_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+func TestParseReview(t *testing.T) {
+	review, err := chat.ParseReview("```json\n{\"summary\": \"Adds a cache.\", \"findings\": [{\"file\": \"cache.go\", \"line\": 12, \"severity\": \"major\", \"comment\": \"Map is not locked.\"}]}\n```")
+	require.NoError(t, err, "code fences around the JSON are allowed")
+	assert.Equal(t, "Adds a cache.", review.Summary)
+	require.Len(t, review.Findings, 1)
+	assert.Equal(t, 12, review.Findings[0].Line)
+
+	_, err = chat.ParseReview("Looks good to me!")
+	assert.Error(t, err)
+}
+
+func TestFormatReview(t *testing.T) {
+	out := chat.FormatReview(&chat.Review{
+		Summary: "Adds a cache.",
+		Findings: []chat.ReviewFinding{
+			{File: "cache.go", Line: 30, Severity: "nit", Comment: "Typo in comment."},
+			{File: "main.go", Severity: "minor", Comment: "Unused flag."},
+			{File: "cache.go", Line: 12, Severity: "critical", Comment: "Map is not locked."},
+		},
+	})
+
+	assert.True(t, strings.HasPrefix(out, "Adds a cache."))
+	assert.Less(t, strings.Index(out, "cache.go"), strings.Index(out, "main.go"), "files keep the order they first appear in")
+	assert.Less(t, strings.Index(out, "critical"), strings.Index(out, "nit"), "severe findings come first")
+	assert.Contains(t, out, "L12: Map is not locked.")
+	assert.Equal(t, 1, strings.Count(out, "cache.go"), "findings are grouped by file")
+
+	assert.Contains(t, chat.FormatReview(&chat.Review{Summary: "Fine."}), "No problems found")
+}
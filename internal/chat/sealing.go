@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+// sealContent encrypts content for guildID with sealer. Without a sealer it
+// returns nil, and content is stored as it is.
+func sealContent(ctx context.Context, sealer storage.Sealer, guildID discord.GuildID, content string) ([]byte, error) {
+	if sealer == nil {
+		return nil, nil
+	}
+
+	sealed, err := sealer.Seal(ctx, guildID.String(), []byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt content: %w", err)
+	}
+
+	return sealed, nil
+}
+
+// openContent decrypts content sealed by sealContent for guildID.
+func openContent(ctx context.Context, sealer storage.Sealer, guildID discord.GuildID, sealed []byte) (string, error) {
+	if sealer == nil {
+		return "", errors.New("content is encrypted but encryption is not configured")
+	}
+
+	plaintext, err := sealer.Open(ctx, guildID.String(), sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// seedMarker starts the summary line recording the seed a /chat thread uses.
+const seedMarker = "**Seed:** "
+
+type seedKey struct{}
+
+// WithSeed returns a context whose chat completion requests use seed, so
+// that OpenAI samples the same reply for the same prompt where it can.
+func WithSeed(ctx context.Context, seed int) context.Context {
+	return context.WithValue(ctx, seedKey{}, seed)
+}
+
+// SeedFrom returns the seed set with WithSeed, or nil.
+func SeedFrom(ctx context.Context) *int {
+	seed, ok := ctx.Value(seedKey{}).(int)
+	if !ok {
+		return nil
+	}
+
+	return &seed
+}
+
+func seedSummaryLine(seed *int) string {
+	if seed == nil {
+		return ""
+	}
+
+	return seedMarker + strconv.Itoa(*seed) + "\n"
+}
+
+// parseSeed reads the seed from a thread summary message.
+func parseSeed(content string, referencedMessage *discord.Message) *int {
+	if content == "" && referencedMessage != nil {
+		content = referencedMessage.Content
+	}
+
+	_, rest, ok := strings.Cut(content, seedMarker)
+	if !ok {
+		return nil
+	}
+	value, _, _ := strings.Cut(rest, "\n")
+	seed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return nil
+	}
+
+	return &seed
+}
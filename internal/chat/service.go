@@ -4,16 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internaldiscord "github.com/Raikerian/go-discord-chatgpt/internal/discord"
+	"github.com/Raikerian/go-discord-chatgpt/internal/hooks"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	"github.com/Raikerian/go-discord-chatgpt/internal/permissions"
+	"github.com/Raikerian/go-discord-chatgpt/internal/prompts"
+	"github.com/Raikerian/go-discord-chatgpt/internal/youtube"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
 
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/diamondburned/arikawa/v3/session"
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/sashabaranov/go-openai"
 
 	"go.uber.org/zap"
@@ -31,11 +42,36 @@ type Service struct {
 	ses    *session.Session
 
 	interactionManager  DiscordInteractionManager
-	aiProvider          AIProvider
 	conversationStore   ConversationStore
 	modelSelector       ModelSelector
 	titleGenerator      ThreadTitleGenerator
+	suggestions         SuggestionGenerator
 	messageEmbedService MessageEmbedService
+	archiver            *ConversationArchiver
+	characters          characters.Store
+	prompts             prompts.Store
+	webhooks            internaldiscord.WebhookPool
+	discussions         DiscussionRunner
+	refiner             Refiner
+	aiProvider          AIProvider
+	hooks               hooks.Pipeline
+	links               LinkReader
+	images              ImageInliner
+	contextWindow       ContextWindowManager
+	transcripts         youtube.TranscriptFetcher
+	voiceNotes          VoiceNotes
+	normalizer          ContentNormalizer
+	limiter             RequestLimiter
+	deprecations        *ModelDeprecations
+	pricing             pkgopenai.PricingService
+	deadLetters         *DeadLetters
+	outbox              *Outbox
+	budgets             *Budgets
+	mirror              *AuditMirror
+	permissions         *permissions.Policy // The /chat rule also applies to mentions
+	ignored             moderation.IgnoreList
+	loops               moderation.LoopDetector
+	mutes               moderation.ThreadMutes
 
 	// ongoingRequests stores cancel functions for ongoing OpenAI requests per thread.
 	// key: discord.ChannelID, value: context.CancelFunc
@@ -44,6 +80,49 @@ type Service struct {
 	// threadMutexes ensures sequential processing per thread for cache consistency.
 	// key: discord.ChannelID, value: *sync.Mutex
 	threadMutexes sync.Map
+
+	// pendingTurns records the last user message of each thread that has no
+	// reply yet, so quick follow-ups can be merged into it.
+	// key: discord.ChannelID, value: pendingTurn
+	pendingTurns sync.Map
+
+	// joinedGuilds records whether the bot is a member of a guild, so that
+	// user-installed interactions from other guilds are answered in place.
+	// key: discord.GuildID, value: bool
+	joinedGuilds sync.Map
+
+	// pendingPatches holds the patches the model made for a thread's reply
+	// until the reply is posted.
+	// key: discord.ChannelID, value: []patchFile
+	pendingPatches sync.Map
+
+	// threadSpends tracks the estimated cost of each thread against its
+	// cost ceiling while its conversation is cached.
+	// key: discord.ChannelID, value: *threadSpend
+	threadSpends sync.Map
+
+	// pinnedAnswers indexes the answers pinned in each thread.
+	// key: discord.ChannelID, value: *answerIndex
+	pinnedAnswers sync.Map
+
+	// pendingSuggestions holds the conversation of a thread's reply, with the
+	// reply, until the reply is posted and questions are suggested for it.
+	// key: discord.ChannelID, value: []openai.ChatCompletionMessage
+	pendingSuggestions sync.Map
+
+	// defaultThreadPolicy applies to threads whose initiator did not pick a policy.
+	defaultThreadPolicy ThreadPolicy
+	threadRoleIDs       []discord.RoleID
+
+	// blockedNotices remembers which users were already told they cannot
+	// continue a thread, so repeated messages do not trigger repeated notices.
+	// key: "<threadID>:<userID>"
+	blockedNotices *lru.Cache[string, bool]
+
+	// threadParents remembers the channel each thread was started in, whose
+	// system prompt the thread uses unless it has its own.
+	// key: thread ID, value: parent channel ID, NullChannelID for non-threads
+	threadParents *lru.Cache[discord.ChannelID, discord.ChannelID]
 }
 
 // NewService creates a new refactored chat Service.
@@ -52,23 +131,98 @@ func NewService(
 	cfg *config.Config,
 	ses *session.Session,
 	interactionManager DiscordInteractionManager,
-	aiProvider AIProvider,
 	conversationStore ConversationStore,
 	modelSelector ModelSelector,
 	titleGenerator ThreadTitleGenerator,
+	suggestionGenerator SuggestionGenerator,
 	messageEmbedService MessageEmbedService,
+	archiver *ConversationArchiver,
+	characterStore characters.Store,
+	promptStore prompts.Store,
+	webhookPool internaldiscord.WebhookPool,
+	discussionRunner DiscussionRunner,
+	refiner Refiner,
+	aiProvider AIProvider,
+	hookPipeline hooks.Pipeline,
+	linkReader LinkReader,
+	imageInliner ImageInliner,
+	contextWindow ContextWindowManager,
+	transcriptFetcher youtube.TranscriptFetcher,
+	voiceNotes VoiceNotes,
+	normalizer ContentNormalizer,
+	limiter RequestLimiter,
+	deprecations *ModelDeprecations,
+	pricing pkgopenai.PricingService,
+	deadLetters *DeadLetters,
+	outbox *Outbox,
+	budgets *Budgets,
+	mirror *AuditMirror,
+	permissionPolicy *permissions.Policy,
+	ignored moderation.IgnoreList,
+	loops moderation.LoopDetector,
+	mutes moderation.ThreadMutes,
 ) *Service {
-	return &Service{
+	s := &Service{
 		logger:              logger.Named("chat_service_orchestrator"),
 		cfg:                 cfg,
 		ses:                 ses,
 		interactionManager:  interactionManager,
-		aiProvider:          aiProvider,
 		conversationStore:   conversationStore,
 		modelSelector:       modelSelector,
 		titleGenerator:      titleGenerator,
+		suggestions:         suggestionGenerator,
 		messageEmbedService: messageEmbedService,
+		archiver:            archiver,
+		characters:          characterStore,
+		prompts:             promptStore,
+		webhooks:            webhookPool,
+		discussions:         discussionRunner,
+		refiner:             refiner,
+		aiProvider:          aiProvider,
+		hooks:               hookPipeline,
+		links:               linkReader,
+		images:              imageInliner,
+		contextWindow:       contextWindow,
+		transcripts:         transcriptFetcher,
+		voiceNotes:          voiceNotes,
+		normalizer:          normalizer,
+		limiter:             limiter,
+		deprecations:        deprecations,
+		pricing:             pricing,
+		deadLetters:         deadLetters,
+		outbox:              outbox,
+		budgets:             budgets,
+		mirror:              mirror,
+		permissions:         permissionPolicy,
+		ignored:             ignored,
+		loops:               loops,
+		mutes:               mutes,
+		blockedNotices:      NewNegativeThreadCache(1000),
+		threadParents:       newThreadParentsCache(),
+	}
+
+	policy, err := ParseThreadPolicy(cfg.OpenAI.ThreadPolicy, ThreadPolicyAnyone)
+	if err != nil {
+		s.logger.Warn("Invalid thread policy in config, allowing anyone to continue threads", zap.Error(err))
+		policy = ThreadPolicyAnyone
+	}
+	s.defaultThreadPolicy = policy
+
+	for _, idStr := range cfg.OpenAI.ThreadRoleIDs {
+		sf, err := discord.ParseSnowflake(idStr)
+		if err != nil {
+			s.logger.Warn("Ignoring invalid thread role ID in config", zap.String("roleID", idStr), zap.Error(err))
+
+			continue
+		}
+		s.threadRoleIDs = append(s.threadRoleIDs, discord.RoleID(sf))
 	}
+
+	conversationStore.OnRemove(s.forgetSpend)
+	ses.AddHandler(s.handleGuildCreate)
+	ses.AddHandler(s.handleGuildDelete)
+
+	return s
 }
 
 // getOrCreateThreadMutex returns a mutex for the given thread to ensure sequential processing.
@@ -78,11 +232,20 @@ func (s *Service) getOrCreateThreadMutex(channelID discord.ChannelID) *sync.Mute
 	return mutex.(*sync.Mutex)
 }
 
-// HandleChatInteraction processes a new chat command.
-func (s *Service) HandleChatInteraction(ctx context.Context, e *gateway.InteractionCreateEvent, userPrompt, modelOption string) error {
+// HandleChatInteraction processes a new chat command. policyOption picks who
+// may continue the thread; empty uses the configured default. A non-nil
+// character answers instead of the bot.
+func (s *Service) HandleChatInteraction(
+	ctx context.Context,
+	e *gateway.InteractionCreateEvent,
+	userPrompt, modelOption, policyOption string,
+	character *characters.Character,
+) error {
+	user := e.Sender()
+	ctx = withRequester(ctx, user.ID)
 	s.logger.Info("Chat interaction processing started",
-		zap.String("user", e.Member.User.Username),
-		zap.String("userID", e.Member.User.ID.String()),
+		zap.String("user", user.Username),
+		zap.String("userID", user.ID.String()),
 		zap.String("userPrompt", userPrompt),
 		zap.String("modelOption", modelOption),
 	)
@@ -101,20 +264,23 @@ func (s *Service) HandleChatInteraction(ctx context.Context, e *gateway.Interact
 	}
 	s.logger.Info("Determined model for chat", zap.String("modelToUse", modelToUse))
 
-	userDisplayName := GetUserDisplayName(&e.Member.User)
-	botDisplayName, err := s.getBotDisplayName()
+	if !s.canOpenThread(e.GuildID) {
+		return s.handleInPlaceChat(ctx, e, userPrompt, modelToUse, character)
+	}
+
+	policy, err := ParseThreadPolicy(policyOption, s.defaultThreadPolicy)
 	if err != nil {
-		s.logger.Error("Failed to get bot display name", zap.Error(err))
-		botDisplayName = defaultBotName
+		return err
+	}
+	access := ThreadAccess{Policy: policy, InitiatorID: user.ID}
+	characterName := ""
+	if character != nil {
+		characterName = character.Name
 	}
 
-	summaryMessage := fmt.Sprintf(
-		"Starting new chat session with %s!\n**User:** %s\n**Prompt:** %s\n**Model:** %s\n\nFuture messages in this thread will continue the conversation.",
-		e.Member.User.Username,
-		e.Member.User.Mention(),
-		userPrompt,
-		modelToUse,
-	)
+	// The seed is recorded in the summary so follow-ups and rebuilt threads keep it
+	seed := SeedFrom(ctx)
+	summaryMessage := sessionSummary(user, access, characterName, seed, userPrompt, modelToUse)
 
 	originalMessage, err := s.interactionManager.SendInitialResponse(s.ses, e.ID, e.Token, e.AppID, summaryMessage)
 	if err != nil {
@@ -125,33 +291,83 @@ func (s *Service) HandleChatInteraction(ctx context.Context, e *gateway.Interact
 		zap.String("channelID", originalMessage.ChannelID.String()),
 	)
 
-	threadName := MakeThreadName(e.Member.User.Username, userPrompt, 100)
+	threadName := MakeThreadName(user.Username, userPrompt, 100)
 	newThread, err := s.interactionManager.CreateThreadForInteraction(s.ses, originalMessage, e.AppID, e.Token, threadName, summaryMessage)
 	if err != nil {
 		return err
 	}
+	s.threadParents.Add(newThread.ID, originalMessage.ChannelID)
 	s.logger.Info("Thread created successfully",
 		zap.String("threadID", newThread.ID.String()),
 		zap.String("threadName", newThread.Name),
 	)
 
-	stopTypingIndicator := s.interactionManager.StartTypingIndicator(s.ses, newThread.ID)
+	if err := s.answerInThread(ctx, e.GuildID, e.ChannelID, newThread.ID, user, userPrompt, modelToUse, access, character, seed, nil); err != nil {
+		return err
+	}
+
+	s.logger.Info("Chat interaction processing completed successfully", zap.String("threadID", newThread.ID.String()))
+
+	return nil
+}
+
+// sessionSummary builds the message that opens a chat thread, which threads
+// are rebuilt from when their conversation is not cached.
+func sessionSummary(user *discord.User, access ThreadAccess, characterName string, seed *int, userPrompt, model string) string {
+	return fmt.Sprintf(
+		"Starting new chat session with %s!\n**User:** %s\n%s%s%s**Prompt:** %s\n**Model:** %s\n\nFuture messages in this thread will continue the conversation.",
+		user.Username,
+		user.Mention(),
+		access.summaryLine(),
+		characterSummaryLine(characterName),
+		seedSummaryLine(seed),
+		userPrompt,
+		model,
+	)
+}
+
+// answerInThread answers the prompt that started the conversation in
+// threadID, with the images among attachments, and stores the conversation.
+// channelID is where the prompt was written.
+func (s *Service) answerInThread(
+	ctx context.Context,
+	guildID discord.GuildID,
+	channelID, threadID discord.ChannelID,
+	user *discord.User,
+	userPrompt, model string,
+	access ThreadAccess,
+	character *characters.Character,
+	seed *int,
+	attachments []discord.Attachment,
+) error {
+	characterName := ""
+	if character != nil {
+		characterName = character.Name
+	}
+	userDisplayName := GetUserDisplayName(user)
+	botDisplayName, err := s.getBotDisplayName()
+	if err != nil {
+		s.logger.Error("Failed to get bot display name", zap.Error(err))
+		botDisplayName = defaultBotName
+	}
+
+	stopTypingIndicator := s.interactionManager.StartTypingIndicator(s.ses, threadID)
 	defer stopTypingIndicator()
 
-	// Prepare the OpenAI messages
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: userPrompt,
-			Name:    SanitizeOpenAIName(userDisplayName),
-		},
+	// Prepare the OpenAI messages; the summary keeps the prompt as typed
+	modelPrompt, truncationNotice := s.limitPrompt(guildID, s.normalizer.NormalizeText(channelID, userPrompt))
+	if truncationNotice != "" {
+		if _, err := s.interactionManager.SendMessage(s.ses, threadID, truncationNotice, ""); err != nil {
+			s.logger.Warn("Failed to send truncation notice", zap.Error(err), zap.String("threadID", threadID.String()))
+		}
 	}
+	messages := []openai.ChatCompletionMessage{UserTurn(modelPrompt, SanitizeOpenAIName(userDisplayName), attachments)}
 
-	aiResponse, err := s.aiProvider.GetChatCompletion(ctx, modelToUse, messages)
+	aiResponse, calls, err := s.complete(ctx, guildID, threadID, model, withPersona(character, ForModel(s.cfg.OpenAI.Vision, model, messages)))
 	if err != nil {
-		errMsgToThread := "Sorry, I encountered an error trying to reach the AI. Please try again later."
-		if _, sendErr := s.interactionManager.SendMessage(s.ses, newThread.ID, errMsgToThread); sendErr != nil {
-			s.logger.Error("Failed to send error message to thread after OpenAI failure", zap.Error(sendErr), zap.String("threadID", newThread.ID.String()))
+		errMsgToThread := userErrorMessage(err, "Sorry, I encountered an error trying to reach the AI. Please try again later.")
+		if _, sendErr := s.interactionManager.SendMessage(s.ses, threadID, errMsgToThread, ""); sendErr != nil {
+			s.logger.Error("Failed to send error message to thread after OpenAI failure", zap.Error(sendErr), zap.String("threadID", threadID.String()))
 		}
 
 		return err
@@ -159,36 +375,71 @@ func (s *Service) HandleChatInteraction(ctx context.Context, e *gateway.Interact
 
 	aiMessageContent := aiResponse.Choices[0].Message.Content
 
-	// Send AI response and capture the last message
-	lastMessage, err := s.interactionManager.SendMessage(s.ses, newThread.ID, aiMessageContent)
-	if err != nil {
-		s.logger.Error("Failed to send AI response to thread", zap.Error(err), zap.String("threadID", newThread.ID.String()))
+	if err := s.sendReply(ctx, guildID, threadID, character, aiMessageContent, calls); err != nil {
+		s.logger.Error("Failed to send AI response to thread", zap.Error(err), zap.String("threadID", threadID.String()))
 
 		return fmt.Errorf("failed to send AI response to Discord: %w", err)
 	}
-
-	// Add usage footer to the last message sent
-	if embedErr := s.messageEmbedService.AddUsageFooter(ctx, lastMessage, aiResponse.Usage, modelToUse); embedErr != nil {
-		// Log but don't fail the entire operation
-		s.logger.Warn("Failed to add usage footer", zap.Error(embedErr))
-	}
+	s.mirrorReply(guildID, threadID, user.ID, userPrompt, aiMessageContent, calls)
 
 	// Generate thread title asynchronously after successful AI response
-	titleCtx, titleCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	titleCtx, titleCancel := context.WithTimeout(internalopenai.WithGuild(context.Background(), guildID), 10*time.Second)
 	go func() {
 		defer titleCancel()
-		s.generateAndUpdateThreadTitle(titleCtx, newThread.ID, messages, &aiResponse.Choices[0].Message)
+		s.generateAndUpdateThreadTitle(titleCtx, threadID, messages, &aiResponse.Choices[0].Message)
 	}()
 
-	s.conversationStore.StoreInitialConversation(newThread.ID.String(), userPrompt, aiMessageContent, modelToUse, userDisplayName, botDisplayName, SanitizeOpenAIName)
-
-	s.logger.Info("Chat interaction processing completed successfully", zap.String("threadID", newThread.ID.String()))
+	s.conversationStore.StoreInitialConversation(threadID.String(), modelPrompt, aiMessageContent, model, userDisplayName, assistantName(character, botDisplayName), access, characterName, seed, SanitizeOpenAIName)
+	if len(messages[0].MultiContent) > 0 {
+		// The prompt's images stay in the conversation, as they do for thread messages
+		if data, found := s.conversationStore.GetConversation(threadID.String()); found {
+			history := slices.Clone(data.Messages)
+			history[0] = messages[0]
+			s.conversationStore.UpdateConversationMessages(threadID.String(), history, model)
+		}
+	}
+	s.pinThread(guildID, threadID, model, aiResponse.Model, nil)
 
 	return nil
 }
 
+// Silenced reports whether the bot stays quiet for a message from userID in
+// channelID of guildID: replies in the channel are paused for a loop, the
+// thread was muted with /mute-thread or handed off to a human, or the user
+// is ignored.
+func (s *Service) Silenced(guildID discord.GuildID, channelID discord.ChannelID, userID discord.UserID) bool {
+	return s.silenceNotice(guildID, channelID, userID) != ""
+}
+
+// silenceNotice returns why the bot stays quiet for userID in channelID, to
+// tell a user whose button was not acted on, or an empty string.
+func (s *Service) silenceNotice(guildID discord.GuildID, channelID discord.ChannelID, userID discord.UserID) string {
+	switch {
+	case s.loops != nil && s.loops.Paused(channelID):
+		s.logger.Debug("Ignoring message in channel paused for a loop", zap.String("channelID", channelID.String()))
+
+		return "My replies in this channel are paused."
+	case s.mutes != nil && s.mutes.Muted(channelID):
+		s.logger.Debug("Ignoring message in muted thread", zap.String("channelID", channelID.String()))
+
+		return "My replies in this thread are muted."
+	case s.ignored != nil && s.ignored.Ignored(guildID, userID):
+		// Ignored users are told as on their other interactions
+		s.logger.Debug("Ignoring message from ignored user", zap.String("authorID", userID.String()), zap.String("channelID", channelID.String()))
+
+		return "You cannot use this bot here."
+	}
+
+	return ""
+}
+
 // HandleThreadMessage processes a follow-up message in an existing chat thread.
 func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageCreateEvent) error {
+	// Messages replayed by buttons, such as suggested questions, do not pass
+	// the gateway handler's checks
+	if s.Silenced(evt.GuildID, evt.ChannelID, evt.Author.ID) {
+		return nil
+	}
 	s.logger.Info("Handling thread message",
 		zap.String("threadID", evt.ChannelID.String()),
 		zap.String("authorID", evt.Author.ID.String()),
@@ -212,7 +463,7 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 	defer threadMutex.Unlock()
 
 	// 3. SET UP NEW REQUEST CONTEXT
-	requestCtx, cancel := context.WithCancel(ctx)
+	requestCtx, cancel := context.WithCancel(withRequester(ctx, evt.Author.ID))
 	s.ongoingRequests.Store(evt.ChannelID, cancel)
 
 	defer func() {
@@ -230,6 +481,11 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 	var modelToUse string
 	cachedData, found := s.conversationStore.GetConversation(threadIDStr)
 
+	if !found {
+		// Archived conversations are restored from cold storage before falling back to Discord history
+		cachedData, found = s.archiver.Rehydrate(requestCtx, threadIDStr)
+	}
+
 	if found {
 		s.logger.Debug("Found conversation in primary cache", zap.String("threadID", threadIDStr))
 		modelToUse = cachedData.Model
@@ -266,24 +522,53 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 		cachedData = reconstructedData
 		modelToUse = reconstructedModelName
 	}
+	if replacement, ok := s.deprecations.Replacement(modelToUse); ok {
+		// Stored with the next update, so the thread stays on the replacement
+		s.logger.Info("Migrating thread off deprecated model",
+			zap.String("threadID", threadIDStr),
+			zap.String("model", modelToUse),
+			zap.String("replacement", replacement))
+		modelToUse = replacement
+	}
 
-	// 4. IMMEDIATELY add user message to cache (after reconstruction if needed)
-	authorDisplayName := GetUserDisplayName(&evt.Author)
-	newUserMessage := openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: evt.Content,
-		Name:    SanitizeOpenAIName(authorDisplayName),
+	if !cachedData.Access.Allows(evt.Author.ID, evt.Member, s.threadRoleIDs) {
+		s.logger.Info("Ignoring thread message from user not allowed by thread policy",
+			zap.String("threadID", threadIDStr),
+			zap.String("authorID", evt.Author.ID.String()),
+			zap.String("policy", string(cachedData.Access.Policy)),
+		)
+		s.sendBlockedNotice(evt, cachedData.Access)
+
+		return nil
+	}
+
+	character := s.threadCharacter(evt.GuildID, cachedData.Character)
+	if cachedData.Seed != nil {
+		requestCtx = WithSeed(requestCtx, *cachedData.Seed)
+	}
+	requestCtx = WithModelPin(requestCtx, cachedData.Pin)
+
+	// 4. IMMEDIATELY add user message to cache (after reconstruction if needed).
+	// A resumed turn is already in the conversation.
+	messages := cachedData.Messages
+	resumed := isResumedTurn(ctx)
+	if !resumed {
+		messages = s.cacheThreadMessage(evt, cachedData.Messages, modelToUse)
 	}
 
-	// Copy existing messages and add the new user message
-	messages := append(cachedData.Messages, newUserMessage)
+	// Threads over their cost ceiling keep the message until the initiator
+	// confirms further spending
+	if s.spendingPaused(evt, cachedData.Access.InitiatorID) {
+		return nil
+	}
 
-	// Update cache with user message immediately
-	s.conversationStore.UpdateConversationMessages(threadIDStr, messages, modelToUse)
-	s.logger.Debug("User message added to cache immediately",
-		zap.String("threadID", threadIDStr),
-		zap.String("userMessage", evt.Content),
-		zap.Int("totalMessages", len(messages)))
+	// Give the user a moment to send follow-ups, which cancel this request
+	// and are merged into the same turn
+	if !resumed && !s.waitForFollowUps(requestCtx) {
+		s.logger.Debug("Thread message superseded by a follow-up", zap.String("threadID", threadIDStr))
+
+		return nil
+	}
 
 	// 5. Send to OpenAI (this should return quickly if canceled)
 	stopTyping := s.interactionManager.StartTypingIndicator(s.ses, evt.ChannelID)
@@ -295,12 +580,16 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 		zap.Int("historyLength", len(messages)),
 	)
 
-	aiResponse, err := s.aiProvider.GetChatCompletion(requestCtx, modelToUse, messages)
+	aiResponse, calls, err := s.complete(requestCtx, evt.GuildID, evt.ChannelID, modelToUse, withPersona(character, ForModel(s.cfg.OpenAI.Vision, modelToUse, messages)))
 
 	// Handle cancellation
 	if errors.Is(requestCtx.Err(), context.Canceled) {
 		s.logger.Info("OpenAI request was canceled, user message preserved in cache",
 			zap.String("threadID", threadIDStr))
+		var interrupted *InterruptedError
+		if errors.As(err, &interrupted) {
+			s.keepInterruptedReply(ctx, evt, character, modelToUse, interrupted.Partial)
+		}
 
 		return nil // User message already cached, lock will be released by defer
 	}
@@ -309,8 +598,8 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 	if err != nil {
 		s.logger.Error("OpenAI completion failed for thread message", zap.Error(err))
 		// Send error to Discord but preserve user message in cache
-		errMsg := "Sorry, I encountered an error. Please try again."
-		if _, sendErr := s.interactionManager.SendMessage(s.ses, evt.ChannelID, errMsg); sendErr != nil {
+		errMsg := userErrorMessage(err, "Sorry, I encountered an error. Please try again.")
+		if _, sendErr := s.interactionManager.SendMessage(s.ses, evt.ChannelID, errMsg, ""); sendErr != nil {
 			s.logger.Error("Failed to send error message", zap.Error(sendErr))
 		}
 
@@ -321,7 +610,7 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 	if len(aiResponse.Choices) == 0 || aiResponse.Choices[0].Message.Content == "" {
 		s.logger.Error("OpenAI returned no choices or empty message content", zap.String("threadID", threadIDStr))
 		errMsgToThread := "Sorry, I received an empty response from the AI. Please try again."
-		if _, sendErr := s.interactionManager.SendMessage(s.ses, evt.ChannelID, errMsgToThread); sendErr != nil {
+		if _, sendErr := s.interactionManager.SendMessage(s.ses, evt.ChannelID, errMsgToThread, ""); sendErr != nil {
 			s.logger.Error("Failed to send error message to thread for empty AI response", zap.Error(sendErr), zap.String("threadID", threadIDStr))
 		}
 
@@ -335,19 +624,13 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 		zap.Int("completionTokens", aiResponse.Usage.CompletionTokens),
 	)
 
-	// Send response to Discord and capture the last message
-	lastMessage, err := s.interactionManager.SendMessage(s.ses, evt.ChannelID, aiMessageContent)
-	if err != nil {
+	// Send response to Discord
+	if err := s.sendReply(requestCtx, evt.GuildID, evt.ChannelID, character, aiMessageContent, calls); err != nil {
 		s.logger.Error("Failed to send AI response to thread", zap.Error(err), zap.String("threadID", threadIDStr))
 
 		return fmt.Errorf("failed to send AI response to Discord: %w", err)
 	}
-
-	// Add usage footer to the last message sent
-	if embedErr := s.messageEmbedService.AddUsageFooter(requestCtx, lastMessage, aiResponse.Usage, modelToUse); embedErr != nil {
-		// Log but don't fail the entire operation
-		s.logger.Warn("Failed to add usage footer", zap.Error(embedErr))
-	}
+	s.mirrorReply(evt.GuildID, evt.ChannelID, evt.Author.ID, evt.Content, aiMessageContent, calls)
 
 	// 7. Add AI response to cache (with validation)
 	currentCachedData, found := s.conversationStore.GetConversation(threadIDStr)
@@ -361,17 +644,19 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 	botDisplayName, err := s.getBotDisplayName()
 	if err != nil {
 		s.logger.Error("Failed to get bot display name", zap.Error(err))
-		botDisplayName = "Assistant"
+		botDisplayName = defaultBotName
 	}
 
 	aiMessage := openai.ChatCompletionMessage{
 		Role:    openai.ChatMessageRoleAssistant,
 		Content: aiMessageContent,
-		Name:    SanitizeOpenAIName(botDisplayName),
+		Name:    SanitizeOpenAIName(assistantName(character, botDisplayName)),
 	}
 
 	finalMessages := append(currentCachedData.Messages, aiMessage)
 	s.conversationStore.UpdateConversationMessages(threadIDStr, finalMessages, modelToUse)
+	s.pinThread(evt.GuildID, evt.ChannelID, modelToUse, aiResponse.Model, cachedData.Pin)
+	s.pendingTurns.Delete(evt.ChannelID)
 	s.logger.Info("AI response added to cache",
 		zap.String("threadID", threadIDStr),
 		zap.Int("finalMessageCount", len(finalMessages)))
@@ -379,6 +664,202 @@ func (s *Service) HandleThreadMessage(ctx context.Context, evt *gateway.MessageC
 	return nil
 }
 
+// cacheThreadMessage adds the message of evt to messages, merged into the
+// author's previous message if that is still waiting for a reply, and stores
+// the result right away.
+func (s *Service) cacheThreadMessage(evt *gateway.MessageCreateEvent, messages []openai.ChatCompletionMessage, model string) []openai.ChatCompletionMessage {
+	threadIDStr := evt.ChannelID.String()
+	authorDisplayName := GetUserDisplayName(&evt.Author)
+	content, truncationNotice := s.limitPrompt(evt.GuildID, s.normalizer.Normalize(&evt.Message))
+	newUserMessage := UserTurn(content, SanitizeOpenAIName(authorDisplayName), evt.Attachments)
+	if truncationNotice != "" {
+		s.replyToMessage(evt, truncationNotice)
+	}
+
+	messages, merged := s.addUserTurn(evt.ChannelID, evt.Author.ID, messages, newUserMessage)
+
+	s.conversationStore.UpdateConversationMessages(threadIDStr, messages, model)
+	s.conversationStore.AddParticipant(threadIDStr, evt.Author.ID)
+	s.logger.Debug("User message added to cache immediately",
+		zap.String("threadID", threadIDStr),
+		zap.String("userMessage", evt.Content),
+		zap.Bool("merged", merged),
+		zap.Int("totalMessages", len(messages)))
+
+	return messages
+}
+
+// interruptedMarker ends replies cut short by a newer message.
+const interruptedMarker = "*(interrupted)*"
+
+// keepInterruptedReply posts the part of a streamed reply received before a
+// newer message canceled it, and caches it so the next reply knows what was
+// already said. It runs while the thread lock is still held, so the newer
+// message sees the partial reply in the history.
+func (s *Service) keepInterruptedReply(ctx context.Context, evt *gateway.MessageCreateEvent, character *characters.Character, model, partial string) {
+	threadIDStr := evt.ChannelID.String()
+	partial = strings.TrimSpace(s.hooks.AfterResponse(evt.GuildID, partial))
+	if partial == "" {
+		return
+	}
+	content := partial + "\n\n" + interruptedMarker
+
+	if err := s.sendReply(ctx, evt.GuildID, evt.ChannelID, character, content, nil); err != nil {
+		s.logger.Warn("Failed to post interrupted reply", zap.Error(err), zap.String("threadID", threadIDStr))
+	}
+
+	current, found := s.conversationStore.GetConversation(threadIDStr)
+	if !found {
+		return
+	}
+	botDisplayName, err := s.getBotDisplayName()
+	if err != nil {
+		botDisplayName = defaultBotName
+	}
+	messages := append(current.Messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleAssistant,
+		Content: content,
+		Name:    SanitizeOpenAIName(assistantName(character, botDisplayName)),
+	})
+	s.conversationStore.UpdateConversationMessages(threadIDStr, messages, model)
+	s.pendingTurns.Delete(evt.ChannelID)
+	s.logger.Info("Interrupted reply kept",
+		zap.String("threadID", threadIDStr),
+		zap.Int("partialLength", len(partial)))
+}
+
+// complete runs the prompt hooks, reads linked pages and downloads images
+// into the request, requests a reply and runs the response hooks on it. The reply returned is
+// the one to post and cache, marked if it reached the reply token limit. The request waits for a slot from the limiter
+// first; while it waits, a queue notice is shown in threadID if it is valid.
+func (s *Service) complete(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, []CallUsage, error) {
+	ctx = internalopenai.WithGuild(ctx, guildID)
+	if err := s.budgets.Check(guildID, requesterFrom(ctx)); err != nil {
+		return nil, nil, err
+	}
+	conversation := messages
+	messages = withSystemPrompt(s.systemPrompt(ctx, guildID, threadID), messages)
+	ctx = withPinnedAnswers(ctx, s.pinnedAnswerTexts(guildID, threadID))
+	request, fitCalls, err := s.contextWindow.Fit(ctx, guildID, threadID, model, s.images.Inline(ctx, s.links.Enrich(ctx, s.hooks.BeforeRequest(guildID, messages))))
+	s.recordSpend(guildID, threadID, fitCalls)
+	s.recordBudgetUsage(ctx, guildID, fitCalls)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, patches := withPatchSession(ctx, request)
+
+	onQueued, dequeued := s.queueNotice(threadID)
+	release, err := s.limiter.Acquire(ctx, guildID, onQueued)
+	dequeued()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, calls, err := s.refiner.Complete(ctx, guildID, model, request)
+	release(err)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.recordSpend(guildID, threadID, calls)
+	s.recordBudgetUsage(ctx, guildID, calls)
+	calls = append(fitCalls, calls...)
+	if files := patches.files(); len(files) > 0 && threadID.IsValid() {
+		s.pendingPatches.Store(threadID, files)
+	} else {
+		s.pendingPatches.Delete(threadID)
+	}
+	if len(resp.Choices) > 0 {
+		resp.Choices[0].Message.Content = AddCodeLanguageHints(s.hooks.AfterResponse(guildID, resp.Choices[0].Message.Content))
+		if resp.Choices[0].FinishReason == openai.FinishReasonLength && resp.Choices[0].Message.Content != "" {
+			resp.Choices[0].Message.Content += "\n\n" + cutOffMarker
+		}
+	}
+	if s.cfg.OpenAI.Suggestions.Enabled && threadID.IsValid() && len(resp.Choices) > 0 {
+		s.pendingSuggestions.Store(threadID, append(slices.Clone(conversation), resp.Choices[0].Message))
+	} else {
+		s.pendingSuggestions.Delete(threadID)
+	}
+
+	return resp, calls, nil
+}
+
+// sendReply posts an AI reply to a thread with a footer showing the usage of
+// the calls made for it, and the guild's disclosure line on every part. With a
+// character the reply is posted under its identity, falling back to the bot's
+// own identity if the webhook cannot be used. The reply is kept in the
+// outbox until Discord accepts it, and queued as a dead letter if Discord
+// permanently refuses it.
+func (s *Service) sendReply(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, character *characters.Character, content string, calls []CallUsage) error {
+	outboxID := s.recordReply(ctx, guildID, threadID, character, content)
+	err := s.postReply(ctx, guildID, threadID, character, content, calls)
+	switch {
+	case err == nil:
+		s.markDelivered(outboxID)
+	case IsPermanentDeliveryError(err):
+		s.deadLetter(ctx, guildID, threadID, character, content, err)
+		s.markDelivered(outboxID)
+	}
+
+	return err
+}
+
+// postReply posts a reply as described for sendReply, without queueing it
+// when it fails.
+func (s *Service) postReply(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, character *characters.Character, content string, calls []CallUsage) error {
+	disclosure := s.disclosure(guildID)
+	if character != nil {
+		footer := s.messageEmbedService.TurnUsageEmbed(calls)
+		_, err := s.sendPersonaMessage(threadID, character, content, &footer, disclosure)
+		if err == nil {
+			s.sendPatches(threadID)
+
+			return nil
+		}
+		s.logger.Warn("Failed to reply as character, replying as the bot",
+			zap.Error(err),
+			zap.String("threadID", threadID.String()),
+			zap.String("character", character.Name))
+	}
+
+	lastMessage, err := s.interactionManager.SendMessage(s.ses, threadID, content, disclosure)
+	if err != nil {
+		return err
+	}
+
+	// Add usage footer to the last message sent
+	if embedErr := s.messageEmbedService.AddTurnUsageFooter(ctx, lastMessage, calls); embedErr != nil {
+		// Log but don't fail the entire operation
+		s.logger.Warn("Failed to add usage footer", zap.Error(embedErr))
+	}
+	s.addReplyButtons(ctx, guildID, threadID, lastMessage)
+	s.sendPatches(threadID)
+
+	return nil
+}
+
+// disclosure returns the footer marking replies in guildID as AI-generated, if configured.
+func (s *Service) disclosure(guildID discord.GuildID) string {
+	return DisclosureFooter(s.cfg.DisclosureText(guildID.String()))
+}
+
+// sendBlockedNotice replies once per user and thread to explain why their message was ignored.
+func (s *Service) sendBlockedNotice(evt *gateway.MessageCreateEvent, access ThreadAccess) {
+	key := evt.ChannelID.String() + ":" + evt.Author.ID.String()
+	if s.blockedNotices.Contains(key) {
+		return
+	}
+	s.blockedNotices.Add(key, true)
+
+	_, err := s.ses.SendMessageComplex(evt.ChannelID, api.SendMessageData{
+		Content:   access.blockedNotice(evt.Author.ID),
+		Reference: &discord.MessageReference{MessageID: evt.ID},
+		// Only ping the blocked user, not the initiator
+		AllowedMentions: &api.AllowedMentions{Users: []discord.UserID{evt.Author.ID}},
+	})
+	if err != nil {
+		s.logger.Warn("Failed to send thread policy notice", zap.Error(err), zap.String("threadID", evt.ChannelID.String()))
+	}
+}
+
 // generateAndUpdateThreadTitle generates a title for the thread based on the conversation
 // and updates the Discord thread name asynchronously.
 func (s *Service) generateAndUpdateThreadTitle(ctx context.Context, threadID discord.ChannelID, userMessages []openai.ChatCompletionMessage, aiResponse *openai.ChatCompletionMessage) {
@@ -0,0 +1,267 @@
+package chat_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/httputil/httpdriver"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/hooks"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+	"github.com/Raikerian/go-discord-chatgpt/internal/permissions"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// testModel is priced in models.json at $2 per million input tokens and $8
+// per million output tokens, so each fakeAI reply costs $0.01.
+const testModel = "gpt-4.1"
+
+// fakeDiscord answers the Discord API requests of a session, recording them.
+type fakeDiscord struct {
+	mu       sync.Mutex
+	requests []fakeRequest
+	nextID   int
+	history  map[discord.ChannelID][]discord.Message // Newest first, served to message history requests
+}
+
+type fakeRequest struct {
+	Method string
+	Path   string
+	Body   map[string]any
+	ID     int // ID of the message or thread created in response
+}
+
+// content returns the content posted by the request.
+func (r fakeRequest) content() string {
+	if data, ok := r.Body["data"].(map[string]any); ok {
+		content, _ := data["content"].(string)
+
+		return content
+	}
+	content, _ := r.Body["content"].(string)
+
+	return content
+}
+
+func (f *fakeDiscord) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/api/v"+api.Version)
+	request := fakeRequest{Method: req.Method, Path: path}
+	if req.Body != nil {
+		payload, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(payload, &request.Body)
+	}
+
+	f.mu.Lock()
+	f.nextID++
+	id := f.nextID
+	request.ID = 1000 + id
+	f.requests = append(f.requests, request)
+	f.mu.Unlock()
+
+	status, body := http.StatusOK, "{}"
+	switch parts := strings.Split(strings.Trim(path, "/"), "/"); {
+	case path == "/users/@me":
+		body = `{"id":"100","username":"bot","bot":true}`
+	case req.Method == http.MethodGet && parts[0] == "channels" && len(parts) == 3 && parts[2] == "messages":
+		body = f.messagesBefore(parts[1], req.URL.Query().Get("before"))
+	case parts[0] == "channels" && len(parts) >= 3 && parts[2] == "messages":
+		content, _ := json.Marshal(request.content())
+		body = fmt.Sprintf(`{"id":"%d","channel_id":%q,"content":%s}`, request.ID, parts[1], content)
+	case parts[0] == "webhooks" && strings.HasSuffix(path, "/messages/@original"):
+		body = fmt.Sprintf(`{"id":"%d","channel_id":"10","content":""}`, request.ID)
+	case strings.HasSuffix(path, "/callback") || strings.HasSuffix(path, "/typing"):
+		status, body = http.StatusNoContent, ""
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// messagesBefore returns the history of channelID older than before, as JSON.
+func (f *fakeDiscord) messagesBefore(channelID, before string) string {
+	id, _ := discord.ParseSnowflake(channelID)
+	beforeID, _ := discord.ParseSnowflake(before)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	msgs := []discord.Message{}
+	for _, msg := range f.history[discord.ChannelID(id)] {
+		if !beforeID.IsValid() || msg.ID < discord.MessageID(beforeID) {
+			msgs = append(msgs, msg)
+		}
+	}
+	body, _ := json.Marshal(msgs)
+
+	return string(body)
+}
+
+// posted returns the requests creating or editing messages and responding to
+// interactions, in order.
+func (f *fakeDiscord) posted() []fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var posted []fakeRequest
+	for _, request := range f.requests {
+		if request.Method != http.MethodGet && !strings.HasSuffix(request.Path, "/typing") {
+			posted = append(posted, request)
+		}
+	}
+
+	return posted
+}
+
+// fakeAI replies to every request with the same usage.
+type fakeAI struct {
+	mu        sync.Mutex
+	requests  [][]openai.ChatCompletionMessage
+	noChoices bool // Reply without choices, as a filtered completion
+}
+
+func (f *fakeAI) GetChatCompletion(_ context.Context, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, messages)
+	if f.noChoices {
+		return &openai.ChatCompletionResponse{Model: model}, nil
+	}
+
+	return &openai.ChatCompletionResponse{
+		Model: model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: fmt.Sprintf("reply %d", len(f.requests))},
+			FinishReason: openai.FinishReasonStop,
+		}},
+		Usage: openai.Usage{PromptTokens: 1000, CompletionTokens: 1000, TotalTokens: 2000},
+	}, nil
+}
+
+// GenerateTitle names every thread the same.
+func (f *fakeAI) GenerateTitle(context.Context, []openai.ChatCompletionMessage) (string, error) {
+	return "Thread title", nil
+}
+
+// asked returns the last user message of each request.
+func (f *fakeAI) asked() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var asked []string
+	for _, messages := range f.requests {
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == openai.ChatMessageRoleUser {
+				asked = append(asked, messages[i].Content)
+
+				break
+			}
+		}
+	}
+
+	return asked
+}
+
+// requestText joins the contents of messages.
+func requestText(messages []openai.ChatCompletionMessage) string {
+	var text strings.Builder
+	for _, message := range messages {
+		text.WriteString(message.Content)
+		text.WriteString("\n")
+	}
+
+	return text.String()
+}
+
+// fakeSession returns a session sending its API requests to fake.
+func fakeSession(fake *fakeDiscord) *session.Session {
+	ses := session.New("Bot test")
+	ses.Client.Client.Client = httpdriver.WrapClient(http.Client{Transport: fake})
+
+	return ses
+}
+
+// testService is a chat Service talking to fake Discord and OpenAI APIs,
+// archiving conversations in memory.
+type testService struct {
+	*chat.Service
+	store    chat.ConversationStore
+	archiver *chat.ConversationArchiver
+	mutes    moderation.ThreadMutes
+	discord  *fakeDiscord
+	ai       *fakeAI
+}
+
+func newTestService(t *testing.T, cfg *config.Config, suggestions chat.SuggestionGenerator) *testService {
+	t.Helper()
+	logger := zap.NewNop()
+	fake := &fakeDiscord{}
+	ses := fakeSession(fake)
+	ai := &fakeAI{}
+	pricing := pkgopenai.NewPricingService("../../models.json")
+	hookPipeline, err := hooks.NewPipeline(logger, nil)
+	require.NoError(t, err)
+	policy, err := permissions.NewPolicy(cfg)
+	require.NoError(t, err)
+	state := storage.NewMemoryProvider()
+	ignored, err := moderation.NewIgnoreListProvider(cfg, state)
+	require.NoError(t, err)
+	mutes, err := moderation.NewThreadMutesProvider(cfg, state)
+	require.NoError(t, err)
+
+	store := chat.NewConversationStore(logger, 10, 10, chat.NewSummaryParser(logger), chat.NewContentNormalizer(ses), nil)
+	archiver := chat.NewConversationArchiver(logger, cfg, ses, store, storage.NewMemoryProvider(), nil)
+	service := chat.NewService(
+		logger, cfg, ses,
+		chat.NewDiscordInteractionManager(logger, nil),
+		store,
+		chat.NewModelSelector(logger, config.NewStaticProvider(cfg)),
+		ai,
+		suggestions,
+		chat.NewDiscordEmbedService(ses, chat.NewOpenAIUsageFormatter(pricing), logger),
+		archiver,
+		nil, nil, nil, nil,
+		chat.NewRefiner(logger, cfg, ai, nil),
+		ai,
+		hookPipeline,
+		chat.NewLinkReader(logger, cfg, nil),
+		chat.NewImageInliner(logger, cfg),
+		chat.NewContextWindowManager(logger, cfg, pricing, ai),
+		nil,
+		chat.NewVoiceNotes(logger, cfg, nil),
+		chat.NewContentNormalizer(ses),
+		chat.NewRequestLimiter(logger, cfg),
+		nil,
+		pricing,
+		nil, nil, nil, nil, policy,
+		ignored, moderation.NewLoopDetector(cfg), mutes,
+	)
+
+	return &testService{Service: service, store: store, archiver: archiver, mutes: mutes, discord: fake, ai: ai}
+}
+
+// startThread caches a conversation in threadID as if initiatorID had
+// started it with /chat.
+func (ts *testService) startThread(threadID discord.ChannelID, initiatorID discord.UserID, policy chat.ThreadPolicy) {
+	ts.store.StoreInitialConversation(threadID.String(), "hello", "hi there", testModel, "alice", "bot",
+		chat.ThreadAccess{Policy: policy, InitiatorID: initiatorID}, "", nil, chat.SanitizeOpenAIName)
+}
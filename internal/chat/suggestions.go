@@ -0,0 +1,373 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// StepSuggestions is the call suggesting follow-up questions for a reply.
+const StepSuggestions = "suggestions"
+
+// SuggestionComponentPrefix starts the custom IDs of the suggested question
+// buttons on AI replies, which end with the button's position. They are
+// routed to /chat, which hands them to HandleSuggestion.
+const SuggestionComponentPrefix = "chat:suggestion:"
+
+const (
+	// maxSuggestions is the number of questions suggested per reply when
+	// suggestions.count is unset, and the most it may be.
+	maxSuggestions = 3
+	// maxSuggestionChars is the longest question suggested, the length of a
+	// button label.
+	maxSuggestionChars = 80
+	// suggestionContextTokens bounds the conversation sent to suggest questions.
+	suggestionContextTokens = 4000
+	// suggestionTimeout bounds the request suggesting questions for a reply.
+	suggestionTimeout = 15 * time.Second
+	// suggestionPrefix starts the messages asking a suggested question on
+	// behalf of the member who clicked it.
+	suggestionPrefix = "💬 "
+)
+
+// suggestionSchema is the structured output of the request suggesting questions.
+var suggestionSchema = &jsonschema.Definition{
+	Type: jsonschema.Object,
+	Properties: map[string]jsonschema.Definition{
+		"questions": {
+			Type:        jsonschema.Array,
+			Description: "The follow-up questions, most useful first",
+			Items:       &jsonschema.Definition{Type: jsonschema.String},
+		},
+	},
+	Required:             []string{"questions"},
+	AdditionalProperties: false,
+}
+
+// SuggestionGenerator suggests follow-up questions the user may ask after an
+// AI reply, returning the usage of the call.
+type SuggestionGenerator interface {
+	Suggest(ctx context.Context, messages []openai.ChatCompletionMessage, count int) ([]string, CallUsage, error)
+}
+
+// OpenAISuggestionGenerator implements SuggestionGenerator with structured
+// outputs, sent through the AIProvider of chat replies so that they are
+// retried and metered like them.
+type OpenAISuggestionGenerator struct {
+	aiProvider AIProvider
+	cfg        *config.Config
+	logger     *zap.Logger
+}
+
+// NewOpenAISuggestionGenerator creates a new OpenAI-based suggestion generator.
+func NewOpenAISuggestionGenerator(aiProvider AIProvider, cfg *config.Config, logger *zap.Logger) SuggestionGenerator {
+	return &OpenAISuggestionGenerator{
+		aiProvider: aiProvider,
+		cfg:        cfg,
+		logger:     logger.Named("suggestion_generator"),
+	}
+}
+
+// Suggest suggests up to count follow-up questions for the conversation in
+// messages, whose last message is the reply they follow.
+func (g *OpenAISuggestionGenerator) Suggest(ctx context.Context, messages []openai.ChatCompletionMessage, count int) ([]string, CallUsage, error) {
+	model := g.cfg.OpenAI.Suggestions.Model
+	if model == "" {
+		model = openai.GPT4Dot1Nano
+	}
+	systemMsg := openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleSystem,
+		Content: fmt.Sprintf("Suggest up to %d short follow-up questions the user could ask next in this conversation. "+
+			"Write them as the user would ask them, in the language of the conversation. "+
+			"Each must be under %d characters, make sense on its own and not be answered already. "+
+			"Suggest none if the conversation needs no follow-up. "+
+			`Answer with a JSON object such as {"questions": ["..."]}.`, count, maxSuggestionChars),
+	}
+	fitted, _ := FitContext(model, messages, suggestionContextTokens)
+	chatMessages := make([]openai.ChatCompletionMessage, 0, len(fitted)+1)
+	chatMessages = append(chatMessages, systemMsg)
+	chatMessages = append(chatMessages, fitted...)
+
+	resp, err := completeStructured(ctx, g.aiProvider, model, chatMessages, &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   "follow_up_questions",
+			Schema: suggestionSchema,
+			Strict: true,
+		},
+	})
+	if err != nil {
+		return nil, CallUsage{}, err
+	}
+	call := CallUsage{Step: StepSuggestions, Model: model, Usage: resp.Usage}
+	if len(resp.Choices) == 0 {
+		g.logger.Warn("OpenAI returned no choices for follow-up suggestions")
+
+		return nil, call, nil
+	}
+
+	questions, err := ParseSuggestions(resp.Choices[0].Message.Content, count)
+	if err != nil {
+		return nil, call, err
+	}
+	g.logger.Debug("Suggested follow-up questions", zap.Strings("questions", questions))
+
+	return questions, call, nil
+}
+
+// ParseSuggestions returns up to count questions from the structured output
+// of a suggestion request, leaving out blank, repeated and overlong ones.
+func ParseSuggestions(content string, count int) ([]string, error) {
+	var output struct {
+		Questions []string `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse follow-up suggestions: %w", err)
+	}
+
+	var questions []string
+	seen := make(map[string]bool)
+	for _, question := range output.Questions {
+		question = strings.Join(strings.Fields(question), " ")
+		key := strings.ToLower(question)
+		if question == "" || utf8.RuneCountInString(question) > maxSuggestionChars || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if questions = append(questions, question); len(questions) == count {
+			break
+		}
+	}
+
+	return questions, nil
+}
+
+// suggestionCount returns the number of questions to suggest per reply.
+func (s *Service) suggestionCount() int {
+	if count := s.cfg.OpenAI.Suggestions.Count; count > 0 && count < maxSuggestions {
+		return count
+	}
+
+	return maxSuggestions
+}
+
+// addReplyButtons attaches the pin button and suggested questions to an AI
+// reply posted as msg in threadID, as configured. The pin button is added
+// right away, the questions once they are suggested in the background.
+func (s *Service) addReplyButtons(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, msg *discord.Message) {
+	pending, _ := s.pendingSuggestions.LoadAndDelete(threadID)
+	if msg == nil {
+		return
+	}
+	s.setReplyButtons(guildID, msg, nil)
+
+	conversation, _ := pending.([]openai.ChatCompletionMessage)
+	if len(conversation) == 0 {
+		return
+	}
+	// The reply's request is over, but its guild and requester still apply
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), suggestionTimeout)
+	go func() {
+		defer cancel()
+		questions, err := s.suggest(ctx, guildID, threadID, conversation)
+		if err != nil {
+			s.logger.Warn("Failed to suggest follow-up questions", zap.Error(err), zap.String("threadID", threadID.String()))
+
+			return
+		}
+		if len(questions) > 0 {
+			s.setReplyButtons(guildID, msg, questions)
+		}
+	}()
+}
+
+// suggest asks for follow-up questions to the reply ending conversation in
+// threadID. The request waits for the limiter and counts towards budgets and
+// the thread's cost ceiling like replies, and is skipped once either has
+// been reached.
+func (s *Service) suggest(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, conversation []openai.ChatCompletionMessage) ([]string, error) {
+	if s.budgets.Check(guildID, requesterFrom(ctx)) != nil || s.costCeilingReached(guildID, threadID) {
+		return nil, nil
+	}
+
+	release, err := s.limiter.Acquire(ctx, guildID, nil)
+	if err != nil {
+		return nil, err
+	}
+	questions, call, err := s.suggestions.Suggest(ctx, conversation, s.suggestionCount())
+	release(err)
+	if call.Model != "" {
+		s.recordSpend(guildID, threadID, []CallUsage{call})
+		s.recordBudgetUsage(ctx, guildID, []CallUsage{call})
+	}
+
+	return questions, err
+}
+
+// setReplyButtons replaces the buttons of an AI reply with the pin button, if
+// pinned answers are enabled, and buttons asking questions.
+func (s *Service) setReplyButtons(guildID discord.GuildID, msg *discord.Message, questions []string) {
+	pinnable := s.cfg.OpenAI.PinnedAnswers.Enabled
+	pinned := false
+	if pinnable {
+		index := s.answerIndex(guildID, msg.ChannelID)
+		index.mu.Lock()
+		pinned = index.has(msg.ID)
+		index.mu.Unlock()
+	}
+	components := replyComponents(pinnable, pinned, questions)
+	if len(components) == 0 {
+		return
+	}
+	if _, err := s.ses.EditMessageComplex(msg.ChannelID, msg.ID, api.EditMessageData{Components: &components}); err != nil {
+		s.logger.Warn("Failed to add buttons to reply", zap.Error(err), zap.String("messageID", msg.ID.String()))
+	}
+}
+
+// HandleSuggestion handles the suggested question buttons on AI replies. The
+// question is posted in the thread on behalf of the member who clicked it and
+// answered as their next message, and the reply's questions are removed. Only
+// members who may continue the thread can ask its suggested questions, which
+// is checked before anything is posted.
+func (s *Service) HandleSuggestion(ctx context.Context, e *gateway.InteractionCreateEvent, id discord.ComponentID) error {
+	msg := e.Message
+	if msg == nil {
+		return errors.New("suggestion button interaction has no message")
+	}
+	threadID := e.ChannelID
+	sender := e.Sender()
+	if sender == nil {
+		return errors.New("suggestion button interaction has no user")
+	}
+
+	pinned, question := false, ""
+	for _, button := range replyButtons(msg) {
+		switch button.CustomID {
+		case PinAnswerComponentID:
+			pinned = button.Disabled
+		case id:
+			question = button.Label
+		}
+	}
+	if question == "" || s.conversationStore.IsInNegativeCache(threadID.String()) {
+		return s.respondComponent(e, api.MessageInteractionWithSource, "This question is no longer available.", true)
+	}
+	// Loaded as HandleThreadMessage would, so that the check holds after a
+	// restart or once the conversation left the cache
+	data, err := s.loadConversation(ctx, threadID)
+	if err != nil {
+		s.logger.Warn("Failed to load conversation for suggested question", zap.Error(err), zap.String("threadID", threadID.String()))
+
+		return s.respondComponent(e, api.MessageInteractionWithSource, "This question is no longer available.", true)
+	}
+	if !data.Access.Allows(sender.ID, e.Member, s.threadRoleIDs) {
+		return s.respondComponent(e, api.MessageInteractionWithSource, "Only people who can continue this conversation can ask its suggested questions.", true)
+	}
+	if notice := s.silenceNotice(e.GuildID, threadID, sender.ID); notice != "" {
+		return s.respondComponent(e, api.MessageInteractionWithSource, notice, true)
+	}
+
+	err = s.ses.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(suggestionPrefix + question),
+			AllowedMentions: &api.AllowedMentions{},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to respond to suggestion button: %w", err)
+	}
+	posted, err := s.ses.InteractionResponse(e.AppID, e.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get suggested question message: %w", err)
+	}
+
+	// Each reply's questions can be asked once
+	components := replyComponents(s.cfg.OpenAI.PinnedAnswers.Enabled, pinned, nil)
+	if components == nil {
+		components = discord.ContainerComponents{}
+	}
+	if _, err := s.ses.EditMessageComplex(msg.ChannelID, msg.ID, api.EditMessageData{Components: &components}); err != nil {
+		s.logger.Warn("Failed to remove suggested questions from reply", zap.Error(err), zap.String("messageID", msg.ID.String()))
+	}
+
+	s.logger.Info("Suggested question asked",
+		zap.String("threadID", threadID.String()),
+		zap.String("messageID", msg.ID.String()),
+		zap.String("userID", sender.ID.String()))
+
+	return s.HandleThreadMessage(ctx, &gateway.MessageCreateEvent{
+		Message: discord.Message{
+			ID:        posted.ID,
+			ChannelID: threadID,
+			GuildID:   e.GuildID,
+			Author:    *sender,
+			Content:   question,
+			Timestamp: posted.Timestamp,
+		},
+		Member: e.Member,
+	})
+}
+
+// replyComponents returns the buttons of an AI reply: a row per suggested
+// question, then the pin button if the reply can be pinned.
+func replyComponents(pinnable, pinned bool, questions []string) discord.ContainerComponents {
+	var components discord.ContainerComponents
+	for i, question := range questions {
+		components = append(components, &discord.ActionRowComponent{&discord.ButtonComponent{
+			Label:    question,
+			CustomID: discord.ComponentID(SuggestionComponentPrefix + strconv.Itoa(i)),
+			Style:    discord.SecondaryButtonStyle(),
+		}})
+	}
+	if pinnable {
+		components = append(components, &discord.ActionRowComponent{pinAnswerButton(pinned)})
+	}
+
+	return components
+}
+
+// replyButtons returns the buttons on msg.
+func replyButtons(msg *discord.Message) []*discord.ButtonComponent {
+	var buttons []*discord.ButtonComponent
+	for _, component := range msg.Components {
+		row, ok := component.(*discord.ActionRowComponent)
+		if !ok {
+			continue
+		}
+		for _, interactive := range *row {
+			if button, ok := interactive.(*discord.ButtonComponent); ok {
+				buttons = append(buttons, button)
+			}
+		}
+	}
+
+	return buttons
+}
+
+// suggestedQuestions returns the questions suggested on msg.
+func suggestedQuestions(msg *discord.Message) []string {
+	var questions []string
+	for _, button := range replyButtons(msg) {
+		if strings.HasPrefix(string(button.CustomID), SuggestionComponentPrefix) {
+			questions = append(questions, button.Label)
+		}
+	}
+
+	return questions
+}
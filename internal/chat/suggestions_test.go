@@ -0,0 +1,141 @@
+package chat_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func TestParseSuggestions(t *testing.T) {
+	long := strings.Repeat("why ", 25) + "?"
+	questions, err := chat.ParseSuggestions(`{"questions": ["How do  I test it?", "", "how do I test it?", "`+long+`", "What about errors?", "And channels?"]}`, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"How do I test it?", "What about errors?", "And channels?"}, questions, "blank, repeated and overlong questions are left out")
+
+	questions, err = chat.ParseSuggestions(`{"questions": ["One?", "Two?", "Three?"]}`, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"One?", "Two?"}, questions)
+
+	_, err = chat.ParseSuggestions("not json", 3)
+	assert.Error(t, err)
+}
+
+// fakeSuggestions suggests the same questions for every reply, at the cost
+// of a fakeAI reply.
+type fakeSuggestions struct {
+	questions []string
+	calls     atomic.Int32
+}
+
+func (f *fakeSuggestions) Suggest(_ context.Context, _ []openai.ChatCompletionMessage, count int) ([]string, chat.CallUsage, error) {
+	f.calls.Add(1)
+
+	return f.questions[:count], chat.CallUsage{
+		Step:  chat.StepSuggestions,
+		Model: testModel,
+		Usage: openai.Usage{PromptTokens: 1000, CompletionTokens: 1000, TotalTokens: 2000},
+	}, nil
+}
+
+// newSuggestionsService returns a testService suggesting questions in a
+// thread started by initiatorID, after answering its first message.
+func newSuggestionsService(t *testing.T, cfg *config.Config, suggestions chat.SuggestionGenerator, threadID discord.ChannelID, initiatorID discord.UserID) *testService {
+	t.Helper()
+	cfg.OpenAI.Suggestions = config.SuggestionsConfig{Enabled: true, Count: 2}
+	ts := newTestService(t, cfg, suggestions)
+	ts.startThread(threadID, initiatorID, chat.ThreadPolicyInitiator)
+
+	require.NoError(t, ts.HandleThreadMessage(t.Context(), threadMessage(threadID, initiatorID, 1, "first")))
+	require.Eventually(t, func() bool {
+		for _, request := range ts.discord.posted() {
+			if request.Method == http.MethodPatch && strings.Contains(fmt.Sprint(request.Body["components"]), "What next?") {
+				return true
+			}
+		}
+
+		return false
+	}, time.Second, 10*time.Millisecond, "the reply gets its questions")
+
+	return ts
+}
+
+func TestSuggestions_CostCeiling(t *testing.T) {
+	suggestions := &fakeSuggestions{questions: []string{"What next?", "Why?"}}
+	ts := newSuggestionsService(t, costCeilingConfig(0.02), suggestions, 10, 5)
+
+	// The reply and its suggestions cost $0.01 each, reaching the ceiling
+	require.NoError(t, ts.HandleThreadMessage(t.Context(), threadMessage(10, 5, 2, "second")))
+	assert.Equal(t, []string{"first"}, ts.ai.asked())
+	assert.Equal(t, int32(1), suggestions.calls.Load())
+}
+
+func TestHandleSuggestion(t *testing.T) {
+	const threadID, initiatorID, otherID = discord.ChannelID(10), discord.UserID(5), discord.UserID(6)
+	ts := newSuggestionsService(t, &config.Config{}, &fakeSuggestions{questions: []string{"What next?", "Why?"}}, threadID, initiatorID)
+	ctx := t.Context()
+
+	reply := &discord.Message{ID: 99, ChannelID: threadID, Components: discord.ContainerComponents{
+		&discord.ActionRowComponent{&discord.ButtonComponent{Label: "What next?", CustomID: chat.SuggestionComponentPrefix + "0"}},
+		&discord.ActionRowComponent{&discord.ButtonComponent{Label: "Why?", CustomID: chat.SuggestionComponentPrefix + "1"}},
+	}}
+	click := func(userID discord.UserID) *gateway.InteractionCreateEvent {
+		e := continueSpending(threadID, userID)
+		e.Message = reply
+		e.Data = &discord.ButtonInteraction{CustomID: chat.SuggestionComponentPrefix + "0"}
+
+		return e
+	}
+
+	// Access is checked on the rebuilt conversation before anything is posted
+	require.NoError(t, ts.archiver.Archive(ctx, threadID.String()))
+	require.NoError(t, ts.HandleSuggestion(ctx, click(otherID), chat.SuggestionComponentPrefix+"0"))
+	refusal := ts.discord.posted()[len(ts.discord.posted())-1]
+	assert.Contains(t, refusal.content(), "Only people who can continue this conversation")
+	assert.InDelta(t, float64(discord.EphemeralMessage), refusal.Body["data"].(map[string]any)["flags"], 0)
+	for _, request := range ts.discord.posted() {
+		assert.NotContains(t, request.content(), "💬", "the question is not posted")
+	}
+	assert.Len(t, ts.ai.asked(), 1)
+
+	require.NoError(t, ts.HandleSuggestion(ctx, click(initiatorID), chat.SuggestionComponentPrefix+"0"))
+	assert.Equal(t, []string{"first", "What next?"}, ts.ai.asked())
+	var asked, removed bool
+	for _, request := range ts.discord.posted() {
+		asked = asked || request.content() == "💬 What next?"
+		removed = removed || (strings.HasSuffix(request.Path, "/messages/99") && fmt.Sprint(request.Body["components"]) == "[]")
+	}
+	assert.True(t, asked, "the question is posted for the clicker")
+	assert.True(t, removed, "the reply's questions can be asked once")
+}
+
+func TestHandleSuggestion_MutedThread(t *testing.T) {
+	const threadID, initiatorID = discord.ChannelID(10), discord.UserID(5)
+	ts := newSuggestionsService(t, &config.Config{}, &fakeSuggestions{questions: []string{"What next?", "Why?"}}, threadID, initiatorID)
+	_, err := ts.mutes.Mute(threadID, initiatorID)
+	require.NoError(t, err)
+
+	e := continueSpending(threadID, initiatorID)
+	e.Message = &discord.Message{ID: 99, ChannelID: threadID, Components: discord.ContainerComponents{
+		&discord.ActionRowComponent{&discord.ButtonComponent{Label: "What next?", CustomID: chat.SuggestionComponentPrefix + "0"}},
+	}}
+	e.Data = &discord.ButtonInteraction{CustomID: chat.SuggestionComponentPrefix + "0"}
+	require.NoError(t, ts.HandleSuggestion(t.Context(), e, chat.SuggestionComponentPrefix+"0"))
+
+	notice := ts.discord.posted()[len(ts.discord.posted())-1]
+	assert.Contains(t, notice.content(), "muted")
+	assert.InDelta(t, float64(discord.EphemeralMessage), notice.Body["data"].(map[string]any)["flags"], 0)
+	assert.Equal(t, []string{"first"}, ts.ai.asked(), "a muted thread is not answered")
+}
@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+)
+
+// summarizePrompt asks for the summary posted by /summarize.
+const summarizePrompt = "Summarize the conversation so far for the people in it. " +
+	"Use a few short bullet points covering the topics discussed, what was decided or answered, and any open questions. " +
+	"Write the summary only, without addressing anyone and without new advice."
+
+// summaryKeptMessages is how many of the latest messages stay verbatim when
+// a summary replaces the history before them.
+const summaryKeptMessages = 4
+
+// summarySystemPrefix introduces a summary standing in for earlier messages.
+const summarySystemPrefix = "Summary of the earlier conversation in this thread:\n"
+
+// ErrNothingToSummarize is returned for conversations with no messages yet.
+var ErrNothingToSummarize = errors.New("conversation has no messages to summarize")
+
+// ThreadSummary is the result of Summarize.
+type ThreadSummary struct {
+	Text  string
+	Calls []CallUsage
+	// Replaced is how many cached messages the summary now stands in for,
+	// zero when the history was kept.
+	Replaced int
+}
+
+// Summarize summarizes the conversation of threadID for userID, loaded from
+// the cache, the archive or the thread's history. With compact, the cached
+// history is replaced by the summary followed by the latest messages, so
+// later replies send fewer tokens; the thread itself is unchanged.
+func (s *Service) Summarize(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID, userID discord.UserID, compact bool) (*ThreadSummary, error) {
+	data, err := s.loadConversation(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Messages) == 0 {
+		return nil, ErrNothingToSummarize
+	}
+
+	model := data.Model
+	if replacement, ok := s.deprecations.Replacement(model); ok {
+		model = replacement
+	}
+
+	ctx = internalopenai.WithGuild(withRequester(ctx, userID), guildID)
+	if err := s.budgets.Check(guildID, requesterFrom(ctx)); err != nil {
+		return nil, err
+	}
+	messages, calls, err := s.contextWindow.Fit(ctx, guildID, threadID, model, s.images.Inline(ctx, ForModel(s.cfg.OpenAI.Vision, model, data.Messages)))
+	s.recordSpend(guildID, threadID, calls)
+	s.recordBudgetUsage(ctx, guildID, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.limiter.Acquire(ctx, guildID, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := summarizeMessages(ctx, s.aiProvider, model, messages)
+	release(err)
+	if err != nil {
+		return nil, err
+	}
+	summaryCall := CallUsage{Step: "summary", Model: model, Usage: resp.Usage}
+	s.recordSpend(guildID, threadID, []CallUsage{summaryCall})
+	s.recordBudgetUsage(ctx, guildID, []CallUsage{summaryCall})
+	calls = append(calls, summaryCall)
+
+	summary := &ThreadSummary{Text: strings.TrimSpace(resp.Choices[0].Message.Content), Calls: calls}
+	if compact {
+		summary.Replaced = s.compactConversation(threadID, data, summary.Text)
+	}
+	s.logger.Info("Summarized thread",
+		zap.String("threadID", threadID.String()),
+		zap.Int("historyLength", len(data.Messages)),
+		zap.Int("replaced", summary.Replaced))
+
+	return summary, nil
+}
+
+// summarizeMessages asks provider for a summary of messages, in its
+// summarizing mode when it has one.
+func summarizeMessages(ctx context.Context, aiProvider AIProvider, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	if provider, ok := aiProvider.(SummarizingProvider); ok {
+		return provider.Summarize(ctx, model, messages, summarizePrompt)
+	}
+
+	request := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	request = append(request, messages...)
+	request = append(request, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: summarizePrompt})
+
+	return aiProvider.GetChatCompletion(ctx, model, request)
+}
+
+// compactConversation replaces the cached history of threadID, as it was in
+// data, with summary and the latest messages. It returns how many messages
+// were replaced, zero when the history is too short to gain from it or
+// changed while the summary was written.
+func (s *Service) compactConversation(threadID discord.ChannelID, data *MessagesCacheData, summary string) int {
+	replaced := len(data.Messages) - summaryKeptMessages
+	if replaced <= 1 {
+		return 0
+	}
+
+	threadIDStr := threadID.String()
+	if current, found := s.conversationStore.GetConversation(threadIDStr); found && len(current.Messages) != len(data.Messages) {
+		s.logger.Info("Conversation changed while it was summarized, keeping its history", zap.String("threadID", threadIDStr))
+
+		return 0
+	}
+
+	messages := make([]openai.ChatCompletionMessage, 0, summaryKeptMessages+1)
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: summarySystemPrefix + summary})
+	messages = append(messages, data.Messages[replaced:]...)
+	s.conversationStore.UpdateConversationMessages(threadIDStr, messages, data.Model)
+
+	return replaced
+}
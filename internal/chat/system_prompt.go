@@ -0,0 +1,93 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/prompts"
+)
+
+// threadParentsCacheSize bounds how many channels' parents are remembered.
+const threadParentsCacheSize = 1024
+
+type channelKey struct{}
+
+// withChannel records that the AI requests made with ctx were asked for in
+// channelID, for requests that belong to no thread.
+func withChannel(ctx context.Context, channelID discord.ChannelID) context.Context {
+	return context.WithValue(ctx, channelKey{}, channelID)
+}
+
+func channelFrom(ctx context.Context) discord.ChannelID {
+	channelID, _ := ctx.Value(channelKey{}).(discord.ChannelID)
+
+	return channelID
+}
+
+// systemPrompt returns the system prompt of a request in threadID, or in the
+// channel of ctx outside threads. It is resolved for every request rather
+// than kept in the conversation, so threads rebuilt from their history or
+// the archive pick it up, as well as later /prompt changes.
+func (s *Service) systemPrompt(ctx context.Context, guildID discord.GuildID, threadID discord.ChannelID) string {
+	channelID := threadID
+	if !channelID.IsValid() {
+		channelID = channelFrom(ctx)
+	}
+
+	var channelIDs []discord.ChannelID
+	if s.prompts != nil && guildID.IsValid() && channelID.IsValid() {
+		channelIDs = []discord.ChannelID{channelID, s.threadParent(channelID)}
+	}
+	prompt, _ := prompts.Resolve(s.prompts, s.cfg.Prompts.Default, guildID, channelIDs...)
+
+	return prompt
+}
+
+// threadParent returns the channel threadID was started in, or
+// discord.NullChannelID when it is not a thread.
+func (s *Service) threadParent(threadID discord.ChannelID) discord.ChannelID {
+	if parentID, ok := s.threadParents.Get(threadID); ok {
+		return parentID
+	}
+
+	ch, err := s.ses.Channel(threadID)
+	if err != nil {
+		s.logger.Debug("Failed to look up channel for its system prompt", zap.Error(err), zap.String("channelID", threadID.String()))
+
+		return discord.NullChannelID
+	}
+	parentID := discord.NullChannelID
+	switch ch.Type {
+	case discord.GuildPublicThread, discord.GuildPrivateThread, discord.GuildAnnouncementThread:
+		parentID = ch.ParentID
+	}
+	s.threadParents.Add(threadID, parentID)
+
+	return parentID
+}
+
+func newThreadParentsCache() *lru.Cache[discord.ChannelID, discord.ChannelID] {
+	cache, err := lru.New[discord.ChannelID, discord.ChannelID](threadParentsCacheSize)
+	if err != nil {
+		panic(err)
+	}
+
+	return cache
+}
+
+// withSystemPrompt prepends prompt to messages. Without a prompt messages are
+// returned unchanged.
+func withSystemPrompt(prompt string, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if prompt == "" {
+		return messages
+	}
+
+	withSystem := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	withSystem = append(withSystem, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: prompt})
+
+	return append(withSystem, messages...)
+}
@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// ThreadPolicy controls who may continue a conversation in a chat thread.
+type ThreadPolicy string
+
+const (
+	// ThreadPolicyAnyone lets anyone who can post in the thread continue the conversation.
+	ThreadPolicyAnyone ThreadPolicy = "anyone"
+	// ThreadPolicyInitiator limits the conversation to the user who ran /chat.
+	ThreadPolicyInitiator ThreadPolicy = "initiator"
+	// ThreadPolicyRoles limits the conversation to the initiator and members
+	// holding one of the configured thread roles.
+	ThreadPolicyRoles ThreadPolicy = "roles"
+)
+
+const (
+	participantsMarker = "**Participants:** "
+	// blockedNoticePrefix marks bot notices to users who may not continue a
+	// conversation, so that they are left out of reconstructed history.
+	blockedNoticePrefix = "🔒 "
+)
+
+// threadPolicyLabels are shown in the thread summary message and parsed back
+// when a conversation is reconstructed.
+var threadPolicyLabels = map[ThreadPolicy]string{
+	ThreadPolicyAnyone:    "anyone",
+	ThreadPolicyInitiator: "initiator only",
+	ThreadPolicyRoles:     "initiator and allowed roles",
+}
+
+// ParseThreadPolicy validates a policy name. An empty name returns fallback.
+func ParseThreadPolicy(name string, fallback ThreadPolicy) (ThreadPolicy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return fallback, nil
+	}
+
+	policy := ThreadPolicy(name)
+	if _, ok := threadPolicyLabels[policy]; !ok {
+		return "", fmt.Errorf("unknown thread policy %q, use anyone, initiator or roles", name)
+	}
+
+	return policy, nil
+}
+
+// ThreadAccess records who may continue the conversation in a thread.
+type ThreadAccess struct {
+	Policy      ThreadPolicy
+	InitiatorID discord.UserID
+}
+
+// Allows reports whether a thread message author may continue the conversation.
+// member is the author's guild member, if known.
+func (a ThreadAccess) Allows(authorID discord.UserID, member *discord.Member, allowedRoles []discord.RoleID) bool {
+	if a.Policy == "" || a.Policy == ThreadPolicyAnyone || authorID == a.InitiatorID {
+		return true
+	}
+	if a.Policy != ThreadPolicyRoles || member == nil {
+		return false
+	}
+
+	for _, memberRole := range member.RoleIDs {
+		for _, allowed := range allowedRoles {
+			if memberRole == allowed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// summaryLine returns the summary message line describing the policy.
+func (a ThreadAccess) summaryLine() string {
+	return participantsMarker + threadPolicyLabels[a.Policy] + "\n"
+}
+
+// blockedNotice explains to a user why their thread message was ignored.
+func (a ThreadAccess) blockedNotice(authorID discord.UserID) string {
+	if a.Policy == ThreadPolicyRoles {
+		return fmt.Sprintf("%sSorry %s, only %s and members with an allowed role can continue this conversation. Start your own with /chat!",
+			blockedNoticePrefix, authorID.Mention(), a.InitiatorID.Mention())
+	}
+
+	return fmt.Sprintf("%sSorry %s, only %s can continue this conversation. Start your own with /chat!",
+		blockedNoticePrefix, authorID.Mention(), a.InitiatorID.Mention())
+}
+
+// parseThreadAccess reads the policy and initiator from a thread summary message.
+// Summaries written before policies existed allow anyone to participate.
+func parseThreadAccess(content string, referencedMessage *discord.Message) ThreadAccess {
+	access := ThreadAccess{Policy: ThreadPolicyAnyone}
+	if content == "" && referencedMessage != nil {
+		content = referencedMessage.Content
+	}
+
+	if referencedMessage != nil && referencedMessage.Interaction != nil {
+		access.InitiatorID = referencedMessage.Interaction.User.ID
+	}
+	if _, rest, ok := strings.Cut(content, "**User:** <@"); ok {
+		if id, _, ok := strings.Cut(rest, ">"); ok {
+			if sf, err := discord.ParseSnowflake(strings.TrimPrefix(id, "!")); err == nil {
+				access.InitiatorID = discord.UserID(sf)
+			}
+		}
+	}
+
+	if _, rest, ok := strings.Cut(content, participantsMarker); ok {
+		label, _, _ := strings.Cut(rest, "\n")
+		for policy, l := range threadPolicyLabels {
+			if l == strings.TrimSpace(label) {
+				access.Policy = policy
+			}
+		}
+	}
+
+	// Without a known initiator the restriction cannot be enforced
+	if !access.InitiatorID.IsValid() {
+		access.Policy = ThreadPolicyAnyone
+	}
+
+	return access
+}
@@ -0,0 +1,59 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+func TestParseThreadPolicy(t *testing.T) {
+	policy, err := chat.ParseThreadPolicy("", chat.ThreadPolicyInitiator)
+	require.NoError(t, err)
+	assert.Equal(t, chat.ThreadPolicyInitiator, policy)
+
+	policy, err = chat.ParseThreadPolicy(" Roles ", chat.ThreadPolicyAnyone)
+	require.NoError(t, err)
+	assert.Equal(t, chat.ThreadPolicyRoles, policy)
+
+	_, err = chat.ParseThreadPolicy("friends", chat.ThreadPolicyAnyone)
+	assert.ErrorContains(t, err, `"friends"`)
+}
+
+func TestThreadAccessAllows(t *testing.T) {
+	const (
+		initiator = discord.UserID(1)
+		other     = discord.UserID(2)
+		allowed   = discord.RoleID(10)
+	)
+	roles := []discord.RoleID{allowed}
+	withRole := &discord.Member{RoleIDs: []discord.RoleID{5, allowed}}
+	withoutRole := &discord.Member{RoleIDs: []discord.RoleID{5}}
+
+	t.Run("Anyone", func(t *testing.T) {
+		access := chat.ThreadAccess{Policy: chat.ThreadPolicyAnyone, InitiatorID: initiator}
+		assert.True(t, access.Allows(other, nil, roles))
+	})
+
+	t.Run("UnsetPolicyAllowsAnyone", func(t *testing.T) {
+		assert.True(t, chat.ThreadAccess{}.Allows(other, nil, roles))
+	})
+
+	t.Run("Initiator", func(t *testing.T) {
+		access := chat.ThreadAccess{Policy: chat.ThreadPolicyInitiator, InitiatorID: initiator}
+		assert.True(t, access.Allows(initiator, nil, roles))
+		assert.False(t, access.Allows(other, withRole, roles), "roles do not matter under the initiator policy")
+	})
+
+	t.Run("Roles", func(t *testing.T) {
+		access := chat.ThreadAccess{Policy: chat.ThreadPolicyRoles, InitiatorID: initiator}
+		assert.True(t, access.Allows(initiator, nil, roles))
+		assert.True(t, access.Allows(other, withRole, roles))
+		assert.False(t, access.Allows(other, withoutRole, roles))
+		assert.False(t, access.Allows(other, nil, roles), "unknown members are not allowed")
+		assert.False(t, access.Allows(other, withRole, nil), "no roles are allowed when none are configured")
+	})
+}
@@ -6,18 +6,20 @@ import (
 
 	"github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
+
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
 )
 
 // OpenAITitleGenerator implements ThreadTitleGenerator using OpenAI API.
 type OpenAITitleGenerator struct {
-	client *openai.Client
+	keys   internalopenai.KeyResolver
 	logger *zap.Logger
 }
 
 // NewOpenAITitleGenerator creates a new OpenAI-based title generator.
-func NewOpenAITitleGenerator(client *openai.Client, logger *zap.Logger) ThreadTitleGenerator {
+func NewOpenAITitleGenerator(keys internalopenai.KeyResolver, logger *zap.Logger) ThreadTitleGenerator {
 	return &OpenAITitleGenerator{
-		client: client,
+		keys:   keys,
 		logger: logger.Named("title_generator"),
 	}
 }
@@ -38,7 +40,7 @@ func (g *OpenAITitleGenerator) GenerateTitle(ctx context.Context, messages []ope
 	chatMessages = append(chatMessages, messages...)
 
 	// 3) Call the Chat Completions endpoint
-	resp, err := g.client.CreateChatCompletion(
+	resp, err := g.keys.Client(internalopenai.GuildFrom(ctx)).CreateChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
 			Model:            openai.GPT4Dot1Nano,
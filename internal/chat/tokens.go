@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"strings"
+	"sync"
+
+	tiktoken "github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// defaultEncoding is the tokenizer of current OpenAI models (gpt-4o,
+// gpt-4.1, the o series and later), used for models without a known one.
+const defaultEncoding = tiktoken.MODEL_O200K_BASE
+
+// claudeMargin raises the counts of Claude models, whose tokenizer is not
+// published, by a fifth over defaultEncoding's, as Claude splits text into
+// more tokens than OpenAI's tokenizers do.
+const claudeMargin = 5
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = make(map[string]*tiktoken.Tiktoken)
+)
+
+// CountTokens counts the tokens of text for model with the model's BPE
+// tokenizer: cl100k_base for gpt-4 and gpt-3.5-turbo, o200k_base for the
+// rest. Counts are exact for OpenAI models; Claude models are counted with
+// o200k_base plus a fifth, which errs on the high side. model may be empty
+// for the tokenizer of current OpenAI models.
+func CountTokens(model, text string) int {
+	if text == "" {
+		return 0
+	}
+
+	name, claude := encodingFor(model)
+	encoding := loadEncoding(name)
+	if encoding == nil {
+		// Every token covers at least one byte
+		return len(text)
+	}
+	tokens := len(encoding.EncodeOrdinary(text))
+	if claude {
+		tokens += ceilDiv(tokens, claudeMargin)
+	}
+
+	return tokens
+}
+
+// encodingFor returns the name of the encoding counting the tokens of model,
+// and whether model is a Claude model that encoding only approximates.
+func encodingFor(model string) (string, bool) {
+	if strings.HasPrefix(model, anthropicModelPrefix) {
+		return defaultEncoding, true
+	}
+	if name, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return name, false
+	}
+	for prefix, name := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return name, false
+		}
+	}
+
+	return defaultEncoding, false
+}
+
+// loadEncoding returns the named encoding, building it on first use, or nil
+// if it cannot be built.
+func loadEncoding(name string) *tiktoken.Tiktoken {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if encoding, ok := encodings[name]; ok {
+		return encoding
+	}
+	if len(encodings) == 0 {
+		// The vocabularies are built in, so counting never downloads them
+		tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+	}
+	encoding, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		encoding = nil
+	}
+	// A failure is remembered too, so it is not retried on every count
+	encodings[name] = encoding
+
+	return encoding
+}
+
+func ceilDiv(n, d int) int {
+	return (n + d - 1) / d
+}
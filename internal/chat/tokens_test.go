@@ -0,0 +1,47 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+func TestCountTokens(t *testing.T) {
+	const (
+		english = "The quick brown fox jumps over the lazy dog."
+		cjk     = "你好，世界！今天天气很好。"
+		code    = "func main() {\n\tfmt.Println(\"hi\")\n}"
+	)
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		// o200k_base
+		{"gpt-4o", english, 10},
+		{"gpt-4o", cjk, 9},
+		{"gpt-4o", code, 10},
+		{"gpt-4.1-mini", cjk, 9},
+		{"", cjk, 9},
+		// cl100k_base, which splits CJK text much finer
+		{"gpt-4", english, 10},
+		{"gpt-4", cjk, 16},
+		{"gpt-4", code, 10},
+		{"gpt-3.5-turbo", cjk, 16},
+		// o200k_base plus a fifth, rounded up
+		{"claude-sonnet-4-5", english, 12},
+		{"claude-sonnet-4-5", cjk, 11},
+		{"claude-sonnet-4-5", code, 12},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, chat.CountTokens(tt.model, tt.text), "%s: %q", tt.model, tt.text)
+	}
+
+	assert.Zero(t, chat.CountTokens("gpt-4o", ""))
+	assert.Equal(t, 2, chat.CountTokens("gpt-4o", "hello world"), "a space before a word is part of it")
+	assert.Equal(t, 2, chat.CountTokens("gpt-4o", "12345"), "numbers split into groups of three digits")
+	assert.Equal(t, 3, chat.CountTokens("gpt-4o", "a\n\n\nb"), "a run of line breaks is one token")
+	assert.Equal(t, 6, chat.CountTokens("gpt-4o", "Привет, как дела?"))
+}
@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	// truncationMarker replaces the middle of text cut by TruncateMiddle.
+	truncationMarker = "\n\n[… %d characters omitted …]\n\n"
+	// truncationNoticePrefix marks bot notices about shortened messages, so
+	// that they are left out of reconstructed history.
+	truncationNoticePrefix = "✂️ "
+	// cutOffMarker ends replies that reached the reply token limit.
+	cutOffMarker = "*(cut off at the reply length limit)*"
+)
+
+// TruncateMiddle shortens text to at most maxChars characters by replacing
+// its middle with a marker, so that both the beginning and the end of a
+// long paste are kept. Cuts are moved to nearby line breaks where possible.
+// It reports whether text was shortened; a maxChars of zero keeps all text.
+func TruncateMiddle(text string, maxChars int) (string, bool) {
+	runes := []rune(text)
+	if maxChars <= 0 || len(runes) <= maxChars {
+		return text, false
+	}
+
+	keep := maxChars - len([]rune(fmt.Sprintf(truncationMarker, len(runes))))
+	if keep < 2 {
+		return string(runes[:maxChars]), true
+	}
+
+	head := lineBreakBefore(runes, keep-keep/2)
+	tail := lineBreakAfter(runes, len(runes)-keep/2)
+	omitted := tail - head
+
+	return strings.TrimRight(string(runes[:head]), " \n") +
+		fmt.Sprintf(truncationMarker, omitted) +
+		strings.TrimLeft(string(runes[tail:]), " \n"), true
+}
+
+// lineBreakBefore returns the position after the last line break in the
+// final quarter of runes[:end], or end if there is none.
+func lineBreakBefore(runes []rune, end int) int {
+	for i := end - 1; i >= end-end/4; i-- {
+		if runes[i] == '\n' {
+			return i + 1
+		}
+	}
+
+	return end
+}
+
+// lineBreakAfter returns the position of the first line break in the first
+// quarter of runes[start:], or start if there is none.
+func lineBreakAfter(runes []rune, start int) int {
+	limit := start + (len(runes)-start)/4
+	for i := start; i < limit; i++ {
+		if runes[i] == '\n' {
+			return i
+		}
+	}
+
+	return start
+}
+
+// limitPrompt applies the prompt limit of guildID to a user message. The
+// notice to show the user is empty when the message was kept whole.
+func (s *Service) limitPrompt(guildID discord.GuildID, text string) (string, string) {
+	maxChars := s.cfg.Limits(guildID.String()).MaxPromptChars
+	limited, truncated := TruncateMiddle(text, maxChars)
+	if !truncated {
+		return text, ""
+	}
+
+	return limited, truncationNoticePrefix + fmt.Sprintf(
+		"Your message was %d characters long, so only its beginning and end were sent (the limit is %d).",
+		len([]rune(text)), maxChars)
+}
+
+// limitAttachmentText applies the attachment text limit of guildID to each
+// text extracted from an attachment, returning the notice like limitPrompt.
+func (s *Service) limitAttachmentText(guildID discord.GuildID, texts []string) ([]string, string) {
+	maxChars := s.cfg.Limits(guildID.String()).MaxAttachmentChars
+	shortened := 0
+	limited := make([]string, len(texts))
+	for i, text := range texts {
+		var truncated bool
+		limited[i], truncated = TruncateMiddle(text, maxChars)
+		if truncated {
+			shortened++
+		}
+	}
+	if shortened == 0 {
+		return texts, ""
+	}
+
+	return limited, truncationNoticePrefix + fmt.Sprintf(
+		"%d of the attachments were too long, so only the beginning and end of their text were used (the limit is %d characters).",
+		shortened, maxChars)
+}
@@ -2,16 +2,28 @@
 package chat
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
 
 	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
 )
 
+// CallUsage is the usage of one model call made for a reply. Refined replies
+// take several calls, possibly on different models.
+type CallUsage struct {
+	Step  string // what the call was for, such as "draft" or "critique"
+	Model string
+	Usage openai.Usage
+}
+
 // UsageFormatter defines the interface for formatting OpenAI usage information.
 type UsageFormatter interface {
 	FormatUsage(usage openai.Usage, modelName string) (string, error)
+	// FormatTurnUsage formats the combined usage of every call made for one reply.
+	FormatTurnUsage(calls []CallUsage) (string, error)
 }
 
 // openAIUsageFormatter implements the UsageFormatter interface for OpenAI responses.
@@ -76,6 +88,80 @@ func (f *openAIUsageFormatter) FormatUsage(usage openai.Usage, modelName string)
 		cost), nil
 }
 
+// FormatTurnUsage formats the calls of a reply. A single call is formatted
+// like FormatUsage; several calls are summed, with the cost of each call
+// priced by its own model.
+func (f *openAIUsageFormatter) FormatTurnUsage(calls []CallUsage) (string, error) {
+	if len(calls) == 0 {
+		return "", errors.New("no calls to format")
+	}
+	if len(calls) == 1 {
+		return f.FormatUsage(calls[0].Usage, calls[0].Model)
+	}
+
+	var total openai.Usage
+	var cost float64
+	costKnown := true
+	steps := make([]string, len(calls))
+	for i, call := range calls {
+		steps[i] = call.Step
+		total.PromptTokens += call.Usage.PromptTokens
+		total.CompletionTokens += call.Usage.CompletionTokens
+		total.TotalTokens += call.Usage.TotalTokens
+
+		callCost, err := f.callCost(call)
+		if err != nil {
+			costKnown = false
+
+			continue
+		}
+		cost += callCost
+	}
+
+	text := fmt.Sprintf("%d calls (%s) | Input: %d | Output: %d | Total: %d tokens",
+		len(calls),
+		strings.Join(steps, ", "),
+		total.PromptTokens,
+		total.CompletionTokens,
+		total.TotalTokens)
+	if !costKnown {
+		return text, nil
+	}
+
+	return fmt.Sprintf("%s\nCost: $%.6f", text, cost), nil
+}
+
+// turnCost sums the cost of calls priced by pricingService. It reports false
+// when a call's model cannot be priced.
+func turnCost(pricingService pkgopenai.PricingService, calls []CallUsage) (float64, bool) {
+	f := &openAIUsageFormatter{pricingService: pricingService}
+	var cost float64
+	for _, call := range calls {
+		callCost, err := f.callCost(call)
+		if err != nil {
+			return cost, false
+		}
+		cost += callCost
+	}
+
+	return cost, true
+}
+
+// callCost prices a single call, accounting for cached input tokens.
+func (f *openAIUsageFormatter) callCost(call CallUsage) (float64, error) {
+	cachedTokens := f.extractCachedTokens(call.Usage)
+	if cachedTokens > 0 {
+		return f.pricingService.CalculateCachedTokenCost(
+			call.Model,
+			cachedTokens,
+			call.Usage.PromptTokens-cachedTokens,
+			call.Usage.CompletionTokens,
+		)
+	}
+
+	return f.pricingService.CalculateTokenCost(call.Model, call.Usage.PromptTokens, call.Usage.CompletionTokens)
+}
+
 // extractCachedTokens extracts cached token information from usage if available.
 func (f *openAIUsageFormatter) extractCachedTokens(usage openai.Usage) int {
 	// Extract cached tokens from PromptTokensDetails if available
@@ -79,3 +79,48 @@ func TestOpenAIUsageFormatter_FormatUsage_CostCalculationError(t *testing.T) {
 	assert.Contains(t, result, "Total: 150")
 	assert.NotContains(t, result, "Cost:") // Should not include cost when calculation fails
 }
+
+func TestOpenAIUsageFormatter_FormatTurnUsage_MultipleCalls(t *testing.T) {
+	mockPricing := test.NewMockPricingService(t)
+
+	formatter := chat.NewOpenAIUsageFormatter(mockPricing)
+
+	calls := []chat.CallUsage{
+		{Step: chat.StepDraft, Model: "gpt-4", Usage: openai.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}},
+		{Step: chat.StepCritique, Model: "gpt-4o-mini", Usage: openai.Usage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100}},
+		{Step: chat.StepRevision, Model: "gpt-4", Usage: openai.Usage{PromptTokens: 200, CompletionTokens: 60, TotalTokens: 260}},
+	}
+
+	mockPricing.On("CalculateTokenCost", "gpt-4", 100, 50).Return(0.004, nil)
+	mockPricing.On("CalculateTokenCost", "gpt-4o-mini", 80, 20).Return(0.0001, nil)
+	mockPricing.On("CalculateTokenCost", "gpt-4", 200, 60).Return(0.006, nil)
+
+	result, err := formatter.FormatTurnUsage(calls)
+
+	assert.NoError(t, err)
+	assert.Contains(t, result, "3 calls (draft, critique, revision)")
+	assert.Contains(t, result, "Input: 380")
+	assert.Contains(t, result, "Output: 130")
+	assert.Contains(t, result, "Total: 510")
+	assert.Contains(t, result, "Cost: $0.010100")
+}
+
+func TestOpenAIUsageFormatter_FormatTurnUsage_UnknownCost(t *testing.T) {
+	mockPricing := test.NewMockPricingService(t)
+
+	formatter := chat.NewOpenAIUsageFormatter(mockPricing)
+
+	calls := []chat.CallUsage{
+		{Step: chat.StepDraft, Model: "gpt-4", Usage: openai.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}},
+		{Step: chat.StepCritique, Model: "unknown-model", Usage: openai.Usage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100}},
+	}
+
+	mockPricing.On("CalculateTokenCost", "gpt-4", 100, 50).Return(0.004, nil)
+	mockPricing.On("CalculateTokenCost", "unknown-model", 80, 20).Return(0.0, assert.AnError)
+
+	result, err := formatter.FormatTurnUsage(calls)
+
+	assert.NoError(t, err)
+	assert.Contains(t, result, "2 calls (draft, critique)")
+	assert.NotContains(t, result, "Cost:") // A partial cost would understate the turn
+}
@@ -102,24 +102,49 @@ func MakeThreadName(username, prompt string, maxLength int) string {
 	return name
 }
 
-// SendLongMessage sends a message to a Discord channel, splitting it into multiple messages
-// if it exceeds discordMaxMessageLength. Returns the last message sent.
-func SendLongMessage(s *session.Session, channelID discord.ChannelID, content string) (*discord.Message, error) {
-	if len(content) <= discordMaxMessageLength {
-		return s.SendMessageComplex(channelID, api.SendMessageData{Content: content})
+// SplitMessage splits content into parts no longer than discordMaxMessageLength,
+// preferring to break at newlines or spaces.
+func SplitMessage(content string) []string {
+	return splitMessage(content, discordMaxMessageLength)
+}
+
+// SplitMessageWithFooter splits content like SplitMessage, leaving room to end
+// every part with footer on its own line. An empty footer adds nothing.
+func SplitMessageWithFooter(content, footer string) []string {
+	if footer == "" {
+		return SplitMessage(content)
 	}
 
+	parts := splitMessage(content, discordMaxMessageLength-len(footer)-1)
+	for i, part := range parts {
+		parts[i] = part + "\n" + footer
+	}
+
+	return parts
+}
+
+// DisclosureFooter formats a disclosure line as Discord subtext, or returns
+// an empty string for an empty text.
+func DisclosureFooter(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	return "-# " + text
+}
+
+func splitMessage(content string, maxLength int) []string {
 	var parts []string
 	remainingContent := content
 	for remainingContent != "" {
-		if len(remainingContent) <= discordMaxMessageLength {
+		if len(remainingContent) <= maxLength {
 			parts = append(parts, remainingContent)
 
 			break
 		}
 
 		// Find a good place to split (e.g., newline, space) to avoid breaking words/sentences awkwardly.
-		splitAt := discordMaxMessageLength
+		splitAt := maxLength
 		// Try to split at the last newline within the limit
 		lastNewline := strings.LastIndex(remainingContent[:splitAt], "\\n")
 		if lastNewline != -1 && lastNewline > 0 { // lastNewline > 0 to ensure we don't create empty messages if it starts with \\n
@@ -137,12 +162,25 @@ func SendLongMessage(s *session.Session, channelID discord.ChannelID, content st
 		remainingContent = strings.TrimSpace(remainingContent[splitAt:])
 	}
 
+	return parts
+}
+
+// SendLongMessage sends a message to a Discord channel, splitting it into multiple messages
+// if it exceeds discordMaxMessageLength. Every part ends with footer, if any.
+// Returns the last message sent. allowed limits the mentions that notify; nil
+// lets Discord parse every mention.
+func SendLongMessage(s *session.Session, channelID discord.ChannelID, content, footer string, allowed *api.AllowedMentions) (*discord.Message, error) {
+	parts := SplitMessageWithFooter(content, footer)
+	if len(parts) <= 1 {
+		return s.SendMessageComplex(channelID, api.SendMessageData{Content: strings.Join(parts, ""), AllowedMentions: allowed})
+	}
+
 	var lastMessage *discord.Message
 	for i, part := range parts {
 		if strings.TrimSpace(part) == "" { // Avoid sending empty messages
 			continue
 		}
-		msg, err := s.SendMessageComplex(channelID, api.SendMessageData{Content: part})
+		msg, err := s.SendMessageComplex(channelID, api.SendMessageData{Content: part, AllowedMentions: allowed})
 		if err != nil {
 			return nil, fmt.Errorf("failed to send message part %d/%d: %w", i+1, len(parts), err)
 		}
@@ -0,0 +1,70 @@
+package chat_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func TestSplitMessageWithFooter(t *testing.T) {
+	footer := chat.DisclosureFooter(config.DefaultDisclosureText)
+	assert.Equal(t, "-# AI-generated, may be inaccurate", footer)
+
+	content := strings.Repeat("word ", 700)
+	parts := chat.SplitMessageWithFooter(content, footer)
+	assert.Len(t, parts, 2)
+	for _, part := range parts {
+		assert.LessOrEqual(t, len(part), 2000)
+		assert.True(t, strings.HasSuffix(part, "\n"+footer), "every part carries the footer")
+	}
+
+	assert.Equal(t, chat.SplitMessage(content), chat.SplitMessageWithFooter(content, ""))
+	assert.Empty(t, chat.DisclosureFooter(""))
+}
+
+func TestConfigDisclosureText(t *testing.T) {
+	off, on := false, true
+	cfg := &config.Config{
+		OpenAI: config.OpenAIConfig{Disclosure: config.DisclosureConfig{Enabled: true}},
+		Guilds: map[string]config.GuildConfig{
+			"1": {Chat: config.GuildChatConfig{Disclosure: &off}},
+			"2": {Chat: config.GuildChatConfig{DisclosureText: "Written by a bot"}},
+		},
+	}
+	assert.Equal(t, config.DefaultDisclosureText, cfg.DisclosureText("3"))
+	assert.Empty(t, cfg.DisclosureText("1"))
+	assert.Equal(t, "Written by a bot", cfg.DisclosureText("2"))
+
+	cfg.OpenAI.Disclosure.Enabled = false
+	assert.Empty(t, cfg.DisclosureText("3"))
+	cfg.Guilds["1"] = config.GuildConfig{Chat: config.GuildChatConfig{Disclosure: &on}}
+	assert.Equal(t, config.DefaultDisclosureText, cfg.DisclosureText("1"))
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	short, truncated := chat.TruncateMiddle("hello", 10)
+	assert.False(t, truncated)
+	assert.Equal(t, "hello", short)
+
+	unlimited, truncated := chat.TruncateMiddle(strings.Repeat("a", 100), 0)
+	assert.False(t, truncated)
+	assert.Len(t, unlimited, 100)
+
+	paste := "BEGIN\n" + strings.Repeat("line of a long paste\n", 200) + "END"
+	limited, truncated := chat.TruncateMiddle(paste, 300)
+	assert.True(t, truncated)
+	assert.LessOrEqual(t, len([]rune(limited)), 300)
+	assert.True(t, strings.HasPrefix(limited, "BEGIN\n"))
+	assert.True(t, strings.HasSuffix(limited, "\nEND"))
+	assert.Contains(t, limited, "characters omitted")
+	assert.Contains(t, limited, "a long paste\n\n[…", "cuts are moved to line breaks")
+	assert.Contains(t, limited, "…]\n\nline of")
+
+	tiny, truncated := chat.TruncateMiddle(strings.Repeat("é", 50), 5)
+	assert.True(t, truncated)
+	assert.Equal(t, "ééééé", tiny)
+}
@@ -0,0 +1,168 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/youtube"
+)
+
+// defaultVideoQuestion is asked when /video is used without a question.
+const defaultVideoQuestion = "Summarize this video in a few short paragraphs, then list its key points."
+
+const videoInstructions = "You answer questions about a YouTube video using its transcript below. " +
+	"Base your answer on the transcript only, and say so when it does not cover the question."
+
+// HandleVideoInteraction answers question about the YouTube video at videoURL
+// from its transcript. An empty question asks for a summary. The answer is
+// sent in the interaction response.
+func (s *Service) HandleVideoInteraction(ctx context.Context, e *gateway.InteractionCreateEvent, videoURL, question, modelOption string) error {
+	videoID, ok := youtube.VideoID(videoURL)
+	if !ok {
+		return youtube.ErrNotVideo
+	}
+
+	model, err := s.modelSelector.SelectModel(modelOption)
+	if err != nil {
+		s.logger.Error("Failed to determine model", zap.Error(err))
+
+		return err
+	}
+
+	if err := s.interactionManager.DeferResponse(s.ses, e.ID, e.Token); err != nil {
+		return err
+	}
+
+	transcript, err := s.transcripts.Fetch(ctx, videoID)
+	if err != nil {
+		errMsg := "Sorry, I could not read the captions of that video."
+		if errors.Is(err, youtube.ErrNoCaptions) {
+			errMsg = "That video has no captions, so I cannot tell what it says."
+		}
+		if _, sendErr := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, errMsg, ""); sendErr != nil {
+			s.logger.Error("Failed to send transcript error message", zap.Error(sendErr))
+		}
+
+		return fmt.Errorf("failed to fetch transcript: %w", err)
+	}
+
+	if question == "" {
+		question = defaultVideoQuestion
+	}
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: videoInstructions + "\n\n" + formatTranscript(transcript)},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: question,
+			Name:    SanitizeOpenAIName(GetUserDisplayName(e.Sender())),
+		},
+	}
+
+	aiResponse, _, err := s.complete(withChannel(withRequester(ctx, e.SenderID()), e.ChannelID), e.GuildID, discord.NullChannelID, model, messages)
+	if err != nil {
+		errMsg := userErrorMessage(err, "Sorry, I encountered an error trying to reach the AI. Please try again later.")
+		if _, sendErr := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, errMsg, ""); sendErr != nil {
+			s.logger.Error("Failed to send error message after OpenAI failure", zap.Error(sendErr))
+		}
+
+		return err
+	}
+
+	title := transcript.Title
+	if title == "" {
+		title = videoID
+	}
+	content := fmt.Sprintf("**Video:** [%s](<%s>)\n**Model:** %s\n\n%s", title, transcript.URL(), model, aiResponse.Choices[0].Message.Content)
+	if _, err := s.interactionManager.SendInteractionMessage(s.ses, e.AppID, e.Token, content, s.disclosure(e.GuildID)); err != nil {
+		s.logger.Error("Failed to send video answer", zap.Error(err))
+
+		return fmt.Errorf("failed to send AI response to Discord: %w", err)
+	}
+
+	s.logger.Info("Video interaction completed",
+		zap.String("videoID", videoID),
+		zap.String("model", model))
+
+	return nil
+}
+
+// formatTranscript renders a transcript as a titled block of text for the model.
+func formatTranscript(t *youtube.Transcript) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- Transcript of %q <%s> (%s", t.Title, t.URL(), t.Language)
+	if t.Generated {
+		sb.WriteString(", generated captions")
+	}
+	sb.WriteString(")\n")
+	sb.WriteString(t.Text)
+	if t.Truncated {
+		sb.WriteString("\n[transcript truncated]")
+	}
+
+	return sb.String()
+}
+
+// videoTranscriptTool is the name the model calls the transcript tool by.
+const videoTranscriptTool = "youtube_transcript"
+
+// NewVideoTool creates the YouTube transcript tool, or returns nil unless
+// YouTube is enabled and the links mode is "tool".
+func NewVideoTool(logger *zap.Logger, cfg *config.Config, transcripts youtube.TranscriptFetcher) Tool {
+	if !cfg.OpenAI.YouTube.Enabled || !strings.EqualFold(cfg.OpenAI.Links.Mode, LinksTool) {
+		return nil
+	}
+
+	return &videoTool{logger: logger.Named("video_tool"), transcripts: transcripts}
+}
+
+type videoTool struct {
+	logger      *zap.Logger
+	transcripts youtube.TranscriptFetcher
+}
+
+// Definition describes youtube_transcript to the model.
+func (t *videoTool) Definition() openai.FunctionDefinition {
+	return openai.FunctionDefinition{
+		Name:        videoTranscriptTool,
+		Description: "Fetch the caption transcript of a YouTube video. Use it to summarize or answer questions about a video link.",
+		Parameters: jsonschema.Definition{
+			Type: jsonschema.Object,
+			Properties: map[string]jsonschema.Definition{
+				"url": {Type: jsonschema.String, Description: "The YouTube link or video ID"},
+			},
+			Required: []string{"url"},
+		},
+	}
+}
+
+// Call fetches the transcript of the video named in arguments.
+func (t *videoTool) Call(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	videoID, ok := youtube.VideoID(args.URL)
+	if !ok {
+		return "", youtube.ErrNotVideo
+	}
+
+	transcript, err := t.transcripts.Fetch(ctx, videoID)
+	if err != nil {
+		return "", err
+	}
+	t.logger.Debug("Model read a video transcript", zap.String("videoID", videoID))
+
+	return formatTranscript(transcript), nil
+}
@@ -0,0 +1,248 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+)
+
+const (
+	defaultTranscriptionModel = openai.Whisper1
+	defaultVoiceNoteMaxBytes  = 25 << 20
+	voiceNoteDownloadTimeout  = 30 * time.Second
+)
+
+// audioExtensions are the attachment types transcribed when Discord sends no content type.
+var audioExtensions = map[string]bool{
+	".ogg": true, ".oga": true, ".mp3": true, ".m4a": true, ".wav": true,
+	".webm": true, ".flac": true, ".mpga": true,
+}
+
+// Transcriber turns speech into text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, filename string, audio io.Reader) (string, error)
+}
+
+// NewWhisperTranscriber creates a Transcriber using the OpenAI transcription API.
+func NewWhisperTranscriber(logger *zap.Logger, cfg *config.Config, keys internalopenai.KeyResolver) Transcriber {
+	model := cfg.OpenAI.VoiceNotes.Model
+	if model == "" {
+		model = defaultTranscriptionModel
+	}
+
+	return &whisperTranscriber{
+		logger:   logger.Named("whisper_transcriber"),
+		keys:     keys,
+		model:    model,
+		language: cfg.OpenAI.VoiceNotes.Language,
+	}
+}
+
+type whisperTranscriber struct {
+	logger   *zap.Logger
+	keys     internalopenai.KeyResolver
+	model    string
+	language string
+}
+
+// Transcribe sends audio to the transcription model. filename tells the API the audio format.
+func (w *whisperTranscriber) Transcribe(ctx context.Context, filename string, audio io.Reader) (string, error) {
+	resp, err := w.keys.Client(internalopenai.GuildFrom(ctx)).CreateTranscription(ctx, openai.AudioRequest{
+		Model:    w.model,
+		FilePath: filename,
+		Reader:   audio,
+		Language: w.language,
+	})
+	if err != nil {
+		return "", fmt.Errorf("transcription failed: %w", err)
+	}
+	w.logger.Debug("Transcribed audio", zap.String("filename", filename), zap.Float64("durationSeconds", resp.Duration))
+
+	return strings.TrimSpace(resp.Text), nil
+}
+
+// VoiceNotes transcribes the audio attached to messages.
+type VoiceNotes interface {
+	// Allowed reports whether audio posted in ch is transcribed.
+	Allowed(ch *discord.Channel) bool
+	// Transcribe returns the transcripts of the audio attachments of msg, in
+	// order. Messages without audio have none.
+	Transcribe(ctx context.Context, msg *discord.Message) ([]string, error)
+}
+
+// NewVoiceNotes creates VoiceNotes following the voice_notes config.
+func NewVoiceNotes(logger *zap.Logger, cfg *config.Config, transcriber Transcriber) VoiceNotes {
+	return NewVoiceNotesFromClient(logger, cfg.OpenAI.VoiceNotes, transcriber, &http.Client{Timeout: voiceNoteDownloadTimeout})
+}
+
+// NewVoiceNotesFromClient creates VoiceNotes downloading attachments with client.
+func NewVoiceNotesFromClient(logger *zap.Logger, cfg config.VoiceNotesConfig, transcriber Transcriber, client *http.Client) VoiceNotes {
+	maxBytes := cfg.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultVoiceNoteMaxBytes
+	}
+	var channels map[string]bool
+	if len(cfg.ChannelIDs) > 0 {
+		channels = make(map[string]bool, len(cfg.ChannelIDs))
+		for _, id := range cfg.ChannelIDs {
+			channels[id] = true
+		}
+	}
+
+	return &voiceNotes{
+		logger:      logger.Named("voice_notes"),
+		enabled:     cfg.Enabled,
+		channels:    channels,
+		maxBytes:    uint64(maxBytes),
+		transcriber: transcriber,
+		client:      client,
+	}
+}
+
+type voiceNotes struct {
+	logger      *zap.Logger
+	enabled     bool
+	channels    map[string]bool // nil allows every channel
+	maxBytes    uint64
+	transcriber Transcriber
+	client      *http.Client
+}
+
+// Allowed checks the channel and, for threads, its parent against the configured channels.
+func (v *voiceNotes) Allowed(ch *discord.Channel) bool {
+	if !v.enabled || ch == nil {
+		return false
+	}
+
+	return v.channels == nil || v.channels[ch.ID.String()] || (ch.ParentID.IsValid() && v.channels[ch.ParentID.String()])
+}
+
+// Transcribe downloads and transcribes each audio attachment. Attachments over
+// the size limit are skipped.
+func (v *voiceNotes) Transcribe(ctx context.Context, msg *discord.Message) ([]string, error) {
+	var transcripts []string
+	for _, a := range msg.Attachments {
+		if !isAudio(a) {
+			continue
+		}
+		if a.Size > v.maxBytes {
+			v.logger.Info("Skipping audio attachment over the size limit",
+				zap.String("messageID", msg.ID.String()),
+				zap.Uint64("size", a.Size))
+
+			continue
+		}
+
+		text, err := v.transcribe(ctx, a)
+		if err != nil {
+			return transcripts, fmt.Errorf("failed to transcribe %s: %w", a.Filename, err)
+		}
+		if text != "" {
+			transcripts = append(transcripts, text)
+		}
+	}
+
+	return transcripts, nil
+}
+
+func (v *voiceNotes) transcribe(ctx context.Context, a discord.Attachment) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download audio: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download audio: status %d", resp.StatusCode)
+	}
+
+	return v.transcriber.Transcribe(ctx, a.Filename, io.LimitReader(resp.Body, int64(v.maxBytes)))
+}
+
+// isAudio reports whether a is an audio file, such as a Discord voice message.
+func isAudio(a discord.Attachment) bool {
+	if a.ContentType != "" {
+		return strings.HasPrefix(a.ContentType, "audio/")
+	}
+
+	return audioExtensions[strings.ToLower(path.Ext(a.Filename))]
+}
+
+// HandleVoiceNote transcribes the audio attached to a message in an allowed
+// channel and replies with the text. With feed_conversation, transcribed
+// messages in threads then continue the conversation. It reports whether the
+// message was fully handled, in which case it needs no further processing.
+func (s *Service) HandleVoiceNote(ctx context.Context, evt *gateway.MessageCreateEvent, ch *discord.Channel) (bool, error) {
+	if !s.voiceNotes.Allowed(ch) {
+		return false, nil
+	}
+
+	transcripts, err := s.voiceNotes.Transcribe(internalopenai.WithGuild(ctx, evt.GuildID), &evt.Message)
+	if len(transcripts) == 0 {
+		if err != nil {
+			s.replyToMessage(evt, "⚠️ Sorry, I could not transcribe that audio.")
+
+			return true, err
+		}
+
+		return false, nil
+	}
+	if err != nil {
+		s.logger.Warn("Failed to transcribe some attachments", zap.Error(err), zap.String("messageID", evt.ID.String()))
+	}
+
+	transcripts, truncationNotice := s.limitAttachmentText(evt.GuildID, transcripts)
+	transcript := strings.Join(transcripts, "\n\n")
+	s.replyToMessage(evt, "🎙️ **Transcript:**\n"+transcript)
+	if truncationNotice != "" {
+		s.replyToMessage(evt, truncationNotice)
+	}
+	s.logger.Info("Transcribed voice note",
+		zap.String("channelID", evt.ChannelID.String()),
+		zap.String("messageID", evt.ID.String()),
+		zap.Int("attachments", len(transcripts)))
+
+	isThread := ch.Type == discord.GuildPublicThread || ch.Type == discord.GuildPrivateThread || ch.Type == discord.GuildAnnouncementThread
+	if !s.cfg.OpenAI.VoiceNotes.FeedConversation || !isThread {
+		// Typed text alongside the audio is still answered as usual
+		return evt.Content == "", nil
+	}
+
+	// The transcript stands in for what the user would have typed
+	fed := *evt
+	fed.Content = strings.TrimSpace(evt.Content + "\n\n" + transcript)
+
+	return true, s.HandleThreadMessage(ctx, &fed)
+}
+
+// replyToMessage answers a message in its channel, splitting long content.
+func (s *Service) replyToMessage(evt *gateway.MessageCreateEvent, content string) {
+	for i, part := range SplitMessage(content) {
+		data := api.SendMessageData{Content: part, AllowedMentions: &api.AllowedMentions{}}
+		if i == 0 {
+			data.Reference = &discord.MessageReference{MessageID: evt.ID}
+		}
+		if _, err := s.ses.SendMessageComplex(evt.ChannelID, data); err != nil {
+			s.logger.Error("Failed to reply to message", zap.Error(err), zap.String("messageID", evt.ID.String()))
+
+			return
+		}
+	}
+}
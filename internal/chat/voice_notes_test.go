@@ -0,0 +1,88 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// echoTranscriber "transcribes" audio by returning its bytes.
+type echoTranscriber struct {
+	filenames []string
+	fail      bool
+}
+
+func (e *echoTranscriber) Transcribe(_ context.Context, filename string, audio io.Reader) (string, error) {
+	if e.fail {
+		return "", errors.New("model unavailable")
+	}
+	e.filenames = append(e.filenames, filename)
+	b, err := io.ReadAll(audio)
+
+	return string(b), err
+}
+
+func TestVoiceNotes_Allowed(t *testing.T) {
+	cfg := config.VoiceNotesConfig{Enabled: true, ChannelIDs: []string{"10"}}
+	notes := chat.NewVoiceNotesFromClient(zap.NewNop(), cfg, &echoTranscriber{}, http.DefaultClient)
+
+	assert.True(t, notes.Allowed(&discord.Channel{ID: 10}))
+	assert.True(t, notes.Allowed(&discord.Channel{ID: 11, ParentID: 10}), "threads of allowed channels are allowed")
+	assert.False(t, notes.Allowed(&discord.Channel{ID: 12}))
+
+	cfg.ChannelIDs = nil
+	assert.True(t, chat.NewVoiceNotesFromClient(zap.NewNop(), cfg, &echoTranscriber{}, http.DefaultClient).Allowed(&discord.Channel{ID: 12}))
+
+	cfg.Enabled = false
+	assert.False(t, chat.NewVoiceNotesFromClient(zap.NewNop(), cfg, &echoTranscriber{}, http.DefaultClient).Allowed(&discord.Channel{ID: 10}))
+}
+
+func TestVoiceNotes_Transcribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello from " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	transcriber := &echoTranscriber{}
+	notes := chat.NewVoiceNotesFromClient(zap.NewNop(), config.VoiceNotesConfig{Enabled: true, MaxBytes: 100}, transcriber, server.Client())
+
+	msg := &discord.Message{Attachments: []discord.Attachment{
+		{Filename: "voice-message.ogg", ContentType: "audio/ogg", Size: 10, URL: server.URL + "/a"},
+		{Filename: "photo.png", ContentType: "image/png", Size: 10, URL: server.URL + "/b"},
+		{Filename: "song.mp3", Size: 10, URL: server.URL + "/c"},
+		{Filename: "long.wav", ContentType: "audio/wav", Size: 1000, URL: server.URL + "/d"},
+	}}
+
+	transcripts, err := notes.Transcribe(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello from /a", "hello from /c"}, transcripts)
+	assert.Equal(t, []string{"voice-message.ogg", "song.mp3"}, transcriber.filenames, "images and oversized audio are skipped")
+
+	transcripts, err = notes.Transcribe(context.Background(), &discord.Message{Content: "no audio"})
+	require.NoError(t, err)
+	assert.Empty(t, transcripts)
+}
+
+func TestVoiceNotes_TranscribeFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("audio"))
+	}))
+	defer server.Close()
+
+	notes := chat.NewVoiceNotesFromClient(zap.NewNop(), config.VoiceNotesConfig{Enabled: true}, &echoTranscriber{fail: true}, server.Client())
+	msg := &discord.Message{Attachments: []discord.Attachment{{Filename: "a.ogg", ContentType: "audio/ogg", URL: server.URL}}}
+
+	_, err := notes.Transcribe(context.Background(), msg)
+	assert.ErrorContains(t, err, "model unavailable")
+}
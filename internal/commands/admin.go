@@ -0,0 +1,563 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/backup"
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+	"github.com/Raikerian/go-discord-chatgpt/internal/permissions"
+)
+
+// Ignore list scopes offered by /admin ignore.
+const (
+	scopeServer = "server"
+	scopeGlobal = "global"
+)
+
+// maxBackupAttachment is the largest backup /admin backup attaches, under
+// Discord's upload limit for bots. Larger backups are taken with the CLI.
+const maxBackupAttachment = 8 << 20
+
+// AdminCommand lets server managers and bot operators moderate who may use
+// the bot and deliver replies that could not be posted, and bot operators
+// back up the bot's data.
+type AdminCommand struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	ignored     moderation.IgnoreList
+	loops       moderation.LoopDetector
+	chatService *chat.Service
+	deadLetters *chat.DeadLetters
+	backups     *backup.Service
+	policy      *permissions.Policy
+}
+
+// NewAdminCommand creates a new AdminCommand.
+func NewAdminCommand(
+	logger *zap.Logger,
+	cfg *config.Config,
+	ignoreList moderation.IgnoreList,
+	loops moderation.LoopDetector,
+	chatService *chat.Service,
+	deadLetters *chat.DeadLetters,
+	backups *backup.Service,
+	policy *permissions.Policy,
+) Command {
+	return &AdminCommand{
+		logger:      logger.Named("admin_command"),
+		cfg:         cfg,
+		ignored:     ignoreList,
+		loops:       loops,
+		chatService: chatService,
+		deadLetters: deadLetters,
+		backups:     backups,
+		policy:      policy,
+	}
+}
+
+// Name returns the name of the command.
+func (c *AdminCommand) Name() string {
+	return "admin"
+}
+
+// Description returns the description of the command.
+func (c *AdminCommand) Description() string {
+	return "Moderate who can use the bot"
+}
+
+// DefaultMemberPermissions restricts the command to server managers by default.
+func (c *AdminCommand) DefaultMemberPermissions() discord.Permissions {
+	return discord.PermissionManageGuild
+}
+
+// RequiredPermissions keeps the command to server managers even when the
+// server's integration settings let others see it.
+func (c *AdminCommand) RequiredPermissions() discord.Permissions {
+	return discord.PermissionManageGuild
+}
+
+// Options returns the ignore and loop subcommand groups, the backup and
+// permissions subcommands, and the delivery group when the dead letter queue is enabled.
+func (c *AdminCommand) Options() []discord.CommandOption {
+	scope := func() *discord.StringOption {
+		return &discord.StringOption{
+			OptionName:  "scope",
+			Description: "This server only, or every server (bot operators only)",
+			Choices: []discord.StringChoice{
+				{Name: "This server", Value: scopeServer},
+				{Name: "Every server", Value: scopeGlobal},
+			},
+		}
+	}
+
+	options := []discord.CommandOption{
+		&discord.SubcommandGroupOption{
+			OptionName:  "ignore",
+			Description: "Manage the users whose messages and commands the bot ignores",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "add",
+					Description: "Ignore a user's messages and commands",
+					Options: []discord.CommandOptionValue{
+						&discord.UserOption{OptionName: "user", Description: "User to ignore", Required: true},
+						scope(),
+					},
+				},
+				{
+					OptionName:  "remove",
+					Description: "Stop ignoring a user",
+					Options: []discord.CommandOptionValue{
+						&discord.UserOption{OptionName: "user", Description: "User to stop ignoring", Required: true},
+						scope(),
+					},
+				},
+				{
+					OptionName:  "list",
+					Description: "List the ignored users",
+					Options:     []discord.CommandOptionValue{scope()},
+				},
+			},
+		},
+		&discord.SubcommandGroupOption{
+			OptionName:  "loop",
+			Description: "Manage replies paused by loop detection",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "resume",
+					Description: "Resume replies in this channel after a loop pause",
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "backup",
+			Description: "Download a backup of the bot's data (bot operators only)",
+		},
+		&discord.SubcommandOption{
+			OptionName:  "permissions",
+			Description: "Show who may use the bot's commands in this server",
+			Options: []discord.CommandOptionValue{
+				&discord.UserOption{OptionName: "user", Description: "Member to check the commands for (default: you)"},
+				&discord.StringOption{OptionName: "command", Description: "Command to check, such as \"voice\" or \"admin ignore\" (default: all restricted)"},
+			},
+		},
+	}
+
+	if c.cfg != nil && c.cfg.DeadLetters.Enabled {
+		options = append(options, &discord.SubcommandGroupOption{
+			OptionName:  "delivery",
+			Description: "Manage AI replies that could not be posted",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "list",
+					Description: "List the replies waiting to be delivered",
+				},
+				{
+					OptionName:  "retry",
+					Description: "Post a reply that could not be delivered",
+					Options: []discord.CommandOptionValue{
+						&discord.StringOption{OptionName: "id", Description: "ID of the undelivered reply", Required: true},
+						&discord.ChannelOption{
+							OptionName:  "channel",
+							Description: "Channel to post it in (optional, defaults to where it was meant to go)",
+						},
+					},
+				},
+			},
+		})
+	}
+
+	return options
+}
+
+// Execute runs the selected subcommand.
+func (c *AdminCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if len(data.Options) > 0 {
+		switch data.Options[0].Name {
+		case "backup":
+			return c.backup(ctx, s, e)
+		case "permissions":
+			return c.permissions(s, e, data.Options[0])
+		}
+	}
+	if len(data.Options) == 0 || len(data.Options[0].Options) == 0 {
+		return errors.New("admin subcommand is missing")
+	}
+	group, sub := data.Options[0], data.Options[0].Options[0]
+	switch group.Name {
+	case "ignore":
+	case "loop":
+		if sub.Name != "resume" {
+			return fmt.Errorf("unknown admin loop subcommand %q", sub.Name)
+		}
+
+		return c.resume(s, e)
+	case "delivery":
+		return c.delivery(ctx, s, e, sub)
+	default:
+		return fmt.Errorf("unknown admin subcommand group %q", group.Name)
+	}
+
+	scope := scopeServer
+	var userID discord.UserID
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "scope":
+			scope = opt.String()
+		case "user":
+			sf, err := opt.SnowflakeValue()
+			if err != nil {
+				return fmt.Errorf("invalid user option: %w", err)
+			}
+			userID = discord.UserID(sf)
+		}
+	}
+
+	guildID := e.GuildID
+	switch {
+	case scope == scopeGlobal:
+		if !moderation.IsAdmin(c.cfg, e.SenderID()) {
+			metrics.MarkDropped(ctx, metrics.DropPermission)
+
+			return respondEphemeral(s, e, "Only bot operators can manage the global ignore list.")
+		}
+		guildID = moderation.Global
+	case !e.GuildID.IsValid():
+		return respondEphemeral(s, e, "The server ignore list can only be managed in servers.")
+	}
+
+	switch sub.Name {
+	case "add":
+		return c.add(s, e, guildID, userID)
+	case "remove":
+		return c.remove(s, e, guildID, userID)
+	case "list":
+		return c.list(s, e, guildID)
+	default:
+		return fmt.Errorf("unknown admin ignore subcommand %q", sub.Name)
+	}
+}
+
+func (c *AdminCommand) add(s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, userID discord.UserID) error {
+	switch {
+	case userID == e.SenderID():
+		return respondEphemeral(s, e, "You cannot ignore yourself.")
+	case moderation.IsAdmin(c.cfg, userID):
+		return respondEphemeral(s, e, "Bot operators cannot be ignored.")
+	}
+
+	added, err := c.ignored.Add(guildID, userID)
+	if err != nil {
+		c.logger.Warn("Failed to ignore user", zap.Error(err), zap.String("guildID", guildID.String()), zap.String("userID", userID.String()))
+
+		return respondEphemeral(s, e, "❌ Could not update the ignore list: "+err.Error())
+	}
+	if !added {
+		return respondEphemeral(s, e, fmt.Sprintf("%s is already ignored %s.", userID.Mention(), scopeLabel(guildID)))
+	}
+
+	c.logger.Info("User ignored",
+		zap.String("guildID", guildID.String()),
+		zap.String("userID", userID.String()),
+		zap.String("moderatorID", e.SenderID().String()))
+
+	return respondEphemeral(s, e, fmt.Sprintf("🔇 %s is now ignored %s.", userID.Mention(), scopeLabel(guildID)))
+}
+
+func (c *AdminCommand) remove(s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, userID discord.UserID) error {
+	removed, err := c.ignored.Remove(guildID, userID)
+	if err != nil {
+		c.logger.Warn("Failed to stop ignoring user", zap.Error(err), zap.String("guildID", guildID.String()), zap.String("userID", userID.String()))
+
+		return respondEphemeral(s, e, "❌ Could not update the ignore list: "+err.Error())
+	}
+	if !removed {
+		return respondEphemeral(s, e, fmt.Sprintf("%s is not ignored %s.", userID.Mention(), scopeLabel(guildID)))
+	}
+
+	c.logger.Info("User no longer ignored",
+		zap.String("guildID", guildID.String()),
+		zap.String("userID", userID.String()),
+		zap.String("moderatorID", e.SenderID().String()))
+
+	return respondEphemeral(s, e, fmt.Sprintf("🔊 %s is no longer ignored %s.", userID.Mention(), scopeLabel(guildID)))
+}
+
+func (c *AdminCommand) list(s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID) error {
+	users := c.ignored.List(guildID)
+	if len(users) == 0 {
+		return respondEphemeral(s, e, fmt.Sprintf("No users are ignored %s.", scopeLabel(guildID)))
+	}
+
+	mentions := make([]string, len(users))
+	for i, userID := range users {
+		mentions[i] = userID.Mention()
+	}
+
+	return respondEphemeral(s, e, fmt.Sprintf("🔇 **Ignored %s (%d)**\n%s", scopeLabel(guildID), len(users), strings.Join(mentions, "\n")))
+}
+
+// resume lifts a loop pause in the channel the command was used in.
+func (c *AdminCommand) resume(s *session.Session, e *gateway.InteractionCreateEvent) error {
+	if !c.loops.Resume(e.ChannelID) {
+		return respondEphemeral(s, e, "Replies are not paused in this channel.")
+	}
+
+	c.logger.Info("Loop pause lifted",
+		zap.String("guildID", e.GuildID.String()),
+		zap.String("channelID", e.ChannelID.String()),
+		zap.String("moderatorID", e.SenderID().String()))
+
+	return respondEphemeral(s, e, "▶️ Replies in this channel are resumed.")
+}
+
+// delivery lists or retries the undelivered replies. Server managers see
+// their server's replies; bot operators see every server's.
+func (c *AdminCommand) delivery(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, sub discord.CommandInteractionOption) error {
+	guildID := e.GuildID
+	if moderation.IsAdmin(c.cfg, e.SenderID()) {
+		guildID = discord.NullGuildID
+	} else if !guildID.IsValid() {
+		return respondEphemeral(s, e, "Undelivered replies can only be managed in servers.")
+	}
+
+	switch sub.Name {
+	case "list":
+		return c.listDeadLetters(s, e, guildID)
+	case "retry":
+	default:
+		return fmt.Errorf("unknown admin delivery subcommand %q", sub.Name)
+	}
+
+	var id string
+	var channelID discord.ChannelID
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "id":
+			id = strings.TrimSpace(opt.String())
+		case "channel":
+			sf, err := opt.SnowflakeValue()
+			if err != nil {
+				return fmt.Errorf("invalid channel option: %w", err)
+			}
+			channelID = discord.ChannelID(sf)
+		}
+	}
+
+	letter, err := c.deadLetters.Get(ctx, id)
+	if errors.Is(err, chat.ErrDeadLetterNotFound) || (err == nil && guildID.IsValid() && letter.GuildID != guildID) {
+		return respondEphemeral(s, e, fmt.Sprintf("No undelivered reply has the ID `%s`.", id))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Long replies are posted in several parts, which can take longer than
+	// the initial response window
+	err = s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.DeferredMessageInteractionWithSource,
+		Data: &api.InteractionResponseData{Flags: discord.EphemeralMessage},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to defer admin delivery response: %w", err)
+	}
+
+	var content string
+	if _, err := c.chatService.RetryDeadLetter(ctx, id, channelID); err != nil {
+		c.logger.Warn("Failed to retry dead letter", zap.Error(err), zap.String("id", id))
+		content = "❌ The reply still could not be posted: " + err.Error()
+	} else {
+		if !channelID.IsValid() {
+			channelID = letter.ChannelID
+		}
+		c.logger.Info("Dead letter retried",
+			zap.String("id", id),
+			zap.String("channelID", channelID.String()),
+			zap.String("moderatorID", e.SenderID().String()))
+		content = fmt.Sprintf("📬 The reply was posted in %s.", channelID.Mention())
+	}
+
+	_, err = s.EditInteractionResponse(e.AppID, e.Token, api.EditInteractionResponseData{
+		Content:         option.NewNullableString(content),
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send admin delivery result: %w", err)
+	}
+
+	return nil
+}
+
+// backup attaches a backup of the bot's data for a bot operator. Backups
+// hold every server's data, so server managers cannot take them.
+func (c *AdminCommand) backup(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent) error {
+	if !moderation.IsAdmin(c.cfg, e.SenderID()) {
+		metrics.MarkDropped(ctx, metrics.DropPermission)
+
+		return respondEphemeral(s, e, "Only bot operators can back up the bot's data.")
+	}
+
+	// Reading the archive can take longer than the initial response window
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.DeferredMessageInteractionWithSource,
+		Data: &api.InteractionResponseData{Flags: discord.EphemeralMessage},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to defer admin backup response: %w", err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := c.backups.Create(ctx, &buf, AppVersion)
+	edit := api.EditInteractionResponseData{AllowedMentions: &api.AllowedMentions{}}
+	switch {
+	case err != nil:
+		c.logger.Warn("Failed to create backup", zap.Error(err))
+		edit.Content = option.NewNullableString("❌ Could not create the backup: " + err.Error())
+	case buf.Len() > maxBackupAttachment:
+		edit.Content = option.NewNullableString(fmt.Sprintf(
+			"The backup is %d MiB, too large to attach. Run `go-discord-chatgpt backup <file>` on the host instead.", buf.Len()>>20))
+	default:
+		c.logger.Info("Backup created",
+			zap.Int("bytes", buf.Len()),
+			zap.Int("entries", len(manifest.Entries)),
+			zap.String("moderatorID", e.SenderID().String()))
+		edit.Content = option.NewNullableString(fmt.Sprintf("💾 Backup of %s. Restore it with `go-discord-chatgpt restore <file>` while the bot is stopped.", manifest.Summary()))
+		edit.Files = []sendpart.File{{
+			Name:   fmt.Sprintf("backup-%s.tar.gz", manifest.CreatedAt.Format("20060102-150405")),
+			Reader: &buf,
+		}}
+	}
+
+	if _, err := s.EditInteractionResponse(e.AppID, e.Token, edit); err != nil {
+		return fmt.Errorf("failed to send admin backup: %w", err)
+	}
+
+	return nil
+}
+
+// permissions lists the command permission rules in effect in the server,
+// marking those a member passes, or checks one command for the member.
+func (c *AdminCommand) permissions(s *session.Session, e *gateway.InteractionCreateEvent, sub discord.CommandInteractionOption) error {
+	if !e.GuildID.IsValid() {
+		return respondEphemeral(s, e, "Command permissions can only be inspected in servers.")
+	}
+
+	member := e.Member
+	var command string
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "command":
+			command = strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(opt.String()), "/")), " ")
+		case "user":
+			sf, err := opt.SnowflakeValue()
+			if err != nil {
+				return fmt.Errorf("invalid user option: %w", err)
+			}
+			if member == nil || member.User.ID != discord.UserID(sf) {
+				if member, err = s.Member(e.GuildID, discord.UserID(sf)); err != nil {
+					return respondEphemeral(s, e, fmt.Sprintf("%s is not a member of this server.", discord.UserID(sf).Mention()))
+				}
+			}
+		}
+	}
+	if member == nil {
+		return errors.New("admin permissions used without a member")
+	}
+
+	rules := c.policy.Rules(e.GuildID)
+	if command != "" {
+		rule, ok := c.policy.Rule(e.GuildID, command)
+		if !ok {
+			return respondEphemeral(s, e, fmt.Sprintf("🔓 No permission rule restricts `/%s` in this server; every member may use it.", command))
+		}
+		rules = []permissions.Rule{rule}
+	}
+	if len(rules) == 0 {
+		return respondEphemeral(s, e, "🔓 No permission rules restrict the bot's commands in this server.")
+	}
+
+	lines := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		path := rule.Command
+		if command != "" {
+			path = command
+		}
+		decision, err := c.policy.Check(s, e.GuildID, member, 0, path)
+		if err != nil {
+			c.logger.Warn("Failed to check command permissions", zap.Error(err), zap.String("command", path))
+
+			return respondEphemeral(s, e, "❌ Could not check the member's permissions: "+err.Error())
+		}
+		mark := "✅"
+		if !decision.Allowed {
+			mark = "❌"
+		}
+		lines = append(lines, fmt.Sprintf("%s `/%s`: %s (%s)", mark, path, describeRule(rule), rule.Source))
+	}
+	content := fmt.Sprintf("🔐 **Command permissions for %s**\n%s", member.User.ID.Mention(), strings.Join(lines, "\n"))
+	if moderation.IsAdmin(c.cfg, member.User.ID) {
+		content += "\nBot operators may use every command."
+	}
+	content = chat.SplitMessage(content)[0]
+
+	return respondEphemeral(s, e, content)
+}
+
+func (c *AdminCommand) listDeadLetters(s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID) error {
+	letters := c.deadLetters.List(guildID)
+	if len(letters) == 0 {
+		return respondEphemeral(s, e, "No replies are waiting to be delivered.")
+	}
+
+	lines := make([]string, 0, len(letters))
+	for _, letter := range letters {
+		lines = append(lines, fmt.Sprintf("`%s` %s <t:%d:R>: %s", letter.ID, letter.ChannelID.Mention(), letter.FailedAt.Unix(), letter.Error))
+	}
+	content := fmt.Sprintf("📮 **Undelivered replies (%d)**\n%s", len(letters), strings.Join(lines, "\n"))
+	// Only the newest replies fit in one response
+	content = chat.SplitMessage(content)[0]
+
+	return respondEphemeral(s, e, content)
+}
+
+// describeRule lists what a rule requires.
+func describeRule(rule permissions.Rule) string {
+	var needs []string
+	if len(rule.RoleIDs) > 0 {
+		roles := make([]string, len(rule.RoleIDs))
+		for i, id := range rule.RoleIDs {
+			roles[i] = id.Mention()
+		}
+		needs = append(needs, "one of "+strings.Join(roles, ", "))
+	}
+	if rule.Permissions != 0 {
+		needs = append(needs, "`"+permissions.Names(rule.Permissions)+"`")
+	}
+	if len(needs) == 0 {
+		return "any member"
+	}
+
+	return strings.Join(needs, " and ")
+}
+
+func scopeLabel(guildID discord.GuildID) string {
+	if guildID == moderation.Global {
+		return "in every server"
+	}
+
+	return "in this server"
+}
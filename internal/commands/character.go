@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+)
+
+// CharacterCommand lets server managers create the characters /chat can answer as.
+type CharacterCommand struct {
+	logger     *zap.Logger
+	characters characters.Store
+}
+
+// NewCharacterCommand creates a new CharacterCommand.
+func NewCharacterCommand(logger *zap.Logger, characterStore characters.Store) Command {
+	return &CharacterCommand{
+		logger:     logger.Named("character_command"),
+		characters: characterStore,
+	}
+}
+
+// Name returns the name of the command.
+func (c *CharacterCommand) Name() string {
+	return "character"
+}
+
+// Description returns the description of the command.
+func (c *CharacterCommand) Description() string {
+	return "Manage the characters /chat can answer as"
+}
+
+// DefaultMemberPermissions restricts the command to server managers by default.
+func (c *CharacterCommand) DefaultMemberPermissions() discord.Permissions {
+	return discord.PermissionManageGuild
+}
+
+// Options returns the create, delete and list subcommands.
+func (c *CharacterCommand) Options() []discord.CommandOption {
+	return []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "create",
+			Description: "Create or replace a character",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "Name the character posts under",
+					Required:    true,
+					MaxLength:   option.NewInt(characters.MaxNameLength),
+				},
+				&discord.StringOption{
+					OptionName:  "personality",
+					Description: "Who the character is and how it talks",
+					Required:    true,
+					MaxLength:   option.NewInt(characters.MaxPersonalityLength),
+				},
+				&discord.StringOption{
+					OptionName:  "avatar_url",
+					Description: "Image URL used as the character's avatar (optional)",
+				},
+				&discord.StringOption{
+					OptionName:  "example_dialogue",
+					Description: "Sample lines showing how the character speaks (optional)",
+					MaxLength:   option.NewInt(characters.MaxExampleDialogueLength),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "delete",
+			Description: "Delete a character",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "Name of the character to delete",
+					Required:    true,
+					MaxLength:   option.NewInt(characters.MaxNameLength),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List this server's characters",
+		},
+	}
+}
+
+// Execute runs the selected subcommand.
+func (c *CharacterCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if !e.GuildID.IsValid() {
+		return respondEphemeral(s, e, "Characters can only be managed in servers.")
+	}
+	if len(data.Options) == 0 {
+		return errors.New("character subcommand is missing")
+	}
+
+	sub := data.Options[0]
+	values := make(map[string]string, len(sub.Options))
+	for _, opt := range sub.Options {
+		values[opt.Name] = opt.String()
+	}
+
+	switch sub.Name {
+	case "create":
+		return c.create(s, e, characters.Character{
+			Name:            values["name"],
+			Personality:     values["personality"],
+			AvatarURL:       values["avatar_url"],
+			ExampleDialogue: values["example_dialogue"],
+		})
+	case "delete":
+		return c.delete(s, e, values["name"])
+	case "list":
+		return c.list(s, e)
+	default:
+		return fmt.Errorf("unknown character subcommand %q", sub.Name)
+	}
+}
+
+func (c *CharacterCommand) create(s *session.Session, e *gateway.InteractionCreateEvent, character characters.Character) error {
+	_, existed := c.characters.Get(e.GuildID, character.Name)
+	if err := c.characters.Save(e.GuildID, character); err != nil {
+		c.logger.Warn("Failed to save character",
+			zap.Error(err),
+			zap.String("guildID", e.GuildID.String()),
+			zap.String("character", character.Name))
+
+		return respondEphemeral(s, e, "❌ Could not save the character: "+err.Error())
+	}
+
+	c.logger.Info("Character saved",
+		zap.String("guildID", e.GuildID.String()),
+		zap.String("character", character.Name),
+		zap.String("userID", e.SenderID().String()))
+
+	verb := "created"
+	if existed {
+		verb = "updated"
+	}
+
+	return respondEphemeral(s, e, fmt.Sprintf("🎭 Character **%s** %s. Use `/chat as:%s` to talk to it.",
+		strings.TrimSpace(character.Name), verb, strings.TrimSpace(character.Name)))
+}
+
+func (c *CharacterCommand) delete(s *session.Session, e *gateway.InteractionCreateEvent, name string) error {
+	deleted, err := c.characters.Delete(e.GuildID, name)
+	if err != nil {
+		c.logger.Warn("Failed to delete character",
+			zap.Error(err),
+			zap.String("guildID", e.GuildID.String()),
+			zap.String("character", name))
+
+		return respondEphemeral(s, e, "❌ Could not delete the character: "+err.Error())
+	}
+	if !deleted {
+		return respondEphemeral(s, e, fmt.Sprintf("There is no character named %q.", name))
+	}
+
+	c.logger.Info("Character deleted", zap.String("guildID", e.GuildID.String()), zap.String("character", name))
+
+	return respondEphemeral(s, e, fmt.Sprintf("🗑️ Character **%s** deleted. Existing threads continue as the bot.", name))
+}
+
+func (c *CharacterCommand) list(s *session.Session, e *gateway.InteractionCreateEvent) error {
+	list := c.characters.List(e.GuildID)
+	if len(list) == 0 {
+		return respondEphemeral(s, e, "This server has no characters yet. Create one with `/character create`.")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🎭 **Characters (%d/%d)**\n", len(list), characters.MaxPerGuild)
+	for _, ch := range list {
+		fmt.Fprintf(&sb, "• **%s**: %s\n", ch.Name, summarize(ch.Personality, 80))
+	}
+
+	return respondEphemeral(s, e, strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// summarize shortens text to a single line of at most limit runes.
+func summarize(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-1]) + "…"
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -14,8 +15,10 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
 	"github.com/Raikerian/go-discord-chatgpt/internal/chat" // Import the new chat service package
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
 )
 
 // ChatCommand handles the /chat command logic.
@@ -24,15 +27,20 @@ type ChatCommand struct {
 	logger      *zap.Logger
 	cfg         *config.Config // Retained for model list in Options()
 	chatService *chat.Service
+	characters  characters.Store
+	pricing     pkgopenai.PricingService // Model metadata for the model choices
 }
 
 // NewChatCommand creates a new ChatCommand.
-// It requires a logger, config, and the chat.Service.
-func NewChatCommand(logger *zap.Logger, cfg *config.Config, chatService *chat.Service) Command {
+// It requires a logger, config, the chat.Service, the guilds' characters and
+// the model metadata.
+func NewChatCommand(logger *zap.Logger, cfg *config.Config, chatService *chat.Service, characterStore characters.Store, pricing pkgopenai.PricingService) Command {
 	return &ChatCommand{
 		logger:      logger.Named("chat_command"),
 		cfg:         cfg,
 		chatService: chatService,
+		characters:  characterStore,
+		pricing:     pricing,
 	}
 }
 
@@ -46,9 +54,15 @@ func (c *ChatCommand) Description() string {
 	return "Starts a chat session with ChatGPT."
 }
 
+// UserInstallable reports that the command works for user installs.
+func (c *ChatCommand) UserInstallable() bool {
+	return true
+}
+
 // Options returns the command options for the /chat command.
-// It includes a required "message" option and an optional "model" option
-// if AI models are configured.
+// It includes a required "message" option, an optional "model" option
+// if AI models are configured, an optional "participants" thread policy, an
+// optional "as" character and an optional "seed" for reproducible answers.
 func (c *ChatCommand) Options() []discord.CommandOption {
 	baseOptions := []discord.CommandOption{
 		&discord.StringOption{
@@ -59,42 +73,71 @@ func (c *ChatCommand) Options() []discord.CommandOption {
 	}
 
 	// Model options are still determined here based on config
-	if c.cfg != nil && len(c.cfg.OpenAI.Models) > 0 {
-		modelChoices := make([]discord.StringChoice, len(c.cfg.OpenAI.Models))
-		for i, modelName := range c.cfg.OpenAI.Models {
-			modelChoices[i] = discord.StringChoice{
-				Name:  modelName,
-				Value: modelName,
-			}
-		}
-
+	if c.cfg != nil && len(c.cfg.ChatModels()) > 0 {
 		baseOptions = append(baseOptions, &discord.StringOption{
 			OptionName:  "model",
 			Description: "Specific AI model to use (optional, defaults to first configured model)",
 			Required:    false,
-			Choices:     modelChoices,
+			Choices:     modelChoices(c.cfg.ChatModels(), c.pricing),
 		})
 	}
 
+	baseOptions = append(baseOptions, &discord.StringOption{
+		OptionName:  "participants",
+		Description: "Who may continue the conversation in the thread (optional, defaults to server setting)",
+		Choices: []discord.StringChoice{
+			{Name: "Anyone", Value: string(chat.ThreadPolicyAnyone)},
+			{Name: "Only me", Value: string(chat.ThreadPolicyInitiator)},
+			{Name: "Me and allowed roles", Value: string(chat.ThreadPolicyRoles)},
+		},
+	})
+
+	// Characters differ per guild, so the name is typed rather than picked from choices
+	baseOptions = append(baseOptions, &discord.StringOption{
+		OptionName:  "as",
+		Description: "Name of a server character to answer as (optional, see /character list)",
+		MaxLength:   option.NewInt(characters.MaxNameLength),
+	})
+
+	baseOptions = append(baseOptions, &discord.IntegerOption{
+		OptionName:  "seed",
+		Description: "Fixed seed to reproduce answers when debugging (optional)",
+		Min:         option.NewInt(0),
+	})
+
 	return baseOptions
 }
 
 // Execute handles the execution of the /chat command.
 // It parses options and then calls the chat.Service to handle the core logic.
 func (c *ChatCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	// Member is only set in guilds; user-installed DM interactions carry User instead
+	user := e.Sender()
 	c.logger.Info("Chat command execution initiated",
-		zap.String("user", e.Member.User.Username),
-		zap.String("userID", e.Member.User.ID.String()),
+		zap.String("user", user.Username),
+		zap.String("userID", user.ID.String()),
 	)
 
 	// 1. Parse options
-	var userPrompt, modelOption string
+	var userPrompt, modelOption, policyOption, characterOption string
+	var seedOption *int
 	for _, opt := range data.Options {
 		switch opt.Name {
 		case "message":
 			userPrompt = opt.String()
 		case "model":
 			modelOption = opt.String()
+		case "participants":
+			policyOption = opt.String()
+		case "as":
+			characterOption = opt.String()
+		case "seed":
+			seed, err := opt.IntValue()
+			if err != nil {
+				return fmt.Errorf("invalid seed option: %w", err)
+			}
+			value := int(seed)
+			seedOption = &value
 		}
 	}
 
@@ -117,7 +160,7 @@ func (c *ChatCommand) Execute(ctx context.Context, s *session.Session, e *gatewa
 	}
 
 	// 3. Validate model configuration (initial validation before calling service)
-	if len(c.cfg.OpenAI.Models) == 0 {
+	if len(c.cfg.ChatModels()) == 0 {
 		c.logger.Error("No OpenAI models configured")
 		errMsg := "Error: No AI models are configured. Please contact an administrator."
 		resp := api.InteractionResponse{
@@ -134,9 +177,33 @@ func (c *ChatCommand) Execute(ctx context.Context, s *session.Session, e *gatewa
 		return errors.New("no openai models configured") // Return error to stop further processing
 	}
 
-	// 4. Delegate to the chat service
+	// 4. Resolve the character to answer as
+	var character *characters.Character
+	if characterOption != "" {
+		found, errMsg := findCharacter(c.characters, e.GuildID, characterOption)
+		if errMsg != "" {
+			resp := api.InteractionResponse{
+				Type: api.MessageInteractionWithSource,
+				Data: &api.InteractionResponseData{
+					Content: option.NewNullableString(errMsg),
+					Flags:   discord.EphemeralMessage,
+				},
+			}
+			if err := s.RespondInteraction(e.ID, e.Token, resp); err != nil {
+				c.logger.Error("Failed to send ephemeral error for unknown character", zap.Error(err))
+			}
+
+			return fmt.Errorf("unknown character %q", characterOption)
+		}
+		character = &found
+	}
+
+	// 5. Delegate to the chat service
 	// The service will handle the rest: creating thread, calling OpenAI, sending messages, caching.
-	err := c.chatService.HandleChatInteraction(ctx, e, userPrompt, modelOption)
+	if seedOption != nil {
+		ctx = chat.WithSeed(ctx, *seedOption)
+	}
+	err := c.chatService.HandleChatInteraction(ctx, e, userPrompt, modelOption, policyOption, character)
 	if err != nil {
 		// The service itself logs detailed errors.
 		// The service also attempts to inform the user in the thread if possible.
@@ -156,7 +223,53 @@ func (c *ChatCommand) Execute(ctx context.Context, s *session.Session, e *gatewa
 		return fmt.Errorf("chat interaction failed: %w", err)
 	}
 
-	c.logger.Info("Chat command execution successfully delegated to chat service", zap.String("user", e.Member.User.Username))
+	c.logger.Info("Chat command execution successfully delegated to chat service", zap.String("user", user.Username))
 
 	return nil
 }
+
+// HandleComponent handles the Continue button on cost ceiling notices, and
+// the pin and suggested question buttons on replies in /chat threads.
+func (c *ChatCommand) HandleComponent(ctx context.Context, _ *session.Session, e *gateway.InteractionCreateEvent, data discord.ComponentInteraction) error {
+	switch id := data.ID(); {
+	case id == chat.ContinueSpendingComponentID:
+		if err := c.chatService.HandleContinueSpending(ctx, e); err != nil {
+			return fmt.Errorf("failed to continue spending: %w", err)
+		}
+	case id == chat.PinAnswerComponentID:
+		if err := c.chatService.HandlePinAnswer(ctx, e); err != nil {
+			return fmt.Errorf("failed to pin answer: %w", err)
+		}
+	case strings.HasPrefix(string(id), chat.SuggestionComponentPrefix):
+		if err := c.chatService.HandleSuggestion(ctx, e, id); err != nil {
+			return fmt.Errorf("failed to ask suggested question: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown chat component: %s", data.ID())
+	}
+
+	return nil
+}
+
+// findCharacter looks up a character of guildID. If there is none, it returns
+// a message for the user listing the available characters.
+func findCharacter(store characters.Store, guildID discord.GuildID, name string) (characters.Character, string) {
+	if !guildID.IsValid() {
+		return characters.Character{}, "Characters are only available in servers."
+	}
+
+	if character, ok := store.Get(guildID, name); ok {
+		return character, ""
+	}
+
+	available := store.List(guildID)
+	if len(available) == 0 {
+		return characters.Character{}, fmt.Sprintf("There is no character named %q. This server has no characters yet; create one with /character create.", name)
+	}
+	names := make([]string, len(available))
+	for i, ch := range available {
+		names[i] = ch.Name
+	}
+
+	return characters.Character{}, fmt.Sprintf("There is no character named %q. Available characters: %s", name, strings.Join(names, ", "))
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"strings"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
@@ -15,3 +16,43 @@ type Command interface {
 	Options() []discord.CommandOption
 	Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error
 }
+
+// ComponentHandler is implemented by commands that attach message components
+// (such as buttons) to their responses. Component custom IDs must be prefixed
+// with the owning command's name and a colon, e.g. "voice:go", so that
+// interactions can be routed back to the command.
+type ComponentHandler interface {
+	HandleComponent(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data discord.ComponentInteraction) error
+}
+
+// UserInstallable is implemented by commands that may be used when the app is
+// installed to a user account, including from DMs and servers the bot has not
+// joined. Such commands must not assume e.Member or guild state is available.
+type UserInstallable interface {
+	UserInstallable() bool
+}
+
+// PermissionRestricted is implemented by commands that only members with the
+// returned permissions may use by default. Server admins can still change who
+// may use them in the server's integration settings.
+type PermissionRestricted interface {
+	DefaultMemberPermissions() discord.Permissions
+}
+
+// PermissionRequirer is implemented by commands that members need the returned
+// permissions to use. Unlike DefaultMemberPermissions, the CommandManager
+// checks them before the command runs, and only the bot's permissions config
+// can replace them.
+type PermissionRequirer interface {
+	RequiredPermissions() discord.Permissions
+}
+
+// ComponentID builds a custom ID routed to the named command.
+func ComponentID(commandName, action string) discord.ComponentID {
+	return discord.ComponentID(commandName + ":" + action)
+}
+
+// ParseComponentID splits a custom ID built by ComponentID into its command name and action.
+func ParseComponentID(id discord.ComponentID) (commandName, action string, ok bool) {
+	return strings.Cut(string(id), ":")
+}
@@ -1,12 +1,30 @@
 package commands
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
 
+	"github.com/sashabaranov/go-openai"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/i18n"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	"github.com/Raikerian/go-discord-chatgpt/internal/permissions"
 )
 
 // CommandManager handles the registration of slash commands with Discord.
@@ -14,6 +32,11 @@ type CommandManager struct {
 	session       *session.Session
 	applicationID discord.AppID
 	logger        *zap.Logger
+	catalog       *i18n.Catalog
+	userInstall   config.UserInstallConfig
+	metrics       *metrics.Metrics
+	funnel        *metrics.Funnel
+	policy        *permissions.Policy
 	commandMap    map[string]Command // Internal map to store commands
 }
 
@@ -23,7 +46,13 @@ type CommandManagerParams struct {
 	Session       *session.Session
 	ApplicationID discord.AppID
 	Logger        *zap.Logger
-	Commands      []Command `group:"commands"` // Injected by Fx
+	Commands      []Command        `group:"commands"` // Injected by Fx
+	Catalog       *i18n.Catalog    `optional:"true"`  // Translations for command names and descriptions
+	Config        *config.Config   `optional:"true"`  // Supplies user install settings
+	Metrics       *metrics.Metrics `optional:"true"`  // Records invocations and their duration
+	Funnel        *metrics.Funnel  `optional:"true"`  // Counts interactions that got no response, and why
+	// Policy is checked before commands run, when it restricts them.
+	Policy *permissions.Policy `optional:"true"`
 }
 
 // NewCommandManager creates a new CommandManager.
@@ -37,8 +66,15 @@ func NewCommandManager(params CommandManagerParams) *CommandManager {
 		session:       params.Session,
 		applicationID: params.ApplicationID,
 		logger:        params.Logger,
+		catalog:       params.Catalog,
+		metrics:       params.Metrics,
+		funnel:        params.Funnel,
+		policy:        params.Policy,
 		commandMap:    make(map[string]Command),
 	}
+	if params.Config != nil {
+		cm.userInstall = params.Config.Discord.UserInstall
+	}
 
 	for _, cmd := range params.Commands {
 		if cmd == nil {
@@ -52,6 +88,9 @@ func NewCommandManager(params CommandManagerParams) *CommandManager {
 			continue
 		}
 		cm.commandMap[cmd.Name()] = cmd
+		if pr, ok := cmd.(PermissionRequirer); ok {
+			cm.policy.Declare(cmd.Name(), pr.RequiredPermissions())
+		}
 		params.Logger.Debug("Loaded command via Fx", zap.String("commandName", cmd.Name()))
 	}
 	params.Logger.Info("CommandManager created", zap.Int("numberOfCommandsLoaded", len(cm.commandMap)))
@@ -64,34 +103,217 @@ func (cm *CommandManager) GetCommand(name string) (Command, bool) {
 	cmd, ok := cm.commandMap[name]
 	if !ok {
 		cm.logger.Warn("Attempted to get unknown command", zap.String("commandName", name))
+
+		return nil, false
+	}
+	if cm.policy.Restricts(name) {
+		cmd = guardedCommand{Command: cmd, policy: cm.policy, logger: cm.logger}
+	}
+	if cm.metrics != nil || cm.funnel != nil {
+		return observedCommand{Command: cmd, metrics: cm.metrics, funnel: cm.funnel}, true
+	}
+
+	return cmd, true
+}
+
+// GetComponentHandler returns the command that owns a component custom ID, if it handles components.
+func (cm *CommandManager) GetComponentHandler(customID discord.ComponentID) (ComponentHandler, bool) {
+	name, _, ok := ParseComponentID(customID)
+	if !ok {
+		return nil, false
+	}
+
+	cmd, ok := cm.commandMap[name]
+	if !ok {
+		return nil, false
+	}
+
+	handler, ok := cmd.(ComponentHandler)
+	if ok && cm.policy.Restricts(name) {
+		handler = guardedComponentHandler{ComponentHandler: handler, name: name, policy: cm.policy, logger: cm.logger}
+	}
+	if ok && (cm.metrics != nil || cm.funnel != nil) {
+		return observedComponentHandler{ComponentHandler: handler, name: name, metrics: cm.metrics, funnel: cm.funnel}, true
+	}
+
+	return handler, ok
+}
+
+// RecordDropped counts an interaction turned away before reaching its
+// command, such as one from an ignored user, in the funnel.
+func (cm *CommandManager) RecordDropped(data discord.InteractionData, reason string) {
+	switch data := data.(type) {
+	case *discord.CommandInteraction:
+		cm.funnel.Record(data.Name, reason)
+	case discord.ComponentInteraction:
+		if name, _, ok := ParseComponentID(data.ID()); ok {
+			cm.funnel.Record(name, reason)
+		}
+	}
+}
+
+// guardedCommand runs a command only for members its permission rule allows.
+type guardedCommand struct {
+	Command
+	policy *permissions.Policy
+	logger *zap.Logger
+}
+
+func (c guardedCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if allowed, err := checkPermissions(ctx, s, e, c.policy, c.logger, permissions.Path(data)); !allowed {
+		return err
+	}
+
+	return c.Command.Execute(ctx, s, e, data)
+}
+
+// guardedComponentHandler handles the components of the named command only
+// for members allowed to use the command.
+type guardedComponentHandler struct {
+	ComponentHandler
+	name   string
+	policy *permissions.Policy
+	logger *zap.Logger
+}
+
+func (h guardedComponentHandler) HandleComponent(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data discord.ComponentInteraction) error {
+	if allowed, err := checkPermissions(ctx, s, e, h.policy, h.logger, h.name); !allowed {
+		return err
+	}
+
+	return h.ComponentHandler.HandleComponent(ctx, s, e, data)
+}
+
+// checkPermissions checks the sender of e against the rule for path,
+// answering with what they lack when they may not use it.
+func checkPermissions(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, policy *permissions.Policy, logger *zap.Logger, path string) (bool, error) {
+	var perms discord.Permissions
+	if e.Channel != nil {
+		perms = e.Channel.SelfPermissions
+	}
+	decision, err := policy.Check(s, e.GuildID, e.Member, perms, path)
+	if err != nil {
+		logger.Warn("Failed to check command permissions", zap.Error(err), zap.String("command", path), zap.String("userID", e.SenderID().String()))
+		err = errors.Join(err, respondEphemeral(s, e, "❌ Could not check your permissions for this command. Please try again."))
+
+		return false, err
+	}
+	if decision.Allowed {
+		return true, nil
+	}
+
+	metrics.MarkDropped(ctx, metrics.DropPermission)
+	logger.Info("Command denied by permissions",
+		zap.String("command", path),
+		zap.String("rule", decision.Rule.Command),
+		zap.String("userID", e.SenderID().String()),
+		zap.String("guildID", e.GuildID.String()))
+
+	return false, respondEphemeral(s, e, decision.Message(e.GuildID, path))
+}
+
+// respondEphemeral replies to the interaction with content only its sender
+// sees. Replies quote user-supplied text, such as prompts and character
+// descriptions, so they ping no one.
+func respondEphemeral(s *session.Session, e *gateway.InteractionCreateEvent, content string) error {
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(content),
+			Flags:           discord.EphemeralMessage,
+			AllowedMentions: &api.AllowedMentions{},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to respond to interaction: %w", err)
+	}
+
+	return nil
+}
+
+// observedCommand records each execution of a command in the metrics and
+// the funnel.
+type observedCommand struct {
+	Command
+	metrics *metrics.Metrics
+	funnel  *metrics.Funnel
+}
+
+func (c observedCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	start := time.Now()
+	ctx = metrics.WithDropMark(ctx)
+	err := c.Command.Execute(ctx, s, e, data)
+	c.metrics.ObserveCommand(c.Name(), "command", time.Since(start), err)
+	c.funnel.Record(c.Name(), funnelOutcome(ctx, err))
+
+	return err
+}
+
+// observedComponentHandler records each component interaction of the named
+// command in the metrics and the funnel.
+type observedComponentHandler struct {
+	ComponentHandler
+	name    string
+	metrics *metrics.Metrics
+	funnel  *metrics.Funnel
+}
+
+func (h observedComponentHandler) HandleComponent(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data discord.ComponentInteraction) error {
+	start := time.Now()
+	ctx = metrics.WithDropMark(ctx)
+	err := h.ComponentHandler.HandleComponent(ctx, s, e, data)
+	h.metrics.ObserveCommand(h.name, "component", time.Since(start), err)
+	h.funnel.Record(h.name, funnelOutcome(ctx, err))
+
+	return err
+}
+
+// funnelOutcome returns the funnel outcome of an interaction handled with
+// ctx: the drop its handler marked, or else where err came from.
+func funnelOutcome(ctx context.Context, err error) string {
+	if reason := metrics.Dropped(ctx); reason != "" {
+		return reason
+	}
+	if err == nil {
+		return metrics.Responded
 	}
 
-	return cmd, ok
+	var exhausted *chat.BudgetExhaustedError
+	var unavailable *internalopenai.UnavailableError
+	var apiErr *openai.APIError
+	var requestErr *openai.RequestError
+	var discordErr *httputil.HTTPError
+	switch {
+	case errors.As(err, &exhausted),
+		errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusTooManyRequests,
+		errors.As(err, &requestErr) && requestErr.HTTPStatusCode == http.StatusTooManyRequests:
+		return metrics.DropRateLimit
+	case errors.As(err, &apiErr), errors.As(err, &requestErr), errors.As(err, &unavailable):
+		return metrics.DropOpenAIError
+	case errors.As(err, &discordErr):
+		return metrics.DropDiscordError
+	default:
+		return metrics.DropError
+	}
 }
 
 // RegisterCommands registers all loaded commands with Discord for the specified guilds.
+// When user install is enabled, user-installable commands are always registered
+// globally, since Discord only supports user installs for global commands.
 func (cm *CommandManager) RegisterCommands(guildIDs []discord.GuildID) {
 	cm.logger.Info("Registering slash commands with Discord for specified guilds...", zap.Int("commandCount", len(cm.commandMap)))
-	cmdsToRegister := make([]api.CreateCommandData, 0, len(cm.commandMap))
-	for _, cmd := range cm.commandMap {
-		cmdsToRegister = append(cmdsToRegister, api.CreateCommandData{
-			Name:        cmd.Name(),
-			Description: cmd.Description(),
-			Options:     cmd.Options(),
-		})
-		cm.logger.Debug("Preparing to register command", zap.String("commandName", cmd.Name()))
-	}
+	guildCmds, globalCmds := cm.CommandsToRegister(len(guildIDs) > 0)
 
-	if len(cmdsToRegister) == 0 {
+	if len(guildCmds) == 0 && len(globalCmds) == 0 {
 		cm.logger.Info("No commands to register.")
 
 		return
 	}
 
-	// Global commands registration (if no guildIDs are specified)
-	if len(guildIDs) == 0 {
-		cm.logger.Info("No specific guild IDs provided, attempting to register commands globally.")
-		registered, err := cm.session.BulkOverwriteCommands(cm.applicationID, cmdsToRegister)
+	// Global commands registration (if no guildIDs are specified, or for user installs)
+	if len(guildIDs) == 0 || len(globalCmds) > 0 {
+		cm.logger.Info("Registering commands globally.", zap.Int("count", len(globalCmds)))
+		registered, err := cm.bulkOverwriteCommands(api.EndpointApplications+cm.applicationID.String()+"/commands", globalCmds)
 		if err != nil {
 			cm.logger.Error("Failed to bulk overwrite global commands",
 				zap.Error(err),
@@ -103,13 +325,12 @@ func (cm *CommandManager) RegisterCommands(guildIDs []discord.GuildID) {
 				zap.Stringer("applicationID", cm.applicationID),
 			)
 		}
-
-		return // Exit after attempting global registration
 	}
 
 	// Guild-specific commands registration
 	for _, guildID := range guildIDs {
-		registered, err := cm.session.BulkOverwriteGuildCommands(cm.applicationID, guildID, cmdsToRegister)
+		registered, err := cm.bulkOverwriteCommands(
+			api.EndpointApplications+cm.applicationID.String()+"/guilds/"+guildID.String()+"/commands", guildCmds)
 		if err != nil {
 			cm.logger.Error("Failed to bulk overwrite commands for guild",
 				zap.Error(err),
@@ -127,6 +348,129 @@ func (cm *CommandManager) RegisterCommands(guildIDs []discord.GuildID) {
 	}
 }
 
+// CommandsToRegister splits the loaded commands into those registered per guild
+// and those registered globally, sorted by name. Without guild scoping every
+// command is global.
+func (cm *CommandManager) CommandsToRegister(guildScoped bool) (guildCmds, globalCmds []CommandData) {
+	names := make([]string, 0, len(cm.commandMap))
+	for name := range cm.commandMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cmd := cm.commandMap[name]
+		data := CommandData{CreateCommandData: cm.localizedCommand(cmd)}
+		if pr, ok := cmd.(PermissionRestricted); ok {
+			perms := pr.DefaultMemberPermissions()
+			data.DefaultMemberPermissions = &perms
+		}
+
+		userInstallable := false
+		if ui, ok := cmd.(UserInstallable); ok {
+			userInstallable = ui.UserInstallable()
+		}
+
+		switch {
+		case cm.userInstall.Enabled && userInstallable:
+			data.IntegrationTypes = []IntegrationType{GuildInstall, UserInstall}
+			data.Contexts = []InteractionContextType{ContextGuild, ContextBotDM}
+			if cm.userInstall.GroupDMs {
+				data.Contexts = append(data.Contexts, ContextPrivateChannel)
+			}
+			globalCmds = append(globalCmds, data)
+		case cm.userInstall.Enabled:
+			// Keep guild-only commands out of DMs now that the app can be reached there
+			data.IntegrationTypes = []IntegrationType{GuildInstall}
+			data.Contexts = []InteractionContextType{ContextGuild}
+			fallthrough
+		default:
+			if guildScoped {
+				guildCmds = append(guildCmds, data)
+			} else {
+				globalCmds = append(globalCmds, data)
+			}
+		}
+		cm.logger.Debug("Preparing to register command",
+			zap.String("commandName", name),
+			zap.Bool("userInstall", cm.userInstall.Enabled && userInstallable))
+	}
+
+	return guildCmds, globalCmds
+}
+
+// bulkOverwriteCommands replaces the commands registered at a commands endpoint.
+// It is used instead of the api.Client helpers so that the installation fields
+// of CommandData are sent.
+func (cm *CommandManager) bulkOverwriteCommands(endpoint string, cmds []CommandData) ([]discord.Command, error) {
+	if cmds == nil {
+		cmds = []CommandData{} // An empty array, not null, clears the commands
+	}
+
+	var registered []discord.Command
+
+	return registered, cm.session.RequestJSON(&registered, "PUT", endpoint, httputil.WithJSONBody(cmds))
+}
+
+// localizedCommand builds the registration data for cmd, filling in Discord
+// localization maps from the catalog. Untranslated strings fall back to English.
+func (cm *CommandManager) localizedCommand(cmd Command) api.CreateCommandData {
+	prefix := "commands." + cmd.Name()
+	options := cmd.Options()
+	for _, opt := range options {
+		cm.localizeOption(prefix+".options", opt)
+	}
+
+	return api.CreateCommandData{
+		Name:                     cmd.Name(),
+		NameLocalizations:        cm.catalog.Localizations(prefix + ".name"),
+		Description:              cmd.Description(),
+		DescriptionLocalizations: cm.catalog.Localizations(prefix + ".description"),
+		Options:                  options,
+	}
+}
+
+// localizeOption fills in the localization maps of a command option and its choices.
+func (cm *CommandManager) localizeOption(prefix string, opt discord.CommandOption) {
+	key := prefix + "." + opt.Name()
+	name := cm.catalog.Localizations(key + ".name")
+	description := cm.catalog.Localizations(key + ".description")
+
+	switch o := opt.(type) {
+	case *discord.StringOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+		for i := range o.Choices {
+			o.Choices[i].NameLocalizations = cm.catalog.Localizations(key + ".choices." + o.Choices[i].Value)
+		}
+	case *discord.IntegerOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+	case *discord.NumberOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+	case *discord.BooleanOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+	case *discord.UserOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+	case *discord.ChannelOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+	case *discord.RoleOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+	case *discord.MentionableOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+	case *discord.AttachmentOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+	case *discord.SubcommandOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+		for _, sub := range o.Options {
+			cm.localizeOption(key+".options", sub)
+		}
+	case *discord.SubcommandGroupOption:
+		o.OptionNameLocalizations, o.DescriptionLocalizations = name, description
+		for _, sub := range o.Subcommands {
+			cm.localizeOption(key+".options", sub)
+		}
+	}
+}
+
 // UnregisterAllCommands unregisters all commands for the specified guilds or globally.
 func (cm *CommandManager) UnregisterAllCommands(guildIDs []discord.GuildID) {
 	cm.logger.Info("Unregistering all slash commands...", zap.Stringer("applicationID", cm.applicationID))
@@ -148,6 +492,16 @@ func (cm *CommandManager) UnregisterAllCommands(guildIDs []discord.GuildID) {
 		return // Exit after attempting global unregistration
 	}
 
+	// User-installable commands are global even when guilds are configured
+	if cm.userInstall.Enabled {
+		if _, err := cm.session.BulkOverwriteCommands(cm.applicationID, []api.CreateCommandData{}); err != nil {
+			cm.logger.Error("Failed to unregister global user-installable commands",
+				zap.Error(err),
+				zap.Stringer("applicationID", cm.applicationID),
+			)
+		}
+	}
+
 	// Guild-specific commands unregistration
 	for _, guildID := range guildIDs {
 		_, err := cm.session.BulkOverwriteGuildCommands(cm.applicationID, guildID, []api.CreateCommandData{})
@@ -1,14 +1,21 @@
 package commands_test
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/Raikerian/go-discord-chatgpt/internal/commands"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/permissions"
 	"github.com/Raikerian/go-discord-chatgpt/pkg/test"
 )
 
@@ -124,4 +131,197 @@ func TestNewCommandManager(t *testing.T) {
 		assert.True(t, ok)
 		assert.Equal(t, mockCmd1, retCmd1)
 	})
+
+	t.Run("PermissionPolicy", func(t *testing.T) {
+		open := test.NewMockCommand(t)
+		open.On("Name").Return("ping")
+		restricted := test.NewMockCommand(t)
+		restricted.On("Name").Return("voice")
+
+		policy, err := permissions.NewPolicy(&config.Config{Permissions: config.PermissionsConfig{
+			"voice start": {RoleIDs: []string{"10"}},
+		}})
+		require.NoError(t, err)
+		cm := commands.NewCommandManager(commands.CommandManagerParams{
+			ApplicationID: appID,
+			Logger:        zap.NewNop(),
+			Commands:      []commands.Command{open, restricted},
+			Policy:        policy,
+		})
+
+		cmd, ok := cm.GetCommand("ping")
+		assert.True(t, ok)
+		assert.Equal(t, open, cmd, "commands no rule restricts run unchecked")
+
+		cmd, ok = cm.GetCommand("voice")
+		assert.True(t, ok)
+		assert.NotEqual(t, restricted, cmd, "restricted commands are checked before they run")
+		assert.Equal(t, "voice", cmd.Name())
+	})
+}
+
+// componentCommand is a command that also handles message components.
+type componentCommand struct {
+	*test.MockCommand
+}
+
+func (componentCommand) HandleComponent(context.Context, *session.Session, *gateway.InteractionCreateEvent, discord.ComponentInteraction) error {
+	return nil
+}
+
+func TestGetComponentHandler(t *testing.T) {
+	plainCmd := test.NewMockCommand(t)
+	plainCmd.On("Name").Return("ping")
+
+	buttonMock := test.NewMockCommand(t)
+	buttonMock.On("Name").Return("voice")
+	buttonCmd := componentCommand{buttonMock}
+
+	cm := commands.NewCommandManager(commands.CommandManagerParams{
+		ApplicationID: discord.AppID(12345),
+		Logger:        zap.NewNop(),
+		Commands:      []commands.Command{plainCmd, buttonCmd},
+	})
+
+	handler, ok := cm.GetComponentHandler(commands.ComponentID("voice", "go"))
+	assert.True(t, ok)
+	assert.Equal(t, buttonCmd, handler)
+
+	_, ok = cm.GetComponentHandler(commands.ComponentID("ping", "go"))
+	assert.False(t, ok, "commands without HandleComponent are not component handlers")
+
+	_, ok = cm.GetComponentHandler(commands.ComponentID("missing", "go"))
+	assert.False(t, ok)
+
+	_, ok = cm.GetComponentHandler("no-prefix")
+	assert.False(t, ok)
+}
+
+// userCommand is a command that can be used from user installs.
+type userCommand struct {
+	*test.MockCommand
+}
+
+func (userCommand) UserInstallable() bool {
+	return true
+}
+
+func TestCommandsToRegister(t *testing.T) {
+	newCommands := func(t *testing.T) []commands.Command {
+		t.Helper()
+
+		guildCmd := test.NewMockCommand(t)
+		guildCmd.On("Name").Return("voice")
+		guildCmd.On("Description").Return("Voice")
+		guildCmd.On("Options").Return(nil)
+
+		chatMock := test.NewMockCommand(t)
+		chatMock.On("Name").Return("chat")
+		chatMock.On("Description").Return("Chat")
+		chatMock.On("Options").Return(nil)
+
+		return []commands.Command{guildCmd, userCommand{chatMock}}
+	}
+
+	t.Run("UserInstallDisabled", func(t *testing.T) {
+		cm := commands.NewCommandManager(commands.CommandManagerParams{
+			ApplicationID: discord.AppID(12345),
+			Logger:        zap.NewNop(),
+			Commands:      newCommands(t),
+		})
+
+		guildCmds, globalCmds := cm.CommandsToRegister(true)
+		require.Len(t, guildCmds, 2)
+		assert.Empty(t, globalCmds)
+		assert.Equal(t, "chat", guildCmds[0].Name)
+		assert.Empty(t, guildCmds[0].IntegrationTypes)
+
+		guildCmds, globalCmds = cm.CommandsToRegister(false)
+		assert.Empty(t, guildCmds)
+		assert.Len(t, globalCmds, 2)
+	})
+
+	t.Run("UserInstallEnabled", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.Discord.UserInstall.Enabled = true
+
+		cm := commands.NewCommandManager(commands.CommandManagerParams{
+			ApplicationID: discord.AppID(12345),
+			Logger:        zap.NewNop(),
+			Commands:      newCommands(t),
+			Config:        cfg,
+		})
+
+		guildCmds, globalCmds := cm.CommandsToRegister(true)
+		require.Len(t, guildCmds, 1)
+		require.Len(t, globalCmds, 1)
+
+		assert.Equal(t, "voice", guildCmds[0].Name)
+		assert.Equal(t, []commands.InteractionContextType{commands.ContextGuild}, guildCmds[0].Contexts)
+
+		assert.Equal(t, "chat", globalCmds[0].Name)
+		assert.Equal(t, []commands.IntegrationType{commands.GuildInstall, commands.UserInstall}, globalCmds[0].IntegrationTypes)
+		assert.Equal(t, []commands.InteractionContextType{commands.ContextGuild, commands.ContextBotDM}, globalCmds[0].Contexts)
+	})
+}
+
+func TestCommandDataMarshalJSON(t *testing.T) {
+	data := commands.CommandData{
+		CreateCommandData: api.CreateCommandData{Name: "chat", Description: "Chat"},
+	}
+
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "integration_types")
+	assert.Contains(t, string(raw), `"dm_permission":true`)
+
+	data.IntegrationTypes = []commands.IntegrationType{commands.GuildInstall, commands.UserInstall}
+	data.Contexts = []commands.InteractionContextType{commands.ContextGuild, commands.ContextBotDM, commands.ContextPrivateChannel}
+
+	raw, err = json.Marshal(data)
+	require.NoError(t, err)
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(raw, &fields))
+	assert.Equal(t, "chat", fields["name"])
+	assert.Equal(t, []any{0.0, 1.0}, fields["integration_types"])
+	assert.Equal(t, []any{0.0, 1.0, 2.0}, fields["contexts"])
+	assert.NotContains(t, fields, "dm_permission")
+}
+
+// adminCommand is a command restricted to server managers by default.
+type adminCommand struct {
+	*test.MockCommand
+}
+
+func (adminCommand) DefaultMemberPermissions() discord.Permissions {
+	return discord.PermissionManageGuild
+}
+
+func TestCommandsToRegister_DefaultMemberPermissions(t *testing.T) {
+	adminMock := test.NewMockCommand(t)
+	adminMock.On("Name").Return("character")
+	adminMock.On("Description").Return("Characters")
+	adminMock.On("Options").Return(nil)
+
+	openMock := test.NewMockCommand(t)
+	openMock.On("Name").Return("ping")
+	openMock.On("Description").Return("Ping")
+	openMock.On("Options").Return(nil)
+
+	cm := commands.NewCommandManager(commands.CommandManagerParams{
+		ApplicationID: discord.AppID(12345),
+		Logger:        zap.NewNop(),
+		Commands:      []commands.Command{adminCommand{adminMock}, openMock},
+	})
+
+	guildCmds, _ := cm.CommandsToRegister(true)
+	require.Len(t, guildCmds, 2)
+	require.NotNil(t, guildCmds[0].DefaultMemberPermissions)
+	assert.Equal(t, discord.PermissionManageGuild, *guildCmds[0].DefaultMemberPermissions)
+	assert.Nil(t, guildCmds[1].DefaultMemberPermissions)
+
+	raw, err := json.Marshal(guildCmds[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"default_member_permissions":"32"`)
 }
@@ -0,0 +1,302 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/state"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	"github.com/Raikerian/go-discord-chatgpt/internal/retention"
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+// openAIPingTimeout bounds the OpenAI check, so a hung API still gets a report.
+const openAIPingTimeout = 10 * time.Second
+
+// Embed colors of the diagnostics report.
+const (
+	diagnosticsHealthy  = 0x57F287
+	diagnosticsDegraded = 0xED4245
+)
+
+// DiagnosticsCommand gives server owners a health snapshot of the bot in
+// their server: gateway latency, the voice session, OpenAI, the caches, the
+// retention purges and the effective configuration, with secrets redacted.
+type DiagnosticsCommand struct {
+	logger        *zap.Logger
+	cfg           *config.Config
+	state         *state.State
+	keys          internalopenai.KeyResolver
+	conversations chat.ConversationStore
+	voiceService  *voice.Service
+	retention     *retention.Job
+}
+
+// NewDiagnosticsCommand creates a new DiagnosticsCommand.
+func NewDiagnosticsCommand(
+	logger *zap.Logger,
+	cfg *config.Config,
+	st *state.State,
+	keys internalopenai.KeyResolver,
+	conversations chat.ConversationStore,
+	voiceService *voice.Service,
+	retentionJob *retention.Job,
+) Command {
+	return &DiagnosticsCommand{
+		logger:        logger.Named("diagnostics_command"),
+		cfg:           cfg,
+		state:         st,
+		keys:          keys,
+		conversations: conversations,
+		voiceService:  voiceService,
+		retention:     retentionJob,
+	}
+}
+
+// Name returns the name of the command.
+func (c *DiagnosticsCommand) Name() string {
+	return "diagnostics"
+}
+
+// Description returns the description of the command.
+func (c *DiagnosticsCommand) Description() string {
+	return "Check the bot's health in this server (server owner only)"
+}
+
+// DefaultMemberPermissions hides the command from members who are not
+// administrators; Execute further limits it to the server owner.
+func (c *DiagnosticsCommand) DefaultMemberPermissions() discord.Permissions {
+	return discord.PermissionAdministrator
+}
+
+// Options returns the command options.
+func (c *DiagnosticsCommand) Options() []discord.CommandOption {
+	return nil
+}
+
+// Execute runs the checks and replies with the report.
+func (c *DiagnosticsCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if !e.GuildID.IsValid() {
+		return respondEphemeral(s, e, "Diagnostics can only be run in servers.")
+	}
+	if !c.isOwner(e.GuildID, e.SenderID()) {
+		metrics.MarkDropped(ctx, metrics.DropPermission)
+
+		return respondEphemeral(s, e, "Only the server owner can run diagnostics.")
+	}
+
+	// The OpenAI check can take a while
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.DeferredMessageInteractionWithSource,
+		Data: &api.InteractionResponseData{Flags: discord.EphemeralMessage},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to defer diagnostics response: %w", err)
+	}
+
+	healthy := true
+	check := func(ok bool, value string) string {
+		if !ok {
+			healthy = false
+
+			return "❌ " + value
+		}
+
+		return "✅ " + value
+	}
+
+	fields := []discord.EmbedField{
+		{Name: "Discord gateway", Value: c.gatewayReport(s, check), Inline: true},
+		{Name: "OpenAI", Value: c.openAIReport(ctx, e.GuildID, check), Inline: true},
+		{Name: "Voice", Value: c.voiceReport(e.GuildID, check)},
+		{Name: "Caches", Value: c.cacheReport()},
+	}
+	if c.retention.Enabled() {
+		fields = append(fields, discord.EmbedField{Name: "Retention", Value: c.retentionReport(check)})
+	}
+	fields = append(fields, discord.EmbedField{Name: "Configuration", Value: c.configReport(e.GuildID)})
+	color := discord.Color(diagnosticsHealthy)
+	if !healthy {
+		color = diagnosticsDegraded
+	}
+
+	c.logger.Info("Diagnostics run",
+		zap.String("guildID", e.GuildID.String()),
+		zap.String("userID", e.SenderID().String()),
+		zap.Bool("healthy", healthy))
+
+	_, err = s.EditInteractionResponse(e.AppID, e.Token, api.EditInteractionResponseData{
+		Embeds: &[]discord.Embed{{
+			Title:     "🩺 Diagnostics",
+			Fields:    fields,
+			Color:     color,
+			Timestamp: discord.NowTimestamp(),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send diagnostics report: %w", err)
+	}
+
+	return nil
+}
+
+// isOwner reports whether userID owns guildID or operates the bot.
+func (c *DiagnosticsCommand) isOwner(guildID discord.GuildID, userID discord.UserID) bool {
+	if moderation.IsAdmin(c.cfg, userID) {
+		return true
+	}
+	guild, err := c.state.Guild(guildID)
+	if err != nil {
+		c.logger.Warn("Failed to look up guild owner", zap.Error(err), zap.String("guildID", guildID.String()))
+
+		return false
+	}
+
+	return guild.OwnerID == userID
+}
+
+func (c *DiagnosticsCommand) gatewayReport(s *session.Session, check func(bool, string) string) string {
+	if !s.GatewayIsAlive() {
+		return check(false, "Disconnected")
+	}
+	latency := s.Gateway().Latency()
+	if latency <= 0 {
+		return check(true, "Connected, no heartbeat yet")
+	}
+
+	return check(true, fmt.Sprintf("%d ms heartbeat", latency.Milliseconds()))
+}
+
+// openAIReport lists the models with the guild's credentials, timing the
+// round trip.
+func (c *DiagnosticsCommand) openAIReport(ctx context.Context, guildID discord.GuildID, check func(bool, string) string) string {
+	ctx, cancel := context.WithTimeout(ctx, openAIPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.keys.Client(guildID).ListModels(ctx)
+	elapsed := time.Since(start)
+	if err != nil {
+		c.logger.Warn("OpenAI diagnostics check failed", zap.Error(err), zap.String("guildID", guildID.String()))
+
+		return check(false, "Unreachable: "+summarize(err.Error(), 200))
+	}
+
+	return check(true, fmt.Sprintf("%d ms to list models", elapsed.Milliseconds()))
+}
+
+func (c *DiagnosticsCommand) voiceReport(guildID discord.GuildID, check func(bool, string) string) string {
+	if reason := c.voiceService.DisabledReason(); reason != "" {
+		return check(false, "Disabled: "+reason)
+	}
+
+	status, err := c.voiceService.GetStatus(guildID)
+	if err != nil || !status.Active {
+		return "No session in this server"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(check(status.State == voice.SessionStateActive, fmt.Sprintf("Session %s in <#%s>", status.State, status.ChannelID)))
+	fmt.Fprintf(&sb, "\nModel %s, %d listening, running %s, $%.4f so far",
+		status.Model, len(status.ActiveUsers), time.Since(status.StartTime).Round(time.Second), status.SessionCost)
+	if status.Metrics.Responses > 0 {
+		fmt.Fprintf(&sb, "\n%d responses, %d ms average latency", status.Metrics.Responses, status.Metrics.LatencyAvg.Milliseconds())
+	}
+
+	return sb.String()
+}
+
+func (c *DiagnosticsCommand) cacheReport() string {
+	stats := c.conversations.Stats()
+
+	return fmt.Sprintf("Conversations: %d/%d\nIgnored threads: %d/%d",
+		stats.Conversations, stats.ConversationsSize, stats.IgnoredThreads, stats.IgnoredThreadsSize)
+}
+
+// retentionReport lists the records purged of each data type since startup,
+// across every server.
+func (c *DiagnosticsCommand) retentionReport(check func(bool, string) string) string {
+	stats := c.retention.Stats()
+	if len(stats) == 0 {
+		return "No purge has run yet"
+	}
+
+	lines := make([]string, 0, len(stats))
+	for _, st := range stats {
+		line := fmt.Sprintf("%s: %d purged, %d in the last run %s ago",
+			st.DataType, st.Purged, st.LastPurged, time.Since(st.LastRun).Round(time.Minute))
+		if st.LastError != nil {
+			line += ", failed: " + summarize(st.LastError.Error(), 100)
+		}
+		lines = append(lines, check(st.LastError == nil, line))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// configReport summarizes the configuration that applies to guildID. Keys
+// are redacted; only whether they are set, and whose, is shown.
+func (c *DiagnosticsCommand) configReport(guildID discord.GuildID) string {
+	guild := c.cfg.Guild(guildID.String())
+	creds := c.keys.Credentials(guildID)
+	userBudget, guildBudget := c.cfg.BudgetLimits(guildID.String())
+	keyOwner := "global"
+	if guild.OpenAI.APIKey != "" {
+		keyOwner = "this server's"
+	}
+
+	lines := []string{
+		fmt.Sprintf("OpenAI key: %s (%s)", redactSecret(creds.APIKey), keyOwner),
+		"Models: " + strings.Join(c.cfg.ChatModels(), ", "),
+		fmt.Sprintf("Concurrent requests: %d (per server %d)", c.cfg.OpenAI.MaxConcurrentRequests, c.cfg.OpenAI.MaxConcurrentRequestsPerGuild),
+		"Voice models: " + strings.Join(c.cfg.Voice.AllowedModels, ", "),
+		fmt.Sprintf("Archive: %s, budgets: %s, outbox: %s",
+			enabledText(c.cfg.Archive.Enabled), enabledText(!userBudget.IsZero() || !guildBudget.IsZero()), enabledText(c.cfg.Outbox.Enabled)),
+	}
+	if creds.Organization != "" || creds.Project != "" {
+		lines = append(lines, fmt.Sprintf("Organization: %s, project: %s", orUnset(creds.Organization), orUnset(creds.Project)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// redactSecret hides all of secret but its last four characters.
+func redactSecret(secret string) string {
+	const shown = 4
+	if secret == "" {
+		return "not set"
+	}
+	if len(secret) <= 3*shown {
+		return "set"
+	}
+
+	return "…" + secret[len(secret)-shown:]
+}
+
+func enabledText(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+
+	return "off"
+}
+
+func orUnset(value string) string {
+	if value == "" {
+		return "not set"
+	}
+
+	return value
+}
@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// maxTurnsOption bounds the turns option; the configured limit usually applies first.
+const maxTurnsOption = 50
+
+// DiscussCommand has several server characters take turns on a prompt.
+type DiscussCommand struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	chatService *chat.Service
+	characters  characters.Store
+	pricing     pkgopenai.PricingService
+}
+
+// NewDiscussCommand creates a new DiscussCommand.
+func NewDiscussCommand(logger *zap.Logger, cfg *config.Config, chatService *chat.Service, characterStore characters.Store, pricing pkgopenai.PricingService) Command {
+	return &DiscussCommand{
+		logger:      logger.Named("discuss_command"),
+		cfg:         cfg,
+		chatService: chatService,
+		characters:  characterStore,
+		pricing:     pricing,
+	}
+}
+
+// Name returns the name of the command.
+func (c *DiscussCommand) Name() string {
+	return "discuss"
+}
+
+// Description returns the description of the command.
+func (c *DiscussCommand) Description() string {
+	return "Have server characters discuss a prompt (experimental)"
+}
+
+// Options returns the prompt, the characters taking part and optional limits.
+func (c *DiscussCommand) Options() []discord.CommandOption {
+	options := []discord.CommandOption{
+		&discord.StringOption{
+			OptionName:  "prompt",
+			Description: "What the characters should discuss",
+			Required:    true,
+		},
+		&discord.StringOption{
+			OptionName:  "characters",
+			Description: fmt.Sprintf("%d to %d character names separated by commas", chat.MinDiscussionCast, chat.MaxDiscussionCast),
+			Required:    true,
+		},
+		&discord.IntegerOption{
+			OptionName:  "turns",
+			Description: "Number of replies across all characters (optional, capped by server setting)",
+			Min:         option.NewInt(chat.MinDiscussionCast),
+			Max:         option.NewInt(maxTurnsOption),
+		},
+	}
+
+	if c.cfg != nil && len(c.cfg.ChatModels()) > 0 {
+		options = append(options, &discord.StringOption{
+			OptionName:  "model",
+			Description: "Specific AI model to use (optional, defaults to first configured model)",
+			Choices:     modelChoices(c.cfg.ChatModels(), c.pricing),
+		})
+	}
+
+	return options
+}
+
+// Execute validates the cast and hands the discussion to the chat service.
+func (c *DiscussCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if !c.cfg.Characters.Discussion.Enabled {
+		return respondEphemeral(s, e, "Discussions are disabled on this bot.")
+	}
+	if !e.GuildID.IsValid() {
+		return respondEphemeral(s, e, "Discussions are only available in servers.")
+	}
+
+	var prompt, castOption, modelOption string
+	var turns int
+	for _, opt := range data.Options {
+		switch opt.Name {
+		case "prompt":
+			prompt = strings.TrimSpace(opt.String())
+		case "characters":
+			castOption = opt.String()
+		case "model":
+			modelOption = opt.String()
+		case "turns":
+			if n, err := opt.IntValue(); err == nil {
+				turns = int(n)
+			}
+		}
+	}
+	if prompt == "" {
+		return respondEphemeral(s, e, "Your prompt cannot be empty.")
+	}
+
+	cast, errMsg := c.resolveCast(e.GuildID, castOption)
+	if errMsg != "" {
+		return respondEphemeral(s, e, errMsg)
+	}
+
+	c.logger.Info("Discussion requested",
+		zap.String("guildID", e.GuildID.String()),
+		zap.String("userID", e.SenderID().String()),
+		zap.Int("characters", len(cast)),
+		zap.Int("turns", turns))
+
+	err := c.chatService.HandleDiscussionInteraction(ctx, e, chat.Discussion{
+		Prompt: prompt,
+		Cast:   cast,
+		Turns:  turns,
+	}, modelOption)
+	if err != nil {
+		return fmt.Errorf("discussion interaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// resolveCast looks up the comma separated character names. On failure it
+// returns a message for the user.
+func (c *DiscussCommand) resolveCast(guildID discord.GuildID, names string) ([]characters.Character, string) {
+	var cast []characters.Character
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		character, errMsg := findCharacter(c.characters, guildID, name)
+		if errMsg != "" {
+			return nil, errMsg
+		}
+		cast = append(cast, character)
+	}
+
+	if len(cast) < chat.MinDiscussionCast || len(cast) > chat.MaxDiscussionCast {
+		return nil, fmt.Sprintf("A discussion needs %d to %d different characters, separated by commas.", chat.MinDiscussionCast, chat.MaxDiscussionCast)
+	}
+
+	return cast, ""
+}
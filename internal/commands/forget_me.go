@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/privacy"
+)
+
+// ForgetMeCommand deletes the personal data the bot holds about the invoking user.
+type ForgetMeCommand struct {
+	logger  *zap.Logger
+	erasers []privacy.Eraser
+}
+
+// NewForgetMeCommand creates a new ForgetMeCommand. erasers are collected
+// from the Fx group "erasers".
+func NewForgetMeCommand(logger *zap.Logger, erasers []privacy.Eraser) Command {
+	return &ForgetMeCommand{
+		logger:  logger.Named("forget_me_command"),
+		erasers: erasers,
+	}
+}
+
+// Name returns the name of the command.
+func (c *ForgetMeCommand) Name() string {
+	return "forget-me"
+}
+
+// Description returns the description of the command.
+func (c *ForgetMeCommand) Description() string {
+	return "Deletes the conversations and other data the bot has stored about you."
+}
+
+// UserInstallable reports that the command works for user installs.
+func (c *ForgetMeCommand) UserInstallable() bool {
+	return true
+}
+
+// Options returns the command options.
+func (c *ForgetMeCommand) Options() []discord.CommandOption {
+	return nil
+}
+
+// Execute deletes the user's data and reports what was removed.
+func (c *ForgetMeCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	userID := e.SenderID()
+	c.logger.Info("Forget-me requested", zap.String("userID", userID.String()))
+
+	// Erasing cold storage can take longer than the initial response window
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.DeferredMessageInteractionWithSource,
+		Data: &api.InteractionResponseData{Flags: discord.EphemeralMessage},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to defer forget-me response: %w", err)
+	}
+
+	results := privacy.ForgetUser(ctx, c.erasers, userID)
+
+	var sb strings.Builder
+	failed := false
+	for _, r := range results {
+		if r.Err != nil {
+			failed = true
+			c.logger.Error("Failed to erase user data",
+				zap.String("eraser", r.Name),
+				zap.String("userID", userID.String()),
+				zap.Error(r.Err))
+			fmt.Fprintf(&sb, "• %s: ❌ failed (%d removed before the error)\n", r.Name, r.Removed)
+
+			continue
+		}
+		fmt.Fprintf(&sb, "• %s: %d removed\n", r.Name, r.Removed)
+	}
+
+	header := "🗑️ **Your data has been deleted.**\n"
+	if failed {
+		header = "⚠️ **Some of your data could not be deleted.** Please try again later or contact an administrator.\n"
+	}
+	content := header + sb.String() +
+		"\nConversations you started are deleted and will not be continued. " +
+		"Conversations started by others that you posted in are dropped from the bot's memory and archive; " +
+		"if someone continues one, the bot reads the thread again from Discord, including your messages there.\n" +
+		"Messages you posted in Discord are not affected; delete them in Discord if you want them gone too."
+
+	_, err = s.EditInteractionResponse(e.AppID, e.Token, api.EditInteractionResponseData{
+		Content: option.NewNullableString(content),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send forget-me confirmation: %w", err)
+	}
+
+	c.logger.Info("Forget-me completed", zap.String("userID", userID.String()), zap.Bool("failed", failed))
+
+	return nil
+}
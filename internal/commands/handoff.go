@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+)
+
+// handoffSummaryTimeout bounds the summary request, well within the 15
+// minutes an interaction token stays valid.
+const handoffSummaryTimeout = 2 * time.Minute
+
+// HandoffCommand hands a chat thread over to human moderators: it pings the
+// guild's handoff roles, mutes the bot in the thread and posts a summary of
+// the conversation so far.
+type HandoffCommand struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	chatService *chat.Service
+	mutes       moderation.ThreadMutes
+}
+
+// NewHandoffCommand creates a new HandoffCommand.
+func NewHandoffCommand(logger *zap.Logger, cfg *config.Config, chatService *chat.Service, mutes moderation.ThreadMutes) Command {
+	return &HandoffCommand{
+		logger:      logger.Named("handoff_command"),
+		cfg:         cfg,
+		chatService: chatService,
+		mutes:       mutes,
+	}
+}
+
+// Name returns the name of the command.
+func (c *HandoffCommand) Name() string {
+	return "handoff"
+}
+
+// Description returns the description of the command.
+func (c *HandoffCommand) Description() string {
+	return "Hand this thread over to a human moderator"
+}
+
+// Options returns the command options.
+func (c *HandoffCommand) Options() []discord.CommandOption {
+	return nil
+}
+
+// Execute mutes the thread and pings the moderators at once, then posts the
+// summary as a follow-up once it is ready.
+func (c *HandoffCommand) Execute(_ context.Context, s *session.Session, e *gateway.InteractionCreateEvent, _ *discord.CommandInteraction) error {
+	if !managedThread(s, c.logger, e.ChannelID) {
+		return respondEphemeral(s, e, "This only works in chat threads started by the bot.")
+	}
+
+	if _, err := c.mutes.Mute(e.ChannelID, e.SenderID()); err != nil {
+		c.logger.Error("Failed to mute thread for handoff", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+
+		return respondEphemeral(s, e, "❌ Could not hand this thread over: "+err.Error())
+	}
+
+	roleIDs := c.handoffRoles(e.GuildID)
+	mentions := make([]string, len(roleIDs))
+	for i, roleID := range roleIDs {
+		mentions[i] = roleID.Mention()
+	}
+	content := fmt.Sprintf("🙋 %s asked for a human to take over. I have stopped replying here; `/unmute-thread` brings me back.", e.SenderID().Mention())
+	if len(mentions) > 0 {
+		content += "\n" + strings.Join(mentions, " ")
+	}
+
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(content),
+			AllowedMentions: &api.AllowedMentions{Roles: roleIDs},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to respond to handoff: %w", err)
+	}
+	c.logger.Info("Thread handed off",
+		zap.String("guildID", e.GuildID.String()),
+		zap.String("threadID", e.ChannelID.String()),
+		zap.String("userID", e.SenderID().String()))
+
+	// The command context ends with Execute, so the summary gets its own
+	go c.postSummary(s, e)
+
+	return nil
+}
+
+// postSummary summarizes the conversation and posts it as follow-ups.
+func (c *HandoffCommand) postSummary(s *session.Session, e *gateway.InteractionCreateEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), handoffSummaryTimeout)
+	defer cancel()
+
+	summary, err := c.chatService.HandOff(ctx, e.GuildID, e.ChannelID)
+	content := "📋 **Conversation so far**\n" + summary
+	if err != nil {
+		c.logger.Warn("Failed to summarize thread for handoff", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+		content = "📋 I could not summarize this conversation, so please read the thread above."
+		if errors.Is(err, chat.ErrNotManaged) {
+			content = "📋 There is no conversation to summarize in this thread."
+		}
+	}
+
+	for _, part := range chat.SplitMessage(content) {
+		_, err := s.FollowUpInteraction(e.AppID, e.Token, api.InteractionResponseData{
+			Content:         option.NewNullableString(part),
+			AllowedMentions: &api.AllowedMentions{},
+		})
+		if err != nil {
+			c.logger.Error("Failed to post handoff summary", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+
+			return
+		}
+	}
+}
+
+// handoffRoles returns the roles configured to take over threads in guildID.
+func (c *HandoffCommand) handoffRoles(guildID discord.GuildID) []discord.RoleID {
+	var roleIDs []discord.RoleID
+	for _, id := range c.cfg.Guild(guildID.String()).Moderation.HandoffRoleIDs {
+		sf, err := discord.ParseSnowflake(id)
+		if err != nil {
+			c.logger.Warn("Invalid handoff role", zap.Error(err), zap.String("roleID", id))
+
+			continue
+		}
+		roleIDs = append(roleIDs, discord.RoleID(sf))
+	}
+
+	return roleIDs
+}
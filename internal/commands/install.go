@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/api"
+)
+
+// IntegrationType is a Discord application installation context.
+type IntegrationType int
+
+const (
+	// GuildInstall is an app installed to a server.
+	GuildInstall IntegrationType = 0
+	// UserInstall is an app installed to a user account.
+	UserInstall IntegrationType = 1
+)
+
+// InteractionContextType is a Discord surface a command can be used from.
+type InteractionContextType int
+
+const (
+	// ContextGuild is a server channel.
+	ContextGuild InteractionContextType = 0
+	// ContextBotDM is the DM channel with the bot user.
+	ContextBotDM InteractionContextType = 1
+	// ContextPrivateChannel is a group DM or a DM between other users.
+	ContextPrivateChannel InteractionContextType = 2
+)
+
+// CommandData is the registration payload for a command. It extends
+// api.CreateCommandData with the installation fields arikawa does not model yet.
+type CommandData struct {
+	api.CreateCommandData
+
+	IntegrationTypes []IntegrationType        `json:"-"`
+	Contexts         []InteractionContextType `json:"-"`
+}
+
+// MarshalJSON encodes the embedded command data and adds integration_types and contexts.
+func (d CommandData) MarshalJSON() ([]byte, error) {
+	raw, err := json.Marshal(d.CreateCommandData)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.IntegrationTypes) == 0 && len(d.Contexts) == 0 {
+		return raw, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode command data: %w", err)
+	}
+
+	// dm_permission is superseded by contexts
+	delete(fields, "dm_permission")
+
+	if len(d.IntegrationTypes) > 0 {
+		if fields["integration_types"], err = json.Marshal(d.IntegrationTypes); err != nil {
+			return nil, err
+		}
+	}
+	if len(d.Contexts) > 0 {
+		if fields["contexts"], err = json.Marshal(d.Contexts); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(fields)
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// ModelCommand shows the model snapshot a chat thread is pinned to, and lets
+// its initiator move the thread to the current defaults or another model.
+// Threads otherwise keep the snapshot and settings their first reply used.
+type ModelCommand struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	chatService *chat.Service
+	pricing     pkgopenai.PricingService
+}
+
+// NewModelCommand creates a new ModelCommand.
+func NewModelCommand(logger *zap.Logger, cfg *config.Config, chatService *chat.Service, pricing pkgopenai.PricingService) Command {
+	return &ModelCommand{
+		logger:      logger.Named("model_command"),
+		cfg:         cfg,
+		chatService: chatService,
+		pricing:     pricing,
+	}
+}
+
+// Name returns the name of the command.
+func (c *ModelCommand) Name() string {
+	return "model"
+}
+
+// Description returns the description of the command.
+func (c *ModelCommand) Description() string {
+	return "Show or upgrade the model version this thread is pinned to"
+}
+
+// Options returns the show and upgrade subcommands.
+func (c *ModelCommand) Options() []discord.CommandOption {
+	upgrade := &discord.SubcommandOption{
+		OptionName:  "upgrade",
+		Description: "Use the current model version and settings from the next reply on",
+	}
+	if models := c.cfg.ChatModels(); len(models) > 0 {
+		upgrade.Options = []discord.CommandOptionValue{
+			&discord.StringOption{
+				OptionName:  "model",
+				Description: "Switch the thread to another model (default: keep its model)",
+				Choices:     modelChoices(models, c.pricing),
+			},
+		}
+	}
+
+	return []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "show",
+			Description: "Show the model version and settings this thread's replies use",
+		},
+		upgrade,
+	}
+}
+
+// Execute runs the selected subcommand.
+func (c *ModelCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if len(data.Options) == 0 {
+		return errors.New("model subcommand is missing")
+	}
+	if !managedThread(s, c.logger, e.ChannelID) {
+		return c.respond(s, e, "This only works in chat threads started by the bot.", true)
+	}
+
+	sub := data.Options[0]
+	switch sub.Name {
+	case "show":
+		return c.show(ctx, s, e)
+	case "upgrade":
+		var model string
+		for _, opt := range sub.Options {
+			if opt.Name == "model" {
+				model = opt.String()
+			}
+		}
+
+		return c.upgrade(ctx, s, e, model)
+	default:
+		return fmt.Errorf("unknown model subcommand %q", sub.Name)
+	}
+}
+
+func (c *ModelCommand) show(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent) error {
+	state, ok := c.chatService.ThreadModel(ctx, e.ChannelID)
+	if !ok {
+		return c.respond(s, e, "🧠 This thread's conversation is not loaded. Its next reply pins the model version it uses.", true)
+	}
+	if state.Pin == nil {
+		return c.respond(s, e, fmt.Sprintf("🧠 This thread uses `%s`. Its next reply pins the current version and settings.", state.Model), true)
+	}
+
+	content := fmt.Sprintf("🧠 This thread is pinned to `%s` (`%s`)", state.Pin.Snapshot, state.Model)
+	if state.Pin.MaxResponseTokens > 0 {
+		content += fmt.Sprintf(", with replies of up to %d tokens", state.Pin.MaxResponseTokens)
+	}
+	content += ".\nUse `/model upgrade` to move it to the current version and settings."
+
+	return c.respond(s, e, content, true)
+}
+
+func (c *ModelCommand) upgrade(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, model string) error {
+	user := e.SenderID()
+	if state, ok := c.chatService.ThreadModel(ctx, e.ChannelID); ok && state.InitiatorID.IsValid() &&
+		state.InitiatorID != user && !moderation.IsAdmin(c.cfg, user) {
+		metrics.MarkDropped(ctx, metrics.DropPermission)
+
+		return c.respond(s, e, "Only the member who started this thread can change its model.", true)
+	}
+
+	upgraded, err := c.chatService.UpgradeThreadModel(ctx, e.ChannelID, model)
+	if err != nil {
+		c.logger.Warn("Failed to upgrade thread model", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+
+		return c.respond(s, e, "❌ Could not upgrade this thread: "+err.Error(), true)
+	}
+
+	return c.respond(s, e, fmt.Sprintf("🔄 %s upgraded this thread to `%s`. The next reply uses its current version and settings, and the thread keeps them from then on.",
+		user.Mention(), upgraded), false)
+}
+
+// respond replies to the interaction, only to the user when ephemeral.
+func (c *ModelCommand) respond(s *session.Session, e *gateway.InteractionCreateEvent, content string, ephemeral bool) error {
+	data := &api.InteractionResponseData{
+		Content:         option.NewNullableString(content),
+		AllowedMentions: &api.AllowedMentions{},
+	}
+	if ephemeral {
+		data.Flags = discord.EphemeralMessage
+	}
+
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{Type: api.MessageInteractionWithSource, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to respond to model command: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// maxChoiceNameLength is Discord's limit for the name of a command choice.
+const maxChoiceNameLength = 100
+
+// ModelsCommand lists the configured models with what each of them can do,
+// so users can pick one for /chat, /discuss or /video.
+type ModelsCommand struct {
+	logger  *zap.Logger
+	cfg     *config.Config
+	pricing pkgopenai.PricingService
+}
+
+// NewModelsCommand creates a new ModelsCommand.
+func NewModelsCommand(logger *zap.Logger, cfg *config.Config, pricing pkgopenai.PricingService) Command {
+	return &ModelsCommand{
+		logger:  logger.Named("models_command"),
+		cfg:     cfg,
+		pricing: pricing,
+	}
+}
+
+// Name returns the name of the command.
+func (c *ModelsCommand) Name() string {
+	return "models"
+}
+
+// Description returns the description of the command.
+func (c *ModelsCommand) Description() string {
+	return "List the available AI models with their capabilities and knowledge cutoff"
+}
+
+// UserInstallable reports that the command works for user installs.
+func (c *ModelsCommand) UserInstallable() bool {
+	return true
+}
+
+// Options returns the command options.
+func (c *ModelsCommand) Options() []discord.CommandOption {
+	return nil
+}
+
+// Execute lists the configured models, the default first.
+func (c *ModelsCommand) Execute(_ context.Context, s *session.Session, e *gateway.InteractionCreateEvent, _ *discord.CommandInteraction) error {
+	content := "No models are configured on this bot."
+	if models := c.cfg.ChatModels(); len(models) > 0 {
+		lines := make([]string, 0, len(models)+1)
+		lines = append(lines, "🧠 **Available models**")
+		for i, modelName := range models {
+			line := "• " + c.describe(modelName)
+			if i == 0 {
+				line += " *(default)*"
+			}
+			lines = append(lines, line)
+		}
+		content = strings.Join(lines, "\n")
+	}
+
+	parts := chat.SplitMessage(content)
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content: option.NewNullableString(parts[0]),
+			Flags:   discord.EphemeralMessage,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to respond to models: %w", err)
+	}
+
+	for _, part := range parts[1:] {
+		_, err := s.FollowUpInteraction(e.AppID, e.Token, api.InteractionResponseData{
+			Content: option.NewNullableString(part),
+			Flags:   discord.EphemeralMessage,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send models follow-up: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// describe formats the metadata of modelName for the list.
+func (c *ModelsCommand) describe(modelName string) string {
+	info, err := c.pricing.GetModelPricing(modelName)
+	if err != nil {
+		c.logger.Debug("No metadata for model", zap.String("model", modelName))
+
+		return fmt.Sprintf("`%s`: no details known", modelName)
+	}
+
+	details := []string{info.Summary()}
+	if info.ContextSize != nil {
+		details = append(details, fmt.Sprintf("%dK context", *info.ContextSize/1000))
+	}
+	if info.Pricing.OutputPerMillion != nil {
+		details = append(details, fmt.Sprintf("$%.2f in / $%.2f out per 1M tokens", info.Pricing.InputPerMillion, *info.Pricing.OutputPerMillion))
+	} else {
+		details = append(details, fmt.Sprintf("$%.2f in per 1M tokens", info.Pricing.InputPerMillion))
+	}
+	if info.Deprecation != nil {
+		details = append(details, "⚠️ deprecated")
+	}
+
+	return fmt.Sprintf("**%s** (`%s`): %s", info.DisplayName, modelName, strings.Join(details, " · "))
+}
+
+// modelChoices returns the choices for a model option, naming each model with
+// its capabilities and knowledge cutoff when models.json knows them.
+func modelChoices(models []string, pricing pkgopenai.PricingService) []discord.StringChoice {
+	choices := make([]discord.StringChoice, len(models))
+	for i, modelName := range models {
+		name := modelName
+		if info, err := pricing.GetModelPricing(modelName); err == nil {
+			name += " · " + info.Summary()
+		}
+		if runes := []rune(name); len(runes) > maxChoiceNameLength {
+			name = string(runes[:maxChoiceNameLength-1]) + "…"
+		}
+		choices[i] = discord.StringChoice{Name: name, Value: modelName}
+	}
+
+	return choices
+}
@@ -30,5 +30,81 @@ var Module = fx.Module("commands",
 			fx.As(new(Command)),
 			fx.ResultTags(`group:"commands"`),
 		),
+		fx.Annotate(
+			NewCharacterCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewPromptCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewDiagnosticsCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewAdminCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewDiscussCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewVideoCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewReviewCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewModelsCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewModelCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewMuteThreadCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewUnmuteThreadCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewHandoffCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewSummarizeCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewStatsCommand,
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
+		fx.Annotate(
+			NewForgetMeCommand,
+			fx.ParamTags(``, `group:"erasers"`),
+			fx.As(new(Command)),
+			fx.ResultTags(`group:"commands"`),
+		),
 	),
 )
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+)
+
+const muteThreadCommandName = "mute-thread"
+
+// The toggle buttons on mute notices. Both are routed to /mute-thread.
+var (
+	muteComponentID   = ComponentID(muteThreadCommandName, "mute")
+	unmuteComponentID = ComponentID(muteThreadCommandName, "unmute")
+)
+
+// ThreadMuteCommand mutes or unmutes the bot in the managed thread it is
+// used in. Participants use it to pause the bot without archiving the thread.
+type ThreadMuteCommand struct {
+	logger *zap.Logger
+	mutes  moderation.ThreadMutes
+	mute   bool
+}
+
+// NewMuteThreadCommand creates the /mute-thread command, which also handles
+// the mute and unmute buttons.
+func NewMuteThreadCommand(logger *zap.Logger, mutes moderation.ThreadMutes) Command {
+	return &ThreadMuteCommand{logger: logger.Named("mute_thread_command"), mutes: mutes, mute: true}
+}
+
+// NewUnmuteThreadCommand creates the /unmute-thread command.
+func NewUnmuteThreadCommand(logger *zap.Logger, mutes moderation.ThreadMutes) Command {
+	return &ThreadMuteCommand{logger: logger.Named("unmute_thread_command"), mutes: mutes}
+}
+
+// Name returns the name of the command.
+func (c *ThreadMuteCommand) Name() string {
+	if c.mute {
+		return muteThreadCommandName
+	}
+
+	return "unmute-thread"
+}
+
+// Description returns the description of the command.
+func (c *ThreadMuteCommand) Description() string {
+	if c.mute {
+		return "Stop the bot from replying in this thread"
+	}
+
+	return "Let the bot reply in this thread again"
+}
+
+// Options returns the command options.
+func (c *ThreadMuteCommand) Options() []discord.CommandOption {
+	return nil
+}
+
+// Execute mutes or unmutes the thread the command was used in.
+func (c *ThreadMuteCommand) Execute(_ context.Context, s *session.Session, e *gateway.InteractionCreateEvent, _ *discord.CommandInteraction) error {
+	content, mute, err := c.toggle(s, e, c.mute)
+	if err != nil || content == "" {
+		return err
+	}
+
+	return c.respond(s, e, api.MessageInteractionWithSource, content, mute)
+}
+
+// HandleComponent handles the buttons on mute notices, updating the notice.
+func (c *ThreadMuteCommand) HandleComponent(_ context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data discord.ComponentInteraction) error {
+	var mute bool
+	switch data.ID() {
+	case muteComponentID:
+		mute = true
+	case unmuteComponentID:
+	default:
+		return fmt.Errorf("unknown mute component: %s", data.ID())
+	}
+
+	content, muted, err := c.toggle(s, e, mute)
+	if err != nil || content == "" {
+		return err
+	}
+
+	return c.respond(s, e, api.UpdateMessage, content, muted)
+}
+
+// toggle applies mute to the thread of e and returns the notice to show with
+// the resulting state. An empty notice means an error was already answered.
+func (c *ThreadMuteCommand) toggle(s *session.Session, e *gateway.InteractionCreateEvent, mute bool) (string, bool, error) {
+	if !managedThread(s, c.logger, e.ChannelID) {
+		return "", false, respondEphemeral(s, e, "This only works in chat threads started by the bot.")
+	}
+
+	user := e.SenderID()
+	if !mute {
+		if _, err := c.mutes.Unmute(e.ChannelID); err != nil {
+			c.logger.Error("Failed to unmute thread", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+
+			return "", false, respondEphemeral(s, e, "❌ Could not unmute this thread: "+err.Error())
+		}
+		c.logger.Info("Thread unmuted", zap.String("threadID", e.ChannelID.String()), zap.String("userID", user.String()))
+
+		return fmt.Sprintf("🔊 %s unmuted me. I will reply in this thread again.", user.Mention()), false, nil
+	}
+
+	if _, err := c.mutes.Mute(e.ChannelID, user); err != nil {
+		c.logger.Error("Failed to mute thread", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+
+		return "", false, respondEphemeral(s, e, "❌ Could not mute this thread: "+err.Error())
+	}
+	c.logger.Info("Thread muted", zap.String("threadID", e.ChannelID.String()), zap.String("userID", user.String()))
+
+	return fmt.Sprintf("🔇 %s muted me. I will not reply in this thread until someone uses `/unmute-thread` or the button below.", user.Mention()), true, nil
+}
+
+// managedThread reports whether channelID is a thread the bot started.
+func managedThread(s *session.Session, logger *zap.Logger, channelID discord.ChannelID) bool {
+	ch, err := s.Channel(channelID)
+	if err != nil {
+		logger.Warn("Failed to fetch channel", zap.Error(err), zap.String("channelID", channelID.String()))
+
+		return false
+	}
+	if ch.Type != discord.GuildPublicThread && ch.Type != discord.GuildPrivateThread && ch.Type != discord.GuildAnnouncementThread {
+		return false
+	}
+	self, err := s.Me()
+	if err != nil {
+		logger.Warn("Failed to get self user", zap.Error(err))
+
+		return false
+	}
+
+	return ch.OwnerID == self.ID
+}
+
+// respond posts or updates the mute notice with the button for the other state.
+func (c *ThreadMuteCommand) respond(s *session.Session, e *gateway.InteractionCreateEvent, responseType api.InteractionResponseType, content string, muted bool) error {
+	button := &discord.ButtonComponent{
+		Label:    "Mute",
+		CustomID: muteComponentID,
+		Style:    discord.SecondaryButtonStyle(),
+		Emoji:    &discord.ComponentEmoji{Name: "🔇"},
+	}
+	if muted {
+		button = &discord.ButtonComponent{
+			Label:    "Unmute",
+			CustomID: unmuteComponentID,
+			Style:    discord.PrimaryButtonStyle(),
+			Emoji:    &discord.ComponentEmoji{Name: "🔊"},
+		}
+	}
+	components := discord.ContainerComponents{&discord.ActionRowComponent{button}}
+
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: responseType,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(content),
+			Components:      &components,
+			AllowedMentions: &api.AllowedMentions{},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to respond to %s: %w", c.Name(), err)
+	}
+
+	return nil
+}
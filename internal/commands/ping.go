@@ -29,6 +29,11 @@ func (c *PingCommand) Description() string {
 	return "Responds with Pong!"
 }
 
+// UserInstallable reports that the command works for user installs.
+func (c *PingCommand) UserInstallable() bool {
+	return true
+}
+
 // Options returns the command options.
 func (c *PingCommand) Options() []discord.CommandOption {
 	return nil // No options for this command
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/prompts"
+)
+
+// Scopes offered by /prompt.
+const (
+	promptScopeServer  = "server"
+	promptScopeChannel = "channel"
+)
+
+// PromptCommand lets server managers set the system prompt /chat answers with,
+// for their server or a single channel.
+type PromptCommand struct {
+	logger  *zap.Logger
+	cfg     *config.Config
+	prompts prompts.Store
+}
+
+// NewPromptCommand creates a new PromptCommand.
+func NewPromptCommand(logger *zap.Logger, cfg *config.Config, promptStore prompts.Store) Command {
+	return &PromptCommand{
+		logger:  logger.Named("prompt_command"),
+		cfg:     cfg,
+		prompts: promptStore,
+	}
+}
+
+// Name returns the name of the command.
+func (c *PromptCommand) Name() string {
+	return "prompt"
+}
+
+// Description returns the description of the command.
+func (c *PromptCommand) Description() string {
+	return "Manage the system prompt /chat answers with"
+}
+
+// DefaultMemberPermissions restricts the command to server managers by default.
+func (c *PromptCommand) DefaultMemberPermissions() discord.Permissions {
+	return discord.PermissionManageGuild
+}
+
+// Options returns the set, show and clear subcommands.
+func (c *PromptCommand) Options() []discord.CommandOption {
+	scope := func() *discord.StringOption {
+		return &discord.StringOption{
+			OptionName:  "scope",
+			Description: "The whole server, or only this channel (default: server)",
+			Choices: []discord.StringChoice{
+				{Name: "This server", Value: promptScopeServer},
+				{Name: "This channel", Value: promptScopeChannel},
+			},
+		}
+	}
+
+	return []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "set",
+			Description: "Set or replace the system prompt",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "prompt",
+					Description: "Instructions sent before every conversation",
+					Required:    true,
+					MaxLength:   option.NewInt(prompts.MaxLength),
+				},
+				scope(),
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "show",
+			Description: "Show the system prompt used in this channel",
+		},
+		&discord.SubcommandOption{
+			OptionName:  "clear",
+			Description: "Remove a system prompt",
+			Options:     []discord.CommandOptionValue{scope()},
+		},
+	}
+}
+
+// Execute runs the selected subcommand.
+func (c *PromptCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if !e.GuildID.IsValid() {
+		return respondEphemeral(s, e, "System prompts can only be managed in servers.")
+	}
+	if len(data.Options) == 0 {
+		return errors.New("prompt subcommand is missing")
+	}
+
+	sub := data.Options[0]
+	values := make(map[string]string, len(sub.Options))
+	for _, opt := range sub.Options {
+		values[opt.Name] = opt.String()
+	}
+	channelID, where := discord.NullChannelID, "this server"
+	if values["scope"] == promptScopeChannel {
+		channelID, where = e.ChannelID, "this channel"
+	}
+
+	switch sub.Name {
+	case "set":
+		return c.set(s, e, channelID, where, values["prompt"])
+	case "show":
+		return c.show(s, e)
+	case "clear":
+		return c.clear(s, e, channelID, where)
+	default:
+		return fmt.Errorf("unknown prompt subcommand %q", sub.Name)
+	}
+}
+
+func (c *PromptCommand) set(s *session.Session, e *gateway.InteractionCreateEvent, channelID discord.ChannelID, where, prompt string) error {
+	if err := c.prompts.Set(e.GuildID, channelID, prompt); err != nil {
+		c.logger.Warn("Failed to set system prompt",
+			zap.Error(err),
+			zap.String("guildID", e.GuildID.String()),
+			zap.String("channelID", channelID.String()))
+
+		return respondEphemeral(s, e, "❌ Could not set the system prompt: "+err.Error())
+	}
+
+	c.logger.Info("System prompt set",
+		zap.String("guildID", e.GuildID.String()),
+		zap.String("channelID", channelID.String()),
+		zap.String("userID", e.SenderID().String()))
+
+	return respondEphemeral(s, e, fmt.Sprintf("📝 System prompt for %s set. It applies to new messages in every conversation, including existing threads.", where))
+}
+
+func (c *PromptCommand) show(s *session.Session, e *gateway.InteractionCreateEvent) error {
+	channelIDs := []discord.ChannelID{e.ChannelID}
+	if e.Channel != nil && e.Channel.ParentID.IsValid() && isThread(e.Channel.Type) {
+		channelIDs = append(channelIDs, e.Channel.ParentID)
+	}
+
+	prompt, source := prompts.Resolve(c.prompts, c.cfg.Prompts.Default, e.GuildID, channelIDs...)
+	var from string
+	switch source {
+	case prompts.SourceNone:
+		return respondEphemeral(s, e, "No system prompt is used here. Set one with `/prompt set`.")
+	case prompts.SourceChannel:
+		from = "set for this channel"
+	case prompts.SourceGuild:
+		from = "set for this server"
+	default:
+		from = "the bot's default"
+	}
+
+	return respondEphemeral(s, e, fmt.Sprintf("📝 **System prompt used here** (%s):\n>>> %s", from, prompt))
+}
+
+func (c *PromptCommand) clear(s *session.Session, e *gateway.InteractionCreateEvent, channelID discord.ChannelID, where string) error {
+	cleared, err := c.prompts.Clear(e.GuildID, channelID)
+	if err != nil {
+		c.logger.Warn("Failed to clear system prompt",
+			zap.Error(err),
+			zap.String("guildID", e.GuildID.String()),
+			zap.String("channelID", channelID.String()))
+
+		return respondEphemeral(s, e, "❌ Could not clear the system prompt: "+err.Error())
+	}
+	if !cleared {
+		return respondEphemeral(s, e, fmt.Sprintf("There is no system prompt set for %s.", where))
+	}
+
+	c.logger.Info("System prompt cleared", zap.String("guildID", e.GuildID.String()), zap.String("channelID", channelID.String()))
+
+	return respondEphemeral(s, e, fmt.Sprintf("🗑️ System prompt for %s cleared.", where))
+}
+
+func isThread(t discord.ChannelType) bool {
+	return t == discord.GuildPublicThread || t == discord.GuildPrivateThread || t == discord.GuildAnnouncementThread
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// maxDiffOptionLength is Discord's limit for a string option.
+const maxDiffOptionLength = 6000
+
+// ReviewCommand reviews a pasted or attached diff and lists the findings by file.
+type ReviewCommand struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	chatService *chat.Service
+	pricing     pkgopenai.PricingService
+}
+
+// NewReviewCommand creates a new ReviewCommand.
+func NewReviewCommand(logger *zap.Logger, cfg *config.Config, chatService *chat.Service, pricing pkgopenai.PricingService) Command {
+	return &ReviewCommand{
+		logger:      logger.Named("review_command"),
+		cfg:         cfg,
+		chatService: chatService,
+		pricing:     pricing,
+	}
+}
+
+// Name returns the name of the command.
+func (c *ReviewCommand) Name() string {
+	return "review"
+}
+
+// Description returns the description of the command.
+func (c *ReviewCommand) Description() string {
+	return "Review a code change from a pasted or attached diff"
+}
+
+// UserInstallable reports that the command works for user installs.
+func (c *ReviewCommand) UserInstallable() bool {
+	return true
+}
+
+// Options returns the pasted diff, the attached diff and an optional model.
+func (c *ReviewCommand) Options() []discord.CommandOption {
+	options := []discord.CommandOption{
+		&discord.StringOption{
+			OptionName:  "diff",
+			Description: "The diff to review, as printed by git diff (or attach it as a file)",
+			MaxLength:   option.NewInt(maxDiffOptionLength),
+		},
+		&discord.AttachmentOption{
+			OptionName:  "file",
+			Description: "A .diff or .patch file to review",
+		},
+	}
+
+	if c.cfg != nil && len(c.cfg.ChatModels()) > 0 {
+		options = append(options, &discord.StringOption{
+			OptionName:  "model",
+			Description: "Specific AI model to use (optional, defaults to the review model)",
+			Choices:     modelChoices(c.cfg.ChatModels(), c.pricing),
+		})
+	}
+
+	return options
+}
+
+// Execute collects the diff and hands it to the chat service.
+func (c *ReviewCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if !c.cfg.OpenAI.Review.Enabled {
+		return respondEphemeral(s, e, "Code review is disabled on this bot.")
+	}
+
+	var diff, modelOption string
+	var attachment *discord.Attachment
+	for _, opt := range data.Options {
+		switch opt.Name {
+		case "diff":
+			diff = opt.String()
+		case "file":
+			id, err := opt.SnowflakeValue()
+			if err != nil {
+				return respondEphemeral(s, e, "That attachment could not be read.")
+			}
+			if a, ok := data.Resolved.Attachments[discord.AttachmentID(id)]; ok {
+				attachment = &a
+			}
+		case "model":
+			modelOption = opt.String()
+		}
+	}
+	if diff == "" && attachment == nil {
+		return respondEphemeral(s, e, "Paste a diff or attach one to review.")
+	}
+
+	c.logger.Info("Code review requested",
+		zap.String("userID", e.SenderID().String()),
+		zap.Int("diffChars", len(diff)),
+		zap.Bool("hasAttachment", attachment != nil))
+
+	err := c.chatService.HandleReviewInteraction(ctx, e, diff, attachment, modelOption)
+	if err != nil && !errors.Is(err, chat.ErrEmptyDiff) {
+		return fmt.Errorf("review interaction failed: %w", err)
+	}
+
+	return nil
+}
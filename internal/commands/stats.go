@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+)
+
+// maxStatsMessageLength is Discord's message length limit.
+const maxStatsMessageLength = 2000
+
+// StatsCommand shows bot operators the interaction funnel of each command:
+// how many interactions got their response and where the others dropped.
+type StatsCommand struct {
+	cfg    *config.Config
+	funnel *metrics.Funnel
+}
+
+// NewStatsCommand creates a new StatsCommand.
+func NewStatsCommand(cfg *config.Config, funnel *metrics.Funnel) Command {
+	return &StatsCommand{cfg: cfg, funnel: funnel}
+}
+
+// Name returns the name of the command.
+func (c *StatsCommand) Name() string {
+	return "stats"
+}
+
+// Description returns the description of the command.
+func (c *StatsCommand) Description() string {
+	return "Show how many interactions each command answered and why the rest dropped (bot operators only)"
+}
+
+// DefaultMemberPermissions hides the command from members who are not
+// administrators; Execute further limits it to bot operators.
+func (c *StatsCommand) DefaultMemberPermissions() discord.Permissions {
+	return discord.PermissionAdministrator
+}
+
+// Options returns the command options.
+func (c *StatsCommand) Options() []discord.CommandOption {
+	return nil
+}
+
+// Execute replies with the funnel of every command used since the bot
+// started.
+func (c *StatsCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if !moderation.IsAdmin(c.cfg, e.SenderID()) {
+		metrics.MarkDropped(ctx, metrics.DropPermission)
+
+		return respondEphemeral(s, e, "Only bot operators can view the bot's stats.")
+	}
+
+	since, stats := c.funnel.Snapshot()
+
+	return respondEphemeral(s, e, formatFunnel(since.Unix(), stats))
+}
+
+// formatFunnel renders stats counted since the Unix time since, cut to fit
+// a message.
+func formatFunnel(since int64, stats []metrics.FunnelStats) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 **Interaction funnel** since <t:%d:R>\n", since)
+	if len(stats) == 0 {
+		b.WriteString("No commands used yet.")
+
+		return b.String()
+	}
+
+	for i, st := range stats {
+		line := fmt.Sprintf("**/%s**: %d invoked, %d responded (%.0f%%)", st.Command, st.Invoked, st.Responded,
+			100*float64(st.Responded)/float64(st.Invoked))
+		if st.Dropped() > 0 {
+			var drops []string
+			for _, reason := range metrics.DropReasons {
+				if n := st.Drops[reason]; n > 0 {
+					drops = append(drops, fmt.Sprintf("%s %d", reason, n))
+				}
+			}
+			line += "; dropped: " + strings.Join(drops, ", ")
+		}
+		line += "\n"
+
+		more := fmt.Sprintf("…and %d more commands", len(stats)-i)
+		if b.Len()+len(line)+len(more) > maxStatsMessageLength {
+			b.WriteString(more)
+
+			break
+		}
+		b.WriteString(line)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+// summarizeTimeout bounds the summary request, well within the 15 minutes an
+// interaction token stays valid.
+const summarizeTimeout = 2 * time.Minute
+
+// SummarizeCommand posts a summary of a chat thread and can replace the
+// history the bot remembers with it, making later replies cheaper.
+type SummarizeCommand struct {
+	logger      *zap.Logger
+	chatService *chat.Service
+}
+
+// NewSummarizeCommand creates a new SummarizeCommand.
+func NewSummarizeCommand(logger *zap.Logger, chatService *chat.Service) Command {
+	return &SummarizeCommand{
+		logger:      logger.Named("summarize_command"),
+		chatService: chatService,
+	}
+}
+
+// Name returns the name of the command.
+func (c *SummarizeCommand) Name() string {
+	return "summarize"
+}
+
+// Description returns the description of the command.
+func (c *SummarizeCommand) Description() string {
+	return "Summarize the conversation in this thread"
+}
+
+// Options returns the command options.
+func (c *SummarizeCommand) Options() []discord.CommandOption {
+	return []discord.CommandOption{
+		&discord.BooleanOption{
+			OptionName:  "compact",
+			Description: "Also make the bot remember only the summary and the latest messages, so replies cost less",
+			Required:    false,
+		},
+	}
+}
+
+// Execute defers the response, since summaries take a while, and posts the
+// summary once it is ready.
+func (c *SummarizeCommand) Execute(_ context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if !managedThread(s, c.logger, e.ChannelID) {
+		return respondEphemeral(s, e, "This only works in chat threads started by the bot.")
+	}
+
+	compact := false
+	for _, opt := range data.Options {
+		if opt.Name != "compact" {
+			continue
+		}
+		value, err := opt.BoolValue()
+		if err != nil {
+			return respondEphemeral(s, e, "Invalid compact value")
+		}
+		compact = value
+	}
+
+	err := s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{Type: api.DeferredMessageInteractionWithSource})
+	if err != nil {
+		return fmt.Errorf("failed to defer summarize response: %w", err)
+	}
+
+	// The command context ends with Execute, so the summary gets its own
+	go c.postSummary(s, e, compact)
+
+	return nil
+}
+
+// postSummary summarizes the conversation and posts it in place of the
+// deferred response.
+func (c *SummarizeCommand) postSummary(s *session.Session, e *gateway.InteractionCreateEvent, compact bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), summarizeTimeout)
+	defer cancel()
+
+	summary, err := c.chatService.Summarize(ctx, e.GuildID, e.ChannelID, e.SenderID(), compact)
+	var content string
+	switch {
+	case errors.Is(err, chat.ErrNotManaged), errors.Is(err, chat.ErrNothingToSummarize):
+		content = "📝 There is no conversation to summarize in this thread."
+	case err != nil:
+		c.logger.Warn("Failed to summarize thread", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+		content = "❌ Sorry, I could not summarize this conversation. Please try again later."
+	default:
+		content = "📝 **Conversation summary**\n" + summary.Text
+		if summary.Replaced > 0 {
+			content += fmt.Sprintf("\n\n🗜️ From now on I remember this summary instead of the %d earlier messages.", summary.Replaced)
+		} else if compact {
+			content += "\n\n🗜️ The conversation is still short, so I kept all of it."
+		}
+	}
+
+	parts := chat.SplitMessage(content)
+	_, err = s.EditInteractionResponse(e.AppID, e.Token, api.EditInteractionResponseData{
+		Content:         option.NewNullableString(parts[0]),
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	if err != nil {
+		c.logger.Error("Failed to post thread summary", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+
+		return
+	}
+	for _, part := range parts[1:] {
+		_, err := s.FollowUpInteraction(e.AppID, e.Token, api.InteractionResponseData{
+			Content:         option.NewNullableString(part),
+			AllowedMentions: &api.AllowedMentions{},
+		})
+		if err != nil {
+			c.logger.Error("Failed to post thread summary", zap.Error(err), zap.String("threadID", e.ChannelID.String()))
+
+			return
+		}
+	}
+}
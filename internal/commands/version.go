@@ -32,6 +32,11 @@ func (c *VersionCommand) Description() string {
 	return "Displays the current version of the bot."
 }
 
+// UserInstallable reports that the command works for user installs.
+func (c *VersionCommand) UserInstallable() bool {
+	return true
+}
+
 // Options returns the command options.
 func (c *VersionCommand) Options() []discord.CommandOption {
 	return nil // No options for this command
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/youtube"
+	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
+)
+
+// VideoCommand summarizes or answers questions about a YouTube video from its captions.
+type VideoCommand struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	chatService *chat.Service
+	pricing     pkgopenai.PricingService
+}
+
+// NewVideoCommand creates a new VideoCommand.
+func NewVideoCommand(logger *zap.Logger, cfg *config.Config, chatService *chat.Service, pricing pkgopenai.PricingService) Command {
+	return &VideoCommand{
+		logger:      logger.Named("video_command"),
+		cfg:         cfg,
+		chatService: chatService,
+		pricing:     pricing,
+	}
+}
+
+// Name returns the name of the command.
+func (c *VideoCommand) Name() string {
+	return "video"
+}
+
+// Description returns the description of the command.
+func (c *VideoCommand) Description() string {
+	return "Summarize or ask about a YouTube video"
+}
+
+// UserInstallable reports that the command works for user installs.
+func (c *VideoCommand) UserInstallable() bool {
+	return true
+}
+
+// Options returns the video link, an optional question and an optional model.
+func (c *VideoCommand) Options() []discord.CommandOption {
+	options := []discord.CommandOption{
+		&discord.StringOption{
+			OptionName:  "url",
+			Description: "Link to the YouTube video",
+			Required:    true,
+		},
+		&discord.StringOption{
+			OptionName:  "question",
+			Description: "What to ask about the video (optional, defaults to a summary)",
+		},
+	}
+
+	if c.cfg != nil && len(c.cfg.ChatModels()) > 0 {
+		options = append(options, &discord.StringOption{
+			OptionName:  "model",
+			Description: "Specific AI model to use (optional, defaults to first configured model)",
+			Choices:     modelChoices(c.cfg.ChatModels(), c.pricing),
+		})
+	}
+
+	return options
+}
+
+// Execute checks the link and hands the question to the chat service.
+func (c *VideoCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
+	if !c.cfg.OpenAI.YouTube.Enabled {
+		return respondEphemeral(s, e, "Video summaries are disabled on this bot.")
+	}
+
+	var videoURL, question, modelOption string
+	for _, opt := range data.Options {
+		switch opt.Name {
+		case "url":
+			videoURL = opt.String()
+		case "question":
+			question = strings.TrimSpace(opt.String())
+		case "model":
+			modelOption = opt.String()
+		}
+	}
+	if _, ok := youtube.VideoID(videoURL); !ok {
+		return respondEphemeral(s, e, "That does not look like a YouTube video link.")
+	}
+
+	c.logger.Info("Video question requested",
+		zap.String("userID", e.SenderID().String()),
+		zap.String("url", videoURL),
+		zap.Bool("hasQuestion", question != ""))
+
+	err := c.chatService.HandleVideoInteraction(ctx, e, videoURL, question, modelOption)
+	if err != nil && !errors.Is(err, youtube.ErrNoCaptions) {
+		return fmt.Errorf("video interaction failed: %w", err)
+	}
+
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
 	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
 
 	"github.com/diamondburned/arikawa/v3/api"
@@ -16,9 +18,31 @@ import (
 	"github.com/diamondburned/arikawa/v3/session"
 	"github.com/diamondburned/arikawa/v3/state"
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
 	"go.uber.org/zap"
 )
 
+// maxVoiceMessageLength is the longest message Discord accepts; longer
+// transcripts are attached as a file.
+const maxVoiceMessageLength = 2000
+
+// voiceGoComponentID is the custom ID of the "Respond now" button.
+var voiceGoComponentID = ComponentID("voice", "go")
+
+// respondNowComponents returns the button row attached to manual-turn sessions.
+func respondNowComponents() discord.ContainerComponents {
+	return discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.ButtonComponent{
+				Label:    "Respond now",
+				CustomID: voiceGoComponentID,
+				Style:    discord.PrimaryButtonStyle(),
+				Emoji:    &discord.ComponentEmoji{Name: "🗣️"},
+			},
+		},
+	}
+}
+
 type VoiceCommand struct {
 	logger       *zap.Logger
 	cfg          *config.Config
@@ -61,6 +85,14 @@ func (c *VoiceCommand) Options() []discord.CommandOption {
 				{Name: "start", Value: "start"},
 				{Name: "stop", Value: "stop"},
 				{Name: "status", Value: "status"},
+				{Name: "tune", Value: "tune"},
+				{Name: "go", Value: "go"},
+				{Name: "latency", Value: "latency"},
+				{Name: "transfer", Value: "transfer"},
+				{Name: "schedule", Value: "schedule"},
+				{Name: "unschedule", Value: "unschedule"},
+				{Name: "accessibility", Value: "accessibility"},
+				{Name: "transcript", Value: "transcript"},
 			},
 		},
 		&discord.StringOption{
@@ -68,13 +100,109 @@ func (c *VoiceCommand) Options() []discord.CommandOption {
 			Description: "AI model to use (optional)",
 			Required:    false,
 		},
+		&discord.StringOption{
+			OptionName:  "language",
+			Description: "Language to transcribe and respond in, as an ISO-639-1 code like en or de (start only)",
+			Required:    false,
+			MinLength:   option.NewInt(2),
+			MaxLength:   option.NewInt(2),
+		},
+		&discord.BooleanOption{
+			OptionName:  "manual_turns",
+			Description: "Only respond when you press \"Respond now\" or use /voice go (start only)",
+			Required:    false,
+		},
+		&discord.BooleanOption{
+			OptionName:  "text_only",
+			Description: "Listen in voice but answer in this text channel only (start only)",
+			Required:    false,
+		},
+		&discord.StringOption{
+			OptionName:  "style",
+			Description: "How the assistant answers, e.g. concise for meetings or playful for game nights (start only)",
+			Required:    false,
+			Choices: []discord.StringChoice{
+				{Name: "concise", Value: "concise"},
+				{Name: "chatty", Value: "chatty"},
+				{Name: "playful", Value: "playful"},
+			},
+		},
+		&discord.NumberOption{
+			OptionName:  "temperature",
+			Description: "Creativity of the answers, 0.6-1.2, higher is more varied (start only)",
+			Required:    false,
+			Min:         option.NewFloat(voice.MinTemperature),
+			Max:         option.NewFloat(voice.MaxTemperature),
+		},
+		&discord.NumberOption{
+			OptionName:  "silence_threshold",
+			Description: "Energy threshold for silence detection, 0.0-1.0 (tune only)",
+			Required:    false,
+			Min:         option.NewFloat(0),
+			Max:         option.NewFloat(1),
+		},
+		&discord.IntegerOption{
+			OptionName:  "silence_duration_ms",
+			Description: "Milliseconds of silence before responding (tune only)",
+			Required:    false,
+			Min:         option.NewInt(int(voice.MinSilenceDuration / time.Millisecond)),
+			Max:         option.NewInt(int(voice.MaxSilenceDuration / time.Millisecond)),
+		},
+		&discord.UserOption{
+			OptionName:  "user",
+			Description: "Member in the voice channel to hand control of the session to (transfer only)",
+			Required:    false,
+		},
+		&discord.StringOption{
+			OptionName:  "at",
+			Description: "Start time in UTC, as 2025-03-14 09:30 or 09:30 for the next one (schedule only)",
+			Required:    false,
+		},
+		&discord.BooleanOption{
+			OptionName:  "weekly",
+			Description: "Repeat the session every week (schedule only)",
+			Required:    false,
+		},
+		&discord.IntegerOption{
+			OptionName:  "duration_minutes",
+			Description: "Minutes before the session stops, up to the maximum session length (schedule only)",
+			Required:    false,
+			Min:         option.NewInt(1),
+		},
+		&discord.ChannelOption{
+			OptionName:   "channel",
+			Description:  "Voice channel to join, defaults to yours (schedule only)",
+			Required:     false,
+			ChannelTypes: []discord.ChannelType{discord.GuildVoice},
+		},
+		&discord.RoleOption{
+			OptionName:  "ping_role",
+			Description: "Role to ping when the session starts (schedule only)",
+			Required:    false,
+		},
+		&discord.StringOption{
+			OptionName:  "schedule_id",
+			Description: "ID of the scheduled session to cancel (unschedule only)",
+			Required:    false,
+		},
+		&discord.BooleanOption{
+			OptionName:  "enabled",
+			Description: "Post everything the bot says in voice as text mentioning you (accessibility only)",
+			Required:    false,
+		},
 	}
 }
 
 func (c *VoiceCommand) Execute(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data *discord.CommandInteraction) error {
 	// Get action parameter
 	var action string
-	var model string
+	var opts voice.StartOptions
+	var threshold *float32
+	var duration *time.Duration
+	var targetID discord.UserID
+	var booking voice.Schedule
+	var at, scheduleID string
+	var accessible *bool
 
 	for _, option := range data.Options {
 		switch option.Name {
@@ -83,9 +211,86 @@ func (c *VoiceCommand) Execute(ctx context.Context, s *session.Session, e *gatew
 			c.logger.Debug("Extracted action parameter", zap.String("action", action))
 		case "model":
 			if len(option.Value) > 0 {
-				model = option.String()
-				c.logger.Debug("Extracted model parameter", zap.String("model", model))
+				opts.Model = option.String()
+				c.logger.Debug("Extracted model parameter", zap.String("model", opts.Model))
+			}
+		case "language":
+			opts.Language = option.String()
+			c.logger.Debug("Extracted language parameter", zap.String("language", opts.Language))
+		case "manual_turns":
+			value, err := option.BoolValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid manual_turns value")
+			}
+			opts.ManualTurns = value
+		case "text_only":
+			value, err := option.BoolValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid text_only value")
+			}
+			opts.TextOnly = value
+		case "style":
+			opts.Style = option.String()
+		case "temperature":
+			value, err := option.FloatValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid temperature")
+			}
+			opts.Temperature = float32(value)
+		case "silence_threshold":
+			value, err := option.FloatValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid silence threshold")
+			}
+			t := float32(value)
+			threshold = &t
+		case "silence_duration_ms":
+			value, err := option.IntValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid silence duration")
+			}
+			d := time.Duration(value) * time.Millisecond
+			duration = &d
+		case "user":
+			sf, err := option.SnowflakeValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid user")
+			}
+			targetID = discord.UserID(sf)
+		case "at":
+			at = option.String()
+		case "weekly":
+			value, err := option.BoolValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid weekly value")
+			}
+			booking.Weekly = value
+		case "duration_minutes":
+			value, err := option.IntValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid duration")
+			}
+			booking.Minutes = int(value)
+		case "channel":
+			sf, err := option.SnowflakeValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid channel")
+			}
+			booking.ChannelID = discord.ChannelID(sf)
+		case "ping_role":
+			sf, err := option.SnowflakeValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid role")
+			}
+			booking.RoleID = discord.RoleID(sf)
+		case "schedule_id":
+			scheduleID = option.String()
+		case "enabled":
+			value, err := option.BoolValue()
+			if err != nil {
+				return c.respondError(s, e.ID, e.Token, "Invalid enabled value")
 			}
+			accessible = &value
 		}
 	}
 
@@ -101,17 +306,33 @@ func (c *VoiceCommand) Execute(ctx context.Context, s *session.Session, e *gatew
 	// Execute action
 	switch action {
 	case "start":
-		return c.handleStart(ctx, s, e, guildID, channelID, userID, model)
+		return c.handleStart(ctx, s, e, guildID, channelID, userID, opts)
 	case "stop":
 		return c.handleStop(ctx, s, e, guildID, userID)
 	case "status":
 		return c.handleStatus(ctx, s, e, guildID)
+	case "tune":
+		return c.handleTune(ctx, s, e, guildID, userID, threshold, duration)
+	case "go":
+		return c.handleGo(ctx, s, e, guildID, userID)
+	case "latency":
+		return c.handleLatency(s, e, guildID)
+	case "transfer":
+		return c.handleTransfer(ctx, s, e, guildID, userID, targetID)
+	case "schedule":
+		return c.handleSchedule(s, e, guildID, userID, at, booking)
+	case "unschedule":
+		return c.handleUnschedule(ctx, s, e, guildID, userID, scheduleID)
+	case "accessibility":
+		return c.handleAccessibility(s, e, userID, accessible)
+	case "transcript":
+		return c.handleTranscript(s, e, guildID)
 	default:
 		return c.respondError(s, e.ID, e.Token, "Unknown action: "+action)
 	}
 }
 
-func (c *VoiceCommand) handleStart(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, textChannelID discord.ChannelID, userID discord.UserID, model string) error {
+func (c *VoiceCommand) handleStart(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, textChannelID discord.ChannelID, userID discord.UserID, opts voice.StartOptions) error {
 	// Try to get the user's voice channel
 	voiceChannelID, err := c.getUserVoiceChannel(s, guildID, userID)
 	if err != nil {
@@ -140,7 +361,7 @@ func (c *VoiceCommand) handleStart(ctx context.Context, s *session.Session, e *g
 
 	// Start voice session asynchronously to avoid blocking the interaction response
 	go func() {
-		voiceSession, err := c.voiceService.Start(ctx, guildID, voiceChannelID, textChannelID, userID, model)
+		voiceSession, err := c.voiceService.Start(ctx, guildID, voiceChannelID, textChannelID, userID, opts)
 		if err != nil {
 			c.logger.Error("Failed to start voice session",
 				zap.Error(err),
@@ -149,6 +370,10 @@ func (c *VoiceCommand) handleStart(ctx context.Context, s *session.Session, e *g
 
 			// Send follow-up message with error
 			errorMsg := "❌ Failed to start voice session: " + err.Error()
+			var unavailable *internalopenai.UnavailableError
+			if errors.As(err, &unavailable) {
+				errorMsg = "❌ " + unavailable.UserMessage()
+			}
 			_, followUpErr := s.SendMessage(textChannelID, errorMsg)
 			if followUpErr != nil {
 				c.logger.Error("Failed to send error follow-up message", zap.Error(followUpErr))
@@ -162,11 +387,35 @@ func (c *VoiceCommand) handleStart(ctx context.Context, s *session.Session, e *g
 			usedModel = c.cfg.Voice.DefaultModel
 		}
 
-		successMsg := fmt.Sprintf("✅ Voice AI started in <#%s>\n🤖 Model: `%s`\n\nJust speak in the voice channel and I'll respond!",
-			voiceChannelID, usedModel)
+		languageInfo := ""
+		if voiceSession.Language != "" {
+			languageInfo = fmt.Sprintf("\n🌐 Language: `%s`", voiceSession.Language)
+		}
+		if voiceSession.Style != "" {
+			languageInfo += fmt.Sprintf("\n🎭 Style: `%s`", voiceSession.Style)
+		}
+		if voiceSession.Temperature != 0 {
+			languageInfo += fmt.Sprintf("\n🌡️ Temperature: `%.1f`", voiceSession.Temperature)
+		}
+
+		hint := "Just speak in the voice channel and I'll respond!"
+		if voiceSession.TextOnly {
+			hint = "Just speak in the voice channel and I'll answer here in text!"
+		}
+		var components discord.ContainerComponents
+		if voiceSession.ManualTurns {
+			hint = fmt.Sprintf("Manual turns: I'll respond when <@%s> presses the button below or uses `/voice go`.", userID)
+			components = respondNowComponents()
+		}
+
+		successMsg := fmt.Sprintf("✅ Voice AI started in <#%s>\n🤖 Model: `%s`%s\n\n%s",
+			voiceChannelID, usedModel, languageInfo, hint)
 
 		// Send success follow-up message
-		_, followUpErr := s.SendMessage(textChannelID, successMsg)
+		_, followUpErr := s.SendMessageComplex(textChannelID, api.SendMessageData{
+			Content:    successMsg,
+			Components: components,
+		})
 		if followUpErr != nil {
 			c.logger.Error("Failed to send success follow-up message", zap.Error(followUpErr))
 		}
@@ -196,6 +445,8 @@ func (c *VoiceCommand) handleStop(ctx context.Context, s *session.Session, e *ga
 			return c.respondError(s, e.ID, e.Token, "No active voice session in this server")
 		}
 		if strings.Contains(err.Error(), "permission") {
+			metrics.MarkDropped(ctx, metrics.DropPermission)
+
 			return c.respondError(s, e.ID, e.Token, "You don't have permission to stop this voice session")
 		}
 
@@ -248,8 +499,21 @@ func (c *VoiceCommand) handleStatus(_ context.Context, s *session.Session, e *ga
 			costInfo = fmt.Sprintf("\n💰 Session cost: $%.2f", status.SessionCost)
 		}
 
-		responseText = fmt.Sprintf("🎤 Voice AI Status\n🔊 Channel: <#%s>\n🤖 Model: `%s`\n⏱️ Duration: %s%s%s",
-			status.ChannelID, status.Model, duration, activeUsersList, costInfo)
+		metricsInfo := ""
+		if summary := status.Metrics.Summary(); summary != "" {
+			metricsInfo = "\n" + summary
+		}
+
+		languageInfo := ""
+		if status.Language != "" {
+			languageInfo = fmt.Sprintf("\n🌐 Language: `%s`", status.Language)
+		}
+		if status.TextOnly {
+			languageInfo += "\n💬 Replies: text only"
+		}
+
+		responseText = fmt.Sprintf("🎤 Voice AI Status\n🔊 Channel: <#%s>\n🤖 Model: `%s`%s\n⏱️ Duration: %s%s%s%s",
+			status.ChannelID, status.Model, languageInfo, duration, activeUsersList, costInfo, metricsInfo)
 	}
 
 	resp := api.InteractionResponse{
@@ -262,6 +526,294 @@ func (c *VoiceCommand) handleStatus(_ context.Context, s *session.Session, e *ga
 	return s.RespondInteraction(e.ID, e.Token, resp)
 }
 
+// handleLatency reports where the session's response latency goes, stage by stage.
+func (c *VoiceCommand) handleLatency(s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID) error {
+	status, err := c.voiceService.GetStatus(guildID)
+	if err != nil {
+		c.logger.Error("Failed to get voice session status",
+			zap.Error(err),
+			zap.String("guild_id", guildID.String()))
+
+		return c.respondError(s, e.ID, e.Token, "Failed to get voice session status")
+	}
+
+	responseText := "No active voice session in this server"
+	if status.Active {
+		responseText = status.Metrics.LatencyReport()
+		if responseText == "" {
+			responseText = "No responses yet, so there is no latency to report"
+		}
+	}
+
+	return respondEphemeral(s, e, responseText)
+}
+
+// handleTransfer hands control of the session to targetID.
+func (c *VoiceCommand) handleTransfer(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, userID, targetID discord.UserID) error {
+	if !targetID.IsValid() {
+		return c.respondError(s, e.ID, e.Token, "Choose the member to hand the session to with the user option")
+	}
+
+	if err := c.voiceService.Transfer(guildID, userID, targetID); err != nil {
+		msg := err.Error()
+		switch {
+		case strings.Contains(msg, "no active voice session"):
+			msg = "No active voice session in this server"
+		case strings.Contains(msg, "permission"):
+			metrics.MarkDropped(ctx, metrics.DropPermission)
+			msg = "Only the member controlling this voice session can transfer it"
+		}
+
+		return c.respondError(s, e.ID, e.Token, msg)
+	}
+
+	// The service announces the transfer in the session's text channel
+	return respondEphemeral(s, e, fmt.Sprintf("✅ %s now controls the voice session", targetID.Mention()))
+}
+
+// handleSchedule books a session starting at at, or lists the server's
+// booked sessions when no time is given.
+func (c *VoiceCommand) handleSchedule(s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, userID discord.UserID, at string, booking voice.Schedule) error {
+	if at == "" {
+		return respondEphemeral(s, e, scheduleList(c.voiceService.Schedules(guildID)))
+	}
+
+	startAt, err := voice.ParseScheduleTime(at, time.Now())
+	if err != nil {
+		return c.respondError(s, e.ID, e.Token, err.Error())
+	}
+	if !booking.ChannelID.IsValid() {
+		booking.ChannelID, err = c.getUserVoiceChannel(s, guildID, userID)
+		if err != nil {
+			return c.respondError(s, e.ID, e.Token, "Choose a voice channel with the channel option, or join one first")
+		}
+	}
+	booking.GuildID = guildID
+	booking.TextChannelID = e.ChannelID
+	booking.BookedBy = userID
+	booking.StartAt = startAt
+
+	booking, err = c.voiceService.ScheduleSession(booking)
+	if err != nil {
+		return c.respondError(s, e.ID, e.Token, "Failed to schedule voice session: "+err.Error())
+	}
+
+	repeat := ""
+	if booking.Weekly {
+		repeat = ", every week"
+	}
+
+	return respondEphemeral(s, e, fmt.Sprintf("📅 Voice session `%s` scheduled in %s for %s (<t:%d:R>)%s, lasting %d minutes. Cancel it with `/voice action:unschedule schedule_id:%s`.",
+		booking.ID, booking.ChannelID.Mention(), booking.StartAt.Format("2006-01-02 15:04 UTC"), booking.StartAt.Unix(), repeat, booking.Minutes, booking.ID))
+}
+
+// scheduleList formats the booked sessions of a server.
+func scheduleList(schedules []voice.Schedule) string {
+	if len(schedules) == 0 {
+		return "No voice sessions are scheduled in this server. Book one with `/voice action:schedule at:<time>`."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📅 Scheduled voice sessions:")
+	for _, sch := range schedules {
+		fmt.Fprintf(&sb, "\n• `%s` %s <t:%d:F>, %d minutes", sch.ID, sch.ChannelID.Mention(), sch.StartAt.Unix(), sch.Minutes)
+		if sch.Weekly {
+			sb.WriteString(", weekly")
+		}
+		fmt.Fprintf(&sb, ", booked by %s", sch.BookedBy.Mention())
+	}
+
+	return sb.String()
+}
+
+// handleUnschedule cancels the booked session scheduleID.
+func (c *VoiceCommand) handleUnschedule(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, userID discord.UserID, scheduleID string) error {
+	if scheduleID == "" {
+		return c.respondError(s, e.ID, e.Token, "Give the schedule_id of the session to cancel; /voice action:schedule lists them")
+	}
+
+	if err := c.voiceService.Unschedule(guildID, userID, scheduleID); err != nil {
+		msg := "Failed to cancel scheduled session: " + err.Error()
+		switch {
+		case errors.Is(err, voice.ErrScheduleNotFound):
+			msg = "No scheduled session with that ID in this server"
+		case strings.Contains(err.Error(), "permission"):
+			metrics.MarkDropped(ctx, metrics.DropPermission)
+			msg = "Only the member who booked this session can cancel it"
+		}
+
+		return c.respondError(s, e.ID, e.Token, msg)
+	}
+
+	return respondEphemeral(s, e, fmt.Sprintf("🗑️ Scheduled voice session `%s` cancelled", scheduleID))
+}
+
+// respondEphemeral answers the interaction with content only its user sees.
+func (c *VoiceCommand) handleAccessibility(s *session.Session, e *gateway.InteractionCreateEvent, userID discord.UserID, enabled *bool) error {
+	if enabled == nil {
+		if c.voiceService.Accessibility(userID) {
+			return respondEphemeral(s, e, "🔊 Text alternatives are on: everything the bot says in your voice channel is also posted as text mentioning you. Turn them off with `enabled:False`.")
+		}
+
+		return respondEphemeral(s, e, "Text alternatives are off. Turn them on with `enabled:True` to have everything the bot says in your voice channel posted as text mentioning you.")
+	}
+
+	if err := c.voiceService.SetAccessibility(userID, *enabled); err != nil {
+		c.logger.Error("Failed to save voice accessibility preference",
+			zap.Error(err),
+			zap.String("user_id", userID.String()))
+
+		return c.respondError(s, e.ID, e.Token, "Failed to save your accessibility preference")
+	}
+
+	if *enabled {
+		return respondEphemeral(s, e, "🔊 Text alternatives turned on: everything the bot says in your voice channel will also be posted as text mentioning you.")
+	}
+
+	return respondEphemeral(s, e, "Text alternatives turned off.")
+}
+
+// handleTranscript shows the transcript of the server's current or most
+// recent voice session, attached as a text file when too long for a message.
+func (c *VoiceCommand) handleTranscript(s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID) error {
+	transcript, ok := c.voiceService.Transcript(guildID)
+	if !ok {
+		return respondEphemeral(s, e, "No voice session has run in this server since the bot started")
+	}
+
+	state := "live"
+	if !transcript.Active() {
+		state = fmt.Sprintf("ended <t:%d:R>", transcript.EndedAt.Unix())
+	}
+	header := fmt.Sprintf("📝 Transcript of the voice session in %s, started <t:%d:f> (%s)",
+		transcript.ChannelID.Mention(), transcript.StartedAt.Unix(), state)
+	if transcript.Dropped > 0 {
+		header += fmt.Sprintf(", without its %d earliest turns", transcript.Dropped)
+	}
+	if len(transcript.Turns) == 0 {
+		return respondEphemeral(s, e, header+"\n\nNothing has been said yet.")
+	}
+
+	var lines strings.Builder
+	for _, turn := range transcript.Turns {
+		fmt.Fprintf(&lines, "\n`%s` **%s:** %s", turn.At.UTC().Format(time.TimeOnly), turn.Speaker, turn.Text)
+	}
+	if content := header + "\n" + lines.String(); len(content) <= maxVoiceMessageLength {
+		return respondEphemeral(s, e, content)
+	}
+
+	var text strings.Builder
+	for _, turn := range transcript.Turns {
+		fmt.Fprintf(&text, "[%s] %s: %s\n", turn.At.UTC().Format(time.DateTime), turn.Speaker, turn.Text)
+	}
+
+	return s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(header),
+			Flags:           discord.EphemeralMessage,
+			AllowedMentions: &api.AllowedMentions{},
+			Files: []sendpart.File{{
+				Name:   fmt.Sprintf("voice-transcript-%s.txt", transcript.StartedAt.UTC().Format("20060102-150405")),
+				Reader: strings.NewReader(text.String()),
+			}},
+		},
+	})
+}
+
+func (c *VoiceCommand) handleTune(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, userID discord.UserID, threshold *float32, duration *time.Duration) error {
+	if threshold == nil && duration == nil {
+		return c.respondError(s, e.ID, e.Token, "Provide silence_threshold and/or silence_duration_ms to tune the session")
+	}
+
+	status, err := c.voiceService.Tune(guildID, userID, threshold, duration)
+	if err != nil {
+		if strings.Contains(err.Error(), "no active voice session") {
+			return c.respondError(s, e.ID, e.Token, "No active voice session in this server")
+		}
+		if strings.Contains(err.Error(), "permission") {
+			metrics.MarkDropped(ctx, metrics.DropPermission)
+
+			return c.respondError(s, e.ID, e.Token, "Only the user who started this voice session can tune it")
+		}
+
+		c.logger.Error("Failed to tune voice session",
+			zap.Error(err),
+			zap.String("guild_id", guildID.String()),
+			zap.String("user_id", userID.String()))
+
+		return c.respondError(s, e.ID, e.Token, "Failed to tune voice session: "+err.Error())
+	}
+
+	resp := api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content: option.NewNullableString(fmt.Sprintf("🎚️ Silence detection updated\n🔉 Threshold: `%.3f`\n⏳ Duration: `%s`",
+				status.SilenceThreshold, status.SilenceDuration)),
+		},
+	}
+
+	return s.RespondInteraction(e.ID, e.Token, resp)
+}
+
+func (c *VoiceCommand) handleGo(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, guildID discord.GuildID, userID discord.UserID) error {
+	if msg := c.commitTurn(ctx, guildID, userID); msg != "" {
+		return c.respondError(s, e.ID, e.Token, msg)
+	}
+
+	resp := api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content: option.NewNullableString("🗣️ Responding..."),
+			Flags:   discord.EphemeralMessage,
+		},
+	}
+
+	return s.RespondInteraction(e.ID, e.Token, resp)
+}
+
+// HandleComponent handles the "Respond now" button attached to manual-turn sessions.
+func (c *VoiceCommand) HandleComponent(ctx context.Context, s *session.Session, e *gateway.InteractionCreateEvent, data discord.ComponentInteraction) error {
+	if data.ID() != voiceGoComponentID {
+		return fmt.Errorf("unknown voice component: %s", data.ID())
+	}
+
+	if e.GuildID == 0 {
+		return c.respondError(s, e.ID, e.Token, "Voice commands can only be used in servers")
+	}
+
+	if msg := c.commitTurn(ctx, e.GuildID, e.SenderID()); msg != "" {
+		return c.respondError(s, e.ID, e.Token, msg)
+	}
+
+	// Acknowledge without changing the message so the button stays usable
+	return s.RespondInteraction(e.ID, e.Token, api.InteractionResponse{Type: api.DeferredMessageUpdate})
+}
+
+// commitTurn commits buffered audio, returning a user-facing error message on failure.
+func (c *VoiceCommand) commitTurn(ctx context.Context, guildID discord.GuildID, userID discord.UserID) string {
+	err := c.voiceService.CommitTurn(guildID, userID)
+	if err == nil {
+		return ""
+	}
+
+	if strings.Contains(err.Error(), "no active voice session") {
+		return "No active voice session in this server"
+	}
+	if strings.Contains(err.Error(), "permission") {
+		metrics.MarkDropped(ctx, metrics.DropPermission)
+
+		return "Only the user who started this voice session can trigger a response"
+	}
+
+	c.logger.Error("Failed to commit voice turn",
+		zap.Error(err),
+		zap.String("guild_id", guildID.String()),
+		zap.String("user_id", userID.String()))
+
+	return "Failed to commit voice turn: " + err.Error()
+}
+
 func (c *VoiceCommand) getUserVoiceChannel(s *session.Session, guildID discord.GuildID, userID discord.UserID) (discord.ChannelID, error) {
 	// Try to get the user's voice state from the state manager
 	voiceState, err := c.state.VoiceState(guildID, userID)
@@ -7,5 +7,5 @@ import (
 
 // Module provides configuration dependencies.
 var Module = fx.Module("config",
-	fx.Provide(LoadConfig),
+	fx.Provide(LoadConfig, NewProvider),
 )
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// reloadDelay lets a burst of writes to the config file, as editors and
+// deployment tools make, settle before it is read.
+const reloadDelay = 500 * time.Millisecond
+
+// restartSections are the config sections read only when the bot starts.
+// Changes to them are published, but take effect after a restart.
+var restartSections = []string{"discord", "storage", "archive", "metrics", "reload"}
+
+// Provider publishes the configuration in effect. Components that read it
+// through Current on every use pick up changes to config.yaml without a
+// restart; the rest keep the configuration they started with.
+type Provider interface {
+	// Current returns the configuration in effect, which must not be modified.
+	Current() *Config
+	// Subscribe calls fn with the previous and the new configuration after
+	// each reload, until the returned function is called.
+	Subscribe(fn func(previous, current *Config)) (unsubscribe func())
+}
+
+// NewStaticProvider returns a Provider that always returns cfg.
+func NewStaticProvider(cfg *Config) Provider {
+	return staticProvider{cfg: cfg}
+}
+
+type staticProvider struct {
+	cfg *Config
+}
+
+func (p staticProvider) Current() *Config {
+	return p.cfg
+}
+
+func (p staticProvider) Subscribe(func(previous, current *Config)) func() {
+	return func() {}
+}
+
+// NewProvider returns a Provider of cfg, loaded from path. With reload
+// enabled it watches path while the application runs and publishes every
+// valid change; an invalid file is logged and the running configuration kept.
+func NewProvider(lc fx.Lifecycle, logger *zap.Logger, path string, cfg *Config) Provider {
+	if !cfg.Reload.Enabled {
+		return NewStaticProvider(cfg)
+	}
+
+	w := NewWatcher(logger, path, cfg)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			return w.Start()
+		},
+		OnStop: func(context.Context) error {
+			return w.Stop()
+		},
+	})
+
+	return w
+}
+
+// Watcher is a Provider that reloads its configuration when the file it was
+// loaded from changes.
+type Watcher struct {
+	logger  *zap.Logger
+	path    string
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex
+	subscribers map[int]func(previous, current *Config)
+	nextID      int
+	watcher     *fsnotify.Watcher
+	timer       *time.Timer
+	done        chan struct{}
+}
+
+// NewWatcher creates a Watcher of path, whose configuration is cfg until the
+// file changes. It does not watch the file until Start is called.
+func NewWatcher(logger *zap.Logger, path string, cfg *Config) *Watcher {
+	w := &Watcher{
+		logger:      logger.Named("config_watcher"),
+		path:        path,
+		subscribers: make(map[int]func(previous, current *Config)),
+	}
+	w.current.Store(cfg)
+
+	return w
+}
+
+// Current returns the configuration in effect.
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe calls fn after each reload until the returned function is called.
+func (w *Watcher) Subscribe(fn func(previous, current *Config)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.subscribers[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.subscribers, id)
+	}
+}
+
+// Start watches the config file. It watches the file's directory, as editors
+// and Kubernetes config maps replace the file rather than write to it.
+func (w *Watcher) Start() error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	if err := fsw.Add(filepath.Dir(w.path)); err != nil {
+		_ = fsw.Close()
+
+		return fmt.Errorf("failed to watch %s: %w", w.path, err)
+	}
+
+	w.mu.Lock()
+	w.watcher = fsw
+	w.done = make(chan struct{})
+	w.mu.Unlock()
+
+	go w.watch(fsw, w.done)
+	w.logger.Info("Watching config for changes", zap.String("path", w.path))
+
+	return nil
+}
+
+// Stop stops watching the config file.
+func (w *Watcher) Stop() error {
+	w.mu.Lock()
+	fsw, done := w.watcher, w.done
+	w.watcher = nil
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+	if fsw == nil {
+		return nil
+	}
+
+	err := fsw.Close()
+	<-done
+
+	return err
+}
+
+func (w *Watcher) watch(fsw *fsnotify.Watcher, done chan struct{}) {
+	defer close(done)
+
+	name := filepath.Clean(w.path)
+	for {
+		select {
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != name || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				continue
+			}
+
+			w.mu.Lock()
+			if w.timer == nil {
+				w.timer = time.AfterFunc(reloadDelay, w.Reload)
+			} else {
+				w.timer.Reset(reloadDelay)
+			}
+			w.mu.Unlock()
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warn("Config watcher error", zap.Error(err))
+		}
+	}
+}
+
+// Reload reads the config file and, when it is valid and changed, makes it
+// the configuration in effect and tells the subscribers.
+func (w *Watcher) Reload() {
+	cfg, err := LoadConfig(w.path)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		w.logger.Error("Config reload rejected, keeping the running config", zap.Error(err), zap.String("path", w.path))
+
+		return
+	}
+
+	w.mu.Lock()
+	previous := w.current.Load()
+	changed := ChangedSections(previous, cfg)
+	if len(changed) == 0 {
+		w.mu.Unlock()
+
+		return
+	}
+	w.current.Store(cfg)
+	subscribers := make([]func(previous, current *Config), 0, len(w.subscribers))
+	for _, fn := range w.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	w.mu.Unlock()
+
+	w.logger.Info("Config reloaded", zap.Strings("sections", changed))
+	var restart []string
+	for _, section := range changed {
+		if slices.Contains(restartSections, section) {
+			restart = append(restart, section)
+		}
+	}
+	if len(restart) > 0 {
+		w.logger.Warn("Config changes take effect after a restart", zap.Strings("sections", restart))
+	}
+
+	for _, fn := range subscribers {
+		fn(previous, cfg)
+	}
+}
+
+// ChangedSections returns the top-level sections, by their YAML names, that
+// differ between a and b.
+func ChangedSections(a, b *Config) []string {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	var changed []string
+	for i := range va.NumField() {
+		if reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("yaml"), ",")
+		changed = append(changed, name)
+	}
+
+	return changed
+}
+
+// Validate reports settings that would leave the bot unable to run, so a
+// broken reload never replaces the running configuration.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Discord.BotToken == "" {
+		errs = append(errs, errors.New("discord.bot_token is required"))
+	}
+	if len(c.ChatModels()) == 0 {
+		errs = append(errs, errors.New("openai.models must list at least one model"))
+	}
+	switch c.OpenAI.ThreadPolicy {
+	case "", "anyone", "initiator", "roles":
+	default:
+		errs = append(errs, fmt.Errorf("openai.thread_policy %q is not anyone, initiator or roles", c.OpenAI.ThreadPolicy))
+	}
+	switch c.OpenAI.Limits.ContextOverflow {
+	case "", "trim", "summarize", "reject":
+	default:
+		errs = append(errs, fmt.Errorf("openai.limits.context_overflow %q is not trim, summarize or reject", c.OpenAI.Limits.ContextOverflow))
+	}
+	for _, limits := range []struct {
+		name   string
+		limits BudgetLimits
+	}{{"budgets.user", c.Budgets.User}, {"budgets.guild", c.Budgets.Guild}} {
+		l := limits.limits
+		if l.DailyTokens < 0 || l.MonthlyTokens < 0 || l.DailyUSD < 0 || l.MonthlyUSD < 0 {
+			errs = append(errs, fmt.Errorf("%s limits must not be negative", limits.name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
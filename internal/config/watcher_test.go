@@ -0,0 +1,114 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const baseConfig = `discord:
+  bot_token: "token"
+openai:
+  models: ["gpt-4o-mini"]
+`
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func newWatcher(t *testing.T) (*config.Watcher, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, baseConfig)
+	cfg, err := config.LoadConfig(path)
+	require.NoError(t, err)
+
+	return config.NewWatcher(zap.NewNop(), path, cfg), path
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	w, path := newWatcher(t)
+	initial := w.Current()
+
+	var published [][2]*config.Config
+	unsubscribe := w.Subscribe(func(previous, current *config.Config) {
+		published = append(published, [2]*config.Config{previous, current})
+	})
+
+	writeConfig(t, path, baseConfig+`  thread_policy: "initiator"
+budgets:
+  user:
+    daily_tokens: 1000
+`)
+	w.Reload()
+	require.Len(t, published, 1)
+	assert.Same(t, initial, published[0][0])
+	assert.Same(t, w.Current(), published[0][1])
+	assert.Equal(t, "initiator", w.Current().OpenAI.ThreadPolicy)
+	assert.Equal(t, 1000, w.Current().Budgets.User.DailyTokens)
+	assert.Equal(t, []string{"openai", "budgets"}, config.ChangedSections(initial, w.Current()))
+
+	// Reading the same file again publishes nothing
+	w.Reload()
+	assert.Len(t, published, 1)
+
+	unsubscribe()
+	writeConfig(t, path, baseConfig)
+	w.Reload()
+	assert.Len(t, published, 1, "unsubscribed functions are not called")
+	assert.Empty(t, w.Current().OpenAI.ThreadPolicy)
+}
+
+func TestWatcher_RejectsInvalidConfig(t *testing.T) {
+	w, path := newWatcher(t)
+	running := w.Current()
+
+	for name, content := range map[string]string{
+		"unparsable":       "discord: [",
+		"no bot token":     "openai:\n  models: [\"gpt-4o-mini\"]\n",
+		"no models":        "discord:\n  bot_token: \"token\"\n",
+		"unknown policy":   baseConfig + "  thread_policy: \"everyone\"\n",
+		"negative budgets": baseConfig + "budgets:\n  guild:\n    monthly_usd: -5\n",
+	} {
+		writeConfig(t, path, content)
+		w.Reload()
+		assert.Same(t, running, w.Current(), name)
+	}
+}
+
+func TestWatcher_WatchesFile(t *testing.T) {
+	w, path := newWatcher(t)
+	require.NoError(t, w.Start())
+	defer func() { assert.NoError(t, w.Stop()) }()
+
+	reloaded := make(chan *config.Config, 1)
+	w.Subscribe(func(_, current *config.Config) { reloaded <- current })
+
+	// Editors save by writing a new file and renaming it over the old one
+	tmp := path + ".tmp"
+	writeConfig(t, tmp, strings.Replace(baseConfig, "gpt-4o-mini", "gpt-4.1", 1))
+	require.NoError(t, os.Rename(tmp, path))
+
+	select {
+	case cfg := <-reloaded:
+		assert.Equal(t, []string{"gpt-4.1"}, cfg.OpenAI.Models)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded")
+	}
+}
+
+func TestStaticProvider(t *testing.T) {
+	cfg := &config.Config{}
+	p := config.NewStaticProvider(cfg)
+	assert.Same(t, cfg, p.Current())
+	p.Subscribe(func(_, _ *config.Config) { t.Fatal("static configs do not change") })()
+}
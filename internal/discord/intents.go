@@ -0,0 +1,70 @@
+package discord
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// DefaultIntents are requested when discord.intents is not configured.
+const DefaultIntents = gateway.IntentGuilds |
+	gateway.IntentGuildMessages |
+	gateway.IntentGuildIntegrations |
+	gateway.IntentGuildVoiceStates |
+	gateway.IntentGuildMembers
+
+// intentNames maps config names to gateway intents. Names follow Discord's
+// documentation, lower-cased (e.g. GUILD_VOICE_STATES -> "guild_voice_states").
+var intentNames = map[string]gateway.Intents{
+	"guilds":                   gateway.IntentGuilds,
+	"guild_members":            gateway.IntentGuildMembers,
+	"guild_moderation":         gateway.IntentGuildModeration,
+	"guild_emojis":             gateway.IntentGuildEmojis,
+	"guild_integrations":       gateway.IntentGuildIntegrations,
+	"guild_webhooks":           gateway.IntentGuildWebhooks,
+	"guild_invites":            gateway.IntentGuildInvites,
+	"guild_voice_states":       gateway.IntentGuildVoiceStates,
+	"guild_presences":          gateway.IntentGuildPresences,
+	"guild_messages":           gateway.IntentGuildMessages,
+	"guild_message_reactions":  gateway.IntentGuildMessageReactions,
+	"guild_message_typing":     gateway.IntentGuildMessageTyping,
+	"direct_messages":          gateway.IntentDirectMessages,
+	"direct_message_reactions": gateway.IntentDirectMessageReactions,
+	"direct_message_typing":    gateway.IntentDirectMessageTyping,
+	"message_content":          gateway.IntentMessageContent,
+	"guild_scheduled_events":   gateway.IntentGuildScheduledEvents,
+}
+
+// ParseIntents converts configured intent names into gateway intents.
+// An empty list yields DefaultIntents.
+func ParseIntents(names []string) (gateway.Intents, error) {
+	if len(names) == 0 {
+		return DefaultIntents, nil
+	}
+
+	var intents gateway.Intents
+	for _, name := range names {
+		intent, ok := intentNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("unknown gateway intent %q", name)
+		}
+		intents |= intent
+	}
+
+	return intents, nil
+}
+
+// IntentNames returns the config names of all intents set in intents, sorted.
+func IntentNames(intents gateway.Intents) []string {
+	var names []string
+	for name, intent := range intentNames {
+		if intents.Has(intent) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
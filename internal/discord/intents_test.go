@@ -0,0 +1,38 @@
+package discord_test
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/discord"
+)
+
+func TestParseIntents(t *testing.T) {
+	t.Run("DefaultsWhenEmpty", func(t *testing.T) {
+		intents, err := discord.ParseIntents(nil)
+		require.NoError(t, err)
+		assert.Equal(t, discord.DefaultIntents, intents)
+	})
+
+	t.Run("NamesAreCaseInsensitive", func(t *testing.T) {
+		intents, err := discord.ParseIntents([]string{"GUILDS", " guild_voice_states "})
+		require.NoError(t, err)
+		assert.Equal(t, gateway.IntentGuilds|gateway.IntentGuildVoiceStates, intents)
+		assert.Equal(t, []string{"guild_voice_states", "guilds"}, discord.IntentNames(intents))
+	})
+
+	t.Run("UnknownName", func(t *testing.T) {
+		_, err := discord.ParseIntents([]string{"guilds", "voice"})
+		assert.ErrorContains(t, err, `"voice"`)
+	})
+}
+
+func TestFeatureMissingIntents(t *testing.T) {
+	missing := discord.VoiceFeature.MissingIntents(gateway.IntentGuilds | gateway.IntentGuildMessages)
+	assert.Equal(t, gateway.IntentGuildVoiceStates, missing)
+
+	assert.Zero(t, discord.VoiceFeature.MissingIntents(discord.DefaultIntents))
+}
@@ -0,0 +1,68 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// maxAllowedRoles is the most roles Discord accepts in allowed mentions.
+const maxAllowedRoles = 100
+
+// massMentionReplacer breaks @everyone and @here with a zero-width space so
+// they render as text even where allowed mentions are not applied.
+var massMentionReplacer = strings.NewReplacer("@everyone", "@\u200beveryone", "@here", "@\u200bhere")
+
+// MentionPolicy decides which mentions in generated text may notify anyone.
+// @everyone and @here never do; users and roles only when configured.
+type MentionPolicy struct {
+	users bool
+	roles []discord.RoleID
+}
+
+// NewMentionPolicy creates the MentionPolicy configured under discord.mentions.
+func NewMentionPolicy(cfg *config.Config) (*MentionPolicy, error) {
+	return ParseMentionPolicy(cfg.Discord.Mentions)
+}
+
+// ParseMentionPolicy validates a mentions config.
+func ParseMentionPolicy(cfg config.MentionsConfig) (*MentionPolicy, error) {
+	if len(cfg.RoleIDs) > maxAllowedRoles {
+		return nil, fmt.Errorf("at most %d mentionable roles can be configured, got %d", maxAllowedRoles, len(cfg.RoleIDs))
+	}
+
+	policy := &MentionPolicy{users: cfg.Users}
+	for _, id := range cfg.RoleIDs {
+		sf, err := discord.ParseSnowflake(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("invalid mentionable role ID %q: %w", id, err)
+		}
+		policy.roles = append(policy.roles, discord.RoleID(sf))
+	}
+
+	return policy, nil
+}
+
+// Allowed returns the allowed mentions to send with generated text.
+func (p *MentionPolicy) Allowed() *api.AllowedMentions {
+	// An empty, non-nil Parse stops Discord from parsing any mention type
+	allowed := &api.AllowedMentions{Parse: []api.AllowedMentionType{}}
+	if p == nil {
+		return allowed
+	}
+	if p.users {
+		allowed.Parse = append(allowed.Parse, api.AllowUserMention)
+	}
+	allowed.Roles = p.roles
+
+	return allowed
+}
+
+// Sanitize defuses @everyone and @here in text.
+func (p *MentionPolicy) Sanitize(text string) string {
+	return massMentionReplacer.Replace(text)
+}
@@ -0,0 +1,50 @@
+package discord_test
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	arikawadiscord "github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/discord"
+)
+
+func TestParseMentionPolicy(t *testing.T) {
+	t.Run("DefaultsToNoPings", func(t *testing.T) {
+		policy, err := discord.ParseMentionPolicy(config.MentionsConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, &api.AllowedMentions{Parse: []api.AllowedMentionType{}}, policy.Allowed())
+	})
+
+	t.Run("UsersAndRoles", func(t *testing.T) {
+		policy, err := discord.ParseMentionPolicy(config.MentionsConfig{Users: true, RoleIDs: []string{"42"}})
+		require.NoError(t, err)
+		allowed := policy.Allowed()
+		assert.Equal(t, []api.AllowedMentionType{api.AllowUserMention}, allowed.Parse)
+		assert.Equal(t, []arikawadiscord.RoleID{42}, allowed.Roles)
+		assert.NoError(t, allowed.Verify())
+	})
+
+	t.Run("InvalidRole", func(t *testing.T) {
+		_, err := discord.ParseMentionPolicy(config.MentionsConfig{RoleIDs: []string{"admins"}})
+		assert.ErrorContains(t, err, `"admins"`)
+	})
+
+	t.Run("TooManyRoles", func(t *testing.T) {
+		_, err := discord.ParseMentionPolicy(config.MentionsConfig{RoleIDs: make([]string, 101)})
+		assert.ErrorContains(t, err, "at most 100")
+	})
+}
+
+func TestMentionPolicy_Sanitize(t *testing.T) {
+	policy, err := discord.ParseMentionPolicy(config.MentionsConfig{Users: true})
+	require.NoError(t, err)
+
+	out := policy.Sanitize("Hey @everyone and @here, ask <@123>")
+	assert.NotContains(t, out, "@everyone")
+	assert.NotContains(t, out, "@here")
+	assert.Contains(t, out, "<@123>", "user mentions are left to allowed mentions")
+}
@@ -4,6 +4,7 @@ package discord
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
@@ -22,6 +23,8 @@ var Module = fx.Module("discord",
 		NewSession,
 		NewState,
 		ProvideApplicationID,
+		NewWebhookPool,
+		NewMentionPolicy,
 	),
 )
 
@@ -37,6 +40,7 @@ type SessionParams struct {
 type SessionResult struct {
 	fx.Out
 	Session *session.Session
+	Intents gateway.Intents // Intents requested from the gateway, for feature preflight checks
 }
 
 // NewSession creates and manages a new Discord session.
@@ -49,8 +53,14 @@ func NewSession(params SessionParams) (SessionResult, error) {
 		return SessionResult{}, errors.New("application ID is not set in config")
 	}
 
+	intents, err := ParseIntents(params.Cfg.Discord.Intents)
+	if err != nil {
+		return SessionResult{}, fmt.Errorf("invalid discord intents config: %w", err)
+	}
+
 	s := session.New("Bot " + params.Cfg.Discord.BotToken)
-	s.AddIntents(gateway.IntentGuilds | gateway.IntentGuildMessages | gateway.IntentGuildIntegrations | gateway.IntentGuildVoiceStates | gateway.IntentGuildMembers)
+	s.AddIntents(intents)
+	params.Logger.Info("Requesting gateway intents", zap.Strings("intents", IntentNames(intents)))
 
 	params.LC.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
@@ -65,7 +75,7 @@ func NewSession(params SessionParams) (SessionResult, error) {
 		},
 	})
 
-	return SessionResult{Session: s}, nil
+	return SessionResult{Session: s, Intents: intents}, nil
 }
 
 // StateParams holds dependencies for NewState.
@@ -0,0 +1,192 @@
+package discord
+
+import (
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"go.uber.org/zap"
+)
+
+// Feature describes the gateway intents and guild permissions a bot feature needs.
+type Feature struct {
+	Name        string
+	Intents     gateway.Intents
+	Permissions discord.Permissions
+}
+
+// ChatFeature covers /chat and replies inside conversation threads.
+var ChatFeature = Feature{
+	Name:    "chat",
+	Intents: gateway.IntentGuilds | gateway.IntentGuildMessages | gateway.IntentMessageContent,
+	Permissions: discord.PermissionViewChannel |
+		discord.PermissionSendMessages |
+		discord.PermissionCreatePublicThreads |
+		discord.PermissionSendMessagesInThreads |
+		discord.PermissionReadMessageHistory,
+}
+
+// VoiceFeature covers /voice sessions.
+var VoiceFeature = Feature{
+	Name:    "voice",
+	Intents: gateway.IntentGuilds | gateway.IntentGuildVoiceStates,
+	Permissions: discord.PermissionViewChannel |
+		discord.PermissionConnect |
+		discord.PermissionSpeak |
+		discord.PermissionSendMessages,
+}
+
+// CharacterFeature covers /chat replies posted as a character through webhooks.
+var CharacterFeature = Feature{
+	Name:        "characters",
+	Intents:     gateway.IntentGuilds,
+	Permissions: discord.PermissionManageWebhooks,
+}
+
+// MissingIntents returns the intents f needs that are not in have.
+func (f Feature) MissingIntents(have gateway.Intents) gateway.Intents {
+	return f.Intents &^ have
+}
+
+// CheckIntents logs an actionable warning for every feature whose intents are
+// not requested, and reports whether all features are satisfied.
+func CheckIntents(logger *zap.Logger, have gateway.Intents, features ...Feature) bool {
+	ok := true
+	for _, f := range features {
+		missing := f.MissingIntents(have)
+		if missing == 0 {
+			continue
+		}
+		ok = false
+
+		logger.Warn("Gateway intents required by feature are not enabled; add them to discord.intents in config "+
+			"(privileged intents such as message_content and guild_members must also be enabled in the Discord developer portal)",
+			zap.String("feature", f.Name),
+			zap.Strings("missingIntents", IntentNames(missing)))
+	}
+
+	return ok
+}
+
+// CheckPermissions logs an actionable warning for every guild where the bot's
+// roles lack permissions a feature needs. Channel overwrites are not considered.
+func CheckPermissions(s *session.Session, logger *zap.Logger, guildIDs []discord.GuildID, features ...Feature) {
+	me, err := s.Me()
+	if err != nil {
+		logger.Warn("Skipping permission preflight: failed to get bot user", zap.Error(err))
+
+		return
+	}
+
+	for _, guildID := range guildIDs {
+		missing, err := MissingPermissions(s, guildID, me.ID, features...)
+		if err != nil {
+			logger.Warn("Skipping permission preflight for guild",
+				zap.Stringer("guildID", guildID),
+				zap.Error(err))
+
+			continue
+		}
+
+		for _, f := range features {
+			if missing[f.Name] == 0 {
+				continue
+			}
+
+			logger.Warn("Bot role is missing permissions required by feature; grant them to the bot's role in server settings",
+				zap.String("feature", f.Name),
+				zap.Stringer("guildID", guildID),
+				zap.String("missingPermissions", PermissionNames(missing[f.Name])))
+		}
+	}
+}
+
+// MissingPermissions returns, by feature name, the permissions userID's roles
+// in guildID lack for each feature that is not fully granted. Channel
+// overwrites are not considered.
+func MissingPermissions(s *session.Session, guildID discord.GuildID, userID discord.UserID, features ...Feature) (map[string]discord.Permissions, error) {
+	perms, err := guildPermissions(s, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make(map[string]discord.Permissions)
+	for _, f := range features {
+		if gap := f.Permissions &^ perms; gap != 0 {
+			missing[f.Name] = gap
+		}
+	}
+
+	return missing, nil
+}
+
+// guildPermissions computes the guild-level permissions of userID from its roles.
+func guildPermissions(s *session.Session, guildID discord.GuildID, userID discord.UserID) (discord.Permissions, error) {
+	member, err := s.Member(guildID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return RolePermissions(s, guildID, member.RoleIDs)
+}
+
+// RolePermissions computes the guild-level permissions a member with roleIDs
+// has in guildID. Channel overwrites are not considered.
+func RolePermissions(s *session.Session, guildID discord.GuildID, roleIDs []discord.RoleID) (discord.Permissions, error) {
+	roles, err := s.Roles(guildID)
+	if err != nil {
+		return 0, err
+	}
+
+	memberRoles := make(map[discord.RoleID]struct{}, len(roleIDs))
+	for _, id := range roleIDs {
+		memberRoles[id] = struct{}{}
+	}
+
+	var perms discord.Permissions
+	for _, role := range roles {
+		// The @everyone role shares the guild's ID
+		_, has := memberRoles[role.ID]
+		if has || discord.GuildID(role.ID) == guildID {
+			perms |= role.Permissions
+		}
+	}
+
+	if perms.Has(discord.PermissionAdministrator) {
+		return discord.PermissionAll, nil
+	}
+
+	return perms, nil
+}
+
+// permissionLabels names the permissions checked by the built-in features.
+var permissionLabels = []struct {
+	perm discord.Permissions
+	name string
+}{
+	{discord.PermissionViewChannel, "View Channel"},
+	{discord.PermissionSendMessages, "Send Messages"},
+	{discord.PermissionCreatePublicThreads, "Create Public Threads"},
+	{discord.PermissionSendMessagesInThreads, "Send Messages in Threads"},
+	{discord.PermissionReadMessageHistory, "Read Message History"},
+	{discord.PermissionConnect, "Connect"},
+	{discord.PermissionSpeak, "Speak"},
+	{discord.PermissionManageWebhooks, "Manage Webhooks"},
+}
+
+// PermissionNames lists the labels of perms, such as "Connect, Speak".
+func PermissionNames(perms discord.Permissions) string {
+	var names []string
+	for _, label := range permissionLabels {
+		if perms.Has(label.perm) {
+			perms &^= label.perm
+			names = append(names, label.name)
+		}
+	}
+	if perms != 0 {
+		names = append(names, "other")
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,193 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/api/webhook"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// WebhookName names the webhooks the pool creates in channels.
+	WebhookName = "go-discord-chatgpt characters"
+
+	webhookCacheSize = 256
+	parentCacheSize  = 1024
+
+	errCodeUnknownWebhook = 10015
+)
+
+// WebhookPool posts messages through webhooks owned by the bot, so that they
+// can carry a custom username and avatar. It creates at most one webhook per
+// channel and caches it; messages for a thread go through the webhook of its
+// parent channel, since threads cannot have webhooks of their own.
+type WebhookPool interface {
+	// Execute sends data to channelID, which may be a channel or a thread.
+	Execute(channelID discord.ChannelID, data webhook.ExecuteData) (*discord.Message, error)
+	// Invalidate forgets the cached webhook of a channel.
+	Invalidate(channelID discord.ChannelID)
+}
+
+// WebhookAPI is the subset of the Discord API used by the webhook pool.
+type WebhookAPI interface {
+	Channel(channelID discord.ChannelID) (*discord.Channel, error)
+	ChannelWebhooks(channelID discord.ChannelID) ([]discord.Webhook, error)
+	CreateWebhook(channelID discord.ChannelID, data api.CreateWebhookData) (*discord.Webhook, error)
+	ExecuteWebhook(hook discord.Webhook, data webhook.ExecuteData) (*discord.Message, error)
+}
+
+// sessionWebhookAPI adds webhook execution to a session.
+type sessionWebhookAPI struct {
+	*session.Session
+}
+
+// ExecuteWebhook executes hook with its token and waits for the message.
+func (s sessionWebhookAPI) ExecuteWebhook(hook discord.Webhook, data webhook.ExecuteData) (*discord.Message, error) {
+	return webhook.FromAPI(hook.ID, hook.Token, s.Client).ExecuteAndWait(data)
+}
+
+// NewWebhookPool creates a WebhookPool on the session. Cached webhooks are
+// dropped when Discord reports that a channel's webhooks changed.
+func NewWebhookPool(logger *zap.Logger, ses *session.Session, appID discord.AppID) WebhookPool {
+	pool := NewWebhookPoolFromAPI(logger, sessionWebhookAPI{ses}, appID)
+	ses.AddHandler(func(e *gateway.WebhooksUpdateEvent) {
+		pool.Invalidate(e.ChannelID)
+	})
+
+	return pool
+}
+
+// NewWebhookPoolFromAPI creates a WebhookPool on any WebhookAPI. appID
+// identifies the webhooks the bot created.
+func NewWebhookPoolFromAPI(logger *zap.Logger, webhookAPI WebhookAPI, appID discord.AppID) WebhookPool {
+	hooks, _ := lru.New[discord.ChannelID, discord.Webhook](webhookCacheSize)
+	parents, _ := lru.New[discord.ChannelID, discord.ChannelID](parentCacheSize)
+
+	return &webhookPool{
+		logger:  logger.Named("webhook_pool"),
+		api:     webhookAPI,
+		appID:   appID,
+		hooks:   hooks,
+		parents: parents,
+	}
+}
+
+type webhookPool struct {
+	logger *zap.Logger
+	api    WebhookAPI
+	appID  discord.AppID
+
+	// mu serializes webhook lookups so that concurrent first messages to a
+	// channel do not each create a webhook.
+	mu      sync.Mutex
+	hooks   *lru.Cache[discord.ChannelID, discord.Webhook]   // keyed by webhook channel
+	parents *lru.Cache[discord.ChannelID, discord.ChannelID] // channel or thread to webhook channel
+}
+
+// Execute sends data through the webhook of channelID. A cached webhook that
+// was deleted in Discord is replaced and the message retried once.
+func (p *webhookPool) Execute(channelID discord.ChannelID, data webhook.ExecuteData) (*discord.Message, error) {
+	hookChannelID, isThread, err := p.webhookChannel(channelID)
+	if err != nil {
+		return nil, err
+	}
+	if isThread {
+		data.ThreadID = discord.CommandID(channelID)
+	}
+
+	for attempt := 0; ; attempt++ {
+		hook, err := p.webhook(hookChannelID)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := p.api.ExecuteWebhook(hook, data)
+		if err == nil || attempt > 0 || !isUnknownWebhook(err) {
+			return msg, err
+		}
+
+		p.logger.Info("Cached webhook no longer exists, creating a new one",
+			zap.String("channelID", hookChannelID.String()),
+			zap.String("webhookID", hook.ID.String()))
+		p.hooks.Remove(hookChannelID)
+	}
+}
+
+// Invalidate forgets the cached webhook of channelID.
+func (p *webhookPool) Invalidate(channelID discord.ChannelID) {
+	p.hooks.Remove(channelID)
+}
+
+// webhookChannel returns the channel whose webhook posts to channelID.
+func (p *webhookPool) webhookChannel(channelID discord.ChannelID) (discord.ChannelID, bool, error) {
+	if parent, ok := p.parents.Get(channelID); ok {
+		return parent, parent != channelID, nil
+	}
+
+	ch, err := p.api.Channel(channelID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get channel: %w", err)
+	}
+
+	parent := channelID
+	switch ch.Type {
+	case discord.GuildPublicThread, discord.GuildPrivateThread, discord.GuildAnnouncementThread:
+		if !ch.ParentID.IsValid() {
+			return 0, false, errors.New("thread has no parent channel")
+		}
+		parent = ch.ParentID
+	}
+	p.parents.Add(channelID, parent)
+
+	return parent, parent != channelID, nil
+}
+
+// webhook returns the bot's webhook in channelID, reusing an existing one
+// before creating a new one.
+func (p *webhookPool) webhook(channelID discord.ChannelID) (discord.Webhook, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if hook, ok := p.hooks.Get(channelID); ok {
+		return hook, nil
+	}
+
+	existing, err := p.api.ChannelWebhooks(channelID)
+	if err != nil {
+		return discord.Webhook{}, fmt.Errorf("failed to list channel webhooks (is Manage Webhooks granted?): %w", err)
+	}
+	for _, hook := range existing {
+		if hook.Token != "" && hook.ApplicationID == p.appID && hook.Name == WebhookName {
+			p.hooks.Add(channelID, hook)
+
+			return hook, nil
+		}
+	}
+
+	created, err := p.api.CreateWebhook(channelID, api.CreateWebhookData{Name: WebhookName})
+	if err != nil {
+		return discord.Webhook{}, fmt.Errorf("failed to create webhook (is Manage Webhooks granted?): %w", err)
+	}
+	p.logger.Info("Created webhook", zap.String("channelID", channelID.String()), zap.String("webhookID", created.ID.String()))
+	p.hooks.Add(channelID, *created)
+
+	return *created, nil
+}
+
+func isUnknownWebhook(err error) bool {
+	var httpErr *httputil.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+
+	return httpErr.Code == errCodeUnknownWebhook || httpErr.Status == http.StatusNotFound
+}
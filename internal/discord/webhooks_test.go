@@ -0,0 +1,181 @@
+package discord_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/api/webhook"
+	discordapi "github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/discord"
+)
+
+const testAppID = discordapi.AppID(42)
+
+type fakeWebhookAPI struct {
+	channels map[discordapi.ChannelID]discordapi.Channel
+	hooks    map[discordapi.ChannelID][]discordapi.Webhook
+	// gone holds webhook IDs that fail as deleted when executed.
+	gone map[discordapi.WebhookID]bool
+
+	channelCalls int
+	listCalls    int
+	created      []discordapi.Webhook
+	executed     []webhook.ExecuteData
+	executedBy   []discordapi.WebhookID
+}
+
+func newFakeWebhookAPI() *fakeWebhookAPI {
+	return &fakeWebhookAPI{
+		channels: map[discordapi.ChannelID]discordapi.Channel{
+			10: {ID: 10, Type: discordapi.GuildText},
+			11: {ID: 11, Type: discordapi.GuildPublicThread, ParentID: 10},
+		},
+		hooks: map[discordapi.ChannelID][]discordapi.Webhook{},
+		gone:  map[discordapi.WebhookID]bool{},
+	}
+}
+
+func (f *fakeWebhookAPI) Channel(channelID discordapi.ChannelID) (*discordapi.Channel, error) {
+	f.channelCalls++
+	ch, ok := f.channels[channelID]
+	if !ok {
+		return nil, &httputil.HTTPError{Status: http.StatusNotFound, Code: 10003}
+	}
+
+	return &ch, nil
+}
+
+func (f *fakeWebhookAPI) ChannelWebhooks(channelID discordapi.ChannelID) ([]discordapi.Webhook, error) {
+	f.listCalls++
+
+	return f.hooks[channelID], nil
+}
+
+func (f *fakeWebhookAPI) CreateWebhook(channelID discordapi.ChannelID, data api.CreateWebhookData) (*discordapi.Webhook, error) {
+	hook := discordapi.Webhook{
+		ID:            discordapi.WebhookID(100 + len(f.created)),
+		ChannelID:     channelID,
+		Name:          data.Name,
+		Token:         "token",
+		ApplicationID: testAppID,
+	}
+	f.created = append(f.created, hook)
+	f.hooks[channelID] = append(f.hooks[channelID], hook)
+
+	return &hook, nil
+}
+
+func (f *fakeWebhookAPI) ExecuteWebhook(hook discordapi.Webhook, data webhook.ExecuteData) (*discordapi.Message, error) {
+	if f.gone[hook.ID] {
+		return nil, &httputil.HTTPError{Status: http.StatusNotFound, Code: 10015}
+	}
+	f.executed = append(f.executed, data)
+	f.executedBy = append(f.executedBy, hook.ID)
+
+	return &discordapi.Message{ID: discordapi.MessageID(len(f.executed)), Content: data.Content}, nil
+}
+
+func TestWebhookPool(t *testing.T) {
+	t.Run("CreatesAndCachesWebhook", func(t *testing.T) {
+		fake := newFakeWebhookAPI()
+		pool := discord.NewWebhookPoolFromAPI(zap.NewNop(), fake, testAppID)
+
+		for range 3 {
+			_, err := pool.Execute(10, webhook.ExecuteData{Content: "hi"})
+			require.NoError(t, err)
+		}
+
+		require.Len(t, fake.created, 1)
+		assert.Equal(t, discord.WebhookName, fake.created[0].Name)
+		assert.Equal(t, 1, fake.listCalls)
+		assert.Equal(t, 1, fake.channelCalls)
+		assert.False(t, fake.executed[0].ThreadID.IsValid())
+	})
+
+	t.Run("ThreadsUseParentWebhook", func(t *testing.T) {
+		fake := newFakeWebhookAPI()
+		pool := discord.NewWebhookPoolFromAPI(zap.NewNop(), fake, testAppID)
+
+		_, err := pool.Execute(11, webhook.ExecuteData{Content: "in thread"})
+		require.NoError(t, err)
+		_, err = pool.Execute(10, webhook.ExecuteData{Content: "in channel"})
+		require.NoError(t, err)
+
+		require.Len(t, fake.created, 1)
+		assert.Equal(t, discordapi.ChannelID(10), fake.created[0].ChannelID)
+		assert.Equal(t, discordapi.CommandID(11), fake.executed[0].ThreadID)
+		assert.False(t, fake.executed[1].ThreadID.IsValid())
+	})
+
+	t.Run("ReusesOwnedWebhook", func(t *testing.T) {
+		fake := newFakeWebhookAPI()
+		fake.hooks[10] = []discordapi.Webhook{
+			{ID: 1, Name: discord.WebhookName, Token: "t", ApplicationID: 7},
+			{ID: 2, Name: "someone else's", Token: "t", ApplicationID: testAppID},
+			{ID: 3, Name: discord.WebhookName, ApplicationID: testAppID},
+			{ID: 4, Name: discord.WebhookName, Token: "t", ApplicationID: testAppID},
+		}
+		pool := discord.NewWebhookPoolFromAPI(zap.NewNop(), fake, testAppID)
+
+		_, err := pool.Execute(10, webhook.ExecuteData{Content: "hi"})
+		require.NoError(t, err)
+
+		assert.Empty(t, fake.created)
+		assert.Equal(t, []discordapi.WebhookID{4}, fake.executedBy)
+	})
+
+	t.Run("ReplacesDeletedWebhook", func(t *testing.T) {
+		fake := newFakeWebhookAPI()
+		pool := discord.NewWebhookPoolFromAPI(zap.NewNop(), fake, testAppID)
+
+		_, err := pool.Execute(10, webhook.ExecuteData{Content: "first"})
+		require.NoError(t, err)
+
+		fake.gone[fake.created[0].ID] = true
+		fake.hooks[10] = nil
+
+		msg, err := pool.Execute(10, webhook.ExecuteData{Content: "second"})
+		require.NoError(t, err)
+		assert.Equal(t, "second", msg.Content)
+		require.Len(t, fake.created, 2)
+		assert.Equal(t, fake.created[1].ID, fake.executedBy[1])
+	})
+
+	t.Run("GivesUpAfterOneRetry", func(t *testing.T) {
+		fake := newFakeWebhookAPI()
+		fake.hooks[10] = []discordapi.Webhook{{ID: 1, Name: discord.WebhookName, Token: "t", ApplicationID: testAppID}}
+		fake.gone[1] = true
+		pool := discord.NewWebhookPoolFromAPI(zap.NewNop(), fake, testAppID)
+
+		_, err := pool.Execute(10, webhook.ExecuteData{Content: "hi"})
+		require.Error(t, err)
+		assert.Equal(t, 2, fake.listCalls)
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		fake := newFakeWebhookAPI()
+		pool := discord.NewWebhookPoolFromAPI(zap.NewNop(), fake, testAppID)
+
+		_, err := pool.Execute(10, webhook.ExecuteData{Content: "hi"})
+		require.NoError(t, err)
+		pool.Invalidate(10)
+		_, err = pool.Execute(10, webhook.ExecuteData{Content: "hi"})
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, fake.listCalls)
+		require.Len(t, fake.created, 1)
+	})
+
+	t.Run("UnknownChannel", func(t *testing.T) {
+		pool := discord.NewWebhookPoolFromAPI(zap.NewNop(), newFakeWebhookAPI(), testAppID)
+
+		_, err := pool.Execute(99, webhook.ExecuteData{Content: "hi"})
+		assert.ErrorContains(t, err, "failed to get channel")
+	})
+}
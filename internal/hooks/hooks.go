@@ -0,0 +1,239 @@
+// Package hooks runs operator-defined transformations on prompts before they
+// are sent to the model and on replies before they are posted.
+package hooks
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// Stages at which hooks run.
+const (
+	StagePre  = "pre"
+	StagePost = "post"
+)
+
+// Hook types.
+const (
+	TypeSystem     = "system"      // pre: add a system message
+	TypeReplace    = "replace"     // pre or post: regular expression replacement
+	TypeAppend     = "append"      // post: add text after the reply
+	TypePrepend    = "prepend"     // post: add text before the reply
+	TypeStripLinks = "strip_links" // post: remove links outside the allowed domains
+)
+
+const defaultLinkReplacement = "[link removed]"
+
+// linkPattern matches http(s) links in replies.
+var linkPattern = regexp.MustCompile(`https?://[^\s<>()\[\]]+`)
+
+// Pipeline runs the configured hooks in order.
+type Pipeline interface {
+	// BeforeRequest returns the messages to send for guildID. The given slice is not modified.
+	BeforeRequest(guildID discord.GuildID, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage
+	// AfterResponse returns the reply to post for guildID.
+	AfterResponse(guildID discord.GuildID, content string) string
+}
+
+// Module provides the hook Pipeline.
+var Module = fx.Module("hooks",
+	fx.Provide(NewPipelineProvider),
+)
+
+// NewPipelineProvider creates the Pipeline from the hooks in config.
+func NewPipelineProvider(logger *zap.Logger, cfg *config.Config) (Pipeline, error) {
+	return NewPipeline(logger, cfg.OpenAI.Hooks)
+}
+
+// NewPipeline validates hooks and creates a Pipeline running them in order.
+func NewPipeline(logger *zap.Logger, hooks []config.HookConfig) (Pipeline, error) {
+	p := &pipeline{logger: logger.Named("hooks")}
+	seen := make(map[string]bool, len(hooks))
+	for i, hc := range hooks {
+		if hc.Name == "" {
+			return nil, fmt.Errorf("hook %d has no name", i+1)
+		}
+		if seen[hc.Name] {
+			return nil, fmt.Errorf("hook %q is defined twice", hc.Name)
+		}
+		seen[hc.Name] = true
+
+		h, err := newHook(hc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook %q: %w", hc.Name, err)
+		}
+		p.hooks = append(p.hooks, h)
+	}
+
+	if len(p.hooks) > 0 {
+		p.logger.Info("Prompt hooks configured", zap.Int("count", len(p.hooks)))
+	}
+
+	return p, nil
+}
+
+type hook struct {
+	name   string
+	stage  string
+	guilds map[string]bool // nil runs everywhere
+
+	pre  func(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage
+	post func(content string) string
+}
+
+func (h *hook) appliesTo(guildID discord.GuildID) bool {
+	return h.guilds == nil || h.guilds[guildID.String()]
+}
+
+type pipeline struct {
+	logger *zap.Logger
+	hooks  []*hook
+}
+
+// BeforeRequest runs the pre hooks that apply to guildID.
+func (p *pipeline) BeforeRequest(guildID discord.GuildID, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if len(p.hooks) == 0 {
+		return messages
+	}
+
+	out := append([]openai.ChatCompletionMessage(nil), messages...)
+	for _, h := range p.hooks {
+		if h.stage == StagePre && h.appliesTo(guildID) {
+			out = h.pre(out)
+			p.logger.Debug("Ran prompt hook", zap.String("hook", h.name), zap.String("guildID", guildID.String()))
+		}
+	}
+
+	return out
+}
+
+// AfterResponse runs the post hooks that apply to guildID.
+func (p *pipeline) AfterResponse(guildID discord.GuildID, content string) string {
+	for _, h := range p.hooks {
+		if h.stage == StagePost && h.appliesTo(guildID) {
+			content = h.post(content)
+			p.logger.Debug("Ran response hook", zap.String("hook", h.name), zap.String("guildID", guildID.String()))
+		}
+	}
+
+	return content
+}
+
+func newHook(hc config.HookConfig) (*hook, error) {
+	h := &hook{name: hc.Name, stage: strings.ToLower(hc.Stage)}
+	if h.stage != StagePre && h.stage != StagePost {
+		return nil, fmt.Errorf("stage must be %q or %q, got %q", StagePre, StagePost, hc.Stage)
+	}
+	if len(hc.Guilds) > 0 {
+		h.guilds = make(map[string]bool, len(hc.Guilds))
+		for _, id := range hc.Guilds {
+			h.guilds[id] = true
+		}
+	}
+
+	switch strings.ToLower(hc.Type) {
+	case TypeSystem:
+		if h.stage != StagePre {
+			return nil, fmt.Errorf("%s hooks only run at the %s stage", TypeSystem, StagePre)
+		}
+		if hc.Text == "" {
+			return nil, fmt.Errorf("%s hooks need text", TypeSystem)
+		}
+		h.pre = func(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+			system := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: hc.Text}
+
+			return append([]openai.ChatCompletionMessage{system}, messages...)
+		}
+	case TypeReplace:
+		if hc.Pattern == "" {
+			return nil, fmt.Errorf("%s hooks need a pattern", TypeReplace)
+		}
+		re, err := regexp.Compile(hc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		replace := func(s string) string { return re.ReplaceAllString(s, hc.Replacement) }
+		// Only user text is rewritten; system prompts and earlier replies are the bot's own
+		h.pre = func(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+			for i := range messages {
+				if messages[i].Role != openai.ChatMessageRoleUser {
+					continue
+				}
+				messages[i].Content = replace(messages[i].Content)
+				if len(messages[i].MultiContent) > 0 {
+					// The parts are shared with the caller, so rewrite a copy
+					parts := append([]openai.ChatMessagePart(nil), messages[i].MultiContent...)
+					for j := range parts {
+						if parts[j].Type == openai.ChatMessagePartTypeText {
+							parts[j].Text = replace(parts[j].Text)
+						}
+					}
+					messages[i].MultiContent = parts
+				}
+			}
+
+			return messages
+		}
+		h.post = replace
+	case TypeAppend, TypePrepend:
+		if h.stage != StagePost {
+			return nil, fmt.Errorf("%s hooks only run at the %s stage", hc.Type, StagePost)
+		}
+		if hc.Text == "" {
+			return nil, fmt.Errorf("%s hooks need text", hc.Type)
+		}
+		if strings.EqualFold(hc.Type, TypeAppend) {
+			h.post = func(content string) string { return content + hc.Text }
+		} else {
+			h.post = func(content string) string { return hc.Text + content }
+		}
+	case TypeStripLinks:
+		if h.stage != StagePost {
+			return nil, fmt.Errorf("%s hooks only run at the %s stage", TypeStripLinks, StagePost)
+		}
+		replacement := hc.Replacement
+		if replacement == "" {
+			replacement = defaultLinkReplacement
+		}
+		allowed := hc.AllowedDomains
+		h.post = func(content string) string {
+			return linkPattern.ReplaceAllStringFunc(content, func(link string) string {
+				if linkAllowed(link, allowed) {
+					return link
+				}
+
+				return replacement
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", hc.Type)
+	}
+
+	return h, nil
+}
+
+// linkAllowed reports whether link points to one of domains or their subdomains.
+func linkAllowed(link string, domains []string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package hooks_test
+
+import (
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/hooks"
+)
+
+func TestPipeline_BeforeRequest(t *testing.T) {
+	pipeline, err := hooks.NewPipeline(zap.NewNop(), []config.HookConfig{
+		{Name: "rules", Stage: "pre", Type: "system", Text: "Follow the server rules."},
+		{Name: "secrets", Stage: "pre", Type: "replace", Pattern: `sk-[A-Za-z0-9]{8,}`, Replacement: "[redacted]"},
+		{Name: "only-guild-2", Stage: "pre", Type: "system", Text: "Guild 2 only.", Guilds: []string{"2"}},
+	})
+	require.NoError(t, err)
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "my key is sk-abcdefgh1234"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "sk-abcdefgh1234 is not a key I know"},
+	}
+	out := pipeline.BeforeRequest(1, messages)
+
+	require.Len(t, out, 3)
+	assert.Equal(t, openai.ChatMessageRoleSystem, out[0].Role)
+	assert.Equal(t, "Follow the server rules.", out[0].Content)
+	assert.Equal(t, "my key is [redacted]", out[1].Content)
+	assert.Equal(t, "sk-abcdefgh1234 is not a key I know", out[2].Content, "only user text is rewritten")
+	assert.Equal(t, "my key is sk-abcdefgh1234", messages[0].Content, "input is not modified")
+
+	assert.Len(t, pipeline.BeforeRequest(2, messages), 4)
+}
+
+func TestPipeline_BeforeRequestMultiContent(t *testing.T) {
+	pipeline, err := hooks.NewPipeline(zap.NewNop(), []config.HookConfig{
+		{Name: "secrets", Stage: "pre", Type: "replace", Pattern: `sk-[A-Za-z0-9]{8,}`, Replacement: "[redacted]"},
+	})
+	require.NoError(t, err)
+
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{
+		{Type: openai.ChatMessagePartTypeText, Text: "read sk-abcdefgh1234 off this"},
+		{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "https://cdn.example/key.png"}},
+	}}}
+	out := pipeline.BeforeRequest(1, messages)
+
+	assert.Equal(t, "read [redacted] off this", out[0].MultiContent[0].Text)
+	assert.Equal(t, "read sk-abcdefgh1234 off this", messages[0].MultiContent[0].Text, "input is not modified")
+}
+
+func TestPipeline_AfterResponse(t *testing.T) {
+	pipeline, err := hooks.NewPipeline(zap.NewNop(), []config.HookConfig{
+		{Name: "links", Stage: "post", Type: "strip_links", AllowedDomains: []string{"example.com"}},
+		{Name: "shout", Stage: "post", Type: "replace", Pattern: `(?i)\bhello\b`, Replacement: "HELLO"},
+		{Name: "disclaimer", Stage: "post", Type: "append", Text: "\n_AI generated_"},
+		{Name: "badge", Stage: "post", Type: "prepend", Text: "🤖 "},
+	})
+	require.NoError(t, err)
+
+	out := pipeline.AfterResponse(1, "hello, see https://docs.example.com/a and https://evil.test/x")
+	assert.Equal(t, "🤖 HELLO, see https://docs.example.com/a and [link removed]\n_AI generated_", out)
+}
+
+func TestPipeline_Empty(t *testing.T) {
+	pipeline, err := hooks.NewPipeline(zap.NewNop(), nil)
+	require.NoError(t, err)
+
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}}
+	assert.Equal(t, messages, pipeline.BeforeRequest(1, messages))
+	assert.Equal(t, "hi", pipeline.AfterResponse(1, "hi"))
+}
+
+func TestNewPipeline_Invalid(t *testing.T) {
+	for name, hc := range map[string]config.HookConfig{
+		"no name":           {Stage: "pre", Type: "system", Text: "x"},
+		"bad stage":         {Name: "a", Stage: "during", Type: "system", Text: "x"},
+		"unknown type":      {Name: "a", Stage: "pre", Type: "translate"},
+		"system post":       {Name: "a", Stage: "post", Type: "system", Text: "x"},
+		"append pre":        {Name: "a", Stage: "pre", Type: "append", Text: "x"},
+		"strip_links pre":   {Name: "a", Stage: "pre", Type: "strip_links"},
+		"missing text":      {Name: "a", Stage: "post", Type: "append"},
+		"missing pattern":   {Name: "a", Stage: "pre", Type: "replace"},
+		"invalid pattern":   {Name: "a", Stage: "pre", Type: "replace", Pattern: "("},
+		"missing sys text":  {Name: "a", Stage: "pre", Type: "system"},
+		"empty stage given": {Name: "a", Type: "system", Text: "x"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := hooks.NewPipeline(zap.NewNop(), []config.HookConfig{hc})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("duplicate names", func(t *testing.T) {
+		hc := config.HookConfig{Name: "a", Stage: "pre", Type: "system", Text: "x"}
+		_, err := hooks.NewPipeline(zap.NewNop(), []config.HookConfig{hc, hc})
+		assert.ErrorContains(t, err, "defined twice")
+	})
+}
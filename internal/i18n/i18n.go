@@ -0,0 +1,115 @@
+// Package i18n provides translation catalogs for user-facing bot strings.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/fx"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed locales/*.yaml
+var localeFS embed.FS
+
+// Module provides the embedded translation catalog.
+var Module = fx.Module("i18n",
+	fx.Provide(NewCatalog),
+)
+
+// Catalog holds translated messages for each Discord locale. Message keys are
+// dot-separated paths into the nested YAML catalogs, e.g. "commands.chat.description".
+type Catalog struct {
+	messages map[discord.Language]map[string]string
+}
+
+// NewCatalog loads the catalogs embedded in the binary. Each file under
+// locales/ is named after the Discord locale it translates (e.g. "pt-BR.yaml").
+func NewCatalog() (*Catalog, error) {
+	return LoadCatalog(localeFS, "locales")
+}
+
+// LoadCatalog loads every *.yaml catalog in dir of fsys.
+func LoadCatalog(fsys fs.FS, dir string) (*Catalog, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{messages: make(map[discord.Language]map[string]string, len(files))}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read catalog %s: %w", file, err)
+		}
+
+		var tree map[string]any
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("failed to parse catalog %s: %w", file, err)
+		}
+
+		messages := make(map[string]string)
+		flatten("", tree, messages)
+
+		locale := discord.Language(strings.TrimSuffix(path.Base(file), ".yaml"))
+		c.messages[locale] = messages
+	}
+
+	return c, nil
+}
+
+// Locales returns the locales with a catalog, sorted.
+func (c *Catalog) Locales() []discord.Language {
+	locales := make([]discord.Language, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i] < locales[j] })
+
+	return locales
+}
+
+// Localizations returns the translations of key in every locale that has one,
+// or nil if there are none. The result can be used directly as a Discord
+// localization map.
+func (c *Catalog) Localizations(key string) discord.StringLocales {
+	if c == nil {
+		return nil
+	}
+
+	var locales discord.StringLocales
+	for locale, messages := range c.messages {
+		if msg, ok := messages[key]; ok && msg != "" {
+			if locales == nil {
+				locales = make(discord.StringLocales)
+			}
+			locales[locale] = msg
+		}
+	}
+
+	return locales
+}
+
+// flatten converts a nested YAML tree into dot-separated keys.
+func flatten(prefix string, tree map[string]any, out map[string]string) {
+	for k, v := range tree {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(key, v, out)
+		case string:
+			out[key] = v
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package i18n_test
+
+import (
+	"testing"
+	"testing/fstest"
+	"unicode/utf8"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/i18n"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	fsys := fstest.MapFS{
+		"locales/de.yaml": {Data: []byte("commands:\n  ping:\n    description: Antwortet mit Pong!\n")},
+		"locales/ja.yaml": {Data: []byte("commands:\n  ping:\n    description: Pong! と応答します\n    name: \"\"\n")},
+	}
+
+	catalog, err := i18n.LoadCatalog(fsys, "locales")
+	require.NoError(t, err)
+	assert.Equal(t, []discord.Language{discord.German, discord.Japanese}, catalog.Locales())
+
+	assert.Equal(t, discord.StringLocales{
+		discord.German:   "Antwortet mit Pong!",
+		discord.Japanese: "Pong! と応答します",
+	}, catalog.Localizations("commands.ping.description"))
+
+	// Empty and missing translations are left out so Discord falls back to English
+	assert.Nil(t, catalog.Localizations("commands.ping.name"))
+	assert.Nil(t, catalog.Localizations("commands.chat.description"))
+}
+
+func TestLoadCatalogInvalidYAML(t *testing.T) {
+	fsys := fstest.MapFS{
+		"locales/de.yaml": {Data: []byte("commands: [")},
+	}
+
+	_, err := i18n.LoadCatalog(fsys, "locales")
+	assert.ErrorContains(t, err, "de.yaml")
+}
+
+func TestNilCatalog(t *testing.T) {
+	var catalog *i18n.Catalog
+	assert.Nil(t, catalog.Localizations("commands.ping.description"))
+}
+
+func TestEmbeddedCatalogs(t *testing.T) {
+	catalog, err := i18n.NewCatalog()
+	require.NoError(t, err)
+	require.NotEmpty(t, catalog.Locales())
+
+	for _, key := range []string{
+		"commands.chat.description",
+		"commands.voice.description",
+		"commands.voice.options.action.description",
+	} {
+		locales := catalog.Localizations(key)
+		assert.Len(t, locales, len(catalog.Locales()), "missing translations for %s", key)
+
+		// Discord rejects descriptions longer than 100 characters
+		for locale, msg := range locales {
+			assert.LessOrEqual(t, utf8.RuneCountInString(msg), 100, "%s in %s is too long", key, locale)
+		}
+	}
+}
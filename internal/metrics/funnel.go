@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Why an interaction got no response, from the first check that stopped it
+// to the last step that failed.
+const (
+	DropPermission   = "permission"    // The member may not use it
+	DropRateLimit    = "rate_limit"    // A budget, the request queue or OpenAI's rate limit
+	DropOpenAIError  = "openai_error"  // The AI provider failed
+	DropDiscordError = "discord_error" // Discord refused the response
+	DropError        = "error"         // Anything else
+)
+
+// Responded is the funnel outcome of an interaction that got its response.
+const Responded = "responded"
+
+// DropReasons lists the drop reasons in funnel order.
+var DropReasons = []string{DropPermission, DropRateLimit, DropOpenAIError, DropDiscordError, DropError}
+
+// Funnel counts, per command, the interactions received and where those that
+// got no response dropped out. It counts in memory whether or not metrics
+// are enabled, for /stats, and records each outcome in the metrics too.
+type Funnel struct {
+	metrics *Metrics
+	since   time.Time
+
+	mu    sync.Mutex
+	stats map[string]*FunnelStats
+}
+
+// FunnelStats are the funnel counts of one command.
+type FunnelStats struct {
+	Command   string
+	Invoked   int64
+	Responded int64
+	Drops     map[string]int64 // By drop reason
+}
+
+// Dropped returns the interactions of the command that got no response.
+func (s FunnelStats) Dropped() int64 {
+	return s.Invoked - s.Responded
+}
+
+// NewFunnel creates an empty Funnel recording outcomes in m, which may be nil.
+func NewFunnel(m *Metrics) *Funnel {
+	return &Funnel{metrics: m, since: time.Now(), stats: make(map[string]*FunnelStats)}
+}
+
+// Record counts an interaction with command and its outcome: Responded, or
+// the reason it dropped.
+func (f *Funnel) Record(command, outcome string) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	stats, ok := f.stats[command]
+	if !ok {
+		stats = &FunnelStats{Command: command, Drops: make(map[string]int64)}
+		f.stats[command] = stats
+	}
+	stats.Invoked++
+	if outcome == Responded {
+		stats.Responded++
+	} else {
+		stats.Drops[outcome]++
+	}
+	f.mu.Unlock()
+
+	f.metrics.observeFunnel(command, outcome)
+}
+
+// Snapshot returns when counting started and the counts of every command,
+// the most used first.
+func (f *Funnel) Snapshot() (time.Time, []FunnelStats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats := make([]FunnelStats, 0, len(f.stats))
+	for _, s := range f.stats {
+		copied := *s
+		copied.Drops = maps.Clone(s.Drops)
+		stats = append(stats, copied)
+	}
+	slices.SortFunc(stats, func(a, b FunnelStats) int {
+		if a.Invoked != b.Invoked {
+			return cmp.Compare(b.Invoked, a.Invoked)
+		}
+
+		return cmp.Compare(a.Command, b.Command)
+	})
+
+	return f.since, stats
+}
+
+// dropKey carries the drop mark of the interaction a context handles.
+type dropKey struct{}
+
+type dropMark struct {
+	mu     sync.Mutex
+	reason string
+}
+
+// WithDropMark returns a context for handling one interaction, on which
+// MarkDropped can record why it got no response.
+func WithDropMark(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dropKey{}, &dropMark{})
+}
+
+// MarkDropped records on ctx why its interaction got no response, for
+// handlers that answer with a notice instead of failing, such as when the
+// member may not use the command. The first reason marked is kept.
+func MarkDropped(ctx context.Context, reason string) {
+	mark, ok := ctx.Value(dropKey{}).(*dropMark)
+	if !ok {
+		return
+	}
+
+	mark.mu.Lock()
+	defer mark.mu.Unlock()
+
+	if mark.reason == "" {
+		mark.reason = reason
+	}
+}
+
+// Dropped returns the reason marked on ctx, empty when none was.
+func Dropped(ctx context.Context) string {
+	mark, ok := ctx.Value(dropKey{}).(*dropMark)
+	if !ok {
+		return ""
+	}
+
+	mark.mu.Lock()
+	defer mark.mu.Unlock()
+
+	return mark.reason
+}
@@ -0,0 +1,69 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+)
+
+func TestFunnel(t *testing.T) {
+	m := metrics.NewMetrics(&config.Config{Metrics: config.MetricsConfig{Enabled: true}})
+	f := metrics.NewFunnel(m)
+
+	f.Record("voice", metrics.Responded)
+	f.Record("chat", metrics.Responded)
+	f.Record("chat", metrics.DropRateLimit)
+	f.Record("chat", metrics.DropPermission)
+	f.Record("admin", metrics.DropPermission)
+
+	_, stats := f.Snapshot()
+	require.Len(t, stats, 3)
+	assert.Equal(t, []string{"chat", "admin", "voice"}, []string{stats[0].Command, stats[1].Command, stats[2].Command},
+		"the most used first, then by name")
+	assert.Equal(t, int64(3), stats[0].Invoked)
+	assert.Equal(t, int64(1), stats[0].Responded)
+	assert.Equal(t, int64(2), stats[0].Dropped())
+	assert.Equal(t, map[string]int64{metrics.DropRateLimit: 1, metrics.DropPermission: 1}, stats[0].Drops)
+
+	stats[0].Drops[metrics.DropError] = 5
+	_, again := f.Snapshot()
+	assert.NotContains(t, again[0].Drops, metrics.DropError, "snapshots are copies")
+
+	out := scrape(t, m)
+	for _, line := range []string{
+		"# TYPE discord_chatgpt_interaction_funnel_total counter",
+		`discord_chatgpt_interaction_funnel_total{command="chat",outcome="rate_limit"} 1`,
+		`discord_chatgpt_interaction_funnel_total{command="chat",outcome="responded"} 1`,
+		`discord_chatgpt_interaction_funnel_total{command="admin",outcome="permission"} 1`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
+
+func TestFunnel_NoMetrics(t *testing.T) {
+	f := metrics.NewFunnel(nil)
+	f.Record("chat", metrics.Responded)
+
+	_, stats := f.Snapshot()
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(1), stats[0].Responded, "counts for /stats without metrics")
+
+	var disabled *metrics.Funnel
+	disabled.Record("chat", metrics.Responded)
+}
+
+func TestMarkDropped(t *testing.T) {
+	ctx := t.Context()
+	metrics.MarkDropped(ctx, metrics.DropPermission)
+	assert.Empty(t, metrics.Dropped(ctx), "unmarked contexts ignore drops")
+
+	ctx = metrics.WithDropMark(ctx)
+	assert.Empty(t, metrics.Dropped(ctx))
+	metrics.MarkDropped(ctx, metrics.DropPermission)
+	metrics.MarkDropped(ctx, metrics.DropDiscordError)
+	assert.Equal(t, metrics.DropPermission, metrics.Dropped(ctx), "the first reason marked is kept")
+}
@@ -0,0 +1,255 @@
+// Package metrics exposes the bot's Prometheus metrics over HTTP: command
+// invocations and their response funnel, OpenAI latency and token usage,
+// cache lookups, voice sessions, audio mixer latency and retention purges.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultAddr = ":9090"
+	defaultPath = "/metrics"
+	// namespace prefixes every metric name.
+	namespace = "discord_chatgpt_"
+)
+
+var (
+	// secondsBuckets cover commands and OpenAI calls, from a quick reply to
+	// a long reasoning model.
+	secondsBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	// mixerBuckets cover one mixer call, which must stay well under a 20 ms
+	// frame.
+	mixerBuckets = []float64{0.00001, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.02}
+)
+
+// Module provides the Metrics and the interaction Funnel, and serves the
+// metrics while they are enabled.
+var Module = fx.Module("metrics",
+	fx.Provide(NewMetrics, NewFunnel),
+	fx.Invoke(registerServer),
+)
+
+// Metrics records what the bot does for Prometheus to scrape. A nil *Metrics
+// records nothing, as when metrics are disabled.
+type Metrics struct {
+	registry *Registry
+
+	commands       *CounterVec
+	commandSeconds *HistogramVec
+	openAIRequests *CounterVec
+	openAISeconds  *HistogramVec
+	tokens         *CounterVec
+	cacheLookups   *CounterVec
+	voiceSessions  *CounterVec
+	voiceActive    *GaugeVec
+	mixerSeconds   *HistogramVec
+	mixerErrors    *CounterVec
+	funnel         *CounterVec
+	purgeRuns      *CounterVec
+	purged         *CounterVec
+}
+
+// NewMetrics creates the metrics configured in cfg. It returns nil when
+// metrics are disabled.
+func NewMetrics(cfg *config.Config) *Metrics {
+	if !cfg.Metrics.Enabled {
+		return nil
+	}
+
+	r := NewRegistry()
+
+	return &Metrics{
+		registry: r,
+		commands: r.NewCounterVec(namespace+"command_invocations_total",
+			"Slash commands and component interactions handled, by outcome.", "command", "kind", "outcome"),
+		commandSeconds: r.NewHistogramVec(namespace+"command_duration_seconds",
+			"Time taken to handle slash commands and component interactions.", secondsBuckets, "command", "kind"),
+		openAIRequests: r.NewCounterVec(namespace+"openai_requests_total",
+			"Chat completion requests sent to OpenAI or Anthropic, by outcome.", "model", "outcome"),
+		openAISeconds: r.NewHistogramVec(namespace+"openai_request_duration_seconds",
+			"Time taken by OpenAI or Anthropic to answer chat completion requests.", secondsBuckets, "model"),
+		tokens: r.NewCounterVec(namespace+"openai_tokens_total",
+			"Tokens used by chat completions, by prompt and completion.", "model", "type"),
+		cacheLookups: r.NewCounterVec(namespace+"cache_lookups_total",
+			"Cache lookups by result; the hit rate is hits over all lookups.", "cache", "result"),
+		voiceSessions: r.NewCounterVec(namespace+"voice_sessions_total",
+			"Voice sessions started."),
+		voiceActive: r.NewGaugeVec(namespace+"voice_sessions_active",
+			"Voice sessions in progress."),
+		mixerSeconds: r.NewHistogramVec(namespace+"audio_mixer_duration_seconds",
+			"Time taken by audio mixer calls, adding a frame or draining a turn.", mixerBuckets, "operation"),
+		mixerErrors: r.NewCounterVec(namespace+"audio_mixer_errors_total",
+			"Frames the audio mixer refused."),
+		funnel: r.NewCounterVec(namespace+"interaction_funnel_total",
+			"Interactions by whether they got a response, or else why they dropped: permission, rate_limit, openai_error, discord_error or error.", "command", "outcome"),
+		purgeRuns: r.NewCounterVec(namespace+"retention_purge_runs_total",
+			"Retention purges of a data type, by outcome.", "data_type", "outcome"),
+		purged: r.NewCounterVec(namespace+"retention_purged_records_total",
+			"Stored records deleted once older than their retention window, by data type.", "data_type"),
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = m.registry.WriteTo(w)
+	})
+}
+
+// ObserveCommand records a slash command, or a component interaction when
+// kind is "component", that took d and failed with err, if not nil.
+func (m *Metrics) ObserveCommand(command, kind string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	m.commands.Inc(command, kind, outcome(err))
+	m.commandSeconds.Observe(d.Seconds(), command, kind)
+}
+
+// ObserveOpenAI records a chat completion request to model that took d and
+// failed with err, if not nil.
+func (m *Metrics) ObserveOpenAI(model string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	m.openAIRequests.Inc(model, outcome(err))
+	m.openAISeconds.Observe(d.Seconds(), model)
+}
+
+// AddTokens records the tokens of a chat completion by model.
+func (m *Metrics) AddTokens(model string, promptTokens, completionTokens int) {
+	if m == nil {
+		return
+	}
+
+	m.tokens.Add(float64(promptTokens), model, "prompt")
+	m.tokens.Add(float64(completionTokens), model, "completion")
+}
+
+// CacheLookup records a lookup in the named cache.
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+	if m == nil {
+		return
+	}
+
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.Inc(cache, result)
+}
+
+// VoiceSessionStarted records a voice session starting.
+func (m *Metrics) VoiceSessionStarted() {
+	if m == nil {
+		return
+	}
+
+	m.voiceSessions.Inc()
+	m.voiceActive.Add(1)
+}
+
+// VoiceSessionEnded records a voice session ending.
+func (m *Metrics) VoiceSessionEnded() {
+	if m == nil {
+		return
+	}
+
+	m.voiceActive.Add(-1)
+}
+
+// ObserveMixer records an audio mixer call, "add" or "drain", that took d
+// and failed with err, if not nil.
+func (m *Metrics) ObserveMixer(operation string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	m.mixerSeconds.Observe(d.Seconds(), operation)
+	if err != nil {
+		m.mixerErrors.Inc()
+	}
+}
+
+// ObservePurge records a retention purge of dataType that deleted purged
+// records and failed with err, if not nil.
+func (m *Metrics) ObservePurge(dataType string, purged int, err error) {
+	if m == nil {
+		return
+	}
+
+	m.purgeRuns.Inc(dataType, outcome(err))
+	m.purged.Add(float64(purged), dataType)
+}
+
+// observeFunnel records the funnel outcome of an interaction with command.
+func (m *Metrics) observeFunnel(command, outcome string) {
+	if m == nil {
+		return
+	}
+
+	m.funnel.Inc(command, outcome)
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
+
+// registerServer serves m on metrics.addr while the application runs.
+func registerServer(lc fx.Lifecycle, logger *zap.Logger, cfg *config.Config, m *Metrics) {
+	if m == nil {
+		return
+	}
+
+	addr := cfg.Metrics.Addr
+	if addr == "" {
+		addr = defaultAddr
+	}
+	path := cfg.Metrics.Path
+	if path == "" {
+		path = defaultPath
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, m.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	logger = logger.Named("metrics")
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			// Listen before returning, so a taken port fails startup
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen for metrics on %s: %w", addr, err)
+			}
+			logger.Info("Serving metrics", zap.String("addr", ln.Addr().String()), zap.String("path", path))
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Error("Metrics server stopped", zap.Error(err))
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return srv.Shutdown(ctx)
+		},
+	})
+}
@@ -0,0 +1,98 @@
+package metrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "version=0.0.4")
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.NewMetrics(&config.Config{Metrics: config.MetricsConfig{Enabled: true}})
+	require.NotNil(t, m)
+
+	m.ObserveCommand("chat", "command", 300*time.Millisecond, nil)
+	m.ObserveCommand("chat", "command", 2*time.Second, errors.New("boom"))
+	m.ObserveOpenAI("gpt-4o", time.Second, nil)
+	m.AddTokens("gpt-4o", 120, 30)
+	m.CacheLookup("conversations", true)
+	m.CacheLookup("conversations", false)
+	m.CacheLookup("conversations", true)
+	m.VoiceSessionStarted()
+	m.VoiceSessionStarted()
+	m.VoiceSessionEnded()
+	m.ObserveMixer("add", 40*time.Microsecond, nil)
+	m.ObservePurge("conversations", 3, nil)
+	m.ObservePurge("conversations", 1, errors.New("boom"))
+
+	out := scrape(t, m)
+	for _, line := range []string{
+		"# TYPE discord_chatgpt_command_invocations_total counter",
+		`discord_chatgpt_command_invocations_total{command="chat",kind="command",outcome="error"} 1`,
+		`discord_chatgpt_command_invocations_total{command="chat",kind="command",outcome="ok"} 1`,
+		`discord_chatgpt_command_duration_seconds_bucket{command="chat",kind="command",le="0.5"} 1`,
+		`discord_chatgpt_command_duration_seconds_bucket{command="chat",kind="command",le="2.5"} 2`,
+		`discord_chatgpt_command_duration_seconds_bucket{command="chat",kind="command",le="+Inf"} 2`,
+		`discord_chatgpt_command_duration_seconds_sum{command="chat",kind="command"} 2.3`,
+		`discord_chatgpt_command_duration_seconds_count{command="chat",kind="command"} 2`,
+		`discord_chatgpt_openai_requests_total{model="gpt-4o",outcome="ok"} 1`,
+		`discord_chatgpt_openai_tokens_total{model="gpt-4o",type="completion"} 30`,
+		`discord_chatgpt_openai_tokens_total{model="gpt-4o",type="prompt"} 120`,
+		`discord_chatgpt_cache_lookups_total{cache="conversations",result="hit"} 2`,
+		`discord_chatgpt_cache_lookups_total{cache="conversations",result="miss"} 1`,
+		"discord_chatgpt_voice_sessions_total 2",
+		"discord_chatgpt_voice_sessions_active 1",
+		`discord_chatgpt_audio_mixer_duration_seconds_bucket{operation="add",le="5e-05"} 1`,
+		`discord_chatgpt_retention_purge_runs_total{data_type="conversations",outcome="error"} 1`,
+		`discord_chatgpt_retention_purged_records_total{data_type="conversations"} 4`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
+
+func TestMetrics_Disabled(t *testing.T) {
+	m := metrics.NewMetrics(&config.Config{})
+	assert.Nil(t, m)
+
+	// A nil *Metrics records nothing
+	m.ObserveCommand("chat", "command", time.Second, nil)
+	m.ObserveOpenAI("gpt-4o", time.Second, nil)
+	m.AddTokens("gpt-4o", 1, 1)
+	m.CacheLookup("conversations", true)
+	m.VoiceSessionStarted()
+	m.VoiceSessionEnded()
+	m.ObserveMixer("add", time.Millisecond, nil)
+	m.ObservePurge("conversations", 1, nil)
+}
+
+func TestRegistry_Escaping(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := r.NewCounterVec("test_total", "Help with a \\ backslash\nand a newline.", "name")
+	c.Inc("quote \" and \\ and \n")
+
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	require.NoError(t, err)
+	assert.Equal(t, "# HELP test_total Help with a \\\\ backslash\\nand a newline.\n"+
+		"# TYPE test_total counter\n"+
+		"test_total{name=\"quote \\\" and \\\\ and \\n\"} 1\n", b.String())
+}
@@ -0,0 +1,293 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is a metric family that can write itself in the Prometheus text
+// exposition format.
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metric families and writes them in the order they were
+// registered.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo writes every family in the Prometheus text format, version 0.0.4.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// family holds what every kind of metric has: a name, help text, label names
+// and one series per combination of label values.
+type family[S any] struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*labeled[S]
+}
+
+type labeled[S any] struct {
+	values []string
+	series S
+}
+
+func newFamily[S any](name, help, kind string, labels []string) family[S] {
+	return family[S]{name: name, help: help, kind: kind, labels: labels, series: make(map[string]*labeled[S])}
+}
+
+// with returns the series for values, created by init on first use. The
+// caller must hold f.mu.
+func (f *family[S]) with(values []string, init func() S) *S {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &labeled[S]{values: slices.Clone(values), series: init()}
+		f.series[key] = s
+	}
+
+	return &s.series
+}
+
+// writeHeader writes the HELP and TYPE lines and returns the series sorted
+// by label values. The caller must hold f.mu.
+func (f *family[S]) writeHeader(w *bufio.Writer) []*labeled[S] {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, helpEscaper.Replace(f.help), f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	sorted := make([]*labeled[S], len(keys))
+	for i, key := range keys {
+		sorted[i] = f.series[key]
+	}
+
+	return sorted
+}
+
+// labelString formats names and values as {a="1",b="2"}, with extra pairs
+// appended, or returns "" without any.
+func labelString(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], labelEscaper.Replace(extra[i+1]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// The text format escapes only backslashes and newlines in help text, and
+// quotes too in label values.
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// CounterVec is a family of counters partitioned by labels.
+type CounterVec struct {
+	family[float64]
+}
+
+// NewCounterVec creates and registers a counter family.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{family: newFamily[float64](name, help, "counter", labels)}
+	r.register(c)
+
+	return c
+}
+
+// Add adds delta, which must not be negative, to the counter for values.
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	*c.with(values, func() float64 { return 0 }) += delta
+}
+
+// Inc adds one to the counter for values.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range c.writeHeader(w) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelString(c.labels, s.values), formatFloat(s.series))
+	}
+}
+
+// GaugeVec is a family of gauges partitioned by labels.
+type GaugeVec struct {
+	family[float64]
+}
+
+// NewGaugeVec creates and registers a gauge family.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{family: newFamily[float64](name, help, "gauge", labels)}
+	r.register(g)
+
+	return g
+}
+
+// Add adds delta, which may be negative, to the gauge for values.
+func (g *GaugeVec) Add(delta float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	*g.with(values, func() float64 { return 0 }) += delta
+}
+
+// Set sets the gauge for values.
+func (g *GaugeVec) Set(v float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	*g.with(values, func() float64 { return 0 }) = v
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, s := range g.writeHeader(w) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labelString(g.labels, s.values), formatFloat(s.series))
+	}
+}
+
+// histogram is one series of a HistogramVec: observations per bucket, not
+// cumulative, with the sum and count of all of them.
+type histogram struct {
+	counts []uint64 // One per bucket, then one for +Inf
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec is a family of histograms partitioned by labels, sharing
+// bucket upper bounds.
+type HistogramVec struct {
+	family[histogram]
+	buckets []float64
+}
+
+// NewHistogramVec creates and registers a histogram family with the given
+// bucket upper bounds, sorted ascending.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{family: newFamily[histogram](name, help, "histogram", labels), buckets: slices.Sorted(slices.Values(buckets))}
+	r.register(h)
+
+	return h
+}
+
+// Observe records v in the histogram for values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.with(values, func() histogram { return histogram{counts: make([]uint64, len(h.buckets)+1)} })
+	i, _ := slices.BinarySearch(h.buckets, v)
+	s.counts[i]++
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, s := range h.writeHeader(w) {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.series.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, s.values, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, s.values, "le", "+Inf"), s.series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelString(h.labels, s.values), formatFloat(s.series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelString(h.labels, s.values), s.series.count)
+	}
+}
@@ -0,0 +1,177 @@
+package moderation
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+// savedIgnoreList is the JSON layout of the ignore list.
+type savedIgnoreList struct {
+	Global []discord.UserID                     `json:"global,omitempty"`
+	Guilds map[discord.GuildID][]discord.UserID `json:"guilds,omitempty"`
+}
+
+// documentIgnoreList keeps the ignore list in memory and saves it to a JSON
+// document on every change.
+type documentIgnoreList struct {
+	doc        storage.Document
+	configured map[discord.UserID]bool
+
+	mu     sync.RWMutex
+	guilds map[discord.GuildID]map[discord.UserID]bool // Global holds the global list
+}
+
+// NewFileIgnoreList creates an IgnoreList persisted to the file at path,
+// loading any users already saved there. configured users are ignored
+// everywhere and cannot be removed.
+func NewFileIgnoreList(path string, configured []discord.UserID) (IgnoreList, error) {
+	return NewDocumentIgnoreList(storage.NewDocument(storage.NewFileProvider(""), path), configured)
+}
+
+// NewDocumentIgnoreList creates an IgnoreList persisted to doc, loading any
+// users already saved in it. configured users are ignored everywhere and
+// cannot be removed.
+func NewDocumentIgnoreList(doc storage.Document, configured []discord.UserID) (IgnoreList, error) {
+	l := &documentIgnoreList{
+		doc:        doc,
+		configured: make(map[discord.UserID]bool, len(configured)),
+		guilds:     make(map[discord.GuildID]map[discord.UserID]bool),
+	}
+	for _, userID := range configured {
+		l.configured[userID] = true
+	}
+
+	var saved savedIgnoreList
+	if _, err := doc.Load(&saved); err != nil {
+		return nil, fmt.Errorf("failed to load ignore list: %w", err)
+	}
+	l.load(Global, saved.Global)
+	for guildID, users := range saved.Guilds {
+		l.load(guildID, users)
+	}
+
+	return l, nil
+}
+
+func (l *documentIgnoreList) load(guildID discord.GuildID, users []discord.UserID) {
+	if len(users) == 0 {
+		return
+	}
+	set := make(map[discord.UserID]bool, len(users))
+	for _, userID := range users {
+		set[userID] = true
+	}
+	l.guilds[guildID] = set
+}
+
+// Ignored reports whether userID is ignored in guildID, globally or by config.
+func (l *documentIgnoreList) Ignored(guildID discord.GuildID, userID discord.UserID) bool {
+	if l.configured[userID] {
+		return true
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.guilds[Global][userID] || (guildID.IsValid() && l.guilds[guildID][userID])
+}
+
+// Add ignores userID in guildID.
+func (l *documentIgnoreList) Add(guildID discord.GuildID, userID discord.UserID) (bool, error) {
+	if guildID == Global && l.configured[userID] {
+		return false, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	users := l.guilds[guildID]
+	if users[userID] {
+		return false, nil
+	}
+	if users == nil {
+		users = make(map[discord.UserID]bool)
+		l.guilds[guildID] = users
+	}
+
+	users[userID] = true
+	if err := l.persist(); err != nil {
+		delete(users, userID)
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Remove stops ignoring userID in guildID.
+func (l *documentIgnoreList) Remove(guildID discord.GuildID, userID discord.UserID) (bool, error) {
+	if guildID == Global && l.configured[userID] {
+		return false, ErrConfigured
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.guilds[guildID][userID] {
+		return false, nil
+	}
+
+	delete(l.guilds[guildID], userID)
+	if err := l.persist(); err != nil {
+		l.guilds[guildID][userID] = true
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// List returns the users ignored in guildID, sorted by ID. The global list
+// includes the configured users.
+func (l *documentIgnoreList) List(guildID discord.GuildID) []discord.UserID {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	users := sortedUsers(l.guilds[guildID])
+	if guildID == Global {
+		for userID := range l.configured {
+			if !l.guilds[Global][userID] {
+				users = append(users, userID)
+			}
+		}
+		slices.Sort(users)
+	}
+
+	return users
+}
+
+// persist saves the ignore list. The caller must hold l.mu.
+func (l *documentIgnoreList) persist() error {
+	saved := savedIgnoreList{
+		Global: sortedUsers(l.guilds[Global]),
+		Guilds: make(map[discord.GuildID][]discord.UserID, len(l.guilds)),
+	}
+	for guildID, users := range l.guilds {
+		if guildID != Global && len(users) > 0 {
+			saved.Guilds[guildID] = sortedUsers(users)
+		}
+	}
+
+	return l.doc.Save(saved)
+}
+
+func sortedUsers(users map[discord.UserID]bool) []discord.UserID {
+	list := make([]discord.UserID, 0, len(users))
+	for userID := range users {
+		list = append(list, userID)
+	}
+	slices.Sort(list)
+
+	return list
+}
@@ -0,0 +1,203 @@
+package moderation
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// LoopAlertPrefix starts the alerts sent when a channel is paused, so that
+// they are left out of reconstructed conversations.
+const LoopAlertPrefix = "⏸️ "
+
+const (
+	defaultRepeatThreshold  = 3
+	defaultWebhookThreshold = 6
+	defaultLoopWindow       = 60 * time.Second
+	defaultLoopPause        = 30 * time.Minute
+)
+
+// LoopDetector watches channels for feedback loops between the bot and other
+// bots, webhooks or relays, and pauses the bot's replies where it finds one.
+// Bot authors are filtered before replies are made, but relays re-posting the
+// bot's replies through webhooks or user accounts are not.
+type LoopDetector interface {
+	// Observe records a message, including the bot's own (fromSelf), and
+	// returns why its channel was paused, or an empty string if it was not.
+	Observe(msg *discord.Message, fromSelf bool) string
+	// Paused reports whether replies in channelID are paused.
+	Paused(channelID discord.ChannelID) bool
+	// Resume lifts the pause of channelID and reports whether it was paused.
+	Resume(channelID discord.ChannelID) bool
+}
+
+// NewLoopDetector creates the LoopDetector configured in cfg. When loop
+// detection is off it never pauses a channel.
+func NewLoopDetector(cfg *config.Config) LoopDetector {
+	loops := cfg.Moderation.LoopDetection
+	d := &loopDetector{
+		enabled:          loops.Enabled,
+		repeatThreshold:  loops.RepeatThreshold,
+		webhookThreshold: loops.WebhookThreshold,
+		window:           time.Duration(loops.WindowSeconds) * time.Second,
+		pause:            time.Duration(loops.PauseMinutes) * time.Minute,
+		now:              time.Now,
+		channels:         make(map[discord.ChannelID]*channelActivity),
+	}
+	if d.repeatThreshold <= 0 {
+		d.repeatThreshold = defaultRepeatThreshold
+	}
+	if d.webhookThreshold <= 0 {
+		d.webhookThreshold = defaultWebhookThreshold
+	}
+	if d.window <= 0 {
+		d.window = defaultLoopWindow
+	}
+	if d.pause <= 0 {
+		d.pause = defaultLoopPause
+	}
+
+	return d
+}
+
+type loopDetector struct {
+	enabled          bool
+	repeatThreshold  int
+	webhookThreshold int
+	window           time.Duration
+	pause            time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	channels map[discord.ChannelID]*channelActivity
+}
+
+// channelActivity holds the recent messages of a channel.
+type channelActivity struct {
+	messages    []observedMessage
+	pausedUntil time.Time
+}
+
+type observedMessage struct {
+	at        time.Time
+	content   uint64 // hash of the normalized content, 0 for messages without text
+	automated bool   // posted by a webhook or another bot
+}
+
+// Observe counts identical texts, including copies of the bot's own replies,
+// and webhook or bot posts within the window. Messages are timed by their
+// Discord timestamp.
+func (d *loopDetector) Observe(msg *discord.Message, fromSelf bool) string {
+	if !d.enabled {
+		return ""
+	}
+
+	at := msg.Timestamp.Time()
+	observed := observedMessage{
+		at:        at,
+		content:   contentHash(msg.Content),
+		automated: !fromSelf && (msg.WebhookID.IsValid() || msg.Author.Bot),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	activity := d.channels[msg.ChannelID]
+	if activity == nil {
+		activity = &channelActivity{}
+		d.channels[msg.ChannelID] = activity
+	}
+	activity.prune(at.Add(-d.window))
+	activity.messages = append(activity.messages, observed)
+	d.forgetIdle(at)
+
+	if fromSelf || d.now().Before(activity.pausedUntil) {
+		return ""
+	}
+
+	repeats, automated := 0, 0
+	for _, m := range activity.messages {
+		if observed.content != 0 && m.content == observed.content {
+			repeats++
+		}
+		if m.automated {
+			automated++
+		}
+	}
+
+	var reason string
+	switch {
+	case repeats >= d.repeatThreshold:
+		reason = fmt.Sprintf("the same message was posted %d times within %s", repeats, d.window)
+	case observed.automated && automated >= d.webhookThreshold:
+		reason = fmt.Sprintf("%d messages from webhooks or bots were posted within %s", automated, d.window)
+	default:
+		return ""
+	}
+	activity.pausedUntil = d.now().Add(d.pause)
+
+	return reason
+}
+
+func (d *loopDetector) Paused(channelID discord.ChannelID) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	activity, ok := d.channels[channelID]
+
+	return ok && d.now().Before(activity.pausedUntil)
+}
+
+func (d *loopDetector) Resume(channelID discord.ChannelID) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	activity, ok := d.channels[channelID]
+	if !ok || !d.now().Before(activity.pausedUntil) {
+		return false
+	}
+	// Start over, so the messages that tripped the pause do not trip it again
+	delete(d.channels, channelID)
+
+	return true
+}
+
+// forgetIdle drops channels with no recent messages and no pause.
+func (d *loopDetector) forgetIdle(at time.Time) {
+	cutoff := at.Add(-d.window)
+	for channelID, activity := range d.channels {
+		idle := len(activity.messages) == 0 || activity.messages[len(activity.messages)-1].at.Before(cutoff)
+		if idle && !d.now().Before(activity.pausedUntil) {
+			delete(d.channels, channelID)
+		}
+	}
+}
+
+func (a *channelActivity) prune(cutoff time.Time) {
+	kept := a.messages[:0]
+	for _, m := range a.messages {
+		if !m.at.Before(cutoff) {
+			kept = append(kept, m)
+		}
+	}
+	a.messages = kept
+}
+
+// contentHash hashes content with case and whitespace differences removed,
+// so that relays reformatting messages slightly still match.
+func contentHash(content string) uint64 {
+	normalized := strings.Join(strings.Fields(strings.ToLower(content)), " ")
+	if normalized == "" {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(normalized))
+
+	return h.Sum64()
+}
@@ -0,0 +1,79 @@
+// Package moderation keeps the users and threads the bot ignores and pauses
+// the bot in channels caught in a feedback loop.
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/fx"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+// Global is the guild ID under which users ignored in every server are listed.
+const Global = discord.NullGuildID
+
+// ErrConfigured is returned by IgnoreList.Remove for users ignored in the
+// config file, which only the operator can change.
+var ErrConfigured = errors.New("this user is ignored in the bot's config file")
+
+// IgnoreList holds the users whose messages and interactions the bot skips,
+// per guild and globally.
+type IgnoreList interface {
+	// Ignored reports whether userID is ignored in guildID or globally.
+	Ignored(guildID discord.GuildID, userID discord.UserID) bool
+	// Add ignores userID in guildID, or everywhere for Global, and reports
+	// whether the user was not ignored there before.
+	Add(guildID discord.GuildID, userID discord.UserID) (bool, error)
+	// Remove stops ignoring userID in guildID, or everywhere for Global, and
+	// reports whether the user was ignored there.
+	Remove(guildID discord.GuildID, userID discord.UserID) (bool, error)
+	// List returns the users ignored in guildID, or globally for Global.
+	List(guildID discord.GuildID) []discord.UserID
+}
+
+// Module provides the IgnoreList, the ThreadMutes and the LoopDetector.
+var Module = fx.Module("moderation",
+	fx.Provide(NewIgnoreListProvider),
+	fx.Provide(NewThreadMutesProvider),
+	fx.Provide(NewLoopDetector),
+)
+
+// NewIgnoreListProvider creates the IgnoreList configured in cfg, kept in the
+// state backend, with the users of moderation.ignored_user_ids ignored
+// everywhere.
+func NewIgnoreListProvider(cfg *config.Config, state storage.Provider) (IgnoreList, error) {
+	path := cfg.Moderation.IgnoreFile
+	if path == "" {
+		path = "ignored_users.json"
+	}
+
+	configured, err := parseUserIDs(cfg.Moderation.IgnoredUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid moderation.ignored_user_ids: %w", err)
+	}
+
+	return NewDocumentIgnoreList(storage.NewDocument(state, path), configured)
+}
+
+// IsAdmin reports whether userID may manage the global ignore list.
+func IsAdmin(cfg *config.Config, userID discord.UserID) bool {
+	return slices.Contains(cfg.Moderation.AdminUserIDs, userID.String())
+}
+
+func parseUserIDs(ids []string) ([]discord.UserID, error) {
+	users := make([]discord.UserID, 0, len(ids))
+	for _, id := range ids {
+		sf, err := discord.ParseSnowflake(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %q: %w", id, err)
+		}
+		users = append(users, discord.UserID(sf))
+	}
+
+	return users, nil
+}
@@ -0,0 +1,154 @@
+package moderation_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func TestFileIgnoreList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "ignored.json")
+	guild, other := discord.GuildID(1), discord.GuildID(2)
+
+	list, err := moderation.NewFileIgnoreList(path, []discord.UserID{99})
+	require.NoError(t, err)
+	assert.True(t, list.Ignored(guild, 99), "configured users are ignored everywhere")
+	assert.False(t, list.Ignored(guild, 10))
+
+	added, err := list.Add(guild, 10)
+	require.NoError(t, err)
+	assert.True(t, added)
+	added, err = list.Add(guild, 10)
+	require.NoError(t, err)
+	assert.False(t, added)
+	_, err = list.Add(moderation.Global, 11)
+	require.NoError(t, err)
+
+	assert.True(t, list.Ignored(guild, 10))
+	assert.False(t, list.Ignored(other, 10), "server entries only apply to their server")
+	assert.True(t, list.Ignored(other, 11))
+	assert.True(t, list.Ignored(discord.NullGuildID, 11), "global entries apply in DMs")
+	assert.Equal(t, []discord.UserID{11, 99}, list.List(moderation.Global))
+
+	_, err = list.Remove(moderation.Global, 99)
+	require.ErrorIs(t, err, moderation.ErrConfigured)
+
+	// The list survives a restart
+	reloaded, err := moderation.NewFileIgnoreList(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []discord.UserID{10}, reloaded.List(guild))
+	assert.Equal(t, []discord.UserID{11}, reloaded.List(moderation.Global))
+
+	removed, err := reloaded.Remove(guild, 10)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = reloaded.Remove(guild, 10)
+	require.NoError(t, err)
+	assert.False(t, removed)
+	assert.False(t, reloaded.Ignored(guild, 10))
+}
+
+func TestNewIgnoreListProvider(t *testing.T) {
+	cfg := &config.Config{Moderation: config.ModerationConfig{
+		IgnoreFile:     filepath.Join(t.TempDir(), "ignored.json"),
+		IgnoredUserIDs: []string{"42"},
+		AdminUserIDs:   []string{"7"},
+	}}
+
+	list, err := moderation.NewIgnoreListProvider(cfg, storage.NewFileProvider(""))
+	require.NoError(t, err)
+	assert.True(t, list.Ignored(discord.GuildID(1), 42))
+	assert.True(t, moderation.IsAdmin(cfg, 7))
+	assert.False(t, moderation.IsAdmin(cfg, 42))
+
+	cfg.Moderation.IgnoredUserIDs = []string{"not-an-id"}
+	_, err = moderation.NewIgnoreListProvider(cfg, storage.NewFileProvider(""))
+	assert.ErrorContains(t, err, "not-an-id")
+}
+
+func TestLoopDetector(t *testing.T) {
+	cfg := &config.Config{Moderation: config.ModerationConfig{LoopDetection: config.LoopDetectionConfig{
+		Enabled:          true,
+		RepeatThreshold:  3,
+		WebhookThreshold: 3,
+		WindowSeconds:    60,
+	}}}
+	start := time.Now()
+	message := func(channelID discord.ChannelID, content string, after time.Duration) *discord.Message {
+		return &discord.Message{ChannelID: channelID, Content: content, Timestamp: discord.NewTimestamp(start.Add(after))}
+	}
+
+	loops := moderation.NewLoopDetector(cfg)
+	assert.Empty(t, loops.Observe(message(1, "Hello there", 0), true), "the bot's own messages never pause")
+	assert.Empty(t, loops.Observe(message(1, "hello  there", time.Second), false))
+	assert.Empty(t, loops.Observe(message(1, "something else", 2*time.Second), false))
+	assert.False(t, loops.Paused(1))
+	assert.NotEmpty(t, loops.Observe(message(1, "HELLO THERE", 3*time.Second), false), "relayed copies of a reply count as repeats")
+	assert.True(t, loops.Paused(1))
+	assert.False(t, loops.Paused(2))
+
+	assert.True(t, loops.Resume(1))
+	assert.False(t, loops.Resume(1))
+	assert.False(t, loops.Paused(1))
+
+	// Repeats outside the window do not add up
+	for i := range 3 {
+		assert.Empty(t, loops.Observe(message(3, "ping", time.Duration(i)*2*time.Minute), false))
+	}
+
+	webhook := func(content string, after time.Duration) *discord.Message {
+		msg := message(4, content, after)
+		msg.WebhookID = 7
+
+		return msg
+	}
+	assert.Empty(t, loops.Observe(webhook("one", 0), false))
+	assert.Empty(t, loops.Observe(webhook("two", time.Second), false))
+	assert.NotEmpty(t, loops.Observe(webhook("three", 2*time.Second), false), "bursts of webhook posts pause")
+	assert.True(t, loops.Paused(4))
+
+	disabled := moderation.NewLoopDetector(&config.Config{})
+	for i := range 10 {
+		assert.Empty(t, disabled.Observe(webhook("same", time.Duration(i)*time.Second), false))
+	}
+}
+
+func TestFileThreadMutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "muted.json")
+
+	mutes, err := moderation.NewFileThreadMutes(path)
+	require.NoError(t, err)
+	assert.False(t, mutes.Muted(1))
+
+	muted, err := mutes.Mute(1, 10)
+	require.NoError(t, err)
+	assert.True(t, muted)
+	muted, err = mutes.Mute(1, 11)
+	require.NoError(t, err)
+	assert.False(t, muted, "muting an already muted thread changes nothing")
+	assert.True(t, mutes.Muted(1))
+	assert.False(t, mutes.Muted(2))
+
+	reloaded, err := moderation.NewFileThreadMutes(path)
+	require.NoError(t, err)
+	assert.True(t, reloaded.Muted(1), "mutes survive restarts")
+
+	unmuted, err := reloaded.Unmute(1)
+	require.NoError(t, err)
+	assert.True(t, unmuted)
+	unmuted, err = reloaded.Unmute(1)
+	require.NoError(t, err)
+	assert.False(t, unmuted)
+
+	reloaded, err = moderation.NewFileThreadMutes(path)
+	require.NoError(t, err)
+	assert.False(t, reloaded.Muted(1))
+}
@@ -0,0 +1,114 @@
+package moderation
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+// ThreadMutes holds the managed threads whose participants told the bot to
+// stop replying. Mutes are kept in the state backend, so they outlive cached conversations
+// and restarts.
+type ThreadMutes interface {
+	// Muted reports whether replies in threadID are muted.
+	Muted(threadID discord.ChannelID) bool
+	// Mute stops replies in threadID on behalf of userID and reports whether
+	// the thread was not muted before.
+	Mute(threadID discord.ChannelID, userID discord.UserID) (bool, error)
+	// Unmute resumes replies in threadID and reports whether it was muted.
+	Unmute(threadID discord.ChannelID) (bool, error)
+}
+
+// threadMute records who muted a thread and when.
+type threadMute struct {
+	MutedBy discord.UserID `json:"muted_by"`
+	MutedAt time.Time      `json:"muted_at"`
+}
+
+// NewThreadMutesProvider creates the ThreadMutes configured in cfg, kept in
+// the state backend.
+func NewThreadMutesProvider(cfg *config.Config, state storage.Provider) (ThreadMutes, error) {
+	path := cfg.Moderation.MutedThreadsFile
+	if path == "" {
+		path = "muted_threads.json"
+	}
+
+	return NewDocumentThreadMutes(storage.NewDocument(state, path))
+}
+
+// NewFileThreadMutes creates ThreadMutes persisted to the file at path,
+// loading the mutes already saved there.
+func NewFileThreadMutes(path string) (ThreadMutes, error) {
+	return NewDocumentThreadMutes(storage.NewDocument(storage.NewFileProvider(""), path))
+}
+
+// NewDocumentThreadMutes creates ThreadMutes persisted to doc, loading the
+// mutes already saved in it.
+func NewDocumentThreadMutes(doc storage.Document) (ThreadMutes, error) {
+	m := &documentThreadMutes{doc: doc, threads: make(map[discord.ChannelID]threadMute)}
+	if _, err := doc.Load(&m.threads); err != nil {
+		return nil, fmt.Errorf("failed to load muted threads: %w", err)
+	}
+
+	return m, nil
+}
+
+// documentThreadMutes keeps the mutes in memory and saves them to a JSON
+// document on every change.
+type documentThreadMutes struct {
+	doc storage.Document
+
+	mu      sync.RWMutex
+	threads map[discord.ChannelID]threadMute
+}
+
+func (m *documentThreadMutes) Muted(threadID discord.ChannelID) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.threads[threadID]
+
+	return ok
+}
+
+func (m *documentThreadMutes) Mute(threadID discord.ChannelID, userID discord.UserID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.threads[threadID]; ok {
+		return false, nil
+	}
+
+	m.threads[threadID] = threadMute{MutedBy: userID, MutedAt: time.Now().UTC()}
+	if err := m.doc.Save(m.threads); err != nil {
+		delete(m.threads, threadID)
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (m *documentThreadMutes) Unmute(threadID discord.ChannelID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mute, ok := m.threads[threadID]
+	if !ok {
+		return false, nil
+	}
+
+	delete(m.threads, threadID)
+	if err := m.doc.Save(m.threads); err != nil {
+		m.threads[threadID] = mute
+
+		return false, err
+	}
+
+	return true, nil
+}
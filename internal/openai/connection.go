@@ -0,0 +1,135 @@
+package openai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	openairt "github.com/WqyJh/go-openai-realtime"
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// defaultRealtimeURL is the realtime endpoint used when no base URL is configured.
+const defaultRealtimeURL = "wss://api.openai.com/v1/realtime"
+
+// NewHTTPClient creates the HTTP client OpenAI requests are sent with, using
+// the proxy and TLS settings of cfg. Without a proxy URL the standard proxy
+// environment variables apply.
+func NewHTTPClient(cfg config.ConnectionConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid openai.connection.proxy_url: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q in openai.connection.proxy_url", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
+
+func newTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.ServerName,
+		// #nosec G402 - opt-in for local gateways with self-signed certificates
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		// #nosec G304 - path comes from the operator's config, not user input
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read openai.connection.tls.ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("openai.connection.tls.ca_file contains no PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load openai.connection.tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// RealtimeURL returns the realtime WebSocket URL of cfg. A custom base URL
+// is turned into one by switching to the WebSocket scheme and appending /realtime.
+func RealtimeURL(cfg config.ConnectionConfig) string {
+	if cfg.RealtimeURL != "" {
+		return cfg.RealtimeURL
+	}
+	if cfg.BaseURL == "" {
+		return defaultRealtimeURL
+	}
+
+	base := strings.TrimSuffix(cfg.BaseURL, "/")
+	switch {
+	case strings.HasPrefix(base, "https://"):
+		base = "wss://" + strings.TrimPrefix(base, "https://")
+	case strings.HasPrefix(base, "http://"):
+		base = "ws://" + strings.TrimPrefix(base, "http://")
+	}
+
+	return base + "/realtime"
+}
+
+// RealtimeConfig returns the realtime client configuration for apiKey with
+// the base URLs and HTTP client of the connection settings.
+func RealtimeConfig(cfg config.ConnectionConfig, apiKey string, httpClient *http.Client) openairt.ClientConfig {
+	clientConfig := openairt.DefaultConfig(apiKey)
+	clientConfig.BaseURL = RealtimeURL(cfg)
+	if cfg.BaseURL != "" {
+		clientConfig.APIBaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	clientConfig.HTTPClient = httpClient
+
+	return clientConfig
+}
+
+// clientConfig returns the go-openai configuration for creds.
+func clientConfig(cfg config.ConnectionConfig, creds Credentials, httpClient *http.Client) openai.ClientConfig {
+	clientConfig := openai.DefaultConfig(creds.APIKey)
+	if cfg.BaseURL != "" {
+		clientConfig.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	clientConfig.OrgID = creds.Organization
+	clientConfig.HTTPClient = httpClient
+	if creds.Project != "" {
+		clientConfig.HTTPClient = &http.Client{Transport: &headerTransport{
+			base:    httpClient.Transport,
+			headers: http.Header{projectHeader: {creds.Project}},
+		}}
+	}
+
+	return clientConfig
+}
@@ -0,0 +1,66 @@
+package openai
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func TestRealtimeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ConnectionConfig
+		want string
+	}{
+		{name: "default", want: defaultRealtimeURL},
+		{name: "https base", cfg: config.ConnectionConfig{BaseURL: "https://gateway.example.com/v1/"}, want: "wss://gateway.example.com/v1/realtime"},
+		{name: "http base", cfg: config.ConnectionConfig{BaseURL: "http://localhost:8080/v1"}, want: "ws://localhost:8080/v1/realtime"},
+		{
+			name: "explicit",
+			cfg:  config.ConnectionConfig{BaseURL: "https://gateway.example.com/v1", RealtimeURL: "wss://rt.example.com"},
+			want: "wss://rt.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RealtimeURL(tt.cfg))
+		})
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	client, err := NewHTTPClient(config.ConnectionConfig{ProxyURL: "socks5://127.0.0.1:1080"})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "https://api.openai.com/v1/models", nil)
+	require.NoError(t, err)
+	proxyURL, err := client.Transport.(*http.Transport).Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "socks5://127.0.0.1:1080", proxyURL.String())
+
+	_, err = NewHTTPClient(config.ConnectionConfig{ProxyURL: "ftp://127.0.0.1"})
+	assert.Error(t, err)
+
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	_, err = NewHTTPClient(config.ConnectionConfig{TLS: config.TLSConfig{CAFile: missing}})
+	assert.Error(t, err)
+}
+
+func TestClientConfig(t *testing.T) {
+	cfg := clientConfig(
+		config.ConnectionConfig{BaseURL: "https://gateway.example.com/v1/"},
+		Credentials{APIKey: "key", Organization: "org"},
+		http.DefaultClient,
+	)
+	assert.Equal(t, "https://gateway.example.com/v1", cfg.BaseURL)
+	assert.Equal(t, "org", cfg.OrgID)
+	assert.Same(t, http.DefaultClient, cfg.HTTPClient)
+
+	withProject := clientConfig(config.ConnectionConfig{}, Credentials{APIKey: "key", Project: "proj"}, http.DefaultClient)
+	assert.Equal(t, "https://api.openai.com/v1", withProject.BaseURL)
+	assert.NotSame(t, http.DefaultClient, withProject.HTTPClient)
+}
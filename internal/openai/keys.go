@@ -0,0 +1,210 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// projectHeader selects the OpenAI project requests are billed to.
+const projectHeader = "OpenAI-Project"
+
+// Credentials are the account settings OpenAI requests are sent with.
+type Credentials struct {
+	APIKey       string
+	Organization string // Optional OpenAI-Organization header
+	Project      string // Optional OpenAI-Project header
+}
+
+// Headers returns the organization and project headers of c.
+func (c Credentials) Headers() http.Header {
+	headers := http.Header{}
+	if c.Organization != "" {
+		headers.Set("OpenAI-Organization", c.Organization)
+	}
+	if c.Project != "" {
+		headers.Set(projectHeader, c.Project)
+	}
+
+	return headers
+}
+
+// Redacted names the API key of c by its last four characters, for logs.
+func (c Credentials) Redacted() string {
+	const shown = 4
+	switch {
+	case c.APIKey == "":
+		return "none"
+	case len(c.APIKey) <= 3*shown:
+		return "set"
+	}
+
+	return "…" + c.APIKey[len(c.APIKey)-shown:]
+}
+
+// KeyResolver picks the OpenAI credentials for each request, so guilds with
+// their own API key, organization or project are billed separately. Requests
+// without a guild, and guilds without overrides, use the global settings.
+type KeyResolver interface {
+	// Credentials returns the credentials for requests made for guildID.
+	Credentials(guildID discord.GuildID) Credentials
+	// RealtimeCredentials is Credentials for the realtime API, which prefers
+	// voice.realtime_api_key over the global key.
+	RealtimeCredentials(guildID discord.GuildID) Credentials
+	// Client returns an OpenAI client sending the credentials of guildID.
+	Client(guildID discord.GuildID) *openai.Client
+	// HTTPClient returns the HTTP client with the configured proxy and TLS
+	// settings, for connections not made through Client.
+	HTTPClient() *http.Client
+}
+
+// NewKeyResolver creates a KeyResolver from the openai settings and the
+// guilds.<id>.openai overrides in cfg. Clients use the openai.connection settings.
+func NewKeyResolver(cfg *config.Config, logger *zap.Logger) (KeyResolver, error) {
+	if cfg.OpenAI.APIKey == "" {
+		logger.Error("OpenAI API key is not configured in config.yaml")
+
+		return nil, errors.New("OpenAI API key (config.OpenAI.APIKey) is not configured")
+	}
+
+	httpClient, err := NewHTTPClient(cfg.OpenAI.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &keyResolver{
+		cfg:        cfg,
+		httpClient: httpClient,
+		clients:    make(map[Credentials]*openai.Client),
+	}
+	for guildID, guild := range cfg.Guilds {
+		if guild.OpenAI.APIKey != "" {
+			logger.Info("Using a separate OpenAI API key for guild", zap.String("guildID", guildID))
+		}
+	}
+
+	return r, nil
+}
+
+// NewKeyResolverFromClient creates a KeyResolver that sends every request
+// through client, e.g. one pointed at a test server.
+func NewKeyResolverFromClient(client *openai.Client) KeyResolver {
+	return staticResolver{client: client}
+}
+
+type keyResolver struct {
+	cfg        *config.Config
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	clients map[Credentials]*openai.Client
+}
+
+// Credentials merges the guild overrides over the global settings. A guild
+// with its own API key uses only its own organization and project, since the
+// global ones belong to another account.
+func (r *keyResolver) Credentials(guildID discord.GuildID) Credentials {
+	return r.resolve(guildID, r.cfg.OpenAI.APIKey)
+}
+
+func (r *keyResolver) RealtimeCredentials(guildID discord.GuildID) Credentials {
+	apiKey := r.cfg.Voice.RealtimeAPIKey
+	if apiKey == "" {
+		apiKey = r.cfg.OpenAI.APIKey
+	}
+
+	return r.resolve(guildID, apiKey)
+}
+
+func (r *keyResolver) resolve(guildID discord.GuildID, globalKey string) Credentials {
+	global := Credentials{
+		APIKey:       globalKey,
+		Organization: r.cfg.OpenAI.Organization,
+		Project:      r.cfg.OpenAI.Project,
+	}
+	if !guildID.IsValid() {
+		return global
+	}
+
+	guild := r.cfg.Guild(guildID.String()).OpenAI
+	if guild.APIKey != "" {
+		return Credentials{APIKey: guild.APIKey, Organization: guild.Organization, Project: guild.Project}
+	}
+	if guild.Organization != "" {
+		global.Organization = guild.Organization
+	}
+	if guild.Project != "" {
+		global.Project = guild.Project
+	}
+
+	return global
+}
+
+// Client returns the client for the credentials of guildID, creating it on
+// first use. Guilds sharing credentials share a client.
+func (r *keyResolver) Client(guildID discord.GuildID) *openai.Client {
+	creds := r.Credentials(guildID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client, ok := r.clients[creds]; ok {
+		return client
+	}
+	client := openai.NewClientWithConfig(clientConfig(r.cfg.OpenAI.Connection, creds, r.httpClient))
+	r.clients[creds] = client
+
+	return client
+}
+
+func (r *keyResolver) HTTPClient() *http.Client {
+	return r.httpClient
+}
+
+type staticResolver struct {
+	client *openai.Client
+}
+
+func (r staticResolver) Credentials(discord.GuildID) Credentials         { return Credentials{} }
+func (r staticResolver) RealtimeCredentials(discord.GuildID) Credentials { return Credentials{} }
+func (r staticResolver) Client(discord.GuildID) *openai.Client           { return r.client }
+func (r staticResolver) HTTPClient() *http.Client                        { return http.DefaultClient }
+
+// headerTransport adds headers to every request, for headers go-openai has no setting for.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+type guildKey struct{}
+
+// WithGuild returns a context whose OpenAI requests are made for guildID.
+func WithGuild(ctx context.Context, guildID discord.GuildID) context.Context {
+	return context.WithValue(ctx, guildKey{}, guildID)
+}
+
+// GuildFrom returns the guild set with WithGuild, or discord.NullGuildID.
+func GuildFrom(ctx context.Context) discord.GuildID {
+	guildID, ok := ctx.Value(guildKey{}).(discord.GuildID)
+	if !ok {
+		return discord.NullGuildID
+	}
+
+	return guildID
+}
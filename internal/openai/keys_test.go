@@ -0,0 +1,73 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func TestKeyResolver_Credentials(t *testing.T) {
+	cfg := &config.Config{
+		OpenAI: config.OpenAIConfig{APIKey: "global", Organization: "org-global", Project: "proj-global"},
+		Voice:  config.VoiceConfig{RealtimeAPIKey: "realtime"},
+		Guilds: map[string]config.GuildConfig{
+			"1": {OpenAI: config.GuildOpenAIConfig{APIKey: "tenant", Project: "proj-tenant"}},
+			"2": {OpenAI: config.GuildOpenAIConfig{Project: "proj-two"}},
+		},
+	}
+	keys, err := NewKeyResolver(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	global := Credentials{APIKey: "global", Organization: "org-global", Project: "proj-global"}
+	assert.Equal(t, global, keys.Credentials(discord.NullGuildID))
+	assert.Equal(t, global, keys.Credentials(3), "guilds without overrides use the global account")
+	assert.Equal(t, Credentials{APIKey: "tenant", Project: "proj-tenant"}, keys.Credentials(1),
+		"a guild with its own key does not inherit the global organization")
+	assert.Equal(t, Credentials{APIKey: "global", Organization: "org-global", Project: "proj-two"}, keys.Credentials(2))
+
+	assert.Equal(t, "realtime", keys.RealtimeCredentials(3).APIKey)
+	assert.Equal(t, "tenant", keys.RealtimeCredentials(1).APIKey, "guild keys take precedence for realtime too")
+
+	assert.Same(t, keys.Client(3), keys.Client(discord.NullGuildID), "guilds sharing credentials share a client")
+	assert.NotSame(t, keys.Client(1), keys.Client(3))
+
+	_, err = NewKeyResolver(&config.Config{}, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestHeaderTransport(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer server.Close()
+
+	headers := Credentials{Organization: "org", Project: "proj"}.Headers()
+	client := &http.Client{Transport: &headerTransport{base: http.DefaultTransport, headers: headers}}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "org", got.Get("OpenAI-Organization"))
+	assert.Equal(t, "proj", got.Get("OpenAI-Project"))
+	assert.Empty(t, Credentials{}.Headers())
+}
+
+func TestCredentials_Redacted(t *testing.T) {
+	assert.Equal(t, "none", Credentials{}.Redacted())
+	assert.Equal(t, "set", Credentials{APIKey: "sk-short"}.Redacted())
+	assert.Equal(t, "…wxyz", Credentials{APIKey: "sk-proj-abcdefghwxyz"}.Redacted())
+}
+
+func TestWithGuild(t *testing.T) {
+	assert.Equal(t, discord.NullGuildID, GuildFrom(context.Background()))
+	assert.Equal(t, discord.GuildID(42), GuildFrom(WithGuild(context.Background(), 42)))
+}
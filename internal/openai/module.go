@@ -2,36 +2,30 @@
 package openai
 
 import (
-	"errors"
-
+	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/sashabaranov/go-openai"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
-	"github.com/Raikerian/go-discord-chatgpt/internal/config"
 	pkgopenai "github.com/Raikerian/go-discord-chatgpt/pkg/openai"
 )
 
 // Module provides OpenAI-related dependencies.
 var Module = fx.Module("openai",
 	fx.Provide(
+		NewKeyResolver,
 		NewClient,
 		NewPricingService,
+		NewResilience,
 	),
 )
 
-// NewClient creates and configures a new OpenAI client.
-func NewClient(cfg *config.Config, logger *zap.Logger) (*openai.Client, error) {
-	if cfg.OpenAI.APIKey == "" {
-		logger.Error("OpenAI API key is not configured in config.yaml")
-
-		return nil, errors.New("OpenAI API key (config.OpenAI.APIKey) is not configured")
-	}
-
-	client := openai.NewClient(cfg.OpenAI.APIKey)
+// NewClient creates the OpenAI client using the global credentials.
+func NewClient(keys KeyResolver, logger *zap.Logger) *openai.Client {
+	client := keys.Client(discord.NullGuildID)
 	logger.Info("OpenAI client created successfully.")
 
-	return client, nil
+	return client
 }
 
 // NewPricingService creates and configures a new OpenAI pricing service.
@@ -0,0 +1,319 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultMaxAttempts     = 3
+	defaultInitialBackoff  = 500 * time.Millisecond
+	defaultMaxBackoff      = 8 * time.Second
+	defaultBreakerFailures = 5
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is wrapped by the UnavailableError of requests not sent
+// because their service kept failing.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// UnavailableError reports a request that failed because its service is
+// temporarily unavailable: its retries ran out on transient failures, or it
+// was not sent while the service's circuit breaker was open.
+type UnavailableError struct {
+	Service string
+	RetryAt time.Time // When requests are sent again; zero if they already are
+	Err     error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s is temporarily unavailable: %v", e.Service, e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// UserMessage tells Discord users the AI is temporarily unavailable, and when
+// to try again if known.
+func (e *UnavailableError) UserMessage() string {
+	if e.RetryAt.IsZero() {
+		return "Sorry, the AI is temporarily unavailable. Please try again in a few moments."
+	}
+
+	return fmt.Sprintf("Sorry, the AI is temporarily unavailable. Please try again <t:%d:R>.", e.RetryAt.Unix())
+}
+
+// permanentError marks a failure not to retry, whatever its cause.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err, returned by a call to Resilience.Do, as not to be
+// retried, such as a streamed reply that stopped after content arrived.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// IsTransient reports whether err is a failure a retry may fix: a rate
+// limit other than an exhausted quota, a server error or a timeout.
+func IsTransient(err error) bool {
+	var permanent *permanentError
+	if err == nil || errors.As(err, &permanent) {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.Code == "insufficient_quota" || apiErr.Type == "insufficient_quota" {
+			return false
+		}
+
+		return transientStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return transientStatus(reqErr.HTTPStatusCode)
+	}
+	var netErr net.Error
+
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func transientStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// Resilience retries transient failures of requests with jittered
+// exponential backoff, and keeps a circuit breaker per service and
+// credentials: once breaker_failures requests in a row have failed, requests
+// to the service with those credentials fail fast with an *UnavailableError
+// until the cooldown ends and a trial request succeeds. Guilds with their own
+// API key thus keep their requests flowing while another key is rate limited
+// or out of quota. A nil Resilience sends every request once.
+type Resilience struct {
+	logger *zap.Logger
+	cfg    config.RetryConfig
+
+	mu       sync.Mutex
+	breakers map[breakerKey]*breaker
+}
+
+// breakerKey identifies the requests sharing a circuit breaker.
+type breakerKey struct {
+	service string
+	creds   Credentials
+}
+
+// breaker is the circuit breaker of a service and credentials.
+type breaker struct {
+	failures  int       // Requests failed in a row
+	openUntil time.Time // Zero while the breaker is closed
+	trial     bool      // A request is testing whether the service is back
+}
+
+// NewResilience creates a Resilience with the openai.retry settings of cfg.
+func NewResilience(logger *zap.Logger, cfg *config.Config) *Resilience {
+	return &Resilience{
+		logger:   logger.Named("resilience"),
+		cfg:      cfg.OpenAI.Retry,
+		breakers: make(map[breakerKey]*breaker),
+	}
+}
+
+// Do runs call for service with creds, retrying transient failures until
+// max_attempts calls were made or ctx ends. When retries run out on a
+// transient failure, or the breaker of service and creds is open, the error
+// is an *UnavailableError. Services without per-guild credentials pass the
+// zero Credentials.
+func (r *Resilience) Do(ctx context.Context, service string, creds Credentials, call func(ctx context.Context) error) error {
+	if r == nil {
+		return call(ctx)
+	}
+	key := breakerKey{service: service, creds: creds}
+	if err := r.allow(key); err != nil {
+		return err
+	}
+
+	maxAttempts := r.cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = call(ctx); !IsTransient(err) || ctx.Err() != nil || attempt >= maxAttempts {
+			break
+		}
+
+		wait := r.backoff(attempt)
+		r.logger.Warn("Retrying request after transient failure",
+			zap.String("service", service),
+			zap.String("apiKey", creds.Redacted()),
+			zap.Int("attempt", attempt),
+			zap.Duration("wait", wait),
+			zap.Error(err))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if ctx.Err() != nil {
+		// Canceled requests say nothing about the service
+		r.release(key)
+
+		return unwrapPermanent(err)
+	}
+	if retryAt, transient := r.record(key, err); transient {
+		return &UnavailableError{Service: service, RetryAt: retryAt, Err: err}
+	}
+
+	return unwrapPermanent(err)
+}
+
+func unwrapPermanent(err error) error {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return permanent.err
+	}
+
+	return err
+}
+
+// allow returns an *UnavailableError while the breaker of key is open,
+// letting one trial request through once the cooldown ended.
+func (r *Resilience) allow(key breakerKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.breaker(key)
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if now := time.Now(); now.Before(b.openUntil) || b.trial {
+		retryAt := b.openUntil
+		if retryAt.Before(now) {
+			// The trial request is still out
+			retryAt = now.Add(r.cooldown())
+		}
+
+		return &UnavailableError{Service: key.service, RetryAt: retryAt, Err: ErrCircuitOpen}
+	}
+	b.trial = true
+
+	return nil
+}
+
+// record updates the breaker of key with the outcome of a request,
+// reporting whether it failed transiently and, if that opened the breaker,
+// until when.
+func (r *Resilience) record(key breakerKey, err error) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.breaker(key)
+	wasTrial := b.trial
+	b.trial = false
+	if !IsTransient(err) {
+		if !b.openUntil.IsZero() {
+			r.logger.Info("Circuit breaker closed, service is back",
+				zap.String("service", key.service),
+				zap.String("apiKey", key.creds.Redacted()))
+		}
+		b.failures = 0
+		b.openUntil = time.Time{}
+
+		return time.Time{}, false
+	}
+
+	b.failures++
+	threshold := r.cfg.BreakerFailures
+	if threshold <= 0 {
+		threshold = defaultBreakerFailures
+	}
+	if !wasTrial && b.failures < threshold {
+		return time.Time{}, true
+	}
+	b.openUntil = time.Now().Add(r.cooldown())
+	r.logger.Error("Circuit breaker opened after repeated failures",
+		zap.String("service", key.service),
+		zap.String("apiKey", key.creds.Redacted()),
+		zap.Int("failures", b.failures),
+		zap.Time("retryAt", b.openUntil),
+		zap.Error(err))
+
+	return b.openUntil, true
+}
+
+// release ends the trial of a request that was canceled, so another one can
+// test the service.
+func (r *Resilience) release(key breakerKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.breaker(key).trial = false
+}
+
+// breaker returns the breaker of key. The caller must hold r.mu.
+func (r *Resilience) breaker(key breakerKey) *breaker {
+	b, ok := r.breakers[key]
+	if !ok {
+		b = &breaker{}
+		r.breakers[key] = b
+	}
+
+	return b
+}
+
+// backoff returns the wait before retry attempt, doubling from the initial
+// backoff up to the maximum, with the upper half picked at random.
+func (r *Resilience) backoff(attempt int) time.Duration {
+	initial := time.Duration(r.cfg.InitialBackoffMS) * time.Millisecond
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	maxBackoff := time.Duration(r.cfg.MaxBackoffMS) * time.Millisecond
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	wait := maxBackoff
+	if shift := attempt - 1; shift < 30 && initial<<shift < maxBackoff {
+		wait = initial << shift
+	}
+
+	// #nosec G404 - jitter needs no cryptographic randomness
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func (r *Resilience) cooldown() time.Duration {
+	if r.cfg.BreakerCooldownSeconds > 0 {
+		return time.Duration(r.cfg.BreakerCooldownSeconds) * time.Second
+	}
+
+	return defaultBreakerCooldown
+}
@@ -0,0 +1,129 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+func newTestResilience() *Resilience {
+	cfg := &config.Config{}
+	cfg.OpenAI.Retry = config.RetryConfig{MaxAttempts: 3, InitialBackoffMS: 1, MaxBackoffMS: 2, BreakerFailures: 2}
+
+	return NewResilience(zap.NewNop(), cfg)
+}
+
+func statusError(status int) error {
+	return &openai.APIError{HTTPStatusCode: status, HTTPStatus: http.StatusText(status)}
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, IsTransient(statusError(http.StatusTooManyRequests)))
+	assert.True(t, IsTransient(fmt.Errorf("wrapped: %w", statusError(http.StatusBadGateway))))
+	assert.True(t, IsTransient(&openai.RequestError{HTTPStatusCode: http.StatusServiceUnavailable}))
+	assert.True(t, IsTransient(context.DeadlineExceeded))
+	assert.False(t, IsTransient(statusError(http.StatusBadRequest)))
+	assert.False(t, IsTransient(&openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Code: "insufficient_quota"}), "an exhausted quota lasts")
+	assert.False(t, IsTransient(Permanent(statusError(http.StatusBadGateway))))
+	assert.False(t, IsTransient(context.Canceled))
+	assert.False(t, IsTransient(nil))
+}
+
+func TestResilience_Retries(t *testing.T) {
+	r := newTestResilience()
+
+	calls := 0
+	err := r.Do(context.Background(), "OpenAI", Credentials{}, func(context.Context) error {
+		if calls++; calls < 3 {
+			return statusError(http.StatusInternalServerError)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls, "transient failures are retried")
+
+	calls = 0
+	err = r.Do(context.Background(), "OpenAI", Credentials{}, func(context.Context) error {
+		calls++
+
+		return statusError(http.StatusBadRequest)
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, calls, "other failures are not")
+	assert.NotErrorAs(t, err, new(*UnavailableError))
+
+	calls = 0
+	err = r.Do(context.Background(), "OpenAI", Credentials{}, func(context.Context) error {
+		calls++
+
+		return Permanent(statusError(http.StatusBadGateway))
+	})
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, statusError(http.StatusBadGateway), err, "permanent errors are returned unwrapped")
+}
+
+func TestResilience_CircuitBreaker(t *testing.T) {
+	r := newTestResilience()
+	failing := func(context.Context) error { return statusError(http.StatusServiceUnavailable) }
+
+	err := r.Do(context.Background(), "OpenAI", Credentials{}, failing)
+	var unavailable *UnavailableError
+	require.ErrorAs(t, err, &unavailable, "running out of retries reports the service unavailable")
+	assert.True(t, unavailable.RetryAt.IsZero(), "the breaker is still closed")
+
+	err = r.Do(context.Background(), "OpenAI", Credentials{}, failing)
+	require.ErrorAs(t, err, &unavailable)
+	assert.False(t, unavailable.RetryAt.IsZero(), "repeated failures open the breaker")
+	assert.Contains(t, unavailable.UserMessage(), "temporarily unavailable")
+
+	calls := 0
+	err = r.Do(context.Background(), "OpenAI", Credentials{}, func(context.Context) error {
+		calls++
+
+		return nil
+	})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Zero(t, calls, "requests fail fast while the breaker is open")
+	require.NoError(t, r.Do(context.Background(), "Anthropic", Credentials{}, func(context.Context) error { return nil }), "each service has its own breaker")
+	guildKey := Credentials{APIKey: "sk-guild-key-1234"}
+	require.NoError(t, r.Do(context.Background(), "OpenAI", guildKey, func(context.Context) error { return nil }),
+		"a guild with its own API key has its own breaker")
+
+	// Once the cooldown ends, a successful trial request closes the breaker
+	r.breakers[breakerKey{service: "OpenAI"}].openUntil = time.Now().Add(-time.Second)
+	require.NoError(t, r.Do(context.Background(), "OpenAI", Credentials{}, func(context.Context) error { return nil }))
+	require.NoError(t, r.Do(context.Background(), "OpenAI", Credentials{}, func(context.Context) error { return nil }))
+}
+
+func TestResilience_CanceledRequests(t *testing.T) {
+	r := newTestResilience()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := r.Do(ctx, "OpenAI", Credentials{}, func(context.Context) error {
+		calls++
+		cancel()
+
+		return statusError(http.StatusServiceUnavailable)
+	})
+	assert.Equal(t, 1, calls, "canceled requests are not retried")
+	assert.NotErrorAs(t, err, new(*UnavailableError))
+	assert.Zero(t, r.breakers[breakerKey{service: "OpenAI"}].failures, "nor counted against the service")
+}
+
+func TestResilience_Nil(t *testing.T) {
+	var r *Resilience
+	err := r.Do(context.Background(), "OpenAI", Credentials{}, func(context.Context) error { return errors.New("boom") })
+	assert.EqualError(t, err, "boom")
+}
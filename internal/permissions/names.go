@@ -0,0 +1,50 @@
+package permissions
+
+import "github.com/diamondburned/arikawa/v3/discord"
+
+// permissionsByName maps the names permissions are configured by, those of
+// Discord's API documentation in lower case, to their flags.
+var permissionsByName = map[string]discord.Permissions{
+	"administrator":            discord.PermissionAdministrator,
+	"manage_guild":             discord.PermissionManageGuild,
+	"manage_channels":          discord.PermissionManageChannels,
+	"manage_roles":             discord.PermissionManageRoles,
+	"manage_messages":          discord.PermissionManageMessages,
+	"manage_threads":           discord.PermissionManageThreads,
+	"manage_webhooks":          discord.PermissionManageWebhooks,
+	"manage_events":            discord.PermissionManageEvents,
+	"manage_nicknames":         discord.PermissionManageNicknames,
+	"view_audit_log":           discord.PermissionViewAuditLog,
+	"kick_members":             discord.PermissionKickMembers,
+	"ban_members":              discord.PermissionBanMembers,
+	"moderate_members":         discord.PermissionModerateMembers,
+	"mention_everyone":         discord.PermissionMentionEveryone,
+	"view_channel":             discord.PermissionViewChannel,
+	"send_messages":            discord.PermissionSendMessages,
+	"send_messages_in_threads": discord.PermissionSendMessagesInThreads,
+	"create_public_threads":    discord.PermissionCreatePublicThreads,
+	"create_private_threads":   discord.PermissionCreatePrivateThreads,
+	"embed_links":              discord.PermissionEmbedLinks,
+	"attach_files":             discord.PermissionAttachFiles,
+	"read_message_history":     discord.PermissionReadMessageHistory,
+	"use_application_commands": discord.PermissionUseSlashCommands,
+	"connect":                  discord.PermissionConnect,
+	"speak":                    discord.PermissionSpeak,
+	"mute_members":             discord.PermissionMuteMembers,
+	"deafen_members":           discord.PermissionDeafenMembers,
+	"move_members":             discord.PermissionMoveMembers,
+	"priority_speaker":         discord.PermissionPrioritySpeaker,
+	"stream":                   discord.PermissionStream,
+}
+
+// permissionNames orders the names Names lists permissions by.
+var permissionNames = []string{
+	"administrator", "manage_guild", "manage_channels", "manage_roles",
+	"manage_messages", "manage_threads", "manage_webhooks", "manage_events",
+	"manage_nicknames", "view_audit_log", "kick_members", "ban_members",
+	"moderate_members", "mention_everyone", "view_channel", "send_messages",
+	"send_messages_in_threads", "create_public_threads", "create_private_threads",
+	"embed_links", "attach_files", "read_message_history", "use_application_commands",
+	"connect", "speak", "mute_members", "deafen_members", "move_members",
+	"priority_speaker", "stream",
+}
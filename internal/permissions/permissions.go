@@ -0,0 +1,342 @@
+// Package permissions decides who may use each slash command: the Discord
+// permissions a command declares, replaced or narrowed per guild by the
+// roles and permissions configured for it.
+package permissions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/session"
+	"go.uber.org/fx"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internaldiscord "github.com/Raikerian/go-discord-chatgpt/internal/discord"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
+)
+
+// Where a Rule comes from.
+const (
+	SourceCommand = "command"      // Declared by the command
+	SourceConfig  = "config"       // The permissions section of the config
+	SourceGuild   = "guild config" // The permissions of the guild's config
+)
+
+// Module provides the Policy.
+var Module = fx.Module("permissions",
+	fx.Provide(NewPolicy),
+)
+
+// Rule is what members need to use a command, or one of its subcommands.
+type Rule struct {
+	Command     string              // Command path, such as "admin ignore"
+	RoleIDs     []discord.RoleID    // Members need one of these, when set
+	Permissions discord.Permissions // Members need all of these
+	Source      string              // SourceCommand, SourceConfig or SourceGuild
+}
+
+// Decision is the outcome of checking a member against the rule of a command.
+type Decision struct {
+	Allowed bool
+	Rule    *Rule               // The rule checked, nil when none applies
+	Missing discord.Permissions // Permissions of the rule the member lacks
+	NoRole  bool                // The member has none of the rule's roles
+}
+
+// Message tells a member who was denied path in guildID what they need to
+// use it.
+func (d Decision) Message(guildID discord.GuildID, path string) string {
+	if !guildID.IsValid() {
+		return fmt.Sprintf("`/%s` can only be used in servers.", path)
+	}
+
+	var needs []string
+	if d.NoRole {
+		roles := make([]string, len(d.Rule.RoleIDs))
+		for i, id := range d.Rule.RoleIDs {
+			roles[i] = id.Mention()
+		}
+		needs = append(needs, "one of these roles: "+strings.Join(roles, ", "))
+	}
+	if d.Missing != 0 {
+		needs = append(needs, "these permissions: `"+Names(d.Missing)+"`")
+	}
+
+	return fmt.Sprintf("🔒 To use `/%s` you need %s.", path, strings.Join(needs, ", and "))
+}
+
+// Policy holds the rules commands are checked against. Rules are looked up
+// from the most specific command path, such as "admin ignore add", to the
+// command itself; a guild's rule for a path replaces the global one.
+type Policy struct {
+	cfg    *config.Config
+	global map[string]configuredRule
+	guilds map[discord.GuildID]map[string]configuredRule
+
+	mu       sync.RWMutex
+	declared map[string]discord.Permissions // key: command name
+}
+
+// NewPolicy creates the Policy configured in cfg.
+func NewPolicy(cfg *config.Config) (*Policy, error) {
+	p := &Policy{
+		cfg:      cfg,
+		guilds:   make(map[discord.GuildID]map[string]configuredRule),
+		declared: make(map[string]discord.Permissions),
+	}
+
+	var err error
+	if p.global, err = parseRules(cfg.Permissions, SourceConfig); err != nil {
+		return nil, fmt.Errorf("invalid permissions: %w", err)
+	}
+	for id, guild := range cfg.Guilds {
+		if len(guild.Permissions) == 0 {
+			continue
+		}
+		sf, err := discord.ParseSnowflake(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid guild ID %q: %w", id, err)
+		}
+		if p.guilds[discord.GuildID(sf)], err = parseRules(guild.Permissions, SourceGuild); err != nil {
+			return nil, fmt.Errorf("invalid guilds.%s.permissions: %w", id, err)
+		}
+	}
+
+	return p, nil
+}
+
+// Declare records the permissions members need to use command, unless the
+// config sets others for it.
+func (p *Policy) Declare(command string, perms discord.Permissions) {
+	if p == nil || perms == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.declared[command] = perms
+}
+
+// Restricts reports whether any rule may apply to command or its
+// subcommands, in any guild.
+func (p *Policy) Restricts(command string) bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.RLock()
+	_, declared := p.declared[command]
+	p.mu.RUnlock()
+	if declared || restricts(p.global, command) {
+		return true
+	}
+	for _, rules := range p.guilds {
+		if restricts(rules, command) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Rule returns the rule for path in guildID: the rule of its most specific
+// configured prefix, with the permissions the command declares when the
+// rule sets none.
+func (p *Policy) Rule(guildID discord.GuildID, path string) (Rule, bool) {
+	if p == nil {
+		return Rule{}, false
+	}
+
+	command, _, _ := strings.Cut(path, " ")
+	p.mu.RLock()
+	declared, hasDeclared := p.declared[command]
+	p.mu.RUnlock()
+
+	for key := path; ; {
+		if rule, ok := p.guilds[guildID][key]; ok {
+			return withDeclared(rule, declared), true
+		}
+		if rule, ok := p.global[key]; ok {
+			return withDeclared(rule, declared), true
+		}
+
+		i := strings.LastIndexByte(key, ' ')
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	if hasDeclared {
+		return Rule{Command: command, Permissions: declared, Source: SourceCommand}, true
+	}
+
+	return Rule{}, false
+}
+
+// Rules returns every rule in effect in guildID, sorted by command path.
+func (p *Policy) Rules(guildID discord.GuildID) []Rule {
+	if p == nil {
+		return nil
+	}
+
+	paths := make(map[string]struct{})
+	for key := range p.global {
+		paths[key] = struct{}{}
+	}
+	for key := range p.guilds[guildID] {
+		paths[key] = struct{}{}
+	}
+	p.mu.RLock()
+	for command := range p.declared {
+		paths[command] = struct{}{}
+	}
+	p.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(paths))
+	for path := range paths {
+		if rule, ok := p.Rule(guildID, path); ok {
+			rule.Command = path
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Command < rules[j].Command })
+
+	return rules
+}
+
+// Check decides whether member may use path in guildID. perms are the
+// member's permissions when known, such as those an interaction carries;
+// otherwise they are computed from the member's roles when the rule needs
+// them. Bot operators may use every command.
+func (p *Policy) Check(s *session.Session, guildID discord.GuildID, member *discord.Member, perms discord.Permissions, path string) (Decision, error) {
+	rule, ok := p.Rule(guildID, path)
+	if !ok {
+		return Decision{Allowed: true}, nil
+	}
+	if member != nil && moderation.IsAdmin(p.cfg, member.User.ID) {
+		return Decision{Allowed: true, Rule: &rule}, nil
+	}
+
+	decision := Decision{Rule: &rule}
+	if member == nil || !guildID.IsValid() {
+		// Roles and permissions only exist in guilds
+		decision.Missing = rule.Permissions
+		decision.NoRole = len(rule.RoleIDs) > 0
+
+		return decision, nil
+	}
+
+	if len(rule.RoleIDs) > 0 {
+		decision.NoRole = !slices.ContainsFunc(member.RoleIDs, func(id discord.RoleID) bool {
+			return slices.Contains(rule.RoleIDs, id)
+		})
+	}
+	if rule.Permissions != 0 {
+		if perms == 0 {
+			var err error
+			if perms, err = internaldiscord.RolePermissions(s, guildID, member.RoleIDs); err != nil {
+				return Decision{}, fmt.Errorf("failed to compute member permissions: %w", err)
+			}
+		}
+		if !perms.Has(discord.PermissionAdministrator) {
+			decision.Missing = rule.Permissions &^ perms
+		}
+	}
+	decision.Allowed = !decision.NoRole && decision.Missing == 0
+
+	return decision, nil
+}
+
+// Path returns the command path of data: the command name followed by the
+// subcommand group and subcommand used, such as "admin ignore add".
+func Path(data *discord.CommandInteraction) string {
+	path := data.Name
+	options := data.Options
+	for len(options) > 0 {
+		opt := options[0]
+		if opt.Type != discord.SubcommandGroupOptionType && opt.Type != discord.SubcommandOptionType {
+			break
+		}
+		path += " " + opt.Name
+		options = opt.Options
+	}
+
+	return path
+}
+
+// Names lists the config names of perms, such as "manage_guild, kick_members".
+func Names(perms discord.Permissions) string {
+	var names []string
+	for _, name := range permissionNames {
+		if perm := permissionsByName[name]; perms.Has(perm) {
+			perms &^= perm
+			names = append(names, name)
+		}
+	}
+	if perms != 0 {
+		names = append(names, "other")
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// configuredRule is a Rule read from the config, which may set no
+// permissions to lift those the command declares.
+type configuredRule struct {
+	Rule
+	permissionsSet bool
+}
+
+func restricts(rules map[string]configuredRule, command string) bool {
+	for key := range rules {
+		if key == command || strings.HasPrefix(key, command+" ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withDeclared fills in the declared permissions of a rule that sets none.
+func withDeclared(rule configuredRule, declared discord.Permissions) Rule {
+	if !rule.permissionsSet {
+		rule.Permissions = declared
+	}
+
+	return rule.Rule
+}
+
+func parseRules(configured config.PermissionsConfig, source string) (map[string]configuredRule, error) {
+	rules := make(map[string]configuredRule, len(configured))
+	for key, c := range configured {
+		path := strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(key), "/")), " ")
+		if path == "" {
+			return nil, errors.New("empty command name")
+		}
+
+		rule := configuredRule{Rule: Rule{Command: path, Source: source}, permissionsSet: c.Permissions != nil}
+		for _, id := range c.RoleIDs {
+			sf, err := discord.ParseSnowflake(id)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid role ID %q: %w", path, id, err)
+			}
+			rule.RoleIDs = append(rule.RoleIDs, discord.RoleID(sf))
+		}
+		for _, name := range c.Permissions {
+			perm, ok := permissionsByName[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown permission %q", path, name)
+			}
+			rule.Permissions |= perm
+		}
+		rules[path] = rule
+	}
+
+	return rules, nil
+}
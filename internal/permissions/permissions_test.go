@@ -0,0 +1,141 @@
+package permissions_test
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/permissions"
+)
+
+func newPolicy(t *testing.T) *permissions.Policy {
+	t.Helper()
+	cfg := &config.Config{
+		Moderation: config.ModerationConfig{AdminUserIDs: []string{"99"}},
+		Permissions: config.PermissionsConfig{
+			"voice":           {RoleIDs: []string{"10", "11"}},
+			"/admin   ignore": {Permissions: []string{"Kick_Members"}},
+		},
+		Guilds: map[string]config.GuildConfig{
+			"1": {Permissions: config.PermissionsConfig{
+				"voice": {RoleIDs: []string{"12"}},
+				"admin": {RoleIDs: []string{"13"}, Permissions: []string{}},
+			}},
+		},
+	}
+	p, err := permissions.NewPolicy(cfg)
+	require.NoError(t, err)
+	p.Declare("admin", discord.PermissionManageGuild)
+
+	return p
+}
+
+func TestPolicy_Rule(t *testing.T) {
+	p := newPolicy(t)
+
+	rule, ok := p.Rule(2, "voice start")
+	require.True(t, ok, "subcommands fall back to the command's rule")
+	assert.Equal(t, "voice", rule.Command)
+	assert.Equal(t, []discord.RoleID{10, 11}, rule.RoleIDs)
+	assert.Equal(t, permissions.SourceConfig, rule.Source)
+
+	rule, ok = p.Rule(1, "voice start")
+	require.True(t, ok)
+	assert.Equal(t, []discord.RoleID{12}, rule.RoleIDs, "guild rules replace the global ones")
+	assert.Equal(t, permissions.SourceGuild, rule.Source)
+
+	rule, _ = p.Rule(2, "admin ignore add")
+	assert.Equal(t, "admin ignore", rule.Command, "the most specific rule applies")
+	assert.Equal(t, discord.PermissionKickMembers, rule.Permissions)
+
+	rule, _ = p.Rule(2, "admin backup")
+	assert.Equal(t, discord.PermissionManageGuild, rule.Permissions)
+	assert.Equal(t, permissions.SourceCommand, rule.Source)
+
+	rule, _ = p.Rule(1, "admin backup")
+	assert.Zero(t, rule.Permissions, "an empty permissions list lifts the declared ones")
+	assert.Equal(t, []discord.RoleID{13}, rule.RoleIDs)
+
+	_, ok = p.Rule(1, "chat")
+	assert.False(t, ok)
+	assert.True(t, p.Restricts("admin"))
+	assert.True(t, p.Restricts("voice"))
+	assert.False(t, p.Restricts("chat"))
+
+	var rules []string
+	for _, r := range p.Rules(1) {
+		rules = append(rules, r.Command)
+	}
+	assert.Equal(t, []string{"admin", "admin ignore", "voice"}, rules)
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p := newPolicy(t)
+	member := func(id discord.UserID, roles ...discord.RoleID) *discord.Member {
+		return &discord.Member{User: discord.User{ID: id}, RoleIDs: roles}
+	}
+
+	d, err := p.Check(nil, 2, member(5, 11), 0, "voice start")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = p.Check(nil, 2, member(5, 12), 0, "voice start")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.True(t, d.NoRole)
+
+	d, err = p.Check(nil, 2, member(5), discord.PermissionManageGuild, "admin ignore add")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, discord.PermissionKickMembers, d.Missing)
+	assert.Equal(t, "kick_members", permissions.Names(d.Missing))
+
+	d, err = p.Check(nil, 2, member(5), discord.PermissionAll, "admin ignore add")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = p.Check(nil, discord.NullGuildID, member(5), 0, "voice")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed, "roles cannot be checked outside servers")
+
+	d, err = p.Check(nil, 2, member(99), 0, "voice")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed, "bot operators may use every command")
+
+	d, err = p.Check(nil, 2, member(5), 0, "chat")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Nil(t, d.Rule)
+}
+
+func TestNewPolicy_Invalid(t *testing.T) {
+	_, err := permissions.NewPolicy(&config.Config{Permissions: config.PermissionsConfig{
+		"voice": {Permissions: []string{"fly"}},
+	}})
+	require.ErrorContains(t, err, `unknown permission "fly"`)
+
+	_, err = permissions.NewPolicy(&config.Config{Guilds: map[string]config.GuildConfig{
+		"1": {Permissions: config.PermissionsConfig{"voice": {RoleIDs: []string{"staff"}}}},
+	}})
+	require.ErrorContains(t, err, "guilds.1.permissions")
+}
+
+func TestPath(t *testing.T) {
+	data := &discord.CommandInteraction{
+		Name: "admin",
+		Options: []discord.CommandInteractionOption{{
+			Type: discord.SubcommandGroupOptionType,
+			Name: "ignore",
+			Options: []discord.CommandInteractionOption{{
+				Type:    discord.SubcommandOptionType,
+				Name:    "add",
+				Options: []discord.CommandInteractionOption{{Type: discord.UserOptionType, Name: "user"}},
+			}},
+		}},
+	}
+	assert.Equal(t, "admin ignore add", permissions.Path(data))
+	assert.Equal(t, "chat", permissions.Path(&discord.CommandInteraction{Name: "chat"}))
+}
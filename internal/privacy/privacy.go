@@ -0,0 +1,35 @@
+// Package privacy coordinates deleting a user's personal data across the bot.
+package privacy
+
+import (
+	"context"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// Eraser deletes the personal data of a user held by one part of the bot.
+// Implementations are provided to the Fx group "erasers" and run by /forget-me.
+type Eraser interface {
+	// Name describes the erased data in the confirmation shown to the user, e.g. "conversations".
+	Name() string
+	// ForgetUser deletes everything stored about userID and returns the number of records removed.
+	ForgetUser(ctx context.Context, userID discord.UserID) (int, error)
+}
+
+// Result is the outcome of one Eraser.
+type Result struct {
+	Name    string
+	Removed int
+	Err     error
+}
+
+// ForgetUser runs every eraser for userID. All erasers run even if one fails.
+func ForgetUser(ctx context.Context, erasers []Eraser, userID discord.UserID) []Result {
+	results := make([]Result, 0, len(erasers))
+	for _, e := range erasers {
+		removed, err := e.ForgetUser(ctx, userID)
+		results = append(results, Result{Name: e.Name(), Removed: removed, Err: err})
+	}
+
+	return results
+}
@@ -0,0 +1,41 @@
+package privacy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/privacy"
+)
+
+type stubEraser struct {
+	name    string
+	removed int
+	err     error
+	calls   []discord.UserID
+}
+
+func (s *stubEraser) Name() string { return s.name }
+
+func (s *stubEraser) ForgetUser(_ context.Context, userID discord.UserID) (int, error) {
+	s.calls = append(s.calls, userID)
+
+	return s.removed, s.err
+}
+
+func TestForgetUser_RunsAllErasers(t *testing.T) {
+	failing := &stubEraser{name: "conversations", removed: 1, err: errors.New("boom")}
+	ok := &stubEraser{name: "memories", removed: 3}
+
+	results := privacy.ForgetUser(context.Background(), []privacy.Eraser{failing, ok}, 42)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, privacy.Result{Name: "conversations", Removed: 1, Err: failing.err}, results[0])
+	assert.Equal(t, privacy.Result{Name: "memories", Removed: 3}, results[1])
+	assert.Equal(t, []discord.UserID{42}, failing.calls)
+	assert.Equal(t, []discord.UserID{42}, ok.calls)
+}
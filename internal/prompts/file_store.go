@@ -0,0 +1,139 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+// guildPrompts is how a guild's prompts are saved.
+type guildPrompts struct {
+	Guild    string                       `json:"guild,omitempty"`
+	Channels map[discord.ChannelID]string `json:"channels,omitempty"`
+}
+
+// documentStore keeps prompts in memory and saves them to a JSON document
+// on every change.
+type documentStore struct {
+	doc storage.Document
+
+	mu     sync.RWMutex
+	guilds map[discord.GuildID]map[discord.ChannelID]string // NullChannelID holds the guild prompt
+}
+
+// NewFileStore creates a Store persisted to the file at path, loading any
+// prompts already saved there.
+func NewFileStore(path string) (Store, error) {
+	return NewDocumentStore(storage.NewDocument(storage.NewFileProvider(""), path))
+}
+
+// NewDocumentStore creates a Store persisted to doc, loading any prompts
+// already saved in it.
+func NewDocumentStore(doc storage.Document) (Store, error) {
+	s := &documentStore{doc: doc, guilds: make(map[discord.GuildID]map[discord.ChannelID]string)}
+
+	var saved map[discord.GuildID]guildPrompts
+	if _, err := doc.Load(&saved); err != nil {
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+	for guildID, g := range saved {
+		byChannel := make(map[discord.ChannelID]string, len(g.Channels)+1)
+		for channelID, prompt := range g.Channels {
+			byChannel[channelID] = prompt
+		}
+		if g.Guild != "" {
+			byChannel[discord.NullChannelID] = g.Guild
+		}
+		s.guilds[guildID] = byChannel
+	}
+
+	return s, nil
+}
+
+// Get returns the prompt set for channelID of guildID.
+func (s *documentStore) Get(guildID discord.GuildID, channelID discord.ChannelID) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prompt, ok := s.guilds[guildID][channelID]
+
+	return prompt, ok
+}
+
+// Set validates and stores prompt, replacing the one set before.
+func (s *documentStore) Set(guildID discord.GuildID, channelID discord.ChannelID, prompt string) error {
+	prompt = strings.TrimSpace(prompt)
+	if err := Validate(prompt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prompts := s.guilds[guildID]
+	if prompts == nil {
+		prompts = make(map[discord.ChannelID]string)
+		s.guilds[guildID] = prompts
+	}
+	previous, existed := prompts[channelID]
+
+	prompts[channelID] = prompt
+	if err := s.persist(); err != nil {
+		if existed {
+			prompts[channelID] = previous
+		} else {
+			delete(prompts, channelID)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Clear removes the prompt set for channelID of guildID.
+func (s *documentStore) Clear(guildID discord.GuildID, channelID discord.ChannelID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.guilds[guildID][channelID]
+	if !ok {
+		return false, nil
+	}
+
+	delete(s.guilds[guildID], channelID)
+	if err := s.persist(); err != nil {
+		s.guilds[guildID][channelID] = previous
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// persist saves all prompts. The caller must hold s.mu.
+func (s *documentStore) persist() error {
+	saved := make(map[discord.GuildID]guildPrompts, len(s.guilds))
+	for guildID, prompts := range s.guilds {
+		if len(prompts) == 0 {
+			continue
+		}
+		g := guildPrompts{Guild: prompts[discord.NullChannelID]}
+		for channelID, prompt := range prompts {
+			if !channelID.IsValid() {
+				continue
+			}
+			if g.Channels == nil {
+				g.Channels = make(map[discord.ChannelID]string)
+			}
+			g.Channels[channelID] = prompt
+		}
+		saved[guildID] = g
+	}
+
+	return s.doc.Save(saved)
+}
@@ -0,0 +1,102 @@
+// Package prompts stores the system prompts guilds and channels set for /chat.
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/fx"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+// MaxLength bounds a prompt, which is sent with every request.
+const MaxLength = 4000
+
+// Source is where a resolved prompt was set.
+type Source int
+
+const (
+	SourceNone Source = iota
+	SourceDefault
+	SourceGuild
+	SourceChannel
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceGuild:
+		return "server"
+	case SourceChannel:
+		return "channel"
+	default:
+		return "none"
+	}
+}
+
+// Validate checks that prompt can be set.
+func Validate(prompt string) error {
+	switch {
+	case strings.TrimSpace(prompt) == "":
+		return errors.New("system prompt is empty")
+	case len([]rune(prompt)) > MaxLength:
+		return fmt.Errorf("system prompt must be at most %d characters", MaxLength)
+	}
+
+	return nil
+}
+
+// Store holds the system prompts of every guild. A prompt set for
+// discord.NullChannelID applies to the whole guild.
+type Store interface {
+	Get(guildID discord.GuildID, channelID discord.ChannelID) (string, bool)
+	// Set creates or replaces a prompt.
+	Set(guildID discord.GuildID, channelID discord.ChannelID, prompt string) error
+	// Clear removes a prompt and reports whether it existed.
+	Clear(guildID discord.GuildID, channelID discord.ChannelID) (bool, error)
+}
+
+// Resolve returns the prompt for a request made in channelIDs of guildID,
+// most specific first, such as a thread and then its parent channel. Channel
+// prompts win over the guild's, which win over defaultPrompt.
+func Resolve(store Store, defaultPrompt string, guildID discord.GuildID, channelIDs ...discord.ChannelID) (string, Source) {
+	if store != nil && guildID.IsValid() {
+		for _, channelID := range channelIDs {
+			if !channelID.IsValid() {
+				continue
+			}
+			if prompt, ok := store.Get(guildID, channelID); ok {
+				return prompt, SourceChannel
+			}
+		}
+		if prompt, ok := store.Get(guildID, discord.NullChannelID); ok {
+			return prompt, SourceGuild
+		}
+	}
+	if strings.TrimSpace(defaultPrompt) != "" {
+		return strings.TrimSpace(defaultPrompt), SourceDefault
+	}
+
+	return "", SourceNone
+}
+
+// Module provides the prompt Store.
+var Module = fx.Module("prompts",
+	fx.Provide(NewStoreProvider),
+)
+
+// NewStoreProvider creates the Store configured in cfg, kept in the state
+// backend.
+func NewStoreProvider(cfg *config.Config, state storage.Provider) (Store, error) {
+	path := cfg.Prompts.File
+	if path == "" {
+		path = "prompts.json"
+	}
+
+	return NewDocumentStore(storage.NewDocument(state, path))
+}
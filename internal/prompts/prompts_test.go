@@ -0,0 +1,89 @@
+package prompts_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/prompts"
+)
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, prompts.Validate("Answer in French."))
+	require.Error(t, prompts.Validate("  "))
+	require.Error(t, prompts.Validate(strings.Repeat("a", prompts.MaxLength+1)))
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "prompts.json")
+	guild := discord.GuildID(1)
+	channel := discord.ChannelID(10)
+
+	store, err := prompts.NewFileStore(path)
+	require.NoError(t, err)
+	_, ok := store.Get(guild, discord.NullChannelID)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set(guild, discord.NullChannelID, " Be concise. "))
+	require.NoError(t, store.Set(guild, channel, "Answer as a pirate."))
+	require.Error(t, store.Set(guild, channel, ""))
+
+	// Prompts survive a restart
+	reloaded, err := prompts.NewFileStore(path)
+	require.NoError(t, err)
+	got, ok := reloaded.Get(guild, discord.NullChannelID)
+	require.True(t, ok)
+	assert.Equal(t, "Be concise.", got)
+	got, ok = reloaded.Get(guild, channel)
+	require.True(t, ok)
+	assert.Equal(t, "Answer as a pirate.", got)
+	_, ok = reloaded.Get(discord.GuildID(2), channel)
+	assert.False(t, ok, "prompts are per guild")
+
+	cleared, err := reloaded.Clear(guild, channel)
+	require.NoError(t, err)
+	assert.True(t, cleared)
+	cleared, err = reloaded.Clear(guild, channel)
+	require.NoError(t, err)
+	assert.False(t, cleared)
+	_, ok = reloaded.Get(guild, discord.NullChannelID)
+	assert.True(t, ok, "clearing a channel keeps the guild prompt")
+}
+
+func TestResolve(t *testing.T) {
+	store, err := prompts.NewFileStore(filepath.Join(t.TempDir(), "prompts.json"))
+	require.NoError(t, err)
+	guild := discord.GuildID(1)
+	thread, parent, other := discord.ChannelID(11), discord.ChannelID(10), discord.ChannelID(20)
+
+	prompt, source := prompts.Resolve(store, "", guild, parent)
+	assert.Empty(t, prompt)
+	assert.Equal(t, prompts.SourceNone, source)
+
+	prompt, source = prompts.Resolve(store, "Be helpful.", guild, parent)
+	assert.Equal(t, "Be helpful.", prompt)
+	assert.Equal(t, prompts.SourceDefault, source)
+
+	require.NoError(t, store.Set(guild, discord.NullChannelID, "Be concise."))
+	require.NoError(t, store.Set(guild, parent, "Answer as a pirate."))
+
+	prompt, source = prompts.Resolve(store, "Be helpful.", guild, thread, parent)
+	assert.Equal(t, "Answer as a pirate.", prompt, "threads use their parent channel's prompt")
+	assert.Equal(t, prompts.SourceChannel, source)
+
+	require.NoError(t, store.Set(guild, thread, "Answer in French."))
+	prompt, _ = prompts.Resolve(store, "Be helpful.", guild, thread, parent)
+	assert.Equal(t, "Answer in French.", prompt)
+
+	prompt, source = prompts.Resolve(store, "Be helpful.", guild, other)
+	assert.Equal(t, "Be concise.", prompt)
+	assert.Equal(t, prompts.SourceGuild, source)
+
+	prompt, source = prompts.Resolve(store, "Be helpful.", discord.NullGuildID, other)
+	assert.Equal(t, "Be helpful.", prompt, "DMs use the default")
+	assert.Equal(t, prompts.SourceDefault, source)
+}
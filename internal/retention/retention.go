@@ -0,0 +1,212 @@
+// Package retention enforces how long the bot keeps stored data.
+package retention
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+)
+
+// Data types with a configurable retention window.
+const (
+	Conversations = "conversations"
+	Transcripts   = "transcripts"
+	AuditLogs     = "audit_logs"
+	Usage         = "usage"
+)
+
+const defaultCheckInterval = 6 * time.Hour
+
+// Purger deletes stored records of one data type. Implementations are
+// provided to the Fx group "purgers".
+type Purger interface {
+	// DataType is the key of the retention window in config, e.g. Conversations.
+	DataType() string
+	// PurgeBefore deletes records last updated before cutoff and returns how many were removed.
+	PurgeBefore(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// Module provides the retention purge job.
+var Module = fx.Module("retention",
+	fx.Provide(
+		fx.Annotate(
+			NewJob,
+			fx.ParamTags(``, ``, ``, `group:"purgers"`),
+		),
+	),
+)
+
+// Stats are the purge metrics of one data type since startup.
+type Stats struct {
+	DataType   string
+	Runs       int
+	Purged     int // total records purged
+	LastRun    time.Time
+	LastPurged int
+	LastError  error
+}
+
+// Job periodically purges data older than its retention window. A disabled
+// job does nothing when started.
+type Job struct {
+	logger        *zap.Logger
+	enabled       bool
+	checkInterval time.Duration
+	windows       map[string]time.Duration
+	purgers       []Purger
+	metrics       *metrics.Metrics
+
+	mu    sync.Mutex
+	stats map[string]*Stats
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJob creates the purge job for the configured retention windows. It
+// records every purge in m, which may be nil.
+func NewJob(logger *zap.Logger, cfg *config.Config, m *metrics.Metrics, purgers []Purger) *Job {
+	j := &Job{
+		logger:        logger.Named("retention"),
+		enabled:       cfg.Retention.Enabled,
+		checkInterval: defaultCheckInterval,
+		windows:       make(map[string]time.Duration),
+		purgers:       purgers,
+		metrics:       m,
+		stats:         make(map[string]*Stats),
+	}
+	if cfg.Retention.CheckIntervalMinutes > 0 {
+		j.checkInterval = time.Duration(cfg.Retention.CheckIntervalMinutes) * time.Minute
+	}
+
+	handled := make(map[string]bool, len(purgers))
+	for _, p := range purgers {
+		handled[p.DataType()] = true
+	}
+	for dataType, days := range cfg.Retention.Days {
+		if days <= 0 {
+			continue
+		}
+		j.windows[dataType] = time.Duration(days) * 24 * time.Hour
+		if j.enabled && !handled[dataType] {
+			j.logger.Info("Retention window configured for a data type the bot does not store",
+				zap.String("dataType", dataType))
+		}
+	}
+
+	return j
+}
+
+// Start runs a purge immediately and then on every check interval.
+func (j *Job) Start() {
+	if !j.Enabled() {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		j.Run(ctx)
+
+		ticker := time.NewTicker(j.checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends the purge schedule and waits for a running purge to finish.
+func (j *Job) Stop() {
+	if j == nil || j.cancel == nil {
+		return
+	}
+	j.cancel()
+	j.wg.Wait()
+}
+
+// Run purges every data type that has a retention window once.
+func (j *Job) Run(ctx context.Context) {
+	now := time.Now()
+	for _, p := range j.purgers {
+		dataType := p.DataType()
+		window, ok := j.windows[dataType]
+		if !ok {
+			continue
+		}
+
+		purged, err := p.PurgeBefore(ctx, now.Add(-window))
+		j.record(dataType, now, purged, err)
+		j.metrics.ObservePurge(dataType, purged, err)
+
+		if err != nil {
+			j.logger.Warn("Failed to purge expired data",
+				zap.String("dataType", dataType),
+				zap.Int("purged", purged),
+				zap.Error(err))
+
+			continue
+		}
+		if purged > 0 {
+			j.logger.Info("Purged expired data",
+				zap.String("dataType", dataType),
+				zap.Int("purged", purged),
+				zap.Duration("retention", window))
+		}
+	}
+}
+
+func (j *Job) record(dataType string, at time.Time, purged int, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	st, ok := j.stats[dataType]
+	if !ok {
+		st = &Stats{DataType: dataType}
+		j.stats[dataType] = st
+	}
+	st.Runs++
+	st.Purged += purged
+	st.LastRun = at
+	st.LastPurged = purged
+	st.LastError = err
+}
+
+// Enabled reports whether the job purges anything.
+func (j *Job) Enabled() bool {
+	return j != nil && j.enabled && len(j.windows) > 0
+}
+
+// Stats returns the purge metrics of every data type that has been purged, sorted by data type.
+func (j *Job) Stats() []Stats {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stats := make([]Stats, 0, len(j.stats))
+	for _, st := range j.stats {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(a, b int) bool { return stats[a].DataType < stats[b].DataType })
+
+	return stats
+}
@@ -0,0 +1,83 @@
+package retention_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/retention"
+)
+
+type stubPurger struct {
+	dataType string
+	purged   int
+	err      error
+	cutoffs  []time.Time
+}
+
+func (p *stubPurger) DataType() string { return p.dataType }
+
+func (p *stubPurger) PurgeBefore(_ context.Context, cutoff time.Time) (int, error) {
+	p.cutoffs = append(p.cutoffs, cutoff)
+
+	return p.purged, p.err
+}
+
+func TestJobRun_UsesRetentionWindows(t *testing.T) {
+	conversations := &stubPurger{dataType: retention.Conversations, purged: 2}
+	usage := &stubPurger{dataType: retention.Usage, err: errors.New("boom")}
+	transcripts := &stubPurger{dataType: retention.Transcripts}
+
+	cfg := &config.Config{Retention: config.RetentionConfig{
+		Enabled: true,
+		Days: map[string]int{
+			retention.Conversations: 30,
+			retention.Usage:         1,
+			retention.Transcripts:   0, // kept forever
+		},
+	}}
+	m := metrics.NewMetrics(&config.Config{Metrics: config.MetricsConfig{Enabled: true}})
+	job := retention.NewJob(zap.NewNop(), cfg, m, []retention.Purger{conversations, usage, transcripts})
+	assert.True(t, job.Enabled())
+
+	before := time.Now()
+	job.Run(context.Background())
+	job.Run(context.Background())
+
+	require.Len(t, conversations.cutoffs, 2)
+	assert.WithinDuration(t, before.Add(-30*24*time.Hour), conversations.cutoffs[0], time.Second)
+	require.Len(t, usage.cutoffs, 2)
+	assert.WithinDuration(t, before.Add(-24*time.Hour), usage.cutoffs[0], time.Second)
+	assert.Empty(t, transcripts.cutoffs)
+
+	stats := job.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, retention.Conversations, stats[0].DataType)
+	assert.Equal(t, 2, stats[0].Runs)
+	assert.Equal(t, 4, stats[0].Purged)
+	assert.Equal(t, 2, stats[0].LastPurged)
+	require.NoError(t, stats[0].LastError)
+	assert.Equal(t, retention.Usage, stats[1].DataType)
+	require.Error(t, stats[1].LastError)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `discord_chatgpt_retention_purged_records_total{data_type="conversations"} 4`)
+	assert.Contains(t, rec.Body.String(), `discord_chatgpt_retention_purge_runs_total{data_type="usage",outcome="error"} 2`)
+}
+
+func TestJob_NilSafe(t *testing.T) {
+	var job *retention.Job
+	job.Start()
+	job.Stop()
+	assert.False(t, job.Enabled())
+	assert.Empty(t, job.Stats())
+}
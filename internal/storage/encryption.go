@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// sealedFormatVersion is bumped when sealedEnvelope changes incompatibly.
+const sealedFormatVersion = 1
+
+// Sealer encrypts data at rest with a key belonging to one guild. Data sealed
+// for one guild cannot be opened with another guild's ID.
+type Sealer interface {
+	Seal(ctx context.Context, guildID string, plaintext []byte) ([]byte, error)
+	Open(ctx context.Context, guildID string, sealed []byte) ([]byte, error)
+}
+
+// KeyProvider supplies the AES-256 keys used by a Sealer.
+type KeyProvider interface {
+	// DataKey returns a key for guildID and its wrapped form, which is stored
+	// alongside the ciphertext and passed back to UnwrapKey. Providers that
+	// derive keys deterministically may return a nil wrapped key.
+	DataKey(ctx context.Context, guildID string) (key, wrapped []byte, err error)
+	// UnwrapKey recovers the key returned by DataKey for guildID.
+	UnwrapKey(ctx context.Context, guildID string, wrapped []byte) ([]byte, error)
+}
+
+// sealedEnvelope is the stored form of sealed data.
+type sealedEnvelope struct {
+	Version    int    `json:"v"`
+	WrappedKey []byte `json:"key,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"data"`
+}
+
+// NewSealer creates the Sealer selected in config. It returns a nil Sealer when
+// encryption is disabled. Besides the archive, it seals the replies kept in
+// the outbox and dead letter queue, so it does not depend on archiving.
+func NewSealer(cfg *config.Config) (Sealer, error) {
+	if !cfg.Archive.Encryption.Enabled {
+		return nil, nil
+	}
+
+	enc := cfg.Archive.Encryption
+	var keys KeyProvider
+	var err error
+	switch enc.Provider {
+	case "", "local":
+		keys, err = NewLocalKeyProvider(enc.KeyFile)
+	case "kms":
+		keys, err = NewKMSKeyProvider(enc.KMS)
+	default:
+		return nil, fmt.Errorf("unknown archive encryption provider %q, use local or kms", enc.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEnvelopeSealer(keys), nil
+}
+
+// NewEnvelopeSealer creates a Sealer that encrypts with AES-256-GCM using keys
+// from keys. The guild ID is bound to the ciphertext as additional data.
+func NewEnvelopeSealer(keys KeyProvider) Sealer {
+	return &envelopeSealer{keys: keys}
+}
+
+type envelopeSealer struct {
+	keys KeyProvider
+}
+
+// Seal encrypts plaintext for guildID.
+func (s *envelopeSealer) Seal(ctx context.Context, guildID string, plaintext []byte) ([]byte, error) {
+	key, wrapped, err := s.keys.DataKey(ctx, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key for guild %s: %w", guildScope(guildID), err)
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return json.Marshal(sealedEnvelope{
+		Version:    sealedFormatVersion,
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(guildScope(guildID))),
+	})
+}
+
+// Open decrypts data sealed for guildID.
+func (s *envelopeSealer) Open(ctx context.Context, guildID string, sealed []byte) ([]byte, error) {
+	var env sealedEnvelope
+	if err := json.Unmarshal(sealed, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode sealed data: %w", err)
+	}
+	if env.Version != sealedFormatVersion {
+		return nil, fmt.Errorf("unsupported sealed data version %d", env.Version)
+	}
+
+	key, err := s.keys.UnwrapKey(ctx, guildID, env.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key for guild %s: %w", guildScope(guildID), err)
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, errors.New("sealed data has an invalid nonce")
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, []byte(guildScope(guildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sealed data: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// guildScope names the key scope of guildID. Data outside a guild, such as
+// DMs, shares a single scope.
+func guildScope(guildID string) string {
+	if guildID == "" || guildID == "0" {
+		return "direct"
+	}
+
+	return guildID
+}
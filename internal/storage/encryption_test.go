@@ -0,0 +1,155 @@
+package storage_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func writeKeyFile(t *testing.T, key []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.key")
+	require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+
+	return path
+}
+
+func testSealer(t *testing.T, sealer storage.Sealer) {
+	t.Helper()
+	ctx := context.Background()
+	plaintext := []byte(`[{"role":"user","content":"secret plans"}]`)
+
+	sealed, err := sealer.Seal(ctx, "111", plaintext)
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "secret plans")
+
+	opened, err := sealer.Open(ctx, "111", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	_, err = sealer.Open(ctx, "222", sealed)
+	require.Error(t, err, "data sealed for one guild must not open for another")
+
+	other, err := sealer.Seal(ctx, "", plaintext)
+	require.NoError(t, err)
+	opened, err = sealer.Open(ctx, "0", other)
+	require.NoError(t, err, "guildless data shares one scope")
+	assert.Equal(t, plaintext, opened)
+}
+
+func TestLocalKeySealer(t *testing.T) {
+	keys, err := storage.NewLocalKeyProvider(writeKeyFile(t, make([]byte, 32)))
+	require.NoError(t, err)
+	testSealer(t, storage.NewEnvelopeSealer(keys))
+
+	_, err = storage.NewLocalKeyProvider(writeKeyFile(t, make([]byte, 16)))
+	require.Error(t, err, "master key must be 32 bytes")
+
+	_, err = storage.NewLocalKeyProvider("")
+	require.Error(t, err)
+}
+
+// fakeKMS implements GenerateDataKey and Decrypt. The "wrapped" key is the
+// plaintext key prefixed with the encryption context, which is enough to
+// check that the provider passes the guild through.
+type fakeKMS struct {
+	calls map[string]int
+}
+
+func (f *fakeKMS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "TrentService.")
+	f.calls[action]++
+	if !strings.Contains(r.Header.Get("Authorization"), "/kms/aws4_request") {
+		w.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	var in struct {
+		KeyID             string            `json:"KeyId"`
+		CiphertextBlob    []byte            `json:"CiphertextBlob"`
+		EncryptionContext map[string]string `json:"EncryptionContext"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+	prefix := in.EncryptionContext["guild_id"] + ":"
+
+	switch action {
+	case "GenerateDataKey":
+		key := make([]byte, 32)
+		copy(key, in.KeyID)
+		_ = json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": key, "CiphertextBlob": append([]byte(prefix), key...)})
+	case "Decrypt":
+		if !strings.HasPrefix(string(in.CiphertextBlob), prefix) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"InvalidCiphertextException"}`))
+
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": in.CiphertextBlob[len(prefix):]})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestKMSKeySealer(t *testing.T) {
+	fake := &fakeKMS{calls: make(map[string]int)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	keys, err := storage.NewKMSKeyProvider(config.KMSConfig{
+		KeyID:           "default-key",
+		GuildKeyIDs:     map[string]string{"222": "guild-key"},
+		Endpoint:        srv.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+	})
+	require.NoError(t, err)
+	testSealer(t, storage.NewEnvelopeSealer(keys))
+
+	// Data keys and unwrapped keys are cached
+	assert.Equal(t, 2, fake.calls["GenerateDataKey"])
+	assert.Equal(t, 1, fake.calls["Decrypt"], "only the cross-guild open reaches KMS")
+
+	key, _, err := keys.DataKey(context.Background(), "222")
+	require.NoError(t, err)
+	assert.Equal(t, "guild-key", strings.TrimRight(string(key), "\x00"))
+}
+
+func TestNewSealer(t *testing.T) {
+	sealer, err := storage.NewSealer(&config.Config{})
+	require.NoError(t, err)
+	assert.Nil(t, sealer)
+
+	cfg := &config.Config{Archive: config.ArchiveConfig{
+		Enabled:    true,
+		Encryption: config.EncryptionConfig{Enabled: true, KeyFile: writeKeyFile(t, make([]byte, 32))},
+	}}
+	sealer, err = storage.NewSealer(cfg)
+	require.NoError(t, err)
+	assert.NotNil(t, sealer)
+
+	cfg.Archive.Enabled = false
+	sealer, err = storage.NewSealer(cfg)
+	require.NoError(t, err)
+	assert.NotNil(t, sealer, "the outbox and dead letters are sealed without the archive")
+
+	cfg.Archive.Encryption.Provider = "vault"
+	_, err = storage.NewSealer(cfg)
+	require.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	// kmsDataKeyLifetime bounds how long a guild reuses one data key before
+	// asking KMS for a new one.
+	kmsDataKeyLifetime = 24 * time.Hour
+	// kmsUnwrapCacheSize bounds the number of unwrapped data keys kept in memory.
+	kmsUnwrapCacheSize = 256
+)
+
+// kmsKeyProvider generates per-guild data keys with AWS KMS. Each data key is
+// wrapped by KMS with the guild ID as encryption context, so a wrapped key can
+// only be unwrapped for the guild it was generated for.
+type kmsKeyProvider struct {
+	client   *http.Client
+	cfg      config.KMSConfig
+	endpoint string
+	now      func() time.Time
+
+	mu        sync.Mutex
+	current   map[string]kmsDataKey
+	unwrapped *lru.Cache[string, []byte]
+}
+
+type kmsDataKey struct {
+	key, wrapped []byte
+	expires      time.Time
+}
+
+// NewKMSKeyProvider creates a KeyProvider backed by AWS KMS. Set Endpoint for
+// KMS-compatible services.
+func NewKMSKeyProvider(cfg config.KMSConfig) (KeyProvider, error) {
+	if cfg.KeyID == "" && len(cfg.GuildKeyIDs) == 0 {
+		return nil, errors.New("archive encryption kms key_id is not set")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("archive encryption kms credentials are not set")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://kms." + cfg.Region + ".amazonaws.com"
+	}
+
+	unwrapped, err := lru.New[string, []byte](kmsUnwrapCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmsKeyProvider{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		cfg:       cfg,
+		endpoint:  strings.TrimSuffix(endpoint, "/") + "/",
+		now:       time.Now,
+		current:   make(map[string]kmsDataKey),
+		unwrapped: unwrapped,
+	}, nil
+}
+
+// DataKey returns the current data key of guildID, generating one with KMS
+// when there is none or it has expired.
+func (p *kmsKeyProvider) DataKey(ctx context.Context, guildID string) ([]byte, []byte, error) {
+	scope := guildScope(guildID)
+
+	p.mu.Lock()
+	dk, ok := p.current[scope]
+	p.mu.Unlock()
+	if ok && p.now().Before(dk.expires) {
+		return dk.key, dk.wrapped, nil
+	}
+
+	keyID := p.cfg.KeyID
+	if override, ok := p.cfg.GuildKeyIDs[guildID]; ok && override != "" {
+		keyID = override
+	}
+	if keyID == "" {
+		return nil, nil, fmt.Errorf("no kms key configured for guild %s", scope)
+	}
+
+	var out struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+		Plaintext      []byte `json:"Plaintext"`
+	}
+	err := p.call(ctx, "GenerateDataKey", map[string]any{
+		"KeyId":             keyID,
+		"KeySpec":           "AES_256",
+		"EncryptionContext": kmsEncryptionContext(scope),
+	}, &out)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.mu.Lock()
+	p.current[scope] = kmsDataKey{key: out.Plaintext, wrapped: out.CiphertextBlob, expires: p.now().Add(kmsDataKeyLifetime)}
+	p.mu.Unlock()
+	p.unwrapped.Add(scope+"/"+string(out.CiphertextBlob), out.Plaintext)
+
+	return out.Plaintext, out.CiphertextBlob, nil
+}
+
+// UnwrapKey decrypts a wrapped data key of guildID with KMS.
+func (p *kmsKeyProvider) UnwrapKey(ctx context.Context, guildID string, wrapped []byte) ([]byte, error) {
+	if len(wrapped) == 0 {
+		return nil, errors.New("sealed data has no wrapped key")
+	}
+
+	scope := guildScope(guildID)
+	cacheKey := scope + "/" + string(wrapped)
+	if key, ok := p.unwrapped.Get(cacheKey); ok {
+		return key, nil
+	}
+
+	var out struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	err := p.call(ctx, "Decrypt", map[string]any{
+		"CiphertextBlob":    wrapped,
+		"EncryptionContext": kmsEncryptionContext(scope),
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	p.unwrapped.Add(cacheKey, out.Plaintext)
+
+	return out.Plaintext, nil
+}
+
+// call invokes a KMS JSON API action and decodes its response into out.
+func (p *kmsKeyProvider) call(ctx context.Context, action string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode kms %s request: %w", action, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	signV4(req, body, awsCredentials{
+		region:          p.cfg.Region,
+		service:         "kms",
+		accessKeyID:     p.cfg.AccessKeyID,
+		secretAccessKey: p.cfg.SecretAccessKey,
+	}, p.now())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kms %s failed: %w", action, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("kms %s failed with status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode kms %s response: %w", action, err)
+	}
+
+	return nil
+}
+
+func kmsEncryptionContext(scope string) map[string]string {
+	return map[string]string{"guild_id": scope}
+}
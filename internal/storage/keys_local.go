@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const dataKeySize = 32
+
+// localKeyProvider derives a key per guild from a master key with HKDF-SHA256,
+// so only the master key has to be kept safe.
+type localKeyProvider struct {
+	master []byte
+}
+
+// NewLocalKeyProvider creates a KeyProvider from a file holding a
+// base64-encoded 32-byte master key.
+func NewLocalKeyProvider(keyFile string) (KeyProvider, error) {
+	if keyFile == "" {
+		return nil, errors.New("archive encryption key_file is not set")
+	}
+
+	// #nosec G304 - keyFile comes from the operator's config, not user input
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive encryption key file: %w", err)
+	}
+
+	master, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("archive encryption key file is not valid base64: %w", err)
+	}
+	if len(master) != dataKeySize {
+		return nil, fmt.Errorf("archive encryption master key must be %d bytes, got %d", dataKeySize, len(master))
+	}
+
+	return &localKeyProvider{master: master}, nil
+}
+
+// DataKey derives the key of guildID. No wrapped key is stored.
+func (p *localKeyProvider) DataKey(_ context.Context, guildID string) ([]byte, []byte, error) {
+	key, err := p.derive(guildID)
+
+	return key, nil, err
+}
+
+// UnwrapKey derives the key of guildID again.
+func (p *localKeyProvider) UnwrapKey(_ context.Context, guildID string, _ []byte) ([]byte, error) {
+	return p.derive(guildID)
+}
+
+func (p *localKeyProvider) derive(guildID string) ([]byte, error) {
+	return hkdf.Key(sha256.New, p.master, nil, "go-discord-chatgpt guild "+guildScope(guildID), dataKeySize)
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// SchemaKey is the key the schema version of the state is kept under.
+	SchemaKey = "schema_version.json"
+
+	migrationLock = "migrations"
+	// migrationLockTTL frees the lock of a replica that died while
+	// migrating, so the next one to start can finish the job.
+	migrationLockTTL = 5 * time.Minute
+	lockPollInterval = 250 * time.Millisecond
+)
+
+// Migration changes how state is persisted, such as the shape of a document.
+// Up must cope with the documents it changes being missing, as on a new
+// install, and with being run again after an interrupted attempt.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, p Provider) error
+}
+
+// Migrations are run in order on every backend, each once. Append new ones
+// with the next version; never change or remove a released migration.
+var Migrations = []Migration{}
+
+// LatestSchemaVersion is the schema version state is migrated to.
+func LatestSchemaVersion() int {
+	if len(Migrations) == 0 {
+		return 0
+	}
+
+	return Migrations[len(Migrations)-1].Version
+}
+
+// SchemaState records the migrations applied to a backend.
+type SchemaState struct {
+	Version int                `json:"version"`
+	Applied []AppliedMigration `json:"applied,omitempty"`
+}
+
+// AppliedMigration is a migration applied to a backend.
+type AppliedMigration struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// ParseSchemaState decodes the document stored under SchemaKey.
+func ParseSchemaState(data []byte) (SchemaState, error) {
+	var state SchemaState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return SchemaState{}, fmt.Errorf("failed to parse %s: %w", SchemaKey, err)
+	}
+
+	return state, nil
+}
+
+// Migrate runs the migrations newer than the schema version recorded in p,
+// in order, recording each as it completes. It holds the migration lock of
+// the backend meanwhile, so of several replicas starting together one
+// migrates and the others wait for it. State written by a newer release is
+// refused rather than risked.
+func Migrate(ctx context.Context, logger *zap.Logger, p Provider, migrations []Migration) error {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			return fmt.Errorf("migration %d %q is out of order", migrations[i].Version, migrations[i].Name)
+		}
+	}
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	unlock, err := Lock(ctx, p, migrationLock, migrationLockTTL)
+	if err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	defer unlock()
+
+	doc := NewDocument(p, SchemaKey)
+	var state SchemaState
+	if _, err := doc.Load(&state); err != nil {
+		return err
+	}
+	switch {
+	case state.Version > latest:
+		return fmt.Errorf("state schema version %d was written by a newer release, which this one (version %d) cannot read; upgrade the bot or restore a backup",
+			state.Version, latest)
+	case state.Version == latest:
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.Version <= state.Version {
+			continue
+		}
+		logger.Info("Migrating state", zap.Int("version", m.Version), zap.String("migration", m.Name))
+		if err := m.Up(ctx, p); err != nil {
+			return fmt.Errorf("migration %d %q failed: %w", m.Version, m.Name, err)
+		}
+		state.Version = m.Version
+		state.Applied = append(state.Applied, AppliedMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()})
+		if err := doc.Save(state); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+	}
+	logger.Info("State migrated", zap.Int("version", state.Version))
+
+	return nil
+}
+
+// locker is implemented by Providers whose backend can hold a lock for
+// every replica sharing it. A lock expires after ttl, in case its holder
+// dies without unlocking.
+type locker interface {
+	tryLock(ctx context.Context, name, token string, ttl time.Duration) (bool, error)
+	unlock(ctx context.Context, name, token string) error
+}
+
+// Lock waits until it holds the named lock of p's backend, or ctx ends, and
+// returns the function that releases it. Providers that cannot lock across
+// replicas are locked for this process only.
+func Lock(ctx context.Context, p Provider, name string, ttl time.Duration) (unlock func(), err error) {
+	l, ok := p.(locker)
+	if !ok {
+		l = processLocks
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(buf)
+	for {
+		held, err := l.tryLock(ctx, name, token, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if held {
+			return func() {
+				ctx, cancel := context.WithTimeout(context.Background(), documentTimeout)
+				defer cancel()
+
+				_ = l.unlock(ctx, name, token)
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("lock %q is held elsewhere: %w", name, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lockTable holds locks in memory, for the memory provider and as the
+// fallback for providers that cannot lock.
+type lockTable struct {
+	mu    sync.Mutex
+	locks map[string]heldLock
+}
+
+type heldLock struct {
+	token   string
+	expires time.Time
+}
+
+var processLocks = newLockTable()
+
+func newLockTable() *lockTable {
+	return &lockTable{locks: make(map[string]heldLock)}
+}
+
+func (t *lockTable) tryLock(_ context.Context, name, token string, ttl time.Duration) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if held, ok := t.locks[name]; ok && time.Now().Before(held.expires) {
+		return false, nil
+	}
+	t.locks[name] = heldLock{token: token, expires: time.Now().Add(ttl)}
+
+	return true, nil
+}
+
+func (t *lockTable) unlock(_ context.Context, name, token string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.locks[name].token != token {
+		return errors.New("lock is not held")
+	}
+	delete(t.locks, name)
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func loadSchema(t *testing.T, p storage.Provider) storage.SchemaState {
+	t.Helper()
+	data, err := p.Get(t.Context(), storage.SchemaKey)
+	require.NoError(t, err)
+	state, err := storage.ParseSchemaState(data)
+	require.NoError(t, err)
+
+	return state
+}
+
+func TestMigrate(t *testing.T) {
+	p := storage.NewMemoryProvider()
+	require.NoError(t, storage.Migrate(t.Context(), zap.NewNop(), p, nil))
+
+	var ran []int
+	step := func(version int, err error) storage.Migration {
+		return storage.Migration{Version: version, Name: "step", Up: func(_ context.Context, _ storage.Provider) error {
+			ran = append(ran, version)
+
+			return err
+		}}
+	}
+
+	require.NoError(t, storage.Migrate(t.Context(), zap.NewNop(), p, []storage.Migration{step(1, nil), step(2, nil)}))
+	assert.Equal(t, []int{1, 2}, ran)
+	state := loadSchema(t, p)
+	assert.Equal(t, 2, state.Version)
+	assert.Len(t, state.Applied, 2)
+
+	// A failed migration keeps the versions before it, and is retried next time
+	ran = nil
+	failing := []storage.Migration{step(1, nil), step(2, nil), step(3, nil), step(4, errors.New("disk full"))}
+	err := storage.Migrate(t.Context(), zap.NewNop(), p, failing)
+	require.ErrorContains(t, err, "disk full")
+	assert.Equal(t, []int{3, 4}, ran, "only newer migrations run")
+	assert.Equal(t, 3, loadSchema(t, p).Version)
+
+	ran = nil
+	failing[3] = step(4, nil)
+	require.NoError(t, storage.Migrate(t.Context(), zap.NewNop(), p, failing))
+	assert.Equal(t, []int{4}, ran)
+
+	// State written by a newer release is left alone
+	err = storage.Migrate(t.Context(), zap.NewNop(), p, failing[:2])
+	require.ErrorContains(t, err, "written by a newer release")
+	assert.Equal(t, 4, loadSchema(t, p).Version)
+
+	err = storage.Migrate(t.Context(), zap.NewNop(), p, []storage.Migration{step(2, nil), step(1, nil)})
+	require.ErrorContains(t, err, "out of order")
+}
+
+func testLock(t *testing.T, p storage.Provider) {
+	t.Helper()
+	ctx := t.Context()
+
+	unlock, err := storage.Lock(ctx, p, "migrations", time.Minute)
+	require.NoError(t, err)
+
+	waiting, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	_, err = storage.Lock(waiting, p, "migrations", time.Minute)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "a held lock keeps others waiting")
+
+	other, err := storage.Lock(ctx, p, "outbox", time.Minute)
+	require.NoError(t, err, "locks are independent")
+	other()
+
+	keys, err := p.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, keys, "locks are not keys")
+
+	unlock()
+	unlock, err = storage.Lock(ctx, p, "migrations", time.Minute)
+	require.NoError(t, err, "an unlocked lock can be taken")
+	unlock()
+}
+
+func TestLock(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		testLock(t, storage.NewMemoryProvider())
+	})
+	t.Run("file", func(t *testing.T) {
+		testLock(t, storage.NewFileProvider(t.TempDir()))
+	})
+	t.Run("redis", func(t *testing.T) {
+		_, addr := newFakeRedis(t, "")
+		p := storage.NewRedisProvider(config.RedisConfig{Addr: addr})
+		defer p.Close()
+
+		testLock(t, p)
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		p, err := storage.OpenProvider(config.StorageConfig{Backend: "sqlite", DSN: filepath.Join(t.TempDir(), "state.db")})
+		require.NoError(t, err)
+		defer p.Close()
+
+		testLock(t, p)
+	})
+}
+
+func TestLock_Expires(t *testing.T) {
+	p := storage.NewFileProvider(t.TempDir())
+	_, err := storage.Lock(t.Context(), p, "migrations", time.Millisecond)
+	require.NoError(t, err)
+
+	// The holder died without unlocking
+	time.Sleep(5 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	unlock, err := storage.Lock(ctx, p, "migrations", time.Minute)
+	require.NoError(t, err, "an expired lock is taken over")
+	unlock()
+}
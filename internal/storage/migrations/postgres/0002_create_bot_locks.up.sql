@@ -0,0 +1 @@
+CREATE TABLE bot_locks (lock_name TEXT PRIMARY KEY, holder TEXT NOT NULL, expires_at BIGINT NOT NULL)
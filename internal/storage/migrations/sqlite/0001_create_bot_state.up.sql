@@ -0,0 +1 @@
+CREATE TABLE IF NOT EXISTS bot_state (state_key TEXT PRIMARY KEY, state_value BLOB NOT NULL)
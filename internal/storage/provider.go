@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// documentTimeout bounds loading or saving one document, so a slow database
+// cannot hang the command that changed it.
+const documentTimeout = 10 * time.Second
+
+// Provider keeps the bot's state by key: budget usage, prompts, characters,
+// ignore lists and the like. It has the semantics of a ColdStore, with keys
+// that may be file paths, and holds connections that Close releases.
+type Provider interface {
+	ColdStore
+	Close() error
+}
+
+// NewProvider creates the state backend selected by storage.backend, with
+// its state migrated to this release, and closes it when the application
+// stops.
+func NewProvider(lc fx.Lifecycle, logger *zap.Logger, cfg *config.Config) (Provider, error) {
+	p, err := OpenProvider(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replicas wait for the one migrating for as long as its lock lasts
+	ctx, cancel := context.WithTimeout(context.Background(), migrationLockTTL)
+	defer cancel()
+
+	if err := Migrate(ctx, logger.Named("storage"), p, Migrations); err != nil {
+		p.Close()
+
+		return nil, err
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(context.Context) error {
+			return p.Close()
+		},
+	})
+
+	return p, nil
+}
+
+// OpenProvider opens the state backend cfg selects.
+func OpenProvider(cfg config.StorageConfig) (Provider, error) {
+	switch cfg.Backend {
+	case "", "file":
+		return NewFileProvider(cfg.Dir), nil
+	case "memory":
+		return NewMemoryProvider(), nil
+	case "sqlite", "postgres":
+		return NewSQLProvider(cfg)
+	case "redis":
+		return NewRedisProvider(cfg.Redis), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q, use file, memory, sqlite, postgres or redis", cfg.Backend)
+	}
+}
+
+// Document is a JSON document kept in a Provider under one key, the way
+// stores save all their entries at once.
+type Document struct {
+	provider Provider
+	key      string
+}
+
+// NewDocument returns the document stored under key in p.
+func NewDocument(p Provider, key string) Document {
+	return Document{provider: p, key: key}
+}
+
+// Key returns the key the document is stored under.
+func (d Document) Key() string {
+	return d.key
+}
+
+// Load decodes the document into v and reports whether it exists.
+func (d Document) Load(v any) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), documentTimeout)
+	defer cancel()
+
+	data, err := d.provider.Get(ctx, d.key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", d.key, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", d.key, err)
+	}
+
+	return true, nil
+}
+
+// Save encodes v as indented JSON and replaces the document with it.
+func (d Document) Save(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", d.key, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), documentTimeout)
+	defer cancel()
+
+	if err := d.provider.Put(ctx, d.key, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", d.key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockFilePrefix names the files holding locks, which are not keys.
+const lockFilePrefix = ".lock-"
+
+// fileProvider keeps each key in a file, as the JSON files of earlier
+// releases did, so switching to it changes nothing on disk.
+type fileProvider struct {
+	dir string
+}
+
+// NewFileProvider creates a Provider keeping each key in a file. Keys are
+// paths; relative ones are resolved in dir, or the working directory when
+// dir is empty.
+func NewFileProvider(dir string) Provider {
+	return &fileProvider{dir: dir}
+}
+
+func (p *fileProvider) path(key string) string {
+	path := filepath.FromSlash(key)
+	if filepath.IsAbs(path) || p.dir == "" {
+		return path
+	}
+
+	return filepath.Join(p.dir, path)
+}
+
+// Put writes data atomically by renaming a temporary file into place.
+func (p *fileProvider) Put(_ context.Context, key string, data []byte) error {
+	return writeFileAtomic(p.path(key), key, data)
+}
+
+// Get reads the file of key.
+func (p *fileProvider) Get(_ context.Context, key string) ([]byte, error) {
+	// #nosec G304 - keys come from the operator's config, not user input
+	data, err := os.ReadFile(p.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+// Delete removes the file of key. Missing files are not an error.
+func (p *fileProvider) Delete(_ context.Context, key string) error {
+	if err := os.Remove(p.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// List walks the directory for files whose relative path starts with
+// prefix.
+func (p *fileProvider) List(_ context.Context, prefix string) ([]string, error) {
+	root := p.dir
+	if root == "" {
+		root = "."
+	}
+
+	var keys []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") || strings.HasPrefix(d.Name(), lockFilePrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage directory: %w", err)
+	}
+
+	return keys, nil
+}
+
+// tryLock creates the lock file of name, holding the token and expiry, and
+// removes it first when its holder let it expire. Replicas sharing the
+// directory over a network file system need one that honors O_EXCL.
+func (p *fileProvider) tryLock(ctx context.Context, name, token string, ttl time.Duration) (bool, error) {
+	path := p.path(lockFilePrefix + name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return false, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		holder, expires, readErr := p.readLock(path)
+		if readErr == nil && holder != "" && time.Now().Before(expires) {
+			return false, nil
+		}
+		// Expired or half written by a holder that died
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = fmt.Fprintf(f, "%s\n%d\n", token, time.Now().Add(ttl).UnixMilli())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (p *fileProvider) unlock(_ context.Context, name, token string) error {
+	path := p.path(lockFilePrefix + name)
+	holder, _, err := p.readLock(path)
+	if err != nil {
+		return err
+	}
+	if holder != token {
+		return errors.New("lock is not held")
+	}
+
+	return os.Remove(path)
+}
+
+// readLock reads the holder and expiry of a lock file.
+func (p *fileProvider) readLock(path string) (holder string, expires time.Time, err error) {
+	// #nosec G304 - the path is built from the configured directory
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	holder, rest, _ := strings.Cut(string(data), "\n")
+	millis, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("malformed lock file %s", path)
+	}
+
+	return holder, time.UnixMilli(millis), nil
+}
+
+func (p *fileProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryProvider keeps keys in memory only, for tests and for deployments
+// that should forget everything on restart.
+type memoryProvider struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+	locks  *lockTable
+}
+
+// NewMemoryProvider creates an empty Provider that keeps keys in memory.
+func NewMemoryProvider() Provider {
+	return &memoryProvider{values: make(map[string][]byte), locks: newLockTable()}
+}
+
+func (p *memoryProvider) Put(_ context.Context, key string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.values[key] = slices.Clone(data)
+
+	return nil
+}
+
+func (p *memoryProvider) Get(_ context.Context, key string) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	data, ok := p.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return slices.Clone(data), nil
+}
+
+func (p *memoryProvider) Delete(_ context.Context, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.values, key)
+
+	return nil
+}
+
+func (p *memoryProvider) List(_ context.Context, prefix string) ([]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var keys []string
+	for key := range p.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys, nil
+}
+
+func (p *memoryProvider) tryLock(ctx context.Context, name, token string, ttl time.Duration) (bool, error) {
+	return p.locks.tryLock(ctx, name, token, ttl)
+}
+
+func (p *memoryProvider) unlock(ctx context.Context, name, token string) error {
+	return p.locks.unlock(ctx, name, token)
+}
+
+func (p *memoryProvider) Close() error {
+	return nil
+}
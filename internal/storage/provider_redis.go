@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultRedisAddr      = "localhost:6379"
+	defaultRedisKeyPrefix = "discord-chatgpt:"
+	// redisTimeout bounds a command when the caller's context has no
+	// deadline.
+	redisTimeout = 5 * time.Second
+	// redisLockPrefix follows the key prefix in the keys of locks, which
+	// List leaves out.
+	redisLockPrefix = "lock:"
+)
+
+// redisError is an error reply from the server, as opposed to a failure to
+// talk to it.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisProvider keeps each key in a Redis string. It speaks RESP over one
+// connection, dialed on first use and again after an I/O error, which is
+// plenty for state that changes when someone runs a command.
+type redisProvider struct {
+	cfg    config.RedisConfig
+	prefix string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisProvider creates a Provider keeping keys in the Redis server cfg
+// selects. It connects on first use.
+func NewRedisProvider(cfg config.RedisConfig) Provider {
+	if cfg.Addr == "" {
+		cfg.Addr = defaultRedisAddr
+	}
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = defaultRedisKeyPrefix
+	}
+
+	return &redisProvider{cfg: cfg, prefix: prefix}
+}
+
+func (p *redisProvider) Put(ctx context.Context, key string, data []byte) error {
+	_, err := p.do(ctx, "SET", p.prefix+key, string(data))
+
+	return err
+}
+
+func (p *redisProvider) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := p.do(ctx, "GET", p.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNotFound
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+
+	return []byte(value), nil
+}
+
+func (p *redisProvider) Delete(ctx context.Context, key string) error {
+	_, err := p.do(ctx, "DEL", p.prefix+key)
+
+	return err
+}
+
+// List scans the keyspace for keys under the prefix. SCAN may return a key
+// more than once, so keys are deduplicated.
+func (p *redisProvider) List(ctx context.Context, prefix string) ([]string, error) {
+	pattern := redisGlobEscaper.Replace(p.prefix+prefix) + "*"
+	seen := make(map[string]bool)
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := p.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("redis: unexpected SCAN reply %v", reply)
+		}
+		cursor, _ = parts[0].(string)
+		batch, _ := parts[1].([]any)
+		for _, k := range batch {
+			key, _ := k.(string)
+			key = strings.TrimPrefix(key, p.prefix)
+			if !seen[key] && !strings.HasPrefix(key, redisLockPrefix) {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			break
+		}
+	}
+
+	return keys, nil
+}
+
+// unlockScript deletes a lock only for its holder, so a holder whose lock
+// expired cannot release the next one's.
+const unlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// tryLock sets the lock key of name unless it exists, expiring after ttl.
+func (p *redisProvider) tryLock(ctx context.Context, name, token string, ttl time.Duration) (bool, error) {
+	reply, err := p.do(ctx, "SET", p.prefix+redisLockPrefix+name, token, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+
+	return reply != nil, nil
+}
+
+func (p *redisProvider) unlock(ctx context.Context, name, token string) error {
+	_, err := p.do(ctx, "EVAL", unlockScript, "1", p.prefix+redisLockPrefix+name, token)
+
+	return err
+}
+
+func (p *redisProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+
+	return err
+}
+
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// do sends one command and returns its reply: a string, an int64, a []any
+// or nil for a missing value.
+func (p *redisProvider) do(ctx context.Context, args ...string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	if p.conn == nil {
+		if err := p.connect(ctx, deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := p.roundTrip(deadline, args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection is in an unknown state, start over next time
+		p.conn.Close()
+		p.conn = nil
+	}
+
+	return reply, err
+}
+
+// connect dials the server, authenticates and selects the database.
+func (p *redisProvider) connect(ctx context.Context, deadline time.Time) error {
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	if p.cfg.TLS {
+		host, _, _ := net.SplitHostPort(p.cfg.Addr)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", p.cfg.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", p.cfg.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis at %s: %w", p.cfg.Addr, err)
+	}
+	p.conn = conn
+	p.r = bufio.NewReader(conn)
+
+	var setup [][]string
+	if p.cfg.Password != "" {
+		if p.cfg.Username != "" {
+			setup = append(setup, []string{"AUTH", p.cfg.Username, p.cfg.Password})
+		} else {
+			setup = append(setup, []string{"AUTH", p.cfg.Password})
+		}
+	}
+	if p.cfg.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(p.cfg.DB)})
+	}
+	for _, args := range setup {
+		if _, err := p.roundTrip(deadline, args); err != nil {
+			conn.Close()
+			p.conn = nil
+
+			return fmt.Errorf("failed to %s on redis: %w", strings.ToLower(args[0]), err)
+		}
+	}
+
+	return nil
+}
+
+func (p *redisProvider) roundTrip(deadline time.Time, args []string) (any, error) {
+	if err := p.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(p.conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	return readRESP(p.r)
+}
+
+// readRESP reads one RESP2 reply.
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
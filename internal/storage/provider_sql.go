@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// sqlMigrations holds the schema of each SQL backend as numbered
+// migrations, migrations/<backend>/<version>_<name>.up.sql, of one statement
+// each. Add a file with the next version to change the schema; never edit
+// a released one.
+//
+//go:embed migrations
+var sqlMigrations embed.FS
+
+// sqlDialect holds what differs between the SQL databases state is kept in.
+type sqlDialect struct {
+	driver     string // database/sql driver used when storage.driver is unset
+	numbered   bool   // Placeholders are $1, $2, ... instead of ?
+	linkedWith string // Import that registers the default driver
+	// migrationLock is run first in the migration transaction to keep other
+	// replicas out until it commits. SQLite locks the database on the first
+	// write instead.
+	migrationLock string
+}
+
+var sqlDialects = map[string]sqlDialect{
+	"sqlite": {driver: "sqlite", linkedWith: "modernc.org/sqlite"},
+	"postgres": {
+		driver: "pgx", numbered: true, linkedWith: "github.com/jackc/pgx/v5/stdlib",
+		migrationLock: "SELECT pg_advisory_xact_lock(7072652593)",
+	},
+}
+
+// sqlProvider keeps each key in a row of the bot_state table.
+type sqlProvider struct {
+	db      *sql.DB
+	backend string
+	dialect sqlDialect
+}
+
+// NewSQLProvider opens the sqlite or postgres database cfg selects and
+// brings its schema up to date. main links the default drivers; another
+// database/sql driver set as storage.driver must be imported there too.
+func NewSQLProvider(cfg config.StorageConfig) (Provider, error) {
+	dialect, ok := sqlDialects[cfg.Backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown SQL storage backend %q", cfg.Backend)
+	}
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("storage.dsn is required for the %s backend", cfg.Backend)
+	}
+	driver := cfg.Driver
+	if driver == "" {
+		driver = dialect.driver
+	}
+	if !slices.Contains(sql.Drivers(), driver) {
+		return nil, fmt.Errorf("database/sql driver %q is not linked into this build, import %s in main or set storage.driver", driver, dialect.linkedWith)
+	}
+
+	db, err := sql.Open(driver, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", cfg.Backend, err)
+	}
+	p := &sqlProvider{db: db, backend: cfg.Backend, dialect: dialect}
+
+	ctx, cancel := context.WithTimeout(context.Background(), documentTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("failed to connect to %s database: %w", cfg.Backend, err)
+	}
+	if err := p.migrate(ctx); err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("failed to migrate %s schema: %w", cfg.Backend, err)
+	}
+
+	return p, nil
+}
+
+// sqlMigration is one file of sqlMigrations.
+type sqlMigration struct {
+	version int
+	name    string
+	file    string
+}
+
+// sqlMigrationsOf returns the migrations of backend in version order.
+func sqlMigrationsOf(backend string) ([]sqlMigration, error) {
+	files, err := fs.Glob(sqlMigrations, "migrations/"+backend+"/*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]sqlMigration, 0, len(files))
+	for _, file := range files {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(path.Base(file), ".up.sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s is not named <version>_<name>.up.sql", file)
+		}
+		migrations = append(migrations, sqlMigration{version: version, name: name, file: file})
+	}
+	slices.SortFunc(migrations, func(a, b sqlMigration) int { return a.version - b.version })
+
+	return migrations, nil
+}
+
+// migrate applies the migrations not yet recorded in schema_migrations, in
+// one transaction, so a failed migration leaves the schema as it was and
+// replicas starting together apply each migration once.
+func (p *sqlProvider) migrate(ctx context.Context) error {
+	migrations, err := sqlMigrationsOf(p.backend)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if p.dialect.migrationLock != "" {
+		if _, err := tx.ExecContext(ctx, p.dialect.migrationLock); err != nil {
+			return fmt.Errorf("failed to take the migration lock: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, name TEXT NOT NULL)"); err != nil {
+		return err
+	}
+	// An empty write takes SQLite's database lock before anything is read
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version < 0"); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+
+			return err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		statement, err := sqlMigrations.ReadFile(m.file)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, string(statement)); err != nil {
+			return fmt.Errorf("migration %d %q failed: %w", m.version, m.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, p.query("INSERT INTO schema_migrations (version, name) VALUES (?, ?)"), m.version, m.name); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// query replaces the ? placeholders of q for dialects that number them.
+func (p *sqlProvider) query(q string) string {
+	if !p.dialect.numbered {
+		return q
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (p *sqlProvider) Put(ctx context.Context, key string, data []byte) error {
+	_, err := p.db.ExecContext(ctx, p.query(
+		"INSERT INTO bot_state (state_key, state_value) VALUES (?, ?) "+
+			"ON CONFLICT (state_key) DO UPDATE SET state_value = excluded.state_value"),
+		key, data)
+
+	return err
+}
+
+func (p *sqlProvider) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := p.db.QueryRowContext(ctx, p.query("SELECT state_value FROM bot_state WHERE state_key = ?"), key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+func (p *sqlProvider) Delete(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, p.query("DELETE FROM bot_state WHERE state_key = ?"), key)
+
+	return err
+}
+
+func (p *sqlProvider) List(ctx context.Context, prefix string) ([]string, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	rows, err := p.db.QueryContext(ctx, p.query(
+		`SELECT state_key FROM bot_state WHERE state_key LIKE ? ESCAPE '\' ORDER BY state_key`), escaped+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// tryLock takes the lock row of name unless another holder's is unexpired.
+func (p *sqlProvider) tryLock(ctx context.Context, name, token string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	result, err := p.db.ExecContext(ctx, p.query(
+		"INSERT INTO bot_locks (lock_name, holder, expires_at) VALUES (?, ?, ?) "+
+			"ON CONFLICT (lock_name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at "+
+			"WHERE bot_locks.expires_at < ?"),
+		name, token, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+
+	return n == 1, err
+}
+
+func (p *sqlProvider) unlock(ctx context.Context, name, token string) error {
+	_, err := p.db.ExecContext(ctx, p.query("DELETE FROM bot_locks WHERE lock_name = ? AND holder = ?"), name, token)
+
+	return err
+}
+
+func (p *sqlProvider) Close() error {
+	return p.db.Close()
+}
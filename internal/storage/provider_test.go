@@ -0,0 +1,261 @@
+package storage_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestMemoryProvider(t *testing.T) {
+	testColdStore(t, storage.NewMemoryProvider())
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	p := storage.NewFileProvider(dir)
+	testColdStore(t, p)
+
+	// Absolute keys are used as they are, like the configured file paths
+	abs := filepath.Join(t.TempDir(), "state", "budgets.json")
+	doc := storage.NewDocument(p, abs)
+	require.NoError(t, doc.Save(map[string]int{"a": 1}))
+	data, err := os.ReadFile(abs)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":1}`, string(data))
+}
+
+func TestDocument(t *testing.T) {
+	p := storage.NewMemoryProvider()
+	doc := storage.NewDocument(p, "prompts.json")
+
+	var got map[string]string
+	found, err := doc.Load(&got)
+	require.NoError(t, err)
+	assert.False(t, found, "a missing document is empty, not an error")
+
+	require.NoError(t, doc.Save(map[string]string{"guild": "Be brief."}))
+	found, err = doc.Load(&got)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, map[string]string{"guild": "Be brief."}, got)
+
+	require.NoError(t, p.Put(t.Context(), "prompts.json", []byte("not json")))
+	_, err = doc.Load(&got)
+	assert.ErrorContains(t, err, "failed to parse prompts.json")
+}
+
+func TestOpenProvider(t *testing.T) {
+	p, err := storage.OpenProvider(config.StorageConfig{})
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+
+	_, err = storage.OpenProvider(config.StorageConfig{Backend: "etcd"})
+	assert.ErrorContains(t, err, "unknown storage backend")
+
+	_, err = storage.OpenProvider(config.StorageConfig{Backend: "sqlite"})
+	assert.ErrorContains(t, err, "storage.dsn is required")
+
+	_, err = storage.OpenProvider(config.StorageConfig{Backend: "postgres", DSN: "postgres://localhost/bot"})
+	assert.ErrorContains(t, err, "github.com/jackc/pgx/v5/stdlib", "a driver missing from the build says how to add it")
+}
+
+func TestSQLProvider(t *testing.T) {
+	cfg := config.StorageConfig{Backend: "sqlite", DSN: filepath.Join(t.TempDir(), "state.db")}
+	p, err := storage.OpenProvider(cfg)
+	require.NoError(t, err)
+
+	testColdStore(t, p)
+
+	// Prefixes are matched literally, not as LIKE patterns
+	for _, key := range []string{"a_b/1", "a%b/2", "axb/3", `a\b/4`} {
+		require.NoError(t, p.Put(t.Context(), key, []byte("{}")))
+	}
+	keys, err := p.List(t.Context(), "a_b/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a_b/1"}, keys)
+	keys, err = p.List(t.Context(), "a%")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a%b/2"}, keys)
+	keys, err = p.List(t.Context(), `a\`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`a\b/4`}, keys)
+	require.NoError(t, p.Close())
+
+	// Reopening applies no migration twice and keeps the state
+	p, err = storage.OpenProvider(cfg)
+	require.NoError(t, err, "applied migrations are skipped")
+	defer p.Close()
+	data, err := p.Get(t.Context(), "axb/3")
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	_, err = storage.OpenProvider(config.StorageConfig{Backend: "sqlite", DSN: cfg.DSN, Driver: "mysql"})
+	assert.ErrorContains(t, err, `driver "mysql" is not linked`)
+}
+
+// fakeRedis is an in-process server speaking enough RESP for the redis
+// backend: AUTH, SELECT, GET, SET, DEL, SCAN and the EVAL that unlocks.
+type fakeRedis struct {
+	t        *testing.T
+	password string
+
+	mu     sync.Mutex
+	values map[string]string
+	auths  int
+}
+
+func newFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeRedis{t: t, password: password, values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			f.auths++
+			if args[len(args)-1] == f.password {
+				authed = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT":
+			reply = "+OK\r\n"
+		case cmd == "SET" && len(args) > 3 && strings.EqualFold(args[3], "NX"):
+			if _, ok := f.values[args[1]]; ok {
+				reply = "$-1\r\n"
+			} else {
+				f.values[args[1]] = args[2]
+				reply = "+OK\r\n"
+			}
+		case cmd == "SET":
+			f.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case cmd == "EVAL":
+			// Only the unlock script: delete KEYS[1] if it holds ARGV[1]
+			n := 0
+			if f.values[args[3]] == args[4] {
+				delete(f.values, args[3])
+				n = 1
+			}
+			reply = ":" + strconv.Itoa(n) + "\r\n"
+		case cmd == "GET":
+			if v, ok := f.values[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case cmd == "DEL":
+			_, ok := f.values[args[1]]
+			delete(f.values, args[1])
+			reply = ":" + strconv.Itoa(map[bool]int{true: 1}[ok]) + "\r\n"
+		case cmd == "SCAN":
+			var keys []string
+			for k := range f.values {
+				if ok, _ := path.Match(args[3], k); ok {
+					keys = append(keys, fmt.Sprintf("$%d\r\n%s\r\n", len(k), k))
+				}
+			}
+			reply = fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n%s", len(keys), strings.Join(keys, ""))
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+
+	return args, nil
+}
+
+func TestRedisProvider(t *testing.T) {
+	f, addr := newFakeRedis(t, "secret")
+	p := storage.NewRedisProvider(config.RedisConfig{Addr: addr, Password: "secret", DB: 2, KeyPrefix: "bot:"})
+	defer p.Close()
+
+	testColdStore(t, p)
+
+	require.NoError(t, p.Put(t.Context(), "outbox.json", []byte("[]")))
+	f.mu.Lock()
+	assert.Contains(t, f.values, "bot:outbox.json", "keys are prefixed")
+	assert.Equal(t, 1, f.auths, "one connection serves every command")
+	f.mu.Unlock()
+
+	// A dropped connection is redialed on the next command
+	require.NoError(t, p.Close())
+	data, err := p.Get(t.Context(), "outbox.json")
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(data))
+
+	bad := storage.NewRedisProvider(config.RedisConfig{Addr: addr, Password: "wrong"})
+	_, err = bad.Get(t.Context(), "outbox.json")
+	assert.ErrorContains(t, err, "WRONGPASS")
+}
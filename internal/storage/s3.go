@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// s3Store stores objects in an S3 or S3-compatible bucket. Requests are signed
+// with AWS Signature Version 4.
+type s3Store struct {
+	client *http.Client
+	cfg    config.S3Config
+	base   *url.URL
+	now    func() time.Time
+}
+
+// NewS3Store creates a ColdStore backed by an S3 bucket. Set Endpoint and
+// PathStyle for S3-compatible services such as MinIO or Cloudflare R2.
+func NewS3Store(cfg config.S3Config) (ColdStore, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("archive s3 bucket is not set")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("archive s3 credentials are not set")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive s3 endpoint: %w", err)
+	}
+	if cfg.PathStyle {
+		base.Path = "/" + cfg.Bucket
+	} else {
+		base.Host = cfg.Bucket + "." + base.Host
+	}
+
+	return &s3Store{client: &http.Client{Timeout: 30 * time.Second}, cfg: cfg, base: base, now: time.Now}, nil
+}
+
+// Put uploads data to key.
+func (s *s3Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return checkS3Response(resp, key)
+}
+
+// Get downloads the object at key.
+func (s *s3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if err := checkS3Response(resp, key); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// Delete removes the object at key. S3 treats missing objects as deleted.
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return checkS3Response(resp, key)
+}
+
+// List returns the keys of all objects whose key starts with prefix.
+func (s *s3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	fullPrefix := strings.TrimPrefix(strings.Trim(s.cfg.Prefix, "/")+"/"+prefix, "/")
+
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {fullPrefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		u := *s.base
+		if u.Path == "" {
+			u.Path = "/"
+		}
+		// SigV4 requires spaces encoded as %20, which url.Values encodes as +
+		u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+		page, err := s.listPage(ctx, &u)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(strings.TrimPrefix(obj.Key, strings.Trim(s.cfg.Prefix, "/")), "/"))
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// listBucketResult is the subset of the ListObjectsV2 response used by List.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3Store) listPage(ctx context.Context, u *url.URL) (*listBucketResult, error) {
+	resp, err := s.send(ctx, http.MethodGet, u, "list", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkS3Response(resp, "list"); err != nil {
+		return nil, err
+	}
+
+	var page listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode s3 list response: %w", err)
+	}
+
+	return &page, nil
+}
+
+func (s *s3Store) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	u := *s.base
+	segments := strings.Split(strings.Trim(s.cfg.Prefix+"/"+key, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	u.RawPath = strings.TrimSuffix(s.base.EscapedPath(), "/") + "/" + strings.Join(segments, "/")
+	u.Path, _ = url.PathUnescape(u.RawPath)
+
+	return s.send(ctx, method, &u, key, body)
+}
+
+func (s *s3Store) send(ctx context.Context, method string, u *url.URL, key string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s failed: %w", method, key, err)
+	}
+
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (s *s3Store) sign(req *http.Request, body []byte) {
+	signV4(req, body, awsCredentials{
+		region:          s.cfg.Region,
+		service:         "s3",
+		accessKeyID:     s.cfg.AccessKeyID,
+		secretAccessKey: s.cfg.SecretAccessKey,
+	}, s.now())
+}
+
+func checkS3Response(resp *http.Response, key string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	return fmt.Errorf("s3 request for %s failed with status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
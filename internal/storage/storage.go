@@ -0,0 +1,54 @@
+// Package storage provides the backends that keep the bot's state and its
+// archived data.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/fx"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+// ErrNotFound is returned by ColdStore.Get when no object exists for a key.
+var ErrNotFound = errors.New("object not found")
+
+// ColdStore stores infrequently accessed blobs by key. Keys are slash-separated
+// paths such as "conversations/123.json".
+type ColdStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	// List returns the keys of all objects whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Module provides the configured state Provider, ColdStore and Sealer.
+var Module = fx.Module("storage",
+	fx.Provide(NewProvider, NewColdStore, NewSealer),
+)
+
+// NewColdStore creates the cold storage backend selected in config. It returns
+// a nil store when archiving is disabled.
+func NewColdStore(cfg *config.Config) (ColdStore, error) {
+	archive := cfg.Archive
+	if !archive.Enabled {
+		return nil, nil
+	}
+
+	switch archive.Backend {
+	case "", "local":
+		dir := archive.LocalDir
+		if dir == "" {
+			dir = "archive"
+		}
+
+		return NewLocalStore(dir)
+	case "s3":
+		return NewS3Store(archive.S3)
+	default:
+		return nil, fmt.Errorf("unknown archive backend %q, use local or s3", archive.Backend)
+	}
+}
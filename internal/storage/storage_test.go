@@ -0,0 +1,139 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+func testColdStore(t *testing.T, store storage.ColdStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := store.Get(ctx, "conversations/1.json")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.NoError(t, store.Put(ctx, "conversations/1.json", []byte(`{"a":1}`)))
+	require.NoError(t, store.Put(ctx, "conversations/1.json", []byte(`{"a":2}`)))
+
+	data, err := store.Get(ctx, "conversations/1.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":2}`, string(data))
+
+	require.NoError(t, store.Delete(ctx, "conversations/1.json"))
+	require.NoError(t, store.Delete(ctx, "conversations/1.json"), "deleting a missing object is not an error")
+
+	_, err = store.Get(ctx, "conversations/1.json")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.NoError(t, store.Put(ctx, "conversations/2.json", []byte("{}")))
+	require.NoError(t, store.Put(ctx, "other/3.json", []byte("{}")))
+
+	keys, err := store.List(ctx, "conversations/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"conversations/2.json"}, keys)
+
+	require.NoError(t, store.Delete(ctx, "conversations/2.json"))
+	require.NoError(t, store.Delete(ctx, "other/3.json"))
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	testColdStore(t, store)
+
+	err = store.Put(context.Background(), "../escape.json", []byte("{}"))
+	assert.ErrorContains(t, err, "invalid storage key")
+}
+
+// fakeS3 is an in-memory S3 endpoint that checks requests are signed.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = io.WriteString(w, "<ListBucketResult>")
+			for path := range f.objects {
+				key := strings.TrimPrefix(path, "/bot/")
+				if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+					_, _ = io.WriteString(w, "<Contents><Key>"+key+"</Key></Contents>")
+				}
+			}
+			_, _ = io.WriteString(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+
+			return
+		}
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		_, _ = w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := storage.NewS3Store(config.S3Config{
+		Bucket:          "bot",
+		Endpoint:        server.URL,
+		PathStyle:       true,
+		Prefix:          "archive",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+
+	testColdStore(t, store)
+
+	require.NoError(t, store.Put(context.Background(), "conversations/4.json", []byte("{}")))
+	assert.Contains(t, fake.objects, "/bot/archive/conversations/4.json")
+}
+
+func TestNewColdStore(t *testing.T) {
+	store, err := storage.NewColdStore(&config.Config{})
+	require.NoError(t, err)
+	assert.Nil(t, store, "archiving is disabled by default")
+
+	_, err = storage.NewColdStore(&config.Config{Archive: config.ArchiveConfig{Enabled: true, Backend: "tape"}})
+	assert.ErrorContains(t, err, `"tape"`)
+
+	_, err = storage.NewColdStore(&config.Config{Archive: config.ArchiveConfig{Enabled: true, Backend: "s3"}})
+	assert.ErrorContains(t, err, "bucket")
+}
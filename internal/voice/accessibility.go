@@ -0,0 +1,159 @@
+package voice
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const defaultAccessibilityFile = "voice_accessibility.json"
+
+// accessibilityStore keeps the members who asked for text alternatives of
+// everything the bot says in voice, in a JSON document.
+type accessibilityStore struct {
+	doc storage.Document
+
+	mu    sync.Mutex
+	users map[discord.UserID]bool
+}
+
+func loadAccessibilityStore(p storage.Provider, path string) (*accessibilityStore, error) {
+	if path == "" {
+		path = defaultAccessibilityFile
+	}
+	store := &accessibilityStore{doc: storage.NewDocument(p, path), users: make(map[discord.UserID]bool)}
+
+	var users []discord.UserID
+	if _, err := store.doc.Load(&users); err != nil {
+		return store, fmt.Errorf("failed to load voice accessibility preferences: %w", err)
+	}
+	for _, userID := range users {
+		store.users[userID] = true
+	}
+
+	return store, nil
+}
+
+func (st *accessibilityStore) enabled(userID discord.UserID) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.users[userID]
+}
+
+func (st *accessibilityStore) set(userID discord.UserID, enabled bool) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.users[userID] == enabled {
+		return nil
+	}
+	if enabled {
+		st.users[userID] = true
+	} else {
+		delete(st.users, userID)
+	}
+
+	users := make([]discord.UserID, 0, len(st.users))
+	for userID := range st.users {
+		users = append(users, userID)
+	}
+	slices.Sort(users)
+	if err := st.doc.Save(users); err != nil {
+		if enabled {
+			delete(st.users, userID)
+		} else {
+			st.users[userID] = true
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// forget drops the preference of userID, reporting whether there was one.
+func (st *accessibilityStore) forget(userID discord.UserID) (bool, error) {
+	if !st.enabled(userID) {
+		return false, nil
+	}
+
+	return true, st.set(userID, false)
+}
+
+// SetAccessibility turns text alternatives of the bot's speech on or off for
+// userID. While on, everything the bot says in a voice channel the member is
+// in is also posted as text mentioning them.
+func (s *Service) SetAccessibility(userID discord.UserID, enabled bool) error {
+	return s.accessibility.set(userID, enabled)
+}
+
+// Accessibility reports whether userID asked for text alternatives.
+func (s *Service) Accessibility(userID discord.UserID) bool {
+	return s.accessibility.enabled(userID)
+}
+
+// mirrorSpeech posts text, said by speaker, to channelID mentioning those of
+// listeners who asked for text alternatives.
+func (s *Service) mirrorSpeech(guildID discord.GuildID, channelID discord.ChannelID, listeners []discord.UserID, speaker, text string) {
+	var mentions []string
+	var allowed []discord.UserID
+	for _, userID := range listeners {
+		if s.accessibility.enabled(userID) {
+			mentions = append(mentions, userID.Mention())
+			allowed = append(allowed, userID)
+		}
+	}
+	text = strings.TrimSpace(text)
+	if len(allowed) == 0 || text == "" || !channelID.IsValid() {
+		return
+	}
+
+	content := fmt.Sprintf("🔊 %s **%s:** %s", strings.Join(mentions, " "), speaker, s.mentions.Sanitize(text))
+	if _, err := chat.SendLongMessage(s.discordSession, channelID, content, "", &api.AllowedMentions{Users: allowed}); err != nil {
+		s.logger.Warn("Failed to post text alternative of voice output",
+			zap.Error(err),
+			zap.String("guild_id", guildID.String()),
+			zap.String("channel_id", channelID.String()))
+	}
+}
+
+// sessionListeners returns the members in voiceSession's channel.
+func sessionListeners(voiceSession *VoiceSession) []discord.UserID {
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	listeners := make([]discord.UserID, 0, len(voiceSession.Participants))
+	for userID := range voiceSession.Participants {
+		listeners = append(listeners, userID)
+	}
+	slices.Sort(listeners)
+
+	return listeners
+}
+
+// channelListeners returns the members in channelID other than bots.
+func (s *Service) channelListeners(guildID discord.GuildID, channelID discord.ChannelID) []discord.UserID {
+	voiceStates, err := s.state.VoiceStates(guildID)
+	if err != nil {
+		return nil
+	}
+
+	var listeners []discord.UserID
+	for i := range voiceStates {
+		vs := &voiceStates[i]
+		if vs.ChannelID == channelID && !s.isBotUser(vs) {
+			listeners = append(listeners, vs.UserID)
+		}
+	}
+
+	return listeners
+}
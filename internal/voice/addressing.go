@@ -0,0 +1,72 @@
+package voice
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+// Addressed reports whether transcript speaks to the bot by one of names,
+// matched case-insensitively as whole words, e.g. "hey bot" for "bot" but not
+// "robot".
+func Addressed(transcript string, names []string) bool {
+	words := " " + strings.Join(normalizedWords(transcript), " ") + " "
+	for _, name := range names {
+		if name := strings.Join(normalizedWords(name), " "); name != "" && strings.Contains(words, " "+name+" ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizedWords splits text into lower-case words without punctuation.
+func normalizedWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// addressNames returns the names the bot answers to in a guild's sessions,
+// preferring the guild override. None means every turn is answered.
+func (s *Service) addressNames(guildKey string) []string {
+	if names := s.guilds[guildKey].Voice.AddressNames; names != nil {
+		return names
+	}
+
+	return s.cfg.AddressNames
+}
+
+// respondIfAddressed requests a response to a turn committed while waiting to
+// hear whether it was addressed to the bot. Turns that were not are left in
+// the conversation as context.
+func (s *Service) respondIfAddressed(ctx context.Context, voiceSession *VoiceSession, transcript string) {
+	voiceSession.mu.Lock()
+	if voiceSession.awaitingAddress == 0 {
+		voiceSession.mu.Unlock()
+
+		return
+	}
+	voiceSession.awaitingAddress--
+	names, paused := voiceSession.AddressNames, voiceSession.paused
+	voiceSession.mu.Unlock()
+
+	if paused {
+		s.logger.Debug("Session paused, not responding",
+			zap.String("guild_id", voiceSession.GuildID.String()))
+
+		return
+	}
+	if len(names) > 0 && !Addressed(transcript, names) {
+		s.logger.Debug("Turn not addressed to the bot, not responding",
+			zap.String("guild_id", voiceSession.GuildID.String()))
+
+		return
+	}
+
+	if err := s.generateResponse(ctx, voiceSession); err != nil {
+		s.logger.Error("Failed to request response generation", zap.Error(err))
+	}
+}
@@ -0,0 +1,20 @@
+package voice_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestAddressed(t *testing.T) {
+	names := []string{"bot", "Hey Jarvis"}
+
+	assert.True(t, voice.Addressed("Hey bot, what's the weather?", names))
+	assert.True(t, voice.Addressed("what do you think, BOT?", names))
+	assert.True(t, voice.Addressed("hey, jarvis! play something", names), "punctuation between words is ignored")
+	assert.False(t, voice.Addressed("the robot in that movie was great", names), "names match whole words only")
+	assert.False(t, voice.Addressed("jarvis is a cool name", names), "multi-word names match as a phrase")
+	assert.False(t, voice.Addressed("anything", nil))
+}
@@ -0,0 +1,148 @@
+package voice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWelcomeAnnouncement = "Welcome, {name}!"
+	defaultGoodbyeAnnouncement = "Goodbye, {name}!"
+	// announcementTimeout bounds synthesizing, joining and speaking one
+	// announcement.
+	announcementTimeout = time.Minute
+	// announcementCooldown keeps members hopping between channels from being
+	// announced on every move.
+	announcementCooldown = 30 * time.Second
+	// announcementSpeaker labels announcements mirrored as text.
+	announcementSpeaker = "Announcement"
+)
+
+// errAnnouncementBusy is returned while the guild's voice connection is taken.
+var errAnnouncementBusy = errors.New("the bot is already speaking in this guild")
+
+// memberKey identifies a member of a guild.
+type memberKey struct {
+	guildID discord.GuildID
+	userID  discord.UserID
+}
+
+// Announce joins channelID in guildID, speaks text and leaves again. It
+// refuses while a voice session or another announcement has the guild's
+// voice connection.
+func (s *Service) Announce(ctx context.Context, guildID discord.GuildID, channelID discord.ChannelID, text string) error {
+	if _, err := s.sessionManager.GetSessionByGuild(guildID); err == nil {
+		return errors.New("voice session already active in this guild")
+	}
+	if _, busy := s.announcing.LoadOrStore(guildID, struct{}{}); busy {
+		return errAnnouncementBusy
+	}
+	defer s.announcing.Delete(guildID)
+
+	ctx, cancel := context.WithTimeout(ctx, announcementTimeout)
+	defer cancel()
+
+	pcm, err := s.tts.Synthesize(ctx, guildID, text)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.voiceManager.JoinChannel(ctx, channelID); err != nil {
+		return fmt.Errorf("failed to join voice channel: %w", err)
+	}
+	defer func() {
+		if err := s.voiceManager.LeaveChannel(context.Background(), channelID); err != nil {
+			s.logger.Warn("Failed to leave voice channel after announcement",
+				zap.Error(err),
+				zap.String("channel_id", channelID.String()))
+		}
+	}()
+
+	s.splitAndPlayAudio(ctx, &VoiceSession{GuildID: guildID, ChannelID: channelID}, pcm)
+	// Voice channels have their own text chat, where the announcement is mirrored
+	s.mirrorSpeech(guildID, channelID, s.channelListeners(guildID, channelID), announcementSpeaker, text)
+	s.logger.Info("Voice announcement played",
+		zap.String("guild_id", guildID.String()),
+		zap.String("channel_id", channelID.String()))
+
+	return nil
+}
+
+// announcementsEnabled reports whether guildID turned on voice announcements.
+func (s *Service) announcementsEnabled(guildID discord.GuildID) bool {
+	enabled := s.guilds[guildID.String()].Voice.Announcements
+
+	return s.disabledReason == "" && enabled != nil && *enabled
+}
+
+// trackVoiceChannel records the voice channel a member is now in and returns
+// the one they were in before, if known.
+func (s *Service) trackVoiceChannel(vs *discord.VoiceState) discord.ChannelID {
+	key := memberKey{guildID: vs.GuildID, userID: vs.UserID}
+
+	var prev any
+	if vs.ChannelID.IsValid() {
+		prev, _ = s.voiceChannels.Swap(key, vs.ChannelID)
+	} else {
+		prev, _ = s.voiceChannels.LoadAndDelete(key)
+	}
+	channelID, _ := prev.(discord.ChannelID)
+
+	return channelID
+}
+
+// announcePresence welcomes a member to the voice channel they joined, or
+// says goodbye to those left in the channel they left, when no voice session
+// is running in the guild.
+func (s *Service) announcePresence(e *gateway.VoiceStateUpdateEvent, prev discord.ChannelID) {
+	vs := &e.VoiceState
+	if vs.ChannelID == prev || !s.announcementsEnabled(e.GuildID) || s.isBotUser(vs) {
+		return
+	}
+
+	template, channelID := s.cfg.Announcements.Welcome, vs.ChannelID
+	if template == "" {
+		template = defaultWelcomeAnnouncement
+	}
+	if !vs.ChannelID.IsValid() {
+		template, channelID = s.cfg.Announcements.Goodbye, prev
+		if template == "" {
+			template = defaultGoodbyeAnnouncement
+		}
+		if !channelID.IsValid() || !s.hasListeners(e.GuildID, channelID, vs.UserID) {
+			return
+		}
+	}
+
+	key := memberKey{guildID: e.GuildID, userID: vs.UserID}
+	if last, ok := s.lastAnnounced.Load(key); ok && time.Since(last.(time.Time)) < announcementCooldown {
+		return
+	}
+	s.lastAnnounced.Store(key, time.Now())
+
+	text := strings.ReplaceAll(template, "{name}", participantName(vs))
+	go func() {
+		if err := s.Announce(context.Background(), e.GuildID, channelID, text); err != nil {
+			s.logger.Warn("Failed to play voice announcement",
+				zap.Error(err),
+				zap.String("guild_id", e.GuildID.String()),
+				zap.String("channel_id", channelID.String()))
+		}
+	}()
+}
+
+// hasListeners reports whether members other than userID and bots are in
+// channelID.
+func (s *Service) hasListeners(guildID discord.GuildID, channelID discord.ChannelID, userID discord.UserID) bool {
+	return slices.ContainsFunc(s.channelListeners(guildID, channelID), func(listener discord.UserID) bool {
+		return listener != userID
+	})
+}
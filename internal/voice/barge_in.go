@@ -0,0 +1,86 @@
+package voice
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// bargeInFrames returns how many consecutive speech frames of one speaker
+// interrupt the assistant, 0 when members never interrupt it.
+func (s *Service) bargeInFrames() int {
+	speech := DefaultBargeIn
+	switch {
+	case s.cfg.BargeInMs < 0:
+		return 0
+	case s.cfg.BargeInMs > 0:
+		speech = time.Duration(s.cfg.BargeInMs) * time.Millisecond
+	}
+
+	return max(int(speech/DefaultFrameDuration), 1)
+}
+
+// watchForBargeIn counts the speech frames of ssrc while the assistant
+// plays, and interrupts it once the speaker has talked over it for long
+// enough. Server VAD sessions are interrupted when the server hears speech.
+func (s *Service) watchForBargeIn(voiceSession *VoiceSession, ssrc uint32, speech bool) {
+	threshold := s.bargeInFrames()
+	if threshold == 0 || voiceSession.ServerVAD {
+		return
+	}
+
+	voiceSession.PlaybackMutex.Lock()
+	playing := voiceSession.PlaybackActive
+	voiceSession.PlaybackMutex.Unlock()
+
+	voiceSession.mu.Lock()
+	if !speech || !playing {
+		delete(voiceSession.speechFrames, ssrc)
+		voiceSession.mu.Unlock()
+
+		return
+	}
+	if voiceSession.speechFrames == nil {
+		voiceSession.speechFrames = make(map[uint32]int)
+	}
+	voiceSession.speechFrames[ssrc]++
+	interrupt := voiceSession.speechFrames[ssrc] == threshold
+	voiceSession.mu.Unlock()
+
+	if interrupt {
+		s.bargeIn(voiceSession)
+	}
+}
+
+// bargeIn interrupts the assistant for a member who started speaking over
+// it: playback fades out, the audio queued is dropped, and the response
+// still streaming is cancelled with the rest of its audio discarded. The
+// member's speech stays in the mix for their turn.
+func (s *Service) bargeIn(voiceSession *VoiceSession) {
+	stopped := s.interruptPlayback(voiceSession)
+
+	voiceSession.mu.Lock()
+	responding := voiceSession.responding
+	if responding {
+		voiceSession.dropResponse = true
+	}
+	voiceSession.mu.Unlock()
+
+	// Server VAD cancels the response itself when it hears speech
+	if responding && !voiceSession.ServerVAD {
+		ctx, cancel := context.WithTimeout(context.Background(), bargeInCancelTimeout)
+		defer cancel()
+
+		if err := s.realtimeProvider.CancelResponse(ctx); err != nil {
+			s.logger.Warn("Failed to cancel interrupted response", zap.Error(err),
+				zap.String("guild_id", voiceSession.GuildID.String()))
+		}
+	}
+
+	if stopped || responding {
+		s.logger.Info("Member spoke over the assistant, interrupted its response",
+			zap.String("guild_id", voiceSession.GuildID.String()),
+			zap.Bool("response_cancelled", responding))
+	}
+}
@@ -0,0 +1,212 @@
+package voice
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+)
+
+const (
+	// captionLines is how many utterances the live captions embed shows.
+	captionLines = 6
+	// captionEditInterval spaces out edits of the embed, well within
+	// Discord's rate limit of five edits per five seconds per channel.
+	captionEditInterval = 2 * time.Second
+	// maxCaptionRunes shortens long utterances so the embed stays readable.
+	maxCaptionRunes = 300
+	// assistantSpeaker labels what the model says.
+	assistantSpeaker = "Assistant"
+	// unknownSpeaker labels utterances whose speakers were not recognized.
+	unknownSpeaker = "Someone"
+)
+
+type captionLine struct {
+	speaker string
+	text    string
+}
+
+// liveCaptions holds the latest transcribed utterances of a session, shown in
+// an embed in its text channel that is edited as they come in. A nil
+// *liveCaptions records nothing, as when captions are off.
+type liveCaptions struct {
+	mu        sync.Mutex
+	lines     []captionLine // Oldest first
+	messageID discord.MessageID
+	dirty     bool
+}
+
+// add captions text said by speaker, keeping the latest captionLines.
+func (c *liveCaptions) add(speaker, text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if c == nil || text == "" {
+		return
+	}
+	if runes := []rune(text); len(runes) > maxCaptionRunes {
+		text = string(runes[:maxCaptionRunes-1]) + "…"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lines = append(c.lines, captionLine{speaker: speaker, text: text})
+	if over := len(c.lines) - captionLines; over > 0 {
+		c.lines = slices.Delete(c.lines, 0, over)
+	}
+	c.dirty = true
+}
+
+// render returns the embed showing the current captions, and false when
+// nothing changed since it was last rendered.
+func (c *liveCaptions) render() (discord.Embed, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return discord.Embed{}, false
+	}
+	c.dirty = false
+
+	var sb strings.Builder
+	for i, line := range c.lines {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("**" + line.speaker + ":** " + line.text)
+	}
+
+	return discord.Embed{
+		Title:       "🎙️ Live captions",
+		Description: sb.String(),
+		Color:       0x5865F2,
+	}, true
+}
+
+// retry has the next update render the captions again after a failed post.
+func (c *liveCaptions) retry() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dirty = true
+}
+
+// liveCaptionsEnabled reports whether sessions in guildID show live captions.
+func (s *Service) liveCaptionsEnabled(guildID discord.GuildID) bool {
+	if enabled := s.guilds[guildID.String()].Voice.LiveCaptions; enabled != nil {
+		return *enabled
+	}
+
+	return s.cfg.LiveCaptions
+}
+
+// attributeTurn records the members heard since the previous turn as the
+// speakers of the turn being committed, for the captions and transcripts of
+// the transcript OpenAI sends back for it.
+func (s *Service) attributeTurn(voiceSession *VoiceSession) {
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	var names []string
+	var userIDs []discord.UserID
+	for userID, user := range voiceSession.ActiveUsers {
+		if !user.LastActivity.After(voiceSession.lastTurnAt) {
+			continue
+		}
+		name, ok := voiceSession.Participants[userID]
+		if !ok {
+			name = userID.Mention()
+		}
+		names = append(names, name)
+		userIDs = append(userIDs, userID)
+	}
+
+	speaker := unknownSpeaker
+	if len(names) > 0 {
+		slices.Sort(names)
+		slices.Sort(userIDs)
+		speaker = strings.Join(names, ", ")
+	}
+	voiceSession.turnSpeakers = append(voiceSession.turnSpeakers, turnSpeakers{name: speaker, userIDs: userIDs})
+	voiceSession.lastTurnAt = time.Now()
+}
+
+// turnSpeakers are the members heard in a committed turn.
+type turnSpeakers struct {
+	name    string // Their names, as captions and transcripts show them
+	userIDs []discord.UserID
+}
+
+// turnSpeaker returns the speakers of the oldest committed turn awaiting its
+// transcript.
+func turnSpeaker(voiceSession *VoiceSession) turnSpeakers {
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	if len(voiceSession.turnSpeakers) == 0 {
+		return turnSpeakers{name: unknownSpeaker}
+	}
+	speaker := voiceSession.turnSpeakers[0]
+	voiceSession.turnSpeakers = voiceSession.turnSpeakers[1:]
+
+	return speaker
+}
+
+// runCaptions posts the session's live captions embed once there is
+// something to show and edits it at captionEditInterval until ctx is done.
+func (s *Service) runCaptions(ctx context.Context, voiceSession *VoiceSession) {
+	ticker := time.NewTicker(captionEditInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.updateCaptions(voiceSession)
+		case <-ctx.Done():
+			// Show the final utterances of the session
+			s.updateCaptions(voiceSession)
+
+			return
+		}
+	}
+}
+
+// updateCaptions posts or edits the live captions embed when it changed.
+func (s *Service) updateCaptions(voiceSession *VoiceSession) {
+	captions := voiceSession.captions
+	embed, changed := captions.render()
+	if !changed {
+		return
+	}
+
+	captions.mu.Lock()
+	messageID := captions.messageID
+	captions.mu.Unlock()
+
+	if messageID.IsValid() {
+		if _, err := s.discordSession.EditEmbeds(voiceSession.TextChannelID, messageID, embed); err != nil {
+			s.logger.Warn("Failed to update live captions",
+				zap.Error(err),
+				zap.String("guild_id", voiceSession.GuildID.String()))
+			captions.retry()
+		}
+
+		return
+	}
+
+	msg, err := s.discordSession.SendEmbeds(voiceSession.TextChannelID, embed)
+	if err != nil {
+		s.logger.Warn("Failed to post live captions",
+			zap.Error(err),
+			zap.String("guild_id", voiceSession.GuildID.String()))
+		captions.retry()
+
+		return
+	}
+	captions.mu.Lock()
+	captions.messageID = msg.ID
+	captions.mu.Unlock()
+}
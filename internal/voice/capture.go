@@ -0,0 +1,184 @@
+package voice
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+// PacketRecorder writes received AudioPackets to a capture file, one JSON
+// object per line, so sessions can be replayed offline with ReplayCapture.
+// A nil *PacketRecorder records nothing, as when capturing is off.
+type PacketRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+	buf  *bufio.Writer
+	enc  *json.Encoder
+}
+
+// NewPacketRecorder creates the capture file at path, replacing any file
+// already there.
+func NewPacketRecorder(path string) (*PacketRecorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	// #nosec G304 - path comes from the operator's config, not user input
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture file: %w", err)
+	}
+	buf := bufio.NewWriter(file)
+
+	return &PacketRecorder{file: file, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+// Record appends packet to the capture.
+func (r *PacketRecorder) Record(packet *AudioPacket) error {
+	if r == nil || packet == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return errors.New("capture is closed")
+	}
+	if err := r.enc.Encode(packet); err != nil {
+		return fmt.Errorf("failed to record packet: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes the capture and closes its file. Later packets are refused.
+func (r *PacketRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.buf.Flush()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close capture file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadPacketCapture reads the packets written by a PacketRecorder, in the
+// order they were received.
+func ReadPacketCapture(r io.Reader) ([]*AudioPacket, error) {
+	var packets []*AudioPacket
+	dec := json.NewDecoder(r)
+	for {
+		var packet AudioPacket
+		err := dec.Decode(&packet)
+		if errors.Is(err, io.EOF) {
+			return packets, nil
+		}
+		if err != nil {
+			return packets, fmt.Errorf("failed to parse packet %d of capture: %w", len(packets)+1, err)
+		}
+		packets = append(packets, &packet)
+	}
+}
+
+// ReplayResult is what a captured session produced when replayed.
+type ReplayResult struct {
+	Mixed        []int16 // 48 kHz mono mix of the admitted frames
+	Admitted     int     // Frames the noise gate let into the mix
+	SpeechFrames int     // Frames the noise gate heard speech in
+	Metrics      MetricsSnapshot
+}
+
+// ReplayCapture feeds captured packets through processor, gate and mixer the
+// way a live session does, without Discord or OpenAI, and drains the mix. A
+// nil gate admits every frame. Given the same packets and fresh components,
+// replays are deterministic, which lets reported audio bugs be reproduced in
+// tests.
+func ReplayCapture(packets []*AudioPacket, processor audio.AudioProcessor, gate *audio.NoiseGate, mixer audio.AudioMixer) (ReplayResult, error) {
+	var result ReplayResult
+	metrics := NewSessionMetrics()
+	for i, packet := range packets {
+		metrics.ObservePacket(packet)
+
+		pcm, err := processor.OpusToPCM48(packet.Opus)
+		if err != nil {
+			return result, fmt.Errorf("failed to decode packet %d: %w", i+1, err)
+		}
+		admit, speech := true, false
+		if gate != nil {
+			admit, speech = gate.Admit(packet.SSRC, pcm)
+		}
+		if speech {
+			result.SpeechFrames++
+			metrics.ObserveSpeech(packet.UserID, packet.ReceivedAt)
+		}
+		if !admit {
+			continue
+		}
+		result.Admitted++
+		if err := mixer.AddFrame(packet.SSRC, packet.RTPTimestamp, pcm); err != nil {
+			return result, fmt.Errorf("failed to mix packet %d: %w", i+1, err)
+		}
+	}
+	result.Mixed = mixer.Drain()
+	result.Metrics = metrics.Snapshot()
+
+	return result, nil
+}
+
+// startCapture records the session's received packets when capture_dir is
+// set, for debugging audio with ReplayCapture.
+func (s *Service) startCapture(voiceSession *VoiceSession) {
+	if s.cfg.CaptureDir == "" {
+		return
+	}
+
+	path := filepath.Join(s.cfg.CaptureDir, fmt.Sprintf("packets_%s_%s.jsonl",
+		filepath.Base(voiceSession.GuildID.String()), time.Now().Format("20060102_150405")))
+	recorder, err := NewPacketRecorder(path)
+	if err != nil {
+		s.logger.Warn("Failed to start packet capture", zap.Error(err), zap.String("guild_id", voiceSession.GuildID.String()))
+
+		return
+	}
+	voiceSession.capture = recorder
+	s.logger.Info("Capturing voice packets",
+		zap.String("guild_id", voiceSession.GuildID.String()),
+		zap.String("file", path))
+}
+
+// capturePacket records packet in the session's capture, if one is running.
+func (s *Service) capturePacket(voiceSession *VoiceSession, packet *AudioPacket) {
+	if err := voiceSession.capture.Record(packet); err != nil {
+		s.logger.Debug("Failed to capture voice packet", zap.Error(err), zap.String("user_id", packet.UserID.String()))
+	}
+}
+
+// stopCapture closes the session's capture, if one is running.
+func (s *Service) stopCapture(voiceSession *VoiceSession) {
+	if err := voiceSession.capture.Close(); err != nil {
+		s.logger.Warn("Failed to finish packet capture", zap.Error(err), zap.String("guild_id", voiceSession.GuildID.String()))
+	}
+}
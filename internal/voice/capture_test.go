@@ -0,0 +1,82 @@
+package voice_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"layeh.com/gopus"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+func TestPacketCaptureReplay(t *testing.T) {
+	encoder, err := gopus.NewEncoder(audio.DiscordSampleRate, audio.DiscordChannels, gopus.Voip)
+	require.NoError(t, err)
+
+	tone := make([]int16, audio.DiscordFrameSize*audio.DiscordChannels)
+	for i := range audio.DiscordFrameSize {
+		sample := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/audio.DiscordSampleRate))
+		tone[2*i], tone[2*i+1] = sample, sample
+	}
+	speech, err := encoder.Encode(tone, audio.DiscordFrameSize, 4000)
+	require.NoError(t, err)
+	quiet, err := gopus.NewEncoder(audio.DiscordSampleRate, audio.DiscordChannels, gopus.Voip)
+	require.NoError(t, err)
+	silence, err := quiet.Encode(make([]int16, len(tone)), audio.DiscordFrameSize, 4000)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "capture", "packets.jsonl")
+	recorder, err := voice.NewPacketRecorder(path)
+	require.NoError(t, err)
+	start := time.Now()
+	for i := range 20 {
+		opus := speech
+		if i >= 5 {
+			opus = silence
+		}
+		require.NoError(t, recorder.Record(&voice.AudioPacket{
+			UserID:       42,
+			SSRC:         1,
+			Opus:         opus,
+			RTPTimestamp: uint32(i * audio.DiscordFrameSize),
+			Sequence:     uint16(i),
+			ReceivedAt:   start.Add(time.Duration(i) * voice.DefaultFrameDuration),
+		}))
+	}
+	require.NoError(t, recorder.Close())
+	require.Error(t, recorder.Record(&voice.AudioPacket{}), "a closed capture refuses packets")
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	packets, err := voice.ReadPacketCapture(file)
+	require.NoError(t, err)
+	require.Len(t, packets, 20)
+	assert.Equal(t, uint32(19*audio.DiscordFrameSize), packets[19].RTPTimestamp)
+	assert.True(t, start.Add(19*voice.DefaultFrameDuration).Equal(packets[19].ReceivedAt))
+
+	replay := func() voice.ReplayResult {
+		processor, err := audio.NewAudioProcessor()
+		require.NoError(t, err)
+		gate := audio.NewNoiseGate(audio.NewSilenceDetector(0.01, time.Second), 2)
+		result, err := voice.ReplayCapture(packets, processor, gate, audio.NewAudioMixer())
+		require.NoError(t, err)
+
+		return result
+	}
+	first := replay()
+	assert.Greater(t, first.Admitted, 5, "speech is admitted")
+	assert.Less(t, first.Admitted, 20, "the gate closes after the silence")
+	assert.Positive(t, first.SpeechFrames)
+	assert.Len(t, first.Mixed, first.Admitted*audio.DiscordFrameSize)
+	require.Len(t, first.Metrics.Users, 1)
+	assert.Equal(t, 20, first.Metrics.Users[0].PacketsReceived)
+
+	assert.Equal(t, first.Mixed, replay().Mixed, "replays are deterministic")
+}
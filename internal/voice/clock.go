@@ -0,0 +1,81 @@
+package voice
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// clockIdleTimeout is how long the playback clock keeps ticking after the
+// last frame was scheduled.
+const clockIdleTimeout = time.Second
+
+// PlaybackClock paces audio frames for every session from one monotonic
+// clock, so concurrent sessions share frame slots instead of each running
+// its own pacer and correcting its own drift.
+type PlaybackClock interface {
+	// Next waits for the next frame slot and returns the time it was
+	// scheduled for, or ctx's error if ctx is done first.
+	Next(ctx context.Context) (time.Time, error)
+}
+
+type playbackClock struct {
+	frame time.Duration
+
+	mu       sync.Mutex
+	running  bool
+	slot     time.Time     // Scheduled time of the coming tick
+	tick     chan struct{} // Closed at slot
+	lastUsed time.Time
+}
+
+// NewPlaybackClock creates a PlaybackClock ticking every DefaultFrameDuration.
+// It only runs while frames are being scheduled.
+func NewPlaybackClock() PlaybackClock {
+	return &playbackClock{frame: DefaultFrameDuration}
+}
+
+func (c *playbackClock) Next(ctx context.Context) (time.Time, error) {
+	c.mu.Lock()
+	c.lastUsed = time.Now()
+	if !c.running {
+		// Start on a fresh epoch; slots are the epoch plus whole frames, so
+		// lateness never accumulates
+		c.running = true
+		c.slot = c.lastUsed.Add(c.frame)
+		c.tick = make(chan struct{})
+		go c.run(c.lastUsed)
+	}
+	slot, tick := c.slot, c.tick
+	c.mu.Unlock()
+
+	select {
+	case <-tick:
+		return slot, nil
+	case <-ctx.Done():
+		return time.Time{}, ctx.Err()
+	}
+}
+
+// run ticks until the clock has been idle for clockIdleTimeout.
+func (c *playbackClock) run(epoch time.Time) {
+	timer := time.NewTimer(c.frame)
+	defer timer.Stop()
+
+	for n := int64(1); ; n++ {
+		<-timer.C
+
+		c.mu.Lock()
+		close(c.tick)
+		if time.Since(c.lastUsed) > clockIdleTimeout {
+			c.running = false
+			c.mu.Unlock()
+
+			return
+		}
+		c.tick = make(chan struct{})
+		c.slot = epoch.Add(time.Duration(n+1) * c.frame)
+		timer.Reset(time.Until(c.slot))
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,27 @@
+package voice_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestPlaybackClock(t *testing.T) {
+	clock := voice.NewPlaybackClock()
+	ctx := context.Background()
+
+	first, err := clock.Next(ctx)
+	require.NoError(t, err)
+	second, err := clock.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, voice.DefaultFrameDuration, second.Sub(first), "slots are whole frames apart")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = clock.Next(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,79 @@
+package voice
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+// Opus presets playback is encoded with at full and lowered quality.
+var (
+	fullQualityPreset = audio.OpusPreset{Bitrate: 48000}
+	lowQualityPreset  = audio.OpusPreset{Bitrate: 24000}
+)
+
+// presetFor returns the playback preset for quality level.
+func presetFor(level QualityLevel) audio.OpusPreset {
+	if level >= QualityLowBitrate {
+		return lowQualityPreset
+	}
+
+	return fullQualityPreset
+}
+
+// sessionCodecs are the Opus codecs of one session, so sessions neither wait
+// on a shared codec nor change one another's quality.
+type sessionCodecs struct {
+	decoders *audio.StreamDecoders
+	pool     *audio.EncoderPool
+
+	mu      sync.Mutex
+	encoder *audio.OpusEncoder
+	closed  bool
+}
+
+func newSessionCodecs(pool *audio.EncoderPool) *sessionCodecs {
+	return &sessionCodecs{decoders: audio.NewStreamDecoders(), pool: pool}
+}
+
+// decode decodes a packet of ssrc with that speaker's decoder.
+func (c *sessionCodecs) decode(ssrc uint32, opus []byte) ([]int16, error) {
+	return c.decoders.Decode(ssrc, opus)
+}
+
+// removeStream forgets the decoder of ssrc.
+func (c *sessionCodecs) removeStream(ssrc uint32) {
+	c.decoders.RemoveStream(ssrc)
+}
+
+// encode encodes a playback frame with the preset for level, swapping the
+// session's encoder for one from the pool when the preset changes.
+func (c *sessionCodecs) encode(pcm48 []int16, level QualityLevel) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errors.New("session codecs closed")
+	}
+	if preset := presetFor(level); c.encoder == nil || c.encoder.Preset() != preset {
+		encoder, err := c.pool.Get(preset)
+		if err != nil {
+			return nil, err
+		}
+		c.pool.Put(c.encoder)
+		c.encoder = encoder
+	}
+
+	return c.encoder.Encode(pcm48)
+}
+
+// close returns the session's encoder to the pool. Later frames are refused.
+func (c *sessionCodecs) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	c.pool.Put(c.encoder)
+	c.encoder = nil
+}
@@ -0,0 +1,85 @@
+package voice
+
+import (
+	"fmt"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	// Bounds of speaking_rate, relative to the model's normal pace.
+	MinSpeakingRate = 0.5
+	MaxSpeakingRate = 2.0
+
+	// responseTokensPerSecond approximates the output tokens, audio and
+	// transcript, of a second of speech. It is generous, so the cap only cuts
+	// off answers that run well past max_response_seconds.
+	responseTokensPerSecond = 40
+)
+
+// ResponseDelivery is how long and how fast a session's answers are spoken.
+// Long monologues are hard to follow in a voice channel, so answers can be
+// held to a length and sped up or slowed down.
+type ResponseDelivery struct {
+	MaxSeconds int     // Target length of an answer, zero for no limit
+	Rate       float64 // Pace relative to normal, 1 for normal
+}
+
+// NewResponseDelivery returns the delivery set by cfg, preferring the guild
+// overrides. Rates out of bounds are clamped to them.
+func NewResponseDelivery(cfg *config.VoiceConfig, override config.GuildVoiceConfig) ResponseDelivery {
+	d := ResponseDelivery{MaxSeconds: cfg.MaxResponseSeconds, Rate: cfg.SpeakingRate}
+	if override.MaxResponseSeconds != nil {
+		d.MaxSeconds = *override.MaxResponseSeconds
+	}
+	if override.SpeakingRate != nil {
+		d.Rate = *override.SpeakingRate
+	}
+
+	d.MaxSeconds = max(d.MaxSeconds, 0)
+	if d.Rate == 0 {
+		d.Rate = 1
+	}
+	d.Rate = min(max(d.Rate, MinSpeakingRate), MaxSpeakingRate)
+
+	return d
+}
+
+// Instructions returns what the model is told about answer length and pace,
+// empty for the defaults. Text-only sessions are not told about pace.
+func (d ResponseDelivery) Instructions(textOnly bool) string {
+	var instructions string
+	if d.MaxSeconds > 0 {
+		if textOnly {
+			instructions = fmt.Sprintf("Keep every answer short enough to read aloud in %d seconds; "+
+				"summarize rather than list everything, and offer to go on if there is more.", d.MaxSeconds)
+		} else {
+			instructions = fmt.Sprintf("Keep every answer under %d seconds of speech; "+
+				"summarize rather than list everything, and offer to go on if there is more.", d.MaxSeconds)
+		}
+	}
+	if textOnly {
+		return instructions
+	}
+
+	var pace string
+	switch {
+	case d.Rate >= 1.1:
+		pace = fmt.Sprintf("Speak quickly, at about %.1f times your normal pace, without skipping words.", d.Rate)
+	case d.Rate <= 0.9:
+		pace = fmt.Sprintf("Speak slowly and clearly, at about %.1f times your normal pace, pausing between sentences.", d.Rate)
+	default:
+		return instructions
+	}
+	if instructions == "" {
+		return pace
+	}
+
+	return instructions + " " + pace
+}
+
+// MaxOutputTokens returns the cap on each response's output tokens, zero for
+// none. It backs up Instructions for answers that ignore them.
+func (d ResponseDelivery) MaxOutputTokens() int {
+	return d.MaxSeconds * responseTokensPerSecond
+}
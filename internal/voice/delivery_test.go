@@ -0,0 +1,48 @@
+package voice_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestNewResponseDelivery(t *testing.T) {
+	cfg := &config.VoiceConfig{MaxResponseSeconds: 20, SpeakingRate: 1.2}
+
+	d := voice.NewResponseDelivery(cfg, config.GuildVoiceConfig{})
+	assert.Equal(t, voice.ResponseDelivery{MaxSeconds: 20, Rate: 1.2}, d)
+
+	unlimited, slow := 0, 0.8
+	d = voice.NewResponseDelivery(cfg, config.GuildVoiceConfig{MaxResponseSeconds: &unlimited, SpeakingRate: &slow})
+	assert.Equal(t, voice.ResponseDelivery{MaxSeconds: 0, Rate: 0.8}, d, "guild overrides win, 0 lifts the limit")
+
+	d = voice.NewResponseDelivery(&config.VoiceConfig{}, config.GuildVoiceConfig{})
+	assert.Equal(t, voice.ResponseDelivery{MaxSeconds: 0, Rate: 1}, d, "defaults to no limit at normal pace")
+
+	d = voice.NewResponseDelivery(&config.VoiceConfig{MaxResponseSeconds: -5, SpeakingRate: 5}, config.GuildVoiceConfig{})
+	assert.Equal(t, voice.ResponseDelivery{MaxSeconds: 0, Rate: voice.MaxSpeakingRate}, d, "out of range values are clamped")
+}
+
+func TestResponseDelivery_Instructions(t *testing.T) {
+	assert.Empty(t, voice.ResponseDelivery{Rate: 1}.Instructions(false))
+
+	limited := voice.ResponseDelivery{MaxSeconds: 15, Rate: 1}
+	assert.Contains(t, limited.Instructions(false), "under 15 seconds of speech")
+	assert.Contains(t, limited.Instructions(true), "read aloud in 15 seconds")
+
+	fast := voice.ResponseDelivery{MaxSeconds: 15, Rate: 1.5}
+	assert.Contains(t, fast.Instructions(false), "1.5 times your normal pace")
+	assert.NotContains(t, fast.Instructions(true), "pace", "text-only sessions have no pace")
+
+	slow := voice.ResponseDelivery{Rate: 0.7}
+	assert.Contains(t, slow.Instructions(false), "Speak slowly")
+}
+
+func TestResponseDelivery_MaxOutputTokens(t *testing.T) {
+	assert.Zero(t, voice.ResponseDelivery{Rate: 1}.MaxOutputTokens())
+	assert.Greater(t, voice.ResponseDelivery{MaxSeconds: 30, Rate: 1}.MaxOutputTokens(),
+		voice.ResponseDelivery{MaxSeconds: 10, Rate: 1}.MaxOutputTokens())
+}
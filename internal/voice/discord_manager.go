@@ -41,13 +41,33 @@ type discordManager struct {
 	activeConnections sync.Map // map[discord.ChannelID]*VoiceConnection
 }
 
-func NewDiscordVoiceManager(logger *zap.Logger, sess *session.Session) DiscordManager {
+// NewDiscordManager creates the DiscordManager backing voice sessions.
+func NewDiscordManager(logger *zap.Logger, sess *session.Session) DiscordManager {
 	return &discordManager{
 		logger:  logger,
 		session: sess,
 	}
 }
 
+// connection returns the active, usable connection for channelID.
+func (m *discordManager) connection(channelID discord.ChannelID) (*VoiceConnection, error) {
+	value, exists := m.activeConnections.Load(channelID)
+	if !exists {
+		return nil, fmt.Errorf("not connected to voice channel %s", channelID)
+	}
+
+	conn, ok := value.(*VoiceConnection)
+	if !ok {
+		return nil, fmt.Errorf("invalid connection type for channel %s", channelID)
+	}
+
+	if conn.Session == nil {
+		return nil, fmt.Errorf("voice session not available for channel %s", channelID)
+	}
+
+	return conn, nil
+}
+
 func (m *discordManager) JoinChannel(ctx context.Context, channelID discord.ChannelID) (*VoiceConnection, error) {
 	// Check if already connected using sync.Map.Load
 	if value, exists := m.activeConnections.Load(channelID); exists {
@@ -142,24 +162,14 @@ func (m *discordManager) LeaveChannel(ctx context.Context, channelID discord.Cha
 }
 
 func (m *discordManager) PlayAudio(ctx context.Context, channelID discord.ChannelID, audio []byte) error {
-	value, exists := m.activeConnections.Load(channelID)
-	if !exists {
-		return fmt.Errorf("not connected to voice channel %s", channelID)
-	}
-
-	conn, ok := value.(*VoiceConnection)
-	if !ok {
-		return fmt.Errorf("invalid connection type for channel %s", channelID)
-	}
-
-	if conn.Session == nil {
-		return fmt.Errorf("voice session not available for channel %s", channelID)
+	conn, err := m.connection(channelID)
+	if err != nil {
+		return err
 	}
 
 	// Send audio data using arikawa voice session
 	// Note: This assumes the audio is already in the correct format (Opus)
-	_, err := conn.Session.Write(audio)
-	if err != nil {
+	if _, err := conn.Session.Write(audio); err != nil {
 		return fmt.Errorf("failed to play audio: %w", err)
 	}
 
@@ -171,18 +181,9 @@ func (m *discordManager) PlayAudio(ctx context.Context, channelID discord.Channe
 }
 
 func (m *discordManager) StartReceiving(ctx context.Context, channelID discord.ChannelID) (<-chan *AudioPacket, error) {
-	value, exists := m.activeConnections.Load(channelID)
-	if !exists {
-		return nil, fmt.Errorf("not connected to voice channel %s", channelID)
-	}
-
-	conn, ok := value.(*VoiceConnection)
-	if !ok {
-		return nil, fmt.Errorf("invalid connection type for channel %s", channelID)
-	}
-
-	if conn.Session == nil {
-		return nil, fmt.Errorf("voice session not available for channel %s", channelID)
+	conn, err := m.connection(channelID)
+	if err != nil {
+		return nil, err
 	}
 
 	audioChannel := make(chan *AudioPacket, 100)
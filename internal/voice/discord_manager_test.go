@@ -0,0 +1,27 @@
+package voice_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestDiscordManagerWithoutConnection(t *testing.T) {
+	m := voice.NewDiscordManager(zap.NewNop(), nil)
+	ctx := context.Background()
+	channelID := discord.ChannelID(42)
+
+	assert.NoError(t, m.LeaveChannel(ctx, channelID), "leaving an unknown channel is a no-op")
+
+	err := m.PlayAudio(ctx, channelID, []byte{0xF8, 0xFF, 0xFE})
+	assert.ErrorContains(t, err, "not connected")
+
+	packets, err := m.StartReceiving(ctx, channelID)
+	assert.ErrorContains(t, err, "not connected")
+	assert.Nil(t, packets)
+}
@@ -0,0 +1,88 @@
+package voice
+
+import (
+	"context"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/privacy"
+)
+
+// accessibilityEraser forgets whether a member asked for text alternatives.
+type accessibilityEraser struct {
+	service *Service
+}
+
+// NewAccessibilityEraser creates the privacy.Eraser for voice accessibility
+// preferences.
+func NewAccessibilityEraser(service *Service) privacy.Eraser {
+	return &accessibilityEraser{service: service}
+}
+
+// Name implements privacy.Eraser.
+func (e *accessibilityEraser) Name() string {
+	return "voice accessibility preference"
+}
+
+// ForgetUser implements privacy.Eraser.
+func (e *accessibilityEraser) ForgetUser(_ context.Context, userID discord.UserID) (int, error) {
+	forgotten, err := e.service.accessibility.forget(userID)
+	if !forgotten {
+		return 0, err
+	}
+
+	return 1, err
+}
+
+// speakerStatsEraser forgets how much a member spoke in the running voice
+// sessions. Sessions keep no participation stats once they end.
+type speakerStatsEraser struct {
+	sessions SessionManager
+}
+
+// NewSpeakerStatsEraser creates the privacy.Eraser for voice participation
+// stats.
+func NewSpeakerStatsEraser(sessions SessionManager) privacy.Eraser {
+	return &speakerStatsEraser{sessions: sessions}
+}
+
+// Name implements privacy.Eraser.
+func (e *speakerStatsEraser) Name() string {
+	return "voice participation stats"
+}
+
+// ForgetUser implements privacy.Eraser. A member still speaking in a session
+// is tracked again from then on.
+func (e *speakerStatsEraser) ForgetUser(_ context.Context, userID discord.UserID) (int, error) {
+	removed := 0
+	for _, voiceSession := range e.sessions.GetActiveSessions() {
+		if voiceSession.Metrics != nil && voiceSession.Metrics.ForgetSpeaker(userID) {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// transcriptEraser forgets what a member said in the session transcripts
+// kept for exports. Transcripts posted to text channels stay there, like the
+// member's other messages.
+type transcriptEraser struct {
+	service *Service
+}
+
+// NewTranscriptEraser creates the privacy.Eraser for voice transcripts.
+func NewTranscriptEraser(service *Service) privacy.Eraser {
+	return &transcriptEraser{service: service}
+}
+
+// Name implements privacy.Eraser.
+func (e *transcriptEraser) Name() string {
+	return "voice transcript turns"
+}
+
+// ForgetUser implements privacy.Eraser. Turns of several members heard at
+// once are dropped whole.
+func (e *transcriptEraser) ForgetUser(_ context.Context, userID discord.UserID) (int, error) {
+	return e.service.history.ForgetSpeaker(userID), nil
+}
@@ -0,0 +1,62 @@
+package voice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// supportedLanguages maps ISO-639-1 codes accepted for transcription to the
+// English language name used in model instructions.
+var supportedLanguages = map[string]string{
+	"ar": "Arabic",
+	"cs": "Czech",
+	"da": "Danish",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fi": "Finnish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"hu": "Hungarian",
+	"id": "Indonesian",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"no": "Norwegian",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ro": "Romanian",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"th": "Thai",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"vi": "Vietnamese",
+	"zh": "Chinese",
+}
+
+// normalizeLanguage validates an ISO-639-1 language code. An empty code means
+// auto-detection and is returned unchanged.
+func normalizeLanguage(code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return "", nil
+	}
+	if _, ok := supportedLanguages[code]; !ok {
+		return "", fmt.Errorf("unsupported language %q, use an ISO-639-1 code such as en, de or ja", code)
+	}
+
+	return code, nil
+}
+
+// languageName returns the English name for a supported language code.
+func languageName(code string) string {
+	if name, ok := supportedLanguages[code]; ok {
+		return name
+	}
+
+	return code
+}
@@ -0,0 +1,161 @@
+package voice
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	// DefaultCPUBudget is the share of the host's CPU time audio processing
+	// may take before quality is lowered.
+	DefaultCPUBudget = 0.5
+	// loadWindow is how often the load is measured.
+	loadWindow = time.Second
+	// loadRecoverWindows is how many quiet windows in a row restore one
+	// quality level; quiet windows use under half the budget.
+	loadRecoverWindows = 5
+	// loadBatchSize is how many queued packets are processed together at
+	// QualityBatched.
+	loadBatchSize = 5
+)
+
+// QualityLevel is how much audio quality is traded for CPU time. Each level
+// keeps the savings of the levels before it.
+type QualityLevel int
+
+const (
+	QualityFull QualityLevel = iota
+	// QualityLowBitrate encodes playback with a lower bitrate Opus preset.
+	// The Opus bindings do not expose encoder complexity, which the bitrate
+	// stands in for.
+	QualityLowBitrate
+	// QualityNoDenoise skips the denoise stage of speakers' streams.
+	QualityNoDenoise
+	// QualityBatched processes received packets in batches, updating the
+	// session once per batch rather than once per packet.
+	QualityBatched
+)
+
+func (l QualityLevel) String() string {
+	switch l {
+	case QualityLowBitrate:
+		return "low bitrate"
+	case QualityNoDenoise:
+		return "no denoise"
+	case QualityBatched:
+		return "batched"
+	default:
+		return "full"
+	}
+}
+
+// LoadMonitor compares the time spent processing audio across all sessions
+// with the CPU time the host has, and lowers quality one level at a time when
+// processing takes more than the budget, instead of letting latency build
+// up. Quality is restored a level at a time once the load drops.
+type LoadMonitor interface {
+	// Observe records that processing finished at at took busy, and returns
+	// the quality level to use from now on and whether it just changed.
+	Observe(busy time.Duration, at time.Time) (QualityLevel, bool)
+	// Level returns the quality level to use.
+	Level() QualityLevel
+}
+
+type loadMonitor struct {
+	logger *zap.Logger
+	budget float64 // Share of total CPU time, zero never lowers quality
+	procs  int
+
+	mu          sync.Mutex
+	windowStart time.Time
+	busy        time.Duration
+	quiet       int
+	level       QualityLevel
+}
+
+// NewLoadMonitor creates a LoadMonitor with the configured cpu_budget.
+func NewLoadMonitor(logger *zap.Logger, cfg *config.Config) LoadMonitor {
+	budget := cfg.Voice.CPUBudget
+	switch {
+	case budget == 0:
+		budget = DefaultCPUBudget
+	case budget < 0:
+		budget = 0
+	}
+
+	return &loadMonitor{
+		logger: logger.Named("voice_load"),
+		budget: budget,
+		procs:  runtime.GOMAXPROCS(0),
+	}
+}
+
+func (m *loadMonitor) Observe(busy time.Duration, at time.Time) (QualityLevel, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.budget == 0 {
+		return m.level, false
+	}
+	if m.windowStart.IsZero() {
+		m.windowStart = at
+	}
+	m.busy += busy
+	elapsed := at.Sub(m.windowStart)
+	if elapsed < loadWindow {
+		return m.level, false
+	}
+
+	load := float64(m.busy) / (float64(elapsed) * float64(m.procs))
+	m.windowStart, m.busy = at, 0
+	previous := m.level
+	switch {
+	case load > m.budget:
+		m.quiet = 0
+		m.level = min(m.level+1, QualityBatched)
+	case load < m.budget/2 && m.level > QualityFull:
+		if m.quiet++; m.quiet >= loadRecoverWindows {
+			m.quiet = 0
+			m.level--
+		}
+	default:
+		m.quiet = 0
+	}
+	if m.level == previous {
+		return m.level, false
+	}
+
+	if m.level > previous {
+		m.logger.Warn("Audio processing over its CPU budget, lowering quality",
+			zap.Float64("load", load),
+			zap.Float64("budget", m.budget),
+			zap.Stringer("quality", m.level))
+	} else {
+		m.logger.Info("Audio processing load dropped, raising quality",
+			zap.Float64("load", load),
+			zap.Stringer("quality", m.level))
+	}
+
+	return m.level, true
+}
+
+func (m *loadMonitor) Level() QualityLevel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.level
+}
+
+// observeLoad records processing that took busy for voiceSession. Sessions
+// pick up a new quality level with their next frame.
+func (s *Service) observeLoad(voiceSession *VoiceSession, busy time.Duration) {
+	level, _ := s.load.Observe(busy, time.Now())
+	if voiceSession.Metrics != nil {
+		voiceSession.Metrics.ObserveProcessing(busy, level)
+	}
+}
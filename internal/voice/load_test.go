@@ -0,0 +1,55 @@
+package voice_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestLoadMonitor(t *testing.T) {
+	monitor := voice.NewLoadMonitor(zap.NewNop(), &config.Config{Voice: config.VoiceConfig{CPUBudget: 0.5}})
+	// A window busy on every core is over any budget
+	overloaded := time.Duration(runtime.GOMAXPROCS(0)) * time.Second
+	at := time.Now()
+	monitor.Observe(0, at)
+
+	for _, want := range []voice.QualityLevel{voice.QualityLowBitrate, voice.QualityNoDenoise, voice.QualityBatched} {
+		at = at.Add(time.Second)
+		level, changed := monitor.Observe(overloaded, at)
+		assert.True(t, changed)
+		assert.Equal(t, want, level)
+	}
+	at = at.Add(time.Second)
+	_, changed := monitor.Observe(overloaded, at)
+	assert.False(t, changed, "quality does not go below batched")
+
+	// Quality comes back one level after five quiet windows
+	for range 4 {
+		at = at.Add(time.Second)
+		_, changed := monitor.Observe(0, at)
+		assert.False(t, changed)
+	}
+	at = at.Add(time.Second)
+	level, changed := monitor.Observe(0, at)
+	assert.True(t, changed)
+	assert.Equal(t, voice.QualityNoDenoise, level)
+	assert.Equal(t, voice.QualityNoDenoise, monitor.Level())
+}
+
+func TestLoadMonitor_Disabled(t *testing.T) {
+	monitor := voice.NewLoadMonitor(zap.NewNop(), &config.Config{Voice: config.VoiceConfig{CPUBudget: -1}})
+	overloaded := time.Duration(runtime.GOMAXPROCS(0)) * time.Second
+	at := time.Now()
+	for range 3 {
+		at = at.Add(time.Second)
+		_, changed := monitor.Observe(overloaded, at)
+		assert.False(t, changed)
+	}
+	assert.Equal(t, voice.QualityFull, monitor.Level())
+}
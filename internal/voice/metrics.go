@@ -0,0 +1,523 @@
+package voice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+// rtpClockRate is the RTP clock used by Discord voice packets (48 kHz).
+const rtpClockRate = audio.DiscordSampleRate
+
+const (
+	// speakerTurnGap is the pause after which a member's speech counts as a
+	// new turn.
+	speakerTurnGap = time.Second
+	// speakerOverlap is how recently someone else, or the assistant, must have
+	// been heard for a new turn to count as interrupting them.
+	speakerOverlap = 300 * time.Millisecond
+)
+
+// LatencyStage is one step between the end of user speech and the first
+// played audio of the reply.
+type LatencyStage int
+
+const (
+	StageTurnDetect LatencyStage = iota // Waiting for silence or a turn request
+	StageMix                            // Draining the mixer
+	StageEncode                         // Resampling, encoding and uploading the turn
+	StageFirstDelta                     // Waiting for OpenAI's first audio delta
+	StagePlayback                       // Decoding and queueing until the first frame plays
+	numLatencyStages
+)
+
+var latencyStageNames = [numLatencyStages]string{"turn detection", "mix", "encode", "API first delta", "playback start"}
+
+// String returns the stage's name as shown in reports.
+func (s LatencyStage) String() string {
+	if s < 0 || s >= numLatencyStages {
+		return fmt.Sprintf("stage %d", int(s))
+	}
+
+	return latencyStageNames[s]
+}
+
+// StageLatency is the time spent in one stage of response latency.
+type StageLatency struct {
+	Stage  LatencyStage
+	Avg    time.Duration
+	Latest time.Duration
+}
+
+// SessionMetrics collects network and processing statistics for a voice session.
+// All methods are safe for concurrent use.
+type SessionMetrics struct {
+	mu sync.Mutex
+
+	epoch   time.Time // reference point for converting arrival times to RTP units
+	streams map[uint32]*streamMetrics
+
+	mixerOps   int
+	mixerTotal time.Duration
+	mixerMax   time.Duration
+
+	playbackFrames int
+	playbackTotal  time.Duration
+	playbackMax    time.Duration
+
+	processingTotal time.Duration
+	lowestQuality   QualityLevel
+
+	lastPacketAt     time.Time
+	pendingSpeechEnd time.Time
+
+	responses     int
+	latencyTotal  time.Duration
+	latencyMax    time.Duration
+	latencyLatest time.Duration
+
+	// Ends of the stages of the pending turn, zero until reached
+	stageEnds   [numLatencyStages]time.Time
+	stageTotal  [numLatencyStages]time.Duration
+	stageLatest [numLatencyStages]time.Duration
+
+	// Participation of each member, and when the assistant was last heard
+	speakers     map[discord.UserID]*speakerMetrics
+	lastAudioOut time.Time
+}
+
+// speakerMetrics tracks how much one member spoke.
+type speakerMetrics struct {
+	speaking      time.Duration
+	turns         int
+	interruptions int
+	lastSpeech    time.Time
+}
+
+// SpeakerStats is a read-only view of how much one member spoke.
+type SpeakerStats struct {
+	UserID        discord.UserID
+	SpeakingTime  time.Duration
+	Turns         int
+	Interruptions int // Turns started over another member or the assistant
+}
+
+// streamMetrics tracks RTP statistics for a single SSRC.
+type streamMetrics struct {
+	userID discord.UserID
+
+	received  int
+	reordered int
+	baseSeq   uint16
+	maxSeq    uint16
+	cycles    int // number of 16-bit sequence wrap-arounds
+
+	// RFC 3550 interarrival jitter, expressed in RTP timestamp units.
+	jitter      float64
+	lastTransit int64
+}
+
+// UserStreamMetrics is a read-only view of the statistics for one user stream.
+type UserStreamMetrics struct {
+	UserID          discord.UserID
+	SSRC            uint32
+	PacketsReceived int
+	PacketsLost     int
+	Reordered       int
+	Jitter          time.Duration
+}
+
+// LossRate returns the fraction of expected packets that never arrived.
+func (u UserStreamMetrics) LossRate() float64 {
+	expected := u.PacketsReceived + u.PacketsLost
+	if expected <= 0 {
+		return 0
+	}
+
+	return float64(u.PacketsLost) / float64(expected)
+}
+
+// MetricsSnapshot is a point-in-time copy of SessionMetrics.
+type MetricsSnapshot struct {
+	Users []UserStreamMetrics
+
+	MixerOps int
+	MixerAvg time.Duration
+	MixerMax time.Duration
+
+	// How late frames were sent after their playback clock slot
+	PlaybackFrames   int
+	PlaybackDriftAvg time.Duration
+	PlaybackDriftMax time.Duration
+
+	// Time spent processing audio, and the lowest quality it ran at
+	ProcessingTime time.Duration
+	LowestQuality  QualityLevel
+
+	Responses     int
+	LatencyAvg    time.Duration
+	LatencyMax    time.Duration
+	LatencyLatest time.Duration
+	// Stages breaks response latency down, in pipeline order
+	Stages []StageLatency
+	// Speakers lists participation, most speaking time first
+	Speakers []SpeakerStats
+}
+
+// NewSessionMetrics creates an empty metrics collector.
+func NewSessionMetrics() *SessionMetrics {
+	return &SessionMetrics{
+		epoch:    time.Now(),
+		streams:  make(map[uint32]*streamMetrics),
+		speakers: make(map[discord.UserID]*speakerMetrics),
+	}
+}
+
+// ObservePacket records the arrival of an RTP packet for loss, reorder and jitter tracking.
+func (m *SessionMetrics) ObservePacket(packet *AudioPacket) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	arrival := packet.ReceivedAt
+	if arrival.IsZero() {
+		arrival = time.Now()
+	}
+	m.lastPacketAt = arrival
+
+	// Arrival time converted to RTP units so it can be compared with the packet timestamp.
+	transit := int64(arrival.Sub(m.epoch))*rtpClockRate/int64(time.Second) - int64(packet.RTPTimestamp)
+
+	st, ok := m.streams[packet.SSRC]
+	if !ok {
+		m.streams[packet.SSRC] = &streamMetrics{
+			userID:      packet.UserID,
+			received:    1,
+			baseSeq:     packet.Sequence,
+			maxSeq:      packet.Sequence,
+			lastTransit: transit,
+		}
+
+		return
+	}
+
+	st.received++
+	st.userID = packet.UserID
+
+	// int16 difference handles sequence wrap-around (RFC 3550 appendix A.1).
+	switch delta := int16(packet.Sequence - st.maxSeq); {
+	case delta > 0:
+		if packet.Sequence < st.maxSeq {
+			st.cycles++
+		}
+		st.maxSeq = packet.Sequence
+	case delta < 0:
+		st.reordered++
+	}
+
+	d := transit - st.lastTransit
+	if d < 0 {
+		d = -d
+	}
+	st.jitter += (float64(d) - st.jitter) / 16
+	st.lastTransit = transit
+}
+
+// ObserveMixer records how long a mixer operation took.
+func (m *SessionMetrics) ObserveMixer(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mixerOps++
+	m.mixerTotal += d
+	m.mixerMax = max(m.mixerMax, d)
+}
+
+// ObservePlaybackDrift records how late a frame was sent after its slot on
+// the playback clock.
+func (m *SessionMetrics) ObservePlaybackDrift(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d = max(d, 0)
+	m.playbackFrames++
+	m.playbackTotal += d
+	m.playbackMax = max(m.playbackMax, d)
+}
+
+// ObserveProcessing records audio processing that took d at quality level.
+func (m *SessionMetrics) ObserveProcessing(d time.Duration, level QualityLevel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.processingTotal += d
+	m.lowestQuality = max(m.lowestQuality, level)
+}
+
+// ObserveSpeech records a frame of speech from userID received at at. Speech
+// after a pause of speakerTurnGap starts a new turn, which interrupts when
+// another member or the assistant was heard within speakerOverlap.
+func (m *SessionMetrics) ObserveSpeech(userID discord.UserID, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sp, ok := m.speakers[userID]
+	if !ok {
+		sp = &speakerMetrics{}
+		m.speakers[userID] = sp
+	}
+	if sp.lastSpeech.IsZero() || at.Sub(sp.lastSpeech) > speakerTurnGap {
+		sp.turns++
+		if m.heardWithin(userID, at, speakerOverlap) {
+			sp.interruptions++
+		}
+	}
+	sp.speaking += DefaultFrameDuration
+	sp.lastSpeech = at
+}
+
+// ForgetSpeaker drops what was recorded about how much userID spoke,
+// reporting whether there was anything.
+func (m *SessionMetrics) ForgetSpeaker(userID discord.UserID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.speakers[userID]
+	delete(m.speakers, userID)
+
+	return ok
+}
+
+// heardWithin reports whether anyone but userID was heard within window
+// before at. The caller must hold m.mu.
+func (m *SessionMetrics) heardWithin(userID discord.UserID, at time.Time, window time.Duration) bool {
+	if !m.lastAudioOut.IsZero() && at.Sub(m.lastAudioOut) <= window {
+		return true
+	}
+	for otherID, other := range m.speakers {
+		if otherID != userID && !other.lastSpeech.IsZero() && at.Sub(other.lastSpeech) <= window {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MarkTurnCommitted marks the last received packet as the end of user speech
+// for the turn being sent to OpenAI.
+func (m *SessionMetrics) MarkTurnCommitted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.lastPacketAt.IsZero() {
+		m.pendingSpeechEnd = m.lastPacketAt
+		m.stageEnds = [numLatencyStages]time.Time{}
+	}
+}
+
+// MarkStage records that the pending turn finished stage at at. Stages
+// already marked, and marks without a committed turn, are ignored.
+func (m *SessionMetrics) MarkStage(stage LatencyStage, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pendingSpeechEnd.IsZero() || stage < 0 || stage >= numLatencyStages || !m.stageEnds[stage].IsZero() {
+		return
+	}
+	m.stageEnds[stage] = at
+}
+
+// MarkAudioOut records end-to-end response latency the first time audio is
+// played after a committed turn, and returns its breakdown by stage.
+// Subsequent calls until the next commit are ignored and return false.
+func (m *SessionMetrics) MarkAudioOut(at time.Time) ([]StageLatency, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastAudioOut = at
+	if m.pendingSpeechEnd.IsZero() {
+		return nil, false
+	}
+
+	latency := at.Sub(m.pendingSpeechEnd)
+	m.stageEnds[StagePlayback] = at
+
+	m.responses++
+	m.latencyTotal += latency
+	m.latencyMax = max(m.latencyMax, latency)
+	m.latencyLatest = latency
+
+	// A stage that was never marked, e.g. when a turn is sent another way,
+	// counts towards the next one
+	stages := make([]StageLatency, numLatencyStages)
+	prev := m.pendingSpeechEnd
+	for stage := range numLatencyStages {
+		var d time.Duration
+		if end := m.stageEnds[stage]; !end.IsZero() {
+			d = max(end.Sub(prev), 0)
+			prev = end
+		}
+		m.stageTotal[stage] += d
+		m.stageLatest[stage] = d
+		stages[stage] = StageLatency{Stage: stage, Avg: d, Latest: d}
+	}
+	m.pendingSpeechEnd = time.Time{}
+
+	return stages, true
+}
+
+// Snapshot returns a copy of the current metrics.
+func (m *SessionMetrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snap := MetricsSnapshot{
+		Users:            make([]UserStreamMetrics, 0, len(m.streams)),
+		MixerOps:         m.mixerOps,
+		MixerMax:         m.mixerMax,
+		PlaybackFrames:   m.playbackFrames,
+		PlaybackDriftMax: m.playbackMax,
+		ProcessingTime:   m.processingTotal,
+		LowestQuality:    m.lowestQuality,
+		Responses:        m.responses,
+		LatencyMax:       m.latencyMax,
+		LatencyLatest:    m.latencyLatest,
+	}
+	if m.mixerOps > 0 {
+		snap.MixerAvg = m.mixerTotal / time.Duration(m.mixerOps)
+	}
+	if m.playbackFrames > 0 {
+		snap.PlaybackDriftAvg = m.playbackTotal / time.Duration(m.playbackFrames)
+	}
+	if m.responses > 0 {
+		snap.LatencyAvg = m.latencyTotal / time.Duration(m.responses)
+		snap.Stages = make([]StageLatency, numLatencyStages)
+		for stage := range numLatencyStages {
+			snap.Stages[stage] = StageLatency{
+				Stage:  stage,
+				Avg:    m.stageTotal[stage] / time.Duration(m.responses),
+				Latest: m.stageLatest[stage],
+			}
+		}
+	}
+
+	for ssrc, st := range m.streams {
+		expected := st.cycles<<16 + int(st.maxSeq) - int(st.baseSeq) + 1
+		snap.Users = append(snap.Users, UserStreamMetrics{
+			UserID:          st.userID,
+			SSRC:            ssrc,
+			PacketsReceived: st.received,
+			PacketsLost:     max(expected-st.received, 0),
+			Reordered:       st.reordered,
+			Jitter:          time.Duration(st.jitter * float64(time.Second) / rtpClockRate),
+		})
+	}
+	sort.Slice(snap.Users, func(i, j int) bool { return snap.Users[i].SSRC < snap.Users[j].SSRC })
+
+	for userID, sp := range m.speakers {
+		snap.Speakers = append(snap.Speakers, SpeakerStats{
+			UserID:        userID,
+			SpeakingTime:  sp.speaking,
+			Turns:         sp.turns,
+			Interruptions: sp.interruptions,
+		})
+	}
+	sort.Slice(snap.Speakers, func(i, j int) bool {
+		if snap.Speakers[i].SpeakingTime != snap.Speakers[j].SpeakingTime {
+			return snap.Speakers[i].SpeakingTime > snap.Speakers[j].SpeakingTime
+		}
+
+		return snap.Speakers[i].UserID < snap.Speakers[j].UserID
+	})
+
+	return snap
+}
+
+// Summary formats the snapshot as a short, Discord-friendly report.
+func (s MetricsSnapshot) Summary() string {
+	var sb strings.Builder
+
+	if s.Responses > 0 {
+		fmt.Fprintf(&sb, "⚡ Response latency: avg %s, max %s (%d responses)\n",
+			s.LatencyAvg.Round(time.Millisecond), s.LatencyMax.Round(time.Millisecond), s.Responses)
+	}
+	if s.MixerOps > 0 {
+		fmt.Fprintf(&sb, "🎛️ Mixer time: avg %s, max %s\n",
+			s.MixerAvg.Round(time.Microsecond), s.MixerMax.Round(time.Microsecond))
+	}
+	if s.PlaybackFrames > 0 {
+		fmt.Fprintf(&sb, "🔊 Playback drift: avg %s, max %s (%d frames)\n",
+			s.PlaybackDriftAvg.Round(time.Microsecond), s.PlaybackDriftMax.Round(time.Microsecond), s.PlaybackFrames)
+	}
+	switch {
+	case s.LowestQuality > QualityFull:
+		fmt.Fprintf(&sb, "⚙️ Audio processing: %s, quality lowered to %s under load\n",
+			s.ProcessingTime.Round(time.Millisecond), s.LowestQuality)
+	case s.ProcessingTime > 0:
+		fmt.Fprintf(&sb, "⚙️ Audio processing: %s\n", s.ProcessingTime.Round(time.Millisecond))
+	}
+	for _, u := range s.Users {
+		fmt.Fprintf(&sb, "📶 <@%s>: %d packets, %.1f%% lost, %d reordered, jitter %s\n",
+			u.UserID, u.PacketsReceived, u.LossRate()*100, u.Reordered, u.Jitter.Round(100*time.Microsecond))
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// ParticipationSummary formats how much each member spoke, or returns an
+// empty string when nobody did.
+func (s MetricsSnapshot) ParticipationSummary() string {
+	var total time.Duration
+	for _, sp := range s.Speakers {
+		total += sp.SpeakingTime
+	}
+	if total == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🗣️ Participation:\n")
+	for _, sp := range s.Speakers {
+		fmt.Fprintf(&sb, "• <@%s>: %s speaking (%.0f%%), %d turns, %d interruptions\n",
+			sp.UserID, sp.SpeakingTime.Round(time.Second), float64(sp.SpeakingTime)/float64(total)*100, sp.Turns, sp.Interruptions)
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// LatencyReport formats the response latency breakdown by stage, or returns
+// an empty string before the first response.
+func (s MetricsSnapshot) LatencyReport() string {
+	if s.Responses == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⚡ Response latency over %d responses: avg %s, max %s, latest %s\n",
+		s.Responses, s.LatencyAvg.Round(time.Millisecond), s.LatencyMax.Round(time.Millisecond), s.LatencyLatest.Round(time.Millisecond))
+	for _, stage := range s.Stages {
+		fmt.Fprintf(&sb, "• %s: avg %s, latest %s\n",
+			stage.Stage, stage.Avg.Round(time.Millisecond), stage.Latest.Round(time.Millisecond))
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// logLatency logs the stage breakdown of a response's latency.
+func (s *Service) logLatency(voiceSession *VoiceSession, stages []StageLatency) {
+	fields := make([]zap.Field, 0, len(stages)+2)
+	fields = append(fields, zap.String("guild_id", voiceSession.GuildID.String()))
+	var total time.Duration
+	for _, stage := range stages {
+		total += stage.Latest
+		fields = append(fields, zap.Duration(strings.ReplaceAll(strings.ToLower(stage.Stage.String()), " ", "_"), stage.Latest))
+	}
+	fields = append(fields, zap.Duration("total", total))
+	s.logger.Info("Response latency", fields...)
+}
@@ -0,0 +1,169 @@
+package voice_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestSessionMetrics_PacketLossAndReorder(t *testing.T) {
+	tests := []struct {
+		name          string
+		sequences     []uint16
+		wantReceived  int
+		wantLost      int
+		wantReordered int
+	}{
+		{
+			name:         "in order",
+			sequences:    []uint16{10, 11, 12, 13},
+			wantReceived: 4,
+		},
+		{
+			name:         "gap",
+			sequences:    []uint16{10, 11, 14, 15},
+			wantReceived: 4,
+			wantLost:     2,
+		},
+		{
+			name:          "reordered",
+			sequences:     []uint16{10, 12, 11, 13},
+			wantReceived:  4,
+			wantReordered: 1,
+		},
+		{
+			name:         "sequence wrap-around",
+			sequences:    []uint16{65534, 65535, 0, 2},
+			wantReceived: 4,
+			wantLost:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := voice.NewSessionMetrics()
+			start := time.Now()
+			for i, seq := range tt.sequences {
+				metrics.ObservePacket(&voice.AudioPacket{
+					UserID:       42,
+					SSRC:         7,
+					Sequence:     seq,
+					RTPTimestamp: uint32(i * 960),
+					ReceivedAt:   start.Add(time.Duration(i) * 20 * time.Millisecond),
+				})
+			}
+
+			snap := metrics.Snapshot()
+			require.Len(t, snap.Users, 1)
+			assert.Equal(t, tt.wantReceived, snap.Users[0].PacketsReceived)
+			assert.Equal(t, tt.wantLost, snap.Users[0].PacketsLost)
+			assert.Equal(t, tt.wantReordered, snap.Users[0].Reordered)
+		})
+	}
+}
+
+func TestSessionMetrics_ResponseLatency(t *testing.T) {
+	metrics := voice.NewSessionMetrics()
+	speechEnd := time.Now()
+
+	metrics.ObservePacket(&voice.AudioPacket{SSRC: 1, ReceivedAt: speechEnd})
+	metrics.MarkTurnCommitted()
+	metrics.MarkAudioOut(speechEnd.Add(800 * time.Millisecond))
+	metrics.MarkAudioOut(speechEnd.Add(900 * time.Millisecond)) // ignored until next commit
+
+	snap := metrics.Snapshot()
+	assert.Equal(t, 1, snap.Responses)
+	assert.Equal(t, 800*time.Millisecond, snap.LatencyAvg)
+	assert.Equal(t, 800*time.Millisecond, snap.LatencyMax)
+}
+
+func TestSessionMetrics_LatencyStages(t *testing.T) {
+	metrics := voice.NewSessionMetrics()
+	speechEnd := time.Now()
+
+	metrics.ObservePacket(&voice.AudioPacket{SSRC: 1, ReceivedAt: speechEnd})
+	metrics.MarkStage(voice.StageMix, speechEnd) // ignored before a commit
+	metrics.MarkTurnCommitted()
+	metrics.MarkStage(voice.StageTurnDetect, speechEnd.Add(500*time.Millisecond))
+	metrics.MarkStage(voice.StageMix, speechEnd.Add(510*time.Millisecond))
+	// Encode is never marked, so its time counts towards the first delta
+	metrics.MarkStage(voice.StageFirstDelta, speechEnd.Add(900*time.Millisecond))
+	metrics.MarkStage(voice.StageFirstDelta, speechEnd.Add(950*time.Millisecond)) // later deltas are ignored
+
+	stages, ok := metrics.MarkAudioOut(speechEnd.Add(1000 * time.Millisecond))
+	require.True(t, ok)
+	require.Len(t, stages, 5)
+	assert.Equal(t, 500*time.Millisecond, stages[voice.StageTurnDetect].Latest)
+	assert.Equal(t, 10*time.Millisecond, stages[voice.StageMix].Latest)
+	assert.Zero(t, stages[voice.StageEncode].Latest)
+	assert.Equal(t, 390*time.Millisecond, stages[voice.StageFirstDelta].Latest)
+	assert.Equal(t, 100*time.Millisecond, stages[voice.StagePlayback].Latest)
+
+	_, ok = metrics.MarkAudioOut(speechEnd.Add(1100 * time.Millisecond))
+	assert.False(t, ok)
+
+	snap := metrics.Snapshot()
+	require.Len(t, snap.Stages, 5)
+	assert.Equal(t, 390*time.Millisecond, snap.Stages[voice.StageFirstDelta].Avg)
+	assert.Contains(t, snap.LatencyReport(), "API first delta: avg 390ms")
+}
+
+func TestSessionMetrics_Participation(t *testing.T) {
+	metrics := voice.NewSessionMetrics()
+	start := time.Now()
+	speak := func(userID discord.UserID, from time.Duration, frames int) {
+		for i := range frames {
+			metrics.ObserveSpeech(userID, start.Add(from+time.Duration(i)*20*time.Millisecond))
+		}
+	}
+
+	speak(1, 0, 50)                    // 1s from the start
+	speak(2, 900*time.Millisecond, 25) // talks over member 1
+	speak(1, 3*time.Second, 10)        // new turn after a pause
+	metrics.MarkAudioOut(start.Add(5 * time.Second))
+	speak(2, 5100*time.Millisecond, 5) // talks over the assistant
+
+	snap := metrics.Snapshot()
+	require.Len(t, snap.Speakers, 2)
+	assert.Equal(t, voice.SpeakerStats{UserID: 1, SpeakingTime: 1200 * time.Millisecond, Turns: 2}, snap.Speakers[0])
+	assert.Equal(t, voice.SpeakerStats{UserID: 2, SpeakingTime: 600 * time.Millisecond, Turns: 2, Interruptions: 2}, snap.Speakers[1])
+	assert.Contains(t, snap.ParticipationSummary(), "<@2>: 1s speaking (33%), 2 turns, 2 interruptions")
+	assert.Empty(t, voice.NewSessionMetrics().Snapshot().ParticipationSummary())
+
+	assert.True(t, metrics.ForgetSpeaker(1))
+	assert.False(t, metrics.ForgetSpeaker(1))
+	snap = metrics.Snapshot()
+	require.Len(t, snap.Speakers, 1)
+	assert.Equal(t, discord.UserID(2), snap.Speakers[0].UserID)
+}
+
+func TestSessionMetrics_PlaybackDrift(t *testing.T) {
+	metrics := voice.NewSessionMetrics()
+	metrics.ObservePlaybackDrift(time.Millisecond)
+	metrics.ObservePlaybackDrift(3 * time.Millisecond)
+	metrics.ObservePlaybackDrift(-time.Millisecond) // early frames count as on time
+
+	snap := metrics.Snapshot()
+	assert.Equal(t, 3, snap.PlaybackFrames)
+	assert.Equal(t, 4*time.Millisecond/3, snap.PlaybackDriftAvg)
+	assert.Equal(t, 3*time.Millisecond, snap.PlaybackDriftMax)
+	assert.Contains(t, snap.Summary(), "Playback drift")
+}
+
+func TestSessionMetrics_Processing(t *testing.T) {
+	metrics := voice.NewSessionMetrics()
+	metrics.ObserveProcessing(2*time.Millisecond, voice.QualityFull)
+	assert.Contains(t, metrics.Snapshot().Summary(), "Audio processing: 2ms")
+
+	metrics.ObserveProcessing(time.Millisecond, voice.QualityNoDenoise)
+	metrics.ObserveProcessing(time.Millisecond, voice.QualityLowBitrate)
+	snap := metrics.Snapshot()
+	assert.Equal(t, 4*time.Millisecond, snap.ProcessingTime)
+	assert.Equal(t, voice.QualityNoDenoise, snap.LowestQuality)
+	assert.Contains(t, snap.Summary(), "quality lowered to no denoise under load")
+}
@@ -8,11 +8,31 @@ import (
 
 var Module = fx.Module("voice",
 	fx.Provide(
-		NewDiscordVoiceManager,
+		NewDiscordManager,
 		audio.NewAudioProcessor,
-		NewRealtimeProvider,
+		NewResilientRealtimeProvider,
+		NewTTSProvider,
+		NewPlaybackClock,
+		NewLoadMonitor,
+		audio.NewEncoderPool,
 		NewSessionManager,
 		audio.NewAudioMixer,
 		NewService,
+		fx.Annotate(
+			NewAccessibilityEraser,
+			fx.ResultTags(`group:"erasers"`),
+		),
+		fx.Annotate(
+			NewSpeakerStatsEraser,
+			fx.ResultTags(`group:"erasers"`),
+		),
+		fx.Annotate(
+			NewTranscriptEraser,
+			fx.ResultTags(`group:"erasers"`),
+		),
+		fx.Annotate(
+			NewTranscriptPurger,
+			fx.ResultTags(`group:"purgers"`),
+		),
 	),
 )
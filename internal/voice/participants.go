@@ -0,0 +1,174 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"go.uber.org/zap"
+)
+
+// baseInstructions grounds the model in the Discord voice channel setting.
+// The session language and participant list are appended to it.
+const baseInstructions = "You are a helpful voice assistant taking part in a Discord voice channel. " +
+	"Several people may be talking to you; keep answers short and conversational."
+
+// VoiceStyles are the answer styles sessions can start with, and the
+// instructions each adds to the session.
+var VoiceStyles = map[string]string{
+	"concise": "Be terse: answer in a sentence or two and skip small talk, as in a meeting.",
+	"chatty":  "Be warm and conversational; elaborate when it helps and ask follow-up questions.",
+	"playful": "Be playful and witty, joke along and keep the energy up, as at a game night.",
+}
+
+// seedParticipants records the users already in the session's channel when it starts.
+func (s *Service) seedParticipants(voiceSession *VoiceSession) {
+	voiceStates, err := s.state.VoiceStates(voiceSession.GuildID)
+	if err != nil {
+		s.logger.Warn("Failed to get voice states for participant list",
+			zap.Error(err),
+			zap.String("guild_id", voiceSession.GuildID.String()))
+
+		return
+	}
+
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	for i := range voiceStates {
+		vs := &voiceStates[i]
+		if vs.ChannelID != voiceSession.ChannelID || s.isBotUser(vs) {
+			continue
+		}
+		voiceSession.Participants[vs.UserID] = participantName(vs)
+	}
+}
+
+// handleVoiceStateUpdate tracks users joining and leaving the channel of an active session.
+func (s *Service) handleVoiceStateUpdate(e *gateway.VoiceStateUpdateEvent) {
+	prev := s.trackVoiceChannel(&e.VoiceState)
+	voiceSession, err := s.sessionManager.GetSessionByGuild(e.GuildID)
+	if err != nil {
+		s.announcePresence(e, prev)
+
+		return
+	}
+
+	vs := &e.VoiceState
+	if s.isBotUser(vs) {
+		return
+	}
+
+	voiceSession.mu.Lock()
+	_, wasPresent := voiceSession.Participants[vs.UserID]
+	isPresent := vs.ChannelID == voiceSession.ChannelID
+	name := participantName(vs)
+	if wasPresent == isPresent {
+		voiceSession.mu.Unlock()
+
+		return
+	}
+
+	var ssrc uint32
+	if isPresent {
+		voiceSession.Participants[vs.UserID] = name
+	} else {
+		if user, ok := voiceSession.ActiveUsers[vs.UserID]; ok {
+			ssrc = user.SSRC
+		}
+		name = voiceSession.Participants[vs.UserID]
+		delete(voiceSession.Participants, vs.UserID)
+		delete(voiceSession.ActiveUsers, vs.UserID)
+	}
+	instructions := sessionInstructions(voiceSession)
+	voiceSession.mu.Unlock()
+
+	ctx := context.Background()
+
+	if isPresent {
+		s.logger.Info("User joined voice session",
+			zap.String("guild_id", e.GuildID.String()),
+			zap.String("user_id", vs.UserID.String()))
+	} else {
+		s.logger.Info("User left voice session",
+			zap.String("guild_id", e.GuildID.String()),
+			zap.String("user_id", vs.UserID.String()))
+
+		// Stop aligning a stream that will never send again
+		if ssrc != 0 {
+			s.audioMixer.RemoveStream(ssrc)
+			voiceSession.Gate.RemoveStream(ssrc)
+			voiceSession.pipeline.removeStream(ssrc)
+			voiceSession.codecs.removeStream(ssrc)
+		}
+	}
+
+	s.handleControllerPresence(voiceSession, vs.UserID, isPresent)
+
+	if err := s.realtimeProvider.UpdateInstructions(ctx, instructions); err != nil {
+		s.logger.Warn("Failed to update session instructions", zap.Error(err))
+	}
+
+	if isPresent && s.cfg.AnnounceParticipants {
+		greeting := fmt.Sprintf("%s just joined the voice channel. Greet them by name in one short sentence.", name)
+		if err := s.realtimeProvider.GenerateResponseWithInstructions(ctx, greeting); err != nil {
+			s.logger.Warn("Failed to request greeting", zap.Error(err))
+		}
+	}
+}
+
+// isBotUser reports whether a voice state belongs to a bot, including this one.
+func (s *Service) isBotUser(vs *discord.VoiceState) bool {
+	if vs.Member != nil && vs.Member.User.Bot {
+		return true
+	}
+
+	me, err := s.state.Me()
+
+	return err == nil && me.ID == vs.UserID
+}
+
+// participantName returns the name a user is shown with in the guild.
+func participantName(vs *discord.VoiceState) string {
+	if vs.Member == nil {
+		return "<@" + vs.UserID.String() + ">"
+	}
+	if vs.Member.Nick != "" {
+		return vs.Member.Nick
+	}
+
+	return vs.Member.User.DisplayOrUsername()
+}
+
+// sessionInstructions builds the model instructions for a session.
+// The caller must hold voiceSession.mu.
+func sessionInstructions(voiceSession *VoiceSession) string {
+	instructions := baseInstructions
+	if voiceSession.Language != "" {
+		instructions += fmt.Sprintf(" Always respond in %s, even if people mix in other languages.", languageName(voiceSession.Language))
+	}
+	if style := VoiceStyles[voiceSession.Style]; style != "" {
+		instructions += " " + style
+	}
+	if delivery := voiceSession.delivery.Instructions(voiceSession.TextOnly); delivery != "" {
+		instructions += " " + delivery
+	}
+	if len(voiceSession.AddressNames) > 0 {
+		instructions += fmt.Sprintf(" People also talk among themselves; you are only asked to respond when they call you %s, "+
+			"so answer the latest message addressed to you.", strings.Join(voiceSession.AddressNames, " or "))
+	}
+	if len(voiceSession.Participants) == 0 {
+		return instructions
+	}
+
+	names := make([]string, 0, len(voiceSession.Participants))
+	for _, name := range voiceSession.Participants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return instructions + " People currently in the channel: " + strings.Join(names, ", ") + "."
+}
@@ -0,0 +1,132 @@
+package voice
+
+import (
+	"fmt"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+// How committed turns are resampled from 48 kHz to OpenAI's 24 kHz.
+const (
+	ResampleSinc     = "sinc"
+	ResampleDecimate = "decimate"
+	ResampleAverage  = "average"
+)
+
+// sessionPipeline holds the audio stages of one session, configured under
+// voice.pipeline. A nil *sessionPipeline leaves audio unchanged.
+type sessionPipeline struct {
+	stream   *audio.StreamPipeline
+	light    *audio.StreamPipeline // stream without denoise, used under load
+	mixed    audio.Chain
+	playback audio.Chain
+	resample string
+}
+
+// stageSpecs returns the enabled stages of stages, in order.
+func stageSpecs(stages []config.AudioStageConfig) []audio.StageSpec {
+	var specs []audio.StageSpec
+	for _, stage := range stages {
+		if stage.Enabled != nil && !*stage.Enabled {
+			continue
+		}
+		specs = append(specs, audio.StageSpec{Name: stage.Stage, Params: stage.Params})
+	}
+
+	return specs
+}
+
+// newPipeline creates the stages of cfg for a session.
+func newPipeline(cfg config.AudioPipelineConfig) (*sessionPipeline, error) {
+	p := &sessionPipeline{resample: cfg.Resample}
+	switch cfg.Resample {
+	case "", ResampleSinc, ResampleDecimate, ResampleAverage:
+	default:
+		return nil, fmt.Errorf("unknown resample method %q", cfg.Resample)
+	}
+
+	var err error
+	streamSpecs := stageSpecs(cfg.Stream)
+	if p.stream, err = audio.NewStreamPipeline(streamSpecs, audio.DiscordSampleRate); err != nil {
+		return nil, fmt.Errorf("stream stages: %w", err)
+	}
+	p.light = p.stream
+	if lightSpecs := withoutStage(streamSpecs, audio.StageDenoise); len(lightSpecs) < len(streamSpecs) {
+		p.light, _ = audio.NewStreamPipeline(lightSpecs, audio.DiscordSampleRate)
+	}
+	if p.mixed, err = audio.NewChain(stageSpecs(cfg.Mixed), audio.DiscordSampleRate); err != nil {
+		return nil, fmt.Errorf("mixed stages: %w", err)
+	}
+	if p.playback, err = audio.NewChain(stageSpecs(cfg.Playback), audio.OpenAISampleRate); err != nil {
+		return nil, fmt.Errorf("playback stages: %w", err)
+	}
+
+	return p, nil
+}
+
+// withoutStage returns specs without the stages named name.
+func withoutStage(specs []audio.StageSpec, name string) []audio.StageSpec {
+	var kept []audio.StageSpec
+	for _, spec := range specs {
+		if spec.Name != name {
+			kept = append(kept, spec)
+		}
+	}
+
+	return kept
+}
+
+// processStream runs a decoded frame of ssrc through the stream stages,
+// skipping denoise from QualityNoDenoise on.
+func (p *sessionPipeline) processStream(ssrc uint32, pcm []int16, level QualityLevel) []int16 {
+	if p == nil {
+		return pcm
+	}
+	if level >= QualityNoDenoise {
+		return p.light.Process(ssrc, pcm)
+	}
+
+	return p.stream.Process(ssrc, pcm)
+}
+
+// removeStream forgets the stream stages' state for ssrc.
+func (p *sessionPipeline) removeStream(ssrc uint32) {
+	if p != nil {
+		p.stream.RemoveStream(ssrc)
+		p.light.RemoveStream(ssrc)
+	}
+}
+
+// processMixed runs a committed turn through the mixed stages.
+func (p *sessionPipeline) processMixed(pcm []int16) []int16 {
+	if p == nil {
+		return pcm
+	}
+
+	return p.mixed.Process(pcm)
+}
+
+// processPlayback runs a frame of the assistant's audio through the playback
+// stages.
+func (p *sessionPipeline) processPlayback(pcm []int16) []int16 {
+	if p == nil {
+		return pcm
+	}
+
+	return p.playback.Process(pcm)
+}
+
+// downsample resamples a committed turn for OpenAI with the configured method.
+func (p *sessionPipeline) downsample(processor audio.AudioProcessor, pcm []int16) ([]int16, error) {
+	if p != nil {
+		switch p.resample {
+		case ResampleDecimate:
+			return audio.Decimate(pcm, audio.DiscordSampleRate, audio.OpenAISampleRate)
+		case ResampleAverage:
+			return audio.DownsampleAverage(pcm, audio.DiscordSampleRate, audio.OpenAISampleRate)
+		}
+	}
+
+	return processor.DownsamplePCM(pcm, audio.DiscordSampleRate, audio.OpenAISampleRate)
+}
@@ -0,0 +1,47 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+
+	internaldiscord "github.com/Raikerian/go-discord-chatgpt/internal/discord"
+)
+
+// preflight checks that the bot may connect to, and unless the session is
+// text-only speak in, channelID and that OpenAI serves model, so that Start
+// fails with a specific error before joining the channel rather than having
+// to undo a half-started session.
+func (s *Service) preflight(ctx context.Context, guildID discord.GuildID, channelID discord.ChannelID, model string, textOnly bool) error {
+	required := discord.PermissionViewChannel | discord.PermissionConnect
+	if !textOnly {
+		required |= discord.PermissionSpeak
+	}
+	if missing := required &^ s.botPermissions(channelID); missing != 0 {
+		return fmt.Errorf("%w: %s", ErrMissingVoicePermissions, internaldiscord.PermissionNames(missing))
+	}
+
+	return s.realtimeProvider.Preflight(ctx, ConnectOptions{Model: model, GuildID: guildID, TextOnly: textOnly})
+}
+
+// botPermissions returns the bot's permissions in channelID, including channel
+// overwrites. The check is skipped, allowing everything, when the state cache
+// cannot tell; joining then reports the problem as before.
+func (s *Service) botPermissions(channelID discord.ChannelID) discord.Permissions {
+	if s.state == nil {
+		return discord.PermissionAll
+	}
+
+	me, err := s.state.Me()
+	if err == nil {
+		var perms discord.Permissions
+		if perms, err = s.state.Permissions(channelID, me.ID); err == nil {
+			return perms
+		}
+	}
+	s.logger.Debug("Skipping voice permission preflight", zap.Error(err), zap.String("channel_id", channelID.String()))
+
+	return discord.PermissionAll
+}
@@ -0,0 +1,131 @@
+package voice
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultPruneKeepItems is how many recent items survive pruning when
+	// only max_context_tokens is set.
+	defaultPruneKeepItems = 10
+	// maxRecapRunes bounds the recap of pruned items, keeping its end.
+	maxRecapRunes = 2000
+	// recapPrefix starts the system message that replaces pruned items.
+	recapPrefix = "Recap of the earlier conversation, removed to save cost:\n"
+)
+
+// trackItem records an item added to the session's server-side conversation.
+func (s *Service) trackItem(voiceSession *VoiceSession, item ConversationItem) {
+	// Voice commands start new topics by deleting every item
+	if !s.pruningEnabled() && !s.cfg.VoiceCommands {
+		return
+	}
+
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	voiceSession.items = append(voiceSession.items, item)
+}
+
+// trackItemText records the text of a conversation item once it is known.
+func (s *Service) trackItemText(voiceSession *VoiceSession, itemID, text string) {
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	for i := range voiceSession.items {
+		if voiceSession.items[i].ID == itemID {
+			voiceSession.items[i].Text = text
+
+			return
+		}
+	}
+}
+
+func (s *Service) pruningEnabled() bool {
+	return s.cfg.MaxConversationItems > 0 || s.cfg.MaxContextTokens > 0
+}
+
+// pruneConversation deletes the oldest conversation items once the session
+// holds more than max_conversation_items, or its last response read more than
+// max_context_tokens, and replaces them with a system message recapping them.
+func (s *Service) pruneConversation(ctx context.Context, voiceSession *VoiceSession, inputTokens int) {
+	maxItems, maxTokens := s.cfg.MaxConversationItems, s.cfg.MaxContextTokens
+	keep := defaultPruneKeepItems
+	if maxItems > 0 {
+		keep = max(maxItems/2, 1)
+	}
+
+	voiceSession.mu.Lock()
+	overItems := maxItems > 0 && len(voiceSession.items) > maxItems
+	overTokens := maxTokens > 0 && inputTokens > maxTokens
+	if voiceSession.pruning || (!overItems && !overTokens) || len(voiceSession.items) <= keep {
+		voiceSession.mu.Unlock()
+
+		return
+	}
+	voiceSession.pruning = true
+	pruned := voiceSession.items[:len(voiceSession.items)-keep]
+	voiceSession.items = append([]ConversationItem(nil), voiceSession.items[len(pruned):]...)
+	voiceSession.mu.Unlock()
+
+	defer func() {
+		voiceSession.mu.Lock()
+		voiceSession.pruning = false
+		voiceSession.mu.Unlock()
+	}()
+
+	for _, item := range pruned {
+		if err := s.realtimeProvider.DeleteItem(ctx, item.ID); err != nil {
+			s.logger.Warn("Failed to delete conversation item",
+				zap.Error(err),
+				zap.String("guild_id", voiceSession.GuildID.String()),
+				zap.String("item_id", item.ID))
+		}
+	}
+	if recap := recapItems(pruned); recap != "" {
+		if err := s.realtimeProvider.AddSystemMessage(ctx, recapPrefix+recap); err != nil {
+			s.logger.Warn("Failed to add recap of pruned conversation",
+				zap.Error(err),
+				zap.String("guild_id", voiceSession.GuildID.String()))
+		}
+	}
+
+	s.logger.Info("Pruned voice conversation",
+		zap.String("guild_id", voiceSession.GuildID.String()),
+		zap.Int("pruned_items", len(pruned)),
+		zap.Int("kept_items", keep),
+		zap.Int("input_tokens", inputTokens))
+}
+
+// recapItems condenses items into one line each, most recent last, keeping
+// the end when they are too long. An earlier recap is carried over as is.
+func recapItems(items []ConversationItem) string {
+	var sb strings.Builder
+	for _, item := range items {
+		text := strings.TrimSpace(item.Text)
+		if text == "" {
+			continue
+		}
+		switch {
+		case item.Role == "system" && strings.HasPrefix(text, recapPrefix):
+			sb.WriteString(strings.TrimPrefix(text, recapPrefix))
+		case item.Role == "assistant":
+			sb.WriteString("Assistant: " + text)
+		case item.Role == "user":
+			sb.WriteString("Participants: " + text)
+		default:
+			continue
+		}
+		sb.WriteString("\n")
+	}
+
+	recap := []rune(strings.TrimSuffix(sb.String(), "\n"))
+	if len(recap) > maxRecapRunes {
+		recap = append([]rune("…"), recap[len(recap)-maxRecapRunes+1:]...)
+	}
+
+	return string(recap)
+}
@@ -0,0 +1,30 @@
+package voice
+
+import (
+	"context"
+	"time"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/retention"
+)
+
+// transcriptPurger enforces the transcripts retention window on the voice
+// session transcripts kept in memory.
+type transcriptPurger struct {
+	service *Service
+}
+
+// NewTranscriptPurger creates the retention.Purger for voice transcripts.
+func NewTranscriptPurger(service *Service) retention.Purger {
+	return &transcriptPurger{service: service}
+}
+
+// DataType implements retention.Purger.
+func (p *transcriptPurger) DataType() string {
+	return retention.Transcripts
+}
+
+// PurgeBefore implements retention.Purger. Transcripts posted to text
+// channels stay there; only the turns kept for exports are purged.
+func (p *transcriptPurger) PurgeBefore(_ context.Context, cutoff time.Time) (int, error) {
+	return p.service.history.PurgeBefore(cutoff), nil
+}
@@ -1,21 +1,32 @@
 package voice
 
 import (
+	"cmp"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"time"
 
 	openairt "github.com/WqyJh/go-openai-realtime"
+	"github.com/coder/websocket"
+	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
 
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
 )
 
 type RealtimeProvider interface {
+	// Check that OpenAI is reachable and serves the model, without connecting
+	Preflight(ctx context.Context, opts ConnectOptions) error
+
 	// Establish connection to OpenAI Realtime
-	Connect(ctx context.Context, model string) (*RealtimeConnection, error)
+	Connect(ctx context.Context, opts ConnectOptions) (*RealtimeConnection, error)
 
 	// Send audio for processing (audio must be base64 encoded)
 	SendAudio(ctx context.Context, audioBase64 string) error
@@ -26,6 +37,21 @@ type RealtimeProvider interface {
 	// Generate response from committed audio
 	GenerateResponse(ctx context.Context) error
 
+	// Cancel the response in progress, e.g. when a member speaks over it
+	CancelResponse(ctx context.Context) error
+
+	// Generate a one-off response following the given instructions (e.g. a spoken greeting)
+	GenerateResponseWithInstructions(ctx context.Context, instructions string) error
+
+	// Replace the session's system instructions
+	UpdateInstructions(ctx context.Context, instructions string) error
+
+	// Remove an item from the server-side conversation
+	DeleteItem(ctx context.Context, itemID string) error
+
+	// Add a system message to the end of the conversation, e.g. a recap of pruned items
+	AddSystemMessage(ctx context.Context, text string) error
+
 	// Receive AI response through event handlers
 	SetResponseHandlers(handlers ResponseHandlers) error
 
@@ -36,6 +62,21 @@ type RealtimeProvider interface {
 	Close() error
 }
 
+// ConnectOptions configures a new OpenAI Realtime connection.
+type ConnectOptions struct {
+	Model    string
+	Language string          // ISO-639-1 transcription language, empty lets Whisper auto-detect
+	TextOnly bool            // Respond with text only, skipping audio output
+	GuildID  discord.GuildID // Guild whose OpenAI credentials the session uses
+	Voice    string          // Realtime voice, empty for voice_profile
+
+	Temperature     float32 // Sampling temperature, 0 for the model default
+	MaxOutputTokens int     // Cap on each response's output tokens, 0 for none
+
+	ServerVAD       bool          // Let OpenAI's turn detection commit turns
+	SilenceDuration time.Duration // Silence that ends a turn under server VAD, 0 for the server default
+}
+
 type RealtimeConnection struct {
 	Connected bool
 	Model     string
@@ -47,7 +88,11 @@ type SessionConfig struct {
 	Voice                   string   // e.g., "shimmer"
 	OutputAudioFormat       string   // "pcm16"
 	InputAudioTranscription bool     // Enable Whisper transcription
-	VADMode                 string   // "server_vad" or "none"
+	VADMode                 string   // VADModeServer, or VADModeClient when the bot commits turns
+	TranscriptionLanguage   string   // ISO-639-1 code forced on Whisper, empty for auto-detect
+	Temperature             float32  // Sampling temperature, 0 for the model default
+	MaxOutputTokens         int      // Cap on each response's output tokens, 0 for none
+	SilenceDurationMs       int      // Silence that ends a turn under server VAD, 0 for the server default
 }
 
 type AudioResponse struct {
@@ -63,55 +108,161 @@ type Usage struct {
 	OutputAudioTokens int
 }
 
+// ConversationItem is a message in the server-side conversation.
+type ConversationItem struct {
+	ID   string
+	Role string // "user", "assistant" or "system"
+	Text string // Text or transcript, empty until known
+}
+
 type ResponseHandlers struct {
 	OnAudioDelta     func(ctx context.Context, audioData []byte)
 	OnTranscript     func(ctx context.Context, transcript string) // AI response transcript
+	OnText           func(ctx context.Context, text string)       // AI text response (text-only sessions)
 	OnUserTranscript func(ctx context.Context, transcript string) // User input transcript
 	OnResponseDone   func(ctx context.Context, usage *Usage)
 	OnError          func(ctx context.Context, err error)
+
+	// Conversation items as they are added, and their text once transcribed
+	OnItemCreated func(ctx context.Context, item ConversationItem)
+	OnItemText    func(ctx context.Context, itemID, text string)
+
+	// Server VAD: speech starting and stopping in the streamed audio, and
+	// the input audio buffer being committed as a turn
+	OnSpeechStarted  func(ctx context.Context)
+	OnSpeechStopped  func(ctx context.Context)
+	OnAudioCommitted func(ctx context.Context)
 }
 
 type openAIRealtimeProvider struct {
 	logger     *zap.Logger
 	cfg        *config.VoiceConfig
-	apiKey     string
+	keys       internalopenai.KeyResolver
+	network    config.ConnectionConfig
 	connection *RealtimeConnection
+	modalities []openairt.Modality
 	handlers   ResponseHandlers
-	client     *openairt.Client
 	conn       *openairt.Conn
 	handler    *openairt.ConnHandler
 }
 
-func NewRealtimeProvider(logger *zap.Logger, cfg *config.Config) RealtimeProvider {
-	voiceCfg := &cfg.Voice
+// NewRealtimeProvider creates a RealtimeProvider. Each connection uses the
+// realtime credentials of its guild from keys, through the configured
+// realtime URL, proxy and TLS settings.
+func NewRealtimeProvider(logger *zap.Logger, cfg *config.Config, keys internalopenai.KeyResolver) RealtimeProvider {
+	return &openAIRealtimeProvider{
+		logger:  logger,
+		cfg:     &cfg.Voice,
+		keys:    keys,
+		network: cfg.OpenAI.Connection,
+	}
+}
+
+// realtimeService names OpenAI Realtime to the circuit breaker and in its errors.
+const realtimeService = "OpenAI Realtime"
+
+// NewResilientRealtimeProvider creates the RealtimeProvider sessions use: one
+// made by NewRealtimeProvider whose preflight checks and connections retry
+// transient failures, and fail fast while OpenAI Realtime keeps failing.
+func NewResilientRealtimeProvider(logger *zap.Logger, cfg *config.Config, keys internalopenai.KeyResolver, resilience *internalopenai.Resilience) RealtimeProvider {
+	return &resilientRealtimeProvider{
+		RealtimeProvider: NewRealtimeProvider(logger, cfg, keys),
+		keys:             keys,
+		resilience:       resilience,
+	}
+}
+
+// resilientRealtimeProvider sends the calls that reach OpenAI before a
+// session starts through resilience, with the breaker of the session guild's
+// realtime credentials. Calls on an open connection are not retried, as the
+// session handles a lost connection itself.
+type resilientRealtimeProvider struct {
+	RealtimeProvider
+	keys       internalopenai.KeyResolver
+	resilience *internalopenai.Resilience
+}
+
+// Preflight checks OpenAI Realtime, retrying transient failures.
+func (p *resilientRealtimeProvider) Preflight(ctx context.Context, opts ConnectOptions) error {
+	return p.resilience.Do(ctx, realtimeService, p.keys.RealtimeCredentials(opts.GuildID), func(ctx context.Context) error {
+		return p.RealtimeProvider.Preflight(ctx, opts)
+	})
+}
+
+// Connect connects to OpenAI Realtime, retrying transient failures.
+func (p *resilientRealtimeProvider) Connect(ctx context.Context, opts ConnectOptions) (*RealtimeConnection, error) {
+	var connection *RealtimeConnection
+	err := p.resilience.Do(ctx, realtimeService, p.keys.RealtimeCredentials(opts.GuildID), func(ctx context.Context) error {
+		var err error
+		connection, err = p.RealtimeProvider.Connect(ctx, opts)
+
+		return err
+	})
+
+	return connection, err
+}
 
-	// Use separate realtime API key if provided, otherwise use main OpenAI key
-	apiKey := voiceCfg.RealtimeAPIKey
-	if apiKey == "" {
-		apiKey = cfg.OpenAI.APIKey
+// Preflight asks the OpenAI API for opts.Model with the guild's credentials,
+// so that an unreachable API, a rejected key or a missing model is reported
+// before a session joins the voice channel.
+func (p *openAIRealtimeProvider) Preflight(ctx context.Context, opts ConnectOptions) error {
+	creds := p.keys.RealtimeCredentials(opts.GuildID)
+	clientConfig := internalopenai.RealtimeConfig(p.network, creds.APIKey, p.keys.HTTPClient())
+	httpClient := clientConfig.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
 	}
 
-	// Create OpenAI Realtime client
-	client := openairt.NewClient(apiKey)
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, clientConfig.APIBaseURL+"/models/"+url.PathEscape(opts.Model), nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRealtimeUnavailable, err)
+	}
+	req.Header = creds.Headers()
+	req.Header.Set("Authorization", "Bearer "+creds.APIKey)
 
-	return &openAIRealtimeProvider{
-		logger: logger,
-		cfg:    voiceCfg,
-		apiKey: apiKey,
-		client: client,
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRealtimeUnavailable, err)
 	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: the API key was rejected (%s)", ErrRealtimeUnavailable, resp.Status)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: model %s is not available to this API key", ErrRealtimeUnavailable, opts.Model)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		// An API error, so the failure is retried as transient
+		apiErr := &openai.APIError{HTTPStatusCode: resp.StatusCode, HTTPStatus: resp.Status, Message: "the model could not be looked up"}
+
+		return fmt.Errorf("%w: %w", ErrRealtimeUnavailable, apiErr)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("%w: %s", ErrRealtimeUnavailable, resp.Status)
+	}
+
+	return nil
 }
 
-func (p *openAIRealtimeProvider) Connect(ctx context.Context, model string) (*RealtimeConnection, error) {
+func (p *openAIRealtimeProvider) Connect(ctx context.Context, opts ConnectOptions) (*RealtimeConnection, error) {
 	if p.connection != nil && p.connection.Connected {
 		return p.connection, nil
 	}
 
+	model := opts.Model
+
 	p.logger.Info("Connecting to OpenAI Realtime API",
 		zap.String("model", model))
 
-	// Establish WebSocket connection
-	conn, err := p.client.Connect(ctx)
+	// Establish WebSocket connection with the guild's credentials
+	creds := p.keys.RealtimeCredentials(opts.GuildID)
+	client := openairt.NewClientWithConfig(internalopenai.RealtimeConfig(p.network, creds.APIKey, p.keys.HTTPClient()))
+	dialer := &headerDialer{
+		dialer:  openairt.NewCoderWebSocketDialer(openairt.CoderWebSocketOptions{DialOptions: &websocket.DialOptions{HTTPClient: p.keys.HTTPClient()}}),
+		headers: creds.Headers(),
+	}
+	conn, err := client.Connect(ctx, openairt.WithDialer(dialer))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to OpenAI Realtime: %w", err)
 	}
@@ -132,13 +283,26 @@ func (p *openAIRealtimeProvider) Connect(ctx context.Context, model string) (*Re
 
 	p.connection = connection
 
+	modalities := []string{"text", "audio"}
+	if opts.TextOnly {
+		modalities = []string{"text"}
+	}
+	vadMode := VADModeClient
+	if opts.ServerVAD {
+		vadMode = VADModeServer
+	}
+
 	// Configure the session with default settings
 	sessionConfig := SessionConfig{
-		Modalities:              []string{"text", "audio"},
-		Voice:                   p.cfg.VoiceProfile,
+		Modalities:              modalities,
+		Voice:                   cmp.Or(opts.Voice, p.cfg.VoiceProfile),
 		OutputAudioFormat:       "pcm16",
 		InputAudioTranscription: true,
-		VADMode:                 p.cfg.VADMode,
+		VADMode:                 vadMode,
+		TranscriptionLanguage:   opts.Language,
+		Temperature:             opts.Temperature,
+		MaxOutputTokens:         opts.MaxOutputTokens,
+		SilenceDurationMs:       int(opts.SilenceDuration.Milliseconds()),
 	}
 
 	err = p.ConfigureSession(sessionConfig)
@@ -186,6 +350,16 @@ func (p *openAIRealtimeProvider) CommitAudio(ctx context.Context) error {
 	return p.conn.SendMessage(ctx, event)
 }
 
+func (p *openAIRealtimeProvider) CancelResponse(ctx context.Context) error {
+	if p.connection == nil || !p.connection.Connected {
+		return errors.New("not connected to OpenAI Realtime API")
+	}
+
+	p.logger.Info("Cancelling response in progress")
+
+	return p.conn.SendMessage(ctx, &openairt.ResponseCancelEvent{})
+}
+
 func (p *openAIRealtimeProvider) GenerateResponse(ctx context.Context) error {
 	if p.connection == nil || !p.connection.Connected {
 		return errors.New("not connected to OpenAI Realtime API")
@@ -196,13 +370,69 @@ func (p *openAIRealtimeProvider) GenerateResponse(ctx context.Context) error {
 	// Create and send ResponseCreateEvent to trigger response generation
 	event := &openairt.ResponseCreateEvent{
 		Response: openairt.ResponseCreateParams{
-			Modalities: []openairt.Modality{openairt.ModalityText, openairt.ModalityAudio},
+			Modalities: p.modalities,
+		},
+	}
+
+	return p.conn.SendMessage(ctx, event)
+}
+
+func (p *openAIRealtimeProvider) GenerateResponseWithInstructions(ctx context.Context, instructions string) error {
+	if p.connection == nil || !p.connection.Connected {
+		return errors.New("not connected to OpenAI Realtime API")
+	}
+
+	p.logger.Info("Requesting instructed response from OpenAI")
+
+	event := &openairt.ResponseCreateEvent{
+		Response: openairt.ResponseCreateParams{
+			Modalities:   p.modalities,
+			Instructions: instructions,
 		},
 	}
 
 	return p.conn.SendMessage(ctx, event)
 }
 
+func (p *openAIRealtimeProvider) UpdateInstructions(ctx context.Context, instructions string) error {
+	if p.connection == nil || !p.connection.Connected {
+		return errors.New("not connected to OpenAI Realtime API")
+	}
+
+	p.logger.Debug("Updating OpenAI session instructions", zap.Int("length", len(instructions)))
+
+	// Turn detection stays disabled, matching ConfigureSession.
+	event := &openairt.SessionUpdateEvent{
+		Session: openairt.ClientSession{
+			Instructions: instructions,
+		},
+	}
+
+	return p.conn.SendMessage(ctx, event)
+}
+
+func (p *openAIRealtimeProvider) DeleteItem(ctx context.Context, itemID string) error {
+	if p.connection == nil || !p.connection.Connected {
+		return errors.New("not connected to OpenAI Realtime API")
+	}
+
+	return p.conn.SendMessage(ctx, &openairt.ConversationItemDeleteEvent{ItemID: itemID})
+}
+
+func (p *openAIRealtimeProvider) AddSystemMessage(ctx context.Context, text string) error {
+	if p.connection == nil || !p.connection.Connected {
+		return errors.New("not connected to OpenAI Realtime API")
+	}
+
+	return p.conn.SendMessage(ctx, &openairt.ConversationItemCreateEvent{
+		Item: openairt.MessageItem{
+			Type:    openairt.MessageItemTypeMessage,
+			Role:    openairt.MessageRoleSystem,
+			Content: []openairt.MessageContentPart{{Type: openairt.MessageContentTypeInputText, Text: text}},
+		},
+	})
+}
+
 func (p *openAIRealtimeProvider) SetResponseHandlers(handlers ResponseHandlers) error {
 	p.handlers = handlers
 
@@ -221,7 +451,11 @@ func (p *openAIRealtimeProvider) ConfigureSession(sessionConfig SessionConfig) e
 		zap.String("voice", sessionConfig.Voice),
 		zap.String("output_format", sessionConfig.OutputAudioFormat),
 		zap.Bool("transcription", sessionConfig.InputAudioTranscription),
-		zap.String("vad_mode", sessionConfig.VADMode))
+		zap.String("vad_mode", sessionConfig.VADMode),
+		zap.Int("silence_duration_ms", sessionConfig.SilenceDurationMs),
+		zap.String("transcription_language", sessionConfig.TranscriptionLanguage),
+		zap.Float32("temperature", sessionConfig.Temperature),
+		zap.Int("max_output_tokens", sessionConfig.MaxOutputTokens))
 
 	// Convert our config to the library's format
 	modalities := make([]openairt.Modality, len(sessionConfig.Modalities))
@@ -247,6 +481,8 @@ func (p *openAIRealtimeProvider) ConfigureSession(sessionConfig SessionConfig) e
 		voice = openairt.VoiceShimmer
 	}
 
+	p.modalities = modalities
+
 	// Create session update event
 	sessionUpdate := &openairt.SessionUpdateEvent{
 		Session: openairt.ClientSession{
@@ -259,12 +495,65 @@ func (p *openAIRealtimeProvider) ConfigureSession(sessionConfig SessionConfig) e
 		},
 	}
 
-	// Configure VAD mode if not using server VAD
-	if sessionConfig.VADMode != "server_vad" {
-		sessionUpdate.Session.TurnDetection = nil // Disable server-side turn detection
+	if sessionConfig.Temperature > 0 {
+		sessionUpdate.Session.Temperature = &sessionConfig.Temperature
+	}
+	if sessionConfig.MaxOutputTokens > 0 {
+		sessionUpdate.Session.MaxOutputTokens = openairt.IntOrInf(sessionConfig.MaxOutputTokens)
+	}
+
+	// A nil TurnDetection disables server-side turn detection. Server VAD
+	// only commits turns: the service decides which to answer, so it does
+	// not create responses itself
+	if sessionConfig.VADMode == VADModeServer {
+		createResponse := false
+		sessionUpdate.Session.TurnDetection = &openairt.ClientTurnDetection{
+			Type: openairt.ClientTurnDetectionTypeServerVad,
+			TurnDetectionParams: openairt.TurnDetectionParams{
+				SilenceDurationMs: sessionConfig.SilenceDurationMs,
+				CreateResponse:    &createResponse,
+			},
+		}
+	}
+
+	if err := p.conn.SendMessage(context.Background(), sessionUpdate); err != nil {
+		return err
+	}
+
+	if sessionConfig.TranscriptionLanguage == "" {
+		return nil
 	}
 
-	return p.conn.SendMessage(context.Background(), sessionUpdate)
+	return p.setTranscriptionLanguage(context.Background(), sessionConfig.TranscriptionLanguage)
+}
+
+// setTranscriptionLanguage forces the Whisper input language. The realtime
+// client library has no field for it, so the session.update is sent raw;
+// omitted fields keep their current values.
+func (p *openAIRealtimeProvider) setTranscriptionLanguage(ctx context.Context, language string) error {
+	type transcription struct {
+		Model    string `json:"model"`
+		Language string `json:"language"`
+	}
+	type session struct {
+		InputAudioTranscription transcription `json:"input_audio_transcription"`
+	}
+	event := struct {
+		Type    openairt.ClientEventType `json:"type"`
+		Session session                  `json:"session"`
+	}{
+		Type: openairt.ClientEventTypeSessionUpdate,
+		Session: session{
+			InputAudioTranscription: transcription{Model: openai.Whisper1, Language: language},
+		},
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcription language update: %w", err)
+	}
+
+	return p.conn.SendMessageRaw(ctx, data)
 }
 
 func (p *openAIRealtimeProvider) Close() error {
@@ -319,6 +608,20 @@ func (p *openAIRealtimeProvider) handleServerEvent(ctx context.Context, event op
 				zap.String("transcript", transcript.Transcript))
 			p.handlers.OnTranscript(ctx, transcript.Transcript)
 		}
+		if p.handlers.OnItemText != nil {
+			p.handlers.OnItemText(ctx, transcript.ItemID, transcript.Transcript)
+		}
+
+	case openairt.ServerEventTypeResponseTextDone:
+		text := event.(openairt.ResponseTextDoneEvent)
+		if p.handlers.OnText != nil {
+			p.logger.Debug("Received AI text from OpenAI",
+				zap.String("text", text.Text))
+			p.handlers.OnText(ctx, text.Text)
+		}
+		if p.handlers.OnItemText != nil {
+			p.handlers.OnItemText(ctx, text.ItemID, text.Text)
+		}
 
 	case openairt.ServerEventTypeConversationItemInputAudioTranscriptionCompleted:
 		inputTranscript := event.(openairt.ConversationItemInputAudioTranscriptionCompletedEvent)
@@ -328,6 +631,19 @@ func (p *openAIRealtimeProvider) handleServerEvent(ctx context.Context, event op
 				zap.String("item_id", inputTranscript.ItemID))
 			p.handlers.OnUserTranscript(ctx, inputTranscript.Transcript)
 		}
+		if p.handlers.OnItemText != nil {
+			p.handlers.OnItemText(ctx, inputTranscript.ItemID, inputTranscript.Transcript)
+		}
+
+	case openairt.ServerEventTypeConversationItemCreated:
+		created := event.(openairt.ConversationItemCreatedEvent)
+		if p.handlers.OnItemCreated != nil && created.Item.Type == openairt.MessageItemTypeMessage {
+			item := ConversationItem{ID: created.Item.ID, Role: string(created.Item.Role)}
+			for _, part := range created.Item.Content {
+				item.Text += part.Text + part.Transcript
+			}
+			p.handlers.OnItemCreated(ctx, item)
+		}
 
 	case openairt.ServerEventTypeConversationItemInputAudioTranscriptionFailed:
 		failedTranscript := event.(openairt.ConversationItemInputAudioTranscriptionFailedEvent)
@@ -337,19 +653,40 @@ func (p *openAIRealtimeProvider) handleServerEvent(ctx context.Context, event op
 
 	case openairt.ServerEventTypeResponseDone:
 		done := event.(openairt.ResponseDoneEvent)
-		if p.handlers.OnResponseDone != nil && done.Response.Usage != nil {
-			usage := &Usage{
+		if p.handlers.OnResponseDone == nil {
+			break
+		}
+		// Cancelled responses may have no usage, but still end
+		var usage *Usage
+		if done.Response.Usage != nil {
+			usage = &Usage{
 				InputTokens:       done.Response.Usage.InputTokens,
 				OutputTokens:      done.Response.Usage.OutputTokens,
 				InputAudioTokens:  done.Response.Usage.InputTokenDetails.AudioTokens,
 				OutputAudioTokens: done.Response.Usage.OutputTokenDetails.AudioTokens,
 			}
 			p.logger.Info("Response completed",
+				zap.String("status", string(done.Response.Status)),
 				zap.Int("input_tokens", usage.InputTokens),
 				zap.Int("output_tokens", usage.OutputTokens),
 				zap.Int("input_audio_tokens", usage.InputAudioTokens),
 				zap.Int("output_audio_tokens", usage.OutputAudioTokens))
-			p.handlers.OnResponseDone(ctx, usage)
+		}
+		p.handlers.OnResponseDone(ctx, usage)
+
+	case openairt.ServerEventTypeInputAudioBufferSpeechStarted:
+		if p.handlers.OnSpeechStarted != nil {
+			p.handlers.OnSpeechStarted(ctx)
+		}
+
+	case openairt.ServerEventTypeInputAudioBufferSpeechStopped:
+		if p.handlers.OnSpeechStopped != nil {
+			p.handlers.OnSpeechStopped(ctx)
+		}
+
+	case openairt.ServerEventTypeInputAudioBufferCommitted:
+		if p.handlers.OnAudioCommitted != nil {
+			p.handlers.OnAudioCommitted(ctx)
 		}
 
 	case openairt.ServerEventTypeError:
@@ -359,3 +696,18 @@ func (p *openAIRealtimeProvider) handleServerEvent(ctx context.Context, event op
 		}
 	}
 }
+
+// headerDialer adds the organization and project headers to the realtime
+// WebSocket handshake, which the realtime client does not set itself.
+type headerDialer struct {
+	dialer  openairt.WebSocketDialer
+	headers http.Header
+}
+
+func (d *headerDialer) Dial(ctx context.Context, url string, header http.Header) (openairt.WebSocketConn, error) {
+	for name, values := range d.headers {
+		header[name] = values
+	}
+
+	return d.dialer.Dial(ctx, url, header)
+}
@@ -0,0 +1,166 @@
+package voice_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestRealtimeProvider_Preflight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer sk-test":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/models/gpt-realtime":
+			_, _ = w.Write([]byte(`{"id":"gpt-realtime","object":"model"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "sk-test"
+	cfg.OpenAI.Connection.BaseURL = server.URL
+	keys, err := internalopenai.NewKeyResolver(cfg, zap.NewNop())
+	require.NoError(t, err)
+	provider := voice.NewRealtimeProvider(zap.NewNop(), cfg, keys)
+
+	require.NoError(t, provider.Preflight(context.Background(), voice.ConnectOptions{Model: "gpt-realtime"}))
+
+	err = provider.Preflight(context.Background(), voice.ConnectOptions{Model: "gpt-missing"})
+	require.ErrorIs(t, err, voice.ErrRealtimeUnavailable)
+	assert.Contains(t, err.Error(), "gpt-missing")
+
+	server.Close()
+	err = provider.Preflight(context.Background(), voice.ConnectOptions{Model: "gpt-realtime"})
+	assert.ErrorIs(t, err, voice.ErrRealtimeUnavailable, "an unreachable API is reported")
+}
+
+func TestRealtimeProvider_ServerVAD(t *testing.T) {
+	sessionUpdates := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.CloseNow() }()
+
+		var update map[string]any
+		if err := wsjson.Read(r.Context(), conn, &update); err != nil {
+			return
+		}
+		sessionUpdates <- update
+		for _, event := range []string{
+			`{"type":"input_audio_buffer.speech_started","event_id":"e1","audio_start_ms":100,"item_id":"item-1"}`,
+			`{"type":"input_audio_buffer.speech_stopped","event_id":"e2","audio_end_ms":900,"item_id":"item-1"}`,
+			`{"type":"input_audio_buffer.committed","event_id":"e3","item_id":"item-1"}`,
+		} {
+			if err := conn.Write(r.Context(), websocket.MessageText, []byte(event)); err != nil {
+				return
+			}
+		}
+		_, _, _ = conn.Read(r.Context())
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "sk-test"
+	cfg.OpenAI.Connection.RealtimeURL = "ws" + strings.TrimPrefix(server.URL, "http")
+	keys, err := internalopenai.NewKeyResolver(cfg, zap.NewNop())
+	require.NoError(t, err)
+	provider := voice.NewRealtimeProvider(zap.NewNop(), cfg, keys)
+
+	events := make(chan string, 3)
+	require.NoError(t, provider.SetResponseHandlers(voice.ResponseHandlers{
+		OnSpeechStarted:  func(context.Context) { events <- "started" },
+		OnSpeechStopped:  func(context.Context) { events <- "stopped" },
+		OnAudioCommitted: func(context.Context) { events <- "committed" },
+	}))
+	_, err = provider.Connect(t.Context(), voice.ConnectOptions{Model: "gpt-realtime", ServerVAD: true, SilenceDuration: 800 * time.Millisecond})
+	require.NoError(t, err)
+	defer func() { _ = provider.Close() }()
+
+	update := <-sessionUpdates
+	session, ok := update["session"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{"type": "server_vad", "silence_duration_ms": 800.0, "create_response": false}, session["turn_detection"],
+		"server VAD commits turns and leaves answering them to the bot")
+	for _, want := range []string{"started", "stopped", "committed"} {
+		select {
+		case got := <-events:
+			assert.Equal(t, want, got)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event", want)
+		}
+	}
+}
+
+func TestRealtimeProvider_CancelResponse(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.CloseNow() }()
+
+		var event map[string]any
+		if err := wsjson.Read(r.Context(), conn, &event); err != nil { // session.update
+			return
+		}
+		if err := wsjson.Read(r.Context(), conn, &event); err != nil {
+			return
+		}
+		eventType, _ := event["type"].(string)
+		received <- eventType
+		done := `{"type":"response.done","event_id":"e1","response":{"id":"resp-1","object":"realtime.response","status":"cancelled","output":[]}}`
+		if err := conn.Write(r.Context(), websocket.MessageText, []byte(done)); err != nil {
+			return
+		}
+		_, _, _ = conn.Read(r.Context())
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "sk-test"
+	cfg.OpenAI.Connection.RealtimeURL = "ws" + strings.TrimPrefix(server.URL, "http")
+	keys, err := internalopenai.NewKeyResolver(cfg, zap.NewNop())
+	require.NoError(t, err)
+	provider := voice.NewRealtimeProvider(zap.NewNop(), cfg, keys)
+
+	usages := make(chan *voice.Usage, 1)
+	require.NoError(t, provider.SetResponseHandlers(voice.ResponseHandlers{
+		OnResponseDone: func(_ context.Context, usage *voice.Usage) { usages <- usage },
+	}))
+	_, err = provider.Connect(t.Context(), voice.ConnectOptions{Model: "gpt-realtime"})
+	require.NoError(t, err)
+	defer func() { _ = provider.Close() }()
+
+	require.NoError(t, provider.CancelResponse(t.Context()))
+	select {
+	case got := <-received:
+		assert.Equal(t, "response.cancel", got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no response.cancel sent")
+	}
+	select {
+	case usage := <-usages:
+		assert.Nil(t, usage, "cancelled responses end without usage")
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled response never ended")
+	}
+}
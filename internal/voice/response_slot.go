@@ -0,0 +1,81 @@
+package voice
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+const (
+	// responseSlotWait is the longest a turn waits for a request slot. After
+	// it the turn is answered anyway, as a late answer is worse than a busy
+	// limiter.
+	responseSlotWait = 3 * time.Second
+	// responseSlotHold frees the slot of a response that never reported it
+	// was done.
+	responseSlotHold = 2 * time.Minute
+)
+
+var errResponseUnfinished = errors.New("voice response did not finish")
+
+// generateResponse requests the answer to a turn once the shared request
+// limiter grants a slot. Voice turns are served before queued text
+// completions, so chat threads in the same guild do not hold up the answer;
+// the slot is freed when the response is done.
+func (s *Service) generateResponse(ctx context.Context, voiceSession *VoiceSession) error {
+	if s.limiter != nil {
+		waitCtx, cancel := context.WithTimeout(chat.WithPriority(ctx, chat.PriorityVoice), responseSlotWait)
+		release, err := s.limiter.Acquire(waitCtx, voiceSession.GuildID, nil)
+		cancel()
+		if err != nil {
+			s.logger.Warn("No request slot free for voice turn, answering anyway",
+				zap.Error(err),
+				zap.String("guild_id", voiceSession.GuildID.String()))
+		} else {
+			s.holdResponseSlot(voiceSession, release)
+		}
+	}
+
+	if err := s.realtimeProvider.GenerateResponse(ctx); err != nil {
+		s.releaseResponseSlot(voiceSession, err)
+
+		return err
+	}
+
+	return nil
+}
+
+// holdResponseSlot keeps release until the session's response is done,
+// freeing a slot still held for an earlier response.
+func (s *Service) holdResponseSlot(voiceSession *VoiceSession, release func(err error)) {
+	expiry := time.AfterFunc(responseSlotHold, func() { release(errResponseUnfinished) })
+
+	voiceSession.mu.Lock()
+	previous := voiceSession.responseSlot
+	voiceSession.responseSlot = func(err error) {
+		expiry.Stop()
+		release(err)
+	}
+	voiceSession.mu.Unlock()
+
+	if previous != nil {
+		previous(nil)
+	}
+}
+
+// releaseResponseSlot frees the request slot held for the session's
+// response, if any.
+func (s *Service) releaseResponseSlot(voiceSession *VoiceSession, err error) {
+	voiceSession.mu.Lock()
+	release := voiceSession.responseSlot
+	voiceSession.responseSlot = nil
+	voiceSession.mu.Unlock()
+
+	if release != nil {
+		release(err)
+	}
+}
@@ -0,0 +1,301 @@
+package voice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
+)
+
+const (
+	defaultScheduleFile = "voice_schedules.json"
+	// scheduleCheckInterval is how often booked sessions are checked.
+	scheduleCheckInterval = 15 * time.Second
+	// scheduleGrace is how late a booked session may still start, e.g. after
+	// a restart; older bookings are skipped.
+	scheduleGrace = 10 * time.Minute
+	// scheduleTimeLayout is how booking times are written, in UTC.
+	scheduleTimeLayout = "2006-01-02 15:04"
+	// maxSchedulesPerGuild bounds the bookings one server can make.
+	maxSchedulesPerGuild = 25
+)
+
+// ErrScheduleNotFound is returned for schedule IDs that are not booked.
+var ErrScheduleNotFound = errors.New("scheduled session not found")
+
+// Schedule is a voice session booked to start at a set time.
+type Schedule struct {
+	ID            string            `json:"id"`
+	GuildID       discord.GuildID   `json:"guild_id"`
+	ChannelID     discord.ChannelID `json:"channel_id"`      // Voice channel to join
+	TextChannelID discord.ChannelID `json:"text_channel_id"` // Where the session is announced
+	BookedBy      discord.UserID    `json:"booked_by"`       // Controls the session once started
+	RoleID        discord.RoleID    `json:"role_id,omitempty"`
+	StartAt       time.Time         `json:"start_at"`
+	Weekly        bool              `json:"weekly,omitempty"`
+	Minutes       int               `json:"minutes"` // Session length before it stops
+}
+
+// ParseScheduleTime parses a booking time in UTC, either a full date and time
+// like "2025-03-14 09:30" or a time of day like "09:30" for its next
+// occurrence after now.
+func ParseScheduleTime(value string, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	if t, err := time.Parse(scheduleTimeLayout, value); err == nil {
+		return t, nil
+	}
+
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time must look like %q or %q (UTC)", "2025-03-14 09:30", "09:30")
+	}
+	t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+// scheduleStore keeps the booked sessions in a JSON document.
+type scheduleStore struct {
+	doc storage.Document
+
+	mu        sync.Mutex
+	schedules []Schedule
+}
+
+func loadScheduleStore(p storage.Provider, path string) (*scheduleStore, error) {
+	if path == "" {
+		path = defaultScheduleFile
+	}
+	store := &scheduleStore{doc: storage.NewDocument(p, path)}
+	if _, err := store.doc.Load(&store.schedules); err != nil {
+		store.schedules = nil
+
+		return store, fmt.Errorf("failed to load voice schedules: %w", err)
+	}
+
+	return store, nil
+}
+
+// persist saves the schedules. The caller must hold st.mu.
+func (st *scheduleStore) persist() error {
+	return st.doc.Save(st.schedules)
+}
+
+// update applies fn to a copy of the schedules and keeps the result once it
+// is saved.
+func (st *scheduleStore) update(fn func([]Schedule) ([]Schedule, error)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	previous := st.schedules
+	next, err := fn(slices.Clone(st.schedules))
+	if err != nil {
+		return err
+	}
+	st.schedules = next
+	if err := st.persist(); err != nil {
+		st.schedules = previous
+
+		return err
+	}
+
+	return nil
+}
+
+// ScheduleSession books sch, returning it with its ID. The session runs for
+// sch.Minutes, at most the configured maximum session length, which is also
+// the default.
+func (s *Service) ScheduleSession(sch Schedule) (Schedule, error) {
+	if s.disabledReason != "" {
+		return sch, fmt.Errorf("voice is disabled on this bot (%s)", s.disabledReason)
+	}
+	if !s.canExecuteCommand(sch.BookedBy) {
+		return sch, errors.New("user does not have permission to use voice commands")
+	}
+	if !sch.StartAt.After(time.Now()) {
+		return sch, errors.New("the start time must be in the future")
+	}
+	maxMinutes := s.cfg.MaxSessionLength
+	if maxMinutes <= 0 {
+		maxMinutes = int(DefaultMaxSessionLength / time.Minute)
+	}
+	if sch.Minutes <= 0 {
+		sch.Minutes = maxMinutes
+	}
+	if sch.Minutes > maxMinutes {
+		return sch, fmt.Errorf("scheduled sessions can last at most %d minutes", maxMinutes)
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return sch, fmt.Errorf("failed to create schedule ID: %w", err)
+	}
+	sch.ID = hex.EncodeToString(id)
+	sch.StartAt = sch.StartAt.UTC().Truncate(time.Minute)
+
+	err := s.schedules.update(func(schedules []Schedule) ([]Schedule, error) {
+		booked := 0
+		for _, other := range schedules {
+			if other.GuildID == sch.GuildID {
+				booked++
+			}
+		}
+		if booked >= maxSchedulesPerGuild {
+			return nil, fmt.Errorf("this server already has %d scheduled sessions", maxSchedulesPerGuild)
+		}
+
+		return append(schedules, sch), nil
+	})
+	if err != nil {
+		return sch, err
+	}
+
+	s.logger.Info("Voice session scheduled",
+		zap.String("id", sch.ID),
+		zap.String("guild_id", sch.GuildID.String()),
+		zap.String("channel_id", sch.ChannelID.String()),
+		zap.Time("start_at", sch.StartAt),
+		zap.Bool("weekly", sch.Weekly))
+
+	return sch, nil
+}
+
+// Unschedule cancels the booking with id in guildID. Only the member who
+// booked it may cancel it.
+func (s *Service) Unschedule(guildID discord.GuildID, userID discord.UserID, id string) error {
+	return s.schedules.update(func(schedules []Schedule) ([]Schedule, error) {
+		i := slices.IndexFunc(schedules, func(sch Schedule) bool { return sch.ID == id && sch.GuildID == guildID })
+		if i < 0 {
+			return nil, ErrScheduleNotFound
+		}
+		if schedules[i].BookedBy != userID {
+			return nil, errors.New("user does not have permission to cancel this scheduled session")
+		}
+
+		return slices.Delete(schedules, i, i+1), nil
+	})
+}
+
+// Schedules returns the sessions booked in guildID, soonest first.
+func (s *Service) Schedules(guildID discord.GuildID) []Schedule {
+	s.schedules.mu.Lock()
+	defer s.schedules.mu.Unlock()
+
+	var schedules []Schedule
+	for _, sch := range s.schedules.schedules {
+		if sch.GuildID == guildID {
+			schedules = append(schedules, sch)
+		}
+	}
+	slices.SortFunc(schedules, func(a, b Schedule) int { return a.StartAt.Compare(b.StartAt) })
+
+	return schedules
+}
+
+// runScheduler starts booked sessions when they are due.
+func (s *Service) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, sch := range s.takeDueSchedules(time.Now()) {
+				s.startScheduled(ctx, sch)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// takeDueSchedules returns the bookings due at now that are recent enough to
+// start. One-off bookings are removed and weekly ones move to their next week.
+func (s *Service) takeDueSchedules(now time.Time) []Schedule {
+	var due []Schedule
+	err := s.schedules.update(func(schedules []Schedule) ([]Schedule, error) {
+		kept := schedules[:0]
+		for _, sch := range schedules {
+			if sch.StartAt.After(now) {
+				kept = append(kept, sch)
+
+				continue
+			}
+			if now.Sub(sch.StartAt) <= scheduleGrace {
+				due = append(due, sch)
+			} else {
+				s.logger.Warn("Skipping scheduled voice session that is too late to start",
+					zap.String("id", sch.ID),
+					zap.Time("start_at", sch.StartAt))
+			}
+			if sch.Weekly {
+				for !sch.StartAt.After(now) {
+					sch.StartAt = sch.StartAt.AddDate(0, 0, 7)
+				}
+				kept = append(kept, sch)
+			}
+		}
+		if len(due) == 0 && len(kept) == len(schedules) {
+			return nil, errNothingDue
+		}
+
+		return kept, nil
+	})
+	if err != nil && !errors.Is(err, errNothingDue) {
+		s.logger.Error("Failed to update voice schedules", zap.Error(err))
+	}
+
+	return due
+}
+
+// errNothingDue leaves the schedules file untouched when no booking is due.
+var errNothingDue = errors.New("no scheduled sessions are due")
+
+// startScheduled starts the booked session sch and pings its role.
+func (s *Service) startScheduled(ctx context.Context, sch Schedule) {
+	s.logger.Info("Starting scheduled voice session",
+		zap.String("id", sch.ID),
+		zap.String("guild_id", sch.GuildID.String()),
+		zap.String("channel_id", sch.ChannelID.String()))
+
+	_, err := s.Start(ctx, sch.GuildID, sch.ChannelID, sch.TextChannelID, sch.BookedBy, StartOptions{
+		MaxLength: time.Duration(sch.Minutes) * time.Minute,
+	})
+
+	var msg string
+	mentions := &api.AllowedMentions{}
+	switch {
+	case err != nil:
+		s.logger.Error("Failed to start scheduled voice session", zap.Error(err), zap.String("id", sch.ID))
+		msg = fmt.Sprintf("❌ The scheduled voice session in %s could not start: %s", sch.ChannelID.Mention(), err)
+	case sch.RoleID.IsValid():
+		msg = fmt.Sprintf("⏰ %s, the scheduled voice session has started in %s for %d minutes.",
+			sch.RoleID.Mention(), sch.ChannelID.Mention(), sch.Minutes)
+		mentions.Roles = []discord.RoleID{sch.RoleID}
+	default:
+		msg = fmt.Sprintf("⏰ The scheduled voice session has started in %s for %d minutes.", sch.ChannelID.Mention(), sch.Minutes)
+	}
+
+	if s.discordSession == nil || !sch.TextChannelID.IsValid() {
+		return
+	}
+	if _, err := s.discordSession.SendMessageComplex(sch.TextChannelID, api.SendMessageData{
+		Content:         msg,
+		AllowedMentions: mentions,
+	}); err != nil {
+		s.logger.Warn("Failed to announce scheduled voice session", zap.Error(err), zap.String("id", sch.ID))
+	}
+}
@@ -0,0 +1,30 @@
+package voice_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestParseScheduleTime(t *testing.T) {
+	now := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+
+	got, err := voice.ParseScheduleTime("2025-03-20 09:30", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 20, 9, 30, 0, 0, time.UTC), got)
+
+	got, err = voice.ParseScheduleTime("11:15", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 14, 11, 15, 0, 0, time.UTC), got, "a later time of day is today")
+
+	got, err = voice.ParseScheduleTime("09:30", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 15, 9, 30, 0, 0, time.UTC), got, "a passed time of day is tomorrow")
+
+	_, err = voice.ParseScheduleTime("next friday", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+package voice
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+// runStreamingLoop is the audio loop of server VAD sessions. Rather than
+// committing turns on a silence timer, it streams the mixed audio to OpenAI
+// every serverVADStreamInterval, and the server's turn detection commits
+// each turn as its speaker stops. Turns are always streamed mixed, whatever
+// the commit mode.
+func (s *Service) runStreamingLoop(ctx context.Context, voiceSession *VoiceSession, audioChannel <-chan *AudioPacket) {
+	ticker := time.NewTicker(serverVADStreamInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Started audio streaming loop for server VAD",
+		zap.String("guild_id", voiceSession.GuildID.String()),
+		zap.Duration("stream_interval", serverVADStreamInterval))
+
+	for {
+		select {
+		case packet, ok := <-audioChannel:
+			if !ok || packet == nil {
+				s.logger.Debug("Audio channel closed, exiting processAudio")
+
+				return
+			}
+
+			s.processAudioPacket(voiceSession, packet)
+			if s.load.Level() >= QualityBatched {
+				if _, closed := s.processQueued(voiceSession, audioChannel); closed {
+					s.logger.Debug("Audio channel closed, exiting processAudio")
+
+					return
+				}
+			}
+			s.touchSession(voiceSession)
+
+		case <-ticker.C:
+			s.streamMixerAudio(ctx, voiceSession)
+
+		case <-ctx.Done():
+			if err := s.endSession(ctx, voiceSession, "context canceled"); err != nil {
+				s.logger.Error("failed to end session", zap.Error(err))
+			}
+
+			return
+		}
+	}
+}
+
+// streamMixerAudio appends the audio mixed since the last tick to OpenAI's
+// input buffer without committing it. The noise gate holds silent frames
+// back, so while a turn may still be going on silence is streamed in their
+// place, for the server to hear the speaker stop.
+func (s *Service) streamMixerAudio(ctx context.Context, voiceSession *VoiceSession) {
+	mixed := s.audioMixer.Drain()
+
+	now := time.Now()
+	voiceSession.mu.Lock()
+	if len(mixed) > 0 {
+		voiceSession.vadPadUntil = now.Add(serverVADPadding)
+	}
+	pad := voiceSession.vadSpeaking || now.Before(voiceSession.vadPadUntil)
+	voiceSession.mu.Unlock()
+
+	var pcm []int16
+	switch {
+	case len(mixed) > 0:
+		processStart := time.Now()
+		downsampled, err := voiceSession.pipeline.downsample(s.audioProcessor, voiceSession.pipeline.processMixed(mixed))
+		s.observeLoad(voiceSession, time.Since(processStart))
+		if err != nil {
+			s.logger.Error("Failed to downsample audio", zap.Error(err))
+
+			return
+		}
+		pcm = downsampled
+	case pad:
+		pcm = make([]int16, audio.OpenAISampleRate*serverVADStreamInterval/time.Second)
+	default:
+		return
+	}
+
+	audioBase64, err := s.audioProcessor.PCMToBase64(audio.PCMInt16ToLE(pcm))
+	if err != nil {
+		s.logger.Error("Failed to convert PCM to base64", zap.Error(err))
+
+		return
+	}
+	if err := s.realtimeProvider.SendAudio(ctx, audioBase64); err != nil {
+		s.logger.Error("Failed to stream audio to OpenAI", zap.Error(err))
+	}
+}
+
+// handleSpeechStarted barges in on the assistant when server VAD hears a
+// member start speaking over it.
+func (s *Service) handleSpeechStarted(voiceSession *VoiceSession) {
+	voiceSession.mu.Lock()
+	voiceSession.vadSpeaking = true
+	voiceSession.mu.Unlock()
+
+	if s.bargeInFrames() > 0 {
+		s.bargeIn(voiceSession)
+	}
+}
+
+// handleSpeechStopped stops streaming silence once server VAD has heard the
+// turn end.
+func (s *Service) handleSpeechStopped(voiceSession *VoiceSession) {
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	voiceSession.vadSpeaking = false
+	voiceSession.vadPadUntil = time.Time{}
+}
+
+// handleAudioCommitted answers a turn server VAD committed. Turns the bot
+// commits itself are answered as it commits them.
+func (s *Service) handleAudioCommitted(ctx context.Context, voiceSession *VoiceSession) {
+	if !voiceSession.ServerVAD {
+		return
+	}
+
+	now := time.Now()
+	voiceSession.mu.Lock()
+	voiceSession.LastAudioTime = now
+	voiceSession.mu.Unlock()
+	if voiceSession.Metrics != nil {
+		voiceSession.Metrics.MarkTurnCommitted()
+		voiceSession.Metrics.MarkStage(StageTurnDetect, now)
+	}
+	s.attributeTurn(voiceSession)
+
+	s.logger.Info("Server VAD committed a turn",
+		zap.String("guild_id", voiceSession.GuildID.String()))
+
+	if err := s.answerTurn(ctx, voiceSession); err != nil {
+		s.logger.Error("Failed to request response generation", zap.Error(err))
+	}
+}
+
+// interruptPlayback stops the assistant's playback, fading out the frame
+// playing and dropping the audio queued after it. It reports whether
+// anything was playing.
+func (s *Service) interruptPlayback(voiceSession *VoiceSession) bool {
+	voiceSession.PlaybackMutex.Lock()
+	defer voiceSession.PlaybackMutex.Unlock()
+
+	if !voiceSession.PlaybackActive {
+		return false
+	}
+	voiceSession.stopPlayback()
+	for {
+		select {
+		case _, ok := <-voiceSession.AudioQueue:
+			if !ok {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+}
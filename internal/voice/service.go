@@ -4,87 +4,183 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internaldiscord "github.com/Raikerian/go-discord-chatgpt/internal/discord"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
 	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
 	"github.com/Raikerian/go-discord-chatgpt/pkg/openai"
 	"github.com/Raikerian/go-discord-chatgpt/pkg/util"
 
+	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/state"
 	"go.uber.org/zap"
 )
 
 type Service struct {
 	logger         *zap.Logger
 	cfg            *config.VoiceConfig
+	guilds         map[string]config.GuildConfig
 	discordSession *session.Session
+	state          *state.State
 	pricingService openai.PricingService
+	mentions       *internaldiscord.MentionPolicy
+	// disclosure returns the AI disclosure line configured for a guild
+	disclosure func(guildID string) string
 
 	voiceManager     DiscordManager
 	audioProcessor   audio.AudioProcessor
 	realtimeProvider RealtimeProvider
 	sessionManager   SessionManager
 	audioMixer       audio.AudioMixer
+	tts              TTSProvider
+	clock            PlaybackClock
+	load             LoadMonitor
+	encoders         *audio.EncoderPool
+	limiter          chat.RequestLimiter
+	metrics          *metrics.Metrics
+
+	// Who may start sessions and with which models, replaced when the
+	// config is reloaded
+	allowlists atomic.Pointer[allowlists]
+
+	// watchdogCancel for stopping the watchdog and scheduler goroutines
+	watchdogCancel context.CancelFunc
 
-	// Optimized lookups for permissions
-	allowedUsersMap  map[string]struct{}
-	allowedModelsMap map[string]struct{}
+	// Sessions booked with /voice schedule
+	schedules *scheduleStore
+	// Members who asked for text alternatives with /voice accessibility
+	accessibility *accessibilityStore
+	// Transcript of each guild's current or most recent session
+	history *TranscriptStore
 
-	// watchdogCancel for stopping the watchdog goroutine
-	watchdogCancel context.CancelFunc
+	// Voice announcements: guilds being spoken to, the channel each member
+	// is in, and when each member was last announced
+	announcing    sync.Map // discord.GuildID -> struct{}
+	voiceChannels sync.Map // memberKey -> discord.ChannelID
+	lastAnnounced sync.Map // memberKey -> time.Time
+
+	// pipeline arranges the audio stages of every session
+	pipeline config.AudioPipelineConfig
+
+	// disabledReason is set when preflight checks found voice unusable
+	disabledReason string
 }
 
 func NewService(
 	logger *zap.Logger,
 	cfg *config.Config,
 	sess *session.Session,
+	st *state.State,
+	intents gateway.Intents,
 	pricingService openai.PricingService,
+	mentions *internaldiscord.MentionPolicy,
 	voiceManager DiscordManager,
 	audioProcessor audio.AudioProcessor,
 	realtimeProvider RealtimeProvider,
 	sessionManager SessionManager,
 	audioMixer audio.AudioMixer,
+	tts TTSProvider,
+	clock PlaybackClock,
+	load LoadMonitor,
+	encoders *audio.EncoderPool,
+	limiter chat.RequestLimiter,
+	storageProvider storage.Provider,
+	m *metrics.Metrics,
+	configs config.Provider,
 ) *Service {
-	// Convert slices to maps for O(1) lookups
-	allowedUsersMap := make(map[string]struct{}, len(cfg.Voice.AllowedUserIDs))
-	for _, id := range cfg.Voice.AllowedUserIDs {
-		allowedUsersMap[id] = struct{}{}
-	}
-
-	allowedModelsMap := make(map[string]struct{}, len(cfg.Voice.AllowedModels))
-	for _, model := range cfg.Voice.AllowedModels {
-		allowedModelsMap[model] = struct{}{}
-	}
-
 	s := &Service{
 		logger:           logger,
 		cfg:              &cfg.Voice,
+		guilds:           cfg.Guilds,
 		discordSession:   sess,
+		state:            st,
 		pricingService:   pricingService,
+		mentions:         mentions,
+		disclosure:       cfg.DisclosureText,
 		voiceManager:     voiceManager,
 		audioProcessor:   audioProcessor,
 		realtimeProvider: realtimeProvider,
 		sessionManager:   sessionManager,
 		audioMixer:       audioMixer,
-		allowedUsersMap:  allowedUsersMap,
-		allowedModelsMap: allowedModelsMap,
+		tts:              tts,
+		clock:            clock,
+		load:             load,
+		encoders:         encoders,
+		limiter:          limiter,
+		metrics:          m,
+		history:          NewTranscriptStore(&cfg.Voice),
+	}
+	s.allowlists.Store(newAllowlists(cfg.Voice))
+	configs.Subscribe(func(_, current *config.Config) {
+		lists := newAllowlists(current.Voice)
+		s.allowlists.Store(lists)
+		logger.Info("Voice allowlists reloaded",
+			zap.Int("allowed_users", len(lists.users)),
+			zap.Strings("allowed_models", lists.models))
+	})
+
+	if missing := internaldiscord.VoiceFeature.MissingIntents(intents); missing != 0 {
+		s.disabledReason = "missing gateway intents: " + strings.Join(internaldiscord.IntentNames(missing), ", ")
+		logger.Warn("Voice sessions disabled; add the missing intents to discord.intents in config",
+			zap.String("reason", s.disabledReason))
+	}
+	if mode := cfg.Voice.CommitMode; mode != "" && mode != CommitModeMixed && mode != CommitModeDominantSpeaker {
+		logger.Warn("Unknown voice commit_mode, committing mixed audio", zap.String("commit_mode", mode))
+	}
+	if _, err := newPipeline(cfg.Voice.Pipeline); err != nil {
+		logger.Warn("Invalid voice pipeline, processing audio without its stages", zap.Error(err))
+	} else {
+		s.pipeline = cfg.Voice.Pipeline
+	}
+
+	schedules, err := loadScheduleStore(storageProvider, cfg.Voice.ScheduleFile)
+	if err != nil {
+		logger.Error("Failed to load scheduled voice sessions, starting without them", zap.Error(err))
+	}
+	s.schedules = schedules
+
+	accessibility, err := loadAccessibilityStore(storageProvider, cfg.Voice.AccessibilityFile)
+	if err != nil {
+		logger.Error("Failed to load voice accessibility preferences, starting without them", zap.Error(err))
 	}
+	s.accessibility = accessibility
 
-	// Start watchdog
+	// Track participants joining and leaving session channels
+	sess.AddHandler(s.handleVoiceStateUpdate)
+
+	// Start watchdog and scheduler
 	ctx, cancel := context.WithCancel(context.Background())
 	s.watchdogCancel = cancel
 	go s.runWatchdog(ctx)
+	go s.runScheduler(ctx)
 
 	return s
 }
 
-func (s *Service) Start(ctx context.Context, guildID discord.GuildID, channelID, textChannelID discord.ChannelID, initiatorID discord.UserID, model string) (*VoiceSession, error) {
+func (s *Service) Start(ctx context.Context, guildID discord.GuildID, channelID, textChannelID discord.ChannelID, initiatorID discord.UserID, opts StartOptions) (*VoiceSession, error) {
+	model, language := opts.Model, opts.Language
+
+	if s.disabledReason != "" {
+		return nil, fmt.Errorf("voice is disabled on this bot (%s)", s.disabledReason)
+	}
+
 	// Check if session already exists for this guild
 	if _, err := s.sessionManager.GetSessionByGuild(guildID); err == nil {
 		return nil, errors.New("voice session already active in this guild")
 	}
+	if _, busy := s.announcing.Load(guildID); busy {
+		return nil, errAnnouncementBusy
+	}
 
 	// Check user permissions
 	if !s.canExecuteCommand(initiatorID) {
@@ -94,7 +190,7 @@ func (s *Service) Start(ctx context.Context, guildID discord.GuildID, channelID,
 	// Check concurrent session limit
 	sessionCount := s.sessionManager.GetSessionCount()
 	if sessionCount >= s.cfg.MaxConcurrentSessions {
-		return nil, fmt.Errorf("maximum concurrent sessions reached (%d)", s.cfg.MaxConcurrentSessions)
+		return nil, fmt.Errorf("%w (%d)", ErrMaxSessionsReached, s.cfg.MaxConcurrentSessions)
 	}
 
 	// Use default model if not specified
@@ -107,11 +203,52 @@ func (s *Service) Start(ctx context.Context, guildID discord.GuildID, channelID,
 		return nil, fmt.Errorf("model %s is not allowed", model)
 	}
 
+	// Use default language if not specified; empty means auto-detect
+	if language == "" {
+		language = s.cfg.Language
+	}
+	language, err := normalizeLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := VoiceStyles[opts.Style]; opts.Style != "" && !ok {
+		return nil, fmt.Errorf("unknown voice style %q", opts.Style)
+	}
+	if t := opts.Temperature; t != 0 && (t < MinTemperature || t > MaxTemperature) {
+		return nil, fmt.Errorf("temperature must be between %.1f and %.1f", MinTemperature, MaxTemperature)
+	}
+
+	// Check everything the session needs before any of it is set up
+	if err := s.preflight(ctx, guildID, channelID, model, opts.TextOnly); err != nil {
+		return nil, err
+	}
+
 	// Create session using session manager
 	voiceSession, err := s.sessionManager.CreateSession(guildID, channelID, textChannelID, initiatorID, model)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
+	voiceSession.Silence = audio.NewSilenceDetector(s.silenceSettings(guildID))
+	voiceSession.Gate = audio.NewNoiseGate(voiceSession.Silence, DefaultNoiseGateHold)
+	voiceSession.Language = language
+	voiceSession.ManualTurns = opts.ManualTurns
+	// Manual turns are committed on request, so server VAD must not commit them
+	voiceSession.ServerVAD = s.cfg.VADMode == VADModeServer && !opts.ManualTurns
+	voiceSession.TextOnly = opts.TextOnly
+	voiceSession.Style = opts.Style
+	voiceSession.Temperature = opts.Temperature
+	voiceSession.delivery = NewResponseDelivery(s.cfg, s.guilds[guildID.String()].Voice)
+	voiceSession.MaxLength = opts.MaxLength
+	voiceSession.AddressNames = s.addressNames(guildID.String())
+	if s.liveCaptionsEnabled(guildID) {
+		voiceSession.captions = &liveCaptions{}
+	}
+	if s.postTranscriptsEnabled(guildID) {
+		voiceSession.transcripts = newTranscriptRelay()
+	}
+	// The pipeline was checked when the service was created
+	voiceSession.pipeline, _ = newPipeline(s.pipeline)
+	voiceSession.codecs = newSessionCodecs(s.encoders)
 
 	// Join voice channel
 	_, err = s.voiceManager.JoinChannel(ctx, channelID)
@@ -124,7 +261,7 @@ func (s *Service) Start(ctx context.Context, guildID discord.GuildID, channelID,
 	}
 
 	// Connect to OpenAI Realtime
-	connection, err := s.realtimeProvider.Connect(ctx, model)
+	connection, err := s.realtimeProvider.Connect(ctx, s.connectOptions(voiceSession))
 	if err != nil {
 		if leaveErr := s.voiceManager.LeaveChannel(ctx, channelID); leaveErr != nil {
 			s.logger.Error("failed to leave voice channel", zap.Error(leaveErr))
@@ -139,6 +276,16 @@ func (s *Service) Start(ctx context.Context, guildID discord.GuildID, channelID,
 	if err := s.sessionManager.SetConnection(guildID, connection); err != nil {
 		return nil, fmt.Errorf("failed to set session connection: %w", err)
 	}
+
+	// Let the model know who is in the channel
+	s.seedParticipants(voiceSession)
+	voiceSession.mu.Lock()
+	instructions := sessionInstructions(voiceSession)
+	voiceSession.mu.Unlock()
+	if err := s.realtimeProvider.UpdateInstructions(ctx, instructions); err != nil {
+		s.logger.Warn("Failed to set session instructions", zap.Error(err))
+	}
+
 	if err := s.sessionManager.UpdateSessionState(guildID, SessionStateActive); err != nil {
 		return nil, fmt.Errorf("failed to update session state: %w", err)
 	}
@@ -150,12 +297,26 @@ func (s *Service) Start(ctx context.Context, guildID discord.GuildID, channelID,
 	}
 
 	// Start audio processing loop
+	s.startCapture(voiceSession)
 	go s.processAudio(sessionCtx, voiceSession)
+	if voiceSession.captions != nil {
+		go s.runCaptions(sessionCtx, voiceSession)
+	}
+	if voiceSession.transcripts != nil {
+		go s.runTranscripts(sessionCtx, voiceSession)
+	}
+	s.history.Begin(guildID, channelID, voiceSession.StartTime)
 
 	s.logger.Info("Voice session started",
 		zap.String("guild_id", guildID.String()),
 		zap.String("channel_id", channelID.String()),
-		zap.String("model", model))
+		zap.String("model", model),
+		zap.String("language", language),
+		zap.Bool("manual_turns", opts.ManualTurns),
+		zap.Bool("text_only", opts.TextOnly),
+		zap.String("style", opts.Style),
+		zap.Float32("temperature", opts.Temperature))
+	s.metrics.VoiceSessionStarted()
 
 	return voiceSession, nil
 }
@@ -174,6 +335,18 @@ func (s *Service) Stop(ctx context.Context, guildID discord.GuildID, userID disc
 	return s.endSession(ctx, voiceSession, "stopped by user")
 }
 
+// Transcript returns the transcript of the current or most recent voice
+// session in guildID, and whether there is one.
+func (s *Service) Transcript(guildID discord.GuildID) (SessionTranscript, bool) {
+	return s.history.Get(guildID)
+}
+
+// DisabledReason returns why voice sessions cannot start on this bot, empty
+// when they can.
+func (s *Service) DisabledReason() string {
+	return s.disabledReason
+}
+
 func (s *Service) GetStatus(guildID discord.GuildID) (*SessionStatus, error) {
 	voiceSession, err := s.sessionManager.GetSessionByGuild(guildID)
 	if err != nil {
@@ -188,26 +361,122 @@ func (s *Service) GetStatus(guildID discord.GuildID) (*SessionStatus, error) {
 
 	status := &SessionStatus{
 		Active:      true,
+		State:       voiceSession.State,
 		GuildID:     voiceSession.GuildID,
 		ChannelID:   voiceSession.ChannelID,
 		StartTime:   voiceSession.StartTime,
 		ActiveUsers: activeUsers,
 		SessionCost: voiceSession.SessionCost,
 		Model:       voiceSession.Model,
+		Language:    voiceSession.Language,
+		ManualTurns: voiceSession.ManualTurns,
+		TextOnly:    voiceSession.TextOnly,
 	}
 	voiceSession.mu.Unlock()
 
+	if voiceSession.Metrics != nil {
+		status.Metrics = voiceSession.Metrics.Snapshot()
+	}
+	if voiceSession.Silence != nil {
+		status.SilenceThreshold = voiceSession.Silence.Threshold()
+		status.SilenceDuration = voiceSession.Silence.Duration()
+	}
+
 	return status, nil
 }
 
+// Tune adjusts silence detection for the active session in guildID without
+// restarting it. Nil arguments leave the corresponding setting unchanged.
+// Only the session's controller may tune a session.
+func (s *Service) Tune(guildID discord.GuildID, userID discord.UserID, threshold *float32, duration *time.Duration) (*SessionStatus, error) {
+	voiceSession, err := s.sessionManager.GetSessionByGuild(guildID)
+	if err != nil {
+		return nil, errors.New("no active voice session in this guild")
+	}
+
+	if !voiceSession.isController(userID) {
+		return nil, errors.New("user does not have permission to tune this session")
+	}
+
+	if voiceSession.Silence == nil {
+		return nil, errors.New("silence detection is not available for this session")
+	}
+
+	if threshold != nil && (*threshold < 0 || *threshold > 1) {
+		return nil, fmt.Errorf("silence threshold must be between 0 and 1, got %g", *threshold)
+	}
+	if duration != nil && (*duration < MinSilenceDuration || *duration > MaxSilenceDuration) {
+		return nil, fmt.Errorf("silence duration must be between %s and %s, got %s", MinSilenceDuration, MaxSilenceDuration, *duration)
+	}
+
+	if threshold != nil {
+		voiceSession.Silence.SetThreshold(*threshold)
+	}
+	if duration != nil {
+		voiceSession.Silence.SetDuration(*duration)
+	}
+
+	s.logger.Info("Voice session tuned",
+		zap.String("guild_id", guildID.String()),
+		zap.String("user_id", userID.String()),
+		zap.Float32("silence_threshold", voiceSession.Silence.Threshold()),
+		zap.Duration("silence_duration", voiceSession.Silence.Duration()))
+
+	return s.GetStatus(guildID)
+}
+
+// CommitTurn asks the session in guildID to send its buffered audio to OpenAI
+// immediately. Only the session's controller may commit turns.
+func (s *Service) CommitTurn(guildID discord.GuildID, userID discord.UserID) error {
+	voiceSession, err := s.sessionManager.GetSessionByGuild(guildID)
+	if err != nil {
+		return errors.New("no active voice session in this guild")
+	}
+
+	if !voiceSession.isController(userID) {
+		return errors.New("user does not have permission to commit turns in this session")
+	}
+
+	// A pending request already covers everything buffered so far
+	select {
+	case voiceSession.turnRequests <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// silenceSettings resolves the silence threshold and duration for guildID,
+// preferring guild overrides, then global config, then package defaults.
+func (s *Service) silenceSettings(guildID discord.GuildID) (float32, time.Duration) {
+	threshold := s.cfg.SilenceThreshold
+	if threshold <= 0 {
+		threshold = DefaultSilenceThreshold
+	}
+	duration := time.Duration(s.cfg.SilenceDuration) * time.Millisecond
+	if duration <= 0 {
+		duration = DefaultSilenceDuration
+	}
+
+	override := s.guilds[guildID.String()].Voice
+	if override.SilenceThreshold != nil {
+		threshold = *override.SilenceThreshold
+	}
+	if override.SilenceDuration != nil {
+		duration = time.Duration(*override.SilenceDuration) * time.Millisecond
+	}
+
+	return threshold, duration
+}
+
 func (s *Service) canExecuteCommand(userID discord.UserID) bool {
 	// Check allowed users list
 	return s.isAllowedUser(userID)
 }
 
 func (s *Service) canStopSession(userID discord.UserID, voiceSession *VoiceSession) bool {
-	// Check if user is initiator
-	if voiceSession.InitiatorID == userID {
+	// Check if user controls the session
+	if voiceSession.isController(userID) {
 		return true
 	}
 
@@ -219,23 +488,103 @@ func (s *Service) canStopSession(userID discord.UserID, voiceSession *VoiceSessi
 	return false
 }
 
+// allowlists are the users who may start voice sessions and the realtime
+// models sessions may use. Empty lists allow everyone and every model.
+type allowlists struct {
+	users     map[string]struct{}
+	models    []string
+	modelsSet map[string]struct{}
+}
+
+func newAllowlists(cfg config.VoiceConfig) *allowlists {
+	// Convert slices to maps for O(1) lookups
+	lists := &allowlists{
+		users:     make(map[string]struct{}, len(cfg.AllowedUserIDs)),
+		models:    cfg.AllowedModels,
+		modelsSet: make(map[string]struct{}, len(cfg.AllowedModels)),
+	}
+	for _, id := range cfg.AllowedUserIDs {
+		lists.users[id] = struct{}{}
+	}
+	for _, model := range cfg.AllowedModels {
+		lists.modelsSet[model] = struct{}{}
+	}
+
+	return lists
+}
+
 func (s *Service) isAllowedUser(userID discord.UserID) bool {
-	if len(s.allowedUsersMap) == 0 {
+	users := s.allowlists.Load().users
+	if len(users) == 0 {
 		return true // If no restrictions, allow everyone
 	}
 
 	userIDStr := userID.String()
-	_, allowed := s.allowedUsersMap[userIDStr]
+	_, allowed := users[userIDStr]
 
 	return allowed
 }
 
+// connectOptions returns the realtime connection settings of voiceSession.
+func (s *Service) connectOptions(voiceSession *VoiceSession) ConnectOptions {
+	var silence time.Duration
+	if voiceSession.Silence != nil {
+		silence = voiceSession.Silence.Duration()
+	}
+
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	return ConnectOptions{
+		Model:    voiceSession.Model,
+		Language: voiceSession.Language,
+		TextOnly: voiceSession.TextOnly,
+		GuildID:  voiceSession.GuildID,
+		Voice:    voiceSession.Voice,
+
+		Temperature:     voiceSession.Temperature,
+		MaxOutputTokens: voiceSession.delivery.MaxOutputTokens(),
+
+		ServerVAD:       voiceSession.ServerVAD,
+		SilenceDuration: silence,
+	}
+}
+
+// awaitsTranscript reports whether turns of voiceSession are answered only
+// once their transcript is in, to check it is addressed to the bot and not a
+// voice command or said while paused. Manual turns are always answered.
+func (s *Service) awaitsTranscript(voiceSession *VoiceSession) bool {
+	voiceSession.mu.Lock()
+	defer voiceSession.mu.Unlock()
+
+	if voiceSession.ManualTurns {
+		return false
+	}
+
+	return len(voiceSession.AddressNames) > 0 || s.cfg.VoiceCommands || voiceSession.paused
+}
+
+// answerTurn requests the answer to the turn just committed. Turns awaiting
+// their transcript are answered, or not, once it is in.
+func (s *Service) answerTurn(ctx context.Context, voiceSession *VoiceSession) error {
+	if !s.awaitsTranscript(voiceSession) {
+		return s.generateResponse(ctx, voiceSession)
+	}
+
+	voiceSession.mu.Lock()
+	voiceSession.awaitingAddress++
+	voiceSession.mu.Unlock()
+
+	return nil
+}
+
 func (s *Service) isModelAllowed(model string) bool {
-	if len(s.allowedModelsMap) == 0 {
+	models := s.allowlists.Load().modelsSet
+	if len(models) == 0 {
 		return true // If no restrictions, allow all models
 	}
 
-	_, allowed := s.allowedModelsMap[model]
+	_, allowed := models[model]
 
 	return allowed
 }
@@ -260,14 +609,18 @@ func (s *Service) processAudio(ctx context.Context, voiceSession *VoiceSession)
 
 func (s *Service) setupAudioHandlers(ctx context.Context, voiceSession *VoiceSession) error {
 	handlers := ResponseHandlers{
-		OnAudioDelta: func(ctx context.Context, audioData []byte) {
+		// Playback follows the session context, so stopping fades it out
+		OnAudioDelta: func(_ context.Context, audioData []byte) {
 			s.handleAudioResponse(ctx, voiceSession, audioData)
 		},
 		OnTranscript: func(ctx context.Context, transcript string) {
 			s.handleTranscript(voiceSession, transcript)
 		},
+		OnText: func(ctx context.Context, text string) {
+			s.handleTextResponse(voiceSession, text)
+		},
 		OnUserTranscript: func(ctx context.Context, transcript string) {
-			s.handleUserTranscript(voiceSession, transcript)
+			s.handleUserTranscript(ctx, voiceSession, transcript)
 		},
 		OnResponseDone: func(ctx context.Context, usage *Usage) {
 			s.handleResponseDone(ctx, voiceSession, usage)
@@ -275,6 +628,21 @@ func (s *Service) setupAudioHandlers(ctx context.Context, voiceSession *VoiceSes
 		OnError: func(ctx context.Context, err error) {
 			s.logger.Error("OpenAI Realtime error", zap.Error(err))
 		},
+		OnItemCreated: func(ctx context.Context, item ConversationItem) {
+			s.trackItem(voiceSession, item)
+		},
+		OnItemText: func(ctx context.Context, itemID, text string) {
+			s.trackItemText(voiceSession, itemID, text)
+		},
+		OnSpeechStarted: func(ctx context.Context) {
+			s.handleSpeechStarted(voiceSession)
+		},
+		OnSpeechStopped: func(ctx context.Context) {
+			s.handleSpeechStopped(voiceSession)
+		},
+		OnAudioCommitted: func(ctx context.Context) {
+			s.handleAudioCommitted(ctx, voiceSession)
+		},
 	}
 
 	err := s.realtimeProvider.SetResponseHandlers(handlers)
@@ -291,8 +659,17 @@ func (s *Service) setupAudioHandlers(ctx context.Context, voiceSession *VoiceSes
 }
 
 func (s *Service) runAudioLoop(ctx context.Context, voiceSession *VoiceSession, audioChannel <-chan *AudioPacket) {
+	if voiceSession.ServerVAD {
+		s.runStreamingLoop(ctx, voiceSession, audioChannel)
+
+		return
+	}
+
 	// Use a debouncer for clean timeout handling
-	timeoutDuration := time.Duration(s.cfg.SilenceDuration) * time.Millisecond
+	_, timeoutDuration := s.silenceSettings(voiceSession.GuildID)
+	if voiceSession.Silence != nil {
+		timeoutDuration = voiceSession.Silence.Duration()
+	}
 	debouncer := util.NewDebouncer(timeoutDuration)
 	defer debouncer.Stop()
 
@@ -309,13 +686,44 @@ func (s *Service) runAudioLoop(ctx context.Context, voiceSession *VoiceSession,
 				return
 			}
 
-			s.processAudioPacket(voiceSession, packet)
+			speech := s.processAudioPacket(voiceSession, packet)
+			if s.load.Level() >= QualityBatched {
+				// Under load, packets already waiting are handled together
+				batchSpeech, closed := s.processQueued(voiceSession, audioChannel)
+				if closed {
+					s.logger.Debug("Audio channel closed, exiting processAudio")
+
+					return
+				}
+				speech = speech || batchSpeech
+			}
+			s.touchSession(voiceSession)
+
+			// Only speech extends the turn; silent frames let the debouncer fire.
+			if !speech {
+				continue
+			}
+
+			// Pick up duration changes from /voice tune
+			if voiceSession.Silence != nil {
+				if d := voiceSession.Silence.Duration(); d != timeoutDuration {
+					timeoutDuration = d
+					debouncer.SetDuration(d)
+				}
+			}
 			debouncer.Reset()
 
 		case <-debouncer.C():
+			if voiceSession.ManualTurns {
+				continue
+			}
 			s.logger.Info("Audio timeout reached, committing audio")
 			s.commitMixerAudio(ctx, voiceSession)
 
+		case <-voiceSession.turnRequests:
+			s.logger.Info("Turn requested, committing audio")
+			s.commitMixerAudio(ctx, voiceSession)
+
 		case <-ctx.Done():
 			if err := s.endSession(ctx, voiceSession, "context canceled"); err != nil {
 				s.logger.Error("failed to end session", zap.Error(err))
@@ -326,7 +734,40 @@ func (s *Service) runAudioLoop(ctx context.Context, voiceSession *VoiceSession,
 	}
 }
 
-func (s *Service) processAudioPacket(voiceSession *VoiceSession, packet *AudioPacket) {
+// processQueued processes the packets already waiting in audioChannel, up to
+// a batch of loadBatchSize with the one just received. It reports whether any
+// contained speech, and whether the channel was closed.
+func (s *Service) processQueued(voiceSession *VoiceSession, audioChannel <-chan *AudioPacket) (speech, closed bool) {
+	for range loadBatchSize - 1 {
+		select {
+		case packet, ok := <-audioChannel:
+			if !ok || packet == nil {
+				return speech, true
+			}
+			if s.processAudioPacket(voiceSession, packet) {
+				speech = true
+			}
+		default:
+			return speech, false
+		}
+	}
+
+	return speech, false
+}
+
+// touchSession marks the session as having just received audio.
+func (s *Service) touchSession(voiceSession *VoiceSession) {
+	if err := s.sessionManager.UpdateActivity(voiceSession.GuildID); err != nil {
+		s.logger.Warn("failed to update session activity", zap.Error(err))
+	}
+	if err := s.sessionManager.UpdateAudioTime(voiceSession.GuildID); err != nil {
+		s.logger.Warn("failed to update session audio time", zap.Error(err))
+	}
+}
+
+// processAudioPacket decodes a packet into the mixer and reports whether it contained speech.
+// Frames the speaker's noise gate holds back are not mixed at all.
+func (s *Service) processAudioPacket(voiceSession *VoiceSession, packet *AudioPacket) bool {
 	s.logger.Debug("Processing audio packet",
 		zap.String("user_id", packet.UserID.String()),
 		zap.Uint32("ssrc", packet.SSRC),
@@ -334,30 +775,53 @@ func (s *Service) processAudioPacket(voiceSession *VoiceSession, packet *AudioPa
 		zap.Uint32("rtp_timestamp", packet.RTPTimestamp),
 		zap.Uint16("sequence", packet.Sequence))
 
-	pcm, err := s.audioProcessor.OpusToPCM48(packet.Opus)
+	s.capturePacket(voiceSession, packet)
+	if voiceSession.Metrics != nil {
+		voiceSession.Metrics.ObservePacket(packet)
+	}
+
+	processStart := time.Now()
+	defer func() { s.observeLoad(voiceSession, time.Since(processStart)) }()
+
+	pcm, err := voiceSession.codecs.decode(packet.SSRC, packet.Opus)
 	if err != nil {
 		s.logger.Error("Failed to convert Opus to PCM",
 			zap.Error(err),
 			zap.String("user_id", packet.UserID.String()))
 
-		return
+		return false
 	}
+	pcm = voiceSession.pipeline.processStream(packet.SSRC, pcm, s.load.Level())
+
+	admit, speech := voiceSession.Gate.Admit(packet.SSRC, pcm)
+	s.watchForBargeIn(voiceSession, packet.SSRC, speech)
+	if !admit {
+		s.logger.Debug("Noise gate held back silent frame",
+			zap.String("user_id", packet.UserID.String()),
+			zap.Uint32("ssrc", packet.SSRC))
 
+		return false
+	}
+	if speech && voiceSession.Metrics != nil {
+		at := packet.ReceivedAt
+		if at.IsZero() {
+			at = time.Now()
+		}
+		voiceSession.Metrics.ObserveSpeech(packet.UserID, at)
+	}
+
+	mixStart := time.Now()
 	err = s.audioMixer.AddFrame(packet.SSRC, packet.RTPTimestamp, pcm)
+	s.metrics.ObserveMixer("add", time.Since(mixStart), err)
+	if voiceSession.Metrics != nil {
+		voiceSession.Metrics.ObserveMixer(time.Since(mixStart))
+	}
 	if err != nil {
 		s.logger.Warn("Failed to push frame to mixer",
 			zap.Error(err),
 			zap.String("user_id", packet.UserID.String()))
 	}
 
-	// Update session activity and audio time
-	if err := s.sessionManager.UpdateActivity(voiceSession.GuildID); err != nil {
-		s.logger.Warn("failed to update session activity", zap.Error(err))
-	}
-	if err := s.sessionManager.UpdateAudioTime(voiceSession.GuildID); err != nil {
-		s.logger.Warn("failed to update session audio time", zap.Error(err))
-	}
-
 	// Update session ActiveUsers
 	voiceSession.mu.Lock()
 	voiceSession.ActiveUsers[packet.UserID] = &UserState{
@@ -370,11 +834,19 @@ func (s *Service) processAudioPacket(voiceSession *VoiceSession, packet *AudioPa
 	s.logger.Debug("Added audio to mixer",
 		zap.String("user_id", packet.UserID.String()),
 		zap.Uint32("rtp_timestamp", packet.RTPTimestamp))
+
+	return speech
 }
 
 // commitMixerAudio gets mixed audio from the mixer and sends it to OpenAI.
 func (s *Service) commitMixerAudio(ctx context.Context, voiceSession *VoiceSession) {
-	mixedAudio := s.audioMixer.Drain()
+	drainStart := time.Now()
+	mixedAudio, streams := s.drainTurn(voiceSession)
+	drainEnd := time.Now()
+	s.metrics.ObserveMixer("drain", drainEnd.Sub(drainStart), nil)
+	if voiceSession.Metrics != nil {
+		voiceSession.Metrics.ObserveMixer(drainEnd.Sub(drainStart))
+	}
 
 	// Check if we got any audio
 	if len(mixedAudio) == 0 {
@@ -383,36 +855,80 @@ func (s *Service) commitMixerAudio(ctx context.Context, voiceSession *VoiceSessi
 		return
 	}
 
+	// Avoid sending silence
+	if voiceSession.Silence != nil {
+		if isSilent, energy := voiceSession.Silence.IsSilent(mixedAudio); isSilent {
+			s.logger.Debug("Mixed audio is silent, skipping send", zap.Float32("energy", energy))
+
+			return
+		}
+	}
+
+	if voiceSession.Metrics != nil {
+		voiceSession.Metrics.MarkTurnCommitted()
+		voiceSession.Metrics.MarkStage(StageTurnDetect, drainStart)
+		voiceSession.Metrics.MarkStage(StageMix, drainEnd)
+	}
+
 	// Update LastAudioTime
 	voiceSession.mu.Lock()
 	voiceSession.LastAudioTime = time.Now()
 	voiceSession.mu.Unlock()
+	s.attributeTurn(voiceSession)
 
 	s.logger.Info("Committing mixer audio",
 		zap.String("guild_id", voiceSession.GuildID.String()),
 		zap.Int("audio_bytes", len(mixedAudio)))
 
-	// // Use DetectSilence from audio processor to avoid sending silence
-	// isSilent, energy := s.audioProcessor.DetectSilence(mixedAudio)
-	// if isSilent {
-	// 	s.logger.Debug("Mixed audio is silent, skipping send", zap.Float32("energy", energy))
-
-	// 	return
-	// }
-
 	s.logger.Debug("Mixed audio obtained",
 		zap.Int("size", len(mixedAudio)),
 		// zap.Float32("energy_level", energyLevel),
 		zap.Duration("actual_duration", time.Duration(len(mixedAudio)/48000*1000)))
 
 	// Continue with the rest of the processing
-	s.processMixedAudio(ctx, voiceSession, mixedAudio)
+	s.processMixedAudio(ctx, voiceSession, mixedAudio, streams)
+}
+
+// drainTurn drains the audio of the turn to commit. In dominant_speaker commit
+// mode this is the loudest speaker's own stream rather than the mix, which
+// transcribes better when people talk over each other; every speaker's
+// stream is returned too, so the others are still recorded.
+func (s *Service) drainTurn(voiceSession *VoiceSession) ([]int16, map[uint32][]int16) {
+	if s.cfg.CommitMode != CommitModeDominantSpeaker {
+		return s.audioMixer.Drain(), nil
+	}
+
+	ssrc, ok := s.audioMixer.GetDominantSpeaker()
+	mixed, streams := s.audioMixer.DrainStreams()
+	if !ok || len(streams[ssrc]) == 0 {
+		return mixed, streams
+	}
+
+	var userID discord.UserID
+	voiceSession.mu.Lock()
+	for _, user := range voiceSession.ActiveUsers {
+		if user.SSRC == ssrc {
+			userID = user.UserID
+
+			break
+		}
+	}
+	voiceSession.mu.Unlock()
+	s.logger.Debug("Committing dominant speaker's stream",
+		zap.Uint32("ssrc", ssrc),
+		zap.String("user_id", userID.String()),
+		zap.Int("speakers", len(streams)))
+
+	return streams[ssrc], streams
 }
 
-func (s *Service) processMixedAudio(ctx context.Context, voiceSession *VoiceSession, mixedAudio []int16) {
+func (s *Service) processMixedAudio(ctx context.Context, voiceSession *VoiceSession, mixedAudio []int16, streams map[uint32][]int16) {
 	s.logger.Info("Processing mixed audio",
 		zap.String("guild_id", voiceSession.GuildID.String()),
 		zap.Int("size", len(mixedAudio)))
+	processStart := time.Now()
+	mixedAudio = voiceSession.pipeline.processMixed(mixedAudio)
+	s.observeLoad(voiceSession, time.Since(processStart))
 
 	// DEBUG: Save audio to WAV files for debugging
 	// Set this to true to enable WAV file saving
@@ -422,11 +938,17 @@ func (s *Service) processMixedAudio(ctx context.Context, voiceSession *VoiceSess
 		if err := s.saveDebugWAV(mixedAudio, 48000, voiceSession.GuildID, "mixed"); err != nil {
 			s.logger.Error("Failed to save mixed audio WAV", zap.Error(err))
 		}
+		// And each speaker's own stream, when they were kept apart
+		for ssrc, stream := range streams {
+			if err := s.saveDebugWAV(stream, 48000, voiceSession.GuildID, fmt.Sprintf("ssrc%d", ssrc)); err != nil {
+				s.logger.Error("Failed to save speaker audio WAV", zap.Error(err), zap.Uint32("ssrc", ssrc))
+			}
+		}
 
 		return
 	}
 
-	downsampledAudio, err := s.audioProcessor.DownsamplePCM(mixedAudio, audio.DiscordSampleRate, audio.OpenAISampleRate)
+	downsampledAudio, err := voiceSession.pipeline.downsample(s.audioProcessor, mixedAudio)
 	if err != nil {
 		s.logger.Error("Failed to downsample audio", zap.Error(err))
 
@@ -460,13 +982,14 @@ func (s *Service) processMixedAudio(ctx context.Context, voiceSession *VoiceSess
 		return
 	}
 
-	// Request response generation
-	err = s.realtimeProvider.GenerateResponse(ctx)
-	if err != nil {
+	if err := s.answerTurn(ctx, voiceSession); err != nil {
 		s.logger.Error("Failed to request response generation", zap.Error(err))
 
 		return
 	}
+	if voiceSession.Metrics != nil {
+		voiceSession.Metrics.MarkStage(StageEncode, time.Now())
+	}
 
 	s.logger.Info("Audio successfully committed to OpenAI",
 		zap.Int("mixed_audio_size", len(mixedAudio)),
@@ -477,6 +1000,20 @@ func (s *Service) handleAudioResponse(ctx context.Context, voiceSession *VoiceSe
 	s.logger.Debug("Received audio chunk from OpenAI",
 		zap.Int("pcm_size", len(audioData)))
 
+	voiceSession.mu.Lock()
+	voiceSession.responding = true
+	drop := voiceSession.dropResponse
+	voiceSession.mu.Unlock()
+	if drop {
+		s.logger.Debug("Dropping audio of interrupted response", zap.Int("pcm_size", len(audioData)))
+
+		return
+	}
+
+	if voiceSession.Metrics != nil {
+		voiceSession.Metrics.MarkStage(StageFirstDelta, time.Now())
+	}
+
 	// Queue audio data for sequential playback to avoid interference
 	s.queueAudioForPlayback(ctx, voiceSession, audioData)
 }
@@ -497,9 +1034,12 @@ func (s *Service) queueAudioForPlayback(ctx context.Context, voiceSession *Voice
 
 	// Start playback worker if not already running
 	voiceSession.PlaybackMutex.Lock()
-	if !voiceSession.PlaybackActive {
+	if !voiceSession.PlaybackActive && ctx.Err() == nil {
+		playCtx, cancel := context.WithCancel(ctx)
 		voiceSession.PlaybackActive = true
-		go s.audioPlaybackWorker(ctx, voiceSession)
+		voiceSession.stopPlayback = cancel
+		voiceSession.playback.Add(1)
+		go s.audioPlaybackWorker(playCtx, voiceSession)
 	}
 	voiceSession.PlaybackMutex.Unlock()
 }
@@ -508,7 +1048,10 @@ func (s *Service) audioPlaybackWorker(ctx context.Context, voiceSession *VoiceSe
 	defer func() {
 		voiceSession.PlaybackMutex.Lock()
 		voiceSession.PlaybackActive = false
+		voiceSession.stopPlayback()
+		voiceSession.stopPlayback = nil
 		voiceSession.PlaybackMutex.Unlock()
+		voiceSession.playback.Done()
 		s.logger.Debug("Audio playback worker stopped")
 	}()
 
@@ -546,8 +1089,12 @@ func (s *Service) splitAndPlayAudio(ctx context.Context, voiceSession *VoiceSess
 
 	// Split audio into 20ms frames and send each frame with frame-paced timing
 	for offset := 0; offset < len(audioData); offset += frameSizeBytes {
-		// Calculate when this frame should be sent (frame-paced timing)
-		expectedFrameTime := frameStartTime.Add(time.Duration(frameIndex) * 20 * time.Millisecond)
+		// Cancelled responses fade out instead of stopping mid-sample
+		if ctx.Err() != nil {
+			s.fadeOut(voiceSession, audioData[offset:])
+
+			return
+		}
 
 		end := min(offset+frameSizeBytes, len(audioData))
 
@@ -567,7 +1114,9 @@ func (s *Service) splitAndPlayAudio(ctx context.Context, voiceSession *VoiceSess
 		}
 
 		// Convert this 20ms PCM frame to Opus for Discord
-		opusData, err := s.audioProcessor.PCM48MonoToOpus(audio.LEToPCMInt16(frameData))
+		encodeStart := time.Now()
+		opusData, err := voiceSession.codecs.encode(voiceSession.pipeline.processPlayback(audio.LEToPCMInt16(frameData)), s.load.Level())
+		s.observeLoad(voiceSession, time.Since(encodeStart))
 		if err != nil {
 			s.logger.Error("Failed to convert PCM frame to Opus",
 				zap.Error(err),
@@ -577,29 +1126,16 @@ func (s *Service) splitAndPlayAudio(ctx context.Context, voiceSession *VoiceSess
 			return
 		}
 
-		// Wait until the exact time this frame should be sent
-		now := time.Now()
-		if now.Before(expectedFrameTime) {
-			waitDuration := expectedFrameTime.Sub(now)
-			timer := time.NewTimer(waitDuration)
-			select {
-			case <-timer.C:
-				// Time to send frame
-			case <-ctx.Done():
-				timer.Stop()
-				s.logger.Debug("Context canceled during frame timing wait")
+		// Wait for the frame's slot on the clock shared by all sessions
+		slot, err := s.clock.Next(ctx)
+		if err != nil {
+			s.logger.Debug("Context canceled during frame timing wait")
+			s.fadeOut(voiceSession, audioData[offset:])
 
-				return
-			}
-			timer.Stop()
-		} else if now.Sub(expectedFrameTime) > 5*time.Millisecond {
-			// We're running late - log timing drift
-			s.logger.Warn("Frame timing drift detected",
-				zap.Int("frame_index", frameIndex),
-				zap.Duration("drift", now.Sub(expectedFrameTime)))
+			return
 		}
 
-		// Send frame to Discord at the precise expected time
+		// Send frame to Discord at its slot
 		sendStartTime := time.Now()
 		err = s.voiceManager.PlayAudio(ctx, voiceSession.ChannelID, opusData)
 		if err != nil {
@@ -611,24 +1147,21 @@ func (s *Service) splitAndPlayAudio(ctx context.Context, voiceSession *VoiceSess
 		}
 		sendDuration := time.Since(sendStartTime)
 
+		if voiceSession.Metrics != nil {
+			voiceSession.Metrics.ObservePlaybackDrift(sendStartTime.Sub(slot))
+			if stages, ok := voiceSession.Metrics.MarkAudioOut(sendStartTime); ok {
+				s.logLatency(voiceSession, stages)
+			}
+		}
+
 		s.logger.Debug("Sent audio frame to Discord",
 			zap.Int("frame_index", frameIndex),
 			zap.Int("pcm_frame_size", len(frameData)),
 			zap.Int("opus_frame_size", len(opusData)),
 			zap.Duration("send_duration", sendDuration),
-			zap.Time("expected_time", expectedFrameTime),
+			zap.Time("slot_time", slot),
 			zap.Time("actual_time", sendStartTime))
 
-		// Adjust frame timing to correct for accumulated drift
-		drift := time.Since(expectedFrameTime)
-		if drift > 5*time.Millisecond {
-			// Resync: push frameStartTime forward by drift to catch up
-			frameStartTime = frameStartTime.Add(drift)
-			s.logger.Debug("Corrected frame timing drift",
-				zap.Int("frame_index", frameIndex),
-				zap.Duration("drift", drift))
-		}
-
 		frameIndex++
 	}
 
@@ -639,26 +1172,115 @@ func (s *Service) splitAndPlayAudio(ctx context.Context, voiceSession *VoiceSess
 		zap.Duration("total_elapsed", time.Since(frameStartTime)))
 }
 
+// fadeOut plays the first fadeOutDuration of the audio left in a cancelled
+// response with its gain ramped down to silence, so playback does not end
+// with a click. It paces frames itself, since the playback context is done.
+func (s *Service) fadeOut(voiceSession *VoiceSession, remaining []byte) {
+	const frameSizeBytes = audio.OpenAIFrameSize * 2
+	frames := int(fadeOutDuration / DefaultFrameDuration)
+	tail := voiceSession.pipeline.processPlayback(audio.LEToPCMInt16(remaining[:min(len(remaining), frames*frameSizeBytes)]))
+	if len(tail) == 0 {
+		return
+	}
+	audio.FadeOut(tail)
+	if rest := len(tail) % audio.OpenAIFrameSize; rest != 0 {
+		tail = append(tail, make([]int16, audio.OpenAIFrameSize-rest)...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*fadeOutDuration)
+	defer cancel()
+
+	for offset := 0; offset < len(tail); offset += audio.OpenAIFrameSize {
+		opusData, err := voiceSession.codecs.encode(tail[offset:offset+audio.OpenAIFrameSize], s.load.Level())
+		if err != nil {
+			s.logger.Debug("Failed to encode fade-out frame", zap.Error(err))
+
+			return
+		}
+		if _, err := s.clock.Next(ctx); err != nil {
+			return
+		}
+		if err := s.voiceManager.PlayAudio(ctx, voiceSession.ChannelID, opusData); err != nil {
+			s.logger.Debug("Failed to send fade-out frame", zap.Error(err))
+
+			return
+		}
+	}
+
+	s.logger.Debug("Faded out cancelled playback",
+		zap.String("guild_id", voiceSession.GuildID.String()),
+		zap.Int("frames", len(tail)/audio.OpenAIFrameSize))
+}
+
+// waitPlayback waits up to timeout for voiceSession's playback worker to stop.
+func waitPlayback(voiceSession *VoiceSession, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		voiceSession.playback.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
+
 func (s *Service) handleTranscript(voiceSession *VoiceSession, transcript string) {
 	s.logger.Info("AI transcript",
 		zap.String("guild_id", voiceSession.GuildID.String()),
 		zap.String("transcript", transcript))
 
-	// Optionally send transcript to text channel
-	// (This could be configured via a setting)
+	voiceSession.captions.add(assistantSpeaker, transcript)
+	voiceSession.transcripts.add(assistantSpeaker, transcript)
+	s.history.Add(voiceSession.GuildID, assistantSpeaker, nil, transcript, true, time.Now())
+	if !voiceSession.TextOnly {
+		s.mirrorSpeech(voiceSession.GuildID, voiceSession.TextChannelID, sessionListeners(voiceSession), assistantSpeaker, transcript)
+	}
+}
+
+// handleTextResponse posts a text-only session's reply to its text channel.
+func (s *Service) handleTextResponse(voiceSession *VoiceSession, text string) {
+	s.logger.Info("AI text response",
+		zap.String("guild_id", voiceSession.GuildID.String()),
+		zap.Int("length", len(text)))
+
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	if _, err := chat.SendLongMessage(s.discordSession, voiceSession.TextChannelID, s.mentions.Sanitize(text),
+		chat.DisclosureFooter(s.disclosure(voiceSession.GuildID.String())), s.mentions.Allowed()); err != nil {
+		s.logger.Error("Failed to send text response",
+			zap.Error(err),
+			zap.String("guild_id", voiceSession.GuildID.String()))
+	}
 }
 
-func (s *Service) handleUserTranscript(voiceSession *VoiceSession, transcript string) {
+func (s *Service) handleUserTranscript(ctx context.Context, voiceSession *VoiceSession, transcript string) {
 	s.logger.Info("User transcript",
 		zap.String("guild_id", voiceSession.GuildID.String()),
 		zap.String("user_id", voiceSession.InitiatorID.String()),
 		zap.String("transcript", transcript))
 
-	// Optionally send user transcript to text channel
-	// (This could be configured via a setting)
+	speaker := turnSpeaker(voiceSession)
+	voiceSession.captions.add(speaker.name, transcript)
+	voiceSession.transcripts.add(speaker.name, transcript)
+	s.history.Add(voiceSession.GuildID, speaker.name, speaker.userIDs, transcript, false, time.Now())
+	if s.takeSpokenCommand(ctx, voiceSession, transcript) {
+		return
+	}
+	s.respondIfAddressed(ctx, voiceSession, transcript)
 }
 
 func (s *Service) handleResponseDone(ctx context.Context, voiceSession *VoiceSession, usage *Usage) {
+	s.releaseResponseSlot(voiceSession, nil)
+	voiceSession.mu.Lock()
+	voiceSession.responding = false
+	voiceSession.dropResponse = false
+	voiceSession.mu.Unlock()
 	if usage == nil {
 		return
 	}
@@ -671,6 +1293,9 @@ func (s *Service) handleResponseDone(ctx context.Context, voiceSession *VoiceSes
 		return
 	}
 
+	// Keep long sessions from resending an ever-growing conversation
+	s.pruneConversation(ctx, voiceSession, usage.InputTokens)
+
 	// Update token usage
 	err := s.sessionManager.UpdateTokenUsage(voiceSession.GuildID, usage.InputAudioTokens, usage.OutputAudioTokens)
 	if err != nil {
@@ -756,6 +1381,9 @@ func (s *Service) endSession(ctx context.Context, voiceSession *VoiceSession, re
 		voiceSession.CancelFunc()
 	}
 
+	// Let playback fade out before the connection goes away
+	waitPlayback(voiceSession, 2*fadeOutDuration)
+
 	// Close OpenAI connection
 	if voiceSession.Connection != nil {
 		// Try to close the connection if it implements io.Closer
@@ -768,8 +1396,12 @@ func (s *Service) endSession(ctx context.Context, voiceSession *VoiceSession, re
 		}
 	}
 
+	s.releaseResponseSlot(voiceSession, nil)
+
 	// Close audio queue to signal workers to stop
 	close(voiceSession.AudioQueue)
+	s.stopCapture(voiceSession)
+	voiceSession.codecs.close()
 
 	// Leave voice channel
 	err := s.voiceManager.LeaveChannel(ctx, voiceSession.ChannelID)
@@ -786,15 +1418,80 @@ func (s *Service) endSession(ctx context.Context, voiceSession *VoiceSession, re
 	voiceSession.mu.Lock()
 	voiceSession.State = SessionStateEnded
 	voiceSession.mu.Unlock()
+	s.history.End(voiceSession.GuildID, time.Now())
+	s.metrics.VoiceSessionEnded()
+
+	var snapshot MetricsSnapshot
+	if voiceSession.Metrics != nil {
+		snapshot = voiceSession.Metrics.Snapshot()
+	}
 
 	s.logger.Info("Voice session ended",
 		zap.String("guild_id", voiceSession.GuildID.String()),
 		zap.String("reason", reason),
-		zap.Float64("cost", voiceSession.SessionCost))
+		zap.Float64("cost", voiceSession.SessionCost),
+		zap.Int("responses", snapshot.Responses),
+		zap.Duration("latency_avg", snapshot.LatencyAvg),
+		zap.Duration("latency_max", snapshot.LatencyMax),
+		zap.Duration("mixer_avg", snapshot.MixerAvg),
+		zap.Duration("mixer_max", snapshot.MixerMax))
+
+	for _, user := range snapshot.Users {
+		s.logger.Info("Voice session stream stats",
+			zap.String("guild_id", voiceSession.GuildID.String()),
+			zap.String("user_id", user.UserID.String()),
+			zap.Uint32("ssrc", user.SSRC),
+			zap.Int("packets_received", user.PacketsReceived),
+			zap.Int("packets_lost", user.PacketsLost),
+			zap.Int("reordered", user.Reordered),
+			zap.Duration("jitter", user.Jitter))
+	}
+	for _, speaker := range snapshot.Speakers {
+		s.logger.Info("Voice session participation",
+			zap.String("guild_id", voiceSession.GuildID.String()),
+			zap.String("user_id", speaker.UserID.String()),
+			zap.Duration("speaking_time", speaker.SpeakingTime),
+			zap.Int("turns", speaker.Turns),
+			zap.Int("interruptions", speaker.Interruptions))
+	}
+
+	s.sendSessionEndMessage(voiceSession, reason, snapshot)
 
 	return nil
 }
 
+// sendSessionEndMessage posts a short session report to the text channel where the session was started.
+func (s *Service) sendSessionEndMessage(voiceSession *VoiceSession, reason string, snapshot MetricsSnapshot) {
+	if s.discordSession == nil || !voiceSession.TextChannelID.IsValid() {
+		return
+	}
+
+	msg := fmt.Sprintf("🔇 Voice session ended (%s)\n⏱️ Duration: %s",
+		reason, time.Since(voiceSession.StartTime).Round(time.Second))
+	if s.cfg.TrackSessionCosts {
+		msg += fmt.Sprintf("\n💰 Session cost: $%.2f", voiceSession.SessionCost)
+	}
+	if summary := snapshot.Summary(); summary != "" {
+		msg += "\n" + summary
+	}
+	if s.cfg.ParticipationSummary {
+		if participation := snapshot.ParticipationSummary(); participation != "" {
+			msg += "\n" + participation
+		}
+	}
+
+	_, err := s.discordSession.SendMessageComplex(voiceSession.TextChannelID, api.SendMessageData{
+		Content: msg,
+		// The participation summary names speakers without pinging them
+		AllowedMentions: &api.AllowedMentions{},
+	})
+	if err != nil {
+		s.logger.Warn("Failed to send session end message",
+			zap.Error(err),
+			zap.String("guild_id", voiceSession.GuildID.String()))
+	}
+}
+
 func (s *Service) runWatchdog(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -808,6 +1505,7 @@ func (s *Service) runWatchdog(ctx context.Context) {
 				lastAudioTime := voiceSession.LastAudioTime
 				startTime := voiceSession.StartTime
 				sessionCost := voiceSession.SessionCost
+				maxLength := voiceSession.MaxLength
 
 				// Clean up stale ActiveUsers entries (users who haven't been seen for 30 seconds)
 				for userID, userState := range voiceSession.ActiveUsers {
@@ -832,7 +1530,10 @@ func (s *Service) runWatchdog(ctx context.Context) {
 				}
 
 				// Check session duration
-				if time.Since(startTime) > time.Duration(s.cfg.MaxSessionLength)*time.Minute {
+				if maxLength <= 0 {
+					maxLength = time.Duration(s.cfg.MaxSessionLength) * time.Minute
+				}
+				if time.Since(startTime) > maxLength {
 					if err := s.endSession(ctx, voiceSession, "maximum session length reached"); err != nil {
 						s.logger.Error("failed to end session", zap.Error(err))
 					}
@@ -86,10 +86,13 @@ func (sm *sessionManager) CreateSession(guildID discord.GuildID, channelID, text
 		LastAudioTime:  time.Now(),
 		State:          SessionStateStarting,
 		ActiveUsers:    make(map[discord.UserID]*UserState),
+		Participants:   make(map[discord.UserID]string),
 		AudioQueue:     make(chan []byte, 100), // Buffer up to 100 audio chunks
 		PlaybackActive: false,
 		Model:          model,
 		LastCostUpdate: time.Now(),
+		Metrics:        NewSessionMetrics(),
+		turnRequests:   make(chan struct{}, 1),
 	}
 
 	// Use LoadOrStore to handle race condition
@@ -259,6 +262,9 @@ var (
 	ErrSessionAlreadyExists = NewVoiceError("session already exists for this guild")
 	ErrSessionNotFound      = NewVoiceError("session not found")
 	ErrMaxSessionsReached   = NewVoiceError("maximum concurrent sessions reached")
+
+	ErrMissingVoicePermissions = NewVoiceError("the bot is missing permissions in the voice channel")
+	ErrRealtimeUnavailable     = NewVoiceError("OpenAI Realtime is unavailable")
 )
 
 // VoiceError represents errors specific to voice operations.
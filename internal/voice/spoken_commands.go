@@ -0,0 +1,328 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// SpokenAction is what a spoken control phrase asks for.
+type SpokenAction int
+
+const (
+	SpokenNone SpokenAction = iota
+	SpokenStop
+	SpokenPause
+	SpokenResume
+	SpokenNewTopic
+	SpokenVoice // Arg is the voice asked for
+	SpokenModel // Arg is the model asked for, as spoken
+)
+
+func (a SpokenAction) String() string {
+	switch a {
+	case SpokenStop:
+		return "stop"
+	case SpokenPause:
+		return "pause"
+	case SpokenResume:
+		return "resume"
+	case SpokenNewTopic:
+		return "new topic"
+	case SpokenVoice:
+		return "voice"
+	case SpokenModel:
+		return "model"
+	default:
+		return "none"
+	}
+}
+
+// SpokenCommand is a control phrase heard in a turn's transcript.
+type SpokenCommand struct {
+	Action SpokenAction
+	Arg    string
+}
+
+// RealtimeVoices are the voices sessions can switch to by voice command.
+var RealtimeVoices = []string{"alloy", "echo", "shimmer"}
+
+// spokenPhrases are the control phrases, as normalized words; "*" stands for
+// the words of the argument.
+var spokenPhrases = []struct {
+	phrase string
+	action SpokenAction
+}{
+	{"stop", SpokenStop},
+	{"stop session", SpokenStop},
+	{"end session", SpokenStop},
+	{"leave channel", SpokenStop},
+	{"leave call", SpokenStop},
+	{"pause", SpokenPause},
+	{"pause session", SpokenPause},
+	{"stop listening", SpokenPause},
+	{"resume", SpokenResume},
+	{"resume session", SpokenResume},
+	{"unpause", SpokenResume},
+	{"start listening", SpokenResume},
+	{"new topic", SpokenNewTopic},
+	{"new conversation", SpokenNewTopic},
+	{"change subject", SpokenNewTopic},
+	{"start over", SpokenNewTopic},
+	{"forget that", SpokenNewTopic},
+	{"switch to * voice", SpokenVoice},
+	{"use * voice", SpokenVoice},
+	{"switch voice to *", SpokenVoice},
+	{"change voice to *", SpokenVoice},
+	{"switch to * model", SpokenModel},
+	{"use * model", SpokenModel},
+	{"switch model to *", SpokenModel},
+	{"change model to *", SpokenModel},
+}
+
+// spokenFillers are left out of a transcript before it is matched, so "okay
+// bot, please pause the session now" is heard as "pause session".
+var spokenFillers = map[string]bool{
+	"okay": true, "ok": true, "hey": true, "please": true, "the": true,
+	"now": true, "can": true, "could": true, "you": true, "a": true,
+}
+
+// ParseSpokenCommand reports the control phrase transcript consists of. The
+// whole transcript must be the phrase, apart from the names the bot is
+// called by and filler words, so phrases mentioned in conversation are not
+// taken as commands.
+func ParseSpokenCommand(transcript string, names []string) (SpokenCommand, bool) {
+	text := " " + strings.Join(normalizedWords(transcript), " ") + " "
+	for _, name := range names {
+		if name := strings.Join(normalizedWords(name), " "); name != "" {
+			text = strings.ReplaceAll(text, " "+name+" ", " ")
+		}
+	}
+	words := slices.DeleteFunc(strings.Fields(text), func(word string) bool { return spokenFillers[word] })
+	if len(words) == 0 {
+		return SpokenCommand{}, false
+	}
+
+	for _, p := range spokenPhrases {
+		before, after, wildcard := strings.Cut(p.phrase, "*")
+		if !wildcard {
+			if strings.Join(words, " ") == p.phrase {
+				return SpokenCommand{Action: p.action}, true
+			}
+
+			continue
+		}
+		prefix, suffix := strings.Fields(before), strings.Fields(after)
+		if len(words) <= len(prefix)+len(suffix) ||
+			!slices.Equal(words[:len(prefix)], prefix) || !slices.Equal(words[len(words)-len(suffix):], suffix) {
+			continue
+		}
+
+		return SpokenCommand{Action: p.action, Arg: strings.Join(words[len(prefix):len(words)-len(suffix)], " ")}, true
+	}
+
+	return SpokenCommand{}, false
+}
+
+// takeSpokenCommand runs the control phrase transcript consists of, when
+// voice commands are on, and reports whether it was one. The turn it ends
+// is not answered.
+func (s *Service) takeSpokenCommand(ctx context.Context, voiceSession *VoiceSession, transcript string) bool {
+	if !s.cfg.VoiceCommands {
+		return false
+	}
+	voiceSession.mu.Lock()
+	command, ok := ParseSpokenCommand(transcript, voiceSession.AddressNames)
+	if ok && voiceSession.awaitingAddress > 0 {
+		voiceSession.awaitingAddress--
+	}
+	voiceSession.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	s.logger.Info("Heard voice command",
+		zap.String("guild_id", voiceSession.GuildID.String()),
+		zap.Stringer("action", command.Action),
+		zap.String("arg", command.Arg))
+
+	// Switching reconnects, which must not happen from the connection's own
+	// event handler
+	go s.runSpokenCommand(ctx, voiceSession, command)
+
+	return true
+}
+
+// runSpokenCommand carries out command and confirms it in the session.
+func (s *Service) runSpokenCommand(ctx context.Context, voiceSession *VoiceSession, command SpokenCommand) {
+	var confirmation string
+	switch command.Action {
+	case SpokenStop:
+		if err := s.endSession(ctx, voiceSession, "stopped by voice command"); err != nil {
+			s.logger.Error("Failed to end session by voice command", zap.Error(err))
+		}
+
+		return
+	case SpokenPause:
+		voiceSession.mu.Lock()
+		voiceSession.paused = true
+		voiceSession.mu.Unlock()
+		confirmation = `Paused: I'm still listening, but will only answer once someone says "resume".`
+	case SpokenResume:
+		voiceSession.mu.Lock()
+		voiceSession.paused = false
+		voiceSession.mu.Unlock()
+		confirmation = "Resumed: I'm answering again."
+	case SpokenNewTopic:
+		s.forgetConversation(ctx, voiceSession)
+		confirmation = "Starting a new topic; I've forgotten what we talked about."
+	case SpokenVoice:
+		confirmation = s.switchVoice(ctx, voiceSession, command.Arg)
+	case SpokenModel:
+		confirmation = s.switchModel(ctx, voiceSession, command.Arg)
+	default:
+		return
+	}
+
+	s.confirmSpokenCommand(ctx, voiceSession, confirmation)
+}
+
+// switchVoice reconnects the session with voice and returns the confirmation.
+func (s *Service) switchVoice(ctx context.Context, voiceSession *VoiceSession, voice string) string {
+	voice = strings.ReplaceAll(voice, " ", "")
+	if !slices.Contains(RealtimeVoices, voice) {
+		return fmt.Sprintf("I don't have a voice called %s. I can use %s.", voice, strings.Join(RealtimeVoices, ", "))
+	}
+
+	voiceSession.mu.Lock()
+	previous := voiceSession.Voice
+	voiceSession.Voice = voice
+	voiceSession.mu.Unlock()
+	if err := s.reconnect(ctx, voiceSession); err != nil {
+		s.logger.Error("Failed to switch voice", zap.Error(err), zap.String("guild_id", voiceSession.GuildID.String()))
+		voiceSession.mu.Lock()
+		voiceSession.Voice = previous
+		voiceSession.mu.Unlock()
+		s.restoreConnection(ctx, voiceSession)
+
+		return "Sorry, I couldn't switch voices."
+	}
+
+	return fmt.Sprintf("Switched to the %s voice. I've started a fresh conversation.", voice)
+}
+
+// switchModel reconnects the session with the allowed model named by spoken
+// and returns the confirmation.
+func (s *Service) switchModel(ctx context.Context, voiceSession *VoiceSession, spoken string) string {
+	model, ok := s.spokenModel(spoken)
+	if !ok {
+		return fmt.Sprintf("I can't tell which model %q is; use /voice start to pick one by name.", spoken)
+	}
+
+	voiceSession.mu.Lock()
+	previous := voiceSession.Model
+	voiceSession.Model = model
+	voiceSession.mu.Unlock()
+	if err := s.reconnect(ctx, voiceSession); err != nil {
+		s.logger.Error("Failed to switch model", zap.Error(err), zap.String("guild_id", voiceSession.GuildID.String()))
+		voiceSession.mu.Lock()
+		voiceSession.Model = previous
+		voiceSession.mu.Unlock()
+		s.restoreConnection(ctx, voiceSession)
+
+		return "Sorry, I couldn't switch models."
+	}
+
+	return fmt.Sprintf("Switched to %s. I've started a fresh conversation.", model)
+}
+
+// spokenModel returns the one allowed model whose name contains the words
+// spoken, e.g. "mini" for gpt-4o-mini-realtime-preview.
+func (s *Service) spokenModel(spoken string) (string, bool) {
+	spokenWords := " " + strings.Join(normalizedWords(spoken), " ") + " "
+	var found string
+	for _, model := range s.allowlists.Load().models {
+		if strings.Contains(" "+strings.Join(normalizedWords(model), " ")+" ", spokenWords) {
+			if found != "" {
+				return "", false
+			}
+			found = model
+		}
+	}
+
+	return found, found != ""
+}
+
+// forgetConversation deletes the session's server-side conversation, so the
+// model starts from its instructions alone.
+func (s *Service) forgetConversation(ctx context.Context, voiceSession *VoiceSession) {
+	voiceSession.mu.Lock()
+	items := voiceSession.items
+	voiceSession.items = nil
+	voiceSession.mu.Unlock()
+
+	for _, item := range items {
+		if err := s.realtimeProvider.DeleteItem(ctx, item.ID); err != nil {
+			s.logger.Warn("Failed to delete conversation item",
+				zap.Error(err),
+				zap.String("guild_id", voiceSession.GuildID.String()),
+				zap.String("item_id", item.ID))
+		}
+	}
+}
+
+// reconnect replaces the session's realtime connection with one using its
+// current model and voice. The server-side conversation starts over.
+func (s *Service) reconnect(ctx context.Context, voiceSession *VoiceSession) error {
+	if err := s.realtimeProvider.Close(); err != nil {
+		s.logger.Warn("Failed to close OpenAI connection", zap.Error(err))
+	}
+
+	connection, err := s.realtimeProvider.Connect(ctx, s.connectOptions(voiceSession))
+	if err != nil {
+		return fmt.Errorf("failed to connect to OpenAI Realtime: %w", err)
+	}
+	if err := s.sessionManager.SetConnection(voiceSession.GuildID, connection); err != nil {
+		return fmt.Errorf("failed to set session connection: %w", err)
+	}
+
+	voiceSession.mu.Lock()
+	voiceSession.items = nil
+	instructions := sessionInstructions(voiceSession)
+	voiceSession.mu.Unlock()
+	if err := s.realtimeProvider.UpdateInstructions(ctx, instructions); err != nil {
+		s.logger.Warn("Failed to set session instructions", zap.Error(err))
+	}
+
+	return nil
+}
+
+// restoreConnection reconnects the session as it was before a switch failed,
+// ending it if that fails too.
+func (s *Service) restoreConnection(ctx context.Context, voiceSession *VoiceSession) {
+	if err := s.reconnect(ctx, voiceSession); err != nil {
+		s.logger.Error("Failed to restore OpenAI connection", zap.Error(err))
+		if endErr := s.endSession(ctx, voiceSession, fmt.Sprintf("lost OpenAI connection: %v", err)); endErr != nil {
+			s.logger.Error("failed to end session", zap.Error(endErr))
+		}
+	}
+}
+
+// confirmSpokenCommand posts confirmation to the session's text channel and,
+// in sessions that speak, has the model say it.
+func (s *Service) confirmSpokenCommand(ctx context.Context, voiceSession *VoiceSession, confirmation string) {
+	if _, err := s.discordSession.SendMessage(voiceSession.TextChannelID, "🎙️ "+confirmation); err != nil {
+		s.logger.Warn("Failed to post voice command confirmation", zap.Error(err))
+	}
+	if voiceSession.TextOnly {
+		return
+	}
+
+	instructions := "Tell the channel this in one short sentence, without adding anything: " + confirmation
+	if err := s.realtimeProvider.GenerateResponseWithInstructions(ctx, instructions); err != nil {
+		s.logger.Warn("Failed to speak voice command confirmation", zap.Error(err))
+	}
+}
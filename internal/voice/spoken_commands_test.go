@@ -0,0 +1,43 @@
+package voice_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestParseSpokenCommand(t *testing.T) {
+	names := []string{"bot", "hey jarvis"}
+	tests := []struct {
+		transcript string
+		want       voice.SpokenCommand
+	}{
+		{"Stop.", voice.SpokenCommand{Action: voice.SpokenStop}},
+		{"Okay bot, please end the session.", voice.SpokenCommand{Action: voice.SpokenStop}},
+		{"Hey Jarvis, pause", voice.SpokenCommand{Action: voice.SpokenPause}},
+		{"Can you stop listening now?", voice.SpokenCommand{Action: voice.SpokenPause}},
+		{"resume, bot", voice.SpokenCommand{Action: voice.SpokenResume}},
+		{"New topic!", voice.SpokenCommand{Action: voice.SpokenNewTopic}},
+		{"Switch to the Echo voice.", voice.SpokenCommand{Action: voice.SpokenVoice, Arg: "echo"}},
+		{"bot, change voice to alloy", voice.SpokenCommand{Action: voice.SpokenVoice, Arg: "alloy"}},
+		{"Use the mini model.", voice.SpokenCommand{Action: voice.SpokenModel, Arg: "mini"}},
+	}
+	for _, tt := range tests {
+		got, ok := voice.ParseSpokenCommand(tt.transcript, names)
+		assert.True(t, ok, tt.transcript)
+		assert.Equal(t, tt.want, got, tt.transcript)
+	}
+
+	for _, transcript := range []string{
+		"We should stop doing that.",
+		"Let's pause the movie after this scene",
+		"bot",
+		"",
+		"use voice",
+	} {
+		_, ok := voice.ParseSpokenCommand(transcript, names)
+		assert.False(t, ok, "%q is not a command", transcript)
+	}
+}
@@ -0,0 +1,188 @@
+package voice
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultTranscriptHistoryTurns = 200
+	defaultTranscriptHistoryBytes = 64 << 10
+)
+
+// TranscriptTurn is one utterance of a session transcript.
+type TranscriptTurn struct {
+	At        time.Time
+	Speaker   string
+	UserIDs   []discord.UserID // Members heard in the turn, for /forget-me
+	Text      string
+	Assistant bool // Said by the bot
+}
+
+// SessionTranscript is the transcript of one voice session.
+type SessionTranscript struct {
+	ChannelID discord.ChannelID
+	StartedAt time.Time
+	EndedAt   time.Time // Zero while the session runs
+	Turns     []TranscriptTurn
+	Dropped   int // Oldest turns dropped to stay within the limits or the retention window
+}
+
+// Active reports whether the session is still running.
+func (t SessionTranscript) Active() bool {
+	return t.EndedAt.IsZero()
+}
+
+// TranscriptStore keeps the rolling transcript of each guild's current or
+// most recent voice session, in memory. A session's transcript replaces the
+// previous one of its guild when it starts, and its oldest turns are dropped
+// once it holds more turns or text than the store's limits.
+type TranscriptStore struct {
+	maxTurns int
+	maxBytes int
+
+	mu       sync.Mutex
+	sessions map[discord.GuildID]*sessionTranscript
+}
+
+type sessionTranscript struct {
+	SessionTranscript
+	bytes int
+}
+
+// NewTranscriptStore creates an empty TranscriptStore with the limits cfg
+// sets.
+func NewTranscriptStore(cfg *config.VoiceConfig) *TranscriptStore {
+	maxTurns := cfg.TranscriptHistoryTurns
+	if maxTurns <= 0 {
+		maxTurns = defaultTranscriptHistoryTurns
+	}
+	maxBytes := cfg.TranscriptHistoryBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultTranscriptHistoryBytes
+	}
+
+	return &TranscriptStore{maxTurns: maxTurns, maxBytes: maxBytes, sessions: make(map[discord.GuildID]*sessionTranscript)}
+}
+
+// Begin starts the transcript of a session in guildID, replacing the
+// guild's previous one.
+func (s *TranscriptStore) Begin(guildID discord.GuildID, channelID discord.ChannelID, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[guildID] = &sessionTranscript{SessionTranscript: SessionTranscript{ChannelID: channelID, StartedAt: at}}
+}
+
+// Add records text said by speaker, the members userIDs, in the running
+// session of guildID. Text longer than the byte limit is cut.
+func (s *TranscriptStore) Add(guildID discord.GuildID, speaker string, userIDs []discord.UserID, text string, assistant bool, at time.Time) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return
+	}
+	if len(text) > s.maxBytes {
+		text = strings.ToValidUTF8(text[:s.maxBytes], "")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.sessions[guildID]
+	if !ok || !t.Active() {
+		return
+	}
+	t.Turns = append(t.Turns, TranscriptTurn{At: at, Speaker: speaker, UserIDs: userIDs, Text: text, Assistant: assistant})
+	t.bytes += len(text)
+	for len(t.Turns) > s.maxTurns || t.bytes > s.maxBytes {
+		t.bytes -= len(t.Turns[0].Text)
+		t.Turns[0] = TranscriptTurn{}
+		t.Turns = t.Turns[1:]
+		t.Dropped++
+	}
+}
+
+// End marks the running session of guildID as ended. Its transcript is kept
+// until the guild's next session starts.
+func (s *TranscriptStore) End(guildID discord.GuildID, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.sessions[guildID]; ok && t.Active() {
+		t.EndedAt = at
+	}
+}
+
+// PurgeBefore drops the turns said before cutoff, and the transcripts of
+// sessions that ended before it, returning how many turns were dropped.
+func (s *TranscriptStore) PurgeBefore(cutoff time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for guildID, t := range s.sessions {
+		if !t.Active() && t.EndedAt.Before(cutoff) {
+			purged += len(t.Turns)
+			delete(s.sessions, guildID)
+
+			continue
+		}
+		expired := 0
+		for expired < len(t.Turns) && t.Turns[expired].At.Before(cutoff) {
+			t.bytes -= len(t.Turns[expired].Text)
+			expired++
+		}
+		if expired > 0 {
+			t.Turns = slices.Delete(t.Turns, 0, expired)
+			t.Dropped += expired
+			purged += expired
+		}
+	}
+
+	return purged
+}
+
+// ForgetSpeaker drops the turns userID was heard in from every transcript,
+// returning how many were dropped. They do not count as Dropped, which
+// exports report as the start of the transcript missing.
+func (s *TranscriptStore) ForgetSpeaker(userID discord.UserID) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forgotten := 0
+	for _, t := range s.sessions {
+		t.Turns = slices.DeleteFunc(t.Turns, func(turn TranscriptTurn) bool {
+			if !slices.Contains(turn.UserIDs, userID) {
+				return false
+			}
+			t.bytes -= len(turn.Text)
+			forgotten++
+
+			return true
+		})
+	}
+
+	return forgotten
+}
+
+// Get returns a copy of the transcript of guildID's current or most recent
+// session, and whether there is one.
+func (s *TranscriptStore) Get(guildID discord.GuildID) (SessionTranscript, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.sessions[guildID]
+	if !ok {
+		return SessionTranscript{}, false
+	}
+	transcript := t.SessionTranscript
+	transcript.Turns = append([]TranscriptTurn(nil), t.Turns...)
+
+	return transcript, true
+}
@@ -0,0 +1,119 @@
+package voice_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+)
+
+func TestTranscriptStore(t *testing.T) {
+	const guildID, channelID = discord.GuildID(1), discord.ChannelID(2)
+	start := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	store := voice.NewTranscriptStore(&config.VoiceConfig{TranscriptHistoryTurns: 3})
+
+	_, ok := store.Get(guildID)
+	assert.False(t, ok)
+	store.Add(guildID, "Alice", nil, "before any session", false, start)
+	_, ok = store.Get(guildID)
+	assert.False(t, ok, "turns outside a session are not recorded")
+
+	store.Begin(guildID, channelID, start)
+	store.Add(guildID, "Alice", nil, "  What's  the\nweather? ", false, start.Add(time.Second))
+	store.Add(guildID, "Assistant", nil, "Sunny.", true, start.Add(2*time.Second))
+	store.Add(guildID, "Bob", nil, "   ", false, start.Add(3*time.Second))
+
+	transcript, ok := store.Get(guildID)
+	require.True(t, ok)
+	assert.True(t, transcript.Active())
+	assert.Equal(t, channelID, transcript.ChannelID)
+	assert.Equal(t, []voice.TranscriptTurn{
+		{At: start.Add(time.Second), Speaker: "Alice", Text: "What's the weather?"},
+		{At: start.Add(2 * time.Second), Speaker: "Assistant", Text: "Sunny.", Assistant: true},
+	}, transcript.Turns)
+
+	// The oldest turns go once there are too many
+	store.Add(guildID, "Bob", nil, "Thanks.", false, start.Add(4*time.Second))
+	store.Add(guildID, "Assistant", nil, "You're welcome.", true, start.Add(5*time.Second))
+	transcript, _ = store.Get(guildID)
+	require.Len(t, transcript.Turns, 3)
+	assert.Equal(t, "Sunny.", transcript.Turns[0].Text)
+	assert.Equal(t, 1, transcript.Dropped)
+
+	// The most recent session is kept once it ends
+	store.End(guildID, start.Add(time.Minute))
+	store.Add(guildID, "Bob", nil, "after the session", false, start.Add(2*time.Minute))
+	transcript, ok = store.Get(guildID)
+	require.True(t, ok)
+	assert.False(t, transcript.Active())
+	assert.Len(t, transcript.Turns, 3)
+
+	store.Begin(guildID, channelID, start.Add(time.Hour))
+	transcript, _ = store.Get(guildID)
+	assert.Empty(t, transcript.Turns, "a new session starts a new transcript")
+}
+
+func TestTranscriptStore_ByteLimit(t *testing.T) {
+	const guildID = discord.GuildID(1)
+	store := voice.NewTranscriptStore(&config.VoiceConfig{TranscriptHistoryBytes: 10})
+	store.Begin(guildID, 2, time.Now())
+
+	store.Add(guildID, "Alice", nil, "hello", false, time.Now())
+	store.Add(guildID, "Bob", nil, "world!", false, time.Now())
+	transcript, _ := store.Get(guildID)
+	require.Len(t, transcript.Turns, 1)
+	assert.Equal(t, "world!", transcript.Turns[0].Text)
+
+	store.Add(guildID, "Alice", nil, strings.Repeat("é", 8), false, time.Now())
+	transcript, _ = store.Get(guildID)
+	require.Len(t, transcript.Turns, 1)
+	assert.Equal(t, strings.Repeat("é", 5), transcript.Turns[0].Text, "a turn too long on its own is cut on a rune boundary")
+}
+
+func TestTranscriptStore_PurgeBefore(t *testing.T) {
+	start := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	store := voice.NewTranscriptStore(&config.VoiceConfig{})
+
+	store.Begin(1, 10, start)
+	store.Add(1, "Alice", nil, "old", false, start.Add(time.Minute))
+	store.Add(1, "Alice", nil, "new", false, start.Add(time.Hour))
+	store.Begin(2, 20, start)
+	store.Add(2, "Bob", nil, "done", false, start.Add(time.Minute))
+	store.End(2, start.Add(2*time.Minute))
+
+	assert.Equal(t, 2, store.PurgeBefore(start.Add(30*time.Minute)))
+
+	transcript, ok := store.Get(1)
+	require.True(t, ok, "a running session keeps its recent turns")
+	assert.Equal(t, []voice.TranscriptTurn{{At: start.Add(time.Hour), Speaker: "Alice", Text: "new"}}, transcript.Turns)
+	assert.Equal(t, 1, transcript.Dropped)
+	_, ok = store.Get(2)
+	assert.False(t, ok, "an ended session is purged whole")
+
+	assert.Zero(t, store.PurgeBefore(start.Add(30*time.Minute)))
+}
+
+func TestTranscriptStore_ForgetSpeaker(t *testing.T) {
+	const guildID, alice, bob = discord.GuildID(1), discord.UserID(5), discord.UserID(6)
+	start := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	store := voice.NewTranscriptStore(&config.VoiceConfig{})
+	store.Begin(guildID, 2, start)
+	store.Add(guildID, "Alice", []discord.UserID{alice}, "My address is 1 Main St.", false, start)
+	store.Add(guildID, "Assistant", nil, "Noted.", true, start.Add(time.Second))
+	store.Add(guildID, "Alice, Bob", []discord.UserID{alice, bob}, "Both of us.", false, start.Add(2*time.Second))
+	store.Add(guildID, "Bob", []discord.UserID{bob}, "Just me.", false, start.Add(3*time.Second))
+
+	assert.Equal(t, 2, store.ForgetSpeaker(alice), "turns she was heard in are dropped, alone or not")
+	transcript, _ := store.Get(guildID)
+	require.Len(t, transcript.Turns, 2)
+	assert.Equal(t, "Noted.", transcript.Turns[0].Text)
+	assert.Equal(t, "Just me.", transcript.Turns[1].Text)
+	assert.Zero(t, transcript.Dropped, "erased turns are not reported as the missing start")
+	assert.Zero(t, store.ForgetSpeaker(alice))
+}
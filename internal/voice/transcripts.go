@@ -0,0 +1,107 @@
+package voice
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
+)
+
+// transcriptRelay collects the transcribed utterances of a session until
+// they are posted to its text channel. Unlike live captions, which keep only
+// the latest lines, every utterance is posted and stays in the channel. A nil
+// *transcriptRelay records nothing, as when transcripts are off.
+type transcriptRelay struct {
+	mu    sync.Mutex
+	lines []string
+	ready chan struct{} // signaled when lines were added
+}
+
+func newTranscriptRelay() *transcriptRelay {
+	return &transcriptRelay{ready: make(chan struct{}, 1)}
+}
+
+// add records text said by speaker.
+func (r *transcriptRelay) add(speaker, text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if r == nil || text == "" {
+		return
+	}
+
+	r.mu.Lock()
+	r.lines = append(r.lines, "**"+speaker+":** "+text)
+	r.mu.Unlock()
+
+	select {
+	case r.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the lines recorded since it was last called.
+func (r *transcriptRelay) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := r.lines
+	r.lines = nil
+
+	return lines
+}
+
+// postTranscriptsEnabled reports whether sessions in guildID post transcripts.
+func (s *Service) postTranscriptsEnabled(guildID discord.GuildID) bool {
+	if enabled := s.guilds[guildID.String()].Voice.PostTranscripts; enabled != nil {
+		return *enabled
+	}
+
+	return s.cfg.PostTranscripts
+}
+
+// runTranscripts posts the session's transcript as it comes in, or every
+// transcript_batch_seconds, until ctx is done.
+func (s *Service) runTranscripts(ctx context.Context, voiceSession *VoiceSession) {
+	relay := voiceSession.transcripts
+	var ready <-chan struct{} = relay.ready
+	var tick <-chan time.Time
+	if s.cfg.TranscriptBatchSeconds > 0 {
+		ticker := time.NewTicker(time.Duration(s.cfg.TranscriptBatchSeconds) * time.Second)
+		defer ticker.Stop()
+		ready, tick = nil, ticker.C
+	}
+
+	for {
+		select {
+		case <-ready:
+		case <-tick:
+		case <-ctx.Done():
+			// Post the session's final utterances
+			s.postTranscript(voiceSession, relay.take())
+
+			return
+		}
+		s.postTranscript(voiceSession, relay.take())
+	}
+}
+
+// postTranscript posts lines to the session's text channel as one message,
+// split when too long. Mentions in them do not ping anyone.
+func (s *Service) postTranscript(voiceSession *VoiceSession, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	content := s.mentions.Sanitize(strings.Join(lines, "\n"))
+	if _, err := chat.SendLongMessage(s.discordSession, voiceSession.TextChannelID, content, "", &api.AllowedMentions{}); err != nil {
+		s.logger.Warn("Failed to post voice transcript",
+			zap.Error(err),
+			zap.String("guild_id", voiceSession.GuildID.String()),
+			zap.Int("lines", len(lines)))
+	}
+}
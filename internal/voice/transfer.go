@@ -0,0 +1,133 @@
+package voice
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"go.uber.org/zap"
+)
+
+// moderatorPermissions mark a member who may take over a session whose
+// initiator has left.
+const moderatorPermissions = discord.PermissionMoveMembers | discord.PermissionMuteMembers
+
+// isController reports whether userID controls the session, i.e. may stop,
+// tune and commit turns in it.
+func (v *VoiceSession) isController(userID discord.UserID) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.InitiatorID == userID
+}
+
+// Transfer hands control of the session in guildID from userID, who must
+// control it, to toUserID, who must be in the session's voice channel.
+func (s *Service) Transfer(guildID discord.GuildID, userID, toUserID discord.UserID) error {
+	voiceSession, err := s.sessionManager.GetSessionByGuild(guildID)
+	if err != nil {
+		return errors.New("no active voice session in this guild")
+	}
+
+	voiceSession.mu.Lock()
+	_, inChannel := voiceSession.Participants[toUserID]
+	initiatorID := voiceSession.InitiatorID
+	voiceSession.mu.Unlock()
+
+	switch {
+	case initiatorID != userID:
+		return errors.New("user does not have permission to transfer this session")
+	case toUserID == userID:
+		return errors.New("user already controls this session")
+	case !inChannel:
+		return errors.New("the new controller must be in the session's voice channel")
+	case !s.canExecuteCommand(toUserID):
+		return errors.New("the new controller is not allowed to use voice commands")
+	}
+
+	s.setController(voiceSession, toUserID, "transferred by "+userID.Mention())
+
+	return nil
+}
+
+// handleControllerPresence moves control of the session to a moderator in
+// the channel when its controller leaves, or when a moderator joins a session
+// whose controller is already gone.
+func (s *Service) handleControllerPresence(voiceSession *VoiceSession, userID discord.UserID, joined bool) {
+	voiceSession.mu.Lock()
+	initiatorID := voiceSession.InitiatorID
+	_, initiatorPresent := voiceSession.Participants[initiatorID]
+	candidates := make([]discord.UserID, 0, len(voiceSession.Participants))
+	for id := range voiceSession.Participants {
+		candidates = append(candidates, id)
+	}
+	voiceSession.mu.Unlock()
+
+	if initiatorPresent {
+		return
+	}
+	if joined {
+		candidates = []discord.UserID{userID}
+	} else if userID != initiatorID {
+		return
+	}
+
+	// Prefer the longest-standing account for a stable choice
+	slices.Sort(candidates)
+	for _, id := range candidates {
+		if s.isModerator(voiceSession.ChannelID, id) && s.canExecuteCommand(id) {
+			s.setController(voiceSession, id, "the previous controller left the channel")
+
+			return
+		}
+	}
+
+	s.logger.Info("Voice session controller left and no moderator is in the channel",
+		zap.String("guild_id", voiceSession.GuildID.String()),
+		zap.String("user_id", initiatorID.String()))
+}
+
+// isModerator reports whether userID may moderate members in channelID.
+func (s *Service) isModerator(channelID discord.ChannelID, userID discord.UserID) bool {
+	if s.state == nil {
+		return false
+	}
+	perms, err := s.state.Permissions(channelID, userID)
+	if err != nil {
+		s.logger.Debug("Failed to get member permissions", zap.Error(err), zap.String("user_id", userID.String()))
+
+		return false
+	}
+
+	return perms&moderatorPermissions != 0
+}
+
+// setController makes userID the session's controller and tells the text
+// channel why.
+func (s *Service) setController(voiceSession *VoiceSession, userID discord.UserID, reason string) {
+	voiceSession.mu.Lock()
+	previous := voiceSession.InitiatorID
+	voiceSession.InitiatorID = userID
+	voiceSession.mu.Unlock()
+
+	s.logger.Info("Voice session control transferred",
+		zap.String("guild_id", voiceSession.GuildID.String()),
+		zap.String("from_user_id", previous.String()),
+		zap.String("to_user_id", userID.String()),
+		zap.String("reason", reason))
+
+	if s.discordSession == nil || !voiceSession.TextChannelID.IsValid() {
+		return
+	}
+	_, err := s.discordSession.SendMessageComplex(voiceSession.TextChannelID, api.SendMessageData{
+		Content:         fmt.Sprintf("🎙️ %s now controls the voice session (%s).", userID.Mention(), reason),
+		AllowedMentions: &api.AllowedMentions{Users: []discord.UserID{userID}},
+	})
+	if err != nil {
+		s.logger.Warn("Failed to announce voice session transfer",
+			zap.Error(err),
+			zap.String("guild_id", voiceSession.GuildID.String()))
+	}
+}
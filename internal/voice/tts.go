@@ -0,0 +1,75 @@
+package voice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	internalopenai "github.com/Raikerian/go-discord-chatgpt/internal/openai"
+)
+
+const (
+	defaultTTSModel = openai.TTSModel1
+	defaultTTSVoice = openai.VoiceShimmer
+	// maxSpeechBytes bounds synthesized speech, about 40 seconds of audio.
+	maxSpeechBytes = 2 << 20
+)
+
+// TTSProvider turns text into speech for announcements.
+type TTSProvider interface {
+	// Synthesize returns text spoken as 24 kHz mono 16-bit little-endian PCM,
+	// the format played back for realtime responses.
+	Synthesize(ctx context.Context, guildID discord.GuildID, text string) ([]byte, error)
+}
+
+type openAITTSProvider struct {
+	keys  internalopenai.KeyResolver
+	model openai.SpeechModel
+	voice openai.SpeechVoice
+}
+
+// NewTTSProvider creates a TTSProvider using OpenAI's speech API with the
+// credentials of each guild, speaking with the configured voice profile.
+func NewTTSProvider(cfg *config.Config, keys internalopenai.KeyResolver) TTSProvider {
+	p := &openAITTSProvider{
+		keys:  keys,
+		model: openai.SpeechModel(cfg.Voice.Announcements.Model),
+		voice: openai.SpeechVoice(cfg.Voice.VoiceProfile),
+	}
+	if p.model == "" {
+		p.model = defaultTTSModel
+	}
+	if p.voice == "" {
+		p.voice = defaultTTSVoice
+	}
+
+	return p
+}
+
+func (p *openAITTSProvider) Synthesize(ctx context.Context, guildID discord.GuildID, text string) ([]byte, error) {
+	resp, err := p.keys.Client(guildID).CreateSpeech(ctx, openai.CreateSpeechRequest{
+		Model:          p.model,
+		Input:          text,
+		Voice:          p.voice,
+		ResponseFormat: openai.SpeechResponseFormatPcm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+	defer func() { _ = resp.Close() }()
+
+	pcm, err := io.ReadAll(io.LimitReader(resp, maxSpeechBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read synthesized speech: %w", err)
+	}
+	if len(pcm) > maxSpeechBytes {
+		return nil, errors.New("synthesized speech is too long to announce")
+	}
+
+	return pcm, nil
+}
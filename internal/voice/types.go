@@ -7,6 +7,8 @@ import (
 
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/voice/udp"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
 )
 
 // VoiceSession represents an active voice session in a guild.
@@ -15,26 +17,121 @@ type VoiceSession struct {
 	GuildID       discord.GuildID   // Guild ID
 	ChannelID     discord.ChannelID // Voice channel
 	TextChannelID discord.ChannelID // Text channel where /voice was invoked
-	InitiatorID   discord.UserID
+	InitiatorID   discord.UserID    // Controls the session, until transferred
 	StartTime     time.Time
 	LastActivity  time.Time
 	LastAudioTime time.Time // Last time non-silent audio was received
 	State         SessionState
 	ActiveUsers   map[discord.UserID]*UserState
-	Connection    any                // WebSocket connection to OpenAI
-	CancelFunc    context.CancelFunc // Cancel function for session context
+	Participants  map[discord.UserID]string // Users in the voice channel, by display name
+	Connection    any                       // WebSocket connection to OpenAI
+	CancelFunc    context.CancelFunc        // Cancel function for session context
 
 	// Audio playback queue to prevent interference
 	AudioQueue     chan []byte
 	PlaybackActive bool
 	PlaybackMutex  sync.Mutex
+	playback       sync.WaitGroup     // Running playback worker, waited on to finish its fade-out
+	stopPlayback   context.CancelFunc // Cancels the running playback worker, set with PlaybackActive
 
 	// Cost tracking
 	InputAudioTokens  int       // Total input audio tokens used
 	OutputAudioTokens int       // Total output audio tokens used
 	SessionCost       float64   // Running total cost
 	Model             string    // Model being used
+	Language          string    // ISO-639-1 transcription and response language, empty for auto-detect
 	LastCostUpdate    time.Time // Last time cost was displayed
+
+	// Network and latency statistics
+	Metrics *SessionMetrics
+
+	// Silence detection, tunable at runtime via /voice tune
+	Silence *audio.SilenceDetector
+	// Gate keeps each speaker's silent frames out of the mix, using Silence
+	Gate *audio.NoiseGate
+
+	// Manual turn control: when set, buffered audio is only committed on request
+	ManualTurns  bool
+	turnRequests chan struct{}
+
+	// ServerVAD sessions stream their audio to OpenAI, whose turn detection
+	// commits each turn. Silence is streamed while vadSpeaking, or until
+	// vadPadUntil after the latest speech, so the server hears turns end
+	ServerVAD   bool
+	vadSpeaking bool
+	vadPadUntil time.Time
+
+	// Barge-in: speechFrames counts each speaker's consecutive speech frames
+	// while the assistant plays; responding is set while a response streams
+	// its audio, and dropResponse while the rest of an interrupted one is
+	// discarded
+	speechFrames map[uint32]int
+	responding   bool
+	dropResponse bool
+
+	// TextOnly sessions listen in voice but reply in TextChannelID without audio
+	TextOnly bool
+
+	// Style and Temperature shape how the model answers
+	Style       string
+	Temperature float32
+	// Voice is the realtime voice, empty for voice_profile
+	Voice string
+
+	// delivery limits the length and sets the pace of spoken answers
+	delivery ResponseDelivery
+
+	// MaxLength overrides max_session_length, e.g. for scheduled sessions
+	MaxLength time.Duration
+
+	// AddressNames, when set, are the names a turn must call the bot by to be
+	// answered; awaitingAddress counts committed turns waiting for their
+	// transcript to tell
+	AddressNames    []string
+	awaitingAddress int
+
+	// paused sessions keep transcribing turns, to hear voice commands, but
+	// do not answer them
+	paused bool
+
+	// responseSlot releases the request limiter slot held while a turn is
+	// being answered
+	responseSlot func(err error)
+
+	// items tracks the server-side conversation for pruning, oldest first
+	items   []ConversationItem
+	pruning bool
+
+	// captions are shown in TextChannelID when live captions are on
+	captions *liveCaptions
+	// transcripts are posted to TextChannelID when post_transcripts is on
+	transcripts *transcriptRelay
+	// turnSpeakers records who spoke in each committed turn awaiting its
+	// transcript, oldest first; lastTurnAt is when the latest was committed
+	turnSpeakers []turnSpeakers
+	lastTurnAt   time.Time
+
+	// pipeline runs the audio stages configured under voice.pipeline
+	pipeline *sessionPipeline
+
+	// capture records received packets when capture_dir is set
+	capture *PacketRecorder
+
+	// codecs decode each speaker and encode playback for this session only
+	codecs *sessionCodecs
+}
+
+// StartOptions are the per-session settings chosen when starting a session.
+type StartOptions struct {
+	Model       string // Realtime model, empty for the configured default
+	Language    string // ISO-639-1 code, empty for the configured default or auto-detect
+	ManualTurns bool   // Commit audio only on /voice go or the Respond now button
+	TextOnly    bool   // Reply in the text channel instead of speaking
+	Style       string // One of VoiceStyles, empty for none
+
+	Temperature float32 // Between MinTemperature and MaxTemperature, zero for the model default
+
+	MaxLength time.Duration // Stop the session after this long, zero for max_session_length
 }
 
 // SessionState represents the current state of a voice session.
@@ -47,6 +144,19 @@ const (
 	SessionStateEnded
 )
 
+func (s SessionState) String() string {
+	switch s {
+	case SessionStateStarting:
+		return "starting"
+	case SessionStateActive:
+		return "active"
+	case SessionStateEnding:
+		return "ending"
+	default:
+		return "ended"
+	}
+}
+
 // UserState tracks individual user activity within a session.
 type UserState struct {
 	UserID       discord.UserID
@@ -57,21 +167,30 @@ type UserState struct {
 // SessionStatus provides a read-only view of session status.
 type SessionStatus struct {
 	Active      bool
+	State       SessionState
 	GuildID     discord.GuildID
 	ChannelID   discord.ChannelID
 	StartTime   time.Time
 	ActiveUsers []discord.UserID
 	SessionCost float64
 	Model       string
+	Language    string
+	ManualTurns bool
+	TextOnly    bool
+	Metrics     MetricsSnapshot
+
+	SilenceThreshold float32
+	SilenceDuration  time.Duration
 }
 
 // AudioPacket represents an audio packet received from Discord.
 type AudioPacket struct {
-	UserID       discord.UserID
-	SSRC         uint32
-	Opus         []byte
-	RTPTimestamp uint32
-	Sequence     uint16
+	UserID       discord.UserID `json:"user_id"`
+	SSRC         uint32         `json:"ssrc"`
+	Opus         []byte         `json:"opus"`
+	RTPTimestamp uint32         `json:"rtp_timestamp"`
+	Sequence     uint16         `json:"sequence"`
+	ReceivedAt   time.Time      `json:"received_at"` // Local arrival time, used for jitter calculation
 }
 
 // NewAudioPacket creates a new AudioPacket from a UDP packet.
@@ -82,5 +201,6 @@ func NewAudioPacket(userID discord.UserID, packet *udp.Packet) *AudioPacket {
 		Opus:         packet.Opus,
 		RTPTimestamp: packet.Timestamp(),
 		Sequence:     packet.Sequence(),
+		ReceivedAt:   time.Now(),
 	}
 }
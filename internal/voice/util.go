@@ -10,12 +10,27 @@ const (
 	DefaultFrameDuration     = 20 * time.Millisecond // 20ms frames
 	DefaultSilenceThreshold  = 0.01                  // Energy threshold
 	DefaultSilenceDuration   = 1500 * time.Millisecond
-	DefaultInactivityTimeout = 120 * time.Second // 2 minutes
-	DefaultMaxSessionLength  = 10 * time.Minute  // 10 minutes
+	DefaultNoiseGateHold     = 15                     // Silent frames a speaker stays in the mix after speech (300ms)
+	DefaultBargeIn           = 200 * time.Millisecond // Speech over the assistant that interrupts it
+	DefaultInactivityTimeout = 120 * time.Second      // 2 minutes
+	DefaultMaxSessionLength  = 10 * time.Minute       // 10 minutes
 
-	// Performance targets.
-	MaxMixingTime     = 10 * time.Millisecond // Target mixing completion time
-	FallbackThreshold = 8 * time.Millisecond  // Switch to fallback mode if exceeded
+	// Commit modes: send every speaker mixed, or only the loudest speaker's stream.
+	CommitModeMixed           = "mixed"
+	CommitModeDominantSpeaker = "dominant_speaker"
+
+	// Turn detection modes: the bot commits a turn after its silence timer,
+	// or OpenAI's server VAD commits turns in the audio streamed to it.
+	VADModeClient = "client_vad"
+	VADModeServer = "server_vad"
+
+	// Sampling temperatures accepted by the realtime API.
+	MinTemperature = 0.6
+	MaxTemperature = 1.2
+
+	// Bounds accepted by /voice tune.
+	MinSilenceDuration = 100 * time.Millisecond
+	MaxSilenceDuration = 10 * time.Second
 
 	// Buffer sizes.
 	AudioBufferSize = 100 // Number of audio packets to buffer
@@ -23,4 +38,9 @@ const (
 
 	// Timeout intervals.
 	AudioTimeoutCheckInterval = 100 * time.Millisecond // How often to check for audio timeouts
+	preflightTimeout          = 5 * time.Second        // How long /voice start waits for OpenAI before joining
+	fadeOutDuration           = 100 * time.Millisecond // Audio faded out when playback is cut short
+	serverVADStreamInterval   = 100 * time.Millisecond // How often mixed audio is streamed under server VAD
+	serverVADPadding          = 3 * time.Second        // Silence streamed after speech for server VAD to end the turn
+	bargeInCancelTimeout      = 5 * time.Second        // How long cancelling an interrupted response may take
 )
@@ -0,0 +1,122 @@
+package webpage
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// skippedTags hold markup, navigation and chrome rather than page content.
+	skippedTags = map[string]bool{
+		"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+		"nav": true, "header": true, "footer": true, "aside": true, "form": true,
+		"iframe": true, "button": true, "select": true,
+	}
+	// blockTags end a line of text.
+	blockTags = map[string]bool{
+		"p": true, "div": true, "br": true, "li": true, "tr": true, "section": true,
+		"article": true, "main": true, "h1": true, "h2": true, "h3": true, "h4": true,
+		"h5": true, "h6": true, "blockquote": true, "pre": true, "table": true,
+		"ul": true, "ol": true, "dd": true, "dt": true, "hr": true, "figcaption": true,
+	}
+
+	tagPattern     = regexp.MustCompile(`(?s)<!--.*?-->|<(/?)([a-zA-Z][a-zA-Z0-9]*)\b[^>]*?(/?)>`)
+	titlePattern   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	contentPattern = regexp.MustCompile(`(?is)<(article|main)\b[^>]*>(.*)</(article|main)>`)
+	spacePattern   = regexp.MustCompile(`[ \t\r\f\v\x{00a0}]+`)
+	linesPattern   = regexp.MustCompile(`\n{3,}`)
+)
+
+// ExtractText returns the title and readable text of an HTML document. Scripts,
+// navigation and other page chrome are dropped, and the article or main element
+// is preferred when the page has one.
+func ExtractText(document string) (title, text string) {
+	if m := titlePattern.FindStringSubmatch(document); m != nil {
+		title = collapse(html.UnescapeString(m[1]))
+	}
+
+	body := document
+	if m := contentPattern.FindStringSubmatch(document); m != nil && strings.EqualFold(m[1], m[3]) {
+		body = m[2]
+	}
+
+	var sb strings.Builder
+	skipping := "" // tag whose content is being dropped
+	depth := 0     // nesting of the skipped tag
+	last := 0
+	for _, loc := range tagPattern.FindAllStringSubmatchIndex(body, -1) {
+		if skipping == "" {
+			sb.WriteString(body[last:loc[0]])
+		}
+		last = loc[1]
+		if loc[4] < 0 {
+			continue // comment
+		}
+
+		closing := loc[3] > loc[2]
+		selfClosing := loc[7] > loc[6]
+		name := strings.ToLower(body[loc[4]:loc[5]])
+
+		if skipping != "" {
+			if name == skipping && !selfClosing {
+				if closing {
+					depth--
+				} else {
+					depth++
+				}
+				if depth == 0 {
+					skipping = ""
+				}
+			}
+
+			continue
+		}
+		if skippedTags[name] && !closing && !selfClosing {
+			skipping, depth = name, 1
+
+			continue
+		}
+		if blockTags[name] {
+			sb.WriteString("\n")
+		}
+	}
+	if skipping == "" {
+		sb.WriteString(body[last:])
+	}
+
+	return title, collapse(html.UnescapeString(sb.String()))
+}
+
+// collapse trims every line, squeezes runs of spaces and keeps at most one blank line in a row.
+func collapse(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spacePattern.ReplaceAllString(line, " "))
+	}
+
+	return strings.TrimSpace(linesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// urlPattern matches http(s) links in user messages.
+var urlPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// FindURLs returns up to max distinct links found in text, in order of appearance.
+func FindURLs(text string, max int) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, link := range urlPattern.FindAllString(text, -1) {
+		// Discord wraps links in <> to suppress embeds, and sentences end in punctuation
+		link = strings.TrimRight(link, ".,;:!?*_~|>")
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		urls = append(urls, link)
+		if len(urls) == max {
+			break
+		}
+	}
+
+	return urls
+}
@@ -0,0 +1,225 @@
+// Package webpage fetches web pages and extracts their readable text.
+package webpage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultMaxBytes = 1 << 20
+	defaultMaxChars = 6000
+	defaultTimeout  = 10 * time.Second
+	maxRedirects    = 5
+
+	userAgent = "go-discord-chatgpt link reader"
+)
+
+var (
+	// ErrDomainNotAllowed is returned for links outside the configured domain lists.
+	ErrDomainNotAllowed = errors.New("domain is not allowed")
+	// ErrPrivateAddress is returned for links resolving to loopback or private networks.
+	ErrPrivateAddress = errors.New("address is not public")
+	// ErrUnsupportedContent is returned for responses that are not HTML or text.
+	ErrUnsupportedContent = errors.New("content is not a web page")
+)
+
+// Page is the readable content of a fetched page.
+type Page struct {
+	URL       string
+	Title     string
+	Text      string
+	Truncated bool // Text was cut at the configured size
+}
+
+// Fetcher downloads pages and extracts their text.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Page, error)
+}
+
+// Module provides the page Fetcher.
+var Module = fx.Module("webpage",
+	fx.Provide(NewFetcherProvider),
+)
+
+// NewFetcherProvider creates a Fetcher from the links config.
+func NewFetcherProvider(cfg *config.Config) Fetcher {
+	return NewFetcher(cfg.OpenAI.Links)
+}
+
+// NewFetcher creates a Fetcher honouring the domain lists and size caps of cfg.
+func NewFetcher(cfg config.LinksConfig) Fetcher {
+	f := &fetcher{
+		allowed:  normalizeDomains(cfg.AllowedDomains),
+		denied:   normalizeDomains(cfg.DeniedDomains),
+		maxBytes: int64(cfg.MaxBytes),
+		maxChars: cfg.MaxChars,
+	}
+	if f.maxBytes <= 0 {
+		f.maxBytes = defaultMaxBytes
+	}
+	if f.maxChars <= 0 {
+		f.maxChars = defaultMaxChars
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !cfg.AllowPrivateNetworks {
+		// Checking the dialed address rather than the resolved name also covers
+		// redirects and DNS rebinding
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return ErrPrivateAddress
+			}
+
+			return nil
+		}
+	}
+
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+
+			return f.checkURL(req.URL)
+		},
+	}
+
+	return f
+}
+
+type fetcher struct {
+	client   *http.Client
+	allowed  []string
+	denied   []string
+	maxBytes int64
+	maxChars int
+}
+
+// Fetch downloads rawURL and extracts its title and text.
+func (f *fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link: %w", err)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,text/plain;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("page returned status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	if !isHTML && mediaType != "text/plain" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContent, mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	page := &Page{URL: resp.Request.URL.String()}
+	if isHTML {
+		page.Title, page.Text = ExtractText(string(body))
+	} else {
+		page.Text = strings.TrimSpace(string(body))
+	}
+
+	if runes := []rune(page.Text); len(runes) > f.maxChars {
+		page.Text = string(runes[:f.maxChars])
+		page.Truncated = true
+	}
+
+	return page, nil
+}
+
+// checkURL rejects non-web schemes and domains outside the configured lists.
+func (f *fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported link scheme %q", u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return errors.New("link has no host")
+	}
+	if matchesDomain(host, f.denied) {
+		return fmt.Errorf("%w: %s", ErrDomainNotAllowed, host)
+	}
+	if len(f.allowed) > 0 && !matchesDomain(host, f.allowed) {
+		return fmt.Errorf("%w: %s", ErrDomainNotAllowed, host)
+	}
+
+	return nil
+}
+
+func normalizeDomains(domains []string) []string {
+	out := make([]string, 0, len(domains))
+	for _, d := range domains {
+		if d = strings.ToLower(strings.Trim(strings.TrimSpace(d), ".")); d != "" {
+			out = append(out, d)
+		}
+	}
+
+	return out
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of one.
+func matchesDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPublic reports whether ip is a globally routable unicast address.
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
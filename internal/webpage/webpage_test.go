@@ -0,0 +1,143 @@
+package webpage_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/webpage"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html><head><title>Cats &amp; Dogs</title><style>body { color: red; }</style></head>
+<body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<article>
+<h1>Why cats nap</h1>
+<p>Cats sleep   up to <b>16 hours</b> a day.</p>
+<script>track("visit")</script>
+<p>Kittens &lt;3 naps.<br>So do old cats.</p>
+<aside><div>Related: <div>nested</div> links</div></aside>
+</article>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestExtractText(t *testing.T) {
+	title, text := webpage.ExtractText(articlePage)
+	assert.Equal(t, "Cats & Dogs", title)
+	assert.Equal(t, "Why cats nap\n\nCats sleep up to 16 hours a day.\n\nKittens <3 naps.\nSo do old cats.", text)
+}
+
+func TestExtractText_NoArticle(t *testing.T) {
+	_, text := webpage.ExtractText(`<body><header>Menu</header><!-- hidden --><div>Hello<p>world</p></div></body>`)
+	assert.Equal(t, "Hello\nworld", text)
+}
+
+func TestFindURLs(t *testing.T) {
+	text := "see https://a.example/x, <https://b.example/y> and (https://c.example) then https://a.example/x again"
+
+	assert.Equal(t, []string{"https://a.example/x", "https://b.example/y", "https://c.example"}, webpage.FindURLs(text, 5))
+	assert.Equal(t, []string{"https://a.example/x"}, webpage.FindURLs(text, 1))
+	assert.Empty(t, webpage.FindURLs("no links here", 3))
+}
+
+func localConfig() config.LinksConfig {
+	return config.LinksConfig{AllowPrivateNetworks: true}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(articlePage))
+		case "/notes":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("  plain notes \n"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		case "/moved":
+			http.Redirect(w, r, "/article", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := webpage.NewFetcher(localConfig())
+
+	page, err := fetcher.Fetch(context.Background(), server.URL+"/moved")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/article", page.URL, "the final URL is reported")
+	assert.Equal(t, "Cats & Dogs", page.Title)
+	assert.Contains(t, page.Text, "16 hours")
+	assert.False(t, page.Truncated)
+
+	page, err = fetcher.Fetch(context.Background(), server.URL+"/notes")
+	require.NoError(t, err)
+	assert.Equal(t, "plain notes", page.Text)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/image")
+	require.ErrorIs(t, err, webpage.ErrUnsupportedContent)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/missing")
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestFetch_SizeCaps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Repeat("a", 500) + strings.Repeat("b", 500)))
+	}))
+	defer server.Close()
+
+	cfg := localConfig()
+	cfg.MaxBytes = 600
+	cfg.MaxChars = 550
+	page, err := webpage.NewFetcher(cfg).Fetch(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Len(t, page.Text, 550)
+	assert.True(t, page.Truncated)
+
+	cfg.MaxChars = 0
+	page, err = webpage.NewFetcher(cfg).Fetch(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Len(t, page.Text, 600, "the download stops at max_bytes")
+}
+
+func TestFetch_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	t.Run("PrivateAddress", func(t *testing.T) {
+		_, err := webpage.NewFetcher(config.LinksConfig{}).Fetch(context.Background(), server.URL)
+		assert.ErrorIs(t, err, webpage.ErrPrivateAddress)
+	})
+
+	t.Run("DeniedDomain", func(t *testing.T) {
+		cfg := localConfig()
+		cfg.DeniedDomains = []string{"Example.com"}
+		_, err := webpage.NewFetcher(cfg).Fetch(context.Background(), "https://www.example.com/page")
+		assert.ErrorIs(t, err, webpage.ErrDomainNotAllowed)
+	})
+
+	t.Run("OutsideAllowList", func(t *testing.T) {
+		cfg := localConfig()
+		cfg.AllowedDomains = []string{"docs.example.com"}
+		_, err := webpage.NewFetcher(cfg).Fetch(context.Background(), server.URL)
+		assert.ErrorIs(t, err, webpage.ErrDomainNotAllowed)
+	})
+
+	t.Run("Scheme", func(t *testing.T) {
+		_, err := webpage.NewFetcher(localConfig()).Fetch(context.Background(), "file:///etc/passwd")
+		assert.ErrorContains(t, err, "scheme")
+	})
+}
@@ -0,0 +1,310 @@
+// Package youtube fetches the captions of YouTube videos.
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+)
+
+const (
+	defaultBaseURL   = "https://www.youtube.com"
+	defaultMaxChars  = 24000
+	defaultCacheSize = 128
+	fetchTimeout     = 15 * time.Second
+	maxPageBytes     = 4 << 20
+)
+
+var (
+	// ErrNoCaptions is returned for videos without any caption track.
+	ErrNoCaptions = errors.New("the video has no captions")
+	// ErrNotVideo is returned for links that do not point to a YouTube video.
+	ErrNotVideo = errors.New("not a YouTube video link")
+)
+
+// videoIDPattern matches the 11 character IDs YouTube assigns to videos.
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// playerResponseMarker precedes the JSON describing the video on a watch page.
+const playerResponseMarker = "ytInitialPlayerResponse = "
+
+// Transcript is the caption text of a video.
+type Transcript struct {
+	VideoID   string
+	Title     string
+	Language  string
+	Generated bool // captions were generated by speech recognition
+	Text      string
+	Truncated bool // Text was cut at the configured size
+}
+
+// URL returns the watch link of the video.
+func (t *Transcript) URL() string {
+	return "https://www.youtube.com/watch?v=" + t.VideoID
+}
+
+// TranscriptFetcher fetches video transcripts, caching them by video ID.
+type TranscriptFetcher interface {
+	Fetch(ctx context.Context, videoID string) (*Transcript, error)
+}
+
+// Module provides the TranscriptFetcher.
+var Module = fx.Module("youtube",
+	fx.Provide(NewTranscriptFetcher),
+)
+
+// VideoID returns the ID of the video rawURL points to. Watch, short,
+// embed, live and youtu.be links are recognised, as are bare IDs.
+func VideoID(rawURL string) (string, bool) {
+	rawURL = strings.Trim(strings.TrimSpace(rawURL), "<>")
+	if videoIDPattern.MatchString(rawURL) {
+		return rawURL, true
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+
+	var id string
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+		} else if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) == 2 {
+			switch parts[0] {
+			case "shorts", "embed", "live", "v":
+				id = parts[1]
+			}
+		}
+	}
+	if !videoIDPattern.MatchString(id) {
+		return "", false
+	}
+
+	return id, true
+}
+
+// NewTranscriptFetcher creates a TranscriptFetcher for youtube.com.
+func NewTranscriptFetcher(logger *zap.Logger, cfg *config.Config) TranscriptFetcher {
+	return NewTranscriptFetcherFromClient(logger, cfg.OpenAI.YouTube, &http.Client{Timeout: fetchTimeout}, defaultBaseURL)
+}
+
+// NewTranscriptFetcherFromClient creates a TranscriptFetcher reading watch
+// pages from baseURL with client.
+func NewTranscriptFetcherFromClient(logger *zap.Logger, cfg config.YouTubeConfig, client *http.Client, baseURL string) TranscriptFetcher {
+	cacheSize := cfg.CacheSize
+	if cacheSize <= 0 {
+		cacheSize = defaultCacheSize
+	}
+	cache, _ := lru.New[string, *Transcript](cacheSize)
+
+	maxChars := cfg.MaxChars
+	if maxChars <= 0 {
+		maxChars = defaultMaxChars
+	}
+	languages := cfg.Languages
+	if len(languages) == 0 {
+		languages = []string{"en"}
+	}
+
+	return &transcriptFetcher{
+		logger:    logger.Named("youtube_transcripts"),
+		client:    client,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		languages: languages,
+		maxChars:  maxChars,
+		cache:     cache,
+	}
+}
+
+type transcriptFetcher struct {
+	logger    *zap.Logger
+	client    *http.Client
+	baseURL   string
+	languages []string
+	maxChars  int
+	cache     *lru.Cache[string, *Transcript]
+}
+
+// captionTrack is one caption language listed in the player response.
+type captionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	Kind         string `json:"kind"` // "asr" for generated captions
+}
+
+type playerResponse struct {
+	VideoDetails struct {
+		Title string `json:"title"`
+	} `json:"videoDetails"`
+	Captions struct {
+		Renderer struct {
+			Tracks []captionTrack `json:"captionTracks"`
+		} `json:"playerCaptionsTracklistRenderer"`
+	} `json:"captions"`
+}
+
+// Fetch returns the transcript of videoID in the most preferred language available.
+func (f *transcriptFetcher) Fetch(ctx context.Context, videoID string) (*Transcript, error) {
+	if !videoIDPattern.MatchString(videoID) {
+		return nil, ErrNotVideo
+	}
+	if t, ok := f.cache.Get(videoID); ok {
+		f.logger.Debug("Transcript cache hit", zap.String("videoID", videoID))
+
+		return t, nil
+	}
+
+	page, err := f.get(ctx, f.baseURL+"/watch?v="+videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load video page: %w", err)
+	}
+	player, err := parsePlayerResponse(page)
+	if err != nil {
+		return nil, err
+	}
+
+	track, ok := pickTrack(player.Captions.Renderer.Tracks, f.languages)
+	if !ok {
+		return nil, ErrNoCaptions
+	}
+	captions, err := f.get(ctx, track.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load captions: %w", err)
+	}
+	text, err := parseCaptions(captions)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return nil, ErrNoCaptions
+	}
+
+	t := &Transcript{
+		VideoID:   videoID,
+		Title:     player.VideoDetails.Title,
+		Language:  track.LanguageCode,
+		Generated: track.Kind == "asr",
+		Text:      text,
+	}
+	if runes := []rune(t.Text); len(runes) > f.maxChars {
+		t.Text = string(runes[:f.maxChars])
+		t.Truncated = true
+	}
+	f.cache.Add(videoID, t)
+
+	f.logger.Info("Fetched transcript",
+		zap.String("videoID", videoID),
+		zap.String("language", t.Language),
+		zap.Int("chars", len(t.Text)),
+		zap.Bool("truncated", t.Truncated))
+
+	return t, nil
+}
+
+func (f *transcriptFetcher) get(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Without a language YouTube may serve a consent page instead of the video
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+}
+
+// parsePlayerResponse decodes the player JSON embedded in a watch page.
+func parsePlayerResponse(page []byte) (*playerResponse, error) {
+	i := strings.Index(string(page), playerResponseMarker)
+	if i < 0 {
+		return nil, errors.New("video page has no player data")
+	}
+
+	var player playerResponse
+	// The decoder stops after the object, ignoring the script that follows
+	dec := json.NewDecoder(strings.NewReader(string(page[i+len(playerResponseMarker):])))
+	if err := dec.Decode(&player); err != nil {
+		return nil, fmt.Errorf("failed to parse player data: %w", err)
+	}
+
+	return &player, nil
+}
+
+// pickTrack chooses the first preferred language, favouring written captions
+// over generated ones, and falls back to the first track.
+func pickTrack(tracks []captionTrack, languages []string) (captionTrack, bool) {
+	if len(tracks) == 0 {
+		return captionTrack{}, false
+	}
+
+	for _, lang := range languages {
+		var generated *captionTrack
+		for i, t := range tracks {
+			if !strings.EqualFold(t.LanguageCode, lang) && !strings.HasPrefix(strings.ToLower(t.LanguageCode), strings.ToLower(lang)+"-") {
+				continue
+			}
+			if t.Kind != "asr" {
+				return t, true
+			}
+			if generated == nil {
+				generated = &tracks[i]
+			}
+		}
+		if generated != nil {
+			return *generated, true
+		}
+	}
+
+	return tracks[0], true
+}
+
+// parseCaptions joins the lines of a timed text document.
+func parseCaptions(doc []byte) (string, error) {
+	var transcript struct {
+		Lines []string `xml:"text"`
+	}
+	if err := xml.Unmarshal(doc, &transcript); err != nil {
+		return "", fmt.Errorf("failed to parse captions: %w", err)
+	}
+
+	lines := make([]string, 0, len(transcript.Lines))
+	for _, line := range transcript.Lines {
+		// Caption text is escaped again inside the XML
+		line = strings.Join(strings.Fields(html.UnescapeString(line)), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, " "), nil
+}
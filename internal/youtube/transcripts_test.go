@@ -0,0 +1,123 @@
+package youtube_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Raikerian/go-discord-chatgpt/internal/config"
+	"github.com/Raikerian/go-discord-chatgpt/internal/youtube"
+)
+
+func TestVideoID(t *testing.T) {
+	for link, want := range map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s": "dQw4w9WgXcQ",
+		"https://m.youtube.com/watch?v=dQw4w9WgXcQ":         "dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?si=abc":               "dQw4w9WgXcQ",
+		"<https://youtube.com/shorts/dQw4w9WgXcQ>":          "dQw4w9WgXcQ",
+		"https://www.youtube.com/embed/dQw4w9WgXcQ":         "dQw4w9WgXcQ",
+		"dQw4w9WgXcQ": "dQw4w9WgXcQ",
+	} {
+		id, ok := youtube.VideoID(link)
+		assert.True(t, ok, link)
+		assert.Equal(t, want, id, link)
+	}
+
+	for _, link := range []string{
+		"https://example.com/watch?v=dQw4w9WgXcQ",
+		"https://www.youtube.com/watch?v=short",
+		"https://www.youtube.com/channel/UC123",
+		"not a link",
+	} {
+		_, ok := youtube.VideoID(link)
+		assert.False(t, ok, link)
+	}
+}
+
+const watchPage = `<html><script>var ytInitialPlayerResponse = {"videoDetails":{"title":"Cats & naps"},` +
+	`"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":[` +
+	`{"baseUrl":"%[1]s/captions?lang=de","languageCode":"de"},` +
+	`{"baseUrl":"%[1]s/captions?lang=en-asr","languageCode":"en","kind":"asr"},` +
+	`{"baseUrl":"%[1]s/captions?lang=en-US","languageCode":"en-US"}]}}};var meta = {};</script></html>`
+
+func newServer(t *testing.T, watchHits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			watchHits.Add(1)
+			if r.URL.Query().Get("v") == "noCaptions1" {
+				_, _ = w.Write([]byte(`var ytInitialPlayerResponse = {"videoDetails":{"title":"Silent"}};`))
+
+				return
+			}
+			_, _ = fmt.Fprintf(w, watchPage, server.URL)
+		case "/captions":
+			_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><transcript><text start="0" dur="1">[%s] Cats</text>`+
+				`<text start="1" dur="2">sleep &amp;#39;a lot&amp;#39;,
+ really</text><text start="3" dur="1"> </text></transcript>`, r.URL.Query().Get("lang"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestTranscriptFetcher_Fetch(t *testing.T) {
+	var hits atomic.Int32
+	server := newServer(t, &hits)
+	fetcher := youtube.NewTranscriptFetcherFromClient(zap.NewNop(), config.YouTubeConfig{}, server.Client(), server.URL)
+
+	transcript, err := fetcher.Fetch(context.Background(), "dQw4w9WgXcQ")
+	require.NoError(t, err)
+	assert.Equal(t, "Cats & naps", transcript.Title)
+	assert.Equal(t, "en-US", transcript.Language, "written captions are preferred over generated ones")
+	assert.False(t, transcript.Generated)
+	assert.Equal(t, "[en-US] Cats sleep 'a lot', really", transcript.Text)
+	assert.Equal(t, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", transcript.URL())
+
+	_, err = fetcher.Fetch(context.Background(), "dQw4w9WgXcQ")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), hits.Load(), "transcripts are cached by video ID")
+}
+
+func TestTranscriptFetcher_Languages(t *testing.T) {
+	var hits atomic.Int32
+	server := newServer(t, &hits)
+
+	cfg := config.YouTubeConfig{Languages: []string{"ja", "de"}, MaxChars: 6}
+	transcript, err := youtube.NewTranscriptFetcherFromClient(zap.NewNop(), cfg, server.Client(), server.URL).
+		Fetch(context.Background(), "dQw4w9WgXcQ")
+	require.NoError(t, err)
+	assert.Equal(t, "de", transcript.Language)
+	assert.Equal(t, "[de] C", transcript.Text)
+	assert.True(t, transcript.Truncated)
+
+	cfg = config.YouTubeConfig{Languages: []string{"ko"}}
+	transcript, err = youtube.NewTranscriptFetcherFromClient(zap.NewNop(), cfg, server.Client(), server.URL).
+		Fetch(context.Background(), "dQw4w9WgXcQ")
+	require.NoError(t, err)
+	assert.Equal(t, "de", transcript.Language, "the first track is used when no language matches")
+}
+
+func TestTranscriptFetcher_Errors(t *testing.T) {
+	var hits atomic.Int32
+	server := newServer(t, &hits)
+	fetcher := youtube.NewTranscriptFetcherFromClient(zap.NewNop(), config.YouTubeConfig{}, server.Client(), server.URL)
+
+	_, err := fetcher.Fetch(context.Background(), "noCaptions1")
+	require.ErrorIs(t, err, youtube.ErrNoCaptions)
+
+	_, err = fetcher.Fetch(context.Background(), "../etc")
+	require.ErrorIs(t, err, youtube.ErrNotVideo)
+}
@@ -9,17 +9,33 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Raikerian/go-discord-chatgpt/internal/adminapi"
 	"github.com/Raikerian/go-discord-chatgpt/internal/app"
+	"github.com/Raikerian/go-discord-chatgpt/internal/backup"
 	"github.com/Raikerian/go-discord-chatgpt/internal/bot"
+	"github.com/Raikerian/go-discord-chatgpt/internal/characters"
 	"github.com/Raikerian/go-discord-chatgpt/internal/chat"
 	"github.com/Raikerian/go-discord-chatgpt/internal/commands"
 	"github.com/Raikerian/go-discord-chatgpt/internal/config"
 	"github.com/Raikerian/go-discord-chatgpt/internal/discord"
+	"github.com/Raikerian/go-discord-chatgpt/internal/hooks"
+	"github.com/Raikerian/go-discord-chatgpt/internal/i18n"
 	"github.com/Raikerian/go-discord-chatgpt/internal/infrastructure"
+	"github.com/Raikerian/go-discord-chatgpt/internal/metrics"
+	"github.com/Raikerian/go-discord-chatgpt/internal/moderation"
 	"github.com/Raikerian/go-discord-chatgpt/internal/openai"
+	"github.com/Raikerian/go-discord-chatgpt/internal/permissions"
+	"github.com/Raikerian/go-discord-chatgpt/internal/prompts"
+	"github.com/Raikerian/go-discord-chatgpt/internal/retention"
+	"github.com/Raikerian/go-discord-chatgpt/internal/storage"
 	"github.com/Raikerian/go-discord-chatgpt/internal/voice"
+	"github.com/Raikerian/go-discord-chatgpt/internal/webpage"
+	"github.com/Raikerian/go-discord-chatgpt/internal/youtube"
 
 	_ "github.com/WqyJh/go-openai-realtime"
+	// database/sql drivers of the sqlite and postgres storage backends
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 
 	pkginfra "github.com/Raikerian/go-discord-chatgpt/pkg/infrastructure"
 
@@ -30,20 +46,43 @@ func main() {
 	// Set a default config path. This can be overridden by environment variables or flags if needed.
 	configPath := "config.yaml"
 
+	// backup and restore run without starting the bot
+	if handled, err := runCLI(configPath, os.Args[1:]); handled {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	// Create the application with all modules
 	application := app.New(
 		// Core modules
 		config.Module,
 		infrastructure.LoggerModule,
+		i18n.Module,
+		metrics.Module,
 
 		// External service modules
 		discord.Module,
 		openai.Module,
+		storage.Module,
 
 		// Application modules
+		characters.Module,
+		prompts.Module,
+		moderation.Module,
+		permissions.Module,
+		hooks.Module,
+		webpage.Module,
+		youtube.Module,
 		chat.Module,
 		voice.Module,
+		backup.Module,
 		commands.Module,
+		retention.Module,
+		adminapi.Module,
 		bot.Module,
 
 		// Supply the config path
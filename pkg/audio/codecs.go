@@ -0,0 +1,141 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"layeh.com/gopus"
+)
+
+const (
+	// maxOpusPacket is the largest encoded frame, as recommended by libopus.
+	maxOpusPacket = 4000
+	// maxIdleEncoders bounds the idle encoders kept per preset.
+	maxIdleEncoders = 8
+)
+
+// OpusPreset is an encoder configuration. Encoders are pooled by preset, so
+// switching one session's quality never reconfigures another's encoder.
+type OpusPreset struct {
+	Bitrate int // Bits per second
+}
+
+// EncoderPool hands out Opus encoders by preset and reuses the ones put
+// back, so sessions can each own an encoder without creating one per
+// response. It is safe for concurrent use; the encoders it returns are not.
+type EncoderPool struct {
+	mu   sync.Mutex
+	idle map[OpusPreset][]*gopus.Encoder
+}
+
+// NewEncoderPool creates an empty EncoderPool.
+func NewEncoderPool() *EncoderPool {
+	return &EncoderPool{idle: make(map[OpusPreset][]*gopus.Encoder)}
+}
+
+// Get returns an encoder for preset, reusing an idle one when there is one.
+func (p *EncoderPool) Get(preset OpusPreset) (*OpusEncoder, error) {
+	p.mu.Lock()
+	if idle := p.idle[preset]; len(idle) > 0 {
+		enc := idle[len(idle)-1]
+		p.idle[preset] = idle[:len(idle)-1]
+		p.mu.Unlock()
+
+		return &OpusEncoder{preset: preset, enc: enc}, nil
+	}
+	p.mu.Unlock()
+
+	enc, err := gopus.NewEncoder(DiscordSampleRate, DiscordChannels, gopus.Voip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create opus encoder: %w", err)
+	}
+	enc.SetBitrate(preset.Bitrate)
+
+	return &OpusEncoder{preset: preset, enc: enc}, nil
+}
+
+// Put returns e to the pool once its owner is done with it. e must not be
+// used afterwards.
+func (p *EncoderPool) Put(e *OpusEncoder) {
+	if e == nil {
+		return
+	}
+	// The next owner starts a new stream
+	e.enc.ResetState()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.idle[e.preset]) < maxIdleEncoders {
+		p.idle[e.preset] = append(p.idle[e.preset], e.enc)
+	}
+}
+
+// OpusEncoder encodes one stream of 20-ms mono frames for Discord. It keeps
+// state between frames, so each stream needs its own.
+type OpusEncoder struct {
+	preset OpusPreset
+	enc    *gopus.Encoder
+}
+
+// Preset returns the configuration e encodes with.
+func (e *OpusEncoder) Preset() OpusPreset {
+	return e.preset
+}
+
+// Encode encodes one 20-ms mono frame (960 samples @48 k) into a
+// Discord-ready Opus packet (interleaved stereo, 48 k clock).
+func (e *OpusEncoder) Encode(pcm48 []int16) ([]byte, error) {
+	if len(pcm48) != DiscordFrameSize {
+		return nil, fmt.Errorf("need 960 samples, got %d", len(pcm48))
+	}
+
+	return e.enc.Encode(monoToStereo(pcm48), DiscordFrameSize, maxOpusPacket)
+}
+
+// StreamDecoders decodes many Opus streams, each with its own decoder since
+// decoding depends on a stream's earlier packets. It is safe for concurrent
+// use.
+type StreamDecoders struct {
+	mu       sync.Mutex
+	decoders map[uint32]*gopus.Decoder // key: SSRC
+}
+
+// NewStreamDecoders creates decoders for the streams of one session.
+func NewStreamDecoders() *StreamDecoders {
+	return &StreamDecoders{decoders: make(map[uint32]*gopus.Decoder)}
+}
+
+// Decode decodes a packet of ssrc into 48 k mono, 960 samples.
+func (d *StreamDecoders) Decode(ssrc uint32, opus []byte) ([]int16, error) {
+	if len(opus) == 0 {
+		return nil, errors.New("opus payload empty")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dec, ok := d.decoders[ssrc]
+	if !ok {
+		var err error
+		if dec, err = gopus.NewDecoder(DiscordSampleRate, DiscordChannels); err != nil {
+			return nil, fmt.Errorf("failed to create opus decoder: %w", err)
+		}
+		d.decoders[ssrc] = dec
+	}
+	raw, err := dec.Decode(opus, DiscordFrameSize, false)
+	if err != nil {
+		return nil, fmt.Errorf("opus decode: %w", err)
+	}
+
+	return stereoToMono(raw), nil
+}
+
+// RemoveStream forgets the decoder of ssrc.
+func (d *StreamDecoders) RemoveStream(ssrc uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.decoders, ssrc)
+}
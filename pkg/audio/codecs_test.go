@@ -0,0 +1,66 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+func toneFrame() []int16 {
+	frame := make([]int16, audio.DiscordFrameSize)
+	for i := range frame {
+		frame[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/audio.DiscordSampleRate))
+	}
+
+	return frame
+}
+
+func TestEncoderPool(t *testing.T) {
+	pool := audio.NewEncoderPool()
+	full := audio.OpusPreset{Bitrate: 48000}
+
+	enc, err := pool.Get(full)
+	require.NoError(t, err)
+	assert.Equal(t, full, enc.Preset())
+	packet, err := enc.Encode(toneFrame())
+	require.NoError(t, err)
+	assert.NotEmpty(t, packet)
+	_, err = enc.Encode(make([]int16, audio.OpenAIFrameSize))
+	assert.Error(t, err, "frames must be 20 ms at 48 kHz")
+	pool.Put(enc)
+
+	// Encoders are handed out by preset
+	low, err := pool.Get(audio.OpusPreset{Bitrate: 24000})
+	require.NoError(t, err)
+	assert.Equal(t, 24000, low.Preset().Bitrate)
+	again, err := pool.Get(full)
+	require.NoError(t, err)
+	assert.Equal(t, full, again.Preset())
+
+	pool.Put(nil)
+}
+
+func TestStreamDecoders(t *testing.T) {
+	pool := audio.NewEncoderPool()
+	enc, err := pool.Get(audio.OpusPreset{Bitrate: 48000})
+	require.NoError(t, err)
+	packet, err := enc.Encode(toneFrame())
+	require.NoError(t, err)
+
+	decoders := audio.NewStreamDecoders()
+	for _, ssrc := range []uint32{1, 2} {
+		pcm, err := decoders.Decode(ssrc, packet)
+		require.NoError(t, err)
+		assert.Len(t, pcm, audio.DiscordFrameSize)
+	}
+	decoders.RemoveStream(1)
+	_, err = decoders.Decode(1, packet)
+	require.NoError(t, err, "a removed stream starts over with a new decoder")
+
+	_, err = decoders.Decode(1, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package audio
+
+import "sync"
+
+// NoiseGate decides per stream which frames reach the mixer. A stream's gate
+// opens on a frame above the detector's threshold and closes again once the
+// stream has been silent for hold frames, so streams carrying only silence or
+// hum are never mixed while the quiet ends of words still are. All methods
+// are safe for concurrent use.
+type NoiseGate struct {
+	detector *SilenceDetector
+	hold     int
+
+	mu   sync.Mutex
+	open map[uint32]int // key: SSRC, value: silent frames left before the gate closes
+}
+
+// NewNoiseGate creates a gate that classifies frames with detector and keeps
+// a stream open for hold silent frames after its last speech.
+func NewNoiseGate(detector *SilenceDetector, hold int) *NoiseGate {
+	return &NoiseGate{
+		detector: detector,
+		hold:     hold,
+		open:     make(map[uint32]int),
+	}
+}
+
+// Admit reports whether pcm from ssrc should be mixed, and whether it is
+// speech. A nil gate admits every frame as speech.
+func (g *NoiseGate) Admit(ssrc uint32, pcm []int16) (admit, speech bool) {
+	if g == nil {
+		return true, true
+	}
+	silent, _ := g.detector.IsSilent(pcm)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !silent {
+		g.open[ssrc] = g.hold
+
+		return true, true
+	}
+	left, ok := g.open[ssrc]
+	if !ok {
+		return false, false
+	}
+	if left <= 0 {
+		delete(g.open, ssrc)
+
+		return false, false
+	}
+	g.open[ssrc] = left - 1
+
+	return true, false
+}
+
+// RemoveStream forgets ssrc, closing its gate.
+func (g *NoiseGate) RemoveStream(ssrc uint32) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.open, ssrc)
+}
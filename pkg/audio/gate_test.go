@@ -0,0 +1,54 @@
+package audio_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+func TestNoiseGate(t *testing.T) {
+	hum := make([]int16, audio.DiscordFrameSize)
+	speech := make([]int16, audio.DiscordFrameSize)
+	for i := range speech {
+		speech[i] = 8000
+		hum[i] = 100
+	}
+
+	g := audio.NewNoiseGate(audio.NewSilenceDetector(0.01, time.Second), 2)
+
+	// A hum-only stream never reaches the mixer
+	for range 5 {
+		admit, _ := g.Admit(1, hum)
+		assert.False(t, admit)
+	}
+
+	admit, isSpeech := g.Admit(2, speech)
+	assert.True(t, admit)
+	assert.True(t, isSpeech)
+
+	// The gate stays open for the hold frames after speech, then closes
+	for range 2 {
+		admit, isSpeech = g.Admit(2, hum)
+		assert.True(t, admit)
+		assert.False(t, isSpeech)
+	}
+	admit, _ = g.Admit(2, hum)
+	assert.False(t, admit)
+
+	// Streams are gated independently
+	g.Admit(2, speech)
+	admit, _ = g.Admit(1, hum)
+	assert.False(t, admit)
+
+	g.RemoveStream(2)
+	admit, _ = g.Admit(2, hum)
+	assert.False(t, admit)
+
+	var disabled *audio.NoiseGate
+	admit, isSpeech = disabled.Admit(1, hum)
+	assert.True(t, admit)
+	assert.True(t, isSpeech)
+}
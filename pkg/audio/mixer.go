@@ -23,12 +23,24 @@ type AudioMixer interface {
 	// Returns mono PCM samples at 48-kHz, then empties the mixer.
 	Drain() []int16
 
+	// DrainStreams returns the mix together with each stream's own audio,
+	// aligned on the same timeline, and clears the mixer state like Drain.
+	DrainStreams() (mixed []int16, streams map[uint32][]int16)
+
+	// GetDominantSpeaker returns the SSRC whose buffered audio carries the
+	// most energy, or false when nothing is buffered.
+	GetDominantSpeaker() (uint32, bool)
+
 	// Clear discards all audio data without returning it.
 	// Immediately resets mixer to initial state.
 	Clear()
 
 	// Len returns the number of mixed samples currently buffered.
 	Len() int
+
+	// RemoveStream forgets the timing state for ssrc, e.g. when its user leaves.
+	// Audio already mixed into the buffer is kept.
+	RemoveStream(ssrc uint32)
 }
 
 // --------------------------- implementation ---------------------------
@@ -56,6 +68,13 @@ type streamState struct {
 	lastFrame  int64
 }
 
+// track is the unmixed audio of one SSRC since the last drain, on the shared
+// mix timeline.
+type track struct {
+	samples []int16
+	energy  float64 // Sum of squared normalized samples
+}
+
 // mixer is a thread-safe implementation of AudioMixer.
 type mixer struct {
 	mu sync.Mutex
@@ -63,6 +82,10 @@ type mixer struct {
 	// Per-SSRC timing information
 	streams map[uint32]*streamState
 
+	// Per-SSRC unmixed audio, kept until the next drain even after
+	// RemoveStream
+	tracks map[uint32]*track
+
 	// Mixed audio buffer in *samples* (int32 to avoid overflow during summing).
 	// Length == nFrames * samplesPerFrame.
 	buffer []int32
@@ -72,6 +95,7 @@ type mixer struct {
 func NewAudioMixer() AudioMixer {
 	return &mixer{
 		streams: make(map[uint32]*streamState),
+		tracks:  make(map[uint32]*track),
 	}
 }
 
@@ -121,6 +145,21 @@ func (m *mixer) AddFrame(ssrc, ts uint32, pcm []int16) error {
 		m.buffer[int(offset)+i] += int32(pcm[i])
 	}
 
+	// 5. Keep the stream's own copy for speaker-attributed commits.
+	tr, ok := m.tracks[ssrc]
+	if !ok {
+		tr = &track{}
+		m.tracks[ssrc] = tr
+	}
+	if int(neededSamples) > len(tr.samples) {
+		tr.samples = append(tr.samples, make([]int16, int(neededSamples)-len(tr.samples))...)
+	}
+	copy(tr.samples[offset:], pcm)
+	for _, v := range pcm {
+		f := float64(v) / 32768
+		tr.energy += f * f
+	}
+
 	return nil
 }
 
@@ -140,21 +179,64 @@ func (m *mixer) Drain() []int16 {
 
 	out := m.copyBuffer()
 
-	// Reset everything to initial state so memory doesn't grow unbounded and
-	// new speakers anchor themselves relative to a fresh timeline.
-	m.buffer = nil
-	m.streams = make(map[uint32]*streamState)
+	m.reset()
 
 	return out
 }
 
+// DrainStreams returns the mix and every stream's track, each padded to the
+// length of the mix, and resets the internal state like Drain.
+func (m *mixer) DrainStreams() ([]int16, map[uint32][]int16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := m.copyBuffer()
+	streams := make(map[uint32][]int16, len(m.tracks))
+	for ssrc, tr := range m.tracks {
+		samples := make([]int16, len(out))
+		copy(samples, tr.samples)
+		streams[ssrc] = samples
+	}
+
+	m.reset()
+
+	return out, streams
+}
+
+// GetDominantSpeaker returns the SSRC with the most energy since the last drain.
+func (m *mixer) GetDominantSpeaker() (uint32, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var (
+		dominant uint32
+		best     float64
+		found    bool
+	)
+	for ssrc, tr := range m.tracks {
+		if !found || tr.energy > best || (tr.energy == best && ssrc < dominant) {
+			dominant, best, found = ssrc, tr.energy, true
+		}
+	}
+
+	return dominant, found
+}
+
 // Clear discards all buffered audio *and* resets stream timing information.
 func (m *mixer) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.buffer = nil
-	m.streams = make(map[uint32]*streamState)
+	m.reset()
+}
+
+// RemoveStream drops the timing state for ssrc so that a later stream reusing
+// it anchors itself afresh.
+func (m *mixer) RemoveStream(ssrc uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.streams, ssrc)
 }
 
 // Len returns the number of mixed samples currently buffered.
@@ -167,6 +249,15 @@ func (m *mixer) Len() int {
 
 /* --------------------------- helpers --------------------------- */
 
+// reset empties the mix, the tracks and the stream timing, so memory doesn't
+// grow unbounded and new speakers anchor themselves relative to a fresh
+// timeline. The caller must hold m.mu.
+func (m *mixer) reset() {
+	m.buffer = nil
+	m.streams = make(map[uint32]*streamState)
+	m.tracks = make(map[uint32]*track)
+}
+
 // copyBuffer converts the int32 accumulator to int16 with simple saturation and
 // returns a *new* slice so callers can safely modify it.
 func (m *mixer) copyBuffer() []int16 {
@@ -1 +1,88 @@
 package audio_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+	"github.com/Raikerian/go-discord-chatgpt/pkg/test"
+)
+
+// The generated mock must stay in sync with the single AudioMixer interface.
+var _ audio.AudioMixer = (*test.MockAudioMixer)(nil)
+
+func frame(v int16) []int16 {
+	pcm := make([]int16, audio.DiscordFrameSize)
+	for i := range pcm {
+		pcm[i] = v
+	}
+
+	return pcm
+}
+
+func TestMixerAlignsStreamsByRTPTimestamp(t *testing.T) {
+	m := audio.NewAudioMixer()
+
+	// A new stream anchors at the current end of the mix, so B's first frame
+	// lines up with A's second frame despite unrelated RTP clocks.
+	require.NoError(t, m.AddFrame(1, 1000, frame(100)))
+	require.NoError(t, m.AddFrame(2, 50000, frame(10)))
+	require.NoError(t, m.AddFrame(1, 1000+audio.DiscordFrameSize, frame(100)))
+
+	mixed := m.Drain()
+	require.Len(t, mixed, 2*audio.DiscordFrameSize)
+	assert.Equal(t, int16(100), mixed[0])
+	assert.Equal(t, int16(110), mixed[audio.DiscordFrameSize])
+	assert.Equal(t, 0, m.Len(), "drain empties the mixer")
+}
+
+func TestMixerRejectsWrongFrameSize(t *testing.T) {
+	m := audio.NewAudioMixer()
+
+	assert.Error(t, m.AddFrame(1, 0, make([]int16, 10)))
+}
+
+func TestMixerRemoveStream(t *testing.T) {
+	m := audio.NewAudioMixer()
+
+	require.NoError(t, m.AddFrame(1, 0, frame(1)))
+	require.NoError(t, m.AddFrame(1, audio.DiscordFrameSize, frame(1)))
+
+	// After removal the SSRC re-anchors at the end of the mix instead of
+	// being aligned to its old base timestamp.
+	m.RemoveStream(1)
+	require.NoError(t, m.AddFrame(1, 0, frame(5)))
+
+	mixed := m.GetMixed()
+	require.Len(t, mixed, 3*audio.DiscordFrameSize)
+	assert.Equal(t, int16(1), mixed[0])
+	assert.Equal(t, int16(5), mixed[2*audio.DiscordFrameSize])
+}
+
+func TestMixerDrainStreams(t *testing.T) {
+	m := audio.NewAudioMixer()
+
+	require.NoError(t, m.AddFrame(1, 0, frame(100)))
+	require.NoError(t, m.AddFrame(2, 0, frame(3000)))
+	require.NoError(t, m.AddFrame(1, audio.DiscordFrameSize, frame(100)))
+
+	dominant, ok := m.GetDominantSpeaker()
+	require.True(t, ok)
+	assert.Equal(t, uint32(2), dominant, "the loudest stream dominates, not the longest")
+
+	mixed, streams := m.DrainStreams()
+	require.Len(t, mixed, 2*audio.DiscordFrameSize)
+	assert.Equal(t, int16(3100), mixed[audio.DiscordFrameSize])
+	require.Len(t, streams, 2)
+	assert.Len(t, streams[2], len(mixed), "tracks share the mix timeline")
+	assert.Equal(t, int16(0), streams[2][0])
+	assert.Equal(t, int16(3000), streams[2][audio.DiscordFrameSize])
+	assert.Equal(t, int16(100), streams[1][0])
+	assert.Equal(t, int16(100), streams[1][audio.DiscordFrameSize])
+
+	_, ok = m.GetDominantSpeaker()
+	assert.False(t, ok, "draining forgets the tracks")
+	assert.Equal(t, 0, m.Len())
+}
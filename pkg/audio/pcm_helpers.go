@@ -20,3 +20,12 @@ func LEToPCMInt16(b []byte) []int16 {
 
 	return out
 }
+
+// FadeOut ramps the gain of samples linearly from full down to silence, in
+// place, so audio cut short ends without a click.
+func FadeOut(samples []int16) {
+	n := len(samples)
+	for i := range samples {
+		samples[i] = int16(int32(samples[i]) * int32(n-1-i) / int32(max(n-1, 1)))
+	}
+}
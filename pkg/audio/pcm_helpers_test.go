@@ -0,0 +1,21 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+func TestFadeOut(t *testing.T) {
+	samples := []int16{1000, 1000, -1000, 1000, 1000}
+	audio.FadeOut(samples)
+	assert.Equal(t, []int16{1000, 750, -500, 250, 0}, samples)
+
+	single := []int16{1000}
+	audio.FadeOut(single)
+	assert.Equal(t, []int16{0}, single, "a lone sample fades straight to silence")
+
+	audio.FadeOut(nil)
+}
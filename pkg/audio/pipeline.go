@@ -0,0 +1,285 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Stage names accepted by NewStage.
+const (
+	StageGain     = "gain"     // Fixed gain; params: db (default 0)
+	StageHighPass = "highpass" // Removes DC offset and rumble; params: cutoff_hz (default 80)
+	StageDenoise  = "denoise"  // Attenuates frames below a noise floor; params: threshold (default 0.01), reduction (default 0.1)
+	StageAGC      = "agc"      // Evens out loudness; params: target (default 0.1), max_gain (default 8), speed (default 0.1)
+)
+
+// agcFloor is the energy below which AGC leaves its gain alone, so silence
+// is never amplified into hiss.
+const agcFloor = 0.002
+
+// StageSpec configures one stage of an audio pipeline.
+type StageSpec struct {
+	Name   string
+	Params map[string]float64
+}
+
+// Stage transforms mono PCM in place and returns it. Stages may keep state
+// from one call to the next, so each stream needs its own.
+type Stage interface {
+	Process(pcm []int16) []int16
+}
+
+// NewStage creates the stage spec describes for audio at sampleRate. Unknown
+// stages and parameters are errors, so typos in config are caught.
+func NewStage(spec StageSpec, sampleRate int) (Stage, error) {
+	var allowed []string
+	switch spec.Name {
+	case StageGain:
+		allowed = []string{"db"}
+	case StageHighPass:
+		allowed = []string{"cutoff_hz"}
+	case StageDenoise:
+		allowed = []string{"threshold", "reduction"}
+	case StageAGC:
+		allowed = []string{"target", "max_gain", "speed"}
+	default:
+		return nil, fmt.Errorf("unknown audio stage %q", spec.Name)
+	}
+	for name := range spec.Params {
+		if !slices.Contains(allowed, name) {
+			return nil, fmt.Errorf("unknown parameter %q for audio stage %q", name, spec.Name)
+		}
+	}
+	param := func(name string, def float64) float64 {
+		if v, ok := spec.Params[name]; ok {
+			return v
+		}
+
+		return def
+	}
+
+	switch spec.Name {
+	case StageGain:
+		return &gainStage{gain: math.Pow(10, param("db", 0)/20)}, nil
+	case StageHighPass:
+		cutoff := param("cutoff_hz", 80)
+		if cutoff <= 0 || cutoff >= float64(sampleRate)/2 {
+			return nil, fmt.Errorf("highpass cutoff_hz must be between 0 and %d", sampleRate/2)
+		}
+		rc := 1 / (2 * math.Pi * cutoff)
+		dt := 1 / float64(sampleRate)
+
+		return &highPassStage{alpha: rc / (rc + dt)}, nil
+	case StageDenoise:
+		reduction := param("reduction", 0.1)
+		if reduction < 0 || reduction > 1 {
+			return nil, errors.New("denoise reduction must be between 0 and 1")
+		}
+
+		return &denoiseStage{threshold: float32(param("threshold", 0.01)), reduction: reduction}, nil
+	default:
+		speed := param("speed", 0.1)
+		if speed <= 0 || speed > 1 {
+			return nil, errors.New("agc speed must be between 0 and 1")
+		}
+
+		return &agcStage{target: param("target", 0.1), maxGain: max(param("max_gain", 8), 1), speed: speed, gain: 1}, nil
+	}
+}
+
+// Chain runs stages in order. An empty Chain leaves audio unchanged.
+type Chain []Stage
+
+// NewChain creates the stages of specs, in order, for audio at sampleRate.
+func NewChain(specs []StageSpec, sampleRate int) (Chain, error) {
+	chain := make(Chain, 0, len(specs))
+	for _, spec := range specs {
+		stage, err := NewStage(spec, sampleRate)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, stage)
+	}
+
+	return chain, nil
+}
+
+// Process runs pcm through every stage of the chain.
+func (c Chain) Process(pcm []int16) []int16 {
+	for _, stage := range c {
+		pcm = stage.Process(pcm)
+	}
+
+	return pcm
+}
+
+// StreamPipeline runs the same stages on many streams, each with its own
+// Chain so stateful stages do not mix streams up. A nil *StreamPipeline
+// leaves audio unchanged. All methods are safe for concurrent use.
+type StreamPipeline struct {
+	specs      []StageSpec
+	sampleRate int
+
+	mu     sync.Mutex
+	chains map[uint32]Chain // key: SSRC
+}
+
+// NewStreamPipeline creates a pipeline of specs for streams at sampleRate,
+// checking the specs once up front. It returns nil for empty specs.
+func NewStreamPipeline(specs []StageSpec, sampleRate int) (*StreamPipeline, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	if _, err := NewChain(specs, sampleRate); err != nil {
+		return nil, err
+	}
+
+	return &StreamPipeline{specs: specs, sampleRate: sampleRate, chains: make(map[uint32]Chain)}, nil
+}
+
+// Process runs a frame of ssrc through that stream's chain.
+func (p *StreamPipeline) Process(ssrc uint32, pcm []int16) []int16 {
+	if p == nil {
+		return pcm
+	}
+
+	p.mu.Lock()
+	chain, ok := p.chains[ssrc]
+	if !ok {
+		// The specs were checked when the pipeline was created
+		chain, _ = NewChain(p.specs, p.sampleRate)
+		p.chains[ssrc] = chain
+	}
+	p.mu.Unlock()
+
+	return chain.Process(pcm)
+}
+
+// RemoveStream forgets the state kept for ssrc.
+func (p *StreamPipeline) RemoveStream(ssrc uint32) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.chains, ssrc)
+}
+
+// clip16 rounds v to the nearest int16, saturating at its limits.
+func clip16(v float64) int16 {
+	return int16(max(min(math.Round(v), math.MaxInt16), math.MinInt16))
+}
+
+type gainStage struct {
+	gain float64
+}
+
+func (g *gainStage) Process(pcm []int16) []int16 {
+	for i, v := range pcm {
+		pcm[i] = clip16(float64(v) * g.gain)
+	}
+
+	return pcm
+}
+
+// highPassStage is a one-pole high-pass filter.
+type highPassStage struct {
+	alpha        float64
+	prevIn, prev float64
+}
+
+func (h *highPassStage) Process(pcm []int16) []int16 {
+	for i, v := range pcm {
+		in := float64(v)
+		h.prev = h.alpha * (h.prev + in - h.prevIn)
+		h.prevIn = in
+		pcm[i] = clip16(h.prev)
+	}
+
+	return pcm
+}
+
+// denoiseStage is a downward expander: frames quieter than the threshold
+// are turned down by reduction, keeping background hiss out of the mix.
+type denoiseStage struct {
+	threshold float32
+	reduction float64
+}
+
+func (d *denoiseStage) Process(pcm []int16) []int16 {
+	if Energy(pcm) >= d.threshold {
+		return pcm
+	}
+	for i, v := range pcm {
+		pcm[i] = clip16(float64(v) * d.reduction)
+	}
+
+	return pcm
+}
+
+// agcStage moves its gain a little towards the one that brings each frame
+// to the target energy, so loud and quiet speakers end up alike.
+type agcStage struct {
+	target, maxGain, speed float64
+	gain                   float64
+}
+
+func (a *agcStage) Process(pcm []int16) []int16 {
+	if energy := float64(Energy(pcm)); energy >= agcFloor {
+		want := min(a.target/energy, a.maxGain)
+		a.gain += (want - a.gain) * a.speed
+	}
+	for i, v := range pcm {
+		pcm[i] = clip16(float64(v) * a.gain)
+	}
+
+	return pcm
+}
+
+// Decimate resamples src from srcRate to dstRate, a whole fraction of it,
+// by keeping every srcRate/dstRate-th sample. It is the cheapest way down,
+// but whatever is above the new Nyquist frequency aliases into the output.
+func Decimate(src []int16, srcRate, dstRate int) ([]int16, error) {
+	if len(src) == 0 {
+		return nil, errors.New("pcm empty")
+	}
+	if srcRate <= dstRate || srcRate%dstRate != 0 {
+		return nil, fmt.Errorf("unsupported ratio %d:%d", srcRate, dstRate)
+	}
+	factor := srcRate / dstRate
+	dst := make([]int16, 0, (len(src)+factor-1)/factor)
+	for i := 0; i < len(src); i += factor {
+		dst = append(dst, src[i])
+	}
+
+	return dst, nil
+}
+
+// DownsampleAverage resamples src from srcRate to dstRate, a whole fraction
+// of it, by averaging each group of samples. It costs a little more than
+// decimation but keeps high frequencies from aliasing into speech.
+func DownsampleAverage(src []int16, srcRate, dstRate int) ([]int16, error) {
+	if len(src) == 0 {
+		return nil, errors.New("pcm empty")
+	}
+	if srcRate <= dstRate || srcRate%dstRate != 0 {
+		return nil, fmt.Errorf("unsupported ratio %d:%d", srcRate, dstRate)
+	}
+	factor := srcRate / dstRate
+	dst := make([]int16, 0, (len(src)+factor-1)/factor)
+	for i := 0; i < len(src); i += factor {
+		group := src[i:min(i+factor, len(src))]
+		var sum int32
+		for _, v := range group {
+			sum += int32(v)
+		}
+		dst = append(dst, int16(sum/int32(len(group))))
+	}
+
+	return dst, nil
+}
@@ -0,0 +1,79 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+func constantFrame(v int16) []int16 {
+	frame := make([]int16, audio.DiscordFrameSize)
+	for i := range frame {
+		frame[i] = v
+	}
+
+	return frame
+}
+
+func TestNewChain_Invalid(t *testing.T) {
+	_, err := audio.NewChain([]audio.StageSpec{{Name: "reverb"}}, audio.DiscordSampleRate)
+	require.Error(t, err)
+	_, err = audio.NewChain([]audio.StageSpec{{Name: audio.StageGain, Params: map[string]float64{"dB": 6}}}, audio.DiscordSampleRate)
+	require.Error(t, err, "parameter names are checked")
+	_, err = audio.NewChain([]audio.StageSpec{{Name: audio.StageHighPass, Params: map[string]float64{"cutoff_hz": 30000}}}, audio.DiscordSampleRate)
+	require.Error(t, err)
+}
+
+func TestChain(t *testing.T) {
+	chain, err := audio.NewChain([]audio.StageSpec{
+		{Name: audio.StageGain, Params: map[string]float64{"db": 6}},
+		{Name: audio.StageDenoise, Params: map[string]float64{"threshold": 0.01, "reduction": 0}},
+	}, audio.DiscordSampleRate)
+	require.NoError(t, err)
+
+	assert.InDelta(t, 2000, chain.Process(constantFrame(1000))[0], 5, "+6 dB doubles the level")
+	assert.Equal(t, int16(0), chain.Process(constantFrame(100))[0], "frames under the noise floor are muted")
+	assert.Equal(t, int16(32767), chain.Process(constantFrame(30000))[0], "gain saturates instead of wrapping")
+}
+
+func TestHighPass_RemovesDCOffset(t *testing.T) {
+	chain, err := audio.NewChain([]audio.StageSpec{{Name: audio.StageHighPass}}, audio.DiscordSampleRate)
+	require.NoError(t, err)
+
+	var last []int16
+	for range 10 {
+		last = chain.Process(constantFrame(5000))
+	}
+	assert.InDelta(t, 0, last[len(last)-1], 1)
+}
+
+func TestStreamPipeline_AGC(t *testing.T) {
+	p, err := audio.NewStreamPipeline([]audio.StageSpec{{Name: audio.StageAGC, Params: map[string]float64{"target": 0.1, "speed": 0.5}}}, audio.DiscordSampleRate)
+	require.NoError(t, err)
+
+	var quiet, loud []int16
+	for range 20 {
+		quiet = p.Process(1, constantFrame(800))
+		loud = p.Process(2, constantFrame(16000))
+	}
+	assert.InDelta(t, 3277, quiet[0], 100, "a quiet speaker is brought up to the target")
+	assert.InDelta(t, 3277, loud[0], 100, "a loud speaker is brought down to the target, on its own gain")
+
+	var none *audio.StreamPipeline
+	assert.Equal(t, int16(800), none.Process(1, constantFrame(800))[0])
+	none, err = audio.NewStreamPipeline(nil, audio.DiscordSampleRate)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+}
+
+func TestDownsampleAverage(t *testing.T) {
+	out, err := audio.DownsampleAverage([]int16{100, 300, -50, 50, 7}, 48000, 24000)
+	require.NoError(t, err)
+	assert.Equal(t, []int16{200, 0, 7}, out)
+
+	_, err = audio.DownsampleAverage([]int16{1}, 24000, 48000)
+	require.Error(t, err)
+}
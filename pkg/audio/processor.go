@@ -40,6 +40,9 @@ type audioProcessor struct {
 	opusDecoder *gopus.Decoder
 	opusEncoder *gopus.Encoder
 
+	// Resamplers by rate pair, created on first use
+	resamplers sync.Map // key: [2]int{srcRate, dstRate}
+
 	// Thread safety
 	mu sync.RWMutex
 }
@@ -108,40 +111,45 @@ func (p *audioProcessor) PCM48MonoToOpus(pcm48 []int16) ([]byte, error) {
 	return p.opusEncoder.Encode(stereo, DiscordFrameSize, 0)
 }
 
+// DownsamplePCM returns src at the lower dstRate, filtered so nothing above
+// the new Nyquist frequency aliases into it (e.g. 48 k ➜ 24 k).
 func (p *audioProcessor) DownsamplePCM(src []int16, srcRate, dstRate int) ([]int16, error) {
 	if len(src) == 0 {
 		return nil, errors.New("pcm empty")
 	}
-	if srcRate <= dstRate || srcRate%dstRate != 0 {
+	if srcRate <= dstRate {
 		return nil, fmt.Errorf("unsupported ratio %d:%d", srcRate, dstRate)
 	}
-	factor := srcRate / dstRate
-	dst := make([]int16, 0, len(src)/factor)
-	for i := 0; i < len(src); i += factor {
-		dst = append(dst, src[i]) // naive decimation
-	}
 
-	return dst, nil
+	return p.resample(src, srcRate, dstRate)
 }
 
-// UpsamplePCM returns a new slice whose length = len(src)*dstRate/srcRate.
-// Only integer factors are supported (e.g. 24 k ➜ 48 k, factor = 2).
+// UpsamplePCM returns a new slice whose length = len(src)*dstRate/srcRate,
+// interpolated without the images of src's spectrum (e.g. 24 k ➜ 48 k).
 func (p *audioProcessor) UpsamplePCM(src []int16, srcRate, dstRate int) ([]int16, error) {
 	if len(src) == 0 {
 		return nil, errors.New("pcm empty")
 	}
-	if dstRate <= srcRate || dstRate%srcRate != 0 {
+	if dstRate <= srcRate {
 		return nil, fmt.Errorf("unsupported ratio %d:%d", srcRate, dstRate)
 	}
-	factor := dstRate / srcRate
-	dst := make([]int16, len(src)*factor)
-	for i, v := range src {
-		for k := 0; k < factor; k++ {
-			dst[i*factor+k] = v // zero-order hold
+
+	return p.resample(src, srcRate, dstRate)
+}
+
+// resample resamples src with the processor's Resampler for the rates.
+func (p *audioProcessor) resample(src []int16, srcRate, dstRate int) ([]int16, error) {
+	key := [2]int{srcRate, dstRate}
+	r, ok := p.resamplers.Load(key)
+	if !ok {
+		resampler, err := NewResampler(srcRate, dstRate)
+		if err != nil {
+			return nil, err
 		}
+		r, _ = p.resamplers.LoadOrStore(key, resampler)
 	}
 
-	return dst, nil
+	return r.(*Resampler).Resample(src)
 }
 
 /* ---------------------------  Base-64 helpers  ------------------------ */
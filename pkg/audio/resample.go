@@ -0,0 +1,162 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Design of the resampling filter. Each output sample is a weighted sum of
+// the input around it, from a Kaiser-windowed sinc lowpass at the lower of
+// the two rates' Nyquist frequencies.
+const (
+	// sincZeroCrossings is how many zero crossings of the sinc, at the lower
+	// rate, the filter spans on each side. More give a sharper cutoff at the
+	// cost of CPU.
+	sincZeroCrossings = 32
+	// sincRolloff is where the passband ends, as a fraction of the lower
+	// Nyquist frequency: 24 kHz audio keeps everything under 10.2 kHz, well
+	// above speech, and the transition above it blocks aliases.
+	sincRolloff = 0.85
+	// kaiserBeta shapes the window for about 75 dB of stopband attenuation.
+	kaiserBeta = 7.5
+)
+
+// Resampler converts PCM between two rates with a polyphase windowed-sinc
+// filter, which keeps frequencies above the lower rate's Nyquist from
+// aliasing into the output and, when upsampling, keeps the spectrum images
+// out of it. Rates may be any ratio, such as 48 kHz to 24 kHz or 44.1 kHz to
+// 48 kHz. A Resampler holds no state between calls and is safe for
+// concurrent use.
+type Resampler struct {
+	up, down int         // Reduced ratio: up output samples per down input samples
+	delay    int         // Filter center, in upsampled samples
+	phases   [][]float32 // Taps of each phase, in input order
+}
+
+// NewResampler creates a Resampler from srcRate to dstRate.
+func NewResampler(srcRate, dstRate int) (*Resampler, error) {
+	if srcRate <= 0 || dstRate <= 0 {
+		return nil, fmt.Errorf("unsupported ratio %d:%d", srcRate, dstRate)
+	}
+	g := gcd(srcRate, dstRate)
+	up, down := dstRate/g, srcRate/g
+	if up > DiscordSampleRate || down > DiscordSampleRate {
+		return nil, fmt.Errorf("unsupported ratio %d:%d", srcRate, dstRate)
+	}
+
+	// Prototype lowpass at the upsampled rate up*srcRate
+	factor := max(up, down)
+	length := 2*sincZeroCrossings*factor + 1
+	center := (length - 1) / 2
+	cutoff := sincRolloff / (2 * float64(factor)) // Cycles per upsampled sample
+	taps := make([]float64, length)
+	for k := range taps {
+		x := float64(k - center)
+		// Upsampling stuffs up-1 zeros between samples, which the gain of up
+		// makes up for
+		taps[k] = float64(up) * 2 * cutoff * sinc(2*cutoff*x) * kaiser(x/float64(center))
+	}
+
+	// phases[p] are the taps p, p+up, p+2*up… applied to the input samples
+	// before an output of phase p; they are stored reversed, oldest input
+	// first, so a phase is a plain dot product with a window of the input.
+	phases := make([][]float32, up)
+	for p := range phases {
+		n := (length - p + up - 1) / up
+		phase := make([]float32, n)
+		for t := range n {
+			phase[n-1-t] = float32(taps[p+t*up])
+		}
+		phases[p] = phase
+	}
+
+	return &Resampler{up: up, down: down, delay: center, phases: phases}, nil
+}
+
+// Resample returns src at the destination rate, len(src)*dstRate/srcRate
+// samples rounded up. The output is aligned with src, and the audio before
+// and after it is taken as silence.
+func (r *Resampler) Resample(src []int16) ([]int16, error) {
+	if len(src) == 0 {
+		return nil, errors.New("pcm empty")
+	}
+
+	in := make([]float32, len(src))
+	for i, v := range src {
+		in[i] = float32(v)
+	}
+
+	dst := make([]int16, (len(src)*r.up+r.down-1)/r.down)
+	for n := range dst {
+		pos := n*r.down + r.delay
+		phase := r.phases[pos%r.up]
+		last := pos / r.up // Newest input sample the phase reaches
+		first := last - len(phase) + 1
+
+		var sum float32
+		if first >= 0 && last < len(in) {
+			window := in[first : last+1]
+			for t, c := range phase {
+				sum += c * window[t]
+			}
+		} else {
+			// Near the edges, samples outside src are silence
+			for t, c := range phase {
+				if i := first + t; i >= 0 && i < len(in) {
+					sum += c * in[i]
+				}
+			}
+		}
+		dst[n] = roundInt16(sum)
+	}
+
+	return dst, nil
+}
+
+// sinc is the normalized sinc function, sin(πx)/(πx).
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser is the Kaiser window at x, from -1 to 1 across the filter.
+func kaiser(x float64) float64 {
+	return bessel0(kaiserBeta*math.Sqrt(max(1-x*x, 0))) / bessel0(kaiserBeta)
+}
+
+// bessel0 is the zeroth-order modified Bessel function of the first kind,
+// from its power series.
+func bessel0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > 1e-12*sum; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+
+	return sum
+}
+
+func roundInt16(v float32) int16 {
+	switch {
+	case v >= math.MaxInt16:
+		return math.MaxInt16
+	case v <= math.MinInt16:
+		return math.MinInt16
+	case v < 0:
+		return int16(v - 0.5)
+	default:
+		return int16(v + 0.5)
+	}
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
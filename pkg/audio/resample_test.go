@@ -0,0 +1,184 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+// resampleEdge is how many output samples at each end are left out of
+// quality measurements, where the filter reaches past the input.
+const resampleEdge = 100
+
+// tone returns sample i of a tone at freq Hz sampled at rate.
+func tone(freq float64, rate, i int) float64 {
+	return 8000 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+}
+
+// sine returns n samples of a tone at freq Hz sampled at rate.
+func sine(freq float64, rate, n int) []int16 {
+	out := make([]int16, n)
+	for i := range out {
+		out[i] = int16(math.Round(tone(freq, rate, i)))
+	}
+
+	return out
+}
+
+// snr returns the signal-to-noise ratio in dB of got, sampled at rate,
+// against an exact tone at freq, away from the edges. Everything but the
+// tone counts as noise: aliases, images, distortion, passband ripple and
+// rounding to 16 bits, which limits it to about 86 dB.
+func snr(got []int16, freq float64, rate int) float64 {
+	var signal, noise float64
+	for i := resampleEdge; i < len(got)-resampleEdge; i++ {
+		want := tone(freq, rate, i)
+		signal += want * want
+		noise += (float64(got[i]) - want) * (float64(got[i]) - want)
+	}
+
+	return 10 * math.Log10(signal/noise)
+}
+
+// level returns the RMS of pcm relative to a full 8000 amplitude tone in dB,
+// away from the edges.
+func level(pcm []int16) float64 {
+	var sum float64
+	for _, v := range pcm[resampleEdge : len(pcm)-resampleEdge] {
+		sum += float64(v) * float64(v)
+	}
+	rms := math.Sqrt(sum / float64(len(pcm)-2*resampleEdge))
+
+	return 20 * math.Log10(max(rms, 1e-9)/(8000/math.Sqrt2))
+}
+
+func TestResampler_Downsample(t *testing.T) {
+	r, err := audio.NewResampler(audio.DiscordSampleRate, audio.OpenAISampleRate)
+	require.NoError(t, err)
+
+	out, err := r.Resample(sine(1000, audio.DiscordSampleRate, 4800))
+	require.NoError(t, err)
+	require.Len(t, out, 2400)
+	assert.Greater(t, snr(out, 1000, audio.OpenAISampleRate), 60.0, "speech frequencies pass untouched")
+
+	// 15 kHz is above 24 kHz's Nyquist, and decimation folds it down to 9 kHz
+	out, err = r.Resample(sine(15000, audio.DiscordSampleRate, 4800))
+	require.NoError(t, err)
+	assert.Less(t, level(out), -60.0, "tones above Nyquist are filtered instead of aliasing")
+	decimated, err := audio.Decimate(sine(15000, audio.DiscordSampleRate, 4800), audio.DiscordSampleRate, audio.OpenAISampleRate)
+	require.NoError(t, err)
+	assert.Greater(t, level(decimated), -1.0)
+}
+
+func TestResampler_Upsample(t *testing.T) {
+	r, err := audio.NewResampler(audio.OpenAISampleRate, audio.DiscordSampleRate)
+	require.NoError(t, err)
+
+	out, err := r.Resample(sine(3000, audio.OpenAISampleRate, 2400))
+	require.NoError(t, err)
+	require.Len(t, out, 4800)
+	assert.Greater(t, snr(out, 3000, audio.DiscordSampleRate), 60.0, "the image at 21 kHz is filtered out")
+}
+
+func TestResampler_Ratios(t *testing.T) {
+	r, err := audio.NewResampler(44100, audio.DiscordSampleRate)
+	require.NoError(t, err)
+	out, err := r.Resample(sine(1000, 44100, 4410))
+	require.NoError(t, err)
+	require.Len(t, out, 4800)
+	assert.Greater(t, snr(out, 1000, audio.DiscordSampleRate), 60.0)
+
+	_, err = r.Resample(nil)
+	require.Error(t, err)
+	_, err = audio.NewResampler(0, audio.DiscordSampleRate)
+	require.Error(t, err)
+}
+
+func TestAudioProcessor_Resample(t *testing.T) {
+	p, err := audio.NewAudioProcessor()
+	require.NoError(t, err)
+
+	down, err := p.DownsamplePCM(toneFrame(), audio.DiscordSampleRate, audio.OpenAISampleRate)
+	require.NoError(t, err)
+	assert.Len(t, down, audio.OpenAIFrameSize)
+	up, err := p.UpsamplePCM(down, audio.OpenAISampleRate, audio.DiscordSampleRate)
+	require.NoError(t, err)
+	assert.Len(t, up, audio.DiscordFrameSize)
+
+	_, err = p.DownsamplePCM(down, audio.OpenAISampleRate, audio.DiscordSampleRate)
+	require.Error(t, err, "downsampling must lower the rate")
+	_, err = p.UpsamplePCM(up, audio.DiscordSampleRate, audio.OpenAISampleRate)
+	require.Error(t, err, "upsampling must raise the rate")
+}
+
+// BenchmarkDownsample compares the downsampling methods on a second of
+// speech-band audio with hiss above 12 kHz, reporting each one's SNR against
+// the tone alone.
+func BenchmarkDownsample(b *testing.B) {
+	src := sine(1000, audio.DiscordSampleRate, audio.DiscordSampleRate)
+	hiss := sine(15000, audio.DiscordSampleRate, audio.DiscordSampleRate)
+	for i := range src {
+		src[i] += hiss[i] / 10
+	}
+
+	resampler, err := audio.NewResampler(audio.DiscordSampleRate, audio.OpenAISampleRate)
+	require.NoError(b, err)
+	methods := []struct {
+		name     string
+		resample func([]int16) ([]int16, error)
+	}{
+		{"sinc", resampler.Resample},
+		{"average", func(src []int16) ([]int16, error) {
+			return audio.DownsampleAverage(src, audio.DiscordSampleRate, audio.OpenAISampleRate)
+		}},
+		{"decimate", func(src []int16) ([]int16, error) {
+			return audio.Decimate(src, audio.DiscordSampleRate, audio.OpenAISampleRate)
+		}},
+	}
+	for _, m := range methods {
+		b.Run(m.name, func(b *testing.B) {
+			var out []int16
+			for b.Loop() {
+				out, _ = m.resample(src)
+			}
+			b.ReportMetric(snr(out, 1000, audio.OpenAISampleRate), "snr_dB")
+		})
+	}
+}
+
+// BenchmarkUpsample compares the sinc resampler with the zero-order hold
+// it replaced on a second of a 3 kHz tone, reporting each one's SNR.
+func BenchmarkUpsample(b *testing.B) {
+	src := sine(3000, audio.OpenAISampleRate, audio.OpenAISampleRate)
+
+	resampler, err := audio.NewResampler(audio.OpenAISampleRate, audio.DiscordSampleRate)
+	require.NoError(b, err)
+	hold := func(src []int16) ([]int16, error) {
+		dst := make([]int16, 2*len(src))
+		for i, v := range src {
+			dst[2*i], dst[2*i+1] = v, v
+		}
+
+		return dst, nil
+	}
+	methods := []struct {
+		name     string
+		resample func([]int16) ([]int16, error)
+	}{
+		{"sinc", resampler.Resample},
+		{"hold", hold},
+	}
+	for _, m := range methods {
+		b.Run(m.name, func(b *testing.B) {
+			var out []int16
+			for b.Loop() {
+				out, _ = m.resample(src)
+			}
+			b.ReportMetric(snr(out, 3000, audio.DiscordSampleRate), "snr_dB")
+		})
+	}
+}
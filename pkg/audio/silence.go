@@ -0,0 +1,82 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SilenceDetector classifies PCM frames as speech or silence using a normalized
+// RMS energy threshold. Threshold and duration can be changed while a session is
+// running; all methods are safe for concurrent use.
+type SilenceDetector struct {
+	mu        sync.RWMutex
+	threshold float32       // normalized RMS energy (0.0–1.0) below which a frame is silent
+	duration  time.Duration // how long silence must last before a turn is committed
+}
+
+// NewSilenceDetector creates a detector with the given threshold and silence duration.
+func NewSilenceDetector(threshold float32, duration time.Duration) *SilenceDetector {
+	return &SilenceDetector{
+		threshold: threshold,
+		duration:  duration,
+	}
+}
+
+// IsSilent reports whether pcm is below the energy threshold, along with the
+// measured energy.
+func (d *SilenceDetector) IsSilent(pcm []int16) (bool, float32) {
+	energy := Energy(pcm)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return energy < d.threshold, energy
+}
+
+// Threshold returns the current energy threshold.
+func (d *SilenceDetector) Threshold() float32 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.threshold
+}
+
+// Duration returns the current silence duration.
+func (d *SilenceDetector) Duration() time.Duration {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.duration
+}
+
+// SetThreshold updates the energy threshold.
+func (d *SilenceDetector) SetThreshold(threshold float32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.threshold = threshold
+}
+
+// SetDuration updates the silence duration.
+func (d *SilenceDetector) SetDuration(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.duration = duration
+}
+
+// Energy returns the RMS energy of pcm normalized to the 0.0–1.0 range.
+func Energy(pcm []int16) float32 {
+	if len(pcm) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range pcm {
+		f := float64(v) / 32768
+		sum += f * f
+	}
+
+	return float32(math.Sqrt(sum / float64(len(pcm))))
+}
@@ -0,0 +1,35 @@
+package audio_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Raikerian/go-discord-chatgpt/pkg/audio"
+)
+
+func TestSilenceDetector(t *testing.T) {
+	quiet := make([]int16, audio.DiscordFrameSize)
+	loud := make([]int16, audio.DiscordFrameSize)
+	for i := range loud {
+		loud[i] = 8000
+		quiet[i] = 100
+	}
+
+	d := audio.NewSilenceDetector(0.01, 1500*time.Millisecond)
+
+	silent, _ := d.IsSilent(quiet)
+	assert.True(t, silent)
+	silent, energy := d.IsSilent(loud)
+	assert.False(t, silent)
+	assert.InDelta(t, 8000.0/32768, energy, 0.001)
+
+	// Raising the threshold at runtime makes the same frame count as silence.
+	d.SetThreshold(0.5)
+	silent, _ = d.IsSilent(loud)
+	assert.True(t, silent)
+
+	d.SetDuration(500 * time.Millisecond)
+	assert.Equal(t, 500*time.Millisecond, d.Duration())
+}
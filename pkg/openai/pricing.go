@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -17,12 +18,49 @@ type TokenPricing struct {
 	AudioOutputPerMillion *float64 `json:"audio_output_per_million"` // Cost per 1 million audio output tokens in USD (nil if not supported)
 }
 
+// ModelCapabilities lists the inputs and features a model supports.
+type ModelCapabilities struct {
+	Vision bool `json:"vision"` // Accepts image inputs
+	Tools  bool `json:"tools"`  // Supports function/tool calling
+}
+
+// ModelDeprecation marks a model that OpenAI is retiring.
+type ModelDeprecation struct {
+	ShutdownDate string `json:"shutdown_date"` // Date the model stops working as YYYY-MM-DD (empty if not announced)
+	Replacement  string `json:"replacement"`   // Model recommended instead (empty if none)
+}
+
 // ModelInfo contains detailed information about an OpenAI model.
 type ModelInfo struct {
-	Name        string       `json:"name"`         // Model name/identifier
-	DisplayName string       `json:"display_name"` // Human-readable display name
-	Pricing     TokenPricing `json:"pricing"`      // Token pricing information
-	ContextSize *int         `json:"context_size"` // Maximum context window size in tokens (nil if not specified)
+	Name            string            `json:"name"`             // Model name/identifier
+	DisplayName     string            `json:"display_name"`     // Human-readable display name
+	KnowledgeCutoff *string           `json:"knowledge_cutoff"` // Training data cutoff as YYYY-MM (nil if not specified)
+	Capabilities    ModelCapabilities `json:"capabilities"`     // Supported inputs and features
+	Deprecation     *ModelDeprecation `json:"deprecation"`      // Retirement details (nil if not deprecated)
+	Pricing         TokenPricing      `json:"pricing"`          // Token pricing information
+	ContextSize     *int              `json:"context_size"`     // Maximum context window size in tokens (nil if not specified)
+}
+
+// Summary describes the model's capabilities and knowledge cutoff in a short
+// line, such as "vision, tools · knowledge to 2024-06".
+func (m ModelInfo) Summary() string {
+	var capabilities []string
+	if m.Capabilities.Vision {
+		capabilities = append(capabilities, "vision")
+	}
+	if m.Capabilities.Tools {
+		capabilities = append(capabilities, "tools")
+	}
+
+	parts := []string{"text only"}
+	if len(capabilities) > 0 {
+		parts[0] = strings.Join(capabilities, ", ")
+	}
+	if m.KnowledgeCutoff != nil && *m.KnowledgeCutoff != "" {
+		parts = append(parts, "knowledge to "+*m.KnowledgeCutoff)
+	}
+
+	return strings.Join(parts, " · ")
 }
 
 // PricingData contains all OpenAI model pricing information.
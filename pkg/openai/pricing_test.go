@@ -336,3 +336,40 @@ func TestPricingService_GetContextSize(t *testing.T) {
 		})
 	}
 }
+
+func TestModelInfo_Summary(t *testing.T) {
+	cutoff := "2024-06"
+
+	tests := []struct {
+		name  string
+		model ModelInfo
+		want  string
+	}{
+		{
+			name: "vision and tools with cutoff",
+			model: ModelInfo{
+				KnowledgeCutoff: &cutoff,
+				Capabilities:    ModelCapabilities{Vision: true, Tools: true},
+			},
+			want: "vision, tools · knowledge to 2024-06",
+		},
+		{
+			name:  "tools without cutoff",
+			model: ModelInfo{Capabilities: ModelCapabilities{Tools: true}},
+			want:  "tools",
+		},
+		{
+			name:  "text only",
+			model: ModelInfo{KnowledgeCutoff: &cutoff},
+			want:  "text only · knowledge to 2024-06",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.model.Summary(); got != tt.want {
+				t.Errorf("Summary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,9 +5,6 @@
 package test
 
 import (
-	"time"
-
-	"github.com/diamondburned/arikawa/v3/discord"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -38,349 +35,362 @@ func (_m *MockAudioMixer) EXPECT() *MockAudioMixer_Expecter {
 	return &MockAudioMixer_Expecter{mock: &_m.Mock}
 }
 
-// AddUserAudioWithRTP provides a mock function for the type MockAudioMixer
-func (_mock *MockAudioMixer) AddUserAudioWithRTP(userID discord.UserID, audio []byte, timestamp time.Time, rtpTimestamp uint32, sequence uint16) error {
-	ret := _mock.Called(userID, audio, timestamp, rtpTimestamp, sequence)
+// AddFrame provides a mock function for the type MockAudioMixer
+func (_mock *MockAudioMixer) AddFrame(ssrc uint32, ts uint32, pcm []int16) error {
+	ret := _mock.Called(ssrc, ts, pcm)
 
 	if len(ret) == 0 {
-		panic("no return value specified for AddUserAudioWithRTP")
+		panic("no return value specified for AddFrame")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(discord.UserID, []byte, time.Time, uint32, uint16) error); ok {
-		r0 = returnFunc(userID, audio, timestamp, rtpTimestamp, sequence)
+	if returnFunc, ok := ret.Get(0).(func(uint32, uint32, []int16) error); ok {
+		r0 = returnFunc(ssrc, ts, pcm)
 	} else {
 		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockAudioMixer_AddUserAudioWithRTP_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddUserAudioWithRTP'
-type MockAudioMixer_AddUserAudioWithRTP_Call struct {
+// MockAudioMixer_AddFrame_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddFrame'
+type MockAudioMixer_AddFrame_Call struct {
 	*mock.Call
 }
 
-// AddUserAudioWithRTP is a helper method to define mock.On call
-//   - userID
-//   - audio
-//   - timestamp
-//   - rtpTimestamp
-//   - sequence
-func (_e *MockAudioMixer_Expecter) AddUserAudioWithRTP(userID interface{}, audio interface{}, timestamp interface{}, rtpTimestamp interface{}, sequence interface{}) *MockAudioMixer_AddUserAudioWithRTP_Call {
-	return &MockAudioMixer_AddUserAudioWithRTP_Call{Call: _e.mock.On("AddUserAudioWithRTP", userID, audio, timestamp, rtpTimestamp, sequence)}
+// AddFrame is a helper method to define mock.On call
+//   - ssrc
+//   - ts
+//   - pcm
+func (_e *MockAudioMixer_Expecter) AddFrame(ssrc interface{}, ts interface{}, pcm interface{}) *MockAudioMixer_AddFrame_Call {
+	return &MockAudioMixer_AddFrame_Call{Call: _e.mock.On("AddFrame", ssrc, ts, pcm)}
 }
 
-func (_c *MockAudioMixer_AddUserAudioWithRTP_Call) Run(run func(userID discord.UserID, audio []byte, timestamp time.Time, rtpTimestamp uint32, sequence uint16)) *MockAudioMixer_AddUserAudioWithRTP_Call {
+func (_c *MockAudioMixer_AddFrame_Call) Run(run func(ssrc uint32, ts uint32, pcm []int16)) *MockAudioMixer_AddFrame_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(discord.UserID), args[1].([]byte), args[2].(time.Time), args[3].(uint32), args[4].(uint16))
+		run(args[0].(uint32), args[1].(uint32), args[2].([]int16))
 	})
 	return _c
 }
 
-func (_c *MockAudioMixer_AddUserAudioWithRTP_Call) Return(err error) *MockAudioMixer_AddUserAudioWithRTP_Call {
+func (_c *MockAudioMixer_AddFrame_Call) Return(err error) *MockAudioMixer_AddFrame_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockAudioMixer_AddUserAudioWithRTP_Call) RunAndReturn(run func(userID discord.UserID, audio []byte, timestamp time.Time, rtpTimestamp uint32, sequence uint16) error) *MockAudioMixer_AddUserAudioWithRTP_Call {
+func (_c *MockAudioMixer_AddFrame_Call) RunAndReturn(run func(ssrc uint32, ts uint32, pcm []int16) error) *MockAudioMixer_AddFrame_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// ClearAllBuffers provides a mock function for the type MockAudioMixer
-func (_mock *MockAudioMixer) ClearAllBuffers() {
+// Clear provides a mock function for the type MockAudioMixer
+func (_mock *MockAudioMixer) Clear() {
 	_mock.Called()
 	return
 }
 
-// MockAudioMixer_ClearAllBuffers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClearAllBuffers'
-type MockAudioMixer_ClearAllBuffers_Call struct {
+// MockAudioMixer_Clear_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Clear'
+type MockAudioMixer_Clear_Call struct {
 	*mock.Call
 }
 
-// ClearAllBuffers is a helper method to define mock.On call
-func (_e *MockAudioMixer_Expecter) ClearAllBuffers() *MockAudioMixer_ClearAllBuffers_Call {
-	return &MockAudioMixer_ClearAllBuffers_Call{Call: _e.mock.On("ClearAllBuffers")}
+// Clear is a helper method to define mock.On call
+func (_e *MockAudioMixer_Expecter) Clear() *MockAudioMixer_Clear_Call {
+	return &MockAudioMixer_Clear_Call{Call: _e.mock.On("Clear")}
 }
 
-func (_c *MockAudioMixer_ClearAllBuffers_Call) Run(run func()) *MockAudioMixer_ClearAllBuffers_Call {
+func (_c *MockAudioMixer_Clear_Call) Run(run func()) *MockAudioMixer_Clear_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run()
 	})
 	return _c
 }
 
-func (_c *MockAudioMixer_ClearAllBuffers_Call) Return() *MockAudioMixer_ClearAllBuffers_Call {
+func (_c *MockAudioMixer_Clear_Call) Return() *MockAudioMixer_Clear_Call {
 	_c.Call.Return()
 	return _c
 }
 
-func (_c *MockAudioMixer_ClearAllBuffers_Call) RunAndReturn(run func()) *MockAudioMixer_ClearAllBuffers_Call {
+func (_c *MockAudioMixer_Clear_Call) RunAndReturn(run func()) *MockAudioMixer_Clear_Call {
 	_c.Run(run)
 	return _c
 }
 
-// ClearUserBuffer provides a mock function for the type MockAudioMixer
-func (_mock *MockAudioMixer) ClearUserBuffer(userID discord.UserID) {
-	_mock.Called(userID)
-	return
+// Drain provides a mock function for the type MockAudioMixer
+func (_mock *MockAudioMixer) Drain() []int16 {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Drain")
+	}
+
+	var r0 []int16
+	if returnFunc, ok := ret.Get(0).(func() []int16); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int16)
+		}
+	}
+	return r0
 }
 
-// MockAudioMixer_ClearUserBuffer_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClearUserBuffer'
-type MockAudioMixer_ClearUserBuffer_Call struct {
+// MockAudioMixer_Drain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Drain'
+type MockAudioMixer_Drain_Call struct {
 	*mock.Call
 }
 
-// ClearUserBuffer is a helper method to define mock.On call
-//   - userID
-func (_e *MockAudioMixer_Expecter) ClearUserBuffer(userID interface{}) *MockAudioMixer_ClearUserBuffer_Call {
-	return &MockAudioMixer_ClearUserBuffer_Call{Call: _e.mock.On("ClearUserBuffer", userID)}
+// Drain is a helper method to define mock.On call
+func (_e *MockAudioMixer_Expecter) Drain() *MockAudioMixer_Drain_Call {
+	return &MockAudioMixer_Drain_Call{Call: _e.mock.On("Drain")}
 }
 
-func (_c *MockAudioMixer_ClearUserBuffer_Call) Run(run func(userID discord.UserID)) *MockAudioMixer_ClearUserBuffer_Call {
+func (_c *MockAudioMixer_Drain_Call) Run(run func()) *MockAudioMixer_Drain_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(discord.UserID))
+		run()
 	})
 	return _c
 }
 
-func (_c *MockAudioMixer_ClearUserBuffer_Call) Return() *MockAudioMixer_ClearUserBuffer_Call {
-	_c.Call.Return()
+func (_c *MockAudioMixer_Drain_Call) Return(ints []int16) *MockAudioMixer_Drain_Call {
+	_c.Call.Return(ints)
 	return _c
 }
 
-func (_c *MockAudioMixer_ClearUserBuffer_Call) RunAndReturn(run func(userID discord.UserID)) *MockAudioMixer_ClearUserBuffer_Call {
-	_c.Run(run)
+func (_c *MockAudioMixer_Drain_Call) RunAndReturn(run func() []int16) *MockAudioMixer_Drain_Call {
+	_c.Call.Return(run)
 	return _c
 }
 
-// GetAllAvailableMixedAudio provides a mock function for the type MockAudioMixer
-func (_mock *MockAudioMixer) GetAllAvailableMixedAudio() ([]byte, time.Duration, error) {
+// DrainStreams provides a mock function for the type MockAudioMixer
+func (_mock *MockAudioMixer) DrainStreams() ([]int16, map[uint32][]int16) {
 	ret := _mock.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAllAvailableMixedAudio")
+		panic("no return value specified for DrainStreams")
 	}
 
-	var r0 []byte
-	var r1 time.Duration
-	var r2 error
-	if returnFunc, ok := ret.Get(0).(func() ([]byte, time.Duration, error)); ok {
+	var r0 []int16
+	var r1 map[uint32][]int16
+	if returnFunc, ok := ret.Get(0).(func() ([]int16, map[uint32][]int16)); ok {
 		return returnFunc()
 	}
-	if returnFunc, ok := ret.Get(0).(func() []byte); ok {
+	if returnFunc, ok := ret.Get(0).(func() []int16); ok {
 		r0 = returnFunc()
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]byte)
+			r0 = ret.Get(0).([]int16)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func() time.Duration); ok {
+	if returnFunc, ok := ret.Get(1).(func() map[uint32][]int16); ok {
 		r1 = returnFunc()
 	} else {
-		r1 = ret.Get(1).(time.Duration)
-	}
-	if returnFunc, ok := ret.Get(2).(func() error); ok {
-		r2 = returnFunc()
-	} else {
-		r2 = ret.Error(2)
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(map[uint32][]int16)
+		}
 	}
-	return r0, r1, r2
+	return r0, r1
 }
 
-// MockAudioMixer_GetAllAvailableMixedAudio_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAllAvailableMixedAudio'
-type MockAudioMixer_GetAllAvailableMixedAudio_Call struct {
+// MockAudioMixer_DrainStreams_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DrainStreams'
+type MockAudioMixer_DrainStreams_Call struct {
 	*mock.Call
 }
 
-// GetAllAvailableMixedAudio is a helper method to define mock.On call
-func (_e *MockAudioMixer_Expecter) GetAllAvailableMixedAudio() *MockAudioMixer_GetAllAvailableMixedAudio_Call {
-	return &MockAudioMixer_GetAllAvailableMixedAudio_Call{Call: _e.mock.On("GetAllAvailableMixedAudio")}
+// DrainStreams is a helper method to define mock.On call
+func (_e *MockAudioMixer_Expecter) DrainStreams() *MockAudioMixer_DrainStreams_Call {
+	return &MockAudioMixer_DrainStreams_Call{Call: _e.mock.On("DrainStreams")}
 }
 
-func (_c *MockAudioMixer_GetAllAvailableMixedAudio_Call) Run(run func()) *MockAudioMixer_GetAllAvailableMixedAudio_Call {
+func (_c *MockAudioMixer_DrainStreams_Call) Run(run func()) *MockAudioMixer_DrainStreams_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run()
 	})
 	return _c
 }
 
-func (_c *MockAudioMixer_GetAllAvailableMixedAudio_Call) Return(bytes []byte, duration time.Duration, err error) *MockAudioMixer_GetAllAvailableMixedAudio_Call {
-	_c.Call.Return(bytes, duration, err)
+func (_c *MockAudioMixer_DrainStreams_Call) Return(mixed []int16, streams map[uint32][]int16) *MockAudioMixer_DrainStreams_Call {
+	_c.Call.Return(mixed, streams)
 	return _c
 }
 
-func (_c *MockAudioMixer_GetAllAvailableMixedAudio_Call) RunAndReturn(run func() ([]byte, time.Duration, error)) *MockAudioMixer_GetAllAvailableMixedAudio_Call {
+func (_c *MockAudioMixer_DrainStreams_Call) RunAndReturn(run func() ([]int16, map[uint32][]int16)) *MockAudioMixer_DrainStreams_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetAllAvailableMixedAudioAndFlush provides a mock function for the type MockAudioMixer
-func (_mock *MockAudioMixer) GetAllAvailableMixedAudioAndFlush() ([]byte, time.Duration, error) {
+// GetDominantSpeaker provides a mock function for the type MockAudioMixer
+func (_mock *MockAudioMixer) GetDominantSpeaker() (uint32, bool) {
 	ret := _mock.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAllAvailableMixedAudioAndFlush")
+		panic("no return value specified for GetDominantSpeaker")
 	}
 
-	var r0 []byte
-	var r1 time.Duration
-	var r2 error
-	if returnFunc, ok := ret.Get(0).(func() ([]byte, time.Duration, error)); ok {
+	var r0 uint32
+	var r1 bool
+	if returnFunc, ok := ret.Get(0).(func() (uint32, bool)); ok {
 		return returnFunc()
 	}
-	if returnFunc, ok := ret.Get(0).(func() []byte); ok {
+	if returnFunc, ok := ret.Get(0).(func() uint32); ok {
 		r0 = returnFunc()
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]byte)
-		}
+		r0 = ret.Get(0).(uint32)
 	}
-	if returnFunc, ok := ret.Get(1).(func() time.Duration); ok {
+	if returnFunc, ok := ret.Get(1).(func() bool); ok {
 		r1 = returnFunc()
 	} else {
-		r1 = ret.Get(1).(time.Duration)
-	}
-	if returnFunc, ok := ret.Get(2).(func() error); ok {
-		r2 = returnFunc()
-	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Get(1).(bool)
 	}
-	return r0, r1, r2
+	return r0, r1
 }
 
-// MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAllAvailableMixedAudioAndFlush'
-type MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call struct {
+// MockAudioMixer_GetDominantSpeaker_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDominantSpeaker'
+type MockAudioMixer_GetDominantSpeaker_Call struct {
 	*mock.Call
 }
 
-// GetAllAvailableMixedAudioAndFlush is a helper method to define mock.On call
-func (_e *MockAudioMixer_Expecter) GetAllAvailableMixedAudioAndFlush() *MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call {
-	return &MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call{Call: _e.mock.On("GetAllAvailableMixedAudioAndFlush")}
+// GetDominantSpeaker is a helper method to define mock.On call
+func (_e *MockAudioMixer_Expecter) GetDominantSpeaker() *MockAudioMixer_GetDominantSpeaker_Call {
+	return &MockAudioMixer_GetDominantSpeaker_Call{Call: _e.mock.On("GetDominantSpeaker")}
 }
 
-func (_c *MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call) Run(run func()) *MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call {
+func (_c *MockAudioMixer_GetDominantSpeaker_Call) Run(run func()) *MockAudioMixer_GetDominantSpeaker_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run()
 	})
 	return _c
 }
 
-func (_c *MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call) Return(bytes []byte, duration time.Duration, err error) *MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call {
-	_c.Call.Return(bytes, duration, err)
+func (_c *MockAudioMixer_GetDominantSpeaker_Call) Return(v uint32, b bool) *MockAudioMixer_GetDominantSpeaker_Call {
+	_c.Call.Return(v, b)
 	return _c
 }
 
-func (_c *MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call) RunAndReturn(run func() ([]byte, time.Duration, error)) *MockAudioMixer_GetAllAvailableMixedAudioAndFlush_Call {
+func (_c *MockAudioMixer_GetDominantSpeaker_Call) RunAndReturn(run func() (uint32, bool)) *MockAudioMixer_GetDominantSpeaker_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetDominantSpeaker provides a mock function for the type MockAudioMixer
-func (_mock *MockAudioMixer) GetDominantSpeaker() (discord.UserID, float32) {
+// GetMixed provides a mock function for the type MockAudioMixer
+func (_mock *MockAudioMixer) GetMixed() []int16 {
 	ret := _mock.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetDominantSpeaker")
+		panic("no return value specified for GetMixed")
 	}
 
-	var r0 discord.UserID
-	var r1 float32
-	if returnFunc, ok := ret.Get(0).(func() (discord.UserID, float32)); ok {
-		return returnFunc()
-	}
-	if returnFunc, ok := ret.Get(0).(func() discord.UserID); ok {
+	var r0 []int16
+	if returnFunc, ok := ret.Get(0).(func() []int16); ok {
 		r0 = returnFunc()
 	} else {
-		r0 = ret.Get(0).(discord.UserID)
-	}
-	if returnFunc, ok := ret.Get(1).(func() float32); ok {
-		r1 = returnFunc()
-	} else {
-		r1 = ret.Get(1).(float32)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int16)
+		}
 	}
-	return r0, r1
+	return r0
 }
 
-// MockAudioMixer_GetDominantSpeaker_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDominantSpeaker'
-type MockAudioMixer_GetDominantSpeaker_Call struct {
+// MockAudioMixer_GetMixed_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMixed'
+type MockAudioMixer_GetMixed_Call struct {
 	*mock.Call
 }
 
-// GetDominantSpeaker is a helper method to define mock.On call
-func (_e *MockAudioMixer_Expecter) GetDominantSpeaker() *MockAudioMixer_GetDominantSpeaker_Call {
-	return &MockAudioMixer_GetDominantSpeaker_Call{Call: _e.mock.On("GetDominantSpeaker")}
+// GetMixed is a helper method to define mock.On call
+func (_e *MockAudioMixer_Expecter) GetMixed() *MockAudioMixer_GetMixed_Call {
+	return &MockAudioMixer_GetMixed_Call{Call: _e.mock.On("GetMixed")}
 }
 
-func (_c *MockAudioMixer_GetDominantSpeaker_Call) Run(run func()) *MockAudioMixer_GetDominantSpeaker_Call {
+func (_c *MockAudioMixer_GetMixed_Call) Run(run func()) *MockAudioMixer_GetMixed_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run()
 	})
 	return _c
 }
 
-func (_c *MockAudioMixer_GetDominantSpeaker_Call) Return(userID discord.UserID, f float32) *MockAudioMixer_GetDominantSpeaker_Call {
-	_c.Call.Return(userID, f)
+func (_c *MockAudioMixer_GetMixed_Call) Return(ints []int16) *MockAudioMixer_GetMixed_Call {
+	_c.Call.Return(ints)
 	return _c
 }
 
-func (_c *MockAudioMixer_GetDominantSpeaker_Call) RunAndReturn(run func() (discord.UserID, float32)) *MockAudioMixer_GetDominantSpeaker_Call {
+func (_c *MockAudioMixer_GetMixed_Call) RunAndReturn(run func() []int16) *MockAudioMixer_GetMixed_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetMixedAudio provides a mock function for the type MockAudioMixer
-func (_mock *MockAudioMixer) GetMixedAudio(duration time.Duration) ([]byte, error) {
-	ret := _mock.Called(duration)
+// Len provides a mock function for the type MockAudioMixer
+func (_mock *MockAudioMixer) Len() int {
+	ret := _mock.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetMixedAudio")
+		panic("no return value specified for Len")
 	}
 
-	var r0 []byte
-	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(time.Duration) ([]byte, error)); ok {
-		return returnFunc(duration)
-	}
-	if returnFunc, ok := ret.Get(0).(func(time.Duration) []byte); ok {
-		r0 = returnFunc(duration)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]byte)
-		}
-	}
-	if returnFunc, ok := ret.Get(1).(func(time.Duration) error); ok {
-		r1 = returnFunc(duration)
+	var r0 int
+	if returnFunc, ok := ret.Get(0).(func() int); ok {
+		r0 = returnFunc()
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Get(0).(int)
 	}
-	return r0, r1
+	return r0
 }
 
-// MockAudioMixer_GetMixedAudio_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMixedAudio'
-type MockAudioMixer_GetMixedAudio_Call struct {
+// MockAudioMixer_Len_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Len'
+type MockAudioMixer_Len_Call struct {
 	*mock.Call
 }
 
-// GetMixedAudio is a helper method to define mock.On call
-//   - duration
-func (_e *MockAudioMixer_Expecter) GetMixedAudio(duration interface{}) *MockAudioMixer_GetMixedAudio_Call {
-	return &MockAudioMixer_GetMixedAudio_Call{Call: _e.mock.On("GetMixedAudio", duration)}
+// Len is a helper method to define mock.On call
+func (_e *MockAudioMixer_Expecter) Len() *MockAudioMixer_Len_Call {
+	return &MockAudioMixer_Len_Call{Call: _e.mock.On("Len")}
 }
 
-func (_c *MockAudioMixer_GetMixedAudio_Call) Run(run func(duration time.Duration)) *MockAudioMixer_GetMixedAudio_Call {
+func (_c *MockAudioMixer_Len_Call) Run(run func()) *MockAudioMixer_Len_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(time.Duration))
+		run()
 	})
 	return _c
 }
 
-func (_c *MockAudioMixer_GetMixedAudio_Call) Return(bytes []byte, err error) *MockAudioMixer_GetMixedAudio_Call {
-	_c.Call.Return(bytes, err)
+func (_c *MockAudioMixer_Len_Call) Return(n int) *MockAudioMixer_Len_Call {
+	_c.Call.Return(n)
 	return _c
 }
 
-func (_c *MockAudioMixer_GetMixedAudio_Call) RunAndReturn(run func(duration time.Duration) ([]byte, error)) *MockAudioMixer_GetMixedAudio_Call {
+func (_c *MockAudioMixer_Len_Call) RunAndReturn(run func() int) *MockAudioMixer_Len_Call {
 	_c.Call.Return(run)
 	return _c
 }
+
+// RemoveStream provides a mock function for the type MockAudioMixer
+func (_mock *MockAudioMixer) RemoveStream(ssrc uint32) {
+	_mock.Called(ssrc)
+	return
+}
+
+// MockAudioMixer_RemoveStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveStream'
+type MockAudioMixer_RemoveStream_Call struct {
+	*mock.Call
+}
+
+// RemoveStream is a helper method to define mock.On call
+//   - ssrc
+func (_e *MockAudioMixer_Expecter) RemoveStream(ssrc interface{}) *MockAudioMixer_RemoveStream_Call {
+	return &MockAudioMixer_RemoveStream_Call{Call: _e.mock.On("RemoveStream", ssrc)}
+}
+
+func (_c *MockAudioMixer_RemoveStream_Call) Run(run func(ssrc uint32)) *MockAudioMixer_RemoveStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(uint32))
+	})
+	return _c
+}
+
+func (_c *MockAudioMixer_RemoveStream_Call) Return() *MockAudioMixer_RemoveStream_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockAudioMixer_RemoveStream_Call) RunAndReturn(run func(ssrc uint32)) *MockAudioMixer_RemoveStream_Call {
+	_c.Run(run)
+	return _c
+}
@@ -58,6 +58,14 @@ func (d *Debouncer) Reset() {
 	d.timer.Reset(d.duration)
 }
 
+// SetDuration changes the duration used by subsequent calls to Reset.
+func (d *Debouncer) SetDuration(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.duration = duration
+}
+
 // C returns the timer's channel.
 func (d *Debouncer) C() <-chan time.Time {
 	return d.timer.C